@@ -40,11 +40,13 @@ export interface AskRequest {
 }
 
 export interface AskResponse {
-  answer: string;
+  query: string;
+  answer?: string;
   references: Array<{
     resource_id: string;
     content: string;
   }>;
+  took_ms: number;
 }
 
 export interface SaveDocumentRequest {
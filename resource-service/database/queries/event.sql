@@ -1,15 +1,28 @@
 -- name: CreateEvent :one
-INSERT INTO events (name, topic, payload)
-VALUES ($1, $2, $3)
-RETURNING id, name, topic, payload, sent, event_time;
+INSERT INTO events (name, topic, payload, headers)
+VALUES ($1, $2, $3, $4)
+RETURNING id, name, topic, payload, sent, event_time, headers;
 
 -- name: GetNotSentEvents :many
-SELECT id, name, topic, payload, sent, event_time
+SELECT id, name, topic, payload, sent, event_time, headers
 FROM events
 WHERE sent=false
-ORDER BY event_time ASC
+ORDER BY event_time ASC, id ASC
 LIMIT $1 OFFSET $2;
 
+-- name: HasOlderNotSentEvents :one
+SELECT EXISTS (
+    SELECT 1
+    FROM events
+    WHERE sent = false
+      AND (event_time, id) < (sqlc.arg(event_time)::timestamp, sqlc.arg(id)::uuid)
+);
+
+-- name: CountNotSentEvents :one
+SELECT count(*)
+FROM events
+WHERE sent = false;
+
 -- name: MarkEventAsSent :exec
 UPDATE events 
 SET sent = true 
@@ -1,34 +1,74 @@
 -- name: GetResources :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 ORDER BY created_at DESC
 LIMIT $1
 OFFSET $2;
 
 -- name: GetResourcesByOwnerID :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+-- Listings of an owner sort by a whitelisted column: only the CASE matching
+-- sort_by and sort_order yields values, the others are NULL for every row.
+-- The id breaks ties so pages never overlap.
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
-WHERE owner_id = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3;
+WHERE owner_id = sqlc.arg(owner_id)
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_order)::text = 'asc' THEN created_at END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_order)::text = 'desc' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updated_at' AND sqlc.arg(sort_order)::text = 'asc' THEN updated_at END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updated_at' AND sqlc.arg(sort_order)::text = 'desc' THEN updated_at END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_order)::text = 'asc' THEN name END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_order)::text = 'desc' THEN name END DESC,
+    id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetResourcesByOwnerIDAndStatus :many
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+FROM resources
+WHERE owner_id = sqlc.arg(owner_id) AND status = sqlc.arg(status)
+ORDER BY
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_order)::text = 'asc' THEN created_at END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_order)::text = 'desc' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updated_at' AND sqlc.arg(sort_order)::text = 'asc' THEN updated_at END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'updated_at' AND sqlc.arg(sort_order)::text = 'desc' THEN updated_at END DESC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_order)::text = 'asc' THEN name END ASC,
+    CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_order)::text = 'desc' THEN name END DESC,
+    id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetUsersResourceByContentHash :one
+-- The oldest resource of the owner with the content, to detect duplicate uploads
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+FROM resources
+WHERE owner_id = $1 AND content_hash = $2
+ORDER BY created_at
+LIMIT 1;
 
 -- name: GetUsersResourceByID :one
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE id = $1 AND owner_id = $2;
 
 -- name: GetResourceByID :one
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE id = $1;
 
+-- name: GetResourceStatus :one
+-- Only the status, for polling without reading the content of the resource
+SELECT id, status
+FROM resources
+WHERE id = $1 AND owner_id = $2;
+
 -- name: CreateResource :one
 INSERT INTO resources (
-    name, type, url, extracted_content, raw_content, owner_id
+    name, type, url, extracted_content, raw_content, owner_id, content_hash, tenant_id,
+    chunk_size, chunk_overlap, page_first, page_last
 ) VALUES (
-    $1, $2, $3, $4, $5,  $6
-) RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at;
+    $1, $2, $3, $4, $5,  $6, $7, $8, $9, $10, $11, $12
+) RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last;
 
 -- name: UpdateUsersResource :one
 UPDATE resources
@@ -40,9 +80,10 @@ SET
     raw_content = COALESCE($7, raw_content),
     status = COALESCE($8, status),
     owner_id = COALESCE($9, owner_id),
+    content_hash = COALESCE($10, content_hash),
     updated_at = NOW()
 WHERE id = $1 AND owner_id = $2
-RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at;
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last;
 
 -- name: DeleteUsersResource :exec
 DELETE FROM resources
@@ -54,7 +95,7 @@ FROM resources
 WHERE id = $1 AND (owner_id = $2 OR owner_id IS NULL OR owner_id = '');
 
 -- name: GetResourcesWithFilter :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE
     ($1::text IS NULL OR name ILIKE '%' || $1 || '%') AND
@@ -68,25 +109,63 @@ LIMIT $5 OFFSET $6;
 UPDATE resources
 SET status = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at;
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last;
+
+-- name: UpdateResourceVisibility :one
+UPDATE resources
+SET visibility = $3, updated_at = NOW()
+WHERE id = $1 AND owner_id = $2
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last;
+
+-- name: FailStaleResources :many
+UPDATE resources
+SET status = 'failed', updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM resources
+    WHERE status IN ('pending', 'processing') AND updated_at < $1
+    ORDER BY updated_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last;
+
+-- name: UpdateResourceChunks :exec
+UPDATE resources
+SET chunk_ids = $2, chunk_hashes = $3, updated_at = NOW()
+WHERE id = $1;
 
 -- name: GetResourcesByStatus :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE status = $1
 ORDER BY created_at DESC;
 
 -- name: GetResourcesByType :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE type = $1
 ORDER BY created_at DESC;
 
--- name: CountResourcesByOwner :one
+-- name: CountResources :one
+SELECT COUNT(*) as count
+FROM resources;
+
+-- name: CountResourcesByOwnerID :one
 SELECT COUNT(*) as count
 FROM resources
 WHERE owner_id = $1;
 
+-- name: CountResourcesByOwnerIDAndStatus :one
+SELECT COUNT(*) as count
+FROM resources
+WHERE owner_id = $1 AND status = $2;
+
+-- name: CountResourcesByOwnerIDPerStatus :many
+SELECT status, COUNT(*) as count
+FROM resources
+WHERE owner_id = $1
+GROUP BY status;
+
 -- name: CountResourcesByStatus :one
 SELECT COUNT(*) as count
 FROM resources
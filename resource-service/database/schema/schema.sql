@@ -1,11 +1,15 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TYPE resource_type AS ENUM (
-    'pdf', 'txt', 'url'
+    'pdf', 'txt', 'url', 'csv', 'epub'
     );
 
 CREATE TYPE resource_status AS ENUM (
-    'pending', 'processing', 'completed', 'failed'
+    'pending', 'processing', 'completed', 'failed', 'cancelled'
+    );
+
+CREATE TYPE resource_visibility AS ENUM (
+    'private', 'shared', 'public'
     );
 
 CREATE TABLE resources (
@@ -18,7 +22,16 @@ CREATE TABLE resources (
                            status resource_status NOT NULL DEFAULT 'pending',
                            owner_id UUID NOT NULL,
                            created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
-                           updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+                           updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                           chunk_ids TEXT[] NOT NULL DEFAULT '{}',
+                           chunk_hashes TEXT[] NOT NULL DEFAULT '{}',
+                           content_hash TEXT,
+                           visibility resource_visibility NOT NULL DEFAULT 'private',
+                           tenant_id TEXT NOT NULL DEFAULT '',
+                           chunk_size INTEGER,
+                           chunk_overlap INTEGER,
+                           page_first INTEGER,
+                           page_last INTEGER
 );
 
 CREATE TABLE events (
@@ -27,10 +40,13 @@ CREATE TABLE events (
     topic VARCHAR(255) NOT NULL,
     payload JSON NOT NULL,
     sent BOOLEAN NOT NULL DEFAULT FALSE,
-    event_time TIMESTAMP NOT NULL DEFAULT NOW()
+    event_time TIMESTAMP NOT NULL DEFAULT NOW(),
+    headers JSONB NOT NULL DEFAULT '{}'
 );
 
 CREATE INDEX IF NOT EXISTS idx_resources_status ON resources USING HASH (status);
 CREATE INDEX IF NOT EXISTS idx_resources_type ON resources USING HASH (type);
 CREATE INDEX IF NOT EXISTS idx_resources_owner_id ON resources (owner_id);
 CREATE INDEX IF NOT EXISTS idx_resources_created_at ON resources (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_resources_owner_id_content_hash ON resources (owner_id, content_hash);
+CREATE INDEX IF NOT EXISTS idx_events_sent_event_time ON events (sent, event_time);
@@ -11,20 +11,39 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countNotSentEvents = `-- name: CountNotSentEvents :one
+SELECT count(*)
+FROM events
+WHERE sent = false
+`
+
+func (q *Queries) CountNotSentEvents(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countNotSentEvents)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createEvent = `-- name: CreateEvent :one
-INSERT INTO events (name, topic, payload)
-VALUES ($1, $2, $3)
-RETURNING id, name, topic, payload, sent, event_time
+INSERT INTO events (name, topic, payload, headers)
+VALUES ($1, $2, $3, $4)
+RETURNING id, name, topic, payload, sent, event_time, headers
 `
 
 type CreateEventParams struct {
 	Name    string `db:"name" json:"name"`
 	Topic   string `db:"topic" json:"topic"`
 	Payload []byte `db:"payload" json:"payload"`
+	Headers []byte `db:"headers" json:"headers"`
 }
 
 func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Events, error) {
-	row := q.db.QueryRow(ctx, createEvent, arg.Name, arg.Topic, arg.Payload)
+	row := q.db.QueryRow(ctx, createEvent,
+		arg.Name,
+		arg.Topic,
+		arg.Payload,
+		arg.Headers,
+	)
 	var i Events
 	err := row.Scan(
 		&i.ID,
@@ -33,15 +52,16 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event
 		&i.Payload,
 		&i.Sent,
 		&i.EventTime,
+		&i.Headers,
 	)
 	return i, err
 }
 
 const getNotSentEvents = `-- name: GetNotSentEvents :many
-SELECT id, name, topic, payload, sent, event_time
+SELECT id, name, topic, payload, sent, event_time, headers
 FROM events
 WHERE sent=false
-ORDER BY event_time ASC
+ORDER BY event_time ASC, id ASC
 LIMIT $1 OFFSET $2
 `
 
@@ -66,6 +86,7 @@ func (q *Queries) GetNotSentEvents(ctx context.Context, arg GetNotSentEventsPara
 			&i.Payload,
 			&i.Sent,
 			&i.EventTime,
+			&i.Headers,
 		); err != nil {
 			return nil, err
 		}
@@ -77,6 +98,27 @@ func (q *Queries) GetNotSentEvents(ctx context.Context, arg GetNotSentEventsPara
 	return items, nil
 }
 
+const hasOlderNotSentEvents = `-- name: HasOlderNotSentEvents :one
+SELECT EXISTS (
+    SELECT 1
+    FROM events
+    WHERE sent = false
+      AND (event_time, id) < ($1::timestamp, $2::uuid)
+)
+`
+
+type HasOlderNotSentEventsParams struct {
+	EventTime pgtype.Timestamp `db:"event_time" json:"event_time"`
+	ID        pgtype.UUID      `db:"id" json:"id"`
+}
+
+func (q *Queries) HasOlderNotSentEvents(ctx context.Context, arg HasOlderNotSentEventsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasOlderNotSentEvents, arg.EventTime, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const markEventAsSent = `-- name: MarkEventAsSent :exec
 UPDATE events 
 SET sent = true 
@@ -18,6 +18,7 @@ const (
 	ResourceStatusProcessing ResourceStatus = "processing"
 	ResourceStatusCompleted  ResourceStatus = "completed"
 	ResourceStatusFailed     ResourceStatus = "failed"
+	ResourceStatusCancelled  ResourceStatus = "cancelled"
 )
 
 func (e *ResourceStatus) Scan(src interface{}) error {
@@ -58,9 +59,11 @@ func (ns NullResourceStatus) Value() (driver.Value, error) {
 type ResourceType string
 
 const (
-	ResourceTypePdf ResourceType = "pdf"
-	ResourceTypeTxt ResourceType = "txt"
-	ResourceTypeUrl ResourceType = "url"
+	ResourceTypePdf  ResourceType = "pdf"
+	ResourceTypeTxt  ResourceType = "txt"
+	ResourceTypeUrl  ResourceType = "url"
+	ResourceTypeCsv  ResourceType = "csv"
+	ResourceTypeEpub ResourceType = "epub"
 )
 
 func (e *ResourceType) Scan(src interface{}) error {
@@ -98,6 +101,49 @@ func (ns NullResourceType) Value() (driver.Value, error) {
 	return string(ns.ResourceType), nil
 }
 
+type ResourceVisibility string
+
+const (
+	ResourceVisibilityPrivate ResourceVisibility = "private"
+	ResourceVisibilityShared  ResourceVisibility = "shared"
+	ResourceVisibilityPublic  ResourceVisibility = "public"
+)
+
+func (e *ResourceVisibility) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ResourceVisibility(s)
+	case string:
+		*e = ResourceVisibility(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ResourceVisibility: %T", src)
+	}
+	return nil
+}
+
+type NullResourceVisibility struct {
+	ResourceVisibility ResourceVisibility `json:"resource_visibility"`
+	Valid              bool               `json:"valid"` // Valid is true if ResourceVisibility is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullResourceVisibility) Scan(value interface{}) error {
+	if value == nil {
+		ns.ResourceVisibility, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ResourceVisibility.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullResourceVisibility) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ResourceVisibility), nil
+}
+
 type Events struct {
 	ID        pgtype.UUID      `db:"id" json:"id"`
 	Name      string           `db:"name" json:"name"`
@@ -105,6 +151,7 @@ type Events struct {
 	Payload   []byte           `db:"payload" json:"payload"`
 	Sent      bool             `db:"sent" json:"sent"`
 	EventTime pgtype.Timestamp `db:"event_time" json:"event_time"`
+	Headers   []byte           `db:"headers" json:"headers"`
 }
 
 type Resources struct {
@@ -118,4 +165,13 @@ type Resources struct {
 	OwnerID          pgtype.UUID        `db:"owner_id" json:"owner_id"`
 	CreatedAt        pgtype.Timestamptz `db:"created_at" json:"created_at"`
 	UpdatedAt        pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	ChunkIds         []string           `db:"chunk_ids" json:"chunk_ids"`
+	ChunkHashes      []string           `db:"chunk_hashes" json:"chunk_hashes"`
+	ContentHash      pgtype.Text        `db:"content_hash" json:"content_hash"`
+	Visibility       ResourceVisibility `db:"visibility" json:"visibility"`
+	TenantID         string             `db:"tenant_id" json:"tenant_id"`
+	ChunkSize        pgtype.Int4        `db:"chunk_size" json:"chunk_size"`
+	ChunkOverlap     pgtype.Int4        `db:"chunk_overlap" json:"chunk_overlap"`
+	PageFirst        pgtype.Int4        `db:"page_first" json:"page_first"`
+	PageLast         pgtype.Int4        `db:"page_last" json:"page_last"`
 }
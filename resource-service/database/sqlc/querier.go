@@ -12,22 +12,33 @@ import (
 
 type Querier interface {
 	CheckResourceOwnership(ctx context.Context, arg CheckResourceOwnershipParams) (bool, error)
-	CountResourcesByOwner(ctx context.Context, ownerID pgtype.UUID) (int64, error)
+	CountNotSentEvents(ctx context.Context) (int64, error)
+	CountResources(ctx context.Context) (int64, error)
+	CountResourcesByOwnerID(ctx context.Context, ownerID pgtype.UUID) (int64, error)
+	CountResourcesByOwnerIDAndStatus(ctx context.Context, arg CountResourcesByOwnerIDAndStatusParams) (int64, error)
+	CountResourcesByOwnerIDPerStatus(ctx context.Context, ownerID pgtype.UUID) ([]CountResourcesByOwnerIDPerStatusRow, error)
 	CountResourcesByStatus(ctx context.Context, status ResourceStatus) (int64, error)
 	CreateEvent(ctx context.Context, arg CreateEventParams) (Events, error)
 	CreateResource(ctx context.Context, arg CreateResourceParams) (Resources, error)
 	DeleteUsersResource(ctx context.Context, arg DeleteUsersResourceParams) error
+	FailStaleResources(ctx context.Context, arg FailStaleResourcesParams) ([]Resources, error)
 	GetNotSentEvents(ctx context.Context, arg GetNotSentEventsParams) ([]Events, error)
 	GetResourceByID(ctx context.Context, id pgtype.UUID) (Resources, error)
+	GetResourceStatus(ctx context.Context, arg GetResourceStatusParams) (GetResourceStatusRow, error)
 	GetResources(ctx context.Context, arg GetResourcesParams) ([]Resources, error)
 	GetResourcesByOwnerID(ctx context.Context, arg GetResourcesByOwnerIDParams) ([]Resources, error)
+	GetResourcesByOwnerIDAndStatus(ctx context.Context, arg GetResourcesByOwnerIDAndStatusParams) ([]Resources, error)
 	GetResourcesByStatus(ctx context.Context, status ResourceStatus) ([]Resources, error)
 	GetResourcesByType(ctx context.Context, type_ ResourceType) ([]Resources, error)
 	GetResourcesCount(ctx context.Context, arg GetResourcesCountParams) (int64, error)
 	GetResourcesWithFilter(ctx context.Context, arg GetResourcesWithFilterParams) ([]Resources, error)
+	GetUsersResourceByContentHash(ctx context.Context, arg GetUsersResourceByContentHashParams) (Resources, error)
 	GetUsersResourceByID(ctx context.Context, arg GetUsersResourceByIDParams) (Resources, error)
+	HasOlderNotSentEvents(ctx context.Context, arg HasOlderNotSentEventsParams) (bool, error)
 	MarkEventAsSent(ctx context.Context, id pgtype.UUID) error
+	UpdateResourceChunks(ctx context.Context, arg UpdateResourceChunksParams) error
 	UpdateResourceStatus(ctx context.Context, arg UpdateResourceStatusParams) (Resources, error)
+	UpdateResourceVisibility(ctx context.Context, arg UpdateResourceVisibilityParams) (Resources, error)
 	UpdateUsersResource(ctx context.Context, arg UpdateUsersResourceParams) (Resources, error)
 }
 
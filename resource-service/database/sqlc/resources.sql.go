@@ -29,19 +29,81 @@ func (q *Queries) CheckResourceOwnership(ctx context.Context, arg CheckResourceO
 	return owned, err
 }
 
-const countResourcesByOwner = `-- name: CountResourcesByOwner :one
+const countResources = `-- name: CountResources :one
+SELECT COUNT(*) as count
+FROM resources
+`
+
+func (q *Queries) CountResources(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countResources)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countResourcesByOwnerID = `-- name: CountResourcesByOwnerID :one
 SELECT COUNT(*) as count
 FROM resources
 WHERE owner_id = $1
 `
 
-func (q *Queries) CountResourcesByOwner(ctx context.Context, ownerID pgtype.UUID) (int64, error) {
-	row := q.db.QueryRow(ctx, countResourcesByOwner, ownerID)
+func (q *Queries) CountResourcesByOwnerID(ctx context.Context, ownerID pgtype.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countResourcesByOwnerID, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countResourcesByOwnerIDAndStatus = `-- name: CountResourcesByOwnerIDAndStatus :one
+SELECT COUNT(*) as count
+FROM resources
+WHERE owner_id = $1 AND status = $2
+`
+
+type CountResourcesByOwnerIDAndStatusParams struct {
+	OwnerID pgtype.UUID    `db:"owner_id" json:"owner_id"`
+	Status  ResourceStatus `db:"status" json:"status"`
+}
+
+func (q *Queries) CountResourcesByOwnerIDAndStatus(ctx context.Context, arg CountResourcesByOwnerIDAndStatusParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countResourcesByOwnerIDAndStatus, arg.OwnerID, arg.Status)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const countResourcesByOwnerIDPerStatus = `-- name: CountResourcesByOwnerIDPerStatus :many
+SELECT status, COUNT(*) as count
+FROM resources
+WHERE owner_id = $1
+GROUP BY status
+`
+
+type CountResourcesByOwnerIDPerStatusRow struct {
+	Status ResourceStatus `db:"status" json:"status"`
+	Count  int64          `db:"count" json:"count"`
+}
+
+func (q *Queries) CountResourcesByOwnerIDPerStatus(ctx context.Context, ownerID pgtype.UUID) ([]CountResourcesByOwnerIDPerStatusRow, error) {
+	rows, err := q.db.Query(ctx, countResourcesByOwnerIDPerStatus, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountResourcesByOwnerIDPerStatusRow
+	for rows.Next() {
+		var i CountResourcesByOwnerIDPerStatusRow
+		if err := rows.Scan(&i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const countResourcesByStatus = `-- name: CountResourcesByStatus :one
 SELECT COUNT(*) as count
 FROM resources
@@ -57,10 +119,11 @@ func (q *Queries) CountResourcesByStatus(ctx context.Context, status ResourceSta
 
 const createResource = `-- name: CreateResource :one
 INSERT INTO resources (
-    name, type, url, extracted_content, raw_content, owner_id
+    name, type, url, extracted_content, raw_content, owner_id, content_hash, tenant_id,
+    chunk_size, chunk_overlap, page_first, page_last
 ) VALUES (
-    $1, $2, $3, $4, $5,  $6
-) RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+    $1, $2, $3, $4, $5,  $6, $7, $8, $9, $10, $11, $12
+) RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 `
 
 type CreateResourceParams struct {
@@ -70,6 +133,12 @@ type CreateResourceParams struct {
 	ExtractedContent pgtype.Text  `db:"extracted_content" json:"extracted_content"`
 	RawContent       []byte       `db:"raw_content" json:"raw_content"`
 	OwnerID          pgtype.UUID  `db:"owner_id" json:"owner_id"`
+	ContentHash      pgtype.Text  `db:"content_hash" json:"content_hash"`
+	TenantID         string       `db:"tenant_id" json:"tenant_id"`
+	ChunkSize        pgtype.Int4  `db:"chunk_size" json:"chunk_size"`
+	ChunkOverlap     pgtype.Int4  `db:"chunk_overlap" json:"chunk_overlap"`
+	PageFirst        pgtype.Int4  `db:"page_first" json:"page_first"`
+	PageLast         pgtype.Int4  `db:"page_last" json:"page_last"`
 }
 
 func (q *Queries) CreateResource(ctx context.Context, arg CreateResourceParams) (Resources, error) {
@@ -80,6 +149,12 @@ func (q *Queries) CreateResource(ctx context.Context, arg CreateResourceParams)
 		arg.ExtractedContent,
 		arg.RawContent,
 		arg.OwnerID,
+		arg.ContentHash,
+		arg.TenantID,
+		arg.ChunkSize,
+		arg.ChunkOverlap,
+		arg.PageFirst,
+		arg.PageLast,
 	)
 	var i Resources
 	err := row.Scan(
@@ -93,6 +168,15 @@ func (q *Queries) CreateResource(ctx context.Context, arg CreateResourceParams)
 		&i.OwnerID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
 	)
 	return i, err
 }
@@ -112,8 +196,66 @@ func (q *Queries) DeleteUsersResource(ctx context.Context, arg DeleteUsersResour
 	return err
 }
 
+const failStaleResources = `-- name: FailStaleResources :many
+UPDATE resources
+SET status = 'failed', updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM resources
+    WHERE status IN ('pending', 'processing') AND updated_at < $1
+    ORDER BY updated_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+`
+
+type FailStaleResourcesParams struct {
+	UpdatedAt pgtype.Timestamptz `db:"updated_at" json:"updated_at"`
+	Limit     int32              `db:"limit" json:"limit"`
+}
+
+func (q *Queries) FailStaleResources(ctx context.Context, arg FailStaleResourcesParams) ([]Resources, error) {
+	rows, err := q.db.Query(ctx, failStaleResources, arg.UpdatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Resources{}
+	for rows.Next() {
+		var i Resources
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Url,
+			&i.ExtractedContent,
+			&i.RawContent,
+			&i.Status,
+			&i.OwnerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getResourceByID = `-- name: GetResourceByID :one
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE id = $1
 `
@@ -132,12 +274,44 @@ func (q *Queries) GetResourceByID(ctx context.Context, id pgtype.UUID) (Resource
 		&i.OwnerID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
 	)
 	return i, err
 }
 
+const getResourceStatus = `-- name: GetResourceStatus :one
+SELECT id, status
+FROM resources
+WHERE id = $1 AND owner_id = $2
+`
+
+type GetResourceStatusParams struct {
+	ID      pgtype.UUID `db:"id" json:"id"`
+	OwnerID pgtype.UUID `db:"owner_id" json:"owner_id"`
+}
+
+type GetResourceStatusRow struct {
+	ID     pgtype.UUID    `db:"id" json:"id"`
+	Status ResourceStatus `db:"status" json:"status"`
+}
+
+func (q *Queries) GetResourceStatus(ctx context.Context, arg GetResourceStatusParams) (GetResourceStatusRow, error) {
+	row := q.db.QueryRow(ctx, getResourceStatus, arg.ID, arg.OwnerID)
+	var i GetResourceStatusRow
+	err := row.Scan(&i.ID, &i.Status)
+	return i, err
+}
+
 const getResources = `-- name: GetResources :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 ORDER BY created_at DESC
 LIMIT $1
@@ -169,6 +343,15 @@ func (q *Queries) GetResources(ctx context.Context, arg GetResourcesParams) ([]R
 			&i.OwnerID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
 		); err != nil {
 			return nil, err
 		}
@@ -181,22 +364,40 @@ func (q *Queries) GetResources(ctx context.Context, arg GetResourcesParams) ([]R
 }
 
 const getResourcesByOwnerID = `-- name: GetResourcesByOwnerID :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE owner_id = $1
-ORDER BY created_at DESC
-LIMIT $2
-OFFSET $3
+ORDER BY
+    CASE WHEN $2::text = 'created_at' AND $3::text = 'asc' THEN created_at END ASC,
+    CASE WHEN $2::text = 'created_at' AND $3::text = 'desc' THEN created_at END DESC,
+    CASE WHEN $2::text = 'updated_at' AND $3::text = 'asc' THEN updated_at END ASC,
+    CASE WHEN $2::text = 'updated_at' AND $3::text = 'desc' THEN updated_at END DESC,
+    CASE WHEN $2::text = 'name' AND $3::text = 'asc' THEN name END ASC,
+    CASE WHEN $2::text = 'name' AND $3::text = 'desc' THEN name END DESC,
+    id
+LIMIT $4
+OFFSET $5
 `
 
 type GetResourcesByOwnerIDParams struct {
-	OwnerID pgtype.UUID `db:"owner_id" json:"owner_id"`
-	Limit   int32       `db:"limit" json:"limit"`
-	Offset  int32       `db:"offset" json:"offset"`
+	OwnerID   pgtype.UUID `db:"owner_id" json:"owner_id"`
+	SortBy    string      `db:"sort_by" json:"sort_by"`
+	SortOrder string      `db:"sort_order" json:"sort_order"`
+	Limit     int32       `db:"limit" json:"limit"`
+	Offset    int32       `db:"offset" json:"offset"`
 }
 
+// Listings of an owner sort by a whitelisted column: only the CASE matching
+// sort_by and sort_order yields values, the others are NULL for every row.
+// The id breaks ties so pages never overlap.
 func (q *Queries) GetResourcesByOwnerID(ctx context.Context, arg GetResourcesByOwnerIDParams) ([]Resources, error) {
-	rows, err := q.db.Query(ctx, getResourcesByOwnerID, arg.OwnerID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getResourcesByOwnerID,
+		arg.OwnerID,
+		arg.SortBy,
+		arg.SortOrder,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -215,6 +416,87 @@ func (q *Queries) GetResourcesByOwnerID(ctx context.Context, arg GetResourcesByO
 			&i.OwnerID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getResourcesByOwnerIDAndStatus = `-- name: GetResourcesByOwnerIDAndStatus :many
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+FROM resources
+WHERE owner_id = $1 AND status = $2
+ORDER BY
+    CASE WHEN $3::text = 'created_at' AND $4::text = 'asc' THEN created_at END ASC,
+    CASE WHEN $3::text = 'created_at' AND $4::text = 'desc' THEN created_at END DESC,
+    CASE WHEN $3::text = 'updated_at' AND $4::text = 'asc' THEN updated_at END ASC,
+    CASE WHEN $3::text = 'updated_at' AND $4::text = 'desc' THEN updated_at END DESC,
+    CASE WHEN $3::text = 'name' AND $4::text = 'asc' THEN name END ASC,
+    CASE WHEN $3::text = 'name' AND $4::text = 'desc' THEN name END DESC,
+    id
+LIMIT $5
+OFFSET $6
+`
+
+type GetResourcesByOwnerIDAndStatusParams struct {
+	OwnerID   pgtype.UUID    `db:"owner_id" json:"owner_id"`
+	Status    ResourceStatus `db:"status" json:"status"`
+	SortBy    string         `db:"sort_by" json:"sort_by"`
+	SortOrder string         `db:"sort_order" json:"sort_order"`
+	Limit     int32          `db:"limit" json:"limit"`
+	Offset    int32          `db:"offset" json:"offset"`
+}
+
+func (q *Queries) GetResourcesByOwnerIDAndStatus(ctx context.Context, arg GetResourcesByOwnerIDAndStatusParams) ([]Resources, error) {
+	rows, err := q.db.Query(ctx, getResourcesByOwnerIDAndStatus,
+		arg.OwnerID,
+		arg.Status,
+		arg.SortBy,
+		arg.SortOrder,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Resources{}
+	for rows.Next() {
+		var i Resources
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Type,
+			&i.Url,
+			&i.ExtractedContent,
+			&i.RawContent,
+			&i.Status,
+			&i.OwnerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
 		); err != nil {
 			return nil, err
 		}
@@ -227,7 +509,7 @@ func (q *Queries) GetResourcesByOwnerID(ctx context.Context, arg GetResourcesByO
 }
 
 const getResourcesByStatus = `-- name: GetResourcesByStatus :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE status = $1
 ORDER BY created_at DESC
@@ -253,6 +535,15 @@ func (q *Queries) GetResourcesByStatus(ctx context.Context, status ResourceStatu
 			&i.OwnerID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
 		); err != nil {
 			return nil, err
 		}
@@ -265,7 +556,7 @@ func (q *Queries) GetResourcesByStatus(ctx context.Context, status ResourceStatu
 }
 
 const getResourcesByType = `-- name: GetResourcesByType :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE type = $1
 ORDER BY created_at DESC
@@ -291,6 +582,15 @@ func (q *Queries) GetResourcesByType(ctx context.Context, type_ ResourceType) ([
 			&i.OwnerID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
 		); err != nil {
 			return nil, err
 		}
@@ -332,7 +632,7 @@ func (q *Queries) GetResourcesCount(ctx context.Context, arg GetResourcesCountPa
 }
 
 const getResourcesWithFilter = `-- name: GetResourcesWithFilter :many
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE
     ($1::text IS NULL OR name ILIKE '%' || $1 || '%') AND
@@ -379,6 +679,15 @@ func (q *Queries) GetResourcesWithFilter(ctx context.Context, arg GetResourcesWi
 			&i.OwnerID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ChunkIds,
+			&i.ChunkHashes,
+			&i.ContentHash,
+			&i.Visibility,
+			&i.TenantID,
+			&i.ChunkSize,
+			&i.ChunkOverlap,
+			&i.PageFirst,
+			&i.PageLast,
 		); err != nil {
 			return nil, err
 		}
@@ -390,8 +699,49 @@ func (q *Queries) GetResourcesWithFilter(ctx context.Context, arg GetResourcesWi
 	return items, nil
 }
 
+const getUsersResourceByContentHash = `-- name: GetUsersResourceByContentHash :one
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+FROM resources
+WHERE owner_id = $1 AND content_hash = $2
+ORDER BY created_at
+LIMIT 1
+`
+
+type GetUsersResourceByContentHashParams struct {
+	OwnerID     pgtype.UUID `db:"owner_id" json:"owner_id"`
+	ContentHash pgtype.Text `db:"content_hash" json:"content_hash"`
+}
+
+// The oldest resource of the owner with the content, to detect duplicate uploads
+func (q *Queries) GetUsersResourceByContentHash(ctx context.Context, arg GetUsersResourceByContentHashParams) (Resources, error) {
+	row := q.db.QueryRow(ctx, getUsersResourceByContentHash, arg.OwnerID, arg.ContentHash)
+	var i Resources
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Url,
+		&i.ExtractedContent,
+		&i.RawContent,
+		&i.Status,
+		&i.OwnerID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
+	)
+	return i, err
+}
+
 const getUsersResourceByID = `-- name: GetUsersResourceByID :one
-SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+SELECT id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 FROM resources
 WHERE id = $1 AND owner_id = $2
 `
@@ -415,15 +765,41 @@ func (q *Queries) GetUsersResourceByID(ctx context.Context, arg GetUsersResource
 		&i.OwnerID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
 	)
 	return i, err
 }
 
+const updateResourceChunks = `-- name: UpdateResourceChunks :exec
+UPDATE resources
+SET chunk_ids = $2, chunk_hashes = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateResourceChunksParams struct {
+	ID          pgtype.UUID `db:"id" json:"id"`
+	ChunkIds    []string    `db:"chunk_ids" json:"chunk_ids"`
+	ChunkHashes []string    `db:"chunk_hashes" json:"chunk_hashes"`
+}
+
+func (q *Queries) UpdateResourceChunks(ctx context.Context, arg UpdateResourceChunksParams) error {
+	_, err := q.db.Exec(ctx, updateResourceChunks, arg.ID, arg.ChunkIds, arg.ChunkHashes)
+	return err
+}
+
 const updateResourceStatus = `-- name: UpdateResourceStatus :one
 UPDATE resources
 SET status = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 `
 
 type UpdateResourceStatusParams struct {
@@ -445,6 +821,55 @@ func (q *Queries) UpdateResourceStatus(ctx context.Context, arg UpdateResourceSt
 		&i.OwnerID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
+	)
+	return i, err
+}
+
+const updateResourceVisibility = `-- name: UpdateResourceVisibility :one
+UPDATE resources
+SET visibility = $3, updated_at = NOW()
+WHERE id = $1 AND owner_id = $2
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
+`
+
+type UpdateResourceVisibilityParams struct {
+	ID         pgtype.UUID        `db:"id" json:"id"`
+	OwnerID    pgtype.UUID        `db:"owner_id" json:"owner_id"`
+	Visibility ResourceVisibility `db:"visibility" json:"visibility"`
+}
+
+func (q *Queries) UpdateResourceVisibility(ctx context.Context, arg UpdateResourceVisibilityParams) (Resources, error) {
+	row := q.db.QueryRow(ctx, updateResourceVisibility, arg.ID, arg.OwnerID, arg.Visibility)
+	var i Resources
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Type,
+		&i.Url,
+		&i.ExtractedContent,
+		&i.RawContent,
+		&i.Status,
+		&i.OwnerID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
 	)
 	return i, err
 }
@@ -459,9 +884,10 @@ SET
     raw_content = COALESCE($7, raw_content),
     status = COALESCE($8, status),
     owner_id = COALESCE($9, owner_id),
+    content_hash = COALESCE($10, content_hash),
     updated_at = NOW()
 WHERE id = $1 AND owner_id = $2
-RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at
+RETURNING id, name, type, url, extracted_content, raw_content, status, owner_id, created_at, updated_at, chunk_ids, chunk_hashes, content_hash, visibility, tenant_id, chunk_size, chunk_overlap, page_first, page_last
 `
 
 type UpdateUsersResourceParams struct {
@@ -474,6 +900,7 @@ type UpdateUsersResourceParams struct {
 	RawContent       []byte         `db:"raw_content" json:"raw_content"`
 	Status           ResourceStatus `db:"status" json:"status"`
 	OwnerID_2        pgtype.UUID    `db:"owner_id_2" json:"owner_id_2"`
+	ContentHash      pgtype.Text    `db:"content_hash" json:"content_hash"`
 }
 
 func (q *Queries) UpdateUsersResource(ctx context.Context, arg UpdateUsersResourceParams) (Resources, error) {
@@ -487,6 +914,7 @@ func (q *Queries) UpdateUsersResource(ctx context.Context, arg UpdateUsersResour
 		arg.RawContent,
 		arg.Status,
 		arg.OwnerID_2,
+		arg.ContentHash,
 	)
 	var i Resources
 	err := row.Scan(
@@ -500,6 +928,15 @@ func (q *Queries) UpdateUsersResource(ctx context.Context, arg UpdateUsersResour
 		&i.OwnerID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ChunkIds,
+		&i.ChunkHashes,
+		&i.ContentHash,
+		&i.Visibility,
+		&i.TenantID,
+		&i.ChunkSize,
+		&i.ChunkOverlap,
+		&i.PageFirst,
+		&i.PageLast,
 	)
 	return i, err
 }
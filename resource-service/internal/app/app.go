@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
@@ -13,6 +14,11 @@ import (
 	"github.com/nzb3/diploma/resource-service/internal/configurator"
 )
 
+// processor is a background component stopped on shutdown
+type processor interface {
+	Stop()
+}
+
 // App is a structure that configure and run application
 type App struct {
 	serviceProvider *ServiceProvider
@@ -28,7 +34,8 @@ func NewApp(ctx context.Context) (*App, error) {
 	return a, nil
 }
 
-// Start starts the App
+// Start runs the App until ctx is done or a component fails, then shuts it
+// down gracefully, see shutdown
 func (a *App) Start(ctx context.Context) error {
 	const op = "app.Start"
 	defer func() {
@@ -36,40 +43,93 @@ func (a *App) Start(ctx context.Context) error {
 		closer.Wait()
 	}()
 
-	eg, ctx := errgroup.WithContext(ctx)
+	eg, egCtx := errgroup.WithContext(ctx)
+
+	// Requests and processors run on a context of their own, cancelled only
+	// once the shutdown drained them, so that a signal does not drop them
+	runCtx, cancelRun := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRun()
+
+	outboxProcessor := a.serviceProvider.OutboxProcessor(runCtx)
+	indexationProcessor := a.serviceProvider.IndexationProcessor(runCtx)
+	statusReconciler := a.serviceProvider.StatusReconciler(runCtx)
+	reindexer := a.serviceProvider.Reindexer(runCtx)
 
 	// Start the HTTP server
 	eg.Go(func() error {
 		slog.Info("Starting server")
 		a.server.BaseContext = func(_ net.Listener) context.Context {
-			return ctx
+			return runCtx
 		}
-		return a.server.ListenAndServe()
+		if err := a.server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
 	})
 
 	// Start the outbox processor for reliable event delivery
 	eg.Go(func() error {
 		slog.Info("Starting outbox processor")
-		processor := a.serviceProvider.OutboxProcessor(ctx)
-		processor.Start(ctx)
+		outboxProcessor.Start(runCtx)
 		return nil
 	})
 
 	// Start the indexation processor for handling indexation completion events
 	eg.Go(func() error {
 		slog.Info("Starting indexation processor")
-		processor := a.serviceProvider.IndexationProcessor(ctx)
-		return processor.Start(ctx)
+		return indexationProcessor.Start(runCtx)
 	})
 
-	return fmt.Errorf("%s: %w", op, eg.Wait())
+	// Fail the resources whose indexation was lost, e.g. by a restart
+	eg.Go(func() error {
+		slog.Info("Starting status reconciler")
+		statusReconciler.Start(runCtx)
+		return nil
+	})
+
+	eg.Go(func() error {
+		<-egCtx.Done()
+		return a.shutdown(runCtx, cancelRun, reindexer, indexationProcessor, outboxProcessor, statusReconciler)
+	})
+
+	if err := eg.Wait(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// shutdown stops accepting requests and waits for the active ones until the
+// shutdown timeout, then stops the processors. The Kafka clients and the
+// database pool are closed last, by the closer.
+func (a *App) shutdown(ctx context.Context, cancelRun context.CancelFunc, processors ...processor) error {
+	const op = "app.shutdown"
+	defer cancelRun()
+
+	config := a.serviceProvider.ServerConfig(ctx)
+	slog.Info("Shutting down", "timeout", config.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), config.ShutdownTimeout)
+	defer cancel()
+	serverErr := a.server.Shutdown(shutdownCtx)
+
+	for _, p := range processors {
+		p.Stop()
+	}
+	slog.Info("Processors stopped")
+
+	if serverErr != nil {
+		return fmt.Errorf("%s: %w", op, serverErr)
+	}
+	return nil
 }
 
 func (a *App) initDeps(ctx context.Context) error {
 	inits := []func(context.Context) error{
 		a.initConfig,
 		a.initServiceProvider,
+		a.validateConfig,
 		a.initLogger,
+		a.initTracing,
 		a.initServer,
 	}
 
@@ -96,11 +156,24 @@ func (a *App) initServiceProvider(_ context.Context) error {
 	return nil
 }
 
+func (a *App) validateConfig(ctx context.Context) error {
+	const op = "app.validateConfig"
+	if err := a.serviceProvider.ValidateConfig(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
 func (a *App) initLogger(ctx context.Context) error {
 	a.serviceProvider.Logger(ctx)
 	return nil
 }
 
+func (a *App) initTracing(ctx context.Context) error {
+	a.serviceProvider.TracerProvider(ctx)
+	return nil
+}
+
 func (a *App) initServer(ctx context.Context) error {
 	a.server = a.serviceProvider.Server(ctx)
 	return nil
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,33 +12,51 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nzb3/closer"
 	"github.com/nzb3/slogmanager"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 
 	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/controllers/admincontroller"
 	"github.com/nzb3/diploma/resource-service/internal/controllers/middleware"
 	"github.com/nzb3/diploma/resource-service/internal/controllers/resourcecontroller"
 	"github.com/nzb3/diploma/resource-service/internal/domain/services/contentextractor"
 	"github.com/nzb3/diploma/resource-service/internal/domain/services/eventservice"
 	"github.com/nzb3/diploma/resource-service/internal/domain/services/indexationprocessor"
 	"github.com/nzb3/diploma/resource-service/internal/domain/services/outboxprocessor"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/reindexer"
 	"github.com/nzb3/diploma/resource-service/internal/domain/services/resourceservcie"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/statusreconciler"
+	"github.com/nzb3/diploma/resource-service/internal/health"
+	"github.com/nzb3/diploma/resource-service/internal/metrics"
 	"github.com/nzb3/diploma/resource-service/internal/repository/messaging"
 	"github.com/nzb3/diploma/resource-service/internal/repository/messaging/kafka"
 	"github.com/nzb3/diploma/resource-service/internal/repository/pgx"
 	"github.com/nzb3/diploma/resource-service/internal/repository/pgx/events"
 	"github.com/nzb3/diploma/resource-service/internal/repository/pgx/resources"
 	"github.com/nzb3/diploma/resource-service/internal/server"
+	"github.com/nzb3/diploma/resource-service/internal/tracing"
 )
 
+// readinessTimeout bounds all readiness checks so load balancers get an answer quickly
+const readinessTimeout = 2 * time.Second
+
 // ServiceProvider implementation of DI-container haves method to initialize components of application
 type ServiceProvider struct {
 	slogManager         *slogmanager.Manager
+	logConfig           *LogConfig
+	ollamaConfig        *OllamaConfig
 	embeddingLLM        *ollama.LLM
 	generationLLM       *ollama.LLM
 	server              *http.Server
 	resourceController  *resourcecontroller.Controller
+	adminController     *admincontroller.Controller
+	uploadConfig        *resourcecontroller.Config
+	ocrConfig           *contentextractor.OCRConfig
+	fetchConfig         *contentextractor.FetchConfig
 	ginEngine           *gin.Engine
 	resourceService     *resourceservcie.Service
 	serverConfig        *server.Config
@@ -58,6 +77,14 @@ type ServiceProvider struct {
 	eventService        *eventservice.Service
 	outboxProcessor     *outboxprocessor.Processor
 	indexationProcessor *indexationprocessor.Processor
+	reconcilerConfig    *statusreconciler.Config
+	statusReconciler    *statusreconciler.Reconciler
+	reindexConfig       *reindexer.Config
+	reindexer           *reindexer.Reindexer
+	// Tracing components
+	tracingConfig  *tracing.Config
+	tracerProvider *sdktrace.TracerProvider
+	health         *health.Health
 }
 
 // NewServiceProvider creates and returns a new instance of ServiceProvider
@@ -65,18 +92,90 @@ func NewServiceProvider() *ServiceProvider {
 	return &ServiceProvider{}
 }
 
+// ValidateConfig loads every configuration section up front, so that all
+// missing or invalid settings are reported together at startup instead of
+// panicking on the first one when its component is built
+func (sp *ServiceProvider) ValidateConfig(_ context.Context) error {
+	return errors.Join(
+		loadConfig(&sp.logConfig, NewLogConfig),
+		loadConfig(&sp.ollamaConfig, NewOllamaConfig),
+		loadConfig(&sp.serverConfig, server.NewConfig),
+		loadConfig(&sp.authConfig, middleware.NewAuthMiddlewareConfig),
+		loadConfig(&sp.uploadConfig, resourcecontroller.NewConfig),
+		loadConfig(&sp.ocrConfig, contentextractor.NewOCRConfig),
+		loadConfig(&sp.fetchConfig, contentextractor.NewFetchConfig),
+		loadConfig(&sp.repositoryConfig, pgx.NewConfig),
+		loadConfig(&sp.kafkaConfig, kafka.NewConfig),
+		loadConfig(&sp.kafkaConsumerConfig, kafka.NewConsumerConfig),
+		loadConfig(&sp.reconcilerConfig, statusreconciler.NewConfig),
+		loadConfig(&sp.reindexConfig, reindexer.NewConfig),
+		loadConfig(&sp.tracingConfig, tracing.NewConfig),
+	)
+}
+
+// loadConfig stores the config returned by load in field, leaving it unset on error
+func loadConfig[T any](field **T, load func() (*T, error)) error {
+	config, err := load()
+	if err != nil {
+		return err
+	}
+
+	*field = config
+	return nil
+}
+
 // Logger returns the application's slog manager, creating it if it doesn't exist
 func (sp *ServiceProvider) Logger(ctx context.Context) *slogmanager.Manager {
 	if sp.slogManager != nil {
 		return sp.slogManager
 	}
+	config := sp.LogConfig(ctx)
+	format := slogmanager.WithTextFormat()
+	if config.Format == "json" {
+		format = slogmanager.WithJSONFormat()
+	}
+
 	manager := slogmanager.New()
-	manager.AddWriter("stdout", slogmanager.NewWriter(os.Stdout, slogmanager.WithTextFormat()))
+	manager.AddWriter("stdout", slogmanager.NewWriter(os.Stdout, format))
+	handler := middleware.NewRedactHandler(manager.Logger().Handler(), config.MaxValueBytes)
+	slog.SetDefault(slog.New(middleware.NewRequestIDHandler(handler)))
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 	sp.slogManager = manager
 	return sp.slogManager
 }
 
+// LogConfig returns the log configuration, creating it if it doesn't exist.
+// It is loaded before the logger exists, so errors are only returned by panic.
+func (sp *ServiceProvider) LogConfig(_ context.Context) *LogConfig {
+	if sp.logConfig != nil {
+		return sp.logConfig
+	}
+
+	config, err := NewLogConfig()
+	if err != nil {
+		panic(fmt.Errorf("error creating log config: %w", err))
+	}
+
+	sp.logConfig = config
+	return config
+}
+
+// OllamaConfig returns the Ollama configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) OllamaConfig(ctx context.Context) *OllamaConfig {
+	if sp.ollamaConfig != nil {
+		return sp.ollamaConfig
+	}
+
+	config, err := NewOllamaConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating ollama config", "error", err.Error())
+		panic(fmt.Errorf("error creating ollama config: %w", err))
+	}
+
+	sp.ollamaConfig = config
+	return config
+}
+
 // EmbeddingLLM returns the LLM instance for embeddings, creating it if it doesn't exist
 func (sp *ServiceProvider) EmbeddingLLM(ctx context.Context) *ollama.LLM {
 	if sp.embeddingLLM != nil {
@@ -84,8 +183,8 @@ func (sp *ServiceProvider) EmbeddingLLM(ctx context.Context) *ollama.LLM {
 	}
 
 	llm, err := ollama.New(
-		ollama.WithServerURL("http://ollama-embedder:11434/"),
-		ollama.WithModel("bge-m3"),
+		ollama.WithServerURL(sp.OllamaConfig(ctx).EmbedderURL),
+		ollama.WithModel(sp.OllamaConfig(ctx).EmbeddingModel),
 	)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating ollama embedding LLM", "error", err.Error())
@@ -103,8 +202,8 @@ func (sp *ServiceProvider) GeneratingLLM(ctx context.Context) *ollama.LLM {
 		return sp.generationLLM
 	}
 
-	llm, err := ollama.New(ollama.WithServerURL("http://ollama-generator:11434/"),
-		ollama.WithModel("gemma3:4b-it-qat"),
+	llm, err := ollama.New(ollama.WithServerURL(sp.OllamaConfig(ctx).GeneratorURL),
+		ollama.WithModel(sp.OllamaConfig(ctx).GenerationModel),
 	)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating ollama generating LLM", "error", err.Error())
@@ -115,6 +214,45 @@ func (sp *ServiceProvider) GeneratingLLM(ctx context.Context) *ollama.LLM {
 	return llm
 }
 
+// TracingConfig returns the tracing configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) TracingConfig(ctx context.Context) *tracing.Config {
+	if sp.tracingConfig != nil {
+		return sp.tracingConfig
+	}
+
+	config, err := tracing.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating tracing config", "error", err.Error())
+		panic(fmt.Errorf("error creating tracing config: %w", err))
+	}
+
+	sp.tracingConfig = config
+	return config
+}
+
+// TracerProvider returns the OTLP tracer provider, creating it if it doesn't exist.
+// It returns nil when tracing is disabled; the trace context is still propagated.
+func (sp *ServiceProvider) TracerProvider(ctx context.Context) *sdktrace.TracerProvider {
+	if sp.tracerProvider != nil {
+		return sp.tracerProvider
+	}
+
+	provider, err := tracing.NewTracerProvider(ctx, sp.TracingConfig(ctx))
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating tracer provider", "error", err.Error())
+		panic(fmt.Errorf("error creating tracer provider: %w", err))
+	}
+
+	if provider != nil {
+		closer.Add(func() error {
+			return provider.Shutdown(context.Background())
+		})
+	}
+
+	sp.tracerProvider = provider
+	return provider
+}
+
 // AuthConfig returns the auth configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) AuthConfig(ctx context.Context) *middleware.AuthMiddlewareConfig {
 	if sp.authConfig != nil {
@@ -138,32 +276,52 @@ func (sp *ServiceProvider) GinEngine(ctx context.Context) *gin.Engine {
 		return sp.ginEngine
 	}
 	_ = ctx
-	engine := gin.Default()
+	// gin.Default's access log would print tokens passed in the query
+	engine := gin.New()
 
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	corsConfig.AllowAllOrigins = true
 
+	engine.Use(middleware.RequestID())
 	engine.Use(cors.New(corsConfig))
 
-	engine.Use(gin.Logger())
+	engine.Use(gin.LoggerWithFormatter(middleware.AccessLogFormatter))
 	engine.Use(gin.Recovery())
+	engine.Use(otelgin.Middleware(tracing.ServiceName))
+
+	engine.GET("/metrics", metrics.Handler())
+	sp.Health(ctx).RegisterRoutes(engine)
 
 	engine = sp.setupRoutes(
 		ctx,
 		engine,
 		sp.ResourceController(ctx),
+		sp.AdminController(ctx),
 	)
 
 	sp.ginEngine = engine
 	return engine
 }
 
+// Health returns the dependency health checks of the service, creating them if they don't exist
+func (sp *ServiceProvider) Health(ctx context.Context) *health.Health {
+	if sp.health != nil {
+		return sp.health
+	}
+
+	sp.health = health.New(readinessTimeout).
+		AddCheck("postgres", sp.PgxPool(ctx).Ping).
+		AddCheck("kafka_producer", sp.KafkaProducer(ctx).Health).
+		AddCheck("kafka_consumer", sp.KafkaConsumer(ctx).Health)
+	return sp.health
+}
+
 func (sp *ServiceProvider) setupRoutes(ctx context.Context, router *gin.Engine, controllers ...controllers.Controller) *gin.Engine {
 	api := router.Group("/api")
 	v1 := api.Group("/v1")
@@ -239,6 +397,11 @@ func (sp *ServiceProvider) PgxPool(ctx context.Context) *pgxpool.Pool {
 		panic(fmt.Errorf("error pinging database: %w", err))
 	}
 
+	closer.Add(func() error {
+		pool.Close()
+		return nil
+	})
+
 	sp.pgxPool = pool
 	return pool
 }
@@ -290,13 +453,52 @@ func (sp *ServiceProvider) ResourceProcessor(ctx context.Context) *contentextrac
 		return sp.contentExtractor
 	}
 
-	resourceProcessor := contentextractor.NewResourceProcessor()
+	opts := []contentextractor.Option{contentextractor.WithFetchConfig(*sp.FetchConfig(ctx))}
+	if ocrConfig := sp.OCRConfig(ctx); ocrConfig.Enabled {
+		ocr := contentextractor.NewTesseractOCR(ocrConfig.Command, ocrConfig.Language)
+		opts = append(opts, contentextractor.WithOCR(ocr, *ocrConfig))
+	}
+	resourceProcessor := contentextractor.NewResourceProcessor(opts...)
 
 	sp.contentExtractor = resourceProcessor
 
 	return resourceProcessor
 }
 
+// OCRConfig returns the OCR settings of scanned PDFs, creating them if they don't exist
+func (sp *ServiceProvider) OCRConfig(ctx context.Context) *contentextractor.OCRConfig {
+	if sp.ocrConfig != nil {
+		return sp.ocrConfig
+	}
+
+	config, err := contentextractor.NewOCRConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating ocr config", "error", err.Error())
+		panic(fmt.Errorf("error creating ocr config: %w", err))
+	}
+
+	sp.ocrConfig = config
+
+	return sp.ocrConfig
+}
+
+// FetchConfig returns the download settings of url resources, creating them if they don't exist
+func (sp *ServiceProvider) FetchConfig(ctx context.Context) *contentextractor.FetchConfig {
+	if sp.fetchConfig != nil {
+		return sp.fetchConfig
+	}
+
+	config, err := contentextractor.NewFetchConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating fetch config", "error", err.Error())
+		panic(fmt.Errorf("error creating fetch config: %w", err))
+	}
+
+	sp.fetchConfig = config
+
+	return sp.fetchConfig
+}
+
 // ResourceService returns the resource service instance, creating it if it doesn't exist
 func (sp *ServiceProvider) ResourceService(ctx context.Context) *resourceservcie.Service {
 	if sp.resourceService != nil {
@@ -320,13 +522,43 @@ func (sp *ServiceProvider) ResourceController(ctx context.Context) *resourcecont
 		return sp.resourceController
 	}
 
-	controller := resourcecontroller.NewController(sp.ResourceService(ctx))
+	controller := resourcecontroller.NewController(sp.ResourceService(ctx), sp.UploadConfig(ctx))
 
 	sp.resourceController = controller
 
 	return controller
 }
 
+// AdminController returns the admin controller instance, creating it if it doesn't exist
+func (sp *ServiceProvider) AdminController(ctx context.Context) *admincontroller.Controller {
+	if sp.adminController != nil {
+		return sp.adminController
+	}
+
+	controller := admincontroller.NewController(sp.Reindexer(ctx), sp.OutboxProcessor(ctx))
+
+	sp.adminController = controller
+
+	return controller
+}
+
+// UploadConfig returns the upload size limits, creating them if they don't exist
+func (sp *ServiceProvider) UploadConfig(ctx context.Context) *resourcecontroller.Config {
+	if sp.uploadConfig != nil {
+		return sp.uploadConfig
+	}
+
+	config, err := resourcecontroller.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating upload config", "error", err.Error())
+		panic(fmt.Errorf("error creating upload config: %w", err))
+	}
+
+	sp.uploadConfig = config
+
+	return sp.uploadConfig
+}
+
 // KafkaConfig returns the Kafka configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) KafkaConfig(ctx context.Context) *kafka.Config {
 	if sp.kafkaConfig != nil {
@@ -373,6 +605,8 @@ func (sp *ServiceProvider) KafkaProducer(ctx context.Context) messaging.MessageP
 		panic(fmt.Errorf("error creating kafka producer: %w", err))
 	}
 
+	closer.Add(producer.Close)
+
 	sp.kafkaProducer = producer
 	return producer
 }
@@ -439,6 +673,68 @@ func (sp *ServiceProvider) IndexationProcessor(ctx context.Context) *indexationp
 	return processor
 }
 
+// ReconcilerConfig returns the status reconciler configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) ReconcilerConfig(ctx context.Context) *statusreconciler.Config {
+	if sp.reconcilerConfig != nil {
+		return sp.reconcilerConfig
+	}
+
+	config, err := statusreconciler.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating reconciler config", "error", err.Error())
+		panic(fmt.Errorf("error creating reconciler config: %w", err))
+	}
+
+	sp.reconcilerConfig = config
+	return config
+}
+
+// StatusReconciler returns the status reconciler instance, creating it if it doesn't exist
+func (sp *ServiceProvider) StatusReconciler(ctx context.Context) *statusreconciler.Reconciler {
+	if sp.statusReconciler != nil {
+		return sp.statusReconciler
+	}
+
+	reconciler := statusreconciler.NewReconciler(
+		sp.ResourceService(ctx),
+		*sp.ReconcilerConfig(ctx),
+	)
+
+	sp.statusReconciler = reconciler
+	return reconciler
+}
+
+// ReindexConfig returns the reindexer configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) ReindexConfig(ctx context.Context) *reindexer.Config {
+	if sp.reindexConfig != nil {
+		return sp.reindexConfig
+	}
+
+	config, err := reindexer.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating reindex config", "error", err.Error())
+		panic(fmt.Errorf("error creating reindex config: %w", err))
+	}
+
+	sp.reindexConfig = config
+	return config
+}
+
+// Reindexer returns the reindexer instance, creating it if it doesn't exist
+func (sp *ServiceProvider) Reindexer(ctx context.Context) *reindexer.Reindexer {
+	if sp.reindexer != nil {
+		return sp.reindexer
+	}
+
+	r := reindexer.NewReindexer(
+		sp.ResourceService(ctx),
+		*sp.ReindexConfig(ctx),
+	)
+
+	sp.reindexer = r
+	return r
+}
+
 // ServerConfig returns the server configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) ServerConfig(ctx context.Context) *server.Config {
 	if sp.serverConfig != nil {
@@ -462,7 +758,6 @@ func (sp *ServiceProvider) Server(ctx context.Context) *http.Server {
 	}
 
 	s := server.NewServer(
-		ctx,
 		sp.GinEngine(ctx),
 		sp.ServerConfig(ctx),
 	)
@@ -54,14 +54,7 @@ func ParseConfig[T any](configPath string) (*T, error) {
 
 	// Parse from the specified config section
 	if configPath != "" {
-		var sub *viper.Viper
-
-		mode := os.Getenv("GIN_MODE")
-		if mode == "release" {
-			sub = viper.Sub("production")
-		} else {
-			sub = viper.Sub("debug")
-		}
+		sub := viper.Sub(modeSection())
 
 		// If we couldn't get the mode-specific config, try the root level
 		if sub == nil {
@@ -86,13 +79,22 @@ func ParseConfig[T any](configPath string) (*T, error) {
 
 	// Validate configuration
 	if err := validator.Validate(config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, fmt.Errorf("config validation failed: %w", qualify(configPath, err))
 	}
 
 	slog.Debug("Configuration parsed and validated successfully", "section", configPath)
 	return config, nil
 }
 
+// modeSection returns the section of the config file holding the settings of
+// the GIN_MODE the service runs in
+func modeSection() string {
+	if os.Getenv("GIN_MODE") == "release" {
+		return "production"
+	}
+	return "debug"
+}
+
 // GetString gets a string value from config with environment variable fallback
 func GetString(key string) string {
 	return viper.GetString(key)
@@ -113,6 +115,16 @@ func GetStringSlice(key string) []string {
 	return viper.GetStringSlice(key)
 }
 
+// envNames maps the config keys bound to environment variables to their
+// names, so that errors can name the variable to set
+var envNames = map[string]string{}
+
+// bindEnv binds the config key to the environment variable
+func bindEnv(key, env string) {
+	_ = viper.BindEnv(key, env)
+	envNames[key] = env
+}
+
 // SetupEnvironmentMapping configures viper to map environment variables to config keys
 func SetupEnvironmentMapping() {
 	// No prefix for environment variables
@@ -123,11 +135,11 @@ func SetupEnvironmentMapping() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	// Server configuration
-	viper.BindEnv("server.host", "SERVER_HOST")
-	viper.BindEnv("server.port", "SERVER_PORT")
+	bindEnv("server.host", "SERVER_HOST")
+	bindEnv("server.port", "SERVER_PORT")
 
 	// Database configuration (using resource-specific environment variables)
-	viper.BindEnv("database.host", "RESOURCE_DB_HOST")
+	bindEnv("database.host", "RESOURCE_DB_HOST")
 	// Use container port for internal Docker network connections
 	containerPort := viper.GetString("RESOURCE_DB_CONTAINER_PORT")
 	if containerPort == "" {
@@ -136,25 +148,68 @@ func SetupEnvironmentMapping() {
 	viper.Set("database.port", containerPort)
 	// Note: We're not binding RESOURCE_DB_PORT directly to database.port
 	// because within Docker network, we need to use the container port (5432)
-	viper.BindEnv("database.user", "RESOURCE_DB_USER")
-	viper.BindEnv("database.password", "RESOURCE_DB_PASSWORD")
-	viper.BindEnv("database.dbname", "RESOURCE_DB_NAME")
-	viper.BindEnv("database.sslmode", "RESOURCE_DB_SSL_MODE")
+	bindEnv("database.user", "RESOURCE_DB_USER")
+	bindEnv("database.password", "RESOURCE_DB_PASSWORD")
+	bindEnv("database.dbname", "RESOURCE_DB_NAME")
+	bindEnv("database.sslmode", "RESOURCE_DB_SSL_MODE")
 
 	// Auth configuration
-	viper.BindEnv("auth.host", "AUTH_HOST")
-	viper.BindEnv("auth.port", "AUTH_PORT")
-	viper.BindEnv("auth.realm", "AUTH_REALM")
-	viper.BindEnv("auth.client_id", "AUTH_RESOURCE_SERVICE_CLIENT_ID")
-	viper.BindEnv("auth.client_secret", "AUTH_RESOURCE_SERVICE_CLIENT_SECRET")
+	bindEnv("auth.host", "AUTH_HOST")
+	bindEnv("auth.port", "AUTH_PORT")
+	bindEnv("auth.realm", "AUTH_REALM")
+	bindEnv("auth.client_id", "AUTH_RESOURCE_SERVICE_CLIENT_ID")
+	bindEnv("auth.client_secret", "AUTH_RESOURCE_SERVICE_CLIENT_SECRET")
+	bindEnv("auth.issuer", "AUTH_ISSUER")
+	bindEnv("auth.audience", "AUTH_RESOURCE_SERVICE_AUDIENCE")
+	bindEnv("auth.jwks_refresh_interval", "AUTH_JWKS_REFRESH_INTERVAL")
+	bindEnv("auth.tenant_claim", "AUTH_TENANT_CLAIM")
 
 	// Kafka configuration
-	viper.BindEnv("kafka.brokers", "KAFKA_BROKERS")
-	viper.BindEnv("kafka.consumer_group_id", "KAFKA_RESOURCE_SERVICE_CONSUMER_GROUP_ID")
-	viper.BindEnv("kafka.topics.resource", "KAFKA_TOPIC_RESOURCE")
+	bindEnv("kafka.brokers", "KAFKA_BROKERS")
+	bindEnv("kafka.consumer_group_id", "KAFKA_RESOURCE_SERVICE_CONSUMER_GROUP_ID")
+	bindEnv("kafka.topics.resource", "KAFKA_TOPIC_RESOURCE")
+
+	// Upload size limits
+	bindEnv("upload.max_text_bytes", "MAX_TEXT_BYTES")
+	bindEnv("upload.max_pdf_bytes", "MAX_PDF_BYTES")
+	bindEnv("upload.max_url_bytes", "MAX_URL_BYTES")
+	bindEnv("upload.max_import_bytes", "MAX_IMPORT_BYTES")
+
+	// OCR of scanned PDFs
+	bindEnv("ocr.enabled", "OCR_ENABLED")
+	bindEnv("ocr.min_page_chars", "OCR_MIN_PAGE_CHARS")
+	bindEnv("ocr.dpi", "OCR_DPI")
+	bindEnv("ocr.command", "OCR_COMMAND")
+	bindEnv("ocr.language", "OCR_LANGUAGE")
+	bindEnv("ocr.max_pages", "OCR_MAX_PAGES")
+	bindEnv("ocr.page_timeout", "OCR_PAGE_TIMEOUT")
+
+	// Download of url resources
+	bindEnv("fetch.timeout", "FETCH_TIMEOUT")
+	bindEnv("fetch.max_redirects", "FETCH_MAX_REDIRECTS")
+	bindEnv("fetch.max_body_bytes", "FETCH_MAX_BODY_BYTES")
+	bindEnv("fetch.user_agent", "FETCH_USER_AGENT")
+	bindEnv("fetch.allow_private_hosts", "FETCH_ALLOW_PRIVATE_HOSTS")
+
+	// Status reconciler
+	bindEnv("reconciler.interval", "RECONCILER_INTERVAL")
+	bindEnv("reconciler.stale_after", "RECONCILER_STALE_AFTER")
+	bindEnv("reconciler.batch_size", "RECONCILER_BATCH_SIZE")
+
+	// Admin reindex
+	bindEnv("reindex.page_size", "REINDEX_PAGE_SIZE")
+	bindEnv("reindex.rate", "REINDEX_RATE")
 
 	// Logger configuration
-	viper.BindEnv("logger.level", "LOG_LEVEL")
+	bindEnv("logger.level", "LOG_LEVEL")
+	bindEnv("logger.format", "LOG_FORMAT")
+	bindEnv("logger.max_value_bytes", "LOG_MAX_VALUE_BYTES")
+
+	// Ollama servers and models
+	bindEnv("ollama.embedder_url", "OLLAMA_EMBEDDER_URL")
+	bindEnv("ollama.generator_url", "OLLAMA_GENERATOR_URL")
+	bindEnv("ollama.embedding_model", "OLLAMA_EMBEDDING_MODEL")
+	bindEnv("ollama.generation_model", "OLLAMA_GENERATION_MODEL")
 
 	// Handle Kafka brokers specially (comma-separated list)
 	if brokersEnv := viper.GetString("KAFKA_BROKERS"); brokersEnv != "" {
@@ -0,0 +1,179 @@
+package admincontroller
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/controllers/middleware"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/outboxprocessor"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/reindexer"
+)
+
+// Error codes of the admin endpoints
+const (
+	CodeReindexRunning    controllers.ErrorCode = "REINDEX_RUNNING"
+	CodeReindexNotRunning controllers.ErrorCode = "REINDEX_NOT_RUNNING"
+)
+
+type reindexService interface {
+	Start(ctx context.Context, ownerID *uuid.UUID) (reindexer.Progress, error)
+	Progress() reindexer.Progress
+	Cancel() (reindexer.Progress, error)
+}
+
+type outboxProcessor interface {
+	ProcessNow(ctx context.Context) (outboxprocessor.FlushResult, error)
+}
+
+// Controller serves operational endpoints restricted to administrators
+type Controller struct {
+	reindexer       reindexService
+	outboxProcessor outboxProcessor
+}
+
+func NewController(r reindexService, op outboxProcessor) *Controller {
+	return &Controller{
+		reindexer:       r,
+		outboxProcessor: op,
+	}
+}
+
+func (c *Controller) RegisterRoutes(router *gin.RouterGroup) {
+	adminGroup := router.Group("/admin", middleware.RequestLogger(), controllers.RequireRole(controllers.ResourceAdminRole))
+	{
+		adminGroup.POST("/reindex", c.StartReindex())
+		adminGroup.GET("/reindex", c.GetReindexProgress())
+		adminGroup.DELETE("/reindex", c.CancelReindex())
+		adminGroup.POST("/outbox/flush", c.FlushOutbox())
+	}
+}
+
+// StartReindexQuery optionally limits a reindex to the resources of one user
+type StartReindexQuery struct {
+	UserID string `form:"user_id" binding:"omitempty,uuid"`
+}
+
+// StartReindex godoc
+// @Summary      Reindex resources
+// @Description  Republishes the reindex event of every finished resource, or of one user's, e.g. after an embedding model upgrade.
+// @Description  Events are published in the background at the configured rate; search-service drops the old chunks of each resource first.
+// @Description  Resources still being indexed or cancelled by their owner are skipped. Requires the resource-admin realm role.
+// @Tags         admin
+// @Produce      json
+// @Param        user_id  query     string  false  "Only reindex the resources of this user (UUID)"
+// @Success      202      {object}  reindexer.Progress
+// @Failure      400      {object}  controllers.ErrorResponse  "Invalid user id"
+// @Failure      403      {object}  controllers.ErrorResponse  "Missing resource-admin role"
+// @Failure      409      {object}  controllers.ErrorResponse  "A reindex is already running, its progress is in the details"
+// @Failure      500      {object}  controllers.ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /admin/reindex [post]
+func (c *Controller) StartReindex() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var query StartReindexQuery
+		if err := ctx.ShouldBindQuery(&query); err != nil {
+			slog.WarnContext(ctx, "Invalid reindex user id", "error", err)
+			controllers.RespondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "invalid user id")
+			return
+		}
+
+		var ownerID *uuid.UUID
+		if query.UserID != "" {
+			id := uuid.MustParse(query.UserID)
+			ownerID = &id
+		}
+
+		progress, err := c.reindexer.Start(ctx.Request.Context(), ownerID)
+		if errors.Is(err, reindexer.ErrAlreadyRunning) {
+			controllers.RespondWithError(ctx, http.StatusConflict, CodeReindexRunning, err.Error(), progress)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to start reindex", "error", err)
+			controllers.RespondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		adminID, _ := controllers.GetUserID(ctx)
+		slog.InfoContext(ctx, "Admin started reindex", "admin_id", adminID, "owner_id", ownerID, "total", progress.Total)
+		ctx.JSON(http.StatusAccepted, progress)
+	}
+}
+
+// GetReindexProgress godoc
+// @Summary      Get reindex progress
+// @Description  Returns the progress of the running reindex, or of the last one. Requires the resource-admin realm role.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  reindexer.Progress
+// @Failure      403  {object}  controllers.ErrorResponse  "Missing resource-admin role"
+// @Security     ApiKeyAuth
+// @Router       /admin/reindex [get]
+func (c *Controller) GetReindexProgress() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, c.reindexer.Progress())
+	}
+}
+
+// CancelReindex godoc
+// @Summary      Cancel the running reindex
+// @Description  Stops publishing reindex events. Events already published are still processed. Requires the resource-admin realm role.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  reindexer.Progress
+// @Failure      403  {object}  controllers.ErrorResponse  "Missing resource-admin role"
+// @Failure      409  {object}  controllers.ErrorResponse  "No reindex is running"
+// @Security     ApiKeyAuth
+// @Router       /admin/reindex [delete]
+func (c *Controller) CancelReindex() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		progress, err := c.reindexer.Cancel()
+		if errors.Is(err, reindexer.ErrNotRunning) {
+			controllers.RespondWithError(ctx, http.StatusConflict, CodeReindexNotRunning, err.Error())
+			return
+		}
+
+		adminID, _ := controllers.GetUserID(ctx)
+		slog.InfoContext(ctx, "Admin cancelled reindex",
+			"admin_id", adminID,
+			"enqueued", progress.Enqueued,
+			"total", progress.Total)
+		ctx.JSON(http.StatusOK, progress)
+	}
+}
+
+// FlushOutbox godoc
+// @Summary      Flush the outbox
+// @Description  Delivers the pending outbox events without waiting for the next processor tick. Stops at an event that cannot be delivered,
+// @Description  even after retries, so that later events are not delivered ahead of it. Requires the resource-admin realm role.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  outboxprocessor.FlushResult
+// @Failure      403  {object}  controllers.ErrorResponse  "Missing resource-admin role"
+// @Failure      500  {object}  controllers.ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /admin/outbox/flush [post]
+func (c *Controller) FlushOutbox() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := c.outboxProcessor.ProcessNow(ctx.Request.Context())
+		if err != nil {
+			slog.ErrorContext(ctx, "Outbox flush failed", "error", err, "processed", result.Processed)
+			controllers.RespondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		adminID, _ := controllers.GetUserID(ctx)
+		slog.InfoContext(ctx, "Admin flushed outbox",
+			"admin_id", adminID,
+			"processed", result.Processed,
+			"failed", result.Failed,
+			"remaining", result.Remaining)
+		ctx.JSON(http.StatusOK, result)
+	}
+}
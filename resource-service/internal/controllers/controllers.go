@@ -2,24 +2,75 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// ValidateRequest binds the JSON body of a request of type T. Invalid fields
+// are answered with 400 listing each of them with the reason, see
+// ValidationDetails; requests implementing FieldValidator add their own checks.
 func ValidateRequest[T any](ctx *gin.Context) (*T, bool) {
 	var req T
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			RespondWithError(ctx, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "request body is too large", LimitDetails{Limit: maxBytesErr.Limit})
+			return nil, false
+		}
+		fields, ok := fieldErrors(&req, err)
+		if !ok {
+			RespondWithError(ctx, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return nil, false
+		}
+		// The body was decoded, so the request's own checks add to the list
+		if validator, ok := any(&req).(FieldValidator); ok {
+			fields = append(fields, validator.ValidateFields()...)
+		}
+		respondInvalidFields(ctx, fields)
 		return nil, false
 	}
+
+	if validator, ok := any(&req).(FieldValidator); ok {
+		if fields := validator.ValidateFields(); len(fields) > 0 {
+			respondInvalidFields(ctx, fields)
+			return nil, false
+		}
+	}
 	return &req, true
 }
 
+// respondInvalidFields responds with 400 listing every invalid field
+func respondInvalidFields(ctx *gin.Context, fields []FieldError) {
+	RespondWithError(ctx, http.StatusBadRequest, CodeInvalidRequest, "invalid request fields", ValidationDetails{Errors: fields})
+}
+
+// SendSSEEvent writes an event and flushes it, so that it reaches the client
+// at once instead of when the response buffer fills up or the handler returns
 func SendSSEEvent(ctx *gin.Context, event string, data interface{}) {
 	ctx.SSEvent(event, data)
-	ctx.Writer.Flush()
+	Flush(ctx.Writer)
+}
+
+// Flush sends the buffered response to the client. Gin's writer assumes the
+// writer it wraps can flush and panics otherwise, so a writer that can't, e.g.
+// one wrapped by a middleware, keeps the data until the handler returns.
+func Flush(w gin.ResponseWriter) {
+	var inner http.ResponseWriter = w
+	for {
+		unwrapper, ok := inner.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		inner = unwrapper.Unwrap()
+	}
+	if _, ok := inner.(http.Flusher); ok {
+		w.Flush()
+	}
 }
 
 type Controller interface {
@@ -30,8 +81,13 @@ const (
 	UserIDKey    string = "user_id"
 	UserNameKey  string = "user_name"
 	UserRolesKey string = "user_roles"
+	RequestIDKey string = "request_id"
+	TenantIDKey  string = "tenant_id"
 )
 
+// ResourceAdminRole is the Keycloak realm role allowed to audit the resources of all users
+const ResourceAdminRole = "resource-admin"
+
 func GetUserID(ctx context.Context) (uuid.UUID, bool) {
 	id, ok := ctx.Value(UserIDKey).(string)
 	if !ok {
@@ -46,12 +102,43 @@ func GetUserID(ctx context.Context) (uuid.UUID, bool) {
 	return uuidID, ok
 }
 
+func GetRequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestIDKey).(string)
+	return id, ok
+}
+
 func GetUserName(ctx context.Context) (string, bool) {
 	name, ok := ctx.Value(UserNameKey).(string)
 	return name, ok
 }
 
+// GetTenantID returns the tenant of the authenticated user, empty when the
+// token names none
+func GetTenantID(ctx context.Context) string {
+	id, _ := ctx.Value(TenantIDKey).(string)
+	return id
+}
+
 func GetUserRoles(ctx context.Context) ([]string, bool) {
 	roles, ok := ctx.Value(UserRolesKey).([]string)
 	return roles, ok
 }
+
+// HasRole reports whether the authenticated user has the role
+func HasRole(ctx context.Context, role string) bool {
+	roles, _ := GetUserRoles(ctx)
+	return slices.Contains(roles, role)
+}
+
+// RequireRole rejects users without the role with 403
+func RequireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !HasRole(ctx.Request.Context(), role) {
+			slog.WarnContext(ctx, "Access denied: missing role", "role", role)
+			RespondWithError(ctx, http.StatusForbidden, CodeForbidden, "Insufficient permissions")
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -7,12 +7,8 @@ import (
 	"log/slog"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/nzb3/diploma/resource-service/internal/validator"
-
-	"github.com/Nerzal/gocloak/v13"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 
@@ -21,123 +17,152 @@ import (
 )
 
 type AuthMiddlewareConfig struct {
-	Host         string
-	Port         string
-	Realm        string
-	ClientID     string
-	ClientSecret string
+	Host         string `mapstructure:"host" validate:"required"`
+	Port         string `mapstructure:"port" validate:"required"`
+	Realm        string `mapstructure:"realm" validate:"required"`
+	ClientID     string `mapstructure:"client_id" validate:"required"`
+	ClientSecret string `mapstructure:"client_secret" validate:"required"`
+	// Issuer is the iss claim tokens must carry, the realm at Host and Port when unset
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the aud claim tokens must carry, ClientID when unset
+	Audience string `mapstructure:"audience"`
+	// JWKSRefreshInterval is how long the signing keys of the realm are cached
+	JWKSRefreshInterval time.Duration `mapstructure:"jwks_refresh_interval" validate:"min=0"`
+	// TenantClaim is the token claim naming the tenant of the user, recorded
+	// on the resources they create
+	TenantClaim string `mapstructure:"tenant_claim"`
 }
 
 func NewAuthMiddlewareConfig() (*AuthMiddlewareConfig, error) {
-	config := new(AuthMiddlewareConfig)
-	config.Host = configurator.GetString("auth.host")
-	config.Port = configurator.GetString("auth.port")
-	config.Realm = configurator.GetString("auth.realm")
-	config.ClientID = configurator.GetString("auth.client_id")
-	config.ClientSecret = configurator.GetString("auth.client_secret")
-
-	err := validator.Validate(config)
-	if err != nil {
-		return nil, fmt.Errorf("invalid auth middleware config: %w", err)
+	return configurator.LoadKeys("auth", AuthMiddlewareConfig{
+		JWKSRefreshInterval: 15 * time.Minute,
+		TenantClaim:         "tenant_id",
+	})
+}
+
+// realmURL returns the URL of the realm at Host and Port
+func (c *AuthMiddlewareConfig) realmURL() string {
+	return fmt.Sprintf("http://%s:%s/realms/%s", c.Host, c.Port, c.Realm)
+}
+
+func (c *AuthMiddlewareConfig) issuer() string {
+	if c.Issuer != "" {
+		return c.Issuer
 	}
+	return c.realmURL()
+}
 
-	return config, nil
+func (c *AuthMiddlewareConfig) audience() string {
+	if c.Audience != "" {
+		return c.Audience
+	}
+	return c.ClientID
 }
 
+// clockSkew is the leeway given to the time claims of tokens for clocks of
+// Keycloak and the service drifting apart
+const clockSkew = 30 * time.Second
+
+// signingMethods are the algorithms Keycloak signs access tokens with. Any
+// other, above all "none" and HMAC with the public key as secret, is rejected.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// AuthMiddleware validates the access tokens of Keycloak locally: the signature
+// against the cached keys of the realm, and the expiry, issuer and audience
 type AuthMiddleware struct {
-	keycloak    *gocloak.GoCloak
-	config      *AuthMiddlewareConfig
-	publicKey   string
-	publicKeyMu sync.RWMutex
-	lastFetched time.Time
+	config *AuthMiddlewareConfig
+	keys   *keySet
+	parser *jwt.Parser
 }
 
 func NewAuthMiddleware(config *AuthMiddlewareConfig) *AuthMiddleware {
-	keycloakURL := fmt.Sprintf("http://%s:%s", config.Host, config.Port)
 	return &AuthMiddleware{
-		keycloak: gocloak.NewClient(keycloakURL),
-		config:   config,
+		config: config,
+		keys:   newKeySet(config.realmURL()+"/protocol/openid-connect/certs", config.JWKSRefreshInterval),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(config.issuer()),
+			jwt.WithAudience(config.audience()),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(clockSkew),
+		),
 	}
 }
 
-func (k *AuthMiddleware) getToken(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
-	token, claims, headersErr := k.getFromHeaders(ctx)
+func (k *AuthMiddleware) getToken(ctx *gin.Context) (jwt.MapClaims, error) {
+	claims, headersErr := k.getFromHeaders(ctx)
 	if headersErr == nil {
-		return token, claims, nil
+		return claims, nil
 	}
 
-	token, claims, paramsErr := k.getFromParams(ctx)
+	claims, paramsErr := k.getFromParams(ctx)
 	if paramsErr == nil {
-		return token, claims, nil
+		return claims, nil
 	}
 
 	err := errors.Join(headersErr, paramsErr)
-	return nil, nil, fmt.Errorf("token was not found neither in headers nor in params: %w", err)
+	return nil, fmt.Errorf("token was not found neither in headers nor in params: %w", err)
 }
 
-func (k *AuthMiddleware) getFromParams(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
+func (k *AuthMiddleware) getFromParams(ctx *gin.Context) (jwt.MapClaims, error) {
 	tokenString := ctx.Query("auth_token")
 	if tokenString == "" {
-		return nil, nil, errors.New("token is required")
+		return nil, errors.New("token is required")
 	}
 
-	token, claims, err := k.keycloak.DecodeAccessToken(ctx, tokenString, k.config.Realm)
-	if err != nil {
-		return nil, nil, err
-	}
-	return token, claims, nil
+	return k.parseToken(ctx, tokenString)
 }
 
-func (k *AuthMiddleware) getFromHeaders(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
+func (k *AuthMiddleware) getFromHeaders(ctx *gin.Context) (jwt.MapClaims, error) {
 	authHeader := ctx.GetHeader("Authorization")
 	if authHeader == "" {
-		return nil, nil, errors.New("authorization header is required")
+		return nil, errors.New("authorization header is required")
 	}
 
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return nil, nil, errors.New("invalid authorization format")
+		return nil, errors.New("invalid authorization format")
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenString == "" {
-		return nil, nil, errors.New("token not found")
+		return nil, errors.New("token not found")
 	}
 
-	token, claims, err := k.keycloak.DecodeAccessToken(ctx, tokenString, k.config.Realm)
+	return k.parseToken(ctx, tokenString)
+}
+
+// parseToken verifies the signature of the token with the key named by its kid
+// header and validates its claims
+func (k *AuthMiddleware) parseToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := k.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return k.keys.key(ctx, kid)
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return token, claims, nil
+	return claims, nil
 }
 
 func (k *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, _, err := k.getToken(ctx)
-		if err != nil {
-			slog.Error("failed to decode access token", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			return
-		}
-
-		userID, err := token.Claims.GetSubject()
+		claims, err := k.getToken(ctx)
 		if err != nil {
-			slog.Error("failed to get subject from token", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+			slog.ErrorContext(ctx, "failed to validate access token", "error", err)
+			controllers.RespondWithError(ctx, http.StatusUnauthorized, controllers.CodeUnauthorized, "Invalid token")
 			return
 		}
 
-		isValid, err := k.validateToken(ctx, token.Raw)
-		if err != nil || !isValid {
-			slog.Error("token validation failed", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token validation failed"})
+		userID, err := claims.GetSubject()
+		if err != nil || userID == "" {
+			slog.ErrorContext(ctx, "failed to get subject from token", "error", err)
+			controllers.RespondWithError(ctx, http.StatusUnauthorized, controllers.CodeUnauthorized, "Invalid user ID in token")
 			return
 		}
 
-		userName, roles, err := k.getUserInfo(ctx, token.Raw)
-		if err != nil {
-			slog.Error("failed to get user info", "error", err)
-			// Continue anyway as we have the user ID
-		}
+		userName, _ := claims["preferred_username"].(string)
+		roles := realmRoles(&claims)
 
 		ctx.Set(controllers.UserIDKey, userID)
 		ctx.Set(controllers.UserNameKey, userName)
@@ -146,28 +171,47 @@ func (k *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		reqCtx := context.WithValue(ctx.Request.Context(), controllers.UserIDKey, userID)
 		reqCtx = context.WithValue(reqCtx, controllers.UserNameKey, userName)
 		reqCtx = context.WithValue(reqCtx, controllers.UserRolesKey, roles)
+		if tenantID := tenantID(&claims, k.config.TenantClaim); tenantID != "" {
+			ctx.Set(controllers.TenantIDKey, tenantID)
+			reqCtx = context.WithValue(reqCtx, controllers.TenantIDKey, tenantID)
+		}
 		ctx.Request = ctx.Request.WithContext(reqCtx)
 
 		ctx.Next()
 	}
 }
 
-func (k *AuthMiddleware) validateToken(ctx context.Context, tokenString string) (bool, error) {
-	rptResult, err := k.keycloak.RetrospectToken(ctx, tokenString, k.config.ClientID, k.config.ClientSecret, k.config.Realm)
-	if err != nil {
-		return false, fmt.Errorf("failed to introspect token: %w", err)
+// realmRoles reads the Keycloak realm roles from the realm_access claim
+func realmRoles(claims *jwt.MapClaims) []string {
+	if claims == nil {
+		return nil
 	}
 
-	return *rptResult.Active, nil
-}
+	realmAccess, ok := (*claims)["realm_access"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
 
-func (k *AuthMiddleware) getUserInfo(ctx context.Context, tokenString string) (string, []string, error) {
-	userInfo, err := k.keycloak.GetUserInfo(ctx, tokenString, k.config.Realm)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to get user info: %w", err)
+	values, ok := realmAccess["roles"].([]interface{})
+	if !ok {
+		return nil
 	}
 
-	var roles []string
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, ok := value.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// tenantID reads the tenant of the user from the claim, empty when the token has none
+func tenantID(claims *jwt.MapClaims, claim string) string {
+	if claims == nil || claim == "" {
+		return ""
+	}
 
-	return *userInfo.PreferredUsername, roles, nil
+	tenantID, _ := (*claims)[claim].(string)
+	return tenantID
 }
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+)
+
+const testUserID = "3f0c2a4e-8b1d-4c6a-9e2f-5d7b8a9c0e1f"
+
+// jwksServer serves the public keys of a Keycloak realm and counts the requests
+type jwksServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/realms/test/protocol/openid-connect/certs" {
+			http.NotFound(w, r)
+			return
+		}
+		s.requests.Add(1)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		keys := []map[string]string{}
+		for kid, key := range s.keys {
+			keys = append(keys, map[string]string{
+				"kid": kid,
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotate makes the realm sign with a new key, published under kid
+func (s *jwksServer) rotate(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+	return key
+}
+
+// config points the middleware at the server
+func (s *jwksServer) config(t *testing.T) *AuthMiddlewareConfig {
+	t.Helper()
+
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+
+	return &AuthMiddlewareConfig{
+		Host:                host,
+		Port:                port,
+		Realm:               "test",
+		ClientID:            "resource-service",
+		TenantClaim:         "tenant_id",
+		JWKSRefreshInterval: time.Hour,
+	}
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// validClaims are the claims of a token Keycloak issued to the user for the service
+func validClaims(config *AuthMiddlewareConfig) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                testUserID,
+		"iss":                config.issuer(),
+		"aud":                []string{"account", config.ClientID},
+		"exp":                time.Now().Add(5 * time.Minute).Unix(),
+		"iat":                time.Now().Unix(),
+		"preferred_username": "alice",
+		"realm_access":       map[string]any{"roles": []string{"user", controllers.ResourceAdminRole}},
+		"tenant_id":          "acme",
+	}
+}
+
+// authenticate runs a request with the token through the middleware and
+// returns the response together with what the middleware put into the context
+func authenticate(t *testing.T, auth *AuthMiddleware, token string) (*httptest.ResponseRecorder, gin.H) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var seen gin.H
+	router := gin.New()
+	router.GET("/", auth.Authenticate(), func(ctx *gin.Context) {
+		userID, _ := controllers.GetUserID(ctx.Request.Context())
+		userName, _ := controllers.GetUserName(ctx.Request.Context())
+		roles, _ := controllers.GetUserRoles(ctx.Request.Context())
+		tenantID := controllers.GetTenantID(ctx.Request.Context())
+		seen = gin.H{"user_id": userID, "user_name": userName, "roles": roles, "tenant_id": tenantID}
+		ctx.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestAuthenticate_ValidToken(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+	config := server.config(t)
+	auth := NewAuthMiddleware(config)
+
+	w, seen := authenticate(t, auth, signToken(t, key, "key-1", validClaims(config)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, gin.H{
+		"user_id":   uuid.MustParse(testUserID),
+		"user_name": "alice",
+		"roles":     []string{"user", controllers.ResourceAdminRole},
+		"tenant_id": "acme",
+	}, seen)
+
+	// The keys are cached for the next requests
+	w, _ = authenticate(t, auth, signToken(t, key, "key-1", validClaims(config)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 1, server.requests.Load())
+}
+
+func TestAuthenticate_RejectedTokens(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+	config := server.config(t)
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		modify func(jwt.MapClaims)
+	}{
+		{name: "expired", key: key, modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute - clockSkew).Unix() }},
+		{name: "without expiry", key: key, modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "wrong issuer", key: key, modify: func(c jwt.MapClaims) { c["iss"] = "http://evil.example/realms/test" }},
+		{name: "wrong audience", key: key, modify: func(c jwt.MapClaims) { c["aud"] = "account" }},
+		{name: "without subject", key: key, modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "forged signature", key: forged},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(config)
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			w, seen := authenticate(t, NewAuthMiddleware(config), signToken(t, tt.key, "key-1", claims))
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Nil(t, seen)
+		})
+	}
+}
+
+func TestAuthenticate_UnsignedToken(t *testing.T) {
+	server := newJWKSServer(t)
+	server.rotate(t, "key-1")
+	config := server.config(t)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(config))
+	token.Header["kid"] = "key-1"
+	unsigned, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	w, _ := authenticate(t, NewAuthMiddleware(config), unsigned)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAuthenticate_KeyRotationRefreshesOnce(t *testing.T) {
+	server := newJWKSServer(t)
+	oldKey := server.rotate(t, "key-1")
+	config := server.config(t)
+	auth := NewAuthMiddleware(config)
+
+	w, _ := authenticate(t, auth, signToken(t, oldKey, "key-1", validClaims(config)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Keycloak rotated its key; the unknown kid forces a refresh before the cache expires
+	newKey := server.rotate(t, "key-2")
+	w, _ = authenticate(t, auth, signToken(t, newKey, "key-2", validClaims(config)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 2, server.requests.Load())
+
+	// Unknown kids don't refresh again right away
+	w, _ = authenticate(t, auth, signToken(t, newKey, "key-3", validClaims(config)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.EqualValues(t, 2, server.requests.Load())
+}
+
+func TestKeySet_StaleKeysKeptWhenRefreshFails(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+	config := server.config(t)
+	config.JWKSRefreshInterval = time.Minute
+	auth := NewAuthMiddleware(config)
+
+	now := time.Now()
+	auth.keys.now = func() time.Time { return now }
+	w, _ := authenticate(t, auth, signToken(t, key, "key-1", validClaims(config)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// The cached keys are stale and Keycloak is down
+	server.Close()
+	now = now.Add(2 * time.Minute)
+
+	w, _ = authenticate(t, auth, signToken(t, key, "key-1", validClaims(config)))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAuthenticate_TenantClaim(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.rotate(t, "key-1")
+
+	tests := []struct {
+		name       string
+		claim      string
+		modify     func(jwt.MapClaims)
+		wantTenant string
+	}{
+		{name: "default claim", claim: "tenant_id", wantTenant: "acme"},
+		{name: "configured claim", claim: "org", modify: func(c jwt.MapClaims) { c["org"] = "globex" }, wantTenant: "globex"},
+		{name: "claim missing", claim: "tenant_id", modify: func(c jwt.MapClaims) { delete(c, "tenant_id") }},
+		{name: "claim not a string", claim: "tenant_id", modify: func(c jwt.MapClaims) { c["tenant_id"] = 42 }},
+		{name: "no claim configured", claim: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := server.config(t)
+			config.TenantClaim = tt.claim
+			claims := validClaims(config)
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			w, seen := authenticate(t, NewAuthMiddleware(config), signToken(t, key, "key-1", claims))
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tt.wantTenant, seen["tenant_id"])
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords decodes the JSON records written to buf
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func newRedactLogger(buf *bytes.Buffer, maxValueBytes int) *slog.Logger {
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return slog.New(NewRedactHandler(handler, maxValueBytes))
+}
+
+func TestRedactHandler_TruncatesLongContent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf, 16)
+
+	content := strings.Repeat("secret notes ", 100)
+	logger.Info("Indexing resource",
+		"extracted_content", content,
+		"raw_content", []byte(content),
+		"name", "notes.txt")
+
+	record := logRecords(t, &buf)[0]
+	assert.Equal(t, "secret notes sec...(1284 more bytes)", record["extracted_content"])
+	assert.Equal(t, "<1300 bytes>", record["raw_content"])
+	assert.Equal(t, "notes.txt", record["name"])
+}
+
+func TestRedactHandler_DoesNotDumpStructsWholesale(t *testing.T) {
+	type resource struct {
+		ID      string
+		Content string
+	}
+
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf, 64)
+
+	logger.Info("Short", "resource", resource{ID: "r1", Content: "hi"})
+	logger.Info("Long", "resource", resource{ID: "r1", Content: strings.Repeat("x", 1000)})
+
+	records := logRecords(t, &buf)
+	assert.Equal(t, map[string]any{"ID": "r1", "Content": "hi"}, records[0]["resource"])
+	assert.Equal(t, map[string]any{
+		"ID":      "r1",
+		"Content": strings.Repeat("x", 64) + "...(936 more bytes)",
+	}, records[1]["resource"], "long structs are logged field by field")
+}
+
+// formatCounter counts how often it is formatted
+type formatCounter struct {
+	formatted *int
+}
+
+func (c formatCounter) Format(f fmt.State, _ rune) {
+	*c.formatted++
+	fmt.Fprint(f, "x")
+}
+
+func TestRedactHandler_DoesNotFormatLargeValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf, 64)
+
+	var formatted int
+	values := make([]formatCounter, 10000)
+	for i := range values {
+		values[i] = formatCounter{formatted: &formatted}
+	}
+	logger.Info("Chunks", "chunks", values, "by_id", map[int]string{1: strings.Repeat("x", 100), 2: "y"})
+
+	record := logRecords(t, &buf)[0]
+	assert.Equal(t, "<10000 items>", record["chunks"])
+	assert.Equal(t, "<2 items>", record["by_id"])
+	assert.Zero(t, formatted)
+}
+
+func TestFormattedSize_StopsAtCycles(t *testing.T) {
+	var cycle any
+	cycle = &cycle
+
+	assert.Greater(t, formattedSize(reflect.ValueOf(cycle), 64), 64)
+}
+
+func TestRedactHandler_MasksSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newRedactLogger(&buf, DefaultMaxLogValueBytes).With("client_secret", "s3cr3t")
+
+	logger.Info("Request",
+		slog.Group("request", "Authorization", "Bearer abc.def.ghi", "method", "GET"),
+		"headers", http.Header{"Authorization": {"Bearer abc"}, "Accept": {"*/*"}})
+
+	record := logRecords(t, &buf)[0]
+	assert.Equal(t, redacted, record["client_secret"])
+	assert.Equal(t, map[string]any{"Authorization": redacted, "method": "GET"}, record["request"])
+	assert.Equal(t, map[string]any{"Authorization": []any{redacted}, "Accept": []any{"*/*"}}, record["headers"])
+	assert.NotContains(t, buf.String(), "abc")
+}
+
+func TestRedactPath(t *testing.T) {
+	assert.Equal(t, "/resources/events", RedactPath("/resources/events"))
+	assert.Equal(t, "/resources/events?auth_token=%5BREDACTED%5D&limit=10",
+		RedactPath("/resources/events?limit=10&auth_token=abc.def"))
+	assert.Equal(t, "/resources?"+redacted, RedactPath("/resources?auth_token=%zz"))
+}
+
+func TestRequestLogger_RedactsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newRedactLogger(&buf, 40))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var received string
+	engine.POST("/resources", RequestLogger(), func(ctx *gin.Context) {
+		body, _ := ctx.GetRawData()
+		received = string(body)
+		ctx.Status(http.StatusNoContent)
+	})
+
+	body := `{"content":"` + strings.Repeat("note ", 40) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/resources?auth_token=abc.def", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer abc.def")
+	req.Header.Set("Content-Type", "application/json")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	// The handler still reads the whole body
+	assert.Equal(t, body, received)
+
+	incoming := logRecords(t, &buf)[0]
+	assert.Equal(t, "Incoming request", incoming["msg"])
+	assert.Equal(t, "/resources?auth_token=%5BREDACTED%5D", incoming["url"])
+	assert.Equal(t, `{"content":"note note note note note not...(174 more bytes)`, incoming["body"])
+	assert.NotContains(t, buf.String(), "abc.def")
+}
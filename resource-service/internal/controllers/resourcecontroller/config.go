@@ -0,0 +1,63 @@
+package resourcecontroller
+
+import (
+	"encoding/base64"
+
+	"github.com/nzb3/diploma/resource-service/internal/configurator"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// bodyOverhead is the room left in a request body for the fields besides the content
+const bodyOverhead = 64 << 10
+
+// Config holds the maximum content size of each resource type and of an
+// imported archive, in bytes. EPUB e-books share the limit of PDF documents.
+type Config struct {
+	MaxTextBytes   int `yaml:"max_text_bytes" mapstructure:"max_text_bytes" validate:"min=1"`
+	MaxPDFBytes    int `yaml:"max_pdf_bytes" mapstructure:"max_pdf_bytes" validate:"min=1"`
+	MaxURLBytes    int `yaml:"max_url_bytes" mapstructure:"max_url_bytes" validate:"min=1"`
+	MaxImportBytes int `yaml:"max_import_bytes" mapstructure:"max_import_bytes" validate:"min=1"`
+}
+
+// DefaultConfig returns the size limits used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		MaxTextBytes:   10 << 20,
+		MaxPDFBytes:    50 << 20,
+		MaxURLBytes:    2 << 10,
+		MaxImportBytes: 256 << 20,
+	}
+}
+
+// NewConfig loads the upload size limits from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("upload", DefaultConfig())
+}
+
+// maxContentBytes returns the size limit of the content of a resource type.
+// Unknown types get the largest limit and are rejected by validation instead.
+func (c *Config) maxContentBytes(resourceType resourcemodel.ResourceType) int {
+	switch resourceType {
+	case resourcemodel.ResourceTypeText, resourcemodel.ResourceTypeCSV:
+		return c.MaxTextBytes
+	case resourcemodel.ResourceTypePDF, resourcemodel.ResourceTypeEPUB:
+		return c.MaxPDFBytes
+	case resourcemodel.ResourceTypeURL:
+		return c.MaxURLBytes
+	default:
+		return max(c.MaxTextBytes, c.MaxPDFBytes, c.MaxURLBytes)
+	}
+}
+
+// maxBodyBytes returns the size limit of a request body, which carries the
+// content base64 encoded
+func (c *Config) maxBodyBytes() int64 {
+	largest := max(c.MaxTextBytes, c.MaxPDFBytes, c.MaxURLBytes)
+	return int64(base64.StdEncoding.EncodedLen(largest) + bodyOverhead)
+}
+
+// maxImportBodyBytes returns the size limit of an import request body, which
+// carries the archive as a form file
+func (c *Config) maxImportBodyBytes() int64 {
+	return int64(c.MaxImportBytes + bodyOverhead)
+}
@@ -2,10 +2,12 @@ package resourcecontroller
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,20 +23,43 @@ const (
 )
 
 type resourceService interface {
-	SaveUsersResource(ctx context.Context, userID uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, url string) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error)
-	GetUsersResources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]resourcemodel.Resource, error)
+	SaveUsersResource(ctx context.Context, userID uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, url string, priority resourcemodel.ResourcePriority, opts ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error)
+	GetUsersResources(ctx context.Context, userID uuid.UUID, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error)
+	GetUsersResourcesByStatus(ctx context.Context, userID uuid.UUID, status resourcemodel.ResourceStatus, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error)
+	CountUsersResources(ctx context.Context, userID uuid.UUID) (int, error)
+	CountUsersResourcesByStatus(ctx context.Context, userID uuid.UUID, status resourcemodel.ResourceStatus) (int, error)
+	GetUsersResourceStats(ctx context.Context, userID uuid.UUID) (resourcemodel.ResourceStats, error)
+	CountAllResources(ctx context.Context) (int, error)
+	GetAllResources(ctx context.Context, limit, offset int) ([]resourcemodel.Resource, error)
 	GetUsersResourceByID(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error)
+	GetUsersResourceStatus(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.ResourceStatus, error)
 	DeleteUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) error
-	UpdateUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, name *string, content *[]byte) (resourcemodel.Resource, error)
+	CancelUsersResourceIndexation(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error)
+	UpdateUsersResourceVisibility(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error)
+	DeleteUsersResources(ctx context.Context, userID uuid.UUID, resourceIDs []uuid.UUID) ([]resourcemodel.DeleteResult, error)
+	UpdateUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, name *string, resourceType *resourcemodel.ResourceType, content *[]byte) (resourcemodel.Resource, error)
+	GetResourceStatusChannel(resourceID uuid.UUID) (<-chan resourcemodel.ResourceStatusUpdate, bool)
+	RemoveResourceStatusChannel(resourceID uuid.UUID)
 }
 
 type Controller struct {
-	service resourceService
+	service            resourceService
+	config             *Config
+	statusPollInterval time.Duration
 }
 
-func NewController(service resourceService) *Controller {
+// NewController creates the resource controller. Upload size limits default to
+// DefaultConfig when no config is given.
+func NewController(service resourceService, config ...*Config) *Controller {
 	c := &Controller{
-		service: service,
+		service:            service,
+		statusPollInterval: defaultStatusPollInterval,
+	}
+	if len(config) > 0 && config[0] != nil {
+		c.config = config[0]
+	} else {
+		defaults := DefaultConfig()
+		c.config = &defaults
 	}
 	slog.Debug("Initialized resource controller")
 	return c
@@ -44,162 +69,388 @@ func (c *Controller) RegisterRoutes(router *gin.RouterGroup) {
 	slog.Info("Registering resource routes")
 	resourceGroup := router.Group("/resources", middleware.RequestLogger())
 	{
-		resourceGroup.POST("/", middleware.SSEHeadersMiddleware(), c.SaveResource())
-		resourceGroup.PATCH("/:id", c.UpdateResource())
+		resourceGroup.POST("/", c.limitBody(), middleware.SSEHeadersMiddleware(), c.SaveResource())
+		resourceGroup.POST("/upload", c.limitBody(), middleware.SSEHeadersMiddleware(), c.UploadResource())
+		resourceGroup.POST("/import", limitBodyTo(c.config.maxImportBodyBytes()), c.ImportResources())
+		resourceGroup.PATCH("/:id", c.limitBody(), c.UpdateResource())
 		resourceGroup.GET("/", c.GetResources())
+		resourceGroup.GET("/types", c.GetResourceTypes())
+		resourceGroup.GET("/stats", c.GetResourceStats())
+		resourceGroup.GET("/export", c.ExportResources())
 		resourceGroup.GET("/:id", c.GetResourceByID())
+		resourceGroup.GET("/:id/content", c.GetResourceContent())
+		resourceGroup.GET("/:id/status/stream", middleware.SSEHeadersMiddleware(), c.StreamResourceStatus())
+		resourceGroup.POST("/:id/cancel", c.CancelResourceIndexation())
+		resourceGroup.PATCH("/:id/visibility", c.UpdateResourceVisibility())
 		resourceGroup.DELETE("/:id", c.DeleteResource())
+		resourceGroup.DELETE("/", c.DeleteResources())
+	}
+
+	adminGroup := router.Group("/admin", middleware.RequestLogger(), controllers.RequireRole(controllers.ResourceAdminRole))
+	{
+		adminGroup.GET("/resources", c.GetAllResources())
+	}
+}
+
+// limitBody stops reading request bodies past the size the largest allowed
+// content takes, so oversized uploads fail while being read instead of after
+// being buffered
+func (c *Controller) limitBody() gin.HandlerFunc {
+	return limitBodyTo(c.config.maxBodyBytes())
+}
+
+// limitBodyTo stops reading request bodies past limit bytes
+func limitBodyTo(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+		ctx.Next()
 	}
 }
 
+// contentTooLarge responds with 413 when the content exceeds the limit of the
+// resource type
+func (c *Controller) contentTooLarge(ctx *gin.Context, resourceType resourcemodel.ResourceType, size int) bool {
+	limit := c.config.maxContentBytes(resourceType)
+	if size <= limit {
+		return false
+	}
+
+	slog.WarnContext(ctx, "Resource content too large",
+		"type", resourceType,
+		"size", size,
+		"limit", limit)
+	c.respondTooLarge(ctx, resourceType, limit)
+	return true
+}
+
+// respondTooLarge responds with 413 and the limit the content exceeded
+func (c *Controller) respondTooLarge(ctx *gin.Context, resourceType resourcemodel.ResourceType, limit int) {
+	message := fmt.Sprintf("%s content exceeds %d bytes", resourceType, limit)
+	if resourceType == "" {
+		message = fmt.Sprintf("content exceeds %d bytes", limit)
+	}
+	controllers.RespondWithError(ctx, http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge, message,
+		controllers.LimitDetails{Limit: int64(limit)})
+}
+
 // SaveResource godoc
 // @Summary      Create a new resource
 // @Description  Creates a new resource for the authenticated user. Returns the created resource and status updates via SSE.
+// @Description  An optional priority (high, normal or low) moves the resource ahead of or behind other pending indexations.
+// @Description  Optional chunk_size (up to 8192) and chunk_overlap (in characters) override the chunking used whenever the resource is indexed.
+// @Description  An optional pages range such as "3-10" only extracts those pages of a PDF, also when its content is updated.
+// @Description  Content the user already saved is rejected with 409 and the ID of the existing resource, unless force is set.
 // @Tags         resources
 // @Accept       json
 // @Produce      json
 // @Param        request  body      SaveResourceRequest  true  "Resource creation payload"
 // @Success      200      {object}  SSEResourceEvent    "Resource created event (SSE)"
-// @Failure      400      {object}  ErrorResponse       "Invalid user id or request body"
+// @Failure      400      {object}  ErrorResponse       "Invalid user id, request body, priority, chunking, page range, content not matching the type or url on a private address; invalid fields are listed in the details"
+// @Failure      409      {object}  ErrorResponse       "The user already has a resource with the same content, its ID is in the details"
+// @Failure      413      {object}  ErrorResponse            "Content exceeds the size limit of its type"
+// @Failure      422      {object}  ErrorResponse       "The page of a url resource is too large or redirects too many times"
 // @Failure      500      {object}  ErrorResponse       "Internal server error"
+// @Failure      504      {object}  ErrorResponse       "The page of a url resource took too long to download"
 // @Security     ApiKeyAuth
 // @Router       /resources [post]
 func (c *Controller) SaveResource() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		slog.Info("Handling save resource request",
+		slog.InfoContext(ctx, "Handling save resource request",
 			"client", ctx.ClientIP(),
 			"content_type", ctx.ContentType())
 
 		req, ok := controllers.ValidateRequest[SaveResourceRequest](ctx)
 		if !ok {
-			slog.Warn("Invalid save request")
+			slog.WarnContext(ctx, "Invalid save request")
+			return
+		}
+		if c.contentTooLarge(ctx, resourcemodel.ResourceType(req.Type), len(req.Content)) {
 			return
 		}
 
 		userID, ok := controllers.GetUserID(ctx)
 		if !ok {
-			slog.Warn("Invalid user id")
-			c.respondWithError(ctx, http.StatusBadRequest, "Invalid user id")
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
 			return
 		}
 
-		resource, statusUpdateCh, err := c.service.SaveUsersResource(ctx, userID, req.Content, resourcemodel.ResourceType(req.Type), req.Name, req.URL)
+		pages, err := parsePages(req.Pages)
 		if err != nil {
-			slog.Error("Failed to save resource", "error", err)
-			c.respondWithError(ctx, http.StatusInternalServerError, err.Error())
+			slog.WarnContext(ctx, "Invalid page range", "error", err)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
-		// Send initial resource creation event
-		if !c.handleResourceEvent(ctx, resource, true) {
+		extractCtx, warnings := resourcemodel.WithExtractionWarnings(ctx)
+		resource, statusUpdateCh, err := c.service.SaveUsersResource(extractCtx, userID, req.Content, resourcemodel.ResourceType(req.Type), req.Name, req.URL, resourcemodel.ResourcePriority(req.Priority),
+			resourcemodel.WithChunking(req.ChunkSize, req.ChunkOverlap), resourcemodel.WithPageRange(pages), resourcemodel.WithAllowDuplicate(req.Force),
+			resourcemodel.WithTenantID(controllers.GetTenantID(ctx)))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to save resource", "error", err)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
+		resource.Warnings = warnings.List()
 
-		// Stream status updates
-		ctx.Stream(func(w io.Writer) bool {
-			select {
-			case statusUpdate, ok := <-statusUpdateCh:
-				return c.handleStatusUpdateEvent(ctx, statusUpdate, ok)
-			case <-ctx.Done():
-				slog.Warn("Client disconnected", "client", ctx.ClientIP())
-				return false
-			}
-		})
+		c.streamResource(ctx, resource, statusUpdateCh)
 	}
 }
 
+// streamResource sends the created resource and then its status updates as SSE
+func (c *Controller) streamResource(ctx *gin.Context, resource resourcemodel.Resource, statusUpdateCh <-chan resourcemodel.ResourceStatusUpdate) {
+	// Send initial resource creation event
+	if !c.handleResourceEvent(ctx, resource, true) {
+		return
+	}
+
+	// Stream status updates
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case statusUpdate, ok := <-statusUpdateCh:
+			return c.handleStatusUpdateEvent(ctx, statusUpdate, ok)
+		case <-ctx.Done():
+			slog.WarnContext(ctx, "Client disconnected", "client", ctx.ClientIP())
+			return false
+		}
+	})
+}
+
 // UpdateResource godoc
 // @Summary      Update a resource
-// @Description  Updates the name or content of a resource for the authenticated user.
+// @Description  Updates the name, type or content of a resource for the authenticated user.
+// @Description  Changing the type or content re-extracts and re-indexes the resource.
 // @Tags         resources
 // @Accept       json
 // @Produce      json
 // @Param        id       path      string                true   "Resource ID (UUID)"
 // @Param        request  body      UpdateResourceRequest true   "Fields to update"
 // @Success      200      {object}  UpdateResourceResponse
-// @Failure      400      {object}  ErrorResponse         "Invalid user id, resource id, or request body"
+// @Failure      400      {object}  ErrorResponse         "Invalid user id, resource id, request body, type incompatible with content or malformed EPUB; invalid fields are listed in the details"
+// @Failure      403      {object}  ErrorResponse         "Resource belongs to another user"
 // @Failure      404      {object}  ErrorResponse         "Resource not found"
+// @Failure      413      {object}  ErrorResponse            "Content exceeds the size limit of its type"
+// @Failure      422      {object}  ErrorResponse         "EPUB is DRM-protected, has no text or is too large once decompressed"
 // @Failure      500      {object}  ErrorResponse         "Internal server error"
 // @Security     ApiKeyAuth
 // @Router       /resources/{id} [patch]
 func (c *Controller) UpdateResource() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var pathReq GetResourceByIDRequest
-		if err := ctx.ShouldBindUri(&pathReq); err != nil {
-			slog.Error("Error parsing resource ID", "err", err)
-			c.respondWithError(ctx, http.StatusBadRequest, "invalid resource ID")
+		resourceID, ok := parseResourceID(ctx)
+		if !ok {
 			return
 		}
 
 		userID, ok := controllers.GetUserID(ctx)
 		if !ok {
-			slog.Warn("Invalid user id")
-			c.respondWithError(ctx, http.StatusBadRequest, "Invalid user id")
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
 			return
 		}
 
-		var req UpdateResourceRequest
-		if err := ctx.ShouldBindJSON(&req); err != nil {
-			slog.Error("Error parsing request", "err", err)
-			c.respondWithError(ctx, http.StatusBadRequest, err.Error())
+		req, ok := controllers.ValidateRequest[UpdateResourceRequest](ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid update request")
 			return
 		}
 
-		resource, err := c.service.UpdateUsersResource(ctx, userID, pathReq.ID, req.Name, req.Content)
+		var resourceType *resourcemodel.ResourceType
+		if req.Type != nil {
+			t := resourcemodel.ResourceType(*req.Type)
+			resourceType = &t
+		}
+
+		// Without a new type the content keeps the type of the stored resource
+		if req.Content != nil {
+			var contentType resourcemodel.ResourceType
+			if resourceType != nil {
+				contentType = *resourceType
+			} else {
+				stored, err := c.service.GetUsersResourceByID(ctx, userID, resourceID)
+				if err != nil {
+					slog.WarnContext(ctx, "Failed to get resource to update", "error", err)
+					c.respondWithServiceError(ctx, err)
+					return
+				}
+				contentType = stored.Type
+			}
+			if c.contentTooLarge(ctx, contentType, len(*req.Content)) {
+				return
+			}
+		}
+
+		extractCtx, warnings := resourcemodel.WithExtractionWarnings(ctx)
+		resource, err := c.service.UpdateUsersResource(extractCtx, userID, resourceID, req.Name, resourceType, req.Content)
 		if err != nil {
-			slog.Warn("Failed to update resource", "error", err)
-			c.respondWithError(ctx, http.StatusInternalServerError, err.Error())
+			slog.WarnContext(ctx, "Failed to update resource", "error", err)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
+		resource.Warnings = warnings.List()
 
 		response := UpdateResourceResponse{Resource: resource}
 		ctx.JSON(http.StatusOK, response)
 	}
 }
 
+// GetResourceTypes godoc
+// @Summary      List supported resource types
+// @Description  Returns the resource types accepted on creation with a label and the accepted MIME types of each.
+// @Tags         resources
+// @Produce      json
+// @Success      200  {object}  GetResourceTypesResponse
+// @Security     ApiKeyAuth
+// @Router       /resources/types [get]
+func (c *Controller) GetResourceTypes() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, GetResourceTypesResponse{
+			Types: resourcemodel.SupportedResourceTypes(),
+		})
+	}
+}
+
+// GetResourceStats godoc
+// @Summary      Count the resources of the user by status
+// @Description  Returns the number of resources of the authenticated user in total and in every status, statuses without resources counting zero.
+// @Description  Chunk and storage usage of the indexed resources is reported by the search service.
+// @Tags         resources
+// @Produce      json
+// @Success      200     {object}  GetResourceStatsResponse
+// @Failure      400     {object}  ErrorResponse  "Invalid user id"
+// @Failure      500     {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/stats [get]
+func (c *Controller) GetResourceStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		stats, err := c.service.GetUsersResourceStats(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count resources by status", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, GetResourceStatsResponse{Stats: stats})
+	}
+}
+
 // GetResources godoc
 // @Summary      Get list of user resources
-// @Description  Returns a paginated list of resources belonging to the authenticated user.
+// @Description  Returns a paginated list of resources belonging to the authenticated user, optionally only those having a status.
 // @Tags         resources
 // @Accept       json
 // @Produce      json
 // @Param        limit   query     int     false  "Maximum number of resources to return"  minimum(1)  default(10)
 // @Param        offset  query     int     false  "Number of resources to skip before starting to collect the result set"  minimum(0)  default(0)
+// @Param        status  query     string  false  "Only return resources having the status"  Enums(pending, processing, completed, failed, cancelled)
+// @Param        sort    query     string  false  "Column to sort by"  Enums(created_at, updated_at, name)  default(created_at)
+// @Param        order   query     string  false  "Sort direction"  Enums(asc, desc)  default(desc)
 // @Success      200     {object}  GetResourcesResponse
-// @Failure      400     {object}  ErrorResponse  "Invalid user id or bad request"
+// @Failure      400     {object}  ErrorResponse  "Invalid user id, status, sort or bad request"
 // @Failure      500     {object}  ErrorResponse  "Internal server error"
 // @Security     ApiKeyAuth
 // @Router       /resources [get]
 func (c *Controller) GetResources() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		slog.Info("Fetching resources list")
+		slog.InfoContext(ctx, "Fetching resources list")
 
 		userID, ok := controllers.GetUserID(ctx)
 		if !ok {
-			slog.Warn("Invalid user id")
-			c.respondWithError(ctx, http.StatusBadRequest, "Invalid user id")
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
 			return
 		}
 
 		limit, offset := getPaginationParams(ctx)
 
-		resources, err := c.service.GetUsersResources(ctx, userID, limit, offset)
+		sort, err := resourcemodel.ParseResourceSort(ctx.Query("sort"), ctx.Query("order"))
 		if err != nil {
-			slog.Error("Failed to retrieve resources", "error", err)
-			c.respondWithError(ctx, http.StatusInternalServerError, err.Error())
+			slog.WarnContext(ctx, "Invalid sort", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		var resources []resourcemodel.Resource
+		var total int
+		if status, ok := ctx.GetQuery("status"); ok {
+			resourceStatus := resourcemodel.ResourceStatus(status)
+			resources, err = c.service.GetUsersResourcesByStatus(ctx, userID, resourceStatus, limit, offset, sort)
+			if err == nil {
+				total, err = c.service.CountUsersResourcesByStatus(ctx, userID, resourceStatus)
+			}
+		} else {
+			resources, err = c.service.GetUsersResources(ctx, userID, limit, offset, sort)
+			if err == nil {
+				total, err = c.service.CountUsersResources(ctx, userID)
+			}
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resources", "error", err)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
 		response := GetResourcesResponse{
 			Resources: resources,
 			Count:     len(resources),
+			Total:     total,
+			Limit:     limit,
+			Offset:    offset,
 		}
 
-		slog.Info("Successfully fetched resources", "count", len(resources))
+		slog.InfoContext(ctx, "Successfully fetched resources", "count", len(resources))
 		ctx.JSON(http.StatusOK, response)
 	}
 }
 
+// GetAllResources godoc
+// @Summary      List the resources of all users
+// @Description  Returns a paginated list of every user's resources. Requires the resource-admin realm role.
+// @Tags         admin
+// @Produce      json
+// @Param        limit   query     int     false  "Maximum number of resources to return"  minimum(1)  default(10)
+// @Param        offset  query     int     false  "Number of resources to skip before starting to collect the result set"  minimum(0)  default(0)
+// @Success      200     {object}  GetResourcesResponse
+// @Failure      403     {object}  ErrorResponse  "Missing resource-admin role"
+// @Failure      500     {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /admin/resources [get]
+func (c *Controller) GetAllResources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limit, offset := getPaginationParams(ctx)
+
+		resources, err := c.service.GetAllResources(ctx, limit, offset)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resources of all users", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		total, err := c.service.CountAllResources(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to count resources of all users", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		userID, _ := controllers.GetUserID(ctx)
+		slog.InfoContext(ctx, "Admin listed resources of all users", "admin_id", userID, "count", len(resources))
+		ctx.JSON(http.StatusOK, GetResourcesResponse{
+			Resources: resources,
+			Count:     len(resources),
+			Total:     total,
+			Limit:     limit,
+			Offset:    offset,
+		})
+	}
+}
+
 // GetResourceByID godoc
 // @Summary      Get a resource by ID
 // @Description  Returns a single resource by its ID for the authenticated user.
@@ -209,6 +460,7 @@ func (c *Controller) GetResources() gin.HandlerFunc {
 // @Param        id      path      string  true   "Resource ID (UUID)"
 // @Success      200     {object}  GetResourceByIDResponse
 // @Failure      400     {object}  ErrorResponse  "Invalid user id or resource id"
+// @Failure      403     {object}  ErrorResponse  "Resource belongs to another user"
 // @Failure      404     {object}  ErrorResponse  "Resource not found"
 // @Failure      500     {object}  ErrorResponse  "Internal server error"
 // @Security     ApiKeyAuth
@@ -217,37 +469,126 @@ func (c *Controller) GetResourceByID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userID, ok := controllers.GetUserID(ctx)
 		if !ok {
-			slog.Warn("Invalid user id")
-			c.respondWithError(ctx, http.StatusBadRequest, "Invalid user id")
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
 			return
 		}
 
 		var req GetResourceByIDRequest
 		if err := ctx.ShouldBindUri(&req); err != nil {
-			slog.Error("Invalid resource ID format", "error", err)
-			c.respondWithError(ctx, http.StatusBadRequest, "invalid resource ID")
+			slog.ErrorContext(ctx, "Invalid resource ID format", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidResourceID, "invalid resource ID")
 			return
 		}
 
-		slog.Info("Processing get resource request",
+		slog.InfoContext(ctx, "Processing get resource request",
 			"resource_id", req.ID,
 			"client", ctx.ClientIP())
 
 		resource, err := c.service.GetUsersResourceByID(ctx, userID, req.ID)
 		if err != nil {
-			slog.Error("Failed to retrieve resource",
+			slog.ErrorContext(ctx, "Failed to retrieve resource",
 				"resource_id", req.ID,
 				"error", err)
-			c.respondWithError(ctx, http.StatusInternalServerError, err.Error())
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		response := GetResourceByIDResponse{
+			Resource:   resource,
+			ChunkCount: resource.ChunkCount(),
+		}
+		slog.InfoContext(ctx, "Successfully fetched resource")
+		ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// GetResourceContent godoc
+// @Summary      Get the content of a resource
+// @Description  Returns the text extracted from a resource, or its raw content when raw is set, to check the extraction.
+// @Description  Large content can be read in parts with offset and length, counted in characters of the extracted text or bytes of the raw content.
+// @Tags         resources
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true   "Resource ID (UUID)"
+// @Param        raw     query     bool    false  "Return the raw content instead of the extracted text"
+// @Param        offset  query     int     false  "Start of the returned part"
+// @Param        length  query     int     false  "Maximum size of the returned part, the rest of the content when omitted"
+// @Success      200     {object}  GetResourceContentResponse
+// @Failure      400     {object}  ErrorResponse  "Invalid user id, resource id or range"
+// @Failure      403     {object}  ErrorResponse  "Resource belongs to another user"
+// @Failure      404     {object}  ErrorResponse  "Resource not found"
+// @Failure      500     {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/{id}/content [get]
+func (c *Controller) GetResourceContent() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		resourceID, ok := parseResourceID(ctx)
+		if !ok {
+			return
+		}
+
+		var query GetResourceContentQuery
+		if err := ctx.ShouldBindQuery(&query); err != nil {
+			slog.WarnContext(ctx, "Invalid content range", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidContentRange, "invalid content range")
+			return
+		}
+
+		resource, err := c.service.GetUsersResourceByID(ctx, userID, resourceID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resource",
+				"resource_id", resourceID,
+				"error", err)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
-		response := GetResourceByIDResponse{Resource: resource}
-		slog.Info("Successfully fetched resource")
+		response := GetResourceContentResponse{
+			ResourceID: resource.ID,
+			Offset:     query.Offset,
+		}
+		if query.Raw {
+			response.RawContent = sliceRange(resource.RawContent, query.Offset, query.Length)
+			response.Length = len(response.RawContent)
+			response.Total = len(resource.RawContent)
+		} else {
+			// Sliced by characters so that multi-byte ones are never cut in half
+			content := []rune(resource.ExtractedContent)
+			part := sliceRange(content, query.Offset, query.Length)
+			response.Content = string(part)
+			response.Length = len(part)
+			response.Total = len(content)
+		}
+
+		slog.InfoContext(ctx, "Successfully fetched resource content",
+			"resource_id", resource.ID,
+			"raw", query.Raw,
+			"length", response.Length)
 		ctx.JSON(http.StatusOK, response)
 	}
 }
 
+// sliceRange returns up to length elements starting at offset, all remaining
+// ones when length is 0. Ranges past the end are empty.
+func sliceRange[T any](content []T, offset, length int) []T {
+	if offset >= len(content) {
+		return content[:0]
+	}
+	// Compared to what is left, as offset+length may overflow
+	if length <= 0 || length > len(content)-offset {
+		length = len(content) - offset
+	}
+	return content[offset : offset+length]
+}
+
 // DeleteResource godoc
 // @Summary      Delete a resource
 // @Description  Deletes a resource by its ID for the authenticated user.
@@ -257,6 +598,7 @@ func (c *Controller) GetResourceByID() gin.HandlerFunc {
 // @Param        id    path      string  true   "Resource ID (UUID)"
 // @Success      200   {object}  DeleteResourceResponse
 // @Failure      400   {object}  ErrorResponse  "Invalid user id or resource id"
+// @Failure      403   {object}  ErrorResponse  "Resource belongs to another user"
 // @Failure      404   {object}  ErrorResponse  "Resource not found"
 // @Failure      500   {object}  ErrorResponse  "Internal server error"
 // @Security     ApiKeyAuth
@@ -265,43 +607,94 @@ func (c *Controller) DeleteResource() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userID, ok := controllers.GetUserID(ctx)
 		if !ok {
-			slog.Warn("Invalid user id")
-			c.respondWithError(ctx, http.StatusBadRequest, "Invalid user id")
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
 			return
 		}
 
 		var req DeleteResourceRequest
 		if err := ctx.ShouldBindUri(&req); err != nil {
-			slog.Error("Invalid resource ID format", "error", err)
-			c.respondWithError(ctx, http.StatusBadRequest, "invalid resource ID")
+			slog.ErrorContext(ctx, "Invalid resource ID format", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidResourceID, "invalid resource ID")
 			return
 		}
 
-		slog.Info("Processing delete request",
+		slog.InfoContext(ctx, "Processing delete request",
 			"resource_id", req.ID,
 			"client", ctx.ClientIP())
 
 		if err := c.service.DeleteUsersResource(ctx, userID, req.ID); err != nil {
-			slog.Error("Failed to delete resource",
+			slog.ErrorContext(ctx, "Failed to delete resource",
 				"resource_id", req.ID,
 				"error", err)
-			c.respondWithError(ctx, http.StatusInternalServerError, err.Error())
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
 		response := DeleteResourceResponse{Message: "Resource deleted successfully"}
-		slog.Info("Resource deleted successfully", "resource_id", req.ID)
+		slog.InfoContext(ctx, "Resource deleted successfully", "resource_id", req.ID)
 		ctx.JSON(http.StatusOK, response)
 	}
 }
 
+// DeleteResources godoc
+// @Summary      Delete several resources
+// @Description  Deletes the listed resources of the authenticated user in a single transaction.
+// @Description  When one of them is missing or belongs to another user nothing is deleted; the results tell which resources failed.
+// @Tags         resources
+// @Accept       json
+// @Produce      json
+// @Param        request  body      DeleteResourcesRequest   true  "IDs of the resources to delete"
+// @Success      200      {object}  DeleteResourcesResponse
+// @Failure      400      {object}  ErrorResponse            "Invalid user id or request body"
+// @Failure      403      {object}  DeleteResourcesResponse  "A resource belongs to another user, nothing was deleted"
+// @Failure      404      {object}  DeleteResourcesResponse  "A resource was not found, nothing was deleted"
+// @Failure      500      {object}  ErrorResponse            "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources [delete]
+func (c *Controller) DeleteResources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		req, ok := controllers.ValidateRequest[DeleteResourcesRequest](ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid bulk delete request")
+			return
+		}
+
+		slog.InfoContext(ctx, "Processing bulk delete request",
+			"count", len(req.IDs),
+			"client", ctx.ClientIP())
+
+		results, err := c.service.DeleteUsersResources(ctx, userID, req.IDs)
+		if err != nil && results == nil {
+			slog.ErrorContext(ctx, "Failed to delete resources", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Bulk delete rolled back", "error", err)
+			ctx.JSON(errorStatus(err), DeleteResourcesResponse{Results: results})
+			return
+		}
+
+		slog.InfoContext(ctx, "Resources deleted successfully", "count", len(results))
+		ctx.JSON(http.StatusOK, DeleteResourcesResponse{Results: results})
+	}
+}
+
 // SSE Event Handlers
 func (c *Controller) handleResourceEvent(ctx *gin.Context, resource resourcemodel.Resource, ok bool) bool {
 	if !ok {
 		return false
 	}
 
-	slog.Info("Sending resource", "resource_id", resource.ID)
+	slog.InfoContext(ctx, "Sending resource", "resource_id", resource.ID)
 	event := SSEResourceEvent{Resource: resource}
 	controllers.SendSSEEvent(ctx, "resource", event)
 	return false
@@ -309,11 +702,27 @@ func (c *Controller) handleResourceEvent(ctx *gin.Context, resource resourcemode
 
 func (c *Controller) handleStatusUpdateEvent(ctx *gin.Context, update resourcemodel.ResourceStatusUpdate, ok bool) bool {
 	if !ok {
-		slog.Debug("Resource channel closed")
+		slog.DebugContext(ctx, "Resource channel closed")
 		return false
 	}
 
-	slog.Info("Sending status update", "resource_id", update.ResourceID, "status", update.Status)
+	if update.IsProgress() {
+		slog.DebugContext(ctx, "Sending progress update", "resource_id", update.ResourceID, "percent", update.Percent)
+		controllers.SendSSEEvent(ctx, "progress", SSEProgressEvent{
+			ResourceID: update.ResourceID,
+			Percent:    update.Percent,
+		})
+		return true
+	}
+
+	if update.Percent == 100 {
+		controllers.SendSSEEvent(ctx, "progress", SSEProgressEvent{
+			ResourceID: update.ResourceID,
+			Percent:    update.Percent,
+		})
+	}
+
+	slog.InfoContext(ctx, "Sending status update", "resource_id", update.ResourceID, "status", update.Status)
 
 	event := SSEStatusUpdateEvent{
 		ResourceID: update.ResourceID,
@@ -330,7 +739,7 @@ func (c *Controller) handleStatusUpdateEvent(ctx *gin.Context, update resourcemo
 
 func (c *Controller) handleErrorEvent(ctx *gin.Context, err error, ok bool) bool {
 	if ok {
-		slog.Error("Resource processing error", "error", err)
+		slog.ErrorContext(ctx, "Resource processing error", "error", err)
 		event := SSEErrorEvent{Error: err.Error()}
 		controllers.SendSSEEvent(ctx, "error", event)
 	}
@@ -338,14 +747,21 @@ func (c *Controller) handleErrorEvent(ctx *gin.Context, err error, ok bool) bool
 }
 
 func (c *Controller) sendCompletionEvent(ctx *gin.Context, id uuid.UUID) {
-	slog.Info("Resource processing completed", "resource_id", id)
+	slog.InfoContext(ctx, "Resource processing completed", "resource_id", id)
 	event := SSECompletionEvent{ResourceID: id}
 	controllers.SendSSEEvent(ctx, "completed", event)
 }
 
-func (c *Controller) respondWithError(ctx *gin.Context, statusCode int, message string) {
-	response := ErrorResponse{Error: message}
-	ctx.JSON(statusCode, response)
+// parsePages parses an optional page range, nil when it is empty
+func parsePages(pages string) (*resourcemodel.PageRange, error) {
+	if pages == "" {
+		return nil, nil
+	}
+	r, err := resourcemodel.ParsePageRange(pages)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
 }
 
 func getPaginationParams(ctx *gin.Context) (limit, offset int) {
@@ -0,0 +1,741 @@
+package resourcecontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/contentextractor"
+)
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		want     int
+		wantCode controllers.ErrorCode
+	}{
+		{"not found", resourcemodel.ErrResourceNotFound, http.StatusNotFound, CodeResourceNotFound},
+		{"not owner", resourcemodel.ErrNotOwner, http.StatusForbidden, CodeNotResourceOwner},
+		{"wrong type", resourcemodel.ErrorWrongType, http.StatusBadRequest, CodeInvalidResourceType},
+		{"incompatible content", resourcemodel.ErrorIncompatibleType, http.StatusBadRequest, CodeIncompatibleContent},
+		{"page range", resourcemodel.ErrorWrongPageRange, http.StatusBadRequest, CodeInvalidPageRange},
+		{"malformed epub", resourcemodel.ErrMalformedEPUB, http.StatusBadRequest, CodeMalformedEPUB},
+		{"protected epub", resourcemodel.ErrProtectedEPUB, http.StatusUnprocessableEntity, CodeProtectedEPUB},
+		{"epub too large", resourcemodel.ErrEPUBTooLarge, http.StatusUnprocessableEntity, CodeEPUBTooLarge},
+		{"unexpected", errors.New("connection refused"), http.StatusInternalServerError, controllers.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Services wrap errors with the failing operation
+			err := fmt.Errorf("Service.DeleteUsersResource: Service.GetUsersResourceByID: %w", tt.err)
+			status, code := serviceError(err)
+			assert.Equal(t, tt.want, status)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.want, errorStatus(err))
+		})
+	}
+}
+
+// errorCode decodes the code of an error response
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) controllers.ErrorCode {
+	t.Helper()
+	var response ErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), rec.Body.String())
+	assert.NotEmpty(t, response.Message)
+	return response.Code
+}
+
+// adminService serves the admin listing; other methods are not used
+type adminService struct {
+	resourceService
+	resources []resourcemodel.Resource
+	called    bool
+}
+
+func (s *adminService) GetAllResources(context.Context, int, int) ([]resourcemodel.Resource, error) {
+	s.called = true
+	return s.resources, nil
+}
+
+func (s *adminService) CountAllResources(context.Context) (int, error) {
+	return len(s.resources), nil
+}
+
+func TestGetAllResources_RequiresAdminRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		roles      []string
+		wantStatus int
+	}{
+		{"admin", []string{"user", controllers.ResourceAdminRole}, http.StatusOK},
+		{"regular user", []string{"user"}, http.StatusForbidden},
+		{"no roles", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &adminService{resources: []resourcemodel.Resource{{Name: "a"}, {Name: "b"}}}
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				reqCtx := context.WithValue(ctx.Request.Context(), controllers.UserRolesKey, tt.roles)
+				ctx.Request = ctx.Request.WithContext(reqCtx)
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/resources", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantStatus == http.StatusOK, service.called)
+			if tt.wantStatus == http.StatusOK {
+				assert.Contains(t, rec.Body.String(), `"count":2`)
+			} else {
+				assert.Equal(t, controllers.CodeForbidden, errorCode(t, rec))
+			}
+		})
+	}
+}
+
+// contentService serves a single resource owned by owner
+type contentService struct {
+	resourceService
+	owner    uuid.UUID
+	resource resourcemodel.Resource
+}
+
+func (s *contentService) GetUsersResourceByID(_ context.Context, userID uuid.UUID, _ uuid.UUID) (resourcemodel.Resource, error) {
+	if userID != s.owner {
+		return resourcemodel.Resource{}, resourcemodel.ErrNotOwner
+	}
+	return s.resource, nil
+}
+
+func TestGetResourceContent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := uuid.New()
+	service := &contentService{
+		owner: owner,
+		resource: resourcemodel.Resource{
+			ID:               uuid.New(),
+			ExtractedContent: "Привет, world",
+			RawContent:       []byte("raw bytes"),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"whole text", owner, "", http.StatusOK, `"content":"Привет, world","offset":0,"length":13,"total":13`},
+		{"text range in characters", owner, "?offset=2&length=4", http.StatusOK, `"content":"ивет","offset":2,"length":4,"total":13`},
+		{"raw range", owner, "?raw=true&offset=4", http.StatusOK, `"raw_content":"Ynl0ZXM=","offset":4,"length":5,"total":9`},
+		{"past the end", owner, "?offset=100", http.StatusOK, `"offset":100,"length":0,"total":13`},
+		{"length up to the largest int", owner, "?offset=2&length=9223372036854775807", http.StatusOK, `"content":"ивет, world","offset":2,"length":11,"total":13`},
+		{"negative offset", owner, "?offset=-1", http.StatusBadRequest, `"code":"INVALID_CONTENT_RANGE"`},
+		{"other user", uuid.New(), "", http.StatusForbidden, `"code":"NOT_RESOURCE_OWNER"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, tt.userID.String())
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			target := "/resources/" + service.resource.ID.String() + "/content" + tt.query
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestSliceRange(t *testing.T) {
+	content := []rune("abcdef")
+
+	tests := []struct {
+		name           string
+		offset, length int
+		want           string
+	}{
+		{"whole content", 0, 0, "abcdef"},
+		{"range", 1, 3, "bcd"},
+		{"range past the end", 4, 10, "ef"},
+		{"offset past the end", 10, 2, ""},
+		{"largest length", 2, math.MaxInt, "cdef"},
+		{"largest offset and length", math.MaxInt, math.MaxInt, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(sliceRange(content, tt.offset, tt.length)))
+		})
+	}
+}
+
+func TestGetResourceTypes_MatchesExtractor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	NewController(nil).RegisterRoutes(router.Group("/"))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/types", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response GetResourceTypesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+
+	listed := make([]contentextractor.DataType, 0, len(response.Types))
+	for _, info := range response.Types {
+		assert.NotEmpty(t, info.Label, info.Type)
+		assert.NotEmpty(t, info.MIMETypes, info.Type)
+		listed = append(listed, contentextractor.DataType(info.Type))
+	}
+	assert.ElementsMatch(t, contentextractor.NewResourceProcessor().SupportedTypes(), listed)
+}
+
+// uploadService records whether an upload reached the service
+type uploadService struct {
+	resourceService
+	called bool
+}
+
+func (s *uploadService) SaveUsersResource(context.Context, uuid.UUID, []byte, resourcemodel.ResourceType, string, string, resourcemodel.ResourcePriority, ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
+	s.called = true
+	return resourcemodel.Resource{}, nil, resourcemodel.ErrorIncompatibleType
+}
+
+func TestUploadSizeLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &Config{MaxTextBytes: 16, MaxPDFBytes: 64, MaxURLBytes: 16}
+	content := func(size int) string {
+		encoded, _ := json.Marshal([]byte(strings.Repeat("a", size)))
+		return string(encoded)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCalled bool
+	}{
+		{"text over its limit", `{"type":"text","content":` + content(32) + `}`,
+			http.StatusRequestEntityTooLarge, `"code":"PAYLOAD_TOO_LARGE","message":"text content exceeds 16 bytes","details":{"limit":16}`, false},
+		{"pdf under its limit", `{"type":"pdf","content":` + content(32) + `}`,
+			http.StatusBadRequest, `"code":"INCOMPATIBLE_CONTENT"`, true},
+		{"body over the largest limit", `{"type":"pdf","content":` + content(100<<10) + `}`,
+			http.StatusRequestEntityTooLarge, `"code":"PAYLOAD_TOO_LARGE","message":"request body is too large","details":{"limit":`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &uploadService{}
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service, config).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/resources/", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+			assert.Equal(t, tt.wantCalled, service.called)
+		})
+	}
+}
+
+// updateService stores a single text resource and records whether an update
+// reached the service
+type updateService struct {
+	resourceService
+	called bool
+}
+
+func (s *updateService) GetUsersResourceByID(_ context.Context, _ uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error) {
+	return resourcemodel.Resource{ID: resourceID, Type: resourcemodel.ResourceTypeText}, nil
+}
+
+func (s *updateService) UpdateUsersResource(_ context.Context, _ uuid.UUID, resourceID uuid.UUID, _ *string, _ *resourcemodel.ResourceType, _ *[]byte) (resourcemodel.Resource, error) {
+	s.called = true
+	return resourcemodel.Resource{ID: resourceID}, nil
+}
+
+func TestUpdateSizeLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &Config{MaxTextBytes: 16, MaxPDFBytes: 64, MaxURLBytes: 16}
+	content := func(size int) string {
+		encoded, _ := json.Marshal([]byte(strings.Repeat("a", size)))
+		return string(encoded)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCalled bool
+	}{
+		{"content over the limit of the stored type", `{"content":` + content(32) + `}`,
+			http.StatusRequestEntityTooLarge, `"message":"text content exceeds 16 bytes"`, false},
+		{"content within the limit of the stored type", `{"content":` + content(8) + `}`,
+			http.StatusOK, `"resource"`, true},
+		{"content within the limit of the new type", `{"type":"pdf","content":` + content(32) + `}`,
+			http.StatusOK, `"resource"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &updateService{}
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service, config).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/resources/"+uuid.NewString(), strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+			assert.Equal(t, tt.wantCalled, service.called)
+		})
+	}
+}
+
+// uploadRecorder records the resource an upload was saved as
+type uploadRecorder struct {
+	resourceService
+	content      []byte
+	resourceType resourcemodel.ResourceType
+	name         string
+}
+
+func (s *uploadRecorder) SaveUsersResource(_ context.Context, _ uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, _ string, _ resourcemodel.ResourcePriority, _ ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
+	s.content, s.resourceType, s.name = content, resourceType, name
+	return resourcemodel.Resource{ID: uuid.New(), Name: name, Type: resourceType}, nil, nil
+}
+
+// dedupService saves resources unless the user already saved the content,
+// like the resource service does
+type dedupService struct {
+	resourceService
+	saved map[string]uuid.UUID
+}
+
+func (s *dedupService) SaveUsersResource(_ context.Context, _ uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, _ string, _ resourcemodel.ResourcePriority, opts ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
+	resource := resourcemodel.Resource{ID: uuid.New(), Name: name, Type: resourceType}
+	for _, opt := range opts {
+		opt(&resource)
+	}
+	hash := resourcemodel.HashContent(content)
+	if existing, ok := s.saved[hash]; ok && !resource.AllowDuplicate {
+		return resourcemodel.Resource{}, nil, &resourcemodel.DuplicateResourceError{ExistingID: existing}
+	}
+	s.saved[hash] = resource.ID
+	return resource, nil, nil
+}
+
+func TestUploadResource_Duplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &dedupService{saved: map[string]uuid.UUID{}}
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	NewController(service, &Config{MaxTextBytes: 64, MaxPDFBytes: 64, MaxURLBytes: 64}).RegisterRoutes(api)
+
+	upload := func(fields map[string]string) *httptest.ResponseRecorder {
+		body, contentType := multipartBody(t, fields, "notes.txt", []byte("the same notes"))
+		req := httptest.NewRequest(http.MethodPost, "/resources/upload", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, upload(nil).Code)
+	var first uuid.UUID
+	for _, id := range service.saved {
+		first = id
+	}
+
+	// The second identical upload points at the first one
+	rec := upload(nil)
+	require.Equal(t, http.StatusConflict, rec.Code, rec.Body.String())
+	var response struct {
+		Code    controllers.ErrorCode    `json:"code"`
+		Details DuplicateResourceDetails `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, CodeDuplicateResource, response.Code)
+	assert.Equal(t, first, response.Details.ExistingResourceID)
+
+	// Forcing it saves a second resource
+	assert.Equal(t, http.StatusOK, upload(map[string]string{"force": "true"}).Code)
+
+	rec = upload(map[string]string{"force": "maybe"})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, controllers.CodeInvalidRequest, errorCode(t, rec))
+}
+
+func TestSaveResource_Duplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &dedupService{saved: map[string]uuid.UUID{}}
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	NewController(service, &Config{MaxTextBytes: 64}).RegisterRoutes(api)
+
+	save := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/resources/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// "dGhlIHNhbWUgbm90ZXM=" is "the same notes"
+	assert.Equal(t, http.StatusOK, save(`{"content":"dGhlIHNhbWUgbm90ZXM=","type":"text"}`))
+	assert.Equal(t, http.StatusConflict, save(`{"content":"dGhlIHNhbWUgbm90ZXM=","type":"text"}`))
+	assert.Equal(t, http.StatusOK, save(`{"content":"dGhlIHNhbWUgbm90ZXM=","type":"text","force":true}`))
+}
+
+func multipartBody(t *testing.T, fields map[string]string, fileName string, file []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for key, value := range fields {
+		require.NoError(t, writer.WriteField(key, value))
+	}
+	if file != nil {
+		part, err := writer.CreateFormFile("file", fileName)
+		require.NoError(t, err)
+		_, err = part.Write(file)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := &Config{MaxTextBytes: 64, MaxPDFBytes: 128, MaxURLBytes: 32}
+	pdf := []byte("%PDF-1.4\n%%EOF")
+
+	tests := []struct {
+		name       string
+		fields     map[string]string
+		fileName   string
+		file       []byte
+		wantStatus int
+		wantCode   controllers.ErrorCode
+		wantType   resourcemodel.ResourceType
+		wantName   string
+	}{
+		{"pdf type detected", nil, "paper.pdf", pdf, http.StatusOK, "", resourcemodel.ResourceTypePDF, "paper.pdf"},
+		{"text type detected", map[string]string{"name": "Notes"}, "notes.md", []byte("# Notes\n\nplain text"), http.StatusOK, "", resourcemodel.ResourceTypeText, "Notes"},
+		{"declared type kept", map[string]string{"type": "url"}, "link.txt", []byte("https://example.com"), http.StatusOK, "", resourcemodel.ResourceTypeURL, "link.txt"},
+		{"undetectable type", nil, "archive.zip", []byte("PK\x03\x04binary"), http.StatusBadRequest, CodeUndetectableFileType, "", ""},
+		{"missing file", map[string]string{"type": "text"}, "", nil, http.StatusBadRequest, controllers.CodeInvalidRequest, "", ""},
+		{"text over its limit", nil, "big.txt", bytes.Repeat([]byte("a"), 100), http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge, "", ""},
+		{"file over the largest limit", map[string]string{"type": "pdf"}, "big.pdf", bytes.Repeat([]byte("a"), 200), http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &uploadRecorder{}
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service, config).RegisterRoutes(api)
+
+			body, contentType := multipartBody(t, tt.fields, tt.fileName, tt.file)
+			req := httptest.NewRequest(http.MethodPost, "/resources/upload", body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.wantType, service.resourceType)
+			assert.Equal(t, tt.wantName, service.name)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.file, service.content)
+			} else {
+				assert.Equal(t, tt.wantCode, errorCode(t, rec))
+			}
+		})
+	}
+}
+
+// listService pages through fixtures of mixed statuses, filtering and sorting
+// them the way the queries do
+type listService struct {
+	resourceService
+	resources []resourcemodel.Resource
+}
+
+func (s *listService) GetUsersResources(_ context.Context, _ uuid.UUID, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	return page(sorted(s.resources, resourcemodel.ResourceSortOrDefault(sort...)), limit, offset), nil
+}
+
+func (s *listService) CountUsersResources(context.Context, uuid.UUID) (int, error) {
+	return len(s.resources), nil
+}
+
+func (s *listService) GetUsersResourcesByStatus(_ context.Context, _ uuid.UUID, status resourcemodel.ResourceStatus, limit, offset int, _ ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	matching, err := s.withStatus(status)
+	return page(matching, limit, offset), err
+}
+
+func (s *listService) CountUsersResourcesByStatus(_ context.Context, _ uuid.UUID, status resourcemodel.ResourceStatus) (int, error) {
+	matching, err := s.withStatus(status)
+	return len(matching), err
+}
+
+func (s *listService) withStatus(status resourcemodel.ResourceStatus) ([]resourcemodel.Resource, error) {
+	if !status.IsValid() {
+		return nil, fmt.Errorf("Service.GetUsersResourcesByStatus: %w", resourcemodel.ErrorWrongStatus)
+	}
+	var matching []resourcemodel.Resource
+	for _, resource := range s.resources {
+		if resource.Status == status {
+			matching = append(matching, resource)
+		}
+	}
+	return matching, nil
+}
+
+func sorted(resources []resourcemodel.Resource, sort resourcemodel.ResourceSort) []resourcemodel.Resource {
+	sorted := slices.Clone(resources)
+	slices.SortStableFunc(sorted, func(a, b resourcemodel.Resource) int {
+		var c int
+		switch sort.Field {
+		case resourcemodel.ResourceSortCreatedAt:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case resourcemodel.ResourceSortUpdatedAt:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		case resourcemodel.ResourceSortName:
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if sort.Order == resourcemodel.SortOrderDesc {
+			return -c
+		}
+		return c
+	})
+	return sorted
+}
+
+func page(resources []resourcemodel.Resource, limit, offset int) []resourcemodel.Resource {
+	if offset >= len(resources) {
+		return nil
+	}
+	return resources[offset:min(offset+limit, len(resources))]
+}
+
+func TestGetResources_StatusFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &listService{resources: []resourcemodel.Resource{
+		{Name: "done", Status: resourcemodel.ResourceStatusCompleted},
+		{Name: "broken", Status: resourcemodel.ResourceStatusFailed},
+		{Name: "indexing", Status: resourcemodel.ResourceStatusProcessing},
+		{Name: "unreadable", Status: resourcemodel.ResourceStatusFailed},
+	}}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		{"all", "", http.StatusOK, []string{"done", "broken", "indexing", "unreadable"}},
+		{"failed", "?status=failed", http.StatusOK, []string{"broken", "unreadable"}},
+		{"processing", "?status=processing", http.StatusOK, []string{"indexing"}},
+		{"unknown status", "?status=archived", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/"+tt.query, nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, CodeInvalidStatus, errorCode(t, rec))
+				return
+			}
+
+			var response GetResourcesResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			names := make([]string, 0, len(response.Resources))
+			for _, resource := range response.Resources {
+				names = append(names, resource.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+			assert.Equal(t, len(tt.wantNames), response.Count)
+		})
+	}
+}
+
+func TestGetResources_Pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &listService{}
+	for i := range 25 {
+		service.resources = append(service.resources, resourcemodel.Resource{
+			Name:   fmt.Sprintf("resource %d", i),
+			Status: resourcemodel.ResourceStatusCompleted,
+		})
+	}
+	service.resources[3].Status = resourcemodel.ResourceStatusFailed
+
+	tests := []struct {
+		name  string
+		query string
+		want  GetResourcesResponse
+	}{
+		{"first page", "?limit=10", GetResourcesResponse{Count: 10, Total: 25, Limit: 10, Offset: 0}},
+		{"last page", "?limit=10&offset=20", GetResourcesResponse{Count: 5, Total: 25, Limit: 10, Offset: 20}},
+		{"past the end", "?limit=10&offset=30", GetResourcesResponse{Count: 0, Total: 25, Limit: 10, Offset: 30}},
+		{"default page", "", GetResourcesResponse{Count: DefaultLimit, Total: 25, Limit: DefaultLimit, Offset: DefaultOffset}},
+		{"filtered by status", "?status=completed&limit=10&offset=20", GetResourcesResponse{Count: 4, Total: 24, Limit: 10, Offset: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/"+tt.query, nil))
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var response GetResourcesResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Len(t, response.Resources, tt.want.Count)
+			response.Resources = nil
+			assert.Equal(t, tt.want, response)
+		})
+	}
+}
+
+func TestGetResources_Sort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return base.AddDate(0, 0, n) }
+	service := &listService{resources: []resourcemodel.Resource{
+		{Name: "beta", CreatedAt: day(1), UpdatedAt: day(5)},
+		{Name: "alpha", CreatedAt: day(3), UpdatedAt: day(4)},
+		{Name: "gamma", CreatedAt: day(2), UpdatedAt: day(6)},
+	}}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		{"default newest first", "", http.StatusOK, []string{"alpha", "gamma", "beta"}},
+		{"created_at asc", "?sort=created_at&order=asc", http.StatusOK, []string{"beta", "gamma", "alpha"}},
+		{"created_at desc", "?sort=created_at&order=desc", http.StatusOK, []string{"alpha", "gamma", "beta"}},
+		{"updated_at asc", "?sort=updated_at&order=asc", http.StatusOK, []string{"alpha", "beta", "gamma"}},
+		{"updated_at desc", "?sort=updated_at&order=desc", http.StatusOK, []string{"gamma", "beta", "alpha"}},
+		{"name asc", "?sort=name&order=asc", http.StatusOK, []string{"alpha", "beta", "gamma"}},
+		{"name desc", "?sort=name&order=desc", http.StatusOK, []string{"gamma", "beta", "alpha"}},
+		{"order defaults to desc", "?sort=name", http.StatusOK, []string{"gamma", "beta", "alpha"}},
+		{"unknown column", "?sort=owner_id", http.StatusBadRequest, nil},
+		{"injected column", "?sort=name%3BDROP%20TABLE%20resources", http.StatusBadRequest, nil},
+		{"unknown order", "?sort=name&order=up", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/"+tt.query, nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, CodeInvalidSort, errorCode(t, rec))
+				return
+			}
+
+			var response GetResourcesResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			names := make([]string, 0, len(response.Resources))
+			for _, resource := range response.Resources {
+				names = append(names, resource.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package resourcecontroller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// Error codes of the resource endpoints
+const (
+	CodeResourceNotFound     controllers.ErrorCode = "RESOURCE_NOT_FOUND"
+	CodeNotResourceOwner     controllers.ErrorCode = "NOT_RESOURCE_OWNER"
+	CodeInvalidResourceID    controllers.ErrorCode = "INVALID_RESOURCE_ID"
+	CodeInvalidResourceType  controllers.ErrorCode = "INVALID_RESOURCE_TYPE"
+	CodeIncompatibleContent  controllers.ErrorCode = "INCOMPATIBLE_CONTENT"
+	CodeInvalidPriority      controllers.ErrorCode = "INVALID_PRIORITY"
+	CodeInvalidStatus        controllers.ErrorCode = "INVALID_STATUS"
+	CodeInvalidSort          controllers.ErrorCode = "INVALID_SORT"
+	CodeInvalidChunking      controllers.ErrorCode = "INVALID_CHUNKING"
+	CodeInvalidPageRange     controllers.ErrorCode = "INVALID_PAGE_RANGE"
+	CodeInvalidVisibility    controllers.ErrorCode = "INVALID_VISIBILITY"
+	CodeInvalidContentRange  controllers.ErrorCode = "INVALID_CONTENT_RANGE"
+	CodeInvalidImportArchive controllers.ErrorCode = "INVALID_IMPORT_ARCHIVE"
+	CodeUndetectableFileType controllers.ErrorCode = "UNDETECTABLE_FILE_TYPE"
+	CodeDuplicateResource    controllers.ErrorCode = "DUPLICATE_RESOURCE"
+	CodeIndexationFinished   controllers.ErrorCode = "INDEXATION_FINISHED"
+	CodeFetchTimeout         controllers.ErrorCode = "FETCH_TIMEOUT"
+	CodeFetchTooLarge        controllers.ErrorCode = "FETCH_TOO_LARGE"
+	CodeFetchTooManyRedirect controllers.ErrorCode = "FETCH_TOO_MANY_REDIRECTS"
+	CodeFetchBlockedHost     controllers.ErrorCode = "FETCH_BLOCKED_HOST"
+	CodeMalformedEPUB        controllers.ErrorCode = "MALFORMED_EPUB"
+	CodeProtectedEPUB        controllers.ErrorCode = "PROTECTED_EPUB"
+	CodeEmptyEPUB            controllers.ErrorCode = "EMPTY_EPUB"
+	CodeEPUBTooLarge         controllers.ErrorCode = "EPUB_TOO_LARGE"
+)
+
+// serviceErrors maps the domain errors services return to the status and code
+// of the response. Errors not listed here are internal.
+var serviceErrors = []struct {
+	err    error
+	status int
+	code   controllers.ErrorCode
+}{
+	{resourcemodel.ErrResourceNotFound, http.StatusNotFound, CodeResourceNotFound},
+	{resourcemodel.ErrNotOwner, http.StatusForbidden, CodeNotResourceOwner},
+	{resourcemodel.ErrDuplicateResource, http.StatusConflict, CodeDuplicateResource},
+	{resourcemodel.ErrIndexationFinished, http.StatusConflict, CodeIndexationFinished},
+	{resourcemodel.ErrorWrongType, http.StatusBadRequest, CodeInvalidResourceType},
+	{resourcemodel.ErrorIncompatibleType, http.StatusBadRequest, CodeIncompatibleContent},
+	{resourcemodel.ErrorWrongPriority, http.StatusBadRequest, CodeInvalidPriority},
+	{resourcemodel.ErrorWrongStatus, http.StatusBadRequest, CodeInvalidStatus},
+	{resourcemodel.ErrorWrongSort, http.StatusBadRequest, CodeInvalidSort},
+	{resourcemodel.ErrorWrongChunking, http.StatusBadRequest, CodeInvalidChunking},
+	{resourcemodel.ErrorWrongPageRange, http.StatusBadRequest, CodeInvalidPageRange},
+	{resourcemodel.ErrorWrongVisibility, http.StatusBadRequest, CodeInvalidVisibility},
+	{resourcemodel.ErrFetchTimeout, http.StatusGatewayTimeout, CodeFetchTimeout},
+	{resourcemodel.ErrFetchTooLarge, http.StatusUnprocessableEntity, CodeFetchTooLarge},
+	{resourcemodel.ErrFetchTooManyRedirects, http.StatusUnprocessableEntity, CodeFetchTooManyRedirect},
+	{resourcemodel.ErrFetchBlockedHost, http.StatusBadRequest, CodeFetchBlockedHost},
+	{resourcemodel.ErrMalformedEPUB, http.StatusBadRequest, CodeMalformedEPUB},
+	{resourcemodel.ErrProtectedEPUB, http.StatusUnprocessableEntity, CodeProtectedEPUB},
+	{resourcemodel.ErrEmptyEPUB, http.StatusUnprocessableEntity, CodeEmptyEPUB},
+	{resourcemodel.ErrEPUBTooLarge, http.StatusUnprocessableEntity, CodeEPUBTooLarge},
+}
+
+// serviceError maps a service error to the HTTP status code and error code
+// reflecting it
+func serviceError(err error) (int, controllers.ErrorCode) {
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			return e.status, e.code
+		}
+	}
+	return http.StatusInternalServerError, controllers.CodeInternal
+}
+
+// errorStatus maps a service error to the HTTP status code reflecting it
+func errorStatus(err error) int {
+	status, _ := serviceError(err)
+	return status
+}
+
+func (c *Controller) respondWithError(ctx *gin.Context, statusCode int, code controllers.ErrorCode, message string) {
+	controllers.RespondWithError(ctx, statusCode, code, message)
+}
+
+// respondWithServiceError responds with the status and code of a service error
+func (c *Controller) respondWithServiceError(ctx *gin.Context, err error) {
+	status, code := serviceError(err)
+
+	var duplicate *resourcemodel.DuplicateResourceError
+	if errors.As(err, &duplicate) {
+		controllers.RespondWithError(ctx, status, code, err.Error(),
+			DuplicateResourceDetails{ExistingResourceID: duplicate.ExistingID})
+		return
+	}
+	c.respondWithError(ctx, status, code, err.Error())
+}
@@ -0,0 +1,203 @@
+package resourcecontroller
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+const (
+	// exportPageSize is the number of resources loaded at once while exporting
+	exportPageSize = 50
+	// exportManifestVersion is the version of the manifest format
+	exportManifestVersion = 1
+	// exportManifestName is the path of the manifest in an export archive
+	exportManifestName = "manifest.json"
+	// exportDir is the directory of the resource files in an export archive
+	exportDir = "resources/"
+	// maxExportNameRunes bounds the part of a file name taken from the resource name
+	maxExportNameRunes = 64
+)
+
+// ExportResources godoc
+// @Summary      Export all resources as a ZIP archive
+// @Description  Streams a ZIP archive holding the raw content of every resource of the authenticated user,
+// @Description  one file per resource under resources/, and a manifest.json describing them.
+// @Tags         resources
+// @Produce      application/zip
+// @Success      200  {file}    file           "ZIP archive"
+// @Failure      400  {object}  ErrorResponse  "Invalid user id"
+// @Failure      500  {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/export [get]
+func (c *Controller) ExportResources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		// The first page is loaded before answering, so that a failing
+		// database still gets a proper error response
+		page, err := c.service.GetUsersResources(ctx, userID, exportPageSize, 0)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resources to export", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		slog.InfoContext(ctx, "Exporting resources", "client", ctx.ClientIP())
+
+		ctx.Header("Content-Type", "application/zip")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="resources-%s.zip"`, time.Now().UTC().Format("20060102")))
+		ctx.Status(http.StatusOK)
+
+		archive := zip.NewWriter(ctx.Writer)
+		manifest := ExportManifest{
+			Version:    exportManifestVersion,
+			ExportedAt: time.Now().UTC(),
+			Resources:  []ExportManifestEntry{},
+		}
+		// Resources created while exporting shift the pages, so one may be listed twice
+		exported := make(map[uuid.UUID]struct{})
+
+		for offset := 0; ; {
+			for _, resource := range page {
+				if _, ok := exported[resource.ID]; ok {
+					continue
+				}
+				entry, err := writeExportEntry(archive, resource)
+				if err != nil {
+					// The archive is left without its central directory, so the
+					// client cannot mistake it for a complete one
+					slog.ErrorContext(ctx, "Failed to write exported resource",
+						"resource_id", resource.ID,
+						"error", err)
+					return
+				}
+				exported[resource.ID] = struct{}{}
+				manifest.Resources = append(manifest.Resources, entry)
+			}
+			ctx.Writer.Flush()
+
+			if len(page) < exportPageSize {
+				break
+			}
+			offset += len(page)
+			page, err = c.service.GetUsersResources(ctx, userID, exportPageSize, offset)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to retrieve resources to export",
+					"offset", offset,
+					"error", err)
+				return
+			}
+		}
+
+		if err := writeExportManifest(archive, manifest); err != nil {
+			slog.ErrorContext(ctx, "Failed to write export manifest", "error", err)
+			return
+		}
+		if err := archive.Close(); err != nil {
+			slog.ErrorContext(ctx, "Failed to finish export archive", "error", err)
+			return
+		}
+
+		slog.InfoContext(ctx, "Successfully exported resources", "count", len(manifest.Resources))
+	}
+}
+
+// writeExportEntry adds the raw content of resource to archive and returns
+// its manifest entry
+func writeExportEntry(archive *zip.Writer, resource resourcemodel.Resource) (ExportManifestEntry, error) {
+	entry := ExportManifestEntry{
+		ID:        resource.ID,
+		Name:      resource.Name,
+		Type:      resource.Type,
+		URL:       resource.URL,
+		Status:    resource.Status,
+		File:      exportFileName(resource),
+		CreatedAt: resource.CreatedAt,
+		UpdatedAt: resource.UpdatedAt,
+	}
+
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     entry.File,
+		Method:   zip.Deflate,
+		Modified: resource.UpdatedAt,
+	})
+	if err != nil {
+		return ExportManifestEntry{}, err
+	}
+	if _, err := w.Write(resource.RawContent); err != nil {
+		return ExportManifestEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// writeExportManifest adds manifest to archive as manifest.json
+func writeExportManifest(archive *zip.Writer, manifest ExportManifest) error {
+	w, err := archive.Create(exportManifestName)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
+
+// exportFileName names the file of a resource after its name and ID, so that
+// it is recognizable and unique in the archive
+func exportFileName(resource resourcemodel.Resource) string {
+	var name strings.Builder
+	runes := 0
+	for _, r := range strings.TrimSpace(resource.Name) {
+		if runes == maxExportNameRunes {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_', r == '.':
+			name.WriteRune(r)
+		default:
+			name.WriteRune('_')
+		}
+		runes++
+	}
+
+	base := resource.ID.String()
+	if name.Len() > 0 {
+		base = name.String() + "-" + base
+	}
+	return exportDir + base + exportExtension(resource.Type)
+}
+
+// exportExtension returns the file extension of a resource type
+func exportExtension(resourceType resourcemodel.ResourceType) string {
+	switch resourceType {
+	case resourcemodel.ResourceTypeText:
+		return ".txt"
+	case resourcemodel.ResourceTypePDF:
+		return ".pdf"
+	case resourcemodel.ResourceTypeURL:
+		return ".url"
+	case resourcemodel.ResourceTypeCSV:
+		return ".csv"
+	case resourcemodel.ResourceTypeEPUB:
+		return ".epub"
+	default:
+		return ".bin"
+	}
+}
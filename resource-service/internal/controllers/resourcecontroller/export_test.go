@@ -0,0 +1,123 @@
+package resourcecontroller
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// exportService pages through the resources of owner
+type exportService struct {
+	resourceService
+	owner     uuid.UUID
+	resources []resourcemodel.Resource
+	pages     int
+}
+
+func (s *exportService) GetUsersResources(_ context.Context, userID uuid.UUID, limit, offset int, _ ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	s.pages++
+	if userID != s.owner || offset >= len(s.resources) {
+		return nil, nil
+	}
+	return s.resources[offset:min(offset+limit, len(s.resources))], nil
+}
+
+func TestExportResources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := uuid.New()
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	service := &exportService{owner: owner}
+	for i := range exportPageSize + 3 {
+		service.resources = append(service.resources, resourcemodel.Resource{
+			ID:         uuid.New(),
+			Name:       fmt.Sprintf("notes/%d", i),
+			Type:       resourcemodel.ResourceTypeText,
+			RawContent: []byte(fmt.Sprintf("content %d", i)),
+			Status:     resourcemodel.ResourceStatusCompleted,
+			CreatedAt:  createdAt,
+			UpdatedAt:  createdAt,
+		})
+	}
+
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, owner.String())
+		ctx.Next()
+	})
+	NewController(service).RegisterRoutes(api)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/export", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, 2, service.pages)
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	require.Len(t, files, len(service.resources)+1)
+
+	var manifest ExportManifest
+	require.Contains(t, files, exportManifestName)
+	require.NoError(t, json.Unmarshal(readZipFile(t, files[exportManifestName]), &manifest))
+	assert.Equal(t, exportManifestVersion, manifest.Version)
+	require.Len(t, manifest.Resources, len(service.resources))
+
+	for i, entry := range manifest.Resources {
+		resource := service.resources[i]
+		assert.Equal(t, resource.ID, entry.ID)
+		assert.Equal(t, resource.Name, entry.Name)
+		assert.Equal(t, resource.Type, entry.Type)
+		assert.True(t, resource.CreatedAt.Equal(entry.CreatedAt))
+		assert.Equal(t, fmt.Sprintf("resources/notes_%d-%s.txt", i, resource.ID), entry.File)
+
+		require.Contains(t, files, entry.File)
+		assert.Equal(t, resource.RawContent, readZipFile(t, files[entry.File]))
+	}
+}
+
+func TestExportResources_InvalidUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &exportService{}
+	router := gin.New()
+	NewController(service).RegisterRoutes(router.Group("/"))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/resources/export", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, controllers.CodeInvalidUserID, errorCode(t, rec))
+	assert.Zero(t, service.pages)
+}
+
+func readZipFile(t *testing.T, file *zip.File) []byte {
+	t.Helper()
+	r, err := file.Open()
+	require.NoError(t, err)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return content
+}
@@ -0,0 +1,296 @@
+package resourcecontroller
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// importConcurrency bounds the resources saved at once while importing
+const importConcurrency = 4
+
+// maxImportManifestBytes bounds the decompressed manifest of an import. Its
+// entries are a few hundred bytes each, while a small archive may inflate to
+// gigabytes.
+const maxImportManifestBytes = 8 << 20
+
+// errImportTooLarge is returned when the imported archive exceeds its limit
+var errImportTooLarge = errors.New("archive too large")
+
+// errManifestTooLarge is returned when the manifest of an import exceeds maxImportManifestBytes
+var errManifestTooLarge = fmt.Errorf("%s exceeds %d bytes", exportManifestName, maxImportManifestBytes)
+
+// importJob is a manifest entry ready to be saved
+type importJob struct {
+	entry   ExportManifestEntry
+	name    string
+	content []byte
+}
+
+// ImportResources godoc
+// @Summary      Import resources from an export archive
+// @Description  Recreates the resources of a ZIP archive produced by GET /resources/export for the authenticated user.
+// @Description  Resources are indexed as usual with low priority. Names already taken get a numbered suffix.
+// @Description  Entries of unsupported types, missing files or invalid content are skipped and reported in the results.
+// @Tags         resources
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  true  "ZIP archive produced by the export"
+// @Success      200   {object}  ImportResourcesResponse
+// @Failure      400   {object}  ErrorResponse            "Invalid user id, form, archive or manifest"
+// @Failure      413   {object}  ErrorResponse            "Archive or its manifest exceeds the size limit"
+// @Failure      500   {object}  ErrorResponse            "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/import [post]
+func (c *Controller) ImportResources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		archive, err := c.readImport(ctx.Request)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid import", "error", err)
+			var maxBytesErr *http.MaxBytesError
+			if errors.Is(err, errImportTooLarge) || errors.As(err, &maxBytesErr) {
+				controllers.RespondWithError(ctx, http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge,
+					fmt.Sprintf("archive exceeds %d bytes", c.config.MaxImportBytes),
+					controllers.LimitDetails{Limit: int64(c.config.MaxImportBytes)})
+				return
+			}
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidImportArchive, err.Error())
+			return
+		}
+
+		manifest, err := readImportManifest(archive)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid import manifest", "error", err)
+			if errors.Is(err, errManifestTooLarge) {
+				controllers.RespondWithError(ctx, http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge,
+					err.Error(), controllers.LimitDetails{Limit: maxImportManifestBytes})
+				return
+			}
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidImportArchive, err.Error())
+			return
+		}
+
+		taken, err := c.usersResourceNames(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resource names", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		slog.InfoContext(ctx, "Importing resources",
+			"entries", len(manifest.Resources),
+			"client", ctx.ClientIP())
+
+		files := make(map[string]*zip.File, len(archive.File))
+		for _, file := range archive.File {
+			files[file.Name] = file
+		}
+
+		results := make([]ImportResult, len(manifest.Resources))
+		var group errgroup.Group
+		group.SetLimit(importConcurrency)
+		for i, entry := range manifest.Resources {
+			results[i] = ImportResult{File: entry.File}
+
+			// Names are assigned in manifest order, before saving concurrently
+			job, err := c.prepareImport(entry, files, taken)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Name = job.name
+
+			group.Go(func() error {
+				results[i] = c.importResource(ctx, userID, job)
+				return nil
+			})
+		}
+		_ = group.Wait()
+
+		response := ImportResourcesResponse{Results: results}
+		for _, result := range results {
+			if result.ResourceID != nil {
+				response.Imported++
+			} else {
+				response.Skipped++
+			}
+		}
+
+		slog.InfoContext(ctx, "Successfully imported resources",
+			"imported", response.Imported,
+			"skipped", response.Skipped)
+		ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// readImport reads the archive of an import form, up to the import size limit
+func (c *Controller) readImport(req *http.Request) (*zip.Reader, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("expected a multipart/form-data body: %w", err)
+	}
+
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading form: %w", err)
+		}
+
+		if part.FormName() == "file" {
+			data, err = readPart(part, c.config.MaxImportBytes, errImportTooLarge)
+		}
+		_ = part.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if data == nil {
+		return nil, errors.New("file is missing")
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ZIP archive: %w", err)
+	}
+	return archive, nil
+}
+
+// readImportManifest reads the manifest of an export archive, up to maxImportManifestBytes
+func readImportManifest(archive *zip.Reader) (ExportManifest, error) {
+	idx := slices.IndexFunc(archive.File, func(file *zip.File) bool {
+		return file.Name == exportManifestName
+	})
+	if idx < 0 {
+		return ExportManifest{}, fmt.Errorf("%s is missing from the archive", exportManifestName)
+	}
+	file := archive.File[idx]
+	if file.UncompressedSize64 > maxImportManifestBytes {
+		return ExportManifest{}, errManifestTooLarge
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return ExportManifest{}, fmt.Errorf("reading %s: %w", exportManifestName, err)
+	}
+	defer r.Close()
+	// The declared size is not trusted, so the manifest is bounded while reading too
+	data, err := readPart(r, maxImportManifestBytes, errManifestTooLarge)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+
+	var manifest ExportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ExportManifest{}, fmt.Errorf("invalid %s: %w", exportManifestName, err)
+	}
+	if manifest.Version < 1 || manifest.Version > exportManifestVersion {
+		return ExportManifest{}, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// usersResourceNames returns the names of the resources the user already has
+func (c *Controller) usersResourceNames(ctx *gin.Context, userID uuid.UUID) (map[string]struct{}, error) {
+	names := make(map[string]struct{})
+	for offset := 0; ; offset += exportPageSize {
+		page, err := c.service.GetUsersResources(ctx, userID, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range page {
+			names[resource.Name] = struct{}{}
+		}
+		if len(page) < exportPageSize {
+			return names, nil
+		}
+	}
+}
+
+// prepareImport checks a manifest entry, reads its content and reserves a
+// name not in taken for it
+func (c *Controller) prepareImport(entry ExportManifestEntry, files map[string]*zip.File, taken map[string]struct{}) (importJob, error) {
+	if !entry.Type.IsSupported() {
+		return importJob{}, fmt.Errorf("unsupported resource type %q", entry.Type)
+	}
+
+	file, ok := files[entry.File]
+	if !ok {
+		return importJob{}, fmt.Errorf("file %s is missing from the archive", entry.File)
+	}
+
+	limit := c.config.maxContentBytes(entry.Type)
+	if file.UncompressedSize64 > uint64(limit) {
+		return importJob{}, fmt.Errorf("%s content exceeds %d bytes", entry.Type, limit)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return importJob{}, fmt.Errorf("reading %s: %w", entry.File, err)
+	}
+	defer r.Close()
+	// The declared size is not trusted, so the content is bounded while reading too
+	content, err := readPart(r, limit, fmt.Errorf("%s content exceeds %d bytes", entry.Type, limit))
+	if err != nil {
+		return importJob{}, err
+	}
+
+	name := uniqueName(entry.Name, taken)
+	taken[name] = struct{}{}
+	return importJob{entry: entry, name: name, content: content}, nil
+}
+
+// importResource saves a prepared entry as a new resource of the user
+func (c *Controller) importResource(ctx *gin.Context, userID uuid.UUID, job importJob) ImportResult {
+	result := ImportResult{File: job.entry.File, Name: job.name}
+
+	resource, _, err := c.service.SaveUsersResource(ctx, userID, job.content, job.entry.Type, job.name, job.entry.URL, resourcemodel.ResourcePriorityLow,
+		resourcemodel.WithTenantID(controllers.GetTenantID(ctx)))
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to import resource",
+			"file", job.entry.File,
+			"error", err)
+		result.Error = err.Error()
+		return result
+	}
+	// Nobody streams the status of imported resources
+	c.service.RemoveResourceStatusChannel(resource.ID)
+
+	result.ResourceID = &resource.ID
+	return result
+}
+
+// uniqueName returns name, or name with the lowest numbered suffix not in taken
+func uniqueName(name string, taken map[string]struct{}) string {
+	if _, ok := taken[name]; !ok {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package resourcecontroller
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// importService records the imported resources of a user owning existing ones
+type importService struct {
+	resourceService
+	existing []resourcemodel.Resource
+
+	mu       sync.Mutex
+	saved    map[string][]byte
+	released []uuid.UUID
+}
+
+func (s *importService) GetUsersResources(_ context.Context, _ uuid.UUID, _, offset int, _ ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	if offset >= len(s.existing) {
+		return nil, nil
+	}
+	return s.existing[offset:], nil
+}
+
+func (s *importService) SaveUsersResource(_ context.Context, _ uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, _ string, priority resourcemodel.ResourcePriority, _ ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
+	if priority != resourcemodel.ResourcePriorityLow {
+		return resourcemodel.Resource{}, nil, errors.New("imports are indexed with low priority")
+	}
+	if resourceType == resourcemodel.ResourceTypePDF && !bytes.HasPrefix(content, []byte("%PDF-")) {
+		return resourcemodel.Resource{}, nil, resourcemodel.ErrorIncompatibleType
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		s.saved = make(map[string][]byte)
+	}
+	s.saved[name] = content
+	return resourcemodel.Resource{ID: uuid.New(), Name: name}, nil, nil
+}
+
+func (s *importService) RemoveResourceStatusChannel(resourceID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, resourceID)
+}
+
+// exportArchive builds an export archive of the manifest entries and files
+func exportArchive(t *testing.T, entries []ExportManifestEntry, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writeExportManifest(archive, ExportManifest{Version: exportManifestVersion, Resources: entries}))
+	require.NoError(t, archive.Close())
+	return buf.Bytes()
+}
+
+func TestImportResources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	entries := []ExportManifestEntry{
+		{Name: "Notes", Type: resourcemodel.ResourceTypeText, File: "resources/notes.txt"},
+		{Name: "Notes", Type: resourcemodel.ResourceTypeText, File: "resources/notes-copy.txt"},
+		{Name: "Paper", Type: resourcemodel.ResourceTypePDF, File: "resources/paper.pdf"},
+		{Name: "Image", Type: "image", File: "resources/image.png"},
+		{Name: "Missing", Type: resourcemodel.ResourceTypeText, File: "resources/missing.txt"},
+		{Name: "Broken", Type: resourcemodel.ResourceTypePDF, File: "resources/broken.pdf"},
+	}
+	files := map[string]string{
+		"resources/notes.txt":      "first notes",
+		"resources/notes-copy.txt": "second notes",
+		"resources/paper.pdf":      "%PDF-1.4\n%%EOF",
+		"resources/image.png":      "\x89PNG",
+		"resources/broken.pdf":     "not a pdf",
+	}
+	service := &importService{existing: []resourcemodel.Resource{{Name: "Notes"}}}
+
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	NewController(service).RegisterRoutes(api)
+
+	body, contentType := multipartBody(t, nil, "export.zip", exportArchive(t, entries, files))
+	req := httptest.NewRequest(http.MethodPost, "/resources/import", body)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response ImportResourcesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+
+	assert.Equal(t, 3, response.Imported)
+	assert.Equal(t, 3, response.Skipped)
+	require.Len(t, response.Results, len(entries))
+	assert.Equal(t, "Notes (2)", response.Results[0].Name)
+	assert.Equal(t, "Notes (3)", response.Results[1].Name)
+	assert.Equal(t, "Paper", response.Results[2].Name)
+	assert.Contains(t, response.Results[3].Error, "unsupported resource type")
+	assert.Contains(t, response.Results[4].Error, "missing from the archive")
+	assert.NotEmpty(t, response.Results[5].Error)
+	for i, result := range response.Results {
+		assert.Equal(t, entries[i].File, result.File)
+		assert.Equal(t, result.Error == "", result.ResourceID != nil, result.File)
+	}
+
+	assert.Equal(t, map[string][]byte{
+		"Notes (2)": []byte("first notes"),
+		"Notes (3)": []byte("second notes"),
+		"Paper":     []byte("%PDF-1.4\n%%EOF"),
+	}, service.saved)
+	assert.Len(t, service.released, 3)
+}
+
+func TestImportResources_InvalidArchive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	withoutManifest := func() []byte {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		_, err := archive.Create("resources/notes.txt")
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		return buf.Bytes()
+	}
+
+	// A manifest inflating past its limit from a small archive
+	manifestBomb := func() []byte {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		w, err := archive.Create(exportManifestName)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(`{"version":1,"resources":[]}` + strings.Repeat(" ", maxImportManifestBytes)))
+		require.NoError(t, err)
+		require.NoError(t, archive.Close())
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name       string
+		file       []byte
+		wantStatus int
+		wantCode   controllers.ErrorCode
+	}{
+		{"not a zip", []byte("plain text"), http.StatusBadRequest, CodeInvalidImportArchive},
+		{"manifest over the limit", manifestBomb(), http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge},
+		{"no manifest", withoutManifest(), http.StatusBadRequest, CodeInvalidImportArchive},
+		{"over the limit", bytes.Repeat([]byte("a"), 300), http.StatusRequestEntityTooLarge, controllers.CodePayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &importService{}
+			config := DefaultConfig()
+			config.MaxImportBytes = 256
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service, &config).RegisterRoutes(api)
+
+			body, contentType := multipartBody(t, nil, "export.zip", tt.file)
+			req := httptest.NewRequest(http.MethodPost, "/resources/import", body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.wantCode, errorCode(t, rec))
+			assert.Empty(t, service.saved)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package resourcecontroller
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// defaultStatusPollInterval is how often the status of a resource is read
+// again while no status channel reports it, e.g. after a restart of the service
+const defaultStatusPollInterval = 2 * time.Second
+
+// StreamResourceStatus godoc
+// @Summary      Stream the status of a resource
+// @Description  Resumes watching the indexation of a resource, e.g. after the upload stream was interrupted.
+// @Description  The last known status is sent first, followed by progress and status updates until the resource is completed or failed.
+// @Description  A resource that is already completed or failed ends the stream after its status.
+// @Tags         resources
+// @Produce      text/event-stream
+// @Param        id      path      string  true   "Resource ID (UUID)"
+// @Success      200     {object}  SSEStatusUpdateEvent  "Stream of status_update, progress and completed events"
+// @Failure      400     {object}  ErrorResponse  "Invalid user id or resource id"
+// @Failure      403     {object}  ErrorResponse  "Resource belongs to another user"
+// @Failure      404     {object}  ErrorResponse  "Resource not found"
+// @Failure      500     {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/{id}/status/stream [get]
+func (c *Controller) StreamResourceStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		resourceID, ok := parseResourceID(ctx)
+		if !ok {
+			return
+		}
+
+		resource, err := c.service.GetUsersResourceByID(ctx, userID, resourceID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to retrieve resource",
+				"resource_id", resourceID,
+				"error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		slog.InfoContext(ctx, "Streaming resource status",
+			"resource_id", resource.ID,
+			"status", resource.Status,
+			"client", ctx.ClientIP())
+
+		if !c.sendStatus(ctx, resource.ID, resource.Status) {
+			return
+		}
+		c.streamStatus(ctx, userID, resource)
+	}
+}
+
+// streamStatus forwards the updates of the resource's status channel until a
+// terminal status. Without a channel, or once it closed without a terminal
+// update reaching this stream, the status is read from the database instead.
+func (c *Controller) streamStatus(ctx *gin.Context, userID uuid.UUID, resource resourcemodel.Resource) {
+	ticker := time.NewTicker(c.statusPollInterval)
+	defer ticker.Stop()
+
+	// Only one of updates and poll is set at a time
+	var poll <-chan time.Time
+	updates, ok := c.service.GetResourceStatusChannel(resource.ID)
+	if !ok {
+		slog.DebugContext(ctx, "No status channel, polling resource status", "resource_id", resource.ID)
+		poll = ticker.C
+	}
+
+	last := resource.Status
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				// The final update was dropped or read by the upload stream
+				updates, poll = nil, ticker.C
+				return c.sendStoredStatus(ctx, userID, resource.ID, &last)
+			}
+			if !c.handleStatusUpdateEvent(ctx, update, true) {
+				return false
+			}
+			last = update.Status
+			return !update.Status.IsTerminal()
+		case <-poll:
+			return c.sendStoredStatus(ctx, userID, resource.ID, &last)
+		case <-ctx.Request.Context().Done():
+			slog.WarnContext(ctx, "Client disconnected", "client", ctx.ClientIP())
+			return false
+		}
+	})
+}
+
+// sendStoredStatus sends the status of the resource read from the database
+// when it differs from last. It reports whether the stream goes on.
+func (c *Controller) sendStoredStatus(ctx *gin.Context, userID, resourceID uuid.UUID, last *resourcemodel.ResourceStatus) bool {
+	status, err := c.service.GetUsersResourceStatus(ctx, userID, resourceID)
+	if err != nil {
+		return c.handleErrorEvent(ctx, err, true)
+	}
+	if status == *last {
+		return true
+	}
+
+	*last = status
+	return c.sendStatus(ctx, resourceID, status)
+}
+
+// sendStatus sends a status_update event, followed by the completed event for
+// completed resources. It reports whether the stream goes on.
+func (c *Controller) sendStatus(ctx *gin.Context, resourceID uuid.UUID, status resourcemodel.ResourceStatus) bool {
+	controllers.SendSSEEvent(ctx, "status_update", SSEStatusUpdateEvent{
+		ResourceID: resourceID,
+		Status:     status,
+	})
+
+	if status == resourcemodel.ResourceStatusCompleted {
+		c.sendCompletionEvent(ctx, resourceID)
+	}
+	return !status.IsTerminal()
+}
+
+// CancelResourceIndexation godoc
+// @Summary      Cancel the indexation of a resource
+// @Description  Stops indexing a pending or processing resource and marks it cancelled. Chunks indexed so far are dropped.
+// @Description  Streams watching the resource end with the cancelled status.
+// @Tags         resources
+// @Produce      json
+// @Param        id      path      string  true   "Resource ID (UUID)"
+// @Success      200     {object}  CancelResourceIndexationResponse
+// @Failure      400     {object}  ErrorResponse  "Invalid user id or resource id"
+// @Failure      403     {object}  ErrorResponse  "Resource belongs to another user"
+// @Failure      404     {object}  ErrorResponse  "Resource not found"
+// @Failure      409     {object}  ErrorResponse  "Resource indexation already finished"
+// @Failure      500     {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/{id}/cancel [post]
+func (c *Controller) CancelResourceIndexation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		resourceID, ok := parseResourceID(ctx)
+		if !ok {
+			return
+		}
+
+		resource, err := c.service.CancelUsersResourceIndexation(ctx, userID, resourceID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to cancel resource indexation",
+				"resource_id", resourceID,
+				"error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		slog.InfoContext(ctx, "Cancelled resource indexation", "resource_id", resourceID)
+		ctx.JSON(http.StatusOK, CancelResourceIndexationResponse{Resource: resource})
+	}
+}
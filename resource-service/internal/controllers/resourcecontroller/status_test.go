@@ -0,0 +1,193 @@
+package resourcecontroller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// statusService reports the stored statuses of a resource one read after the
+// other, the last one from then on, and its status channel when set
+type statusService struct {
+	resourceService
+	mu       sync.Mutex
+	statuses []resourcemodel.ResourceStatus
+	statusCh chan resourcemodel.ResourceStatusUpdate
+}
+
+func (s *statusService) GetUsersResourceByID(_ context.Context, _ uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error) {
+	return resourcemodel.Resource{ID: resourceID, Status: s.nextStatus()}, nil
+}
+
+func (s *statusService) GetUsersResourceStatus(context.Context, uuid.UUID, uuid.UUID) (resourcemodel.ResourceStatus, error) {
+	return s.nextStatus(), nil
+}
+
+func (s *statusService) nextStatus() resourcemodel.ResourceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.statuses[0]
+	if len(s.statuses) > 1 {
+		s.statuses = s.statuses[1:]
+	}
+	return status
+}
+
+func (s *statusService) GetResourceStatusChannel(uuid.UUID) (<-chan resourcemodel.ResourceStatusUpdate, bool) {
+	return s.statusCh, s.statusCh != nil
+}
+
+// streamStatus reads the whole status stream of a resource
+func streamStatus(t *testing.T, service *statusService) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	controller := NewController(service)
+	controller.statusPollInterval = time.Millisecond
+	controller.RegisterRoutes(api)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/resources/" + uuid.NewString() + "/status/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+// eventNames lists the names of the events of an SSE stream in order
+func eventNames(stream string) []string {
+	var names []string
+	for _, line := range strings.Split(stream, "\n") {
+		if name, ok := strings.CutPrefix(line, "event:"); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func TestStreamResourceStatus_AlreadyCompleted(t *testing.T) {
+	service := &statusService{statuses: []resourcemodel.ResourceStatus{resourcemodel.ResourceStatusCompleted}}
+
+	stream := streamStatus(t, service)
+
+	assert.Equal(t, []string{"status_update", "completed"}, eventNames(stream))
+	assert.Contains(t, stream, `"status":"completed"`)
+}
+
+func TestStreamResourceStatus_AttachesToStatusChannel(t *testing.T) {
+	service := &statusService{
+		statuses: []resourcemodel.ResourceStatus{resourcemodel.ResourceStatusProcessing},
+		statusCh: make(chan resourcemodel.ResourceStatusUpdate, 2),
+	}
+	service.statusCh <- resourcemodel.ResourceStatusUpdate{Status: resourcemodel.ResourceStatusProcessing, Percent: 50}
+	service.statusCh <- resourcemodel.ResourceStatusUpdate{Status: resourcemodel.ResourceStatusFailed}
+
+	stream := streamStatus(t, service)
+
+	assert.Equal(t, []string{"status_update", "progress", "status_update"}, eventNames(stream))
+	assert.Contains(t, stream, `"percent":50`)
+	assert.Contains(t, stream, `"status":"failed"`)
+}
+
+func TestStreamResourceStatus_ChannelClosedBeforeFinalUpdate(t *testing.T) {
+	service := &statusService{
+		statuses: []resourcemodel.ResourceStatus{resourcemodel.ResourceStatusProcessing, resourcemodel.ResourceStatusCompleted},
+		statusCh: make(chan resourcemodel.ResourceStatusUpdate),
+	}
+	close(service.statusCh)
+
+	stream := streamStatus(t, service)
+
+	assert.Equal(t, []string{"status_update", "status_update", "completed"}, eventNames(stream))
+}
+
+func TestStreamResourceStatus_PollsWithoutChannel(t *testing.T) {
+	service := &statusService{statuses: []resourcemodel.ResourceStatus{
+		resourcemodel.ResourceStatusPending,
+		resourcemodel.ResourceStatusProcessing,
+		resourcemodel.ResourceStatusProcessing,
+		resourcemodel.ResourceStatusCompleted,
+	}}
+
+	stream := streamStatus(t, service)
+
+	// Unchanged statuses are not sent again
+	assert.Equal(t, []string{"status_update", "status_update", "status_update", "completed"}, eventNames(stream))
+	assert.Contains(t, stream, `"status":"pending"`)
+	assert.Contains(t, stream, `"status":"processing"`)
+}
+
+// cancelService cancels the indexation of resources that are still processing
+type cancelService struct {
+	resourceService
+	status resourcemodel.ResourceStatus
+}
+
+func (s *cancelService) CancelUsersResourceIndexation(_ context.Context, _ uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error) {
+	if s.status.IsTerminal() {
+		return resourcemodel.Resource{}, resourcemodel.ErrIndexationFinished
+	}
+	return resourcemodel.Resource{ID: resourceID, Status: resourcemodel.ResourceStatusCancelled}, nil
+}
+
+func TestCancelResourceIndexation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		status     resourcemodel.ResourceStatus
+		resourceID string
+		wantStatus int
+		wantCode   controllers.ErrorCode
+	}{
+		{"processing", resourcemodel.ResourceStatusProcessing, uuid.NewString(), http.StatusOK, ""},
+		{"completed", resourcemodel.ResourceStatusCompleted, uuid.NewString(), http.StatusConflict, CodeIndexationFinished},
+		{"invalid id", resourcemodel.ResourceStatusProcessing, "not-a-uuid", http.StatusBadRequest, CodeInvalidResourceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(&cancelService{status: tt.status}).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/resources/"+tt.resourceID+"/cancel", nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, errorCode(t, rec))
+			} else {
+				assert.Contains(t, rec.Body.String(), `"status":"cancelled"`)
+			}
+		})
+	}
+}
@@ -1,24 +1,37 @@
 package resourcecontroller
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
 )
 
 // SaveResourceRequest represents the payload for creating a resource.
 // swagger:model SaveResourceRequest
 type SaveResourceRequest struct {
-	// Resource content (binary data)
+	// Resource content (binary data); the page address for url resources
 	// Required: true
-	Content []byte `json:"content" binding:"required"`
-	// Resource type (e.g. document, image)
+	Content []byte `json:"content" binding:"required,min=1"`
+	// Resource type: text, pdf, url, csv or epub
 	// Required: true
 	Type string `json:"type" binding:"required"`
 	// Optional resource name
 	Name string `json:"name,omitempty"`
 	// Optional resource URL
 	URL string `json:"url,omitempty"`
+	// Optional indexation priority: high, normal (default) or low
+	Priority string `json:"priority,omitempty" binding:"omitempty,oneof=high normal low"`
+	// Optional chunk size in characters up to 8192, search-service default when omitted
+	ChunkSize int `json:"chunk_size,omitempty" binding:"omitempty,min=1,max=8192"`
+	// Optional overlap of neighbouring chunks in characters, must be smaller than chunk_size
+	ChunkOverlap *int `json:"chunk_overlap,omitempty" binding:"omitempty,min=0"`
+	// Optional page range of a PDF to extract, e.g. "3-10", "3-" or "3"; every page when omitted
+	Pages string `json:"pages,omitempty"`
+	// Save the resource even when the user already has one with the same content
+	Force bool `json:"force,omitempty"`
 }
 
 // UpdateResourceRequest represents the payload for updating a resource.
@@ -27,10 +40,20 @@ type SaveResourceRequest struct {
 type UpdateResourceRequest struct {
 	// New resource name (optional)
 	Name *string `json:"name,omitempty"`
+	// New resource type (optional); changing it re-extracts and re-indexes the resource
+	Type *string `json:"type,omitempty"`
 	// New resource content (optional, binary)
 	Content *[]byte `json:"content,omitempty"`
 }
 
+// UpdateResourceVisibilityRequest represents the payload for changing who finds a resource in their searches.
+// swagger:model UpdateResourceVisibilityRequest
+type UpdateResourceVisibilityRequest struct {
+	// New visibility: private (only the owner) or public (every user)
+	// Required: true
+	Visibility string `json:"visibility" binding:"required"`
+}
+
 // GetResourceByIDRequest represents the URI parameter for getting a resource by ID.
 // swagger:model GetResourceByIDRequest
 type GetResourceByIDRequest struct {
@@ -40,6 +63,17 @@ type GetResourceByIDRequest struct {
 	ID uuid.UUID `uri:"id" binding:"required"`
 }
 
+// GetResourceContentQuery represents the query parameters for getting resource content.
+// swagger:model GetResourceContentQuery
+type GetResourceContentQuery struct {
+	// Return the raw content instead of the extracted text
+	Raw bool `form:"raw"`
+	// Start of the returned part, in characters of the text or bytes of the raw content
+	Offset int `form:"offset" binding:"min=0"`
+	// Maximum size of the returned part, the rest of the content when omitted
+	Length int `form:"length" binding:"min=0"`
+}
+
 // DeleteResourceRequest represents the URI parameter for deleting a resource by ID.
 // swagger:model DeleteResourceRequest
 type DeleteResourceRequest struct {
@@ -49,6 +83,14 @@ type DeleteResourceRequest struct {
 	ID uuid.UUID `uri:"id" binding:"required"`
 }
 
+// DeleteResourcesRequest represents the payload for deleting several resources.
+// swagger:model DeleteResourcesRequest
+type DeleteResourcesRequest struct {
+	// IDs of the resources to delete
+	// Required: true
+	IDs []uuid.UUID `json:"ids" binding:"required,min=1,max=100,unique"`
+}
+
 // SaveResourceResponse represents the response for resource creation.
 // swagger:model SaveResourceResponse
 type SaveResourceResponse struct {
@@ -68,8 +110,28 @@ type UpdateResourceResponse struct {
 type GetResourcesResponse struct {
 	// List of resources
 	Resources []resourcemodel.Resource `json:"resources"`
-	// Total count of resources
+	// Number of resources on this page
 	Count int `json:"count"`
+	// Number of resources across all pages
+	Total int `json:"total"`
+	// Maximum number of resources on a page
+	Limit int `json:"limit"`
+	// Number of resources skipped before this page
+	Offset int `json:"offset"`
+}
+
+// GetResourceTypesResponse represents the supported resource types.
+// swagger:model GetResourceTypesResponse
+type GetResourceTypesResponse struct {
+	// Supported resource types
+	Types []resourcemodel.ResourceTypeInfo `json:"types"`
+}
+
+// GetResourceStatsResponse represents the number of resources of the user by status.
+// swagger:model GetResourceStatsResponse
+type GetResourceStatsResponse struct {
+	// Resource counts
+	Stats resourcemodel.ResourceStats `json:"stats"`
 }
 
 // GetResourceByIDResponse represents the response for getting a resource by ID.
@@ -77,6 +139,39 @@ type GetResourcesResponse struct {
 type GetResourceByIDResponse struct {
 	// The resource
 	Resource resourcemodel.Resource `json:"resource"`
+	// Number of chunks the resource is indexed as
+	ChunkCount int `json:"chunk_count"`
+}
+
+// CancelResourceIndexationResponse represents the response for cancelling the indexation of a resource.
+// swagger:model CancelResourceIndexationResponse
+type CancelResourceIndexationResponse struct {
+	// The resource, now cancelled
+	Resource resourcemodel.Resource `json:"resource"`
+}
+
+// UpdateResourceVisibilityResponse represents the response for changing the visibility of a resource.
+// swagger:model UpdateResourceVisibilityResponse
+type UpdateResourceVisibilityResponse struct {
+	// The resource with its new visibility
+	Resource resourcemodel.Resource `json:"resource"`
+}
+
+// GetResourceContentResponse represents a part of the content of a resource.
+// swagger:model GetResourceContentResponse
+type GetResourceContentResponse struct {
+	// Resource ID (UUID)
+	ResourceID uuid.UUID `json:"resource_id"`
+	// Extracted text, empty when the raw content was requested
+	Content string `json:"content,omitempty"`
+	// Raw content (base64 encoded), only when requested
+	RawContent []byte `json:"raw_content,omitempty"`
+	// Start of the returned part
+	Offset int `json:"offset"`
+	// Size of the returned part
+	Length int `json:"length"`
+	// Size of the whole content
+	Total int `json:"total"`
 }
 
 // DeleteResourceResponse represents the response for resource deletion.
@@ -86,13 +181,16 @@ type DeleteResourceResponse struct {
 	Message string `json:"message"`
 }
 
-// ErrorResponse represents a standard error response.
-// swagger:model ErrorResponse
-type ErrorResponse struct {
-	// Error message
-	Error string `json:"error"`
+// DeleteResourcesResponse represents the per resource results of a bulk deletion.
+// swagger:model DeleteResourcesResponse
+type DeleteResourcesResponse struct {
+	// Result for each requested resource, in request order
+	Results []resourcemodel.DeleteResult `json:"results"`
 }
 
+// ErrorResponse represents a standard error response, see controllers.ErrorResponse.
+type ErrorResponse = controllers.ErrorResponse
+
 // SSEResourceEvent represents an SSE event with a resource payload.
 // swagger:model SSEResourceEvent
 type SSEResourceEvent struct {
@@ -109,6 +207,15 @@ type SSEStatusUpdateEvent struct {
 	Status resourcemodel.ResourceStatus `json:"status"`
 }
 
+// SSEProgressEvent represents an SSE event for resource indexation progress.
+// swagger:model SSEProgressEvent
+type SSEProgressEvent struct {
+	// Resource ID (UUID)
+	ResourceID uuid.UUID `json:"resource_id"`
+	// Percent of indexed chunks
+	Percent int `json:"percent"`
+}
+
 // SSECompletionEvent represents an SSE event for resource completion.
 // swagger:model SSECompletionEvent
 type SSECompletionEvent struct {
@@ -122,3 +229,67 @@ type SSEErrorEvent struct {
 	// Error message
 	Error string `json:"error"`
 }
+
+// ExportManifest describes the resources of an export archive. It is stored
+// in the archive as manifest.json.
+// swagger:model ExportManifest
+type ExportManifest struct {
+	// Version of the manifest format
+	Version int `json:"version"`
+	// Time the archive was created
+	ExportedAt time.Time `json:"exported_at"`
+	// Exported resources, in the order of their files in the archive
+	Resources []ExportManifestEntry `json:"resources"`
+}
+
+// ExportManifestEntry describes a resource of an export archive.
+// swagger:model ExportManifestEntry
+type ExportManifestEntry struct {
+	// Resource ID (UUID)
+	ID uuid.UUID `json:"id"`
+	// Resource name
+	Name string `json:"name"`
+	// Resource type
+	Type resourcemodel.ResourceType `json:"type"`
+	// Resource URL, if any
+	URL string `json:"url,omitempty"`
+	// Indexation status at the time of the export
+	Status resourcemodel.ResourceStatus `json:"status,omitempty"`
+	// Path of the file holding the raw content in the archive
+	File string `json:"file"`
+	// Creation time of the resource
+	CreatedAt time.Time `json:"created_at"`
+	// Last update time of the resource
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ImportResourcesResponse summarizes the import of an export archive.
+// swagger:model ImportResourcesResponse
+type ImportResourcesResponse struct {
+	// Number of resources created
+	Imported int `json:"imported"`
+	// Number of manifest entries skipped
+	Skipped int `json:"skipped"`
+	// Outcome of every manifest entry, in manifest order
+	Results []ImportResult `json:"results"`
+}
+
+// ImportResult is the outcome of importing a manifest entry.
+// swagger:model ImportResult
+type ImportResult struct {
+	// Path of the file in the archive
+	File string `json:"file"`
+	// Name of the created resource, renamed when the original one was taken
+	Name string `json:"name,omitempty"`
+	// ID of the created resource, empty when the entry was skipped
+	ResourceID *uuid.UUID `json:"resource_id,omitempty"`
+	// Reason the entry was skipped
+	Error string `json:"error,omitempty"`
+}
+
+// DuplicateResourceDetails are the details of DUPLICATE_RESOURCE errors.
+// swagger:model DuplicateResourceDetails
+type DuplicateResourceDetails struct {
+	// ID of the resource of the user with the same content
+	ExistingResourceID uuid.UUID `json:"existing_resource_id"`
+}
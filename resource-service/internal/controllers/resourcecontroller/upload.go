@@ -0,0 +1,202 @@
+package resourcecontroller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// maxFieldBytes bounds the text fields of an upload form
+const maxFieldBytes = 1 << 10
+
+// errUploadTooLarge is returned when the uploaded file exceeds the largest content limit
+var errUploadTooLarge = errors.New("upload too large")
+
+// upload is a resource read from a multipart form
+type upload struct {
+	content  []byte
+	fileName string
+	name     string
+	typ      string
+	priority string
+	pages    string
+	force    bool
+}
+
+// UploadResource godoc
+// @Summary      Upload a file as a new resource
+// @Description  Creates a new resource from a multipart/form-data upload. Returns the created resource and status updates via SSE.
+// @Description  The type is detected from the file when omitted. Prefer this endpoint over the JSON one for large files.
+// @Tags         resources
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file      formData  file    true   "Resource file"
+// @Param        name      formData  string  false  "Resource name, the file name when omitted"
+// @Param        type      formData  string  false  "Resource type, detected from the file when omitted"
+// @Param        priority  formData  string  false  "Indexation priority: high, normal (default) or low"
+// @Param        pages     formData  string  false  "Page range of a PDF to extract, e.g. 3-10; every page when omitted"
+// @Param        force     formData  bool    false  "Save the file even when the user already has a resource with the same content"
+// @Success      200       {object}  SSEResourceEvent         "Resource created event (SSE)"
+// @Failure      400       {object}  ErrorResponse            "Invalid user id, form, priority, page range, undetectable type, content not matching the type or malformed EPUB"
+// @Failure      409       {object}  ErrorResponse            "The user already has a resource with the same content, its ID is in the details"
+// @Failure      413       {object}  ErrorResponse            "Content exceeds the size limit of its type"
+// @Failure      422       {object}  ErrorResponse            "EPUB is DRM-protected, has no text or is too large once decompressed"
+// @Failure      500       {object}  ErrorResponse            "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/upload [post]
+func (c *Controller) UploadResource() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		slog.InfoContext(ctx, "Handling upload resource request",
+			"client", ctx.ClientIP(),
+			"content_type", ctx.ContentType())
+
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		form, err := c.readUpload(ctx.Request)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid upload", "error", err)
+			var maxBytesErr *http.MaxBytesError
+			if errors.Is(err, errUploadTooLarge) || errors.As(err, &maxBytesErr) {
+				c.respondTooLarge(ctx, "", c.config.maxContentBytes(""))
+				return
+			}
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+
+		resourceType := resourcemodel.ResourceType(form.typ)
+		if resourceType == "" {
+			detected, ok := resourcemodel.DetectResourceType(form.content)
+			if !ok {
+				c.respondWithError(ctx, http.StatusBadRequest, CodeUndetectableFileType, "cannot detect the type of the file, set type")
+				return
+			}
+			resourceType = detected
+			// Sniffing takes a CSV file for plain text, only its name tells them apart
+			if detected == resourcemodel.ResourceTypeText && strings.EqualFold(path.Ext(form.fileName), ".csv") {
+				resourceType = resourcemodel.ResourceTypeCSV
+			}
+		}
+		if c.contentTooLarge(ctx, resourceType, len(form.content)) {
+			return
+		}
+
+		name := form.name
+		if name == "" {
+			name = form.fileName
+		}
+
+		pages, err := parsePages(form.pages)
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid page range", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		extractCtx, warnings := resourcemodel.WithExtractionWarnings(ctx)
+		resource, statusUpdateCh, err := c.service.SaveUsersResource(extractCtx, userID, form.content, resourceType, name, "", resourcemodel.ResourcePriority(form.priority),
+			resourcemodel.WithPageRange(pages), resourcemodel.WithAllowDuplicate(form.force),
+			resourcemodel.WithTenantID(controllers.GetTenantID(ctx)))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to save uploaded resource", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+		resource.Warnings = warnings.List()
+
+		c.streamResource(ctx, resource, statusUpdateCh)
+	}
+}
+
+// readUpload reads the parts of a multipart upload as they arrive, so that a
+// file over the largest content limit is rejected without reading it whole
+func (c *Controller) readUpload(req *http.Request) (upload, error) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return upload{}, fmt.Errorf("expected a multipart/form-data body: %w", err)
+	}
+
+	var form upload
+	limit := c.config.maxContentBytes("")
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return upload{}, fmt.Errorf("reading form: %w", err)
+		}
+
+		switch part.FormName() {
+		case "file":
+			form.content, err = readPart(part, limit, errUploadTooLarge)
+			form.fileName = part.FileName()
+		case "name":
+			form.name, err = readField(part)
+		case "type":
+			form.typ, err = readField(part)
+		case "priority":
+			form.priority, err = readField(part)
+		case "pages":
+			form.pages, err = readField(part)
+		case "force":
+			form.force, err = readBoolField(part)
+		}
+		_ = part.Close()
+		if err != nil {
+			return upload{}, err
+		}
+	}
+
+	if form.content == nil {
+		return upload{}, errors.New("file is missing")
+	}
+	return form, nil
+}
+
+// readPart reads a form part of at most limit bytes, failing with errTooLarge past it
+func readPart(part io.Reader, limit int, errTooLarge error) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(part, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading form: %w", err)
+	}
+	if len(data) > limit {
+		return nil, errTooLarge
+	}
+	return data, nil
+}
+
+// readField reads a text field of an upload form
+func readField(part *multipart.Part) (string, error) {
+	value, err := readPart(part, maxFieldBytes, fmt.Errorf("field %s is too long", part.FormName()))
+	return string(value), err
+}
+
+// readBoolField reads a boolean field of an upload form
+func readBoolField(part *multipart.Part) (bool, error) {
+	value, err := readField(part)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("field %s must be a boolean", part.FormName())
+	}
+	return b, nil
+}
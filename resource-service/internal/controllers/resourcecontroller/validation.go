@@ -0,0 +1,88 @@
+package resourcecontroller
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// parseResourceID returns the resource ID of the path, responding with
+// CodeInvalidResourceID when it is not a UUID. uuid.UUID does not implement
+// gin's BindUnmarshaler, so the ID cannot be bound with the request.
+func parseResourceID(ctx *gin.Context) (uuid.UUID, bool) {
+	resourceID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid resource ID format", "error", err)
+		controllers.RespondWithError(ctx, http.StatusBadRequest, CodeInvalidResourceID, "invalid resource ID")
+		return uuid.Nil, false
+	}
+	return resourceID, true
+}
+
+// ValidateFields checks the type and, for url resources, that the content is
+// a web address
+func (r *SaveResourceRequest) ValidateFields() []controllers.FieldError {
+	var fields []controllers.FieldError
+	if r.Type != "" {
+		fields = append(fields, validateType(resourcemodel.ResourceType(r.Type), r.Content)...)
+	}
+	if r.URL != "" && !isWebURL(r.URL) {
+		fields = append(fields, controllers.FieldError{Field: "url", Reason: webURLReason})
+	}
+	return fields
+}
+
+// ValidateFields checks the provided type and content like on creation
+func (r *UpdateResourceRequest) ValidateFields() []controllers.FieldError {
+	var fields []controllers.FieldError
+	if r.Content != nil && len(*r.Content) == 0 {
+		fields = append(fields, controllers.FieldError{Field: "content", Reason: "must not be empty"})
+	}
+	if r.Type != nil {
+		var content []byte
+		if r.Content != nil {
+			content = *r.Content
+		}
+		fields = append(fields, validateType(resourcemodel.ResourceType(*r.Type), content)...)
+	}
+	return fields
+}
+
+// webURLReason is the reason of fields that must hold a web address
+const webURLReason = "must be an http or https URL"
+
+// validateType checks that the type is supported and that the content of a
+// url resource is a web address. Empty content is left to the other checks.
+func validateType(resourceType resourcemodel.ResourceType, content []byte) []controllers.FieldError {
+	if !resourceType.IsSupported() {
+		return []controllers.FieldError{{Field: "type", Reason: "must be one of: " + supportedTypes()}}
+	}
+	if resourceType == resourcemodel.ResourceTypeURL && len(content) > 0 && !isWebURL(string(content)) {
+		return []controllers.FieldError{{Field: "content", Reason: webURLReason}}
+	}
+	return nil
+}
+
+// supportedTypes lists the supported resource types for error reasons
+func supportedTypes() string {
+	types := resourcemodel.SupportedResourceTypes()
+	names := make([]string, len(types))
+	for i, info := range types {
+		names[i] = string(info.Type)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isWebURL reports whether s is an absolute http or https URL, as url
+// resources are fetched from the web
+func isWebURL(s string) bool {
+	u, err := url.ParseRequestURI(strings.TrimSpace(s))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
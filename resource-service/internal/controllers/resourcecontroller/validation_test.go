@@ -0,0 +1,176 @@
+package resourcecontroller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+)
+
+// fieldErrorsOf decodes the field errors of a 400 INVALID_REQUEST response
+func fieldErrorsOf(t *testing.T, rec *httptest.ResponseRecorder) []controllers.FieldError {
+	t.Helper()
+	require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	var response struct {
+		Code    controllers.ErrorCode         `json:"code"`
+		Details controllers.ValidationDetails `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response), rec.Body.String())
+	assert.Equal(t, controllers.CodeInvalidRequest, response.Code)
+	return response.Details.Errors
+}
+
+func TestSaveResource_FieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	content := func(s string) string {
+		encoded, _ := json.Marshal([]byte(s))
+		return string(encoded)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []controllers.FieldError
+	}{
+		{"missing content", `{"type":"text"}`,
+			[]controllers.FieldError{{Field: "content", Reason: "is required"}}},
+		{"empty content", `{"type":"text","content":""}`,
+			[]controllers.FieldError{{Field: "content", Reason: "must not be empty"}}},
+		{"missing type", `{"content":` + content("notes") + `}`,
+			[]controllers.FieldError{{Field: "type", Reason: "is required"}}},
+		{"unknown type", `{"type":"video","content":` + content("notes") + `}`,
+			[]controllers.FieldError{{Field: "type", Reason: "must be one of: text, pdf, url, csv, epub"}}},
+		{"url content not a URL", `{"type":"url","content":` + content("not a link") + `}`,
+			[]controllers.FieldError{{Field: "content", Reason: "must be an http or https URL"}}},
+		{"url content with another scheme", `{"type":"url","content":` + content("ftp://example.com/file") + `}`,
+			[]controllers.FieldError{{Field: "content", Reason: "must be an http or https URL"}}},
+		{"invalid url field", `{"type":"text","content":` + content("notes") + `,"url":"example"}`,
+			[]controllers.FieldError{{Field: "url", Reason: "must be an http or https URL"}}},
+		{"unknown priority", `{"type":"text","content":` + content("notes") + `,"priority":"urgent"}`,
+			[]controllers.FieldError{{Field: "priority", Reason: "must be one of: high, normal, low"}}},
+		{"negative chunk overlap", `{"type":"text","content":` + content("notes") + `,"chunk_overlap":-1}`,
+			[]controllers.FieldError{{Field: "chunk_overlap", Reason: "must be at least 0"}}},
+		{"wrong field type", `{"type":"text","content":` + content("notes") + `,"chunk_size":"big"}`,
+			[]controllers.FieldError{{Field: "chunk_size", Reason: "must be a number"}}},
+		{"wrong field type and missing field", `{"type":"text","chunk_size":"big"}`,
+			[]controllers.FieldError{
+				{Field: "chunk_size", Reason: "must be a number"},
+				{Field: "content", Reason: "is required"},
+			}},
+		{"wrong field type among invalid fields", `{"type":"video","chunk_size":"big","priority":"urgent"}`,
+			[]controllers.FieldError{
+				{Field: "chunk_size", Reason: "must be a number"},
+				{Field: "content", Reason: "is required"},
+				{Field: "priority", Reason: "must be one of: high, normal, low"},
+				{Field: "type", Reason: "must be one of: text, pdf, url, csv, epub"},
+			}},
+		{"every field at once", `{"type":"url","url":"example","priority":"urgent"}`,
+			[]controllers.FieldError{
+				{Field: "content", Reason: "is required"},
+				{Field: "priority", Reason: "must be one of: high, normal, low"},
+				{Field: "url", Reason: "must be an http or https URL"},
+			}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &uploadService{}
+
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(service).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/resources/", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.want, fieldErrorsOf(t, rec))
+			assert.False(t, service.called)
+		})
+	}
+}
+
+func TestSaveResource_ValidURLResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &uploadService{}
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	NewController(service).RegisterRoutes(api)
+
+	encoded, _ := json.Marshal([]byte("https://example.com/article"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/resources/",
+		strings.NewReader(`{"type":"url","content":`+string(encoded)+`,"url":"https://example.com/article"}`)))
+
+	// The request passes validation and reaches the service
+	assert.True(t, service.called)
+}
+
+func TestUpdateResource_FieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		body string
+		want []controllers.FieldError
+	}{
+		{"unknown type", `{"type":"video"}`,
+			[]controllers.FieldError{{Field: "type", Reason: "must be one of: text, pdf, url, csv, epub"}}},
+		{"empty content", `{"content":""}`,
+			[]controllers.FieldError{{Field: "content", Reason: "must not be empty"}}},
+		{"url content not a URL", `{"type":"url","content":"bm90IGEgbGluaw=="}`,
+			[]controllers.FieldError{{Field: "content", Reason: "must be an http or https URL"}}},
+		{"wrong field type", `{"name":42}`,
+			[]controllers.FieldError{{Field: "name", Reason: "must be a string"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(&uploadService{}).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/resources/"+uuid.NewString(), strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.want, fieldErrorsOf(t, rec))
+		})
+	}
+}
+
+func TestDeleteResources_FieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	api := router.Group("/", func(ctx *gin.Context) {
+		ctx.Set(controllers.UserIDKey, uuid.NewString())
+		ctx.Next()
+	})
+	NewController(&uploadService{}).RegisterRoutes(api)
+
+	id := uuid.NewString()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/resources/", strings.NewReader(`{"ids":["`+id+`","`+id+`"]}`)))
+
+	// Binding tags name the field by its JSON name
+	assert.Equal(t, []controllers.FieldError{{Field: "ids", Reason: "must not contain duplicates"}}, fieldErrorsOf(t, rec))
+}
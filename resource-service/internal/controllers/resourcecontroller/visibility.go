@@ -0,0 +1,59 @@
+package resourcecontroller
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// UpdateResourceVisibility godoc
+// @Summary      Change the visibility of a resource
+// @Description  Sets who finds the resource in their searches: private resources only by their owner, shared and public ones by every user.
+// @Description  The indexed chunks are relabelled without indexing the resource again.
+// @Tags         resources
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                           true  "Resource ID (UUID)"
+// @Param        request  body      UpdateResourceVisibilityRequest  true  "New visibility"
+// @Success      200      {object}  UpdateResourceVisibilityResponse
+// @Failure      400      {object}  ErrorResponse  "Invalid user id, resource id or visibility"
+// @Failure      403      {object}  ErrorResponse  "Resource belongs to another user"
+// @Failure      404      {object}  ErrorResponse  "Resource not found"
+// @Failure      500      {object}  ErrorResponse  "Internal server error"
+// @Security     ApiKeyAuth
+// @Router       /resources/{id}/visibility [patch]
+func (c *Controller) UpdateResourceVisibility() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, ok := controllers.GetUserID(ctx)
+		if !ok {
+			slog.WarnContext(ctx, "Invalid user id")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidUserID, "Invalid user id")
+			return
+		}
+
+		resourceID, ok := parseResourceID(ctx)
+		if !ok {
+			return
+		}
+
+		req, ok := controllers.ValidateRequest[UpdateResourceVisibilityRequest](ctx)
+		if !ok {
+			return
+		}
+
+		resource, err := c.service.UpdateUsersResourceVisibility(ctx, userID, resourceID, resourcemodel.ResourceVisibility(req.Visibility))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to update resource visibility",
+				"resource_id", resourceID,
+				"error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, UpdateResourceVisibilityResponse{Resource: resource})
+	}
+}
@@ -0,0 +1,70 @@
+package resourcecontroller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nzb3/diploma/resource-service/internal/controllers"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// visibilityService sets the visibility of any resource, validating it like
+// the resource service does
+type visibilityService struct {
+	resourceService
+}
+
+func (s *visibilityService) UpdateUsersResourceVisibility(_ context.Context, _ uuid.UUID, resourceID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error) {
+	if !visibility.IsValid() {
+		return resourcemodel.Resource{}, resourcemodel.ErrorWrongVisibility
+	}
+	return resourcemodel.Resource{ID: resourceID, Visibility: visibility}, nil
+}
+
+func TestUpdateResourceVisibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		resourceID string
+		body       string
+		wantStatus int
+		wantCode   controllers.ErrorCode
+	}{
+		{"public", uuid.NewString(), `{"visibility":"public"}`, http.StatusOK, ""},
+		{"unknown visibility", uuid.NewString(), `{"visibility":"everyone"}`, http.StatusBadRequest, CodeInvalidVisibility},
+		{"shared without teams", uuid.NewString(), `{"visibility":"shared"}`, http.StatusBadRequest, CodeInvalidVisibility},
+		{"missing visibility", uuid.NewString(), `{}`, http.StatusBadRequest, controllers.CodeInvalidRequest},
+		{"invalid id", "not-a-uuid", `{"visibility":"public"}`, http.StatusBadRequest, CodeInvalidResourceID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			api := router.Group("/", func(ctx *gin.Context) {
+				ctx.Set(controllers.UserIDKey, uuid.NewString())
+				ctx.Next()
+			})
+			NewController(&visibilityService{}).RegisterRoutes(api)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, "/resources/"+tt.resourceID+"/visibility", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, errorCode(t, rec))
+			} else {
+				assert.Contains(t, rec.Body.String(), `"visibility":"public"`)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError tells which field of a request body is invalid and why
+type FieldError struct {
+	// Field is the JSON name of the field, with the index of list items, e.g. ids[2]
+	Field string `json:"field"`
+	// Reason is a human-readable description of the problem
+	Reason string `json:"reason"`
+}
+
+// ValidationDetails are the details of INVALID_REQUEST errors caused by
+// invalid fields
+type ValidationDetails struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// FieldValidator is implemented by requests checking their fields beyond what
+// the binding tags express, e.g. one field depending on another
+type FieldValidator interface {
+	ValidateFields() []FieldError
+}
+
+// fieldErrors maps the binding error of the request to the fields it concerns.
+// It reports false for errors not caused by a field, e.g. malformed JSON. Of
+// several fields of the wrong type, only the first is reported.
+func fieldErrors[T any](req *T, err error) ([]FieldError, bool) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:  jsonPath(reflect.TypeFor[T](), fe.StructNamespace()),
+				Reason: reason(fe),
+			})
+		}
+		return fields, true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		fields := []FieldError{{
+			Field:  typeErr.Field,
+			Reason: "must be " + jsonKind(typeErr.Type),
+		}}
+		// Decoding goes on past the wrong type, but binding stops before the
+		// binding tags are checked, so they are checked on the rest of the body
+		if validationErr := binding.Validator.ValidateStruct(req); validationErr != nil {
+			tagFields, _ := fieldErrors(req, validationErr)
+			for _, field := range tagFields {
+				if field.Field != typeErr.Field {
+					fields = append(fields, field)
+				}
+			}
+		}
+		return fields, true
+	}
+
+	return nil, false
+}
+
+// jsonPath converts the struct namespace of a validated field, e.g.
+// "DeleteResourcesRequest.IDs[0]", to the JSON path clients sent, "ids[0]"
+func jsonPath(t reflect.Type, namespace string) string {
+	parts := strings.Split(namespace, ".")[1:]
+	for i, part := range parts {
+		name, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			break
+		}
+		field, ok := t.FieldByName(name)
+		if !ok {
+			break
+		}
+
+		if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			name = tag
+		}
+		parts[i] = name + index
+		t = field.Type
+	}
+	return strings.Join(parts, ".")
+}
+
+// reason describes a failed validation rule to clients
+func reason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if isList(fe.Kind()) && fe.Param() == "1" {
+			return "must not be empty"
+		}
+		if isList(fe.Kind()) {
+			return fmt.Sprintf("must have at least %s items", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if isList(fe.Kind()) {
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "unique":
+		return "must not contain duplicates"
+	case "uuid":
+		return "must be a UUID"
+	case "url", "http_url":
+		return "must be a valid URL"
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+func isList(kind reflect.Kind) bool {
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+// jsonKind names the JSON value a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "a base64 encoded string"
+		}
+		return "an array"
+	case reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -14,6 +14,9 @@ type Event struct {
 	Payload   []byte    `json:"payload"`
 	Sent      bool      `json:"sent"`
 	EventTime time.Time `json:"event_time"`
+	// Headers carry the W3C trace context of the operation that created the
+	// event, so that its delivery joins that trace even when retried later
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 func NewEvent[T any](name, topic string, data T) (Event, error) {
@@ -0,0 +1,95 @@
+package resourcemodel
+
+import "strings"
+
+// ChunkPatch describes how the indexed chunks of a resource change with its
+// content. Unchanged chunks keep their embeddings and only move to their new
+// position, so search-service embeds the added chunks only.
+type ChunkPatch struct {
+	RemovedChunkIDs []string     `json:"removed_chunk_ids"`
+	KeptChunks      []KeptChunk  `json:"kept_chunks"`
+	AddedChunks     []AddedChunk `json:"added_chunks"`
+}
+
+// KeptChunk is an indexed chunk whose content is still part of the resource
+type KeptChunk struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// AddedChunk is a chunk of the new content that has no embedding yet
+type AddedChunk struct {
+	Index   int    `json:"index"`
+	Hash    string `json:"hash"`
+	Content string `json:"content"`
+}
+
+// DiffChunks matches the chunks of the new content against the indexed ones by
+// hash. Chunks are given as their contents and hashes in order. A chunk repeated
+// in the content keeps as many embeddings as it had before. Like a full
+// indexation, which drops repeated chunks, a new chunk whose normalized content
+// repeats a kept or earlier chunk is left out, and the indexes of the patch are
+// positions among the remaining chunks. It reports false when the indexed
+// chunks have no hashes to compare against.
+func DiffChunks(chunkIDs, chunkHashes []string, contents, hashes []string) (ChunkPatch, bool) {
+	if len(chunkIDs) == 0 || len(chunkIDs) != len(chunkHashes) {
+		return ChunkPatch{}, false
+	}
+
+	indexed := make(map[string][]string, len(chunkIDs))
+	for i, hash := range chunkHashes {
+		indexed[hash] = append(indexed[hash], chunkIDs[i])
+	}
+
+	// Kept chunks are matched first, so that a new chunk repeating one that
+	// comes later in the content is left out as well
+	keptIDs := make([]string, len(hashes))
+	seen := make(map[string]struct{}, len(hashes))
+	for i, hash := range hashes {
+		if ids := indexed[hash]; len(ids) > 0 {
+			keptIDs[i] = ids[0]
+			indexed[hash] = ids[1:]
+			seen[normalizeChunk(contents[i])] = struct{}{}
+		}
+	}
+
+	patch := ChunkPatch{
+		RemovedChunkIDs: make([]string, 0),
+		KeptChunks:      make([]KeptChunk, 0, len(hashes)),
+		AddedChunks:     make([]AddedChunk, 0),
+	}
+	index := 0
+	for i, hash := range hashes {
+		if keptIDs[i] != "" {
+			patch.KeptChunks = append(patch.KeptChunks, KeptChunk{ID: keptIDs[i], Index: index, Hash: hash})
+			index++
+			continue
+		}
+
+		normalized := normalizeChunk(contents[i])
+		if _, ok := seen[normalized]; ok {
+			continue
+		}
+		seen[normalized] = struct{}{}
+		patch.AddedChunks = append(patch.AddedChunks, AddedChunk{Index: index, Hash: hash, Content: contents[i]})
+		index++
+	}
+
+	// Keep the indexed order for the chunks left over
+	for i, hash := range chunkHashes {
+		ids := indexed[hash]
+		if len(ids) > 0 && ids[0] == chunkIDs[i] {
+			patch.RemovedChunkIDs = append(patch.RemovedChunkIDs, chunkIDs[i])
+			indexed[hash] = ids[1:]
+		}
+	}
+
+	return patch, true
+}
+
+// normalizeChunk lowercases the chunk and collapses its whitespace, the way
+// search-service compares chunks when it drops repeated ones
+func normalizeChunk(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
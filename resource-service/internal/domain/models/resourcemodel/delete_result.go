@@ -0,0 +1,10 @@
+package resourcemodel
+
+import "github.com/google/uuid"
+
+// DeleteResult is the outcome of deleting one resource of a bulk delete
+type DeleteResult struct {
+	ID      uuid.UUID `json:"id"`
+	Deleted bool      `json:"deleted"`
+	Error   string    `json:"error,omitempty"`
+}
@@ -2,10 +2,61 @@ package resourcemodel
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
 )
 
 var ErrNil = errors.New("received nil")
 
+var (
+	ErrResourceNotFound = errors.New("resource not found")
+	ErrNotOwner         = errors.New("resource belongs to another user")
+	// ErrBulkRolledBack marks resources of a bulk operation that were not
+	// changed because another resource of it failed
+	ErrBulkRolledBack = errors.New("rolled back, another resource failed")
+	// ErrDuplicateResource is returned when the owner already has a resource
+	// with the same content, see DuplicateResourceError
+	ErrDuplicateResource = errors.New("resource with the same content already exists")
+	// ErrIndexationFinished is returned when cancelling the indexation of a
+	// resource that is already completed, failed or cancelled
+	ErrIndexationFinished = errors.New("resource indexation already finished")
+)
+
+// Errors of downloading the page of a url resource
+var (
+	ErrFetchTimeout          = errors.New("page download timed out")
+	ErrFetchTooLarge         = errors.New("page is too large")
+	ErrFetchTooManyRedirects = errors.New("page redirects too many times")
+	// ErrFetchBlockedHost is returned for pages on loopback, private or
+	// link-local addresses, which users must not reach through the service
+	ErrFetchBlockedHost = errors.New("page host is not allowed")
+)
+
+// Errors of extracting the text of an EPUB
+var (
+	ErrMalformedEPUB = errors.New("malformed epub")
+	ErrProtectedEPUB = errors.New("epub is DRM-protected")
+	ErrEmptyEPUB     = errors.New("epub has no text")
+	// ErrEPUBTooLarge is returned for EPUBs decompressing past the size the
+	// extractor reads, which is far more than any book takes
+	ErrEPUBTooLarge = errors.New("epub is too large once decompressed")
+)
+
+// DuplicateResourceError carries the resource an upload duplicates. It
+// matches ErrDuplicateResource.
+type DuplicateResourceError struct {
+	ExistingID uuid.UUID
+}
+
+func (e *DuplicateResourceError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDuplicateResource, e.ExistingID)
+}
+
+func (e *DuplicateResourceError) Is(target error) bool {
+	return target == ErrDuplicateResource
+}
+
 type ResourceValidationError error
 
 var (
@@ -15,4 +66,11 @@ var (
 	ErrorMissingID         ResourceValidationError = errors.New("id is missing")
 	ErrorMissingOwnerID    ResourceValidationError = errors.New("owner is missing")
 	ErrorWrongType         ResourceValidationError = errors.New("type is wrong")
+	ErrorIncompatibleType  ResourceValidationError = errors.New("raw_content is not compatible with type")
+	ErrorWrongPriority     ResourceValidationError = errors.New("priority is wrong")
+	ErrorWrongStatus       ResourceValidationError = errors.New("status is wrong")
+	ErrorWrongSort         ResourceValidationError = errors.New("sort is wrong")
+	ErrorWrongChunking     ResourceValidationError = errors.New("chunk overlap must be smaller than chunk size")
+	ErrorWrongPageRange    ResourceValidationError = errors.New("page range is wrong")
+	ErrorWrongVisibility   ResourceValidationError = errors.New("visibility is wrong")
 )
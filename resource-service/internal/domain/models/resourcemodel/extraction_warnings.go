@@ -0,0 +1,42 @@
+package resourcemodel
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+type extractionWarningsKey struct{}
+
+// ExtractionWarnings collects what content extraction wants the user to know
+// about the extracted content, e.g. that it was recognized from scanned pages
+type ExtractionWarnings struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// WithExtractionWarnings returns a context whose content extractions record
+// their warnings in the returned collector
+func WithExtractionWarnings(ctx context.Context) (context.Context, *ExtractionWarnings) {
+	warnings := &ExtractionWarnings{}
+	return context.WithValue(ctx, extractionWarningsKey{}, warnings), warnings
+}
+
+// AddExtractionWarning records a warning in the collector of ctx, if any
+func AddExtractionWarning(ctx context.Context, warning string) {
+	warnings, ok := ctx.Value(extractionWarningsKey{}).(*ExtractionWarnings)
+	if !ok {
+		return
+	}
+
+	warnings.mu.Lock()
+	defer warnings.mu.Unlock()
+	warnings.warnings = append(warnings.warnings, warning)
+}
+
+// List returns the recorded warnings in the order they were added
+func (w *ExtractionWarnings) List() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.warnings)
+}
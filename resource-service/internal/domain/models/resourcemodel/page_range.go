@@ -0,0 +1,77 @@
+package resourcemodel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageRange selects the pages of a PDF to extract, numbered from 1. A zero
+// Last extends the range to the last page.
+type PageRange struct {
+	First int `json:"first"`
+	Last  int `json:"last,omitempty"`
+}
+
+// ParsePageRange parses a page range written as "3-10", "3-" or a single page "3"
+func ParsePageRange(s string) (PageRange, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+
+	var r PageRange
+	var err error
+	if r.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return PageRange{}, fmt.Errorf("%w: %q", ErrorWrongPageRange, s)
+	}
+	switch {
+	case !isRange:
+		r.Last = r.First
+	case strings.TrimSpace(last) != "":
+		if r.Last, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return PageRange{}, fmt.Errorf("%w: %q", ErrorWrongPageRange, s)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return PageRange{}, err
+	}
+	return r, nil
+}
+
+// Validate checks that the range starts at page 1 or later and does not end
+// before it starts. Whether the pages exist is only known once the document
+// is opened, see Within.
+func (r PageRange) Validate() error {
+	if r.First < 1 || (r.Last != 0 && r.Last < r.First) {
+		return fmt.Errorf("%w: %s", ErrorWrongPageRange, r)
+	}
+	return nil
+}
+
+// Within checks the range against the page count of a document and returns
+// the zero-based indexes of its first and last page
+func (r PageRange) Within(pageCount int) (int, int, error) {
+	if err := r.Validate(); err != nil {
+		return 0, 0, err
+	}
+
+	last := r.Last
+	if last == 0 {
+		last = pageCount
+	}
+	if r.First > pageCount || last > pageCount {
+		return 0, 0, fmt.Errorf("%w: pages %s are out of range, the document has %d pages", ErrorWrongPageRange, r, pageCount)
+	}
+	return r.First - 1, last - 1, nil
+}
+
+// String renders the range the way ParsePageRange reads it
+func (r PageRange) String() string {
+	switch {
+	case r.Last == 0:
+		return fmt.Sprintf("%d-", r.First)
+	case r.Last == r.First:
+		return strconv.Itoa(r.First)
+	default:
+		return fmt.Sprintf("%d-%d", r.First, r.Last)
+	}
+}
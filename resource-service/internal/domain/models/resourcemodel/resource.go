@@ -1,9 +1,15 @@
 package resourcemodel
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
@@ -16,6 +22,8 @@ const (
 	ResourceTypeText ResourceType = "text"
 	ResourceTypePDF  ResourceType = "pdf"
 	ResourceTypeURL  ResourceType = "url"
+	ResourceTypeCSV  ResourceType = "csv"
+	ResourceTypeEPUB ResourceType = "epub"
 )
 
 type ResourceEvent struct {
@@ -32,8 +40,42 @@ type Resource struct {
 	RawContent       []byte         `json:"raw_content"`
 	Status           ResourceStatus `json:"status,omitempty"`
 	OwnerID          uuid.UUID      `json:"owner_id,omitempty"`
-	CreatedAt        time.Time      `json:"created_at"`
-	UpdatedAt        time.Time      `json:"updated_at"`
+	// TenantID is the tenant of the owner when the resource was created, which
+	// selects the collection of its chunks in search-service
+	TenantID string `json:"tenant_id,omitempty"`
+	// Visibility tells who else finds the resource in their searches, new
+	// resources are private
+	Visibility ResourceVisibility `json:"visibility,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	// ChunkIDs are the IDs of the embeddings search-service stored for the resource
+	ChunkIDs []string `json:"-"`
+	// ChunkHashes are the content hashes of the chunks, in the order of ChunkIDs
+	ChunkHashes []string `json:"-"`
+	// ContentHash identifies the raw content, see HashContent. Resources saved
+	// before it was recorded have none.
+	ContentHash string `json:"content_hash,omitempty"`
+	// ChunkSize and ChunkOverlap override search-service chunking whenever the
+	// resource is indexed
+	ChunkSize    int  `json:"chunk_size,omitempty"`
+	ChunkOverlap *int `json:"chunk_overlap,omitempty"`
+	// Pages restricts the extraction of a PDF to a page range, also when its
+	// content is updated
+	Pages *PageRange `json:"pages,omitempty"`
+	// AllowDuplicate saves the resource even when its owner already has one
+	// with the same content. It only applies when the resource is created.
+	AllowDuplicate bool `json:"-"`
+	// Warnings tell the user about the extracted content, e.g. that it was
+	// recognized with OCR. They are not stored and only returned by the
+	// request that extracted the content.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// HashContent returns the hex encoded SHA-256 of raw content, which tells
+// uploads of the same document apart from different ones
+func HashContent(rawContent []byte) string {
+	sum := sha256.Sum256(rawContent)
+	return hex.EncodeToString(sum[:])
 }
 
 func NewResource(opts ...ResourceOption) Resource {
@@ -67,6 +109,11 @@ func (r *Resource) SetStatusCompleted() {
 	r.Status = ResourceStatusCompleted
 }
 
+// ChunkCount returns the number of chunks the resource was indexed as
+func (r *Resource) ChunkCount() int {
+	return len(r.ChunkIDs)
+}
+
 func (r *Resource) Validate(validators ...validator.ValidateFunc[Resource]) error {
 	var err error
 	for _, fn := range validators {
@@ -99,12 +146,77 @@ func (r *Resource) HaveName() error {
 }
 
 func (r *Resource) HaveValidType() error {
+	if !r.Type.IsSupported() {
+		return ErrorWrongType
+	}
+	return nil
+}
+
+// HaveCompatibleContent checks that the raw content can be extracted with the
+// resource type. The content type is sniffed from the leading bytes, so e.g. a
+// PDF declared as text is rejected even when its bytes happen to be valid UTF-8.
+func (r *Resource) HaveCompatibleContent() error {
+	detected := sniffMediaType(r.RawContent)
+
 	switch r.Type {
-	case ResourceTypeText, ResourceTypePDF, ResourceTypeURL:
-		return nil
+	case ResourceTypePDF:
+		if !bytes.HasPrefix(r.RawContent, []byte("%PDF-")) {
+			return r.incompatibleContent(detected)
+		}
+	case ResourceTypeEPUB:
+		if detected != epubMediaType {
+			return r.incompatibleContent(detected)
+		}
+	case ResourceTypeURL:
+		u, err := url.ParseRequestURI(strings.TrimSpace(string(r.RawContent)))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrorIncompatibleType
+		}
+	case ResourceTypeText, ResourceTypeCSV:
+		if !strings.HasPrefix(detected, "text/") || !utf8.Valid(r.RawContent) {
+			return r.incompatibleContent(detected)
+		}
 	default:
 		return ErrorWrongType
 	}
+
+	return nil
+}
+
+// incompatibleContent reports the declared type together with the detected one
+func (r *Resource) incompatibleContent(detected string) error {
+	return fmt.Errorf("%w: declared %s, content looks like %s", ErrorIncompatibleType, r.Type, detected)
+}
+
+// MaxChunkSize is the largest chunk size a resource may override chunking with
+const MaxChunkSize = 8192
+
+// HaveValidChunking checks that a chunking override leaves room for the splitter
+// to advance, i.e. the overlap is smaller than the chunk size, and stays within
+// MaxChunkSize.
+func (r *Resource) HaveValidChunking() error {
+	if r.ChunkSize < 0 || r.ChunkSize > MaxChunkSize {
+		return ErrorWrongChunking
+	}
+	if r.ChunkOverlap == nil {
+		return nil
+	}
+	if *r.ChunkOverlap < 0 || *r.ChunkOverlap >= MaxChunkSize || (r.ChunkSize > 0 && *r.ChunkOverlap >= r.ChunkSize) {
+		return ErrorWrongChunking
+	}
+	return nil
+}
+
+// HaveValidPageRange checks that a page range is well formed and only given
+// for PDF resources
+func (r *Resource) HaveValidPageRange() error {
+	if r.Pages == nil {
+		return nil
+	}
+	if r.Type != ResourceTypePDF {
+		return fmt.Errorf("%w: pages only apply to pdf resources", ErrorWrongPageRange)
+	}
+	return r.Pages.Validate()
 }
 
 func (r *Resource) SetDefaultName() {
@@ -163,3 +275,35 @@ func WithOwnerID(ownerID uuid.UUID) ResourceOption {
 		r.OwnerID = ownerID
 	}
 }
+
+// WithTenantID records the tenant of the owner on the resource
+func WithTenantID(tenantID string) ResourceOption {
+	return func(r *Resource) {
+		r.TenantID = tenantID
+	}
+}
+
+// WithChunking overrides the chunk size and overlap used to index the resource.
+// Zero size and nil overlap keep the search-service defaults.
+func WithChunking(size int, overlap *int) ResourceOption {
+	return func(r *Resource) {
+		r.ChunkSize = size
+		r.ChunkOverlap = overlap
+	}
+}
+
+// WithPageRange restricts the extraction of a PDF resource to pages. A nil
+// range extracts every page.
+func WithPageRange(pages *PageRange) ResourceOption {
+	return func(r *Resource) {
+		r.Pages = pages
+	}
+}
+
+// WithAllowDuplicate saves the resource even when its owner already has one
+// with the same content
+func WithAllowDuplicate(allow bool) ResourceOption {
+	return func(r *Resource) {
+		r.AllowDuplicate = allow
+	}
+}
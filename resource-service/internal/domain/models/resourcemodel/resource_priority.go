@@ -0,0 +1,20 @@
+package resourcemodel
+
+// ResourcePriority is the user requested indexation priority of a resource
+type ResourcePriority string
+
+const (
+	ResourcePriorityHigh   ResourcePriority = "high"
+	ResourcePriorityNormal ResourcePriority = "normal"
+	ResourcePriorityLow    ResourcePriority = "low"
+)
+
+// IsValid reports whether the priority is one of the known values
+func (p ResourcePriority) IsValid() bool {
+	switch p {
+	case ResourcePriorityHigh, ResourcePriorityNormal, ResourcePriorityLow:
+		return true
+	default:
+		return false
+	}
+}
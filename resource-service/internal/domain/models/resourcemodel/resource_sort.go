@@ -0,0 +1,81 @@
+package resourcemodel
+
+import (
+	"fmt"
+)
+
+// ResourceSortField is a column resource listings can be sorted by
+type ResourceSortField string
+
+const (
+	ResourceSortCreatedAt ResourceSortField = "created_at"
+	ResourceSortUpdatedAt ResourceSortField = "updated_at"
+	ResourceSortName      ResourceSortField = "name"
+)
+
+// SortOrder is the direction of a sort
+type SortOrder string
+
+const (
+	SortOrderAsc  SortOrder = "asc"
+	SortOrderDesc SortOrder = "desc"
+)
+
+// ResourceSort is the order of a resource listing. Services treat empty parts
+// as the ones of DefaultResourceSort.
+type ResourceSort struct {
+	Field ResourceSortField
+	Order SortOrder
+}
+
+// DefaultResourceSort sorts the newest resources first
+func DefaultResourceSort() ResourceSort {
+	return ResourceSort{Field: ResourceSortCreatedAt, Order: SortOrderDesc}
+}
+
+// ParseResourceSort reads the sort column and order of a listing request.
+// Empty values fall back to DefaultResourceSort.
+func ParseResourceSort(field, order string) (ResourceSort, error) {
+	sort := ResourceSort{Field: ResourceSortField(field), Order: SortOrder(order)}.orDefault()
+	if err := sort.Validate(); err != nil {
+		return ResourceSort{}, err
+	}
+	return sort, nil
+}
+
+// Validate checks that the sort uses a known column and order
+func (s ResourceSort) Validate() error {
+	switch s.Field {
+	case ResourceSortCreatedAt, ResourceSortUpdatedAt, ResourceSortName:
+	default:
+		return fmt.Errorf("%w: unknown column %q", ErrorWrongSort, s.Field)
+	}
+
+	switch s.Order {
+	case SortOrderAsc, SortOrderDesc:
+	default:
+		return fmt.Errorf("%w: unknown order %q", ErrorWrongSort, s.Order)
+	}
+	return nil
+}
+
+// orDefault fills the empty parts of the sort with the default ones
+func (s ResourceSort) orDefault() ResourceSort {
+	defaults := DefaultResourceSort()
+	if s.Field == "" {
+		s.Field = defaults.Field
+	}
+	if s.Order == "" {
+		s.Order = defaults.Order
+	}
+	return s
+}
+
+// ResourceSortOrDefault returns the first of the optional sorts, or the
+// default one without any
+func ResourceSortOrDefault(sort ...ResourceSort) ResourceSort {
+	if len(sort) == 0 {
+		return DefaultResourceSort()
+	}
+	return sort[0].orDefault()
+}
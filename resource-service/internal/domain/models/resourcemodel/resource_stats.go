@@ -0,0 +1,21 @@
+package resourcemodel
+
+// ResourceStats counts the resources of a user
+type ResourceStats struct {
+	Total int `json:"total"`
+	// ByStatus counts the resources in every known status, zero included
+	ByStatus map[ResourceStatus]int `json:"by_status"`
+}
+
+// NewResourceStats sums up the counts of resources per status
+func NewResourceStats(counts map[ResourceStatus]int) ResourceStats {
+	stats := ResourceStats{ByStatus: make(map[ResourceStatus]int, len(ResourceStatuses))}
+	for _, status := range ResourceStatuses {
+		stats.ByStatus[status] = 0
+	}
+	for status, count := range counts {
+		stats.ByStatus[status] += count
+		stats.Total += count
+	}
+	return stats
+}
@@ -11,9 +11,43 @@ const (
 	ResourceStatusCompleted  ResourceStatus = "completed"
 	ResourceStatusProcessing ResourceStatus = "processing"
 	ResourceStatusFailed     ResourceStatus = "failed"
+	// ResourceStatusCancelled marks a resource whose owner cancelled its indexation
+	ResourceStatusCancelled ResourceStatus = "cancelled"
 )
 
+// ResourceStatuses lists the known statuses in the order of indexation
+var ResourceStatuses = []ResourceStatus{
+	ResourceStatusPending,
+	ResourceStatusProcessing,
+	ResourceStatusCompleted,
+	ResourceStatusFailed,
+	ResourceStatusCancelled,
+}
+
+// IsValid reports whether the status is one of the known values
+func (s ResourceStatus) IsValid() bool {
+	switch s {
+	case ResourceStatusPending, ResourceStatusProcessing, ResourceStatusCompleted, ResourceStatusFailed,
+		ResourceStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminal reports whether indexation of the resource has finished
+func (s ResourceStatus) IsTerminal() bool {
+	return s == ResourceStatusCompleted || s == ResourceStatusFailed || s == ResourceStatusCancelled
+}
+
 type ResourceStatusUpdate struct {
 	ResourceID uuid.UUID      `json:"resource_id"`
 	Status     ResourceStatus `json:"status"`
+	// Percent of indexed chunks, reported while Status is processing
+	Percent int `json:"percent,omitempty"`
+}
+
+// IsProgress reports whether the update carries intermediate indexation progress
+func (u ResourceStatusUpdate) IsProgress() bool {
+	return u.Status == ResourceStatusProcessing
 }
@@ -0,0 +1,84 @@
+package resourcemodel
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ResourceTypeInfo describes a supported resource type for clients
+type ResourceTypeInfo struct {
+	Type  ResourceType `json:"type"`
+	Label string       `json:"label"`
+	// MIMETypes are the media types of the content accepted for the type
+	MIMETypes []string `json:"mime_types"`
+}
+
+// resourceTypes lists every supported resource type. Validation and content
+// extraction accept exactly these types, so a new type is added here first.
+var resourceTypes = []ResourceTypeInfo{
+	{Type: ResourceTypeText, Label: "Text", MIMETypes: []string{"text/plain", "text/markdown"}},
+	{Type: ResourceTypePDF, Label: "PDF document", MIMETypes: []string{"application/pdf"}},
+	{Type: ResourceTypeURL, Label: "Web page", MIMETypes: []string{"text/uri-list"}},
+	{Type: ResourceTypeCSV, Label: "CSV table", MIMETypes: []string{"text/csv"}},
+	{Type: ResourceTypeEPUB, Label: "EPUB e-book", MIMETypes: []string{epubMediaType}},
+}
+
+// SupportedResourceTypes returns the supported resource types in display order
+func SupportedResourceTypes() []ResourceTypeInfo {
+	types := make([]ResourceTypeInfo, len(resourceTypes))
+	for i, info := range resourceTypes {
+		info.MIMETypes = append([]string(nil), info.MIMETypes...)
+		types[i] = info
+	}
+	return types
+}
+
+// IsSupported reports whether the type is one of the supported resource types
+func (t ResourceType) IsSupported() bool {
+	for _, info := range resourceTypes {
+		if info.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectResourceType guesses the resource type from the leading bytes of the
+// content. Content that only looks like some text is taken as text.
+func DetectResourceType(content []byte) (ResourceType, bool) {
+	detected := sniffMediaType(content)
+	for _, info := range resourceTypes {
+		if slices.Contains(info.MIMETypes, detected) {
+			return info.Type, true
+		}
+	}
+	if strings.HasPrefix(detected, "text/") {
+		return ResourceTypeText, true
+	}
+	return "", false
+}
+
+// sniffMediaType returns the media type of the content without parameters
+func sniffMediaType(content []byte) string {
+	if isEPUB(content) {
+		return epubMediaType
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	return mediaType
+}
+
+// epubMediaType is the media type of EPUB e-books
+const epubMediaType = "application/epub+zip"
+
+// isEPUB reports whether the content is an EPUB, a ZIP archive whose first
+// file is an uncompressed mimetype file naming the EPUB media type. Content
+// sniffing takes it for any ZIP archive.
+func isEPUB(content []byte) bool {
+	const signature = "PK\x03\x04"
+	const mimetypeOffset = 30
+	return bytes.HasPrefix(content, []byte(signature)) &&
+		bytes.HasPrefix(content[min(mimetypeOffset, len(content)):], []byte("mimetype"+epubMediaType))
+}
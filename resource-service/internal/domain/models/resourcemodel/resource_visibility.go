@@ -0,0 +1,24 @@
+package resourcemodel
+
+// ResourceVisibility tells who finds a resource in their searches
+type ResourceVisibility string
+
+const (
+	// ResourceVisibilityPrivate resources are only searched by their owner
+	ResourceVisibilityPrivate ResourceVisibility = "private"
+	// ResourceVisibilityShared is reserved for resources shared with a team.
+	// There are no teams yet, so it is not a valid visibility.
+	ResourceVisibilityShared ResourceVisibility = "shared"
+	// ResourceVisibilityPublic resources are open to everyone
+	ResourceVisibilityPublic ResourceVisibility = "public"
+)
+
+// IsValid reports whether the visibility can be given to a resource
+func (v ResourceVisibility) IsValid() bool {
+	switch v {
+	case ResourceVisibilityPrivate, ResourceVisibilityPublic:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package contentextractor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvCancelCheckRows is how many rows are converted between checks of the
+// context, so that a large file stops converting soon after a cancellation
+const csvCancelCheckRows = 1000
+
+var errEmptyCSV = errors.New("csv has no rows")
+
+// extractContentCSV converts a CSV file to a Markdown table with the first row
+// as header. search-service splits tables by row and repeats the header in every
+// chunk, so each chunk keeps the names of its columns. Rows may have fewer or
+// more fields than the header; the header is widened to the widest row.
+func (p *ContentExtractor) extractContentCSV(ctx context.Context, data []byte) (string, error) {
+	const op = "ContentExtractor.extractContentCSV"
+
+	// Spreadsheet exports often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	comma := csvDelimiter(data)
+
+	// The first pass only finds the width of the table, the rows are not kept
+	width, rows, err := csvShape(ctx, data, comma)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if rows == 0 {
+		return "", fmt.Errorf("%s: %w", op, errEmptyCSV)
+	}
+
+	reader := newCSVReader(data, comma)
+	header, err := reader.Read()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var table strings.Builder
+	table.Grow(len(data) + len(data)/4)
+
+	cells := make([]string, width)
+	for i := range cells {
+		if i < len(header) {
+			cells[i] = csvCell(header[i])
+		}
+		if cells[i] == "" {
+			cells[i] = "Column " + strconv.Itoa(i+1)
+		}
+	}
+	writeTableRow(&table, cells)
+	for i := range cells {
+		cells[i] = "---"
+	}
+	writeTableRow(&table, cells)
+
+	for row := 1; ; row++ {
+		if row%csvCancelCheckRows == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		for i := range cells {
+			cells[i] = ""
+			if i < len(record) {
+				cells[i] = csvCell(record[i])
+			}
+		}
+		writeTableRow(&table, cells)
+	}
+
+	return table.String(), nil
+}
+
+// csvShape returns the number of fields of the widest row and the number of rows
+func csvShape(ctx context.Context, data []byte, comma rune) (width, rows int, err error) {
+	reader := newCSVReader(data, comma)
+	for {
+		if rows%csvCancelCheckRows == 0 {
+			if err := ctx.Err(); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return width, rows, nil
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		width = max(width, len(record))
+		rows++
+	}
+}
+
+// newCSVReader reads rows of any width, reusing the record between rows. Stray
+// quotes inside unquoted fields, common in hand-written files, are kept as text.
+func newCSVReader(data []byte, comma rune) *csv.Reader {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return reader
+}
+
+// csvDelimiter guesses the delimiter from the first line: the most frequent of
+// comma, semicolon and tab outside of quotes, comma when there is none
+func csvDelimiter(data []byte) rune {
+	counts := map[rune]int{}
+	quoted := false
+	for _, r := range string(data) {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+		if quoted {
+			continue
+		}
+		if r == '\n' {
+			break
+		}
+		if r == ',' || r == ';' || r == '\t' {
+			counts[r]++
+		}
+	}
+
+	delimiter := ','
+	for _, candidate := range []rune{';', '\t'} {
+		if counts[candidate] > counts[delimiter] {
+			delimiter = candidate
+		}
+	}
+	return delimiter
+}
+
+// csvCell makes a field fit in a table cell: line breaks and runs of spaces
+// become single spaces and pipes are escaped
+func csvCell(field string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(field), " "), "|", `\|`)
+}
+
+func writeTableRow(table *strings.Builder, cells []string) {
+	table.WriteString("| ")
+	table.WriteString(strings.Join(cells, " | "))
+	table.WriteString(" |\n")
+}
@@ -0,0 +1,87 @@
+package contentextractor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtractContent_CSVTable(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{
+			name: "header and rows",
+			csv:  "city,population\nBerlin,3850809\nParis,2102650\n",
+			want: "| city | population |\n| --- | --- |\n| Berlin | 3850809 |\n| Paris | 2102650 |\n",
+		},
+		{
+			name: "quoted fields",
+			csv:  "name,notes\n\"Smith, John\",\"said \"\"hi\"\"\nthen left\"\nDoe,a|b\n",
+			want: "| name | notes |\n| --- | --- |\n| Smith, John | said \"hi\" then left |\n| Doe | a\\|b |\n",
+		},
+		{
+			name: "varying column counts",
+			csv:  "a,b\n1\n1,2,3\n",
+			want: "| a | b | Column 3 |\n| --- | --- | --- |\n| 1 |  |  |\n| 1 | 2 | 3 |\n",
+		},
+		{
+			name: "semicolons and byte order mark",
+			csv:  "\ufeffid;label\n1;one\n",
+			want: "| id | label |\n| --- | --- |\n| 1 | one |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := NewResourceProcessor().ExtractContent(context.Background(), []byte(tt.csv), string(ContentTypeCSV))
+			if err != nil {
+				t.Fatalf("ExtractContent returned error: %v", err)
+			}
+			if content != tt.want {
+				t.Errorf("ExtractContent() =\n%s\nwant\n%s", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractContent_CSVHeaderInLargeFile(t *testing.T) {
+	var csv strings.Builder
+	csv.WriteString("id,name\n")
+	for i := 0; i < 3*csvCancelCheckRows; i++ {
+		csv.WriteString("1,row\n")
+	}
+
+	content, err := NewResourceProcessor().ExtractContent(context.Background(), []byte(csv.String()), string(ContentTypeCSV))
+	if err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) != 3*csvCancelCheckRows+2 {
+		t.Fatalf("got %d lines, want %d", len(lines), 3*csvCancelCheckRows+2)
+	}
+	if lines[0] != "| id | name |" {
+		t.Errorf("header = %q", lines[0])
+	}
+}
+
+func TestExtractContent_CSVCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewResourceProcessor().ExtractContent(ctx, []byte("a,b\n1,2\n"), string(ContentTypeCSV))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestExtractContent_CSVEmpty(t *testing.T) {
+	_, err := NewResourceProcessor().ExtractContent(context.Background(), []byte("\n\n"), string(ContentTypeCSV))
+	if !errors.Is(err, errEmptyCSV) {
+		t.Errorf("expected errEmptyCSV, got %v", err)
+	}
+}
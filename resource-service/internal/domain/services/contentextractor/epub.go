@@ -0,0 +1,247 @@
+package contentextractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown/v2"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// epubMaxEntryBytes bounds the decompressed size of a single file of an EPUB,
+// and epubMaxTotalBytes that of all the files read, so that a crafted archive
+// cannot exhaust the memory
+const (
+	epubMaxEntryBytes = 64 << 20
+	epubMaxTotalBytes = 256 << 20
+)
+
+// epubFontObfuscation are the encryption algorithms EPUBs use to obfuscate
+// embedded fonts, which don't protect the text
+var epubFontObfuscation = []string{
+	"http://www.idpf.org/2008/embedding",
+	"http://ns.adobe.com/pdf/enc#RC",
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// epubArchive reads the files of an EPUB within a budget of decompressed bytes
+type epubArchive struct {
+	files     map[string]*zip.File
+	remaining int64
+}
+
+type epubEncryption struct {
+	EncryptedData []struct {
+		EncryptionMethod struct {
+			Algorithm string `xml:"Algorithm,attr"`
+		}
+		CipherReference struct {
+			URI string `xml:"URI,attr"`
+		} `xml:"CipherData>CipherReference"`
+	} `xml:"EncryptedData"`
+}
+
+// extractContentEPUB converts the chapters of an EPUB to Markdown in reading
+// order, as listed by the spine of its package document. Chapters are separated
+// by blank lines and keep their headings, on which search-service splits.
+func (p *ContentExtractor) extractContentEPUB(ctx context.Context, data []byte) (string, error) {
+	const op = "ContentExtractor.extractContentEPUB"
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %w", op, resourcemodel.ErrMalformedEPUB, err)
+	}
+	epub := &epubArchive{
+		files:     make(map[string]*zip.File, len(archive.File)),
+		remaining: epubMaxTotalBytes,
+	}
+	for _, file := range archive.File {
+		epub.files[file.Name] = file
+	}
+
+	encrypted, err := epub.encryptedFiles()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	packagePath, err := epub.packagePath()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	var pkg epubPackage
+	if err := epub.readXML(packagePath, &pkg); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+
+	var content strings.Builder
+	// A spine listing a chapter more than once would have it read and
+	// converted again each time, so only its first place counts
+	read := make(map[string]bool, len(pkg.Spine))
+	for _, itemRef := range pkg.Spine {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		href, ok := hrefs[itemRef.IDRef]
+		if !ok || itemRef.Linear == "no" {
+			continue
+		}
+		chapterPath, err := epubResolve(packagePath, href)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w: %w", op, resourcemodel.ErrMalformedEPUB, err)
+		}
+		if read[chapterPath] {
+			continue
+		}
+		read[chapterPath] = true
+		if encrypted[chapterPath] {
+			return "", fmt.Errorf("%s: %w", op, resourcemodel.ErrProtectedEPUB)
+		}
+
+		chapter, err := epub.readFile(chapterPath)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		text, err := md.ConvertString(string(chapter))
+		if err != nil {
+			return "", fmt.Errorf("%s: %s: %w", op, chapterPath, err)
+		}
+
+		if text = strings.TrimSpace(text); text != "" {
+			content.WriteString(text)
+			content.WriteString("\n\n")
+		}
+	}
+
+	if content.Len() == 0 {
+		return "", fmt.Errorf("%s: %w", op, resourcemodel.ErrEmptyEPUB)
+	}
+	return content.String(), nil
+}
+
+// encryptedFiles returns the files of the EPUB that are encrypted beyond font
+// obfuscation. An Adobe rights file marks the whole book as protected.
+func (e *epubArchive) encryptedFiles() (map[string]bool, error) {
+	if _, ok := e.files["META-INF/rights.xml"]; ok {
+		return nil, resourcemodel.ErrProtectedEPUB
+	}
+	if _, ok := e.files["META-INF/encryption.xml"]; !ok {
+		return nil, nil
+	}
+
+	var encryption epubEncryption
+	if err := e.readXML("META-INF/encryption.xml", &encryption); err != nil {
+		return nil, err
+	}
+
+	encrypted := make(map[string]bool, len(encryption.EncryptedData))
+	for _, data := range encryption.EncryptedData {
+		if slices.Contains(epubFontObfuscation, data.EncryptionMethod.Algorithm) {
+			continue
+		}
+		// References are relative to the root of the container
+		uri, err := url.PathUnescape(data.CipherReference.URI)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", resourcemodel.ErrMalformedEPUB, err)
+		}
+		encrypted[path.Clean(uri)] = true
+	}
+	return encrypted, nil
+}
+
+// packagePath returns the path of the package document, which the container
+// file points to
+func (e *epubArchive) packagePath() (string, error) {
+	var container epubContainer
+	if err := e.readXML("META-INF/container.xml", &container); err != nil {
+		return "", err
+	}
+	if len(container.Rootfiles) == 0 || container.Rootfiles[0].FullPath == "" {
+		return "", fmt.Errorf("%w: no package document", resourcemodel.ErrMalformedEPUB)
+	}
+	return path.Clean(container.Rootfiles[0].FullPath), nil
+}
+
+// epubResolve resolves the href of a manifest item against the package document
+func epubResolve(packagePath, href string) (string, error) {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(path.Dir(packagePath), ref.Path), nil
+}
+
+func (e *epubArchive) readXML(name string, v any) error {
+	data, err := e.readFile(name)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %s: %w", resourcemodel.ErrMalformedEPUB, name, err)
+	}
+	return nil
+}
+
+// readFile reads a file of the EPUB, up to epubMaxEntryBytes and the bytes left
+// in the budget of the archive
+func (e *epubArchive) readFile(name string) ([]byte, error) {
+	file, ok := e.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", resourcemodel.ErrMalformedEPUB, name)
+	}
+
+	limit := min(int64(epubMaxEntryBytes), e.remaining)
+	// The declared size is checked first, and the read bounded as it may lie
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s", resourcemodel.ErrEPUBTooLarge, name)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", resourcemodel.ErrMalformedEPUB, name, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", resourcemodel.ErrMalformedEPUB, name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s", resourcemodel.ErrEPUBTooLarge, name)
+	}
+	e.remaining -= int64(len(data))
+	return data, nil
+}
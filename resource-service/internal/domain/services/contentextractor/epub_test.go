@@ -0,0 +1,154 @@
+package contentextractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+func TestExtractContent_EPUBChaptersInSpineOrder(t *testing.T) {
+	data, err := os.ReadFile("testdata/two_chapters.epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := NewResourceProcessor().ExtractContent(context.Background(), data, string(ContentTypeEPUB))
+	if err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+
+	// The manifest lists the channels chapter first, the spine reads it second
+	goroutines := strings.Index(content, "# Goroutines")
+	channels := strings.Index(content, "# Channels")
+	if goroutines < 0 || channels < 0 || goroutines > channels {
+		t.Fatalf("chapters missing or out of spine order:\n%s", content)
+	}
+	for _, want := range []string{
+		"A goroutine is a function running *concurrently* with other goroutines.",
+		"## Buffered channels",
+		"Sends block only when the buffer is full.",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content misses %q:\n%s", want, content)
+		}
+	}
+	// The table of contents is not part of the reading order
+	if strings.Contains(content, "Table of contents") {
+		t.Errorf("non-linear item extracted:\n%s", content)
+	}
+}
+
+func TestExtractContent_EPUBMalformed(t *testing.T) {
+	tests := map[string][]byte{
+		"not an archive": []byte("PK\x03\x04 truncated"),
+		"no container": buildEPUB(t, map[string]string{
+			"OEBPS/content.opf": "<package/>",
+		}),
+		"missing chapter": buildEPUB(t, map[string]string{
+			"META-INF/container.xml": `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`,
+			"content.opf":            `<package><manifest><item id="c1" href="c1.xhtml" media-type="application/xhtml+xml"/></manifest><spine><itemref idref="c1"/></spine></package>`,
+		}),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewResourceProcessor().ExtractContent(context.Background(), data, string(ContentTypeEPUB))
+			if !errors.Is(err, resourcemodel.ErrMalformedEPUB) {
+				t.Errorf("expected resourcemodel.ErrMalformedEPUB, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExtractContent_EPUBProtected(t *testing.T) {
+	files := map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf":      `<package><manifest><item id="c1" href="c1.xhtml" media-type="application/xhtml+xml"/></manifest><spine><itemref idref="c1"/></spine></package>`,
+		"OEBPS/c1.xhtml":         "encrypted bytes",
+		"META-INF/encryption.xml": `<encryption xmlns="urn:oasis:names:tc:opendocument:xmlns:container" xmlns:enc="http://www.w3.org/2001/04/xmlenc#">
+			<enc:EncryptedData>
+				<enc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+				<enc:CipherData><enc:CipherReference URI="OEBPS/c1.xhtml"/></enc:CipherData>
+			</enc:EncryptedData>
+		</encryption>`,
+	}
+
+	_, err := NewResourceProcessor().ExtractContent(context.Background(), buildEPUB(t, files), string(ContentTypeEPUB))
+	if !errors.Is(err, resourcemodel.ErrProtectedEPUB) {
+		t.Errorf("expected resourcemodel.ErrProtectedEPUB, got %v", err)
+	}
+}
+
+func TestExtractContent_EPUBRepeatedSpineItem(t *testing.T) {
+	spine := strings.Repeat(`<itemref idref="c1"/>`, 1000)
+	files := map[string]string{
+		"META-INF/container.xml": `<container><rootfiles><rootfile full-path="content.opf"/></rootfiles></container>`,
+		"content.opf":            `<package><manifest><item id="c1" href="c1.xhtml" media-type="application/xhtml+xml"/></manifest><spine>` + spine + `</spine></package>`,
+		"c1.xhtml":               "<html><body><p>Only chapter</p></body></html>",
+	}
+
+	content, err := NewResourceProcessor().ExtractContent(context.Background(), buildEPUB(t, files), string(ContentTypeEPUB))
+	if err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+	if n := strings.Count(content, "Only chapter"); n != 1 {
+		t.Errorf("chapter extracted %d times, want once:\n%s", n, content)
+	}
+}
+
+func TestEPUBArchive_ReadWithinBudget(t *testing.T) {
+	data := buildEPUB(t, map[string]string{
+		"c1.xhtml": strings.Repeat("a", 60),
+		"c2.xhtml": strings.Repeat("b", 60),
+	})
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each file fits in the budget, both together do not
+	epub := &epubArchive{files: make(map[string]*zip.File), remaining: 100}
+	for _, file := range archive.File {
+		epub.files[file.Name] = file
+	}
+	if _, err := epub.readFile("c1.xhtml"); err != nil {
+		t.Fatalf("readFile(c1.xhtml) error = %v", err)
+	}
+	if _, err := epub.readFile("c2.xhtml"); !errors.Is(err, resourcemodel.ErrEPUBTooLarge) {
+		t.Errorf("readFile(c2.xhtml) error = %v, want %v", err, resourcemodel.ErrEPUBTooLarge)
+	}
+}
+
+// buildEPUB returns an EPUB archive of the files after the mimetype file
+func buildEPUB(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	mimetype, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mimetype.Write([]byte("application/epub+zip")); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		file, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
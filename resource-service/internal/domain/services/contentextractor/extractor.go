@@ -8,53 +8,135 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
-	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown/v2"
 	"github.com/gen2brain/go-fitz"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
 )
 
 type DataType string
 
 const (
-	ContentTypeText DataType = "text"
-	ContentTypePDF  DataType = "pdf"
-	ContentTypeURL  DataType = "url"
+	ContentTypeText = DataType(resourcemodel.ResourceTypeText)
+	ContentTypePDF  = DataType(resourcemodel.ResourceTypePDF)
+	ContentTypeURL  = DataType(resourcemodel.ResourceTypeURL)
+	ContentTypeCSV  = DataType(resourcemodel.ResourceTypeCSV)
+	ContentTypeEPUB = DataType(resourcemodel.ResourceTypeEPUB)
 )
 
 var (
 	ErrInvalidContentType = errors.New("invalid content type")
 )
 
-type ContentExtractionFunc func(ctx context.Context, reader io.Reader) (string, error)
+// Extractor extracts the text of the content of one data type
+type Extractor interface {
+	Extract(ctx context.Context, data []byte) (string, error)
+}
+
+// ExtractorFunc adapts a function to an Extractor
+type ExtractorFunc func(ctx context.Context, data []byte) (string, error)
+
+func (f ExtractorFunc) Extract(ctx context.Context, data []byte) (string, error) {
+	return f(ctx, data)
+}
+
+// PageExtractor is an Extractor of paged documents, which can restrict the
+// extraction to a page range
+type PageExtractor interface {
+	Extractor
+	ExtractPages(ctx context.Context, data []byte, pages resourcemodel.PageRange) (string, error)
+}
+
+// Option configures a ContentExtractor
+type Option func(*ContentExtractor)
+
+// WithExtractor registers the extractor of a data type, replacing the built-in
+// one. Only the supported resource types are extracted, see ExtractContent.
+func WithExtractor(dataType DataType, extractor Extractor) Option {
+	return func(p *ContentExtractor) {
+		p.extractors[dataType] = extractor
+	}
+}
 
 type ContentExtractor struct {
-	httpClient *http.Client
+	httpClient  *http.Client
+	fetchConfig FetchConfig
+	extractors  map[DataType]Extractor
+	// ocr recognizes the text of scanned PDF pages, nil disables it
+	ocr       OCR
+	ocrConfig OCRConfig
 }
 
-func NewResourceProcessor() *ContentExtractor {
+// NewResourceProcessor creates a content extractor with the built-in extractor
+// of every data type registered, then applies the options
+func NewResourceProcessor(opts ...Option) *ContentExtractor {
 	slog.Debug("Initializing resource service")
-	client := http.DefaultClient
-	return &ContentExtractor{
-		httpClient: client,
-	}
-}
-
-func (p *ContentExtractor) ExtractContent(ctx context.Context, data []byte, dataType string) (string, error) {
-	switch DataType(dataType) {
-	case ContentTypeURL:
-		url := string(data)
-		return p.extractContentURL(ctx, url)
-	case ContentTypePDF:
-		reader := bytes.NewReader(data)
-		return p.extractContentPDF(ctx, reader)
-	case ContentTypeText:
-		reader := bytes.NewReader(data)
-		return p.extractText(reader)
-	default:
+	p := &ContentExtractor{
+		httpClient:  newFetchClient(DefaultFetchConfig()),
+		fetchConfig: DefaultFetchConfig(),
+	}
+	p.extractors = map[DataType]Extractor{
+		ContentTypeURL: ExtractorFunc(func(ctx context.Context, data []byte) (string, error) {
+			return p.extractContentURL(ctx, string(data))
+		}),
+		ContentTypePDF: pdfExtractor{p},
+		ContentTypeText: ExtractorFunc(func(_ context.Context, data []byte) (string, error) {
+			return p.extractText(bytes.NewReader(data))
+		}),
+		ContentTypeCSV:  ExtractorFunc(p.extractContentCSV),
+		ContentTypeEPUB: ExtractorFunc(p.extractContentEPUB),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ExtractContent extracts the text of data. Only the supported resource types
+// are extracted, even when the extractor knows more. A page range restricts
+// the extraction of a paged document such as a PDF to those pages; it is
+// rejected for other types and when the document has fewer pages.
+func (p *ContentExtractor) ExtractContent(ctx context.Context, data []byte, dataType string, pages ...resourcemodel.PageRange) (string, error) {
+	extractor, ok := p.extractors[DataType(dataType)]
+	if !ok || !resourcemodel.ResourceType(dataType).IsSupported() {
 		return "", ErrInvalidContentType
 	}
+
+	if len(pages) == 0 {
+		return extractor.Extract(ctx, data)
+	}
+
+	paged, ok := extractor.(PageExtractor)
+	if !ok {
+		return "", fmt.Errorf("%w: pages do not apply to %s content", resourcemodel.ErrorWrongPageRange, dataType)
+	}
+	return paged.ExtractPages(ctx, data, pages[0])
+}
+
+// SupportedTypes returns the data types the extractor can extract content from
+func (p *ContentExtractor) SupportedTypes() []DataType {
+	types := make([]DataType, 0, len(p.extractors))
+	for dataType := range p.extractors {
+		types = append(types, dataType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// pdfExtractor extracts PDFs as Markdown, page by page
+type pdfExtractor struct {
+	p *ContentExtractor
+}
+
+func (e pdfExtractor) Extract(ctx context.Context, data []byte) (string, error) {
+	return e.p.extractContentPDF(ctx, bytes.NewReader(data), nil)
+}
+
+func (e pdfExtractor) ExtractPages(ctx context.Context, data []byte, pages resourcemodel.PageRange) (string, error) {
+	return e.p.extractContentPDF(ctx, bytes.NewReader(data), &pages)
 }
 
 func (p *ContentExtractor) extractText(reader io.Reader) (string, error) {
@@ -65,6 +147,9 @@ func (p *ContentExtractor) extractText(reader io.Reader) (string, error) {
 	return string(text), nil
 }
 
+// extractContentURL downloads the page at url as configured with
+// WithFetchConfig and extracts its text, as a PDF when it is one and as HTML
+// otherwise. Failed downloads match the resourcemodel.ErrFetch errors.
 func (p *ContentExtractor) extractContentURL(
 	ctx context.Context,
 	url string,
@@ -76,17 +161,16 @@ func (p *ContentExtractor) extractContentURL(
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
-	defer body.Close()
 
 	if isPDF {
-		content, err := p.extractContentPDF(ctx, body)
+		content, err := p.extractContentPDF(ctx, bytes.NewReader(body), nil)
 		if err != nil {
 			return "", fmt.Errorf("%s: %w", op, err)
 		}
 		return content, nil
 	}
 
-	content, err := p.extractContentHTML(ctx, body)
+	content, err := p.extractContentHTML(ctx, bytes.NewReader(body))
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -103,41 +187,57 @@ func (p *ContentExtractor) extractContentHTML(ctx context.Context, reader io.Rea
 	return string(markdown), nil
 }
 
-func (p *ContentExtractor) loadBodyFromURL(ctx context.Context, url string) (io.ReadCloser, bool, error) {
+// loadBodyFromURL downloads the page at url within the configured timeout,
+// failing when it is larger than the configured limit, and reports whether it
+// is a PDF
+func (p *ContentExtractor) loadBodyFromURL(ctx context.Context, url string) ([]byte, bool, error) {
 	const op = "ContentExtractor.loadBodyFromURL"
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, p.fetchConfig.Timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, false, fmt.Errorf("%s: %w", op, err)
 	}
+	req.Header.Set("User-Agent", p.fetchConfig.UserAgent)
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return nil, false, fmt.Errorf("%s: %w", op, err)
+		return nil, false, fmt.Errorf("%s: %w", op, fetchError(ctx, err))
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		defer resp.Body.Close()
 		return nil, false, fmt.Errorf("%s: HTTP request failed with status code %d", op, resp.StatusCode)
 	}
 
+	// The declared length rejects large pages before downloading them, the
+	// limited body those that don't declare it
+	limit := p.fetchConfig.MaxBodyBytes
+	if resp.ContentLength > limit {
+		return nil, false, fmt.Errorf("%s: %w: page of %d bytes exceeds %d bytes",
+			op, resourcemodel.ErrFetchTooLarge, resp.ContentLength, limit)
+	}
+	body, err := io.ReadAll(newLimitedBody(resp.Body, limit))
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", op, fetchError(ctx, err))
+	}
+
 	contentType := resp.Header.Get("Content-Type")
-	isPDF := contentType == "application/pdf" || strings.HasSuffix(strings.ToLower(url), ".pdf")
+	isPDF := contentType == "application/pdf" || strings.HasSuffix(strings.ToLower(resp.Request.URL.Path), ".pdf")
 
-	return resp.Body, isPDF, nil
+	return body, isPDF, nil
 }
 
-func (p *ContentExtractor) extractContentPDF(ctx context.Context, reader io.Reader) (string, error) {
+func (p *ContentExtractor) extractContentPDF(ctx context.Context, reader io.Reader, pages *resourcemodel.PageRange) (string, error) {
 	const op = "ContentExtractor.extractContentPDF"
 	rawContent, err := io.ReadAll(reader)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	markdown, err := p.pdfToMD(ctx, rawContent)
+	markdown, err := p.pdfToMD(ctx, rawContent, pages)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -145,7 +245,8 @@ func (p *ContentExtractor) extractContentPDF(ctx context.Context, reader io.Read
 	return markdown, nil
 }
 
-func (p *ContentExtractor) pdfToMD(ctx context.Context, rawContent []byte) (string, error) {
+// pdfToMD converts the pages of a PDF to markdown, all of them when pages is nil
+func (p *ContentExtractor) pdfToMD(ctx context.Context, rawContent []byte, pages *resourcemodel.PageRange) (string, error) {
 	const op = "ContentExtractor.PDFToMD"
 
 	doc, err := fitz.NewFromMemory(rawContent)
@@ -154,10 +255,18 @@ func (p *ContentExtractor) pdfToMD(ctx context.Context, rawContent []byte) (stri
 	}
 	defer doc.Close()
 
-	numPages := doc.NumPage()
+	first, last := 0, doc.NumPage()-1
+	if pages != nil {
+		first, last, err = pages.Within(doc.NumPage())
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	var mdContent string
+	var recognized, unrecognized int
 
-	for i := 0; i < numPages; i++ {
+	for i := first; i <= last; i++ {
 		html, err := doc.HTML(i, true)
 		if err != nil {
 			return "", fmt.Errorf("%s: %w", op, err)
@@ -168,8 +277,35 @@ func (p *ContentExtractor) pdfToMD(ctx context.Context, rawContent []byte) (stri
 			return "", fmt.Errorf("%s: %w", op, err)
 		}
 
+		// Scanned pages have no text layer, their text is on the image
+		switch {
+		case !p.isScanned(text):
+		case p.ocrCapped(recognized):
+			unrecognized++
+		default:
+			text, err = p.recognizePage(ctx, doc, i)
+			if err != nil {
+				return "", fmt.Errorf("%s: recognizing page %d: %w", op, i+1, err)
+			}
+			recognized++
+		}
+
 		mdContent += text + "\n\n"
 	}
 
+	if recognized > 0 {
+		slog.InfoContext(ctx, "Recognized scanned PDF pages", "pages", recognized)
+		resourcemodel.AddExtractionWarning(ctx, fmt.Sprintf(
+			"the text of %d scanned page(s) was recognized with OCR and may contain errors", recognized))
+	}
+	if unrecognized > 0 {
+		slog.WarnContext(ctx, "Skipped scanned PDF pages over the OCR limit",
+			"pages", unrecognized,
+			"max_pages", p.ocrConfig.MaxPages)
+		resourcemodel.AddExtractionWarning(ctx, fmt.Sprintf(
+			"%d scanned page(s) over the limit of %d were not recognized with OCR and may miss their text",
+			unrecognized, p.ocrConfig.MaxPages))
+	}
+
 	return mdContent, nil
 }
@@ -2,7 +2,13 @@ package contentextractor
 
 import (
 	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
 )
 
 func TestResourceProcessor_pdfToMD(t *testing.T) {
@@ -31,9 +37,9 @@ startxref
 %%EOF`)
 
 	ctx := context.Background()
-	processor := &ContentExtractor{}
+	processor := NewResourceProcessor()
 
-	md, err := processor.pdfToMD(ctx, pdfData)
+	md, err := processor.pdfToMD(ctx, pdfData, nil)
 	if err != nil {
 		t.Fatalf("pdfToMD вернула ошибку: %v", err)
 	}
@@ -42,3 +48,83 @@ startxref
 		t.Errorf("pdfToMD вернула пустой результат")
 	}
 }
+
+func TestExtractContent_PDFPageRange(t *testing.T) {
+	pdfData, err := os.ReadFile("testdata/three_pages.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := []string{"apples", "bananas", "cherries"}
+
+	tests := []struct {
+		name    string
+		pages   []resourcemodel.PageRange
+		want    []string
+		wantErr bool
+	}{
+		{name: "all pages", want: pages},
+		{name: "single page", pages: []resourcemodel.PageRange{{First: 2, Last: 2}}, want: pages[1:2]},
+		{name: "range", pages: []resourcemodel.PageRange{{First: 2, Last: 3}}, want: pages[1:]},
+		{name: "open range", pages: []resourcemodel.PageRange{{First: 3}}, want: pages[2:]},
+		{name: "past the last page", pages: []resourcemodel.PageRange{{First: 2, Last: 4}}, wantErr: true},
+		{name: "first page past the end", pages: []resourcemodel.PageRange{{First: 4}}, wantErr: true},
+		{name: "reversed", pages: []resourcemodel.PageRange{{First: 3, Last: 1}}, wantErr: true},
+	}
+
+	extractor := NewResourceProcessor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := extractor.ExtractContent(context.Background(), pdfData, string(ContentTypePDF), tt.pages...)
+			if tt.wantErr {
+				if !errors.Is(err, resourcemodel.ErrorWrongPageRange) {
+					t.Fatalf("expected a page range error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, page := range pages {
+				if got, want := strings.Contains(content, page), slices.Contains(tt.want, page); got != want {
+					t.Errorf("text of the %s page extracted: %t, want %t\n%s", page, got, want, content)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractContent_PageRangeOnlyForPDF(t *testing.T) {
+	extractor := NewResourceProcessor()
+
+	_, err := extractor.ExtractContent(context.Background(), []byte("plain text"), string(ContentTypeText), resourcemodel.PageRange{First: 1})
+
+	if !errors.Is(err, resourcemodel.ErrorWrongPageRange) {
+		t.Fatalf("expected a page range error, got %v", err)
+	}
+}
+
+func TestExtractContent_RegisteredExtractor(t *testing.T) {
+	var received []byte
+	extractor := NewResourceProcessor(WithExtractor(ContentTypeText, ExtractorFunc(func(_ context.Context, data []byte) (string, error) {
+		received = data
+		return "custom", nil
+	})))
+
+	content, err := extractor.ExtractContent(context.Background(), []byte("plain text"), string(ContentTypeText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "custom" || string(received) != "plain text" {
+		t.Fatalf("registered extractor not used: got %q for %q", content, received)
+	}
+
+	// The other types keep their built-in extractors
+	table, err := extractor.ExtractContent(context.Background(), []byte("a,b\n1,2\n"), string(ContentTypeCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table, "| a | b |") {
+		t.Fatalf("csv not extracted as a table:\n%s", table)
+	}
+}
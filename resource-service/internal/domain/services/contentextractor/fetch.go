@@ -0,0 +1,164 @@
+package contentextractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"github.com/nzb3/diploma/resource-service/internal/configurator"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// FetchConfig configures how the pages of url resources are downloaded
+type FetchConfig struct {
+	// Timeout bounds the whole download, redirects and body included
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout" validate:"gt=0"`
+	// MaxRedirects is the number of redirects followed before giving up
+	MaxRedirects int `yaml:"max_redirects" mapstructure:"max_redirects" validate:"min=0"`
+	// MaxBodyBytes is the size of the largest page downloaded
+	MaxBodyBytes int64  `yaml:"max_body_bytes" mapstructure:"max_body_bytes" validate:"min=1"`
+	UserAgent    string `yaml:"user_agent" mapstructure:"user_agent" validate:"required"`
+	// AllowPrivateHosts lets pages be fetched from loopback, private and
+	// link-local addresses, which are blocked so that users can't reach
+	// internal services through the extractor
+	AllowPrivateHosts bool `yaml:"allow_private_hosts" mapstructure:"allow_private_hosts"`
+}
+
+// DefaultFetchConfig returns the download settings used when none are configured
+func DefaultFetchConfig() FetchConfig {
+	return FetchConfig{
+		Timeout:      10 * time.Second,
+		MaxRedirects: 5,
+		MaxBodyBytes: 20 << 20,
+		UserAgent:    "diploma-resource-service/1.0 (+https://github.com/nzb3/diploma)",
+	}
+}
+
+// NewFetchConfig loads the download settings from config file and environment variables
+func NewFetchConfig() (*FetchConfig, error) {
+	return configurator.LoadKeys("fetch", DefaultFetchConfig())
+}
+
+// WithFetchConfig downloads the pages of url resources as configured instead
+// of with DefaultFetchConfig
+func WithFetchConfig(config FetchConfig) Option {
+	return func(p *ContentExtractor) {
+		p.fetchConfig = config
+		p.httpClient = newFetchClient(config)
+	}
+}
+
+// newFetchClient creates an HTTP client following at most config.MaxRedirects
+// redirects. Unless private hosts are allowed, the address of every
+// connection is checked after DNS resolution, so that neither a redirect nor
+// a host name resolving to an internal address gets past the check.
+func newFetchClient(config FetchConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+	}
+	if !config.AllowPrivateHosts {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkPublicAddress(address)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect on our behalf, bypassing the address check
+	transport.Proxy = nil
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("%w: stopped after %d", resourcemodel.ErrFetchTooManyRedirects, config.MaxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s URL", resourcemodel.ErrFetchBlockedHost, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// checkPublicAddress rejects connections to addresses that are not publicly
+// routable, such as loopback, private, link-local or unspecified ones
+func checkPublicAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsMulticast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || isSharedAddress(ip) {
+		return fmt.Errorf("%w: %s is not a public address", resourcemodel.ErrFetchBlockedHost, ip)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which netip does not
+// count as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isSharedAddress(ip netip.Addr) bool {
+	return sharedAddressSpace.Contains(ip)
+}
+
+// fetchError maps a failed download to the fetch error it stands for. The
+// client wraps the errors of dials and redirects, which are matched as they
+// are.
+func fetchError(ctx context.Context, err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, resourcemodel.ErrFetchBlockedHost), errors.Is(err, resourcemodel.ErrFetchTooManyRedirects):
+		return err
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", resourcemodel.ErrFetchTimeout, err)
+	default:
+		return err
+	}
+}
+
+// limitedBody reads a response body up to limit bytes and fails with
+// resourcemodel.ErrFetchTooLarge past it
+type limitedBody struct {
+	body      io.Reader
+	remaining int64
+	limit     int64
+}
+
+func newLimitedBody(body io.Reader, limit int64) *limitedBody {
+	return &limitedBody{body: body, remaining: limit, limit: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, b.tooLarge()
+	}
+	// One byte more than allowed tells a body of exactly limit bytes from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), b.tooLarge()
+	}
+	return n, err
+}
+
+func (b *limitedBody) tooLarge() error {
+	return fmt.Errorf("%w: page exceeds %d bytes", resourcemodel.ErrFetchTooLarge, b.limit)
+}
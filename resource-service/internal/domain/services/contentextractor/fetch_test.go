@@ -0,0 +1,177 @@
+package contentextractor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// testFetchConfig allows the loopback address of httptest servers
+func testFetchConfig() FetchConfig {
+	config := DefaultFetchConfig()
+	config.AllowPrivateHosts = true
+	return config
+}
+
+// redirectServer redirects /hop/n to /hop/n-1 and serves a page at /hop/0
+func redirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if n > 0 {
+			http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<p>arrived</p>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExtractContent_URLRedirects(t *testing.T) {
+	server := redirectServer(t)
+
+	config := testFetchConfig()
+	config.MaxRedirects = 3
+	p := NewResourceProcessor(WithFetchConfig(config))
+
+	got, err := p.ExtractContent(context.Background(), []byte(server.URL+"/hop/3"), string(ContentTypeURL))
+	if err != nil {
+		t.Fatalf("ExtractContent() error = %v", err)
+	}
+	if !strings.Contains(got, "arrived") {
+		t.Errorf("ExtractContent() = %q, want the page at the end of the redirects", got)
+	}
+
+	_, err = p.ExtractContent(context.Background(), []byte(server.URL+"/hop/4"), string(ContentTypeURL))
+	if !errors.Is(err, resourcemodel.ErrFetchTooManyRedirects) {
+		t.Errorf("ExtractContent() error = %v, want %v", err, resourcemodel.ErrFetchTooManyRedirects)
+	}
+}
+
+func TestExtractContent_URLTooLarge(t *testing.T) {
+	page := "<p>" + strings.Repeat("a", 1024) + "</p>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/chunked" {
+			// Flushing before writing drops the Content-Length header
+			w.(http.Flusher).Flush()
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		}
+		fmt.Fprint(w, page)
+	}))
+	t.Cleanup(server.Close)
+
+	config := testFetchConfig()
+	config.MaxBodyBytes = 512
+	p := NewResourceProcessor(WithFetchConfig(config))
+
+	for _, path := range []string{"/sized", "/chunked"} {
+		t.Run(path, func(t *testing.T) {
+			_, err := p.ExtractContent(context.Background(), []byte(server.URL+path), string(ContentTypeURL))
+			if !errors.Is(err, resourcemodel.ErrFetchTooLarge) {
+				t.Errorf("ExtractContent() error = %v, want %v", err, resourcemodel.ErrFetchTooLarge)
+			}
+		})
+	}
+
+	// A page of exactly the maximum size is accepted
+	config.MaxBodyBytes = int64(len(page))
+	p = NewResourceProcessor(WithFetchConfig(config))
+	if _, err := p.ExtractContent(context.Background(), []byte(server.URL+"/chunked"), string(ContentTypeURL)); err != nil {
+		t.Errorf("ExtractContent() error = %v", err)
+	}
+}
+
+func TestExtractContent_URLTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	config := testFetchConfig()
+	config.Timeout = 50 * time.Millisecond
+	p := NewResourceProcessor(WithFetchConfig(config))
+
+	_, err := p.ExtractContent(context.Background(), []byte(server.URL), string(ContentTypeURL))
+	if !errors.Is(err, resourcemodel.ErrFetchTimeout) {
+		t.Errorf("ExtractContent() error = %v, want %v", err, resourcemodel.ErrFetchTimeout)
+	}
+}
+
+func TestExtractContent_URLBlocksPrivateHosts(t *testing.T) {
+	server := redirectServer(t)
+
+	p := NewResourceProcessor()
+
+	_, err := p.ExtractContent(context.Background(), []byte(server.URL+"/hop/0"), string(ContentTypeURL))
+	if !errors.Is(err, resourcemodel.ErrFetchBlockedHost) {
+		t.Errorf("ExtractContent() error = %v, want %v", err, resourcemodel.ErrFetchBlockedHost)
+	}
+}
+
+func TestCheckPublicAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{"127.0.0.1:80", true},
+		{"[::1]:80", true},
+		{"10.1.2.3:443", true},
+		{"192.168.0.10:80", true},
+		{"169.254.169.254:80", true},
+		{"100.64.0.1:80", true},
+		{"0.0.0.0:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"93.184.216.34:443", false},
+		{"[2606:4700::1111]:443", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := checkPublicAddress(tt.address)
+			if blocked := errors.Is(err, resourcemodel.ErrFetchBlockedHost); blocked != tt.blocked {
+				t.Errorf("checkPublicAddress(%q) = %v, want blocked %v", tt.address, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestExtractContent_URLUserAgent(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		fmt.Fprint(w, "<p>page</p>")
+	}))
+	t.Cleanup(server.Close)
+
+	config := testFetchConfig()
+	config.UserAgent = "test-agent/1.0"
+	p := NewResourceProcessor(WithFetchConfig(config))
+
+	if _, err := p.ExtractContent(context.Background(), []byte(server.URL), string(ContentTypeURL)); err != nil {
+		t.Fatalf("ExtractContent() error = %v", err)
+	}
+	if userAgent != "test-agent/1.0" {
+		t.Errorf("User-Agent = %q, want %q", userAgent, "test-agent/1.0")
+	}
+}
@@ -0,0 +1,131 @@
+package contentextractor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gen2brain/go-fitz"
+
+	"github.com/nzb3/diploma/resource-service/internal/configurator"
+)
+
+// OCRConfig configures the text recognition of scanned PDF pages
+type OCRConfig struct {
+	// Enabled recognizes the text of PDF pages with too little text, which
+	// takes seconds per page
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// MinPageChars is the number of non-space characters below which a page
+	// is taken for a scan
+	MinPageChars int `yaml:"min_page_chars" mapstructure:"min_page_chars" validate:"min=1"`
+	// DPI is the resolution pages are rendered at for recognition
+	DPI     int    `yaml:"dpi" mapstructure:"dpi" validate:"min=72,max=600"`
+	Command string `yaml:"command" mapstructure:"command" validate:"required"`
+	// Language names the Tesseract language data, e.g. eng or eng+rus
+	Language string `yaml:"language" mapstructure:"language" validate:"required"`
+	// MaxPages is the number of scanned pages recognized per document; the
+	// pages after them keep their text layer, if any. Zero recognizes them all.
+	MaxPages int `yaml:"max_pages" mapstructure:"max_pages" validate:"min=0"`
+	// PageTimeout bounds the recognition of a single page. Zero waits for
+	// the recognizer as long as the extraction context allows.
+	PageTimeout time.Duration `yaml:"page_timeout" mapstructure:"page_timeout" validate:"min=0"`
+}
+
+// DefaultOCRConfig returns the OCR settings used when none are configured
+func DefaultOCRConfig() OCRConfig {
+	return OCRConfig{
+		MinPageChars: 20,
+		DPI:          200,
+		Command:      "tesseract",
+		Language:     "eng",
+		MaxPages:     50,
+		PageTimeout:  time.Minute,
+	}
+}
+
+// NewOCRConfig loads the OCR settings from config file and environment variables
+func NewOCRConfig() (*OCRConfig, error) {
+	return configurator.LoadKeys("ocr", DefaultOCRConfig())
+}
+
+// OCR recognizes the text of a page rendered as PNG
+type OCR interface {
+	Recognize(ctx context.Context, image []byte) (string, error)
+}
+
+// WithOCR recognizes the text of scanned PDF pages, those with fewer than
+// config.MinPageChars characters of text, with ocr
+func WithOCR(ocr OCR, config OCRConfig) Option {
+	return func(p *ContentExtractor) {
+		p.ocr = ocr
+		p.ocrConfig = config
+	}
+}
+
+// TesseractOCR recognizes text with the Tesseract command line tool
+type TesseractOCR struct {
+	command  string
+	language string
+}
+
+// NewTesseractOCR creates an OCR running command, the tesseract binary
+func NewTesseractOCR(command, language string) *TesseractOCR {
+	return &TesseractOCR{command: command, language: language}
+}
+
+func (o *TesseractOCR) Recognize(ctx context.Context, image []byte) (string, error) {
+	cmd := exec.CommandContext(ctx, o.command, "stdin", "stdout", "-l", o.language)
+	cmd.Stdin = bytes.NewReader(image)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	text, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(text), nil
+}
+
+// isScanned reports whether the text extracted from a page is too short for
+// a page with text, when OCR is configured
+func (p *ContentExtractor) isScanned(text string) bool {
+	if p.ocr == nil {
+		return false
+	}
+
+	chars := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			chars++
+		}
+	}
+	return chars < p.ocrConfig.MinPageChars
+}
+
+// ocrCapped reports whether the pages recognized so far reach the configured maximum
+func (p *ContentExtractor) ocrCapped(recognized int) bool {
+	return p.ocrConfig.MaxPages > 0 && recognized >= p.ocrConfig.MaxPages
+}
+
+// recognizePage renders a page and recognizes its text within the page timeout
+func (p *ContentExtractor) recognizePage(ctx context.Context, doc *fitz.Document, page int) (string, error) {
+	image, err := doc.ImagePNG(page, float64(p.ocrConfig.DPI))
+	if err != nil {
+		return "", err
+	}
+
+	if p.ocrConfig.PageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ocrConfig.PageTimeout)
+		defer cancel()
+	}
+	text, err := p.ocr.Recognize(ctx, image)
+	if err != nil && ctx.Err() != nil {
+		return "", fmt.Errorf("%w: %w", err, ctx.Err())
+	}
+	return text, err
+}
@@ -0,0 +1,146 @@
+package contentextractor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// blankPDF is a PDF of a single page without a text layer, as scanners produce
+const blankPDF = `%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 1 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>
+endobj
+trailer
+<< /Size 4 /Root 1 0 R >>
+%%EOF`
+
+// blankPDFPages returns a PDF of n pages without a text layer
+func blankPDFPages(n int) []byte {
+	var kids, pages strings.Builder
+	for i := range n {
+		fmt.Fprintf(&kids, "%d 0 R ", i+3)
+		fmt.Fprintf(&pages, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>\nendobj\n", i+3)
+	}
+	return []byte(fmt.Sprintf("%%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n"+
+		"2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n%s"+
+		"trailer\n<< /Size %d /Root 1 0 R >>\n%%%%EOF", kids.String(), n, pages.String(), n+3))
+}
+
+// fakeOCR recognizes every page as the same text
+type fakeOCR struct {
+	text  string
+	err   error
+	pages int
+}
+
+func (o *fakeOCR) Recognize(_ context.Context, image []byte) (string, error) {
+	if !bytes.HasPrefix(image, []byte("\x89PNG")) {
+		return "", errors.New("page not rendered as png")
+	}
+	o.pages++
+	return o.text, o.err
+}
+
+func TestExtractContent_OCRFallbackOnScannedPDF(t *testing.T) {
+	ocr := &fakeOCR{text: "Recognized invoice text"}
+	extractor := NewResourceProcessor(WithOCR(ocr, DefaultOCRConfig()))
+	ctx, warnings := resourcemodel.WithExtractionWarnings(context.Background())
+
+	content, err := extractor.ExtractContent(ctx, []byte(blankPDF), string(ContentTypePDF))
+
+	if err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+	if ocr.pages != 1 {
+		t.Fatalf("expected the scanned page to be recognized once, got %d", ocr.pages)
+	}
+	if !strings.Contains(content, "Recognized invoice text") {
+		t.Errorf("recognized text missing from content:\n%s", content)
+	}
+	if list := warnings.List(); len(list) != 1 || !strings.Contains(list[0], "OCR") {
+		t.Errorf("expected an OCR warning, got %q", list)
+	}
+}
+
+func TestExtractContent_OCRSkipsPagesWithText(t *testing.T) {
+	data, err := os.ReadFile("testdata/three_pages.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ocr := &fakeOCR{text: "recognized"}
+	extractor := NewResourceProcessor(WithOCR(ocr, DefaultOCRConfig()))
+	ctx, warnings := resourcemodel.WithExtractionWarnings(context.Background())
+
+	if _, err := extractor.ExtractContent(ctx, data, string(ContentTypePDF)); err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+	if ocr.pages != 0 || len(warnings.List()) != 0 {
+		t.Errorf("pages with text recognized: %d pages, warnings %q", ocr.pages, warnings.List())
+	}
+}
+
+func TestExtractContent_OCRMaxPages(t *testing.T) {
+	ocr := &fakeOCR{text: "recognized"}
+	config := DefaultOCRConfig()
+	config.MaxPages = 2
+	extractor := NewResourceProcessor(WithOCR(ocr, config))
+	ctx, warnings := resourcemodel.WithExtractionWarnings(context.Background())
+
+	content, err := extractor.ExtractContent(ctx, blankPDFPages(5), string(ContentTypePDF))
+
+	if err != nil {
+		t.Fatalf("ExtractContent returned error: %v", err)
+	}
+	if ocr.pages != 2 || strings.Count(content, "recognized") != 2 {
+		t.Errorf("expected 2 recognized pages, got %d:\n%s", ocr.pages, content)
+	}
+	list := warnings.List()
+	if len(list) != 2 || !strings.Contains(list[1], "3 scanned page(s) over the limit of 2") {
+		t.Errorf("expected a warning about the 3 pages over the limit, got %q", list)
+	}
+}
+
+// blockingOCR recognizes nothing until its context is done
+type blockingOCR struct{}
+
+func (blockingOCR) Recognize(ctx context.Context, _ []byte) (string, error) {
+	<-ctx.Done()
+	return "", errors.New("tesseract: signal: killed")
+}
+
+func TestExtractContent_OCRPageTimeout(t *testing.T) {
+	config := DefaultOCRConfig()
+	config.PageTimeout = 20 * time.Millisecond
+	extractor := NewResourceProcessor(WithOCR(blockingOCR{}, config))
+
+	_, err := extractor.ExtractContent(context.Background(), []byte(blankPDF), string(ContentTypePDF))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the page timeout, got %v", err)
+	}
+}
+
+func TestExtractContent_OCRFailure(t *testing.T) {
+	ocr := &fakeOCR{err: errors.New("tesseract: exit status 1")}
+	extractor := NewResourceProcessor(WithOCR(ocr, DefaultOCRConfig()))
+
+	_, err := extractor.ExtractContent(context.Background(), []byte(blankPDF), string(ContentTypePDF))
+
+	if !errors.Is(err, ocr.err) {
+		t.Errorf("expected the OCR error, got %v", err)
+	}
+}
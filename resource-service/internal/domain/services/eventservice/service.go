@@ -6,15 +6,25 @@ import (
 	"log/slog"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/resource-service/internal/tracing"
 )
 
 // eventRepository defines the interface for event persistence operations
 type eventRepository interface {
 	CreateEvent(ctx context.Context, event eventmodel.Event) (eventmodel.Event, error)
 	GetNotSentEvents(ctx context.Context, limit int, offset int) ([]eventmodel.Event, error)
+	CountNotSentEvents(ctx context.Context) (int, error)
+	// HasOlderNotSentEvents reports whether an event older than the given one
+	// is still waiting in the outbox
+	HasOlderNotSentEvents(ctx context.Context, event eventmodel.Event) (bool, error)
 	MarkEventAsSent(ctx context.Context, eventID uuid.UUID) error
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
 }
 
 // messageProducer defines the interface for publishing messages
@@ -41,43 +51,79 @@ func NewEventService(repo eventRepository, producer messageProducer) *Service {
 
 // PublishEvent publishes a resource-related event using the outbox pattern
 // This method ensures ACID properties by storing the event in the same transaction
-// as the business operation and then attempting immediate delivery
+// as the business operation. The event is delivered once that transaction is
+// committed, so a rolled back operation sends nothing; events failing delivery
+// are retried by the outbox processor. While older events wait in the outbox,
+// the event is left to the processor too, so that it does not overtake them.
 func (s *Service) PublishEvent(ctx context.Context, topic string, eventName string, data interface{}) error {
+	ctx, span := tracing.StartSpan(ctx, "EventService.PublishEvent",
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("event.name", eventName),
+		))
+	err := s.publishEvent(ctx, topic, eventName, data)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (s *Service) publishEvent(ctx context.Context, topic string, eventName string, data interface{}) error {
 	const op = "EventService.PublishEvent"
 
 	event, err := eventmodel.NewEvent(eventName, topic, data)
 	if err != nil {
 		return fmt.Errorf("%s: failed to create event: %w", op, err)
 	}
+	event.Headers = traceHeaders(ctx)
 
 	savedEvent, err := s.eventRepo.CreateEvent(ctx, event)
 	if err != nil {
 		return fmt.Errorf("%s: failed to save event to outbox: %w", op, err)
 	}
 
-	err = s.producer.PublishEvent(ctx, savedEvent)
+	s.eventRepo.AfterCommit(ctx, func(ctx context.Context) {
+		s.deliverEvent(ctx, savedEvent)
+	})
+	return nil
+}
+
+// deliverEvent attempts the immediate delivery of a stored event
+func (s *Service) deliverEvent(ctx context.Context, event eventmodel.Event) {
+	older, err := s.eventRepo.HasOlderNotSentEvents(ctx, event)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to check for older unsent events, leaving the event to the outbox processor",
+			"error", err,
+			"event_id", event.ID,
+			"event_name", event.Name)
+		return
+	}
+	if older {
+		slog.InfoContext(ctx, "Older events are still unsent, leaving the event to the outbox processor",
+			"event_id", event.ID,
+			"event_name", event.Name)
+		return
+	}
+
+	err = s.producer.PublishEvent(ctx, event)
 	if err != nil {
 		slog.WarnContext(ctx, "Failed to publish event immediately, will retry via outbox processor",
 			"error", err,
-			"event_id", savedEvent.ID,
-			"event_name", savedEvent.Name)
-		return nil
+			"event_id", event.ID,
+			"event_name", event.Name)
+		return
 	}
 
-	err = s.eventRepo.MarkEventAsSent(ctx, savedEvent.ID)
+	err = s.eventRepo.MarkEventAsSent(ctx, event.ID)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to mark event as sent after successful publish",
 			"error", err,
-			"event_id", savedEvent.ID,
-			"event_name", savedEvent.Name)
+			"event_id", event.ID,
+			"event_name", event.Name)
 	}
 
 	slog.InfoContext(ctx, "Event published successfully",
-		"event_id", savedEvent.ID,
-		"event_name", savedEvent.Name,
-		"topic", savedEvent.Topic)
-
-	return nil
+		"event_id", event.ID,
+		"event_name", event.Name,
+		"topic", event.Topic)
 }
 
 // GetUnsentEvents retrieves events that haven't been successfully published
@@ -93,6 +139,18 @@ func (s *Service) GetUnsentEvents(ctx context.Context, limit, offset int) ([]eve
 	return events, nil
 }
 
+// CountUnsentEvents returns how many events are still waiting in the outbox
+func (s *Service) CountUnsentEvents(ctx context.Context) (int, error) {
+	const op = "EventService.CountUnsentEvents"
+
+	count, err := s.eventRepo.CountNotSentEvents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to count unsent events: %w", op, err)
+	}
+
+	return count, nil
+}
+
 // ProcessEvent attempts to publish a single event and marks it as sent if successful
 // This is used by the outbox processor
 func (s *Service) ProcessEvent(ctx context.Context, event eventmodel.Event) error {
@@ -122,3 +180,14 @@ func (s *Service) Health(ctx context.Context) error {
 
 	return nil
 }
+
+// traceHeaders returns the W3C trace context of ctx as event headers, nil when
+// ctx is not traced. The producer restores it when the event is published.
+func traceHeaders(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
@@ -12,6 +12,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/eventmodel"
 )
@@ -19,6 +24,13 @@ import (
 // MockEventRepository implements the eventRepository interface for testing
 type MockEventRepository struct {
 	mock.Mock
+	// inTx holds back the functions to run after commit, as in a transaction,
+	// until commit is called
+	inTx        bool
+	afterCommit []func(ctx context.Context)
+	// olderNotSent and olderNotSentErr are what HasOlderNotSentEvents reports
+	olderNotSent    bool
+	olderNotSentErr error
 }
 
 func (m *MockEventRepository) CreateEvent(ctx context.Context, event eventmodel.Event) (eventmodel.Event, error) {
@@ -31,11 +43,37 @@ func (m *MockEventRepository) GetNotSentEvents(ctx context.Context, limit int, o
 	return args.Get(0).([]eventmodel.Event), args.Error(1)
 }
 
+func (m *MockEventRepository) CountNotSentEvents(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockEventRepository) HasOlderNotSentEvents(ctx context.Context, event eventmodel.Event) (bool, error) {
+	return m.olderNotSent, m.olderNotSentErr
+}
+
 func (m *MockEventRepository) MarkEventAsSent(ctx context.Context, eventID uuid.UUID) error {
 	args := m.Called(ctx, eventID)
 	return args.Error(0)
 }
 
+func (m *MockEventRepository) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if !m.inTx {
+		fn(ctx)
+		return
+	}
+	m.afterCommit = append(m.afterCommit, fn)
+}
+
+// commit runs the functions held back by the transaction
+func (m *MockEventRepository) commit(ctx context.Context) {
+	for _, fn := range m.afterCommit {
+		fn(ctx)
+	}
+	m.afterCommit = nil
+	m.inTx = false
+}
+
 // MockMessageProducer implements the messageProducer interface for testing
 type MockMessageProducer struct {
 	mock.Mock
@@ -51,6 +89,22 @@ func (m *MockMessageProducer) Health(ctx context.Context) error {
 	return args.Error(0)
 }
 
+// ctxKey marks the context of a test, so that expectations also match the
+// contexts derived from it, such as that of the span of PublishEvent
+type ctxKey struct{}
+
+func markedContext(parent context.Context) context.Context {
+	return context.WithValue(parent, ctxKey{}, uuid.New())
+}
+
+// derivedFrom matches ctx and the contexts derived from it
+func derivedFrom(ctx context.Context) interface{} {
+	marker := ctx.Value(ctxKey{})
+	return mock.MatchedBy(func(c context.Context) bool {
+		return c.Value(ctxKey{}) == marker
+	})
+}
+
 // EventServiceTestSuite defines the test suite
 type EventServiceTestSuite struct {
 	suite.Suite
@@ -68,7 +122,7 @@ func (suite *EventServiceTestSuite) SetupTest() {
 	suite.mockRepo = &MockEventRepository{}
 	suite.mockProducer = &MockMessageProducer{}
 	suite.service = NewEventService(suite.mockRepo, suite.mockProducer)
-	suite.ctx = context.Background()
+	suite.ctx = markedContext(context.Background())
 	suite.testEventID = uuid.New()
 
 	suite.testData = map[string]interface{}{
@@ -88,6 +142,11 @@ func (suite *EventServiceTestSuite) SetupTest() {
 	}
 }
 
+// derivedCtx matches the context of the test and those derived from it
+func (suite *EventServiceTestSuite) derivedCtx() interface{} {
+	return derivedFrom(suite.ctx)
+}
+
 // Test NewEventService
 func (suite *EventServiceTestSuite) TestNewEventService() {
 	service := NewEventService(suite.mockRepo, suite.mockProducer)
@@ -104,15 +163,15 @@ func (suite *EventServiceTestSuite) TestPublishEvent_Success_ImmediatePublishAnd
 	// Mock CreateEvent to return saved event
 	savedEvent := suite.testEvent
 	savedEvent.ID = uuid.New() // New ID assigned by repository
-	suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 		return e.Name == eventName && e.Topic == "resources"
 	})).Return(savedEvent, nil)
 
 	// Mock successful publish
-	suite.mockProducer.On("PublishEvent", suite.ctx, savedEvent).Return(nil)
+	suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(nil)
 
 	// Mock successful mark as sent
-	suite.mockRepo.On("MarkEventAsSent", suite.ctx, savedEvent.ID).Return(nil)
+	suite.mockRepo.On("MarkEventAsSent", suite.derivedCtx(), savedEvent.ID).Return(nil)
 
 	// Execute
 	err := suite.service.PublishEvent(suite.ctx, "resources", eventName, suite.testData)
@@ -123,12 +182,67 @@ func (suite *EventServiceTestSuite) TestPublishEvent_Success_ImmediatePublishAnd
 	suite.mockProducer.AssertExpectations(suite.T())
 }
 
+// TestPublishEvent_InTransaction tests that an event stored in a transaction is
+// only delivered once the transaction is committed
+func (suite *EventServiceTestSuite) TestPublishEvent_InTransaction() {
+	eventName := "resource.deleted"
+	savedEvent := suite.testEvent
+	savedEvent.ID = uuid.New()
+	suite.mockRepo.inTx = true
+
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.Anything).Return(savedEvent, nil)
+
+	err := suite.service.PublishEvent(suite.ctx, "resources", eventName, suite.testData)
+
+	assert.NoError(suite.T(), err)
+	suite.mockProducer.AssertNotCalled(suite.T(), "PublishEvent", mock.Anything, mock.Anything)
+
+	suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(nil).Once()
+	suite.mockRepo.On("MarkEventAsSent", suite.derivedCtx(), savedEvent.ID).Return(nil).Once()
+
+	suite.mockRepo.commit(suite.ctx)
+
+	suite.mockRepo.AssertExpectations(suite.T())
+	suite.mockProducer.AssertExpectations(suite.T())
+}
+
+// TestPublishEvent_BehindOlderUnsentEvents tests that an event is left to the
+// outbox processor while older events wait there, so that it does not overtake them
+func (suite *EventServiceTestSuite) TestPublishEvent_BehindOlderUnsentEvents() {
+	savedEvent := suite.testEvent
+	savedEvent.ID = uuid.New()
+	suite.mockRepo.olderNotSent = true
+
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.Anything).Return(savedEvent, nil)
+
+	err := suite.service.PublishEvent(suite.ctx, "resources", "resource.updated", suite.testData)
+
+	assert.NoError(suite.T(), err)
+	suite.mockProducer.AssertNotCalled(suite.T(), "PublishEvent", mock.Anything, mock.Anything)
+	suite.mockRepo.AssertNotCalled(suite.T(), "MarkEventAsSent", mock.Anything, mock.Anything)
+}
+
+// TestPublishEvent_OlderUnsentEventsCheckFails tests that an event is left to the
+// outbox processor when it is unknown whether older events wait there
+func (suite *EventServiceTestSuite) TestPublishEvent_OlderUnsentEventsCheckFails() {
+	savedEvent := suite.testEvent
+	savedEvent.ID = uuid.New()
+	suite.mockRepo.olderNotSentErr = errors.New("database error")
+
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.Anything).Return(savedEvent, nil)
+
+	err := suite.service.PublishEvent(suite.ctx, "resources", "resource.updated", suite.testData)
+
+	assert.NoError(suite.T(), err)
+	suite.mockProducer.AssertNotCalled(suite.T(), "PublishEvent", mock.Anything, mock.Anything)
+}
+
 // Test PublishEvent - CreateEvent fails
 func (suite *EventServiceTestSuite) TestPublishEvent_CreateEventFails() {
 	eventName := "resource.created"
 	expectedError := errors.New("database error")
 
-	suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 		return e.Name == eventName && e.Topic == "resources"
 	})).Return(eventmodel.Event{}, expectedError)
 
@@ -149,11 +263,11 @@ func (suite *EventServiceTestSuite) TestPublishEvent_PublishFails_OutboxPattern(
 	savedEvent.ID = uuid.New()
 	publishError := errors.New("kafka connection failed")
 
-	suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 		return e.Name == eventName && e.Topic == "resources"
 	})).Return(savedEvent, nil)
 
-	suite.mockProducer.On("PublishEvent", suite.ctx, savedEvent).Return(publishError)
+	suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(publishError)
 
 	// Execute - should not fail even if publish fails (outbox pattern)
 	err := suite.service.PublishEvent(suite.ctx, "resources", eventName, suite.testData)
@@ -171,12 +285,12 @@ func (suite *EventServiceTestSuite) TestPublishEvent_MarkAsSentFails() {
 	savedEvent.ID = uuid.New()
 	markSentError := errors.New("database connection lost")
 
-	suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+	suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 		return e.Name == eventName && e.Topic == "resources"
 	})).Return(savedEvent, nil)
 
-	suite.mockProducer.On("PublishEvent", suite.ctx, savedEvent).Return(nil)
-	suite.mockRepo.On("MarkEventAsSent", suite.ctx, savedEvent.ID).Return(markSentError)
+	suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(nil)
+	suite.mockRepo.On("MarkEventAsSent", suite.derivedCtx(), savedEvent.ID).Return(markSentError)
 
 	// Execute - should not fail even if marking as sent fails
 	err := suite.service.PublishEvent(suite.ctx, "resources", eventName, suite.testData)
@@ -251,6 +365,32 @@ func (suite *EventServiceTestSuite) TestGetUnsentEvents_EmptyResult() {
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// Test CountUnsentEvents - Success
+func (suite *EventServiceTestSuite) TestCountUnsentEvents_Success() {
+	suite.mockRepo.On("CountNotSentEvents", suite.ctx).Return(250, nil)
+
+	// Execute
+	count, err := suite.service.CountUnsentEvents(suite.ctx)
+
+	// Assert
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 250, count)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// Test CountUnsentEvents - Repository error
+func (suite *EventServiceTestSuite) TestCountUnsentEvents_RepositoryError() {
+	suite.mockRepo.On("CountNotSentEvents", suite.ctx).Return(0, errors.New("database error"))
+
+	// Execute
+	_, err := suite.service.CountUnsentEvents(suite.ctx)
+
+	// Assert
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "failed to count unsent events")
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
 // Test ProcessEvent - Success
 func (suite *EventServiceTestSuite) TestProcessEvent_Success() {
 	suite.mockProducer.On("PublishEvent", suite.ctx, suite.testEvent).Return(nil)
@@ -358,12 +498,12 @@ func (suite *EventServiceTestSuite) TestPublishEvent_DifferentEventTypes() {
 			savedEvent.ID = uuid.New()
 			savedEvent.Name = tc.eventName
 
-			suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+			suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 				return e.Name == tc.eventName && e.Topic == "resources"
 			})).Return(savedEvent, nil)
 
-			suite.mockProducer.On("PublishEvent", suite.ctx, savedEvent).Return(nil)
-			suite.mockRepo.On("MarkEventAsSent", suite.ctx, savedEvent.ID).Return(nil)
+			suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(nil)
+			suite.mockRepo.On("MarkEventAsSent", suite.derivedCtx(), savedEvent.ID).Return(nil)
 
 			// Execute
 			err := suite.service.PublishEvent(suite.ctx, "resources", tc.eventName, tc.data)
@@ -378,13 +518,13 @@ func (suite *EventServiceTestSuite) TestPublishEvent_DifferentEventTypes() {
 
 // Test context cancellation
 func (suite *EventServiceTestSuite) TestPublishEvent_ContextCancelled() {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(markedContext(context.Background()))
 	cancel() // Cancel immediately
 
 	eventName := "resource.created"
 
 	// Even with cancelled context, the operation should depend on repository behavior
-	suite.mockRepo.On("CreateEvent", ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+	suite.mockRepo.On("CreateEvent", derivedFrom(ctx), mock.MatchedBy(func(e eventmodel.Event) bool {
 		return e.Name == eventName && e.Topic == "resources"
 	})).Return(eventmodel.Event{}, context.Canceled)
 
@@ -409,12 +549,12 @@ func (suite *EventServiceTestSuite) TestPublishEvent_Concurrency() {
 		savedEvent := suite.testEvent
 		savedEvent.ID = uuid.New()
 
-		suite.mockRepo.On("CreateEvent", suite.ctx, mock.MatchedBy(func(e eventmodel.Event) bool {
+		suite.mockRepo.On("CreateEvent", suite.derivedCtx(), mock.MatchedBy(func(e eventmodel.Event) bool {
 			return e.Name == eventName && e.Topic == "resources"
 		})).Return(savedEvent, nil).Maybe()
 
-		suite.mockProducer.On("PublishEvent", suite.ctx, savedEvent).Return(nil).Maybe()
-		suite.mockRepo.On("MarkEventAsSent", suite.ctx, savedEvent.ID).Return(nil).Maybe()
+		suite.mockProducer.On("PublishEvent", suite.derivedCtx(), savedEvent).Return(nil).Maybe()
+		suite.mockRepo.On("MarkEventAsSent", suite.derivedCtx(), savedEvent.ID).Return(nil).Maybe()
 	}
 
 	// Execute concurrently
@@ -499,3 +639,41 @@ func TestService_GetUnsentEvents_WithPagination(t *testing.T) {
 		})
 	}
 }
+
+func TestService_PublishEvent_CarriesTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	mockRepo := &MockEventRepository{}
+	mockProducer := &MockMessageProducer{}
+	service := NewEventService(mockRepo, mockProducer)
+
+	var stored eventmodel.Event
+	mockRepo.On("CreateEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(eventmodel.Event)
+	}).Return(eventmodel.Event{ID: uuid.New()}, nil)
+	mockProducer.On("PublishEvent", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("MarkEventAsSent", mock.Anything, mock.Anything).Return(nil)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	err := service.PublishEvent(ctx, "resources", "resource.created", map[string]string{"key": "value"})
+	parent.End()
+	assert.NoError(t, err)
+
+	// The stored headers restore the trace of the operation creating the event
+	restored := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(stored.Headers))
+	assert.Equal(t, parent.SpanContext().TraceID(), trace.SpanContextFromContext(restored).TraceID())
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	assert.Contains(t, names, "EventService.PublishEvent")
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+	"github.com/nzb3/diploma/resource-service/internal/metrics"
 	"github.com/nzb3/diploma/resource-service/internal/repository/messaging"
 )
 
@@ -18,32 +21,53 @@ type IndexationCompleteEvent struct {
 	ResourceID uuid.UUID `json:"resource_id"`
 	Success    bool      `json:"success"`
 	Message    string    `json:"message,omitempty"`
+	ChunkIDs   []string  `json:"chunk_ids,omitempty"`
+	// ChunkHashes are the content hashes of the chunks, in the order of ChunkIDs
+	ChunkHashes []string `json:"chunk_hashes,omitempty"`
 }
 
+// IndexationProgressEvent represents intermediate indexation progress of a resource
+type IndexationProgressEvent struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	Percent    int       `json:"percent"`
+}
+
+const (
+	indexationCompleteTopic = "indexation_complete"
+	indexationProgressTopic = "indexation_progress"
+
+	// defaultFinalUpdateTimeout bounds how long the final status update waits for a reader
+	defaultFinalUpdateTimeout = 5 * time.Second
+)
+
 // resourceService defines the interface for updating resource status and managing channels
 type resourceService interface {
 	UpdateResourceStatus(ctx context.Context, resource resourcemodel.Resource, status resourcemodel.ResourceStatus) (resourcemodel.Resource, error)
-	GetResourceStatusChannel(resourceID uuid.UUID) (chan resourcemodel.ResourceStatusUpdate, bool)
-	RemoveResourceStatusChannel(resourceID uuid.UUID)
+	SendResourceStatus(update resourcemodel.ResourceStatusUpdate) bool
+	FinishResourceStatus(ctx context.Context, update resourcemodel.ResourceStatusUpdate, timeout time.Duration) (found, delivered bool)
 	GetResourceByID(ctx context.Context, resourceID uuid.UUID) (resourcemodel.Resource, error)
+	UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error
 }
 
 // Processor handles indexation completion events and updates resource status
 type Processor struct {
-	resourceService resourceService
-	consumer        messaging.MessageConsumer
-	stopCh          chan struct{}
-	doneCh          chan struct{}
-	wg              sync.WaitGroup
+	resourceService    resourceService
+	consumer           messaging.MessageConsumer
+	finalUpdateTimeout time.Duration
+	inFlight           sync.Map // Resource IDs whose completion is being handled
+	stopCh             chan struct{}
+	doneCh             chan struct{}
+	wg                 sync.WaitGroup
 }
 
 // NewIndexationProcessor creates a new indexation completion processor
 func NewIndexationProcessor(resourceService resourceService, consumer messaging.MessageConsumer) *Processor {
 	return &Processor{
-		resourceService: resourceService,
-		consumer:        consumer,
-		stopCh:          make(chan struct{}),
-		doneCh:          make(chan struct{}),
+		resourceService:    resourceService,
+		consumer:           consumer,
+		finalUpdateTimeout: defaultFinalUpdateTimeout,
+		stopCh:             make(chan struct{}),
+		doneCh:             make(chan struct{}),
 	}
 }
 
@@ -51,7 +75,7 @@ func NewIndexationProcessor(resourceService resourceService, consumer messaging.
 func (p *Processor) Start(ctx context.Context) error {
 	defer close(p.doneCh)
 
-	topics := []string{"indexation_complete"}
+	topics := []string{indexationCompleteTopic, indexationProgressTopic}
 
 	err := p.consumer.Subscribe(ctx, topics, p)
 	if err != nil {
@@ -84,15 +108,28 @@ func (p *Processor) Stop() {
 
 // HandleMessage implements the MessageHandler interface
 func (p *Processor) HandleMessage(ctx context.Context, topic string, key string, value []byte, headers map[string]string) error {
-	const op = "IndexationProcessor.HandleMessage"
-
-	if topic != "indexation_complete" {
+	switch topic {
+	case indexationCompleteTopic:
+	case indexationProgressTopic:
+		p.wg.Add(1)
+		defer p.wg.Done()
+		return p.handleProgress(ctx, value)
+	default:
 		return nil
 	}
 
 	p.wg.Add(1)
 	defer p.wg.Done()
 
+	err := p.handleComplete(ctx, topic, key, value)
+	metrics.IndexationMessagesHandled.WithLabelValues(strconv.FormatBool(err == nil)).Inc()
+	return err
+}
+
+// handleComplete moves the resource into its final status and notifies the status channel reader
+func (p *Processor) handleComplete(ctx context.Context, topic string, key string, value []byte) error {
+	const op = "IndexationProcessor.handleComplete"
+
 	slog.DebugContext(ctx, "Processing indexation complete event",
 		"op", op,
 		"topic", topic,
@@ -113,6 +150,16 @@ func (p *Processor) HandleMessage(ctx context.Context, topic string, key string,
 		"success", event.Success,
 		"message", event.Message)
 
+	// Kafka may redeliver the same completion; concurrent copies are skipped here
+	// and late ones by the terminal status check below.
+	if _, busy := p.inFlight.LoadOrStore(event.ResourceID, struct{}{}); busy {
+		slog.InfoContext(ctx, "Indexation complete event is already being handled, skipping duplicate",
+			"op", op,
+			"resource_id", event.ResourceID)
+		return nil
+	}
+	defer p.inFlight.Delete(event.ResourceID)
+
 	resource, err := p.resourceService.GetResourceByID(ctx, event.ResourceID)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to get resource for status update",
@@ -122,9 +169,27 @@ func (p *Processor) HandleMessage(ctx context.Context, topic string, key string,
 		return fmt.Errorf("%s: failed to get resource: %w", op, err)
 	}
 
+	if resource.Status.IsTerminal() {
+		slog.InfoContext(ctx, "Resource indexation already finalized, skipping duplicate event",
+			"op", op,
+			"resource_id", event.ResourceID,
+			"status", resource.Status)
+		return nil
+	}
+
 	var finalStatus resourcemodel.ResourceStatus
 	if event.Success {
 		finalStatus = resourcemodel.ResourceStatusCompleted
+
+		// Stored before the status, so a completed resource always has its chunks
+		if err := p.resourceService.UpdateResourceChunks(ctx, event.ResourceID, event.ChunkIDs, event.ChunkHashes); err != nil {
+			slog.ErrorContext(ctx, "Failed to store resource chunk IDs",
+				"op", op,
+				"resource_id", event.ResourceID,
+				"chunks_count", len(event.ChunkIDs),
+				"error", err)
+			return fmt.Errorf("%s: failed to store chunk IDs: %w", op, err)
+		}
 	} else {
 		finalStatus = resourcemodel.ResourceStatusFailed
 	}
@@ -145,40 +210,37 @@ func (p *Processor) HandleMessage(ctx context.Context, topic string, key string,
 		"old_status", resource.Status,
 		"new_status", finalStatus)
 
-	statusCh, exists := p.resourceService.GetResourceStatusChannel(event.ResourceID)
-	if exists {
-		statusUpdate := resourcemodel.ResourceStatusUpdate{
-			ResourceID: event.ResourceID,
-			Status:     finalStatus,
-		}
-
-		select {
-		case statusCh <- statusUpdate:
-			slog.InfoContext(ctx, "Sent status update to channel",
-				"op", op,
-				"resource_id", event.ResourceID,
-				"status", finalStatus)
-		case <-ctx.Done():
-			slog.WarnContext(ctx, "Context cancelled while sending status update",
-				"op", op,
-				"resource_id", event.ResourceID)
-			return ctx.Err()
-		default:
-			slog.WarnContext(ctx, "Status channel is full, dropping update",
-				"op", op,
-				"resource_id", event.ResourceID)
-		}
-
-		close(statusCh)
-		p.resourceService.RemoveResourceStatusChannel(event.ResourceID)
+	statusUpdate := resourcemodel.ResourceStatusUpdate{
+		ResourceID: event.ResourceID,
+		Status:     finalStatus,
+	}
+	if event.Success {
+		statusUpdate.Percent = 100
+	}
 
-		slog.InfoContext(ctx, "Closed and removed status channel",
+	// The final update must not be dropped behind queued progress updates,
+	// so wait for the reader instead of giving up on a full channel.
+	found, delivered := p.resourceService.FinishResourceStatus(ctx, statusUpdate, p.finalUpdateTimeout)
+	switch {
+	case !found:
+		slog.WarnContext(ctx, "No status channel found for resource",
 			"op", op,
 			"resource_id", event.ResourceID)
-	} else {
-		slog.WarnContext(ctx, "No status channel found for resource",
+	case delivered:
+		slog.InfoContext(ctx, "Sent final status update and closed status channel",
+			"op", op,
+			"resource_id", event.ResourceID,
+			"status", finalStatus)
+	case ctx.Err() != nil:
+		slog.WarnContext(ctx, "Context cancelled while sending status update",
 			"op", op,
 			"resource_id", event.ResourceID)
+		return ctx.Err()
+	default:
+		slog.WarnContext(ctx, "No reader for status channel, dropped update",
+			"op", op,
+			"resource_id", event.ResourceID,
+			"timeout", p.finalUpdateTimeout)
 	}
 
 	slog.InfoContext(ctx, "Successfully processed indexation complete event",
@@ -189,6 +251,42 @@ func (p *Processor) HandleMessage(ctx context.Context, topic string, key string,
 	return nil
 }
 
+// handleProgress forwards intermediate indexation progress to the resource's status channel.
+// Progress updates are best effort: a busy reader only misses an intermediate percentage.
+func (p *Processor) handleProgress(ctx context.Context, value []byte) error {
+	const op = "IndexationProcessor.handleProgress"
+
+	var event IndexationProgressEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal indexation progress event",
+			"op", op,
+			"error", err,
+			"payload", string(value))
+		return fmt.Errorf("%s: failed to unmarshal event: %w", op, err)
+	}
+
+	// The completion of the resource may be handled at the same time, so the
+	// update goes through the service, which never sends on a closed channel
+	sent := p.resourceService.SendResourceStatus(resourcemodel.ResourceStatusUpdate{
+		ResourceID: event.ResourceID,
+		Status:     resourcemodel.ResourceStatusProcessing,
+		Percent:    event.Percent,
+	})
+	if sent {
+		slog.DebugContext(ctx, "Sent progress update to channel",
+			"op", op,
+			"resource_id", event.ResourceID,
+			"percent", event.Percent)
+	} else {
+		slog.DebugContext(ctx, "No status channel or channel is busy, skipping progress update",
+			"op", op,
+			"resource_id", event.ResourceID,
+			"percent", event.Percent)
+	}
+
+	return nil
+}
+
 // Health checks the health of the indexation processor
 func (p *Processor) Health(ctx context.Context) error {
 	if p.consumer != nil {
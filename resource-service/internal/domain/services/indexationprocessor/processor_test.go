@@ -4,21 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+	"github.com/nzb3/diploma/resource-service/internal/domain/services/resourceservcie"
+	"github.com/nzb3/diploma/resource-service/internal/metrics"
 	"github.com/nzb3/diploma/resource-service/internal/repository/messaging"
 )
 
-// MockResourceService is a mock implementation of resourceService interface
+// MockResourceService is a mock implementation of resourceService interface.
+// Status updates go through real status channels.
 type MockResourceService struct {
 	mock.Mock
+	resourceservcie.StatusChannels
 }
 
 func (m *MockResourceService) UpdateResourceStatus(ctx context.Context, resource resourcemodel.Resource, status resourcemodel.ResourceStatus) (resourcemodel.Resource, error) {
@@ -26,23 +32,16 @@ func (m *MockResourceService) UpdateResourceStatus(ctx context.Context, resource
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
 }
 
-func (m *MockResourceService) GetResourceStatusChannel(resourceID uuid.UUID) (chan resourcemodel.ResourceStatusUpdate, bool) {
-	args := m.Called(resourceID)
-	if args.Get(0) == nil {
-		return nil, args.Bool(1)
-	}
-	return args.Get(0).(chan resourcemodel.ResourceStatusUpdate), args.Bool(1)
-}
-
-func (m *MockResourceService) RemoveResourceStatusChannel(resourceID uuid.UUID) {
-	m.Called(resourceID)
-}
-
 func (m *MockResourceService) GetResourceByID(ctx context.Context, resourceID uuid.UUID) (resourcemodel.Resource, error) {
 	args := m.Called(ctx, resourceID)
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
 }
 
+func (m *MockResourceService) UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error {
+	args := m.Called(ctx, resourceID, chunkIDs, chunkHashes)
+	return args.Error(0)
+}
+
 // MockMessageConsumer is a mock implementation of messaging.MessageConsumer interface
 type MockMessageConsumer struct {
 	mock.Mock
@@ -76,6 +75,7 @@ func (suite *IndexationProcessorTestSuite) SetupTest() {
 	suite.mockResourceService = new(MockResourceService)
 	suite.mockConsumer = new(MockMessageConsumer)
 	suite.processor = NewIndexationProcessor(suite.mockResourceService, suite.mockConsumer)
+	suite.processor.finalUpdateTimeout = 50 * time.Millisecond
 	suite.ctx = context.Background()
 }
 
@@ -84,6 +84,16 @@ func (suite *IndexationProcessorTestSuite) TearDownTest() {
 	suite.mockConsumer.AssertExpectations(suite.T())
 }
 
+// fillStatusChannel registers the status channel of the resource and queues
+// progress updates until it is full
+func (suite *IndexationProcessorTestSuite) fillStatusChannel(resourceID uuid.UUID) <-chan resourcemodel.ResourceStatusUpdate {
+	statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
+	progress := resourcemodel.ResourceStatusUpdate{ResourceID: resourceID, Status: resourcemodel.ResourceStatusProcessing, Percent: 90}
+	for suite.mockResourceService.SendResourceStatus(progress) {
+	}
+	return statusCh
+}
+
 // TestNewIndexationProcessor tests the constructor
 func (suite *IndexationProcessorTestSuite) TestNewIndexationProcessor() {
 	processor := NewIndexationProcessor(suite.mockResourceService, suite.mockConsumer)
@@ -97,7 +107,7 @@ func (suite *IndexationProcessorTestSuite) TestNewIndexationProcessor() {
 
 // TestStart_Success tests successful start of the processor
 func (suite *IndexationProcessorTestSuite) TestStart_Success() {
-	topics := []string{"indexation_complete"}
+	topics := []string{"indexation_complete", "indexation_progress"}
 	
 	suite.mockConsumer.On("Subscribe", mock.Anything, topics, suite.processor).Return(nil).Once()
 	
@@ -112,7 +122,7 @@ func (suite *IndexationProcessorTestSuite) TestStart_Success() {
 
 // TestStart_SubscribeError tests start failure due to subscription error
 func (suite *IndexationProcessorTestSuite) TestStart_SubscribeError() {
-	topics := []string{"indexation_complete"}
+	topics := []string{"indexation_complete", "indexation_progress"}
 	expectedError := errors.New("subscription failed")
 	
 	suite.mockConsumer.On("Subscribe", mock.Anything, topics, suite.processor).Return(expectedError).Once()
@@ -148,9 +158,11 @@ func (suite *IndexationProcessorTestSuite) TestStop() {
 func (suite *IndexationProcessorTestSuite) TestHandleMessage_Success() {
 	resourceID := uuid.New()
 	event := IndexationCompleteEvent{
-		ResourceID: resourceID,
-		Success:    true,
-		Message:    "Indexation completed successfully",
+		ResourceID:  resourceID,
+		Success:     true,
+		Message:     "Indexation completed successfully",
+		ChunkIDs:    []string{"chunk-1", "chunk-2"},
+		ChunkHashes: []string{"hash-1", "hash-2"},
 	}
 	
 	eventJSON, _ := json.Marshal(event)
@@ -163,13 +175,12 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_Success() {
 	updatedResource := resource
 	updatedResource.Status = resourcemodel.ResourceStatusCompleted
 	
-	statusCh := make(chan resourcemodel.ResourceStatusUpdate, 1)
+	statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
 	
 	// Setup expectations
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string{"chunk-1", "chunk-2"}, []string{"hash-1", "hash-2"}).Return(nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
-	suite.mockResourceService.On("GetResourceStatusChannel", resourceID).Return(statusCh, true).Once()
-	suite.mockResourceService.On("RemoveResourceStatusChannel", resourceID).Once()
 	
 	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
 	
@@ -180,6 +191,7 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_Success() {
 	case statusUpdate := <-statusCh:
 		assert.Equal(suite.T(), resourceID, statusUpdate.ResourceID)
 		assert.Equal(suite.T(), resourcemodel.ResourceStatusCompleted, statusUpdate.Status)
+		assert.Equal(suite.T(), 100, statusUpdate.Percent)
 	case <-time.After(100 * time.Millisecond):
 		suite.T().Fatal("Expected status update not received")
 	}
@@ -189,6 +201,91 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_Success() {
 	assert.False(suite.T(), ok, "Channel should be closed")
 }
 
+// TestHandleMessage_DuplicateDelivery tests that a redelivered event causes a single status transition
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_DuplicateDelivery() {
+	resourceID := uuid.New()
+	event := IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+	}
+
+	eventJSON, _ := json.Marshal(event)
+
+	resource := resourcemodel.Resource{
+		ID:     resourceID,
+		Status: resourcemodel.ResourceStatusProcessing,
+	}
+
+	updatedResource := resource
+	updatedResource.Status = resourcemodel.ResourceStatusCompleted
+
+	statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
+
+	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(updatedResource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
+	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
+
+	assert.NotPanics(suite.T(), func() {
+		err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
+		assert.NoError(suite.T(), err)
+
+		err = suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
+		assert.NoError(suite.T(), err)
+	})
+
+	var updates []resourcemodel.ResourceStatusUpdate
+	for update := range statusCh {
+		updates = append(updates, update)
+	}
+	assert.Len(suite.T(), updates, 1)
+}
+
+// TestHandleMessage_ChunkIDsStoreFailure tests that the status is kept when chunk IDs can't be stored, so the event is redelivered
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_ChunkIDsStoreFailure() {
+	resourceID := uuid.New()
+	chunkIDs := []string{"chunk-1"}
+	eventJSON, _ := json.Marshal(IndexationCompleteEvent{ResourceID: resourceID, Success: true, ChunkIDs: chunkIDs})
+
+	resource := resourcemodel.Resource{
+		ID:     resourceID,
+		Status: resourcemodel.ResourceStatusProcessing,
+	}
+	storeErr := errors.New("database unavailable")
+
+	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, chunkIDs, []string(nil)).Return(storeErr).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
+
+	assert.ErrorIs(suite.T(), err, storeErr)
+	suite.mockResourceService.AssertNotCalled(suite.T(), "UpdateResourceStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_DuplicateInFlight tests that a copy delivered while the first is still handled is skipped
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_DuplicateInFlight() {
+	resourceID := uuid.New()
+	eventJSON, _ := json.Marshal(IndexationCompleteEvent{ResourceID: resourceID, Success: true})
+
+	suite.processor.inFlight.Store(resourceID, struct{}{})
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
+
+	assert.NoError(suite.T(), err)
+	suite.mockResourceService.AssertNotCalled(suite.T(), "GetResourceByID", mock.Anything, resourceID)
+}
+
+// TestHandleMessage_CountsHandledMessages tests that completion messages are counted by outcome
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_CountsHandledMessages() {
+	failed := metrics.IndexationMessagesHandled.WithLabelValues("false")
+	before := testutil.ToFloat64(failed)
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", "key", []byte("invalid json"), nil)
+
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), before+1, testutil.ToFloat64(failed))
+}
+
 // TestHandleMessage_FailedIndexation tests handling failed indexation event
 func (suite *IndexationProcessorTestSuite) TestHandleMessage_FailedIndexation() {
 	resourceID := uuid.New()
@@ -208,13 +305,11 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_FailedIndexation()
 	updatedResource := resource
 	updatedResource.Status = resourcemodel.ResourceStatusFailed
 	
-	statusCh := make(chan resourcemodel.ResourceStatusUpdate, 1)
+	statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
 	
 	// Setup expectations
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusFailed).Return(updatedResource, nil).Once()
-	suite.mockResourceService.On("GetResourceStatusChannel", resourceID).Return(statusCh, true).Once()
-	suite.mockResourceService.On("RemoveResourceStatusChannel", resourceID).Once()
 	
 	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
 	
@@ -281,6 +376,7 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_UpdateStatusError()
 	expectedError := errors.New("update failed")
 	
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(resourcemodel.Resource{}, expectedError).Once()
 	
 	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
@@ -311,8 +407,8 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_NoStatusChannel() {
 	
 	// Setup expectations - no status channel exists
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
-	suite.mockResourceService.On("GetResourceStatusChannel", resourceID).Return(nil, false).Once()
 	
 	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
 	
@@ -338,22 +434,163 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_FullStatusChannel()
 	updatedResource := resource
 	updatedResource.Status = resourcemodel.ResourceStatusCompleted
 	
-	// Create a channel with no buffer (will be full immediately)
-	statusCh := make(chan resourcemodel.ResourceStatusUpdate)
+	// Nobody reads the queued progress updates
+	statusCh := suite.fillStatusChannel(resourceID)
 	
 	// Setup expectations
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
-	suite.mockResourceService.On("GetResourceStatusChannel", resourceID).Return(statusCh, true).Once()
-	suite.mockResourceService.On("RemoveResourceStatusChannel", resourceID).Once()
 	
 	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
 	
 	assert.NoError(suite.T(), err)
 	
-	// Verify channel is closed
-	_, ok := <-statusCh
-	assert.False(suite.T(), ok, "Channel should be closed")
+	// The final update was dropped and the channel closed
+	for update := range statusCh {
+		assert.True(suite.T(), update.IsProgress())
+	}
+}
+
+// TestHandleMessage_FinalUpdateNotDroppedBehindProgress tests that the final update waits for a busy reader
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_FinalUpdateNotDroppedBehindProgress() {
+	resourceID := uuid.New()
+	event := IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+	}
+
+	eventJSON, _ := json.Marshal(event)
+
+	resource := resourcemodel.Resource{
+		ID:     resourceID,
+		Status: resourcemodel.ResourceStatusProcessing,
+	}
+
+	updatedResource := resource
+	updatedResource.Status = resourcemodel.ResourceStatusCompleted
+
+	// Channel is full of queued progress updates
+	statusCh := suite.fillStatusChannel(resourceID)
+
+	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
+	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
+
+	received := make(chan []resourcemodel.ResourceStatusUpdate)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		var updates []resourcemodel.ResourceStatusUpdate
+		for update := range statusCh {
+			updates = append(updates, update)
+		}
+		received <- updates
+	}()
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
+	assert.NoError(suite.T(), err)
+
+	updates := <-received
+	if assert.NotEmpty(suite.T(), updates) {
+		assert.Equal(suite.T(), 90, updates[0].Percent)
+		last := updates[len(updates)-1]
+		assert.Equal(suite.T(), resourcemodel.ResourceStatusCompleted, last.Status)
+		assert.Equal(suite.T(), 100, last.Percent)
+	}
+}
+
+// TestHandleMessage_ProgressForwarded tests that progress events are forwarded to the status channel
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_ProgressForwarded() {
+	resourceID := uuid.New()
+	eventJSON, _ := json.Marshal(IndexationProgressEvent{ResourceID: resourceID, Percent: 40})
+
+	statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
+
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_progress", resourceID.String(), eventJSON, nil)
+
+	assert.NoError(suite.T(), err)
+	update := <-statusCh
+	assert.Equal(suite.T(), resourceID, update.ResourceID)
+	assert.Equal(suite.T(), resourcemodel.ResourceStatusProcessing, update.Status)
+	assert.Equal(suite.T(), 40, update.Percent)
+	assert.True(suite.T(), update.IsProgress())
+}
+
+// TestHandleMessage_ProgressSkippedWhenChannelBusy tests that progress never blocks on a busy channel
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_ProgressSkippedWhenChannelBusy() {
+	resourceID := uuid.New()
+	eventJSON, _ := json.Marshal(IndexationProgressEvent{ResourceID: resourceID, Percent: 40})
+
+	statusCh := suite.fillStatusChannel(resourceID)
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_progress", resourceID.String(), eventJSON, nil)
+
+	assert.NoError(suite.T(), err)
+	for len(statusCh) > 0 {
+		assert.Equal(suite.T(), 90, (<-statusCh).Percent, "the skipped update is not queued")
+	}
+}
+
+// TestHandleMessage_ProgressDuringCompletion tests that progress handled at the
+// same time as the completion never sends on the closed status channel
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_ProgressDuringCompletion() {
+	for range 20 {
+		resourceID := uuid.New()
+		resource := resourcemodel.Resource{ID: resourceID, Status: resourcemodel.ResourceStatusProcessing}
+		completed := resource
+		completed.Status = resourcemodel.ResourceStatusCompleted
+
+		suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+		suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
+		suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(completed, nil).Once()
+
+		statusCh := suite.mockResourceService.RegisterResourceStatusChannel(resourceID)
+		received := make(chan []resourcemodel.ResourceStatusUpdate)
+		go func() {
+			var updates []resourcemodel.ResourceStatusUpdate
+			for update := range statusCh {
+				updates = append(updates, update)
+			}
+			received <- updates
+		}()
+
+		completeJSON, _ := json.Marshal(IndexationCompleteEvent{ResourceID: resourceID, Success: true})
+		var wg sync.WaitGroup
+		for percent := 10; percent < 100; percent += 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				progressJSON, _ := json.Marshal(IndexationProgressEvent{ResourceID: resourceID, Percent: percent})
+				assert.NoError(suite.T(), suite.processor.HandleMessage(suite.ctx, "indexation_progress", resourceID.String(), progressJSON, nil))
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(suite.T(), suite.processor.HandleMessage(suite.ctx, "indexation_complete", resourceID.String(), completeJSON, nil))
+		}()
+		wg.Wait()
+
+		// Progress handled after the completion is dropped
+		assert.NoError(suite.T(), suite.processor.HandleMessage(suite.ctx, "indexation_progress", resourceID.String(),
+			[]byte(`{"resource_id":"`+resourceID.String()+`","percent":95}`), nil))
+
+		updates := <-received
+		if assert.NotEmpty(suite.T(), updates) {
+			assert.Equal(suite.T(), resourcemodel.ResourceStatusCompleted, updates[len(updates)-1].Status)
+		}
+	}
+}
+
+// TestHandleMessage_ProgressWithoutChannel tests that progress for unknown resources is ignored
+func (suite *IndexationProcessorTestSuite) TestHandleMessage_ProgressWithoutChannel() {
+	resourceID := uuid.New()
+	eventJSON, _ := json.Marshal(IndexationProgressEvent{ResourceID: resourceID, Percent: 40})
+
+	err := suite.processor.HandleMessage(suite.ctx, "indexation_progress", resourceID.String(), eventJSON, nil)
+
+	assert.NoError(suite.T(), err)
 }
 
 // TestHandleMessage_ContextCancellation tests handling context cancellation during status update
@@ -375,7 +612,7 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_ContextCancellation
 	updatedResource := resource
 	updatedResource.Status = resourcemodel.ResourceStatusCompleted
 	
-	statusCh := make(chan resourcemodel.ResourceStatusUpdate)
+	suite.fillStatusChannel(resourceID)
 	
 	// Create a context that will be cancelled immediately
 	ctx, cancel := context.WithCancel(suite.ctx)
@@ -383,8 +620,8 @@ func (suite *IndexationProcessorTestSuite) TestHandleMessage_ContextCancellation
 	
 	// Setup expectations
 	suite.mockResourceService.On("GetResourceByID", mock.Anything, resourceID).Return(resource, nil).Once()
+	suite.mockResourceService.On("UpdateResourceChunks", mock.Anything, resourceID, []string(nil), []string(nil)).Return(nil).Once()
 	suite.mockResourceService.On("UpdateResourceStatus", mock.Anything, resource, resourcemodel.ResourceStatusCompleted).Return(updatedResource, nil).Once()
-	suite.mockResourceService.On("GetResourceStatusChannel", resourceID).Return(statusCh, true).Once()
 	
 	err := suite.processor.HandleMessage(ctx, "indexation_complete", resourceID.String(), eventJSON, nil)
 	
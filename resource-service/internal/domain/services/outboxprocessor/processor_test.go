@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/resource-service/internal/metrics"
 )
 
 // MockEventService is a simple mock implementation of the eventService interface
@@ -24,6 +26,8 @@ type MockEventService struct {
 	processEventErrorMap     map[string]error // Map event ID to error for more control
 	processEventCallSequence []error          // Sequence of errors to return on successive calls
 	processEventCallIndex    int
+	unsentCount              int
+	countUnsentEventsError   error
 }
 
 func (m *MockEventService) GetUnsentEvents(ctx context.Context, limit, offset int) ([]eventmodel.Event, error) {
@@ -33,6 +37,12 @@ func (m *MockEventService) GetUnsentEvents(ctx context.Context, limit, offset in
 	return m.getUnsentEventsResponse, m.getUnsentEventsError
 }
 
+func (m *MockEventService) CountUnsentEvents(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.unsentCount, m.countUnsentEventsError
+}
+
 func (m *MockEventService) ProcessEvent(ctx context.Context, event eventmodel.Event) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -134,6 +144,9 @@ func TestNewOutboxProcessor(t *testing.T) {
 		if processor.config.RetryDelay != 5*time.Second {
 			t.Errorf("expected default retry delay 5s, got %v", processor.config.RetryDelay)
 		}
+		if processor.config.MaxRetryDelay != time.Minute {
+			t.Errorf("expected default max retry delay 1m, got %v", processor.config.MaxRetryDelay)
+		}
 	})
 }
 
@@ -255,6 +268,25 @@ func TestProcessor_processEvents_NoEvents(t *testing.T) {
 	}
 }
 
+func TestProcessor_processEvents_ReportsWholeBacklog(t *testing.T) {
+	events := []eventmodel.Event{
+		{ID: uuid.New(), Name: "resource.created"},
+		{ID: uuid.New(), Name: "resource.created"},
+	}
+	// The batch is full and many more events wait behind it
+	mockService := &MockEventService{
+		getUnsentEventsResponse: events,
+		unsentCount:             250,
+	}
+
+	processor := NewOutboxProcessor(mockService, Config{BatchSize: len(events)})
+	processor.processEvents(context.Background())
+
+	if got := testutil.ToFloat64(metrics.OutboxUnsentBacklog); got != 250 {
+		t.Errorf("expected an outbox backlog of 250, got %v", got)
+	}
+}
+
 func TestProcessor_processEvents_DatabaseError(t *testing.T) {
 	expectedError := errors.New("database error")
 	mockService := &MockEventService{
@@ -319,6 +351,36 @@ func TestProcessor_processEvents_SuccessfulProcessing(t *testing.T) {
 	}
 }
 
+func TestProcessor_processEvents_PreservesEventTimeOrder(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	events := make([]eventmodel.Event, 10)
+	for i := range events {
+		events[i] = eventmodel.Event{
+			ID:        uuid.New(),
+			Name:      "resource.updated",
+			Topic:     "resources",
+			Payload:   []byte(`{}`),
+			EventTime: created.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	mockService := &MockEventService{getUnsentEventsResponse: events}
+
+	processor := NewDefaultOutboxProcessor(mockService)
+	processor.processEvents(context.Background())
+
+	processedEvents := mockService.GetProcessedEvents()
+	if len(processedEvents) != len(events) {
+		t.Fatalf("expected %d processed events, got %d", len(events), len(processedEvents))
+	}
+	for i, event := range processedEvents {
+		if event.ID != events[i].ID {
+			t.Fatalf("event %d processed out of order: got the one created at %s, want %s",
+				i, event.EventTime.Format(time.TimeOnly), events[i].EventTime.Format(time.TimeOnly))
+		}
+	}
+}
+
 func TestProcessor_processEventWithRetry_SuccessFirstAttempt(t *testing.T) {
 	event := eventmodel.Event{
 		ID:        uuid.New(),
@@ -461,6 +523,78 @@ func TestProcessor_processEventWithRetry_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestProcessor_processEventWithRetry_ExponentialBackoff(t *testing.T) {
+	event := eventmodel.Event{
+		ID:        uuid.New(),
+		Name:      "test.event",
+		Topic:     "test.topic",
+		Payload:   []byte(`{"test": "data"}`),
+		Sent:      false,
+		EventTime: time.Now(),
+	}
+
+	mockService := &MockEventService{
+		processEventError: errors.New("broker unavailable"),
+	}
+
+	config := Config{
+		Interval:      30 * time.Second,
+		BatchSize:     100,
+		MaxRetries:    6,
+		RetryDelay:    100 * time.Millisecond,
+		MaxRetryDelay: time.Second,
+	}
+
+	processor := NewOutboxProcessor(mockService, config)
+
+	var delays []time.Duration
+	processor.after = func(d time.Duration) <-chan time.Time {
+		delays = append(delays, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+
+	err := processor.processEventWithRetry(context.Background(), event)
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	if len(delays) != len(expected) {
+		t.Fatalf("expected %d delays, got %d: %v", len(expected), len(delays), delays)
+	}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("expected delay %v before attempt %d, got %v", expected[i], i+2, delays[i])
+		}
+	}
+}
+
+func TestProcessor_retryDelay_Jitter(t *testing.T) {
+	config := Config{
+		RetryDelay:    time.Second,
+		MaxRetryDelay: time.Minute,
+		RetryJitter:   0.5,
+	}
+
+	processor := NewOutboxProcessor(&MockEventService{}, config)
+
+	for i := 0; i < 100; i++ {
+		delay := processor.retryDelay(3)
+		if delay < 2*time.Second || delay > 6*time.Second {
+			t.Fatalf("expected jittered delay within [2s, 6s], got %v", delay)
+		}
+	}
+}
+
 func TestProcessor_ProcessNow(t *testing.T) {
 	events := []eventmodel.Event{
 		{
@@ -477,16 +611,20 @@ func TestProcessor_ProcessNow(t *testing.T) {
 		getUnsentEventsResponse: events,
 		getUnsentEventsError:    nil,
 		processEventError:       nil,
+		unsentCount:             len(events),
 	}
 
 	processor := NewDefaultOutboxProcessor(mockService)
 
 	ctx := context.Background()
-	err := processor.ProcessNow(ctx)
+	result, err := processor.ProcessNow(ctx)
 
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
+	if result.Processed != 1 || result.Failed != 0 {
+		t.Errorf("expected 1 processed and 0 failed events, got %+v", result)
+	}
 	if mockService.getUnsentEventsCalls != 1 {
 		t.Errorf("expected 1 call to GetUnsentEvents, got %d", mockService.getUnsentEventsCalls)
 	}
@@ -546,3 +684,163 @@ func TestProcessor_ProcessEvents_MixedSuccessAndFailure(t *testing.T) {
 		t.Errorf("expected %d calls to ProcessEvent, got %d", expectedCalls, mockService.processEventCalls)
 	}
 }
+
+func TestProcessor_processEvents_StopsAtFailedEvent(t *testing.T) {
+	created := time.Now().Add(-time.Hour)
+	events := make([]eventmodel.Event, 4)
+	for i := range events {
+		events[i] = eventmodel.Event{
+			ID:        uuid.New(),
+			Name:      "resource.updated",
+			Topic:     "resources",
+			Payload:   []byte(`{}`),
+			EventTime: created.Add(time.Duration(i) * time.Second),
+		}
+	}
+
+	mockService := &MockEventService{getUnsentEventsResponse: events}
+	mockService.SetProcessEventErrorForEvent(events[1].ID.String(), errors.New("broker unavailable"))
+
+	config := Config{
+		Interval:   30 * time.Second,
+		BatchSize:  100,
+		MaxRetries: 2,
+		RetryDelay: 1 * time.Millisecond,
+	}
+
+	processor := NewOutboxProcessor(mockService, config)
+	processor.processEvents(context.Background())
+
+	// The events after the failed one are left for the next run
+	var processedIDs []uuid.UUID
+	for _, event := range mockService.GetProcessedEvents() {
+		processedIDs = append(processedIDs, event.ID)
+	}
+	expectedIDs := []uuid.UUID{events[0].ID, events[1].ID, events[1].ID}
+	if len(processedIDs) != len(expectedIDs) {
+		t.Fatalf("expected ProcessEvent calls for %v, got %v", expectedIDs, processedIDs)
+	}
+	for i := range expectedIDs {
+		if processedIDs[i] != expectedIDs[i] {
+			t.Fatalf("expected ProcessEvent calls for %v, got %v", expectedIDs, processedIDs)
+		}
+	}
+}
+
+// outbox keeps events in memory, marks them as sent once delivered and fails
+// to deliver the ones in failing
+type outbox struct {
+	mu      sync.Mutex
+	events  []eventmodel.Event
+	failing map[uuid.UUID]bool
+}
+
+func newOutbox(count int) *outbox {
+	o := &outbox{failing: make(map[uuid.UUID]bool)}
+	created := time.Now().Add(-time.Hour)
+	for i := range count {
+		o.events = append(o.events, eventmodel.Event{
+			ID:        uuid.New(),
+			Name:      "resource.updated",
+			EventTime: created.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return o
+}
+
+func (o *outbox) GetUnsentEvents(_ context.Context, limit, offset int) ([]eventmodel.Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var unsent []eventmodel.Event
+	for _, event := range o.events {
+		if !event.Sent {
+			unsent = append(unsent, event)
+		}
+	}
+	if offset >= len(unsent) {
+		return nil, nil
+	}
+	return unsent[offset:min(offset+limit, len(unsent))], nil
+}
+
+func (o *outbox) CountUnsentEvents(_ context.Context) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	count := 0
+	for _, event := range o.events {
+		if !event.Sent {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (o *outbox) ProcessEvent(_ context.Context, event eventmodel.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failing[event.ID] {
+		return errors.New("broker unavailable")
+	}
+	for i := range o.events {
+		if o.events[i].ID == event.ID {
+			o.events[i].Sent = true
+		}
+	}
+	return nil
+}
+
+func newFlushProcessor(o *outbox) *Processor {
+	p := NewOutboxProcessor(o, Config{BatchSize: 2, MaxRetries: 2, RetryDelay: time.Millisecond})
+	p.after = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	return p
+}
+
+func TestProcessor_ProcessNow_DrainsEveryBatch(t *testing.T) {
+	o := newOutbox(5)
+
+	result, err := newFlushProcessor(o).ProcessNow(context.Background())
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := (FlushResult{Processed: 5, Failed: 0, Remaining: 0}); result != want {
+		t.Errorf("expected %+v, got %+v", want, result)
+	}
+	if got := testutil.ToFloat64(metrics.OutboxUnsentBacklog); got != 0 {
+		t.Errorf("expected an outbox backlog of 0, got %v", got)
+	}
+}
+
+func TestProcessor_ProcessNow_StopsAtFailingEvent(t *testing.T) {
+	o := newOutbox(5)
+	o.failing[o.events[3].ID] = true
+
+	result, err := newFlushProcessor(o).ProcessNow(context.Background())
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := (FlushResult{Processed: 3, Failed: 1, Remaining: 2}); result != want {
+		t.Errorf("expected %+v, got %+v", want, result)
+	}
+}
+
+func TestProcessor_ProcessNow_CountError(t *testing.T) {
+	mockService := &MockEventService{countUnsentEventsError: errors.New("database error")}
+
+	_, err := NewDefaultOutboxProcessor(mockService).ProcessNow(context.Background())
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if mockService.getUnsentEventsCalls != 0 {
+		t.Errorf("expected no call to GetUnsentEvents, got %d", mockService.getUnsentEventsCalls)
+	}
+}
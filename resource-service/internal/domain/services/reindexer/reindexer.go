@@ -0,0 +1,297 @@
+package reindexer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/configurator"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+var (
+	// ErrAlreadyRunning is returned when a reindex is started while another one runs
+	ErrAlreadyRunning = errors.New("reindex is already running")
+	// ErrNotRunning is returned when no reindex runs that could be cancelled
+	ErrNotRunning = errors.New("no reindex is running")
+)
+
+// resourceService defines the operations the reindexer needs on resources
+type resourceService interface {
+	GetAllResources(ctx context.Context, limit, offset int) ([]resourcemodel.Resource, error)
+	GetUsersResources(ctx context.Context, userID uuid.UUID, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error)
+	CountAllResources(ctx context.Context) (int, error)
+	CountUsersResources(ctx context.Context, userID uuid.UUID) (int, error)
+	ReindexResource(ctx context.Context, resource resourcemodel.Resource) error
+}
+
+// Config holds configuration for the reindexer
+type Config struct {
+	// PageSize is the number of resources read at a time
+	PageSize int `yaml:"page_size" mapstructure:"page_size" validate:"min=1"`
+	// Rate is the number of reindex events published per second, which
+	// bounds the load put on the embedder
+	Rate float64 `yaml:"rate" mapstructure:"rate" validate:"gt=0"`
+}
+
+// DefaultConfig returns the reindexer settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		PageSize: 100,
+		Rate:     2,
+	}
+}
+
+// NewConfig loads the reindexer settings from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("reindex", DefaultConfig())
+}
+
+// State is the stage a reindex is in
+type State string
+
+const (
+	StateIdle      State = "idle"
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateCancelled State = "cancelled"
+	StateFailed    State = "failed"
+)
+
+// Progress reports how far the current or last reindex got
+type Progress struct {
+	State State `json:"state"`
+	// OwnerID limits the reindex to the resources of one user, all users' when nil
+	OwnerID *uuid.UUID `json:"owner_id,omitempty"`
+	// Total is the number of resources when the reindex started
+	Total int `json:"total"`
+	// Enqueued is the number of resources a reindex event was published for
+	Enqueued int `json:"enqueued"`
+	// Skipped is the number of resources still being indexed or cancelled by their owner
+	Skipped int `json:"skipped"`
+	// Failed is the number of resources whose reindex event could not be stored
+	Failed     int        `json:"failed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Reindexer republishes the reindex event of every resource, or of the
+// resources of one user, e.g. after the embedding model was upgraded. Events
+// are published at the configured rate in the background; one reindex runs
+// at a time.
+type Reindexer struct {
+	resourceService resourceService
+	config          Config
+	now             func() time.Time
+
+	mu       sync.Mutex
+	progress Progress
+	cancel   context.CancelFunc
+	doneCh   chan struct{}
+}
+
+// NewReindexer creates a reindexer with the given configuration
+func NewReindexer(resourceService resourceService, config Config) *Reindexer {
+	return &Reindexer{
+		resourceService: resourceService,
+		config:          config,
+		now:             time.Now,
+		progress:        Progress{State: StateIdle},
+	}
+}
+
+// Start begins reindexing the resources of ownerID, or of all users when it
+// is nil, and returns the initial progress. The reindex outlives ctx, it ends
+// when every resource was visited or on Cancel.
+func (r *Reindexer) Start(ctx context.Context, ownerID *uuid.UUID) (Progress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.progress.State == StateRunning {
+		return r.progress, ErrAlreadyRunning
+	}
+
+	total, err := r.count(ctx, ownerID)
+	if err != nil {
+		return r.progress, err
+	}
+
+	startedAt := r.now()
+	r.progress = Progress{
+		State:     StateRunning,
+		OwnerID:   ownerID,
+		Total:     total,
+		StartedAt: &startedAt,
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	r.cancel = cancel
+	r.doneCh = make(chan struct{})
+	go r.run(jobCtx, ownerID, r.doneCh)
+
+	slog.InfoContext(ctx, "Started reindex",
+		"owner_id", ownerID,
+		"total", total,
+		"rate", r.config.Rate)
+	return r.progress, nil
+}
+
+// Progress returns the progress of the current or last reindex
+func (r *Reindexer) Progress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress
+}
+
+// Cancel stops the running reindex and returns its final progress. Events
+// already published are still processed by search-service.
+func (r *Reindexer) Cancel() (Progress, error) {
+	r.mu.Lock()
+	if r.progress.State != StateRunning {
+		r.mu.Unlock()
+		return r.Progress(), ErrNotRunning
+	}
+	cancel, doneCh := r.cancel, r.doneCh
+	r.mu.Unlock()
+
+	cancel()
+	<-doneCh
+	return r.Progress(), nil
+}
+
+// Stop cancels the running reindex, if any, and waits for it to end
+func (r *Reindexer) Stop() {
+	_, _ = r.Cancel()
+}
+
+// run publishes the reindex events a page at a time until every resource was
+// visited or ctx is cancelled
+func (r *Reindexer) run(ctx context.Context, ownerID *uuid.UUID, doneCh chan struct{}) {
+	const op = "Reindexer.run"
+	defer close(doneCh)
+
+	interval := time.Duration(float64(time.Second) / r.config.Rate)
+	published := false
+
+	for offset := 0; ; offset += r.config.PageSize {
+		page, err := r.page(ctx, ownerID, offset)
+		if err != nil {
+			r.finish(ctx, err)
+			return
+		}
+
+		for _, resource := range page {
+			if !reindexable(resource) {
+				r.update(func(p *Progress) { p.Skipped++ })
+				continue
+			}
+
+			if published {
+				if err := wait(ctx, interval); err != nil {
+					r.finish(ctx, err)
+					return
+				}
+			}
+			published = true
+
+			if err := r.resourceService.ReindexResource(ctx, resource); err != nil {
+				if ctx.Err() != nil {
+					r.finish(ctx, ctx.Err())
+					return
+				}
+				slog.ErrorContext(ctx, "Failed to reindex resource",
+					"op", op,
+					"resource_id", resource.ID,
+					"error", err)
+				r.update(func(p *Progress) { p.Failed++ })
+				continue
+			}
+			r.update(func(p *Progress) { p.Enqueued++ })
+		}
+
+		if len(page) < r.config.PageSize {
+			r.finish(ctx, nil)
+			return
+		}
+	}
+}
+
+// page returns the resources of the reindex starting at offset. A user's
+// resources are read oldest first, so that new ones don't move the others
+// between pages. The listing of all users is newest first; resources it
+// visits again are being reindexed by then and skipped.
+func (r *Reindexer) page(ctx context.Context, ownerID *uuid.UUID, offset int) ([]resourcemodel.Resource, error) {
+	if ownerID == nil {
+		return r.resourceService.GetAllResources(ctx, r.config.PageSize, offset)
+	}
+	return r.resourceService.GetUsersResources(ctx, *ownerID, r.config.PageSize, offset,
+		resourcemodel.ResourceSort{Field: resourcemodel.ResourceSortCreatedAt, Order: resourcemodel.SortOrderAsc})
+}
+
+// count returns the number of resources the reindex visits
+func (r *Reindexer) count(ctx context.Context, ownerID *uuid.UUID) (int, error) {
+	if ownerID == nil {
+		return r.resourceService.CountAllResources(ctx)
+	}
+	return r.resourceService.CountUsersResources(ctx, *ownerID)
+}
+
+// update changes the progress of the running reindex
+func (r *Reindexer) update(fn func(p *Progress)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.progress)
+}
+
+// finish records how the reindex ended, err being nil when every resource was visited
+func (r *Reindexer) finish(ctx context.Context, err error) {
+	finishedAt := r.now()
+
+	r.mu.Lock()
+	r.progress.FinishedAt = &finishedAt
+	switch {
+	case err == nil:
+		r.progress.State = StateCompleted
+	case errors.Is(err, context.Canceled):
+		r.progress.State = StateCancelled
+	default:
+		r.progress.State = StateFailed
+		r.progress.Error = err.Error()
+	}
+	progress := r.progress
+	r.mu.Unlock()
+
+	slog.InfoContext(ctx, "Finished reindex",
+		"state", progress.State,
+		"owner_id", progress.OwnerID,
+		"enqueued", progress.Enqueued,
+		"skipped", progress.Skipped,
+		"failed", progress.Failed,
+		"error", progress.Error)
+}
+
+// reindexable reports whether the resource's indexation finished and its
+// owner didn't cancel it. Resources still being indexed get the current model
+// anyway.
+func reindexable(resource resourcemodel.Resource) bool {
+	return resource.Status == resourcemodel.ResourceStatusCompleted ||
+		resource.Status == resourcemodel.ResourceStatusFailed
+}
+
+// wait sleeps for d unless ctx is cancelled first
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,228 @@
+package reindexer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// fakeResourceService keeps resources in memory and records the reindexed
+// ones. Reindexing blocks while gate is set until it is closed.
+type fakeResourceService struct {
+	mu        sync.Mutex
+	resources []resourcemodel.Resource
+	reindexed []uuid.UUID
+	failing   map[uuid.UUID]bool
+	gate      chan struct{}
+}
+
+func (s *fakeResourceService) seed(ownerID uuid.UUID, status resourcemodel.ResourceStatus) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resource := resourcemodel.Resource{
+		ID:        uuid.New(),
+		OwnerID:   ownerID,
+		Status:    status,
+		CreatedAt: time.Now().Add(time.Duration(len(s.resources)) * time.Second),
+	}
+	s.resources = append(s.resources, resource)
+	return resource.ID
+}
+
+func (s *fakeResourceService) filter(ownerID *uuid.UUID) []resourcemodel.Resource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var resources []resourcemodel.Resource
+	for _, resource := range s.resources {
+		if ownerID == nil || resource.OwnerID == *ownerID {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+func paginate(resources []resourcemodel.Resource, limit, offset int) []resourcemodel.Resource {
+	if offset >= len(resources) {
+		return nil
+	}
+	return resources[offset:min(offset+limit, len(resources))]
+}
+
+func (s *fakeResourceService) GetAllResources(_ context.Context, limit, offset int) ([]resourcemodel.Resource, error) {
+	resources := s.filter(nil)
+	sort.Slice(resources, func(i, j int) bool { return resources[i].CreatedAt.After(resources[j].CreatedAt) })
+	return paginate(resources, limit, offset), nil
+}
+
+func (s *fakeResourceService) GetUsersResources(_ context.Context, userID uuid.UUID, limit, offset int, _ ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	return paginate(s.filter(&userID), limit, offset), nil
+}
+
+func (s *fakeResourceService) CountAllResources(_ context.Context) (int, error) {
+	return len(s.filter(nil)), nil
+}
+
+func (s *fakeResourceService) CountUsersResources(_ context.Context, userID uuid.UUID) (int, error) {
+	return len(s.filter(&userID)), nil
+}
+
+func (s *fakeResourceService) ReindexResource(ctx context.Context, resource resourcemodel.Resource) error {
+	if s.gate != nil {
+		select {
+		case <-s.gate:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing[resource.ID] {
+		return errors.New("outbox insert failed")
+	}
+	s.reindexed = append(s.reindexed, resource.ID)
+	return nil
+}
+
+func (s *fakeResourceService) reindexedIDs() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uuid.UUID(nil), s.reindexed...)
+}
+
+// waitFinished polls until the reindex is no longer running
+func waitFinished(t *testing.T, r *Reindexer) Progress {
+	t.Helper()
+	var progress Progress
+	require.Eventually(t, func() bool {
+		progress = r.Progress()
+		return progress.State != StateRunning
+	}, 5*time.Second, time.Millisecond)
+	return progress
+}
+
+func TestReindexer_EnqueuesEveryResource(t *testing.T) {
+	service := &fakeResourceService{}
+	var targets []uuid.UUID
+	for i := 0; i < 5; i++ {
+		targets = append(targets, service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted))
+	}
+	targets = append(targets, service.seed(uuid.New(), resourcemodel.ResourceStatusFailed))
+	service.seed(uuid.New(), resourcemodel.ResourceStatusProcessing)
+	service.seed(uuid.New(), resourcemodel.ResourceStatusCancelled)
+
+	r := NewReindexer(service, Config{PageSize: 2, Rate: 1000})
+
+	started, err := r.Start(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, StateRunning, started.State)
+	assert.Equal(t, 8, started.Total)
+
+	progress := waitFinished(t, r)
+
+	// Every finished resource gets exactly one reindex event
+	assert.Equal(t, StateCompleted, progress.State)
+	assert.ElementsMatch(t, targets, service.reindexedIDs())
+	assert.Equal(t, len(targets), progress.Enqueued)
+	assert.Equal(t, 2, progress.Skipped)
+	assert.Zero(t, progress.Failed)
+	assert.NotNil(t, progress.FinishedAt)
+}
+
+func TestReindexer_ScopedToUser(t *testing.T) {
+	service := &fakeResourceService{}
+	ownerID := uuid.New()
+	targets := []uuid.UUID{
+		service.seed(ownerID, resourcemodel.ResourceStatusCompleted),
+		service.seed(ownerID, resourcemodel.ResourceStatusCompleted),
+		service.seed(ownerID, resourcemodel.ResourceStatusCompleted),
+	}
+	service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted)
+
+	r := NewReindexer(service, Config{PageSize: 3, Rate: 1000})
+
+	started, err := r.Start(context.Background(), &ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, started.Total)
+
+	progress := waitFinished(t, r)
+
+	assert.Equal(t, StateCompleted, progress.State)
+	assert.Equal(t, &ownerID, progress.OwnerID)
+	assert.ElementsMatch(t, targets, service.reindexedIDs())
+}
+
+func TestReindexer_CountsFailures(t *testing.T) {
+	service := &fakeResourceService{}
+	failing := service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted)
+	reindexed := service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted)
+	service.failing = map[uuid.UUID]bool{failing: true}
+
+	r := NewReindexer(service, Config{PageSize: 10, Rate: 1000})
+
+	_, err := r.Start(context.Background(), nil)
+	require.NoError(t, err)
+
+	// A failed resource doesn't stop the others
+	progress := waitFinished(t, r)
+	assert.Equal(t, StateCompleted, progress.State)
+	assert.Equal(t, 1, progress.Failed)
+	assert.Equal(t, []uuid.UUID{reindexed}, service.reindexedIDs())
+}
+
+func TestReindexer_Cancel(t *testing.T) {
+	service := &fakeResourceService{gate: make(chan struct{})}
+	for i := 0; i < 3; i++ {
+		service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted)
+	}
+
+	r := NewReindexer(service, Config{PageSize: 10, Rate: 1000})
+
+	_, err := r.Start(context.Background(), nil)
+	require.NoError(t, err)
+
+	_, err = r.Start(context.Background(), nil)
+	require.ErrorIs(t, err, ErrAlreadyRunning)
+
+	progress, err := r.Cancel()
+	require.NoError(t, err)
+	assert.Equal(t, StateCancelled, progress.State)
+	assert.Empty(t, service.reindexedIDs())
+
+	_, err = r.Cancel()
+	require.ErrorIs(t, err, ErrNotRunning)
+
+	// A new reindex may start once the previous one ended
+	close(service.gate)
+	_, err = r.Start(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, StateCompleted, waitFinished(t, r).State)
+	assert.Len(t, service.reindexedIDs(), 3)
+}
+
+func TestReindexer_Throttled(t *testing.T) {
+	service := &fakeResourceService{}
+	for i := 0; i < 3; i++ {
+		service.seed(uuid.New(), resourcemodel.ResourceStatusCompleted)
+	}
+
+	r := NewReindexer(service, Config{PageSize: 10, Rate: 20})
+
+	started := time.Now()
+	_, err := r.Start(context.Background(), nil)
+	require.NoError(t, err)
+	waitFinished(t, r)
+
+	// Three events at 20 per second wait two intervals of 50ms
+	assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+	assert.Len(t, service.reindexedIDs(), 3)
+}
@@ -0,0 +1,56 @@
+package resourceservcie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/tmc/langchaingo/textsplitter"
+)
+
+// Chunking of search-service, which stores the hash of every chunk it embeds.
+// Content is split the same way here, with the chunking override of the
+// resource if any, to find the chunks an edit touched. When search-service
+// chunks differently, e.g. with a configured size, no hash matches and the
+// patch simply replaces every chunk.
+const (
+	chunkSize    = 512
+	chunkOverlap = 100
+)
+
+// markdownImageRe matches the inline images search-service strips before chunking
+var markdownImageRe = regexp.MustCompile(`!\[[^\]]*\]\([^)]+\)`)
+
+// splitChunks splits extracted content into chunks and returns them with their
+// hashes. A zero size and a nil overlap split with the defaults.
+func splitChunks(content string, size int, overlap *int) ([]string, []string, error) {
+	if size <= 0 {
+		size = chunkSize
+	}
+	overlapSize := chunkOverlap
+	if overlap != nil {
+		overlapSize = *overlap
+	}
+
+	splitter := textsplitter.NewMarkdownTextSplitter(
+		textsplitter.WithChunkSize(size),
+		textsplitter.WithChunkOverlap(overlapSize),
+	)
+
+	chunks, err := splitter.SplitText(markdownImageRe.ReplaceAllString(content, ""))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		hashes[i] = hashChunk(chunk)
+	}
+	return chunks, hashes, nil
+}
+
+// hashChunk returns the hex encoded SHA-256 of the chunk content
+func hashChunk(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,9 +2,9 @@ package resourceservcie
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,20 +14,37 @@ import (
 
 const ResourceTopicName = "resources"
 
+// statusChannelBuffer lets progress updates queue up while the SSE writer is busy
+const statusChannelBuffer = 16
+
+// cancelledUpdateTimeout bounds how long the cancelled status update waits for a reader
+const cancelledUpdateTimeout = 5 * time.Second
+
 type resourceRepository interface {
 	ResourceOwnedByUser(ctx context.Context, resourceID uuid.UUID, userID uuid.UUID) (bool, error)
 	GetResources(ctx context.Context, limit int, offset int) ([]resourcemodel.Resource, error)
-	GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, limit int, offset int) ([]resourcemodel.Resource, error)
+	GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error)
+	GetResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error)
+	CountResources(ctx context.Context) (int, error)
+	CountResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID) (int, error)
+	CountResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus) (int, error)
+	CountResourcesByOwnerIDPerStatus(ctx context.Context, ownerID uuid.UUID) (map[resourcemodel.ResourceStatus]int, error)
 	GetUsersResourceByID(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.Resource, error)
+	GetUsersResourceStatus(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.ResourceStatus, error)
+	GetUsersResourceByContentHash(ctx context.Context, ownerID uuid.UUID, contentHash string) (resourcemodel.Resource, error)
 	GetResourceByID(ctx context.Context, resourceID uuid.UUID) (resourcemodel.Resource, error)
 	SaveResource(ctx context.Context, resource resourcemodel.Resource) (resourcemodel.Resource, error)
 	UpdateUsersResource(ctx context.Context, userID uuid.UUID, resource resourcemodel.Resource) (resourcemodel.Resource, error)
 	UpdateResourceStatus(ctx context.Context, resourceID uuid.UUID, status resourcemodel.ResourceStatus) (resourcemodel.Resource, error)
+	UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error)
+	UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error
+	FailStaleResources(ctx context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error)
 	DeleteUsersResource(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type contentExtractor interface {
-	ExtractContent(ctx context.Context, data []byte, dataType string) (string, error)
+	ExtractContent(ctx context.Context, data []byte, dataType string, pages ...resourcemodel.PageRange) (string, error)
 }
 
 type eventService interface {
@@ -38,8 +55,7 @@ type Service struct {
 	resourceRepo     resourceRepository
 	contentExtractor contentExtractor
 	eventService     eventService
-	// statusChannels maps resource.ID to resourceStatusUpdate channel
-	statusChannels sync.Map
+	StatusChannels
 }
 
 func NewService(rr resourceRepository, ce contentExtractor, es eventService) *Service {
@@ -53,43 +69,77 @@ func NewService(rr resourceRepository, ce contentExtractor, es eventService) *Se
 }
 
 // SaveUsersResource saves a new resource with the given content and type.
-// It also publishes a resource.created event.
-func (s *Service) SaveUsersResource(ctx context.Context, userID uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, url string) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
+// It also publishes a resource.created event carrying the requested indexation
+// priority, which defaults to normal when empty. Options may set further
+// indexation hints such as a chunking override. Content the user already
+// saved is rejected with a DuplicateResourceError unless WithAllowDuplicate
+// is given.
+func (s *Service) SaveUsersResource(ctx context.Context, userID uuid.UUID, content []byte, resourceType resourcemodel.ResourceType, name, url string, priority resourcemodel.ResourcePriority, opts ...resourcemodel.ResourceOption) (resourcemodel.Resource, <-chan resourcemodel.ResourceStatusUpdate, error) {
 	const op = "Service.SaveUsersResource"
 
-	resourceStatusUpdateCh := make(chan resourcemodel.ResourceStatusUpdate)
+	statusCh := newStatusChannel()
+	resourceStatusUpdateCh := statusCh.ch
 
-	resource := resourcemodel.NewResource(
+	if priority == "" {
+		priority = resourcemodel.ResourcePriorityNormal
+	}
+	if !priority.IsValid() {
+		return resourcemodel.Resource{}, resourceStatusUpdateCh, fmt.Errorf("%s: %w", op, resourcemodel.ErrorWrongPriority)
+	}
+
+	resource := resourcemodel.NewResource(append([]resourcemodel.ResourceOption{
 		resourcemodel.WithOwnerID(userID),
 		resourcemodel.WithRawContent(content),
 		resourcemodel.WithType(resourceType),
 		resourcemodel.WithName(name),
 		resourcemodel.WithURL(url),
 		resourcemodel.WithStatus(resourcemodel.ResourceStatusProcessing),
-	)
+	}, opts...)...)
+	if err := resource.Validate(
+		(*resourcemodel.Resource).HaveCompatibleContent,
+		(*resourcemodel.Resource).HaveValidChunking,
+		(*resourcemodel.Resource).HaveValidPageRange,
+	); err != nil {
+		return resourcemodel.Resource{}, resourceStatusUpdateCh, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Checked before the extraction, which may fetch the resource
+	resource.ContentHash = resourcemodel.HashContent(resource.RawContent)
+	if !resource.AllowDuplicate {
+		if err := s.checkDuplicate(ctx, resource); err != nil {
+			return resourcemodel.Resource{}, resourceStatusUpdateCh, fmt.Errorf("%s: %w", op, err)
+		}
+	}
 
 	resource, err := s.extractContent(ctx, resource)
 	if err != nil {
 		return resourcemodel.Resource{}, resourceStatusUpdateCh, fmt.Errorf("%s: %w", op, err)
 	}
 
-	resource, err = s.resourceRepo.SaveResource(ctx, resource)
+	saved, err := s.resourceRepo.SaveResource(ctx, resource)
 	if err != nil {
 		return resourcemodel.Resource{}, resourceStatusUpdateCh, fmt.Errorf("%s: %w", op, err)
 	}
+	resource = saved
 
-	// Register the status channel in sync.Map for indexation processor.
-	// Note that this channel will be closed when the resource is deleted.
-	s.statusChannels.Store(resource.ID, resourceStatusUpdateCh)
+	// Register the status channel for the indexation processor.
+	// It is closed once the indexation finishes or is cancelled.
+	s.registerStatusChannel(resource.ID, statusCh)
 
-	err = s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.created", map[string]interface{}{
+	eventData := map[string]interface{}{
 		"resource_id": resource.ID,
 		"owner_id":    resource.OwnerID,
+		"tenant_id":   resource.TenantID,
 		"name":        resource.Name,
 		"type":        resource.Type,
 		"status":      resource.Status,
+		"visibility":  resource.Visibility,
+		"priority":    priority,
 		"created_at":  resource.CreatedAt,
-	})
+	}
+	addChunking(eventData, resource)
+
+	err = s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.created", eventData)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to publish resource created event", "error", err)
 		return resourcemodel.Resource{}, resourceStatusUpdateCh, err
@@ -98,10 +148,77 @@ func (s *Service) SaveUsersResource(ctx context.Context, userID uuid.UUID, conte
 	return resource, resourceStatusUpdateCh, nil
 }
 
-func (s *Service) GetUsersResources(ctx context.Context, userID uuid.UUID, limit, offset int) ([]resourcemodel.Resource, error) {
+// addChunking adds the chunking override of the resource to the data of its
+// event, so that search-service indexes it the same way every time
+func addChunking(eventData map[string]interface{}, resource resourcemodel.Resource) {
+	if resource.ChunkSize > 0 {
+		eventData["chunk_size"] = resource.ChunkSize
+	}
+	if resource.ChunkOverlap != nil {
+		eventData["chunk_overlap"] = *resource.ChunkOverlap
+	}
+}
+
+// checkDuplicate returns a DuplicateResourceError when the owner already has a
+// resource with the content of resource
+func (s *Service) checkDuplicate(ctx context.Context, resource resourcemodel.Resource) error {
+	existing, err := s.resourceRepo.GetUsersResourceByContentHash(ctx, resource.OwnerID, resource.ContentHash)
+	if errors.Is(err, resourcemodel.ErrResourceNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Rejected duplicate resource", "existing_resource_id", existing.ID)
+	return &resourcemodel.DuplicateResourceError{ExistingID: existing.ID}
+}
+
+// GetUsersResources returns a page of the resources of the user, sorted by the
+// optional sort or else newest first
+func (s *Service) GetUsersResources(ctx context.Context, userID uuid.UUID, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
 	const op = "Service.GetUsersResources"
 	slog.DebugContext(ctx, "Fetching resources list")
 
+	order := resourcemodel.ResourceSortOrDefault(sort...)
+	if err := order.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if limit == 0 {
+		limit = 10
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	resources, err := s.resourceRepo.GetResourcesByOwnerID(ctx, userID, order, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to retrieve resources",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resources, nil
+}
+
+// GetUsersResourcesByStatus returns the resources of the user having the
+// status, sorted like GetUsersResources
+func (s *Service) GetUsersResourcesByStatus(ctx context.Context, userID uuid.UUID, status resourcemodel.ResourceStatus, limit, offset int, sort ...resourcemodel.ResourceSort) ([]resourcemodel.Resource, error) {
+	const op = "Service.GetUsersResourcesByStatus"
+	slog.DebugContext(ctx, "Fetching resources list by status", "status", status)
+
+	if !status.IsValid() {
+		return nil, fmt.Errorf("%s: %w: %q", op, resourcemodel.ErrorWrongStatus, status)
+	}
+
+	order := resourcemodel.ResourceSortOrDefault(sort...)
+	if err := order.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	if limit == 0 {
 		limit = 10
 	}
@@ -110,7 +227,7 @@ func (s *Service) GetUsersResources(ctx context.Context, userID uuid.UUID, limit
 		offset = 0
 	}
 
-	resources, err := s.resourceRepo.GetResourcesByOwnerID(ctx, userID, limit, offset)
+	resources, err := s.resourceRepo.GetResourcesByOwnerIDAndStatus(ctx, userID, status, order, limit, offset)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to retrieve resources",
 			"op", op,
@@ -121,7 +238,86 @@ func (s *Service) GetUsersResources(ctx context.Context, userID uuid.UUID, limit
 	return resources, nil
 }
 
-func (s *Service) UpdateUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, name *string, content *[]byte) (resourcemodel.Resource, error) {
+// GetAllResources returns the resources of all users. It has no owner filter
+// and must only be reachable by administrators.
+func (s *Service) GetAllResources(ctx context.Context, limit, offset int) ([]resourcemodel.Resource, error) {
+	const op = "Service.GetAllResources"
+	slog.DebugContext(ctx, "Fetching resources of all users")
+
+	if limit == 0 {
+		limit = 10
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	resources, err := s.resourceRepo.GetResources(ctx, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to retrieve resources",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resources, nil
+}
+
+// CountUsersResources returns the number of resources of the user
+func (s *Service) CountUsersResources(ctx context.Context, userID uuid.UUID) (int, error) {
+	const op = "Service.CountUsersResources"
+
+	count, err := s.resourceRepo.CountResourcesByOwnerID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// CountUsersResourcesByStatus returns the number of resources of the user
+// having the status
+func (s *Service) CountUsersResourcesByStatus(ctx context.Context, userID uuid.UUID, status resourcemodel.ResourceStatus) (int, error) {
+	const op = "Service.CountUsersResourcesByStatus"
+
+	if !status.IsValid() {
+		return 0, fmt.Errorf("%s: %w: %q", op, resourcemodel.ErrorWrongStatus, status)
+	}
+
+	count, err := s.resourceRepo.CountResourcesByOwnerIDAndStatus(ctx, userID, status)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// GetUsersResourceStats counts the resources of the user in each status
+func (s *Service) GetUsersResourceStats(ctx context.Context, userID uuid.UUID) (resourcemodel.ResourceStats, error) {
+	const op = "Service.GetUsersResourceStats"
+
+	counts, err := s.resourceRepo.CountResourcesByOwnerIDPerStatus(ctx, userID)
+	if err != nil {
+		return resourcemodel.ResourceStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return resourcemodel.NewResourceStats(counts), nil
+}
+
+// CountAllResources returns the number of resources of all users. Like
+// GetAllResources it must only be reachable by administrators.
+func (s *Service) CountAllResources(ctx context.Context) (int, error) {
+	const op = "Service.CountAllResources"
+
+	count, err := s.resourceRepo.CountResources(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// UpdateUsersResource updates the provided fields of a resource. Changing the
+// content or the type re-extracts the resource and publishes resource.updated,
+// which makes search-service re-index it. Renaming alone publishes the lighter
+// resource.metadata_updated, which re-embeds nothing but the name.
+func (s *Service) UpdateUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, name *string, resourceType *resourcemodel.ResourceType, content *[]byte) (resourcemodel.Resource, error) {
 	const op = "Service.UpdateUsersResource"
 
 	resource, err := s.GetUsersResourceByID(ctx, userID, resourceID)
@@ -133,13 +329,40 @@ func (s *Service) UpdateUsersResource(ctx context.Context, userID uuid.UUID, res
 		resource.Name = *name
 	}
 
+	reextract := false
+
+	if resourceType != nil && *resourceType != resource.Type {
+		resource.Type = *resourceType
+		reextract = true
+	}
+
 	if content != nil {
 		resource.RawContent = *content
+		resource.ContentHash = resourcemodel.HashContent(resource.RawContent)
+		reextract = true
+	}
+
+	var (
+		patch   resourcemodel.ChunkPatch
+		patched bool
+	)
 
-		resource.ExtractedContent, err = s.contentExtractor.ExtractContent(ctx, resource.RawContent, string(resource.Type))
+	if reextract {
+		if err := resource.Validate((*resourcemodel.Resource).HaveCompatibleContent); err != nil {
+			return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		previousContent := resource.ExtractedContent
+		resource, err = s.extractContent(ctx, resource)
 		if err != nil {
 			return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
 		}
+
+		if resource.ExtractedContent != previousContent {
+			patch, patched = s.diffChunks(ctx, resource)
+		}
+
+		resource.SetStatusProcessing()
 	}
 
 	resource, err = s.resourceRepo.UpdateUsersResource(ctx, userID, resource)
@@ -147,21 +370,122 @@ func (s *Service) UpdateUsersResource(ctx context.Context, userID uuid.UUID, res
 		return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.updated", map[string]interface{}{
-		"resource_id": resource.ID,
-		"owner_id":    resource.OwnerID,
-		"name":        resource.Name,
-		"type":        resource.Type,
-		"status":      resource.Status,
-		"updated_at":  resource.UpdatedAt,
-	})
+	// Every update carries the content: search-service indexes it again from
+	// the event whenever it replaces the chunks
+	eventName := "resource.updated"
+	eventData := map[string]interface{}{
+		"resource_id":       resource.ID,
+		"owner_id":          resource.OwnerID,
+		"tenant_id":         resource.TenantID,
+		"name":              resource.Name,
+		"url":               resource.URL,
+		"type":              resource.Type,
+		"status":            resource.Status,
+		"visibility":        resource.Visibility,
+		"created_at":        resource.CreatedAt,
+		"updated_at":        resource.UpdatedAt,
+		"extracted_content": resource.ExtractedContent,
+	}
+	addChunking(eventData, resource)
+	switch {
+	case !reextract:
+		// The indexed content is unchanged, search-service only replaces the
+		// chunk embedding the name and URL
+		eventName = "resource.metadata_updated"
+	case patched:
+		// Patched content only re-embeds the chunks that changed
+		eventName = "resource.content_patched"
+		eventData["removed_chunk_ids"] = patch.RemovedChunkIDs
+		eventData["kept_chunks"] = patch.KeptChunks
+		eventData["added_chunks"] = patch.AddedChunks
+	}
+
+	err = s.eventService.PublishEvent(ctx, ResourceTopicName, eventName, eventData)
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to publish resource updated event", "error", err)
+		slog.ErrorContext(ctx, "Failed to publish resource updated event", "event_name", eventName, "error", err)
 	}
 
 	return resource, nil
 }
 
+// UpdateUsersResourceVisibility changes who finds the user's resource in their
+// searches. The resource.visibility_updated event is stored in the same
+// transaction, search-service then relabels the indexed chunks without
+// embedding them again. Setting the current visibility changes nothing.
+func (s *Service) UpdateUsersResourceVisibility(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error) {
+	const op = "Service.UpdateUsersResourceVisibility"
+
+	if !visibility.IsValid() {
+		return resourcemodel.Resource{}, fmt.Errorf("%s: %w: %q", op, resourcemodel.ErrorWrongVisibility, visibility)
+	}
+
+	var updated resourcemodel.Resource
+	err := s.resourceRepo.InTx(ctx, func(ctx context.Context) error {
+		resource, err := s.GetUsersResourceByID(ctx, userID, resourceID)
+		if err != nil {
+			return err
+		}
+		if resource.Visibility == visibility {
+			updated = resource
+			return nil
+		}
+
+		updated, err = s.resourceRepo.UpdateResourceVisibility(ctx, resource.ID, userID, visibility)
+		if err != nil {
+			return err
+		}
+
+		return s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.visibility_updated", map[string]interface{}{
+			"resource_id":    updated.ID,
+			"owner_id":       updated.OwnerID,
+			"tenant_id":      updated.TenantID,
+			"old_visibility": resource.Visibility,
+			"visibility":     updated.Visibility,
+			"updated_at":     updated.UpdatedAt,
+		})
+	})
+	if err != nil {
+		return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.InfoContext(ctx, "Updated resource visibility",
+		"resource_id", updated.ID,
+		"visibility", updated.Visibility)
+	return updated, nil
+}
+
+// diffChunks compares the chunks of the new extracted content with the indexed
+// ones. It reports false when the resource has to be reindexed as a whole,
+// e.g. when it was never indexed or was indexed before chunk hashes were stored.
+func (s *Service) diffChunks(ctx context.Context, resource resourcemodel.Resource) (resourcemodel.ChunkPatch, bool) {
+	const op = "Service.diffChunks"
+
+	if len(resource.ChunkIDs) == 0 {
+		return resourcemodel.ChunkPatch{}, false
+	}
+
+	contents, hashes, err := splitChunks(resource.ExtractedContent, resource.ChunkSize, resource.ChunkOverlap)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to split content, reindexing the whole resource",
+			"op", op,
+			"resource_id", resource.ID,
+			"error", err)
+		return resourcemodel.ChunkPatch{}, false
+	}
+
+	patch, ok := resourcemodel.DiffChunks(resource.ChunkIDs, resource.ChunkHashes, contents, hashes)
+	if !ok {
+		return resourcemodel.ChunkPatch{}, false
+	}
+
+	slog.DebugContext(ctx, "Computed chunk patch",
+		"resource_id", resource.ID,
+		"kept", len(patch.KeptChunks),
+		"added", len(patch.AddedChunks),
+		"removed", len(patch.RemovedChunkIDs))
+	return patch, true
+}
+
 func (s *Service) DeleteUsersResource(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) error {
 	const op = "Service.DeleteUsersResource"
 
@@ -170,7 +494,7 @@ func (s *Service) DeleteUsersResource(ctx context.Context, userID uuid.UUID, res
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = s.resourceRepo.DeleteUsersResource(ctx, userID, resourceID)
+	err = s.resourceRepo.DeleteUsersResource(ctx, resourceID, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -189,10 +513,145 @@ func (s *Service) DeleteUsersResource(ctx context.Context, userID uuid.UUID, res
 	return nil
 }
 
+// DeleteUsersResources deletes the user's resources in a single transaction
+// together with their resource.deleted outbox events. Every resource is
+// checked first; when one is missing or belongs to another user nothing is
+// deleted, its result reports why and the others report ErrBulkRolledBack.
+// The returned error is the first failure, results are nil when the
+// transaction itself failed.
+func (s *Service) DeleteUsersResources(ctx context.Context, userID uuid.UUID, resourceIDs []uuid.UUID) ([]resourcemodel.DeleteResult, error) {
+	const op = "Service.DeleteUsersResources"
+
+	results := make([]resourcemodel.DeleteResult, len(resourceIDs))
+	var failure error
+
+	err := s.resourceRepo.InTx(ctx, func(ctx context.Context) error {
+		resources := make([]resourcemodel.Resource, len(resourceIDs))
+		for i, resourceID := range resourceIDs {
+			results[i].ID = resourceID
+
+			resource, err := s.GetUsersResourceByID(ctx, userID, resourceID)
+			switch {
+			case errors.Is(err, resourcemodel.ErrNotOwner):
+				results[i].Error = resourcemodel.ErrNotOwner.Error()
+			case errors.Is(err, resourcemodel.ErrResourceNotFound):
+				results[i].Error = resourcemodel.ErrResourceNotFound.Error()
+			case err != nil:
+				return err
+			default:
+				resources[i] = resource
+				continue
+			}
+			if failure == nil {
+				failure = err
+			}
+		}
+		if failure != nil {
+			return failure
+		}
+
+		for _, resource := range resources {
+			if err := s.resourceRepo.DeleteUsersResource(ctx, resource.ID, userID); err != nil {
+				return err
+			}
+
+			err := s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.deleted", map[string]interface{}{
+				"resource_id": resource.ID,
+				"owner_id":    userID,
+				"name":        resource.Name,
+				"type":        resource.Type,
+				"deleted_at":  time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && failure == nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if failure != nil {
+		for i := range results {
+			if results[i].Error == "" {
+				results[i].Error = resourcemodel.ErrBulkRolledBack.Error()
+			}
+		}
+		return results, fmt.Errorf("%s: %w", op, failure)
+	}
+
+	for i := range results {
+		results[i].Deleted = true
+	}
+
+	slog.InfoContext(ctx, "Deleted resources", "count", len(resourceIDs))
+	return results, nil
+}
+
+// CancelUsersResourceIndexation stops the indexation of the user's resource and
+// marks it cancelled. The resource.indexation_cancelled event is stored in the
+// same transaction, search-service then stops indexing the resource and drops
+// its chunks. Resources that are no longer indexed fail with
+// resourcemodel.ErrIndexationFinished.
+func (s *Service) CancelUsersResourceIndexation(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error) {
+	const op = "Service.CancelUsersResourceIndexation"
+
+	var cancelled resourcemodel.Resource
+	err := s.resourceRepo.InTx(ctx, func(ctx context.Context) error {
+		resource, err := s.GetUsersResourceByID(ctx, userID, resourceID)
+		if err != nil {
+			return err
+		}
+		if resource.Status.IsTerminal() {
+			return resourcemodel.ErrIndexationFinished
+		}
+
+		cancelled, err = s.resourceRepo.UpdateResourceStatus(ctx, resource.ID, resourcemodel.ResourceStatusCancelled)
+		if err != nil {
+			return err
+		}
+
+		return s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.indexation_cancelled", map[string]interface{}{
+			"resource_id":  resource.ID,
+			"owner_id":     resource.OwnerID,
+			"old_status":   resource.Status,
+			"cancelled_at": time.Now(),
+		})
+	})
+	if err != nil {
+		return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.sendCancelledStatus(cancelled.ID)
+
+	slog.InfoContext(ctx, "Cancelled resource indexation", "resource_id", cancelled.ID)
+	return cancelled, nil
+}
+
+// sendCancelledStatus hands the cancelled status to the reader of the resource's
+// status channel and closes it, taking the channel over from the indexation
+// processor, which then neither sends on nor closes it. It does not wait for
+// the reader.
+func (s *Service) sendCancelledStatus(resourceID uuid.UUID) {
+	statusCh, ok := s.takeStatusChannel(resourceID)
+	if !ok {
+		return
+	}
+
+	update := resourcemodel.ResourceStatusUpdate{ResourceID: resourceID, Status: resourcemodel.ResourceStatusCancelled}
+	go statusCh.finish(context.Background(), update, cancelledUpdateTimeout)
+}
+
+// GetUsersResourceByID returns the resource if it belongs to the user. It fails
+// with resourcemodel.ErrResourceNotFound when the resource does not exist and
+// with resourcemodel.ErrNotOwner when it belongs to another user.
 func (s *Service) GetUsersResourceByID(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.Resource, error) {
 	const op = "Service.GetUsersResourceByID"
 
-	resource, err := s.resourceRepo.GetUsersResourceByID(ctx, userID, resourceID)
+	resource, err := s.resourceRepo.GetUsersResourceByID(ctx, resourceID, userID)
+	if errors.Is(err, resourcemodel.ErrResourceNotFound) {
+		err = s.missingResourceError(ctx, resourceID, err)
+	}
 	if err != nil {
 		return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -200,10 +659,42 @@ func (s *Service) GetUsersResourceByID(ctx context.Context, userID uuid.UUID, re
 	return resource, nil
 }
 
+// GetUsersResourceStatus returns the status of a resource of the user, reading
+// only the status so that it is cheap to poll
+func (s *Service) GetUsersResourceStatus(ctx context.Context, userID uuid.UUID, resourceID uuid.UUID) (resourcemodel.ResourceStatus, error) {
+	const op = "Service.GetUsersResourceStatus"
+
+	status, err := s.resourceRepo.GetUsersResourceStatus(ctx, resourceID, userID)
+	if errors.Is(err, resourcemodel.ErrResourceNotFound) {
+		err = s.missingResourceError(ctx, resourceID, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return status, nil
+}
+
+// missingResourceError tells a resource that does not exist from one owned by
+// another user, which the owner scoped lookup cannot distinguish
+func (s *Service) missingResourceError(ctx context.Context, resourceID uuid.UUID, notFoundErr error) error {
+	if _, err := s.resourceRepo.GetResourceByID(ctx, resourceID); err == nil {
+		return resourcemodel.ErrNotOwner
+	}
+	return notFoundErr
+}
+
 func (s *Service) extractContent(ctx context.Context, resource resourcemodel.Resource) (resourcemodel.Resource, error) {
 	const op = "Service.extractContent"
 
-	content, err := s.contentExtractor.ExtractContent(ctx, resource.RawContent, string(resource.Type))
+	// The page range stays stored when the type of the resource changes, but
+	// only applies to PDFs
+	var pages []resourcemodel.PageRange
+	if resource.Pages != nil && resource.Type == resourcemodel.ResourceTypePDF {
+		pages = append(pages, *resource.Pages)
+	}
+
+	content, err := s.contentExtractor.ExtractContent(ctx, resource.RawContent, string(resource.Type), pages...)
 	if err != nil {
 		return resourcemodel.Resource{}, fmt.Errorf("%s: %w", op, err)
 	}
@@ -243,25 +734,99 @@ func (s *Service) UpdateResourceStatus(
 	return resource, nil
 }
 
-// GetResourceStatusChannel retrieves a status channel for a resource ID
-func (s *Service) GetResourceStatusChannel(resourceID uuid.UUID) (chan resourcemodel.ResourceStatusUpdate, bool) {
-	value, exists := s.statusChannels.Load(resourceID)
-	if !exists {
-		return nil, false
+// FailStaleResources marks up to limit resources still pending or processing
+// that were last updated before updatedBefore as failed, e.g. because their
+// indexation was lost, and publishes their status updates with them
+func (s *Service) FailStaleResources(ctx context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error) {
+	const op = "Service.FailStaleResources"
+
+	var failed []resourcemodel.Resource
+	err := s.resourceRepo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		failed, err = s.resourceRepo.FailStaleResources(ctx, updatedBefore, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, resource := range failed {
+			err := s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.status_updated", map[string]interface{}{
+				"resource_id": resource.ID,
+				"owner_id":    resource.OwnerID,
+				"new_status":  resource.Status,
+				"updated_at":  resource.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fail stale resources",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	ch, ok := value.(chan resourcemodel.ResourceStatusUpdate)
-	if !ok {
-		s.statusChannels.Delete(resourceID)
-		return nil, false
+	return failed, nil
+}
+
+// ReindexResource makes search-service index the resource again, e.g. after the
+// embedding model changed. Like a content update it marks the resource
+// processing and publishes resource.updated, whose consumer drops the old
+// chunks before indexing the extracted content. Both are stored in one
+// transaction.
+func (s *Service) ReindexResource(ctx context.Context, resource resourcemodel.Resource) error {
+	const op = "Service.ReindexResource"
+
+	err := s.resourceRepo.InTx(ctx, func(ctx context.Context) error {
+		updated, err := s.resourceRepo.UpdateResourceStatus(ctx, resource.ID, resourcemodel.ResourceStatusProcessing)
+		if err != nil {
+			return err
+		}
+
+		eventData := map[string]interface{}{
+			"resource_id":       updated.ID,
+			"owner_id":          updated.OwnerID,
+			"tenant_id":         updated.TenantID,
+			"name":              updated.Name,
+			"url":               updated.URL,
+			"type":              updated.Type,
+			"status":            updated.Status,
+			"visibility":        updated.Visibility,
+			"extracted_content": updated.ExtractedContent,
+			"created_at":        updated.CreatedAt,
+			"updated_at":        updated.UpdatedAt,
+		}
+		addChunking(eventData, updated)
+		return s.eventService.PublishEvent(ctx, ResourceTopicName, "resource.updated", eventData)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	return ch, true
+	return nil
 }
 
-// RemoveResourceStatusChannel removes a status channel from the map
-func (s *Service) RemoveResourceStatusChannel(resourceID uuid.UUID) {
-	s.statusChannels.Delete(resourceID)
+// UpdateResourceChunks stores the IDs of the chunks a resource was indexed as
+// together with their content hashes. Hashes not matching the IDs one to one
+// are dropped, the next content update then reindexes the whole resource.
+func (s *Service) UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error {
+	const op = "Service.UpdateResourceChunks"
+
+	if len(chunkHashes) != len(chunkIDs) {
+		chunkHashes = nil
+	}
+
+	if err := s.resourceRepo.UpdateResourceChunks(ctx, resourceID, chunkIDs, chunkHashes); err != nil {
+		slog.ErrorContext(ctx, "Failed to update resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
 // GetResourceByID retrieves a resource by ID (needed for indexation processor)
@@ -12,6 +12,9 @@ package resourceservcie
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,6 +29,8 @@ import (
 // Mock implementations
 type mockResourceRepository struct {
 	mock.Mock
+	txRun bool
+	txErr error
 }
 
 func (m *mockResourceRepository) ResourceOwnedByUser(ctx context.Context, resourceID uuid.UUID, userID uuid.UUID) (bool, error) {
@@ -38,21 +43,56 @@ func (m *mockResourceRepository) GetResources(ctx context.Context, limit int, of
 	return args.Get(0).([]resourcemodel.Resource), args.Error(1)
 }
 
-func (m *mockResourceRepository) GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, limit int, offset int) ([]resourcemodel.Resource, error) {
-	args := m.Called(ctx, ownerID, limit, offset)
+func (m *mockResourceRepository) GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error) {
+	args := m.Called(ctx, ownerID, sort, limit, offset)
 	return args.Get(0).([]resourcemodel.Resource), args.Error(1)
 }
 
+func (m *mockResourceRepository) GetResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error) {
+	args := m.Called(ctx, ownerID, status, sort, limit, offset)
+	return args.Get(0).([]resourcemodel.Resource), args.Error(1)
+}
+
+func (m *mockResourceRepository) CountResources(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockResourceRepository) CountResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID) (int, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockResourceRepository) CountResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus) (int, error) {
+	args := m.Called(ctx, ownerID, status)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockResourceRepository) CountResourcesByOwnerIDPerStatus(ctx context.Context, ownerID uuid.UUID) (map[resourcemodel.ResourceStatus]int, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).(map[resourcemodel.ResourceStatus]int), args.Error(1)
+}
+
 func (m *mockResourceRepository) GetUsersResourceByID(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.Resource, error) {
 	args := m.Called(ctx, resourceID, ownerID)
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
 }
 
+func (m *mockResourceRepository) GetUsersResourceStatus(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.ResourceStatus, error) {
+	args := m.Called(ctx, resourceID, ownerID)
+	return args.Get(0).(resourcemodel.ResourceStatus), args.Error(1)
+}
+
 func (m *mockResourceRepository) GetResourceByID(ctx context.Context, resourceID uuid.UUID) (resourcemodel.Resource, error) {
 	args := m.Called(ctx, resourceID)
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
 }
 
+func (m *mockResourceRepository) GetUsersResourceByContentHash(ctx context.Context, ownerID uuid.UUID, contentHash string) (resourcemodel.Resource, error) {
+	args := m.Called(ctx, ownerID, contentHash)
+	return args.Get(0).(resourcemodel.Resource), args.Error(1)
+}
+
 func (m *mockResourceRepository) SaveResource(ctx context.Context, resource resourcemodel.Resource) (resourcemodel.Resource, error) {
 	args := m.Called(ctx, resource)
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
@@ -68,17 +108,45 @@ func (m *mockResourceRepository) UpdateResourceStatus(ctx context.Context, resou
 	return args.Get(0).(resourcemodel.Resource), args.Error(1)
 }
 
+func (m *mockResourceRepository) UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error {
+	args := m.Called(ctx, resourceID, chunkIDs, chunkHashes)
+	return args.Error(0)
+}
+
+func (m *mockResourceRepository) UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error) {
+	args := m.Called(ctx, resourceID, ownerID, visibility)
+	return args.Get(0).(resourcemodel.Resource), args.Error(1)
+}
+
+func (m *mockResourceRepository) FailStaleResources(ctx context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error) {
+	args := m.Called(ctx, updatedBefore, limit)
+	return args.Get(0).([]resourcemodel.Resource), args.Error(1)
+}
+
 func (m *mockResourceRepository) DeleteUsersResource(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
 	args := m.Called(ctx, id, ownerID)
 	return args.Error(0)
 }
 
+// InTx runs fn directly and records its result, which a real transaction
+// would commit on nil and roll back otherwise
+func (m *mockResourceRepository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.txErr = fn(ctx)
+	m.txRun = true
+	return m.txErr
+}
+
 type mockContentExtractor struct {
 	mock.Mock
 }
 
-func (m *mockContentExtractor) ExtractContent(ctx context.Context, data []byte, dataType string) (string, error) {
-	args := m.Called(ctx, data, dataType)
+func (m *mockContentExtractor) ExtractContent(ctx context.Context, data []byte, dataType string, pages ...resourcemodel.PageRange) (string, error) {
+	// The page range is only passed to the expectation when given
+	callArgs := []interface{}{ctx, data, dataType}
+	if len(pages) > 0 {
+		callArgs = append(callArgs, pages)
+	}
+	args := m.Called(callArgs...)
 	return args.String(0), args.Error(1)
 }
 
@@ -144,11 +212,14 @@ func TestService_SaveUsersResource_Success(t *testing.T) {
 	savedResource.RawContent = content
 	savedResource.ExtractedContent = extractedContent
 	savedResource.Status = resourcemodel.ResourceStatusProcessing
+	savedResource.TenantID = "tenant-1"
 
 	// Mock expectations
 	mockExtractor.On("ExtractContent", ctx, content, string(resourceType)).Return(extractedContent, nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, userID, resourcemodel.HashContent(content)).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
 	mockRepo.On("SaveResource", ctx, mock.MatchedBy(func(r resourcemodel.Resource) bool {
 		return r.OwnerID == userID &&
+			r.TenantID == "tenant-1" &&
 			r.Name == name &&
 			r.Type == resourceType &&
 			r.URL == url &&
@@ -160,15 +231,19 @@ func TestService_SaveUsersResource_Success(t *testing.T) {
 	expectedEventData := map[string]interface{}{
 		"resource_id": savedResource.ID,
 		"owner_id":    savedResource.OwnerID,
+		"tenant_id":   savedResource.TenantID,
 		"name":        savedResource.Name,
 		"type":        savedResource.Type,
 		"status":      savedResource.Status,
+		"priority":    resourcemodel.ResourcePriorityNormal,
+		"visibility":  savedResource.Visibility,
 		"created_at":  savedResource.CreatedAt,
 	}
 	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", expectedEventData).Return(nil)
 
 	// Act
-	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url)
+	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url, "",
+		resourcemodel.WithTenantID("tenant-1"))
 
 	// Assert
 	require.NoError(t, err)
@@ -186,6 +261,267 @@ func TestService_SaveUsersResource_Success(t *testing.T) {
 	mockEvent.AssertExpectations(t)
 }
 
+func TestService_SaveUsersResource_ExplicitPriority(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	content := []byte("test content")
+	savedResource := createTestResource()
+
+	mockExtractor.On("ExtractContent", ctx, content, string(resourcemodel.ResourceTypeText)).Return("extracted", nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("SaveResource", ctx, mock.AnythingOfType("resourcemodel.Resource")).Return(savedResource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["priority"] == resourcemodel.ResourcePriorityHigh
+	})).Return(nil)
+
+	// Act
+	_, _, err := service.SaveUsersResource(ctx, uuid.New(), content, resourcemodel.ResourceTypeText, "name", "", resourcemodel.ResourcePriorityHigh)
+
+	// Assert
+	require.NoError(t, err)
+	mockExtractor.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_SaveUsersResource_PageRange(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	content := []byte("%PDF-1.4\n%%EOF")
+	pages := resourcemodel.PageRange{First: 3, Last: 10}
+
+	mockExtractor.On("ExtractContent", ctx, content, string(resourcemodel.ResourceTypePDF), []resourcemodel.PageRange{pages}).Return("extracted", nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("SaveResource", ctx, mock.AnythingOfType("resourcemodel.Resource")).Return(createTestResource(), nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", mock.Anything).Return(nil)
+
+	// Act
+	_, _, err := service.SaveUsersResource(ctx, uuid.New(), content, resourcemodel.ResourceTypePDF, "name", "", "",
+		resourcemodel.WithPageRange(&pages))
+
+	// Assert
+	require.NoError(t, err)
+	mockExtractor.AssertExpectations(t)
+}
+
+func TestService_SaveUsersResource_PageRangeOnlyForPDF(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	// Act
+	_, _, err := service.SaveUsersResource(context.Background(), uuid.New(), []byte("test content"), resourcemodel.ResourceTypeText, "name", "", "",
+		resourcemodel.WithPageRange(&resourcemodel.PageRange{First: 1}))
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrorWrongPageRange)
+	mockExtractor.AssertNotCalled(t, "ExtractContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SaveUsersResource_InvalidPriority(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	// Act
+	result, _, err := service.SaveUsersResource(context.Background(), uuid.New(), []byte("test content"), resourcemodel.ResourceTypeText, "name", "", "urgent")
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrorWrongPriority)
+	assert.Equal(t, resourcemodel.Resource{}, result)
+	mockExtractor.AssertNotCalled(t, "ExtractContent", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveResource", mock.Anything, mock.Anything)
+	mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SaveUsersResource_ChunkingOverride(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	content := []byte("test content")
+	overlap := 0
+
+	mockExtractor.On("ExtractContent", ctx, content, string(resourcemodel.ResourceTypeText)).Return("extracted", nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	// The override is stored with the resource
+	stored := createTestResource()
+	stored.ChunkSize, stored.ChunkOverlap = 256, &overlap
+	mockRepo.On("SaveResource", ctx, mock.MatchedBy(func(resource resourcemodel.Resource) bool {
+		return resource.ChunkSize == 256 && resource.ChunkOverlap != nil && *resource.ChunkOverlap == 0
+	})).Return(stored, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["chunk_size"] == 256 && data["chunk_overlap"] == 0
+	})).Return(nil)
+
+	// Act
+	result, _, err := service.SaveUsersResource(ctx, uuid.New(), content, resourcemodel.ResourceTypeText, "name", "", "",
+		resourcemodel.WithChunking(256, &overlap))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 256, result.ChunkSize)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_SaveUsersResource_InvalidChunking(t *testing.T) {
+	overlap := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		size    int
+		overlap *int
+	}{
+		{"overlap as large as the chunk", 256, overlap(256)},
+		{"chunk over the maximum", resourcemodel.MaxChunkSize + 1, nil},
+		{"overlap over the maximum", 0, overlap(resourcemodel.MaxChunkSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := &mockResourceRepository{}
+			mockExtractor := &mockContentExtractor{}
+			mockEvent := &mockEventService{}
+
+			service := NewService(mockRepo, mockExtractor, mockEvent)
+
+			// Act
+			_, _, err := service.SaveUsersResource(context.Background(), uuid.New(), []byte("test content"), resourcemodel.ResourceTypeText, "name", "", "",
+				resourcemodel.WithChunking(tt.size, tt.overlap))
+
+			// Assert
+			require.ErrorIs(t, err, resourcemodel.ErrorWrongChunking)
+			mockRepo.AssertNotCalled(t, "SaveResource", mock.Anything, mock.Anything)
+			mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_SaveUsersResource_MislabeledPDF(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+	// Valid UTF-8, so only the sniffed content type gives the PDF away
+	content := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF")
+
+	// Act
+	_, _, err := service.SaveUsersResource(context.Background(), uuid.New(), content, resourcemodel.ResourceTypeText, "name", "", "")
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrorIncompatibleType)
+	assert.Contains(t, err.Error(), "declared text, content looks like application/pdf")
+	mockExtractor.AssertNotCalled(t, "ExtractContent", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveResource", mock.Anything, mock.Anything)
+	mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SaveUsersResource_PlainTextAccepted(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	content := []byte("# Notes\n\nПлан на неделю: review the PDF export and <b>ship</b> it.\n")
+
+	mockExtractor.On("ExtractContent", ctx, content, string(resourcemodel.ResourceTypeText)).Return(string(content), nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("SaveResource", ctx, mock.AnythingOfType("resourcemodel.Resource")).Return(createTestResource(), nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", mock.Anything).Return(nil)
+
+	// Act
+	_, _, err := service.SaveUsersResource(ctx, uuid.New(), content, resourcemodel.ResourceTypeText, "name", "", "")
+
+	// Assert
+	require.NoError(t, err)
+	mockExtractor.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_SaveUsersResource_Duplicate(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	content := []byte("test content")
+	existing := createTestResource()
+
+	mockRepo.On("GetUsersResourceByContentHash", ctx, userID, resourcemodel.HashContent(content)).Return(existing, nil)
+
+	// Act
+	result, _, err := service.SaveUsersResource(ctx, userID, content, resourcemodel.ResourceTypeText, "name", "", "")
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrDuplicateResource)
+	var duplicate *resourcemodel.DuplicateResourceError
+	require.ErrorAs(t, err, &duplicate)
+	assert.Equal(t, existing.ID, duplicate.ExistingID)
+	assert.Equal(t, resourcemodel.Resource{}, result)
+	mockExtractor.AssertNotCalled(t, "ExtractContent", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "SaveResource", mock.Anything, mock.Anything)
+	mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_SaveUsersResource_DuplicateAllowed(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	content := []byte("test content")
+
+	mockExtractor.On("ExtractContent", ctx, content, string(resourcemodel.ResourceTypeText)).Return("extracted", nil)
+	mockRepo.On("SaveResource", ctx, mock.MatchedBy(func(r resourcemodel.Resource) bool {
+		return r.ContentHash == resourcemodel.HashContent(content)
+	})).Return(createTestResource(), nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", mock.Anything).Return(nil)
+
+	// Act
+	_, _, err := service.SaveUsersResource(ctx, uuid.New(), content, resourcemodel.ResourceTypeText, "name", "", "",
+		resourcemodel.WithAllowDuplicate(true))
+
+	// Assert
+	require.NoError(t, err)
+	mockRepo.AssertNotCalled(t, "GetUsersResourceByContentHash", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestService_SaveUsersResource_ExtractContentError(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
@@ -204,10 +540,11 @@ func TestService_SaveUsersResource_ExtractContentError(t *testing.T) {
 	expectedError := errors.New("extraction failed")
 
 	// Mock expectations
+	mockRepo.On("GetUsersResourceByContentHash", ctx, userID, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
 	mockExtractor.On("ExtractContent", ctx, content, string(resourceType)).Return("", expectedError)
 
 	// Act
-	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url)
+	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url, "")
 
 	// Assert
 	require.Error(t, err)
@@ -240,10 +577,11 @@ func TestService_SaveUsersResource_SaveResourceError(t *testing.T) {
 
 	// Mock expectations
 	mockExtractor.On("ExtractContent", ctx, content, string(resourceType)).Return(extractedContent, nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
 	mockRepo.On("SaveResource", ctx, mock.AnythingOfType("resourcemodel.Resource")).Return(resourcemodel.Resource{}, expectedError)
 
 	// Act
-	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url)
+	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url, "")
 
 	// Assert
 	require.Error(t, err)
@@ -275,7 +613,7 @@ func TestService_GetUsersResources_Success(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockRepo.On("GetResourcesByOwnerID", ctx, userID, limit, offset).Return(expectedResources, nil)
+	mockRepo.On("GetResourcesByOwnerID", ctx, userID, resourcemodel.DefaultResourceSort(), limit, offset).Return(expectedResources, nil)
 
 	// Act
 	result, err := service.GetUsersResources(ctx, userID, limit, offset)
@@ -303,7 +641,7 @@ func TestService_GetUsersResources_DefaultValues(t *testing.T) {
 	expectedResources := []resourcemodel.Resource{}
 
 	// Mock expectations - should be called with default values
-	mockRepo.On("GetResourcesByOwnerID", ctx, userID, 10, 0).Return(expectedResources, nil)
+	mockRepo.On("GetResourcesByOwnerID", ctx, userID, resourcemodel.DefaultResourceSort(), 10, 0).Return(expectedResources, nil)
 
 	// Act
 	result, err := service.GetUsersResources(ctx, userID, limit, offset)
@@ -315,6 +653,88 @@ func TestService_GetUsersResources_DefaultValues(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestService_GetUsersResourcesByStatus(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	service := NewService(mockRepo, &mockContentExtractor{}, &mockEventService{})
+
+	ctx := context.Background()
+	userID := uuid.New()
+	failed := createTestResource()
+	failed.Status = resourcemodel.ResourceStatusFailed
+
+	// Filtering happens in the query, so pagination counts only matching resources
+	mockRepo.On("GetResourcesByOwnerIDAndStatus", ctx, userID, resourcemodel.ResourceStatusFailed, resourcemodel.DefaultResourceSort(), 10, 0).
+		Return([]resourcemodel.Resource{failed}, nil)
+
+	// Act
+	result, err := service.GetUsersResourcesByStatus(ctx, userID, resourcemodel.ResourceStatusFailed, 0, -1)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []resourcemodel.Resource{failed}, result)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetResourcesByOwnerID")
+}
+
+func TestService_GetUsersResources_Sort(t *testing.T) {
+	mockRepo := &mockResourceRepository{}
+	service := NewService(mockRepo, &mockContentExtractor{}, &mockEventService{})
+
+	ctx := context.Background()
+	userID := uuid.New()
+	byName := resourcemodel.ResourceSort{Field: resourcemodel.ResourceSortName, Order: resourcemodel.SortOrderAsc}
+	mockRepo.On("GetResourcesByOwnerID", ctx, userID, byName, 10, 0).Return([]resourcemodel.Resource{}, nil)
+
+	_, err := service.GetUsersResources(ctx, userID, 10, 0, byName)
+	require.NoError(t, err)
+
+	_, err = service.GetUsersResources(ctx, userID, 10, 0, resourcemodel.ResourceSort{Field: "owner_id"})
+	require.ErrorIs(t, err, resourcemodel.ErrorWrongSort)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "GetResourcesByOwnerID", 1)
+}
+
+func TestService_GetUsersResourcesByStatus_InvalidStatus(t *testing.T) {
+	mockRepo := &mockResourceRepository{}
+	service := NewService(mockRepo, &mockContentExtractor{}, &mockEventService{})
+
+	result, err := service.GetUsersResourcesByStatus(context.Background(), uuid.New(), "broken", 10, 0)
+
+	require.ErrorIs(t, err, resourcemodel.ErrorWrongStatus)
+	assert.Nil(t, result)
+	mockRepo.AssertNotCalled(t, "GetResourcesByOwnerIDAndStatus")
+}
+
+func TestService_GetAllResources_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	expectedResources := []resourcemodel.Resource{
+		createTestResource(),
+		createTestResource(),
+	}
+
+	// Mock expectations - no owner filter, defaults applied
+	mockRepo.On("GetResources", ctx, 10, 0).Return(expectedResources, nil)
+
+	// Act
+	result, err := service.GetAllResources(ctx, 0, -1)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, expectedResources, result)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetResourcesByOwnerID")
+}
+
 func TestService_GetUsersResources_RepositoryError(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
@@ -331,7 +751,7 @@ func TestService_GetUsersResources_RepositoryError(t *testing.T) {
 	expectedError := errors.New("repository error")
 
 	// Mock expectations
-	mockRepo.On("GetResourcesByOwnerID", ctx, userID, limit, offset).Return([]resourcemodel.Resource{}, expectedError)
+	mockRepo.On("GetResourcesByOwnerID", ctx, userID, resourcemodel.DefaultResourceSort(), limit, offset).Return([]resourcemodel.Resource{}, expectedError)
 
 	// Act
 	result, err := service.GetUsersResources(ctx, userID, limit, offset)
@@ -369,24 +789,29 @@ func TestService_UpdateUsersResource_Success(t *testing.T) {
 	updatedResource.ExtractedContent = extractedContent
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(existingResource, nil)
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
 	mockExtractor.On("ExtractContent", ctx, newContent, string(existingResource.Type)).Return(extractedContent, nil)
 	mockRepo.On("UpdateUsersResource", ctx, userID, mock.MatchedBy(func(r resourcemodel.Resource) bool {
 		return r.Name == newName && string(r.RawContent) == string(newContent) && r.ExtractedContent == extractedContent
 	})).Return(updatedResource, nil)
 
 	expectedEventData := map[string]interface{}{
-		"resource_id": updatedResource.ID,
-		"owner_id":    updatedResource.OwnerID,
-		"name":        updatedResource.Name,
-		"type":        updatedResource.Type,
-		"status":      updatedResource.Status,
-		"updated_at":  updatedResource.UpdatedAt,
+		"resource_id":       updatedResource.ID,
+		"owner_id":          updatedResource.OwnerID,
+		"tenant_id":         updatedResource.TenantID,
+		"name":              updatedResource.Name,
+		"url":               updatedResource.URL,
+		"visibility":        updatedResource.Visibility,
+		"type":              updatedResource.Type,
+		"status":            updatedResource.Status,
+		"created_at":        updatedResource.CreatedAt,
+		"updated_at":        updatedResource.UpdatedAt,
+		"extracted_content": updatedResource.ExtractedContent,
 	}
 	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", expectedEventData).Return(nil)
 
 	// Act
-	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, &newContent)
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, nil, &newContent)
 
 	// Assert
 	require.NoError(t, err)
@@ -418,34 +843,935 @@ func TestService_UpdateUsersResource_OnlyName(t *testing.T) {
 	updatedResource.Name = newName
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(existingResource, nil)
-	mockRepo.On("UpdateUsersResource", ctx, userID, mock.MatchedBy(func(r resourcemodel.Resource) bool {
-		return r.Name == newName
-	})).Return(updatedResource, nil)
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.MatchedBy(func(r resourcemodel.Resource) bool {
+		return r.Name == newName
+	})).Return(updatedResource, nil)
+
+	// Only the name is re-embedded, search-service does not reindex the content
+	expectedEventData := map[string]interface{}{
+		"resource_id":       updatedResource.ID,
+		"owner_id":          updatedResource.OwnerID,
+		"tenant_id":         updatedResource.TenantID,
+		"name":              updatedResource.Name,
+		"type":              updatedResource.Type,
+		"status":            updatedResource.Status,
+		"created_at":        updatedResource.CreatedAt,
+		"updated_at":        updatedResource.UpdatedAt,
+		"url":               updatedResource.URL,
+		"visibility":        updatedResource.Visibility,
+		"extracted_content": updatedResource.ExtractedContent,
+	}
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.metadata_updated", expectedEventData).Return(nil)
+
+	// Act
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, nil, nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, updatedResource, result)
+
+	mockRepo.AssertExpectations(t)
+	mockExtractor.AssertNotCalled(t, "ExtractContent")
+	mockEvent.AssertExpectations(t)
+}
+
+// paragraphs returns text of n paragraphs, each small enough to be a chunk of its own
+func paragraphs(n int, replace map[int]string) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = strings.Repeat(fmt.Sprintf("Paragraph %d sentence. ", i), 20)
+		if text, ok := replace[i]; ok {
+			parts[i] = text
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func TestService_UpdateUsersResource_PatchesChangedChunk(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newContent := []byte("edited raw content")
+	oldText := paragraphs(4, nil)
+	newText := paragraphs(4, map[int]string{2: strings.Repeat("Changed paragraph two. ", 20)})
+
+	_, oldHashes, err := splitChunks(oldText, 0, nil)
+	require.NoError(t, err)
+	require.Len(t, oldHashes, 4)
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	existingResource.ExtractedContent = oldText
+	existingResource.ChunkIDs = []string{"chunk-0", "chunk-1", "chunk-2", "chunk-3"}
+	existingResource.ChunkHashes = oldHashes
+
+	updatedResource := existingResource
+	updatedResource.RawContent = newContent
+	updatedResource.ExtractedContent = newText
+	updatedResource.Status = resourcemodel.ResourceStatusProcessing
+
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockExtractor.On("ExtractContent", ctx, newContent, string(existingResource.Type)).Return(newText, nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.Anything).Return(updatedResource, nil)
+
+	var eventData map[string]interface{}
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.content_patched", mock.Anything).
+		Run(func(args mock.Arguments) {
+			eventData = args.Get(3).(map[string]interface{})
+		}).
+		Return(nil)
+
+	// Act
+	_, err = service.UpdateUsersResource(ctx, userID, resourceID, nil, nil, &newContent)
+
+	// Assert
+	require.NoError(t, err)
+	mockEvent.AssertExpectations(t)
+
+	assert.Equal(t, newText, eventData["extracted_content"])
+	assert.Equal(t, []string{"chunk-2"}, eventData["removed_chunk_ids"])
+
+	added := eventData["added_chunks"].([]resourcemodel.AddedChunk)
+	require.Len(t, added, 1, "only the changed paragraph is re-embedded")
+	assert.Equal(t, 2, added[0].Index)
+	assert.Contains(t, added[0].Content, "Changed paragraph two.")
+
+	kept := eventData["kept_chunks"].([]resourcemodel.KeptChunk)
+	require.Len(t, kept, 3)
+	for _, chunk := range kept {
+		assert.Equal(t, fmt.Sprintf("chunk-%d", chunk.Index), chunk.ID)
+	}
+}
+
+func TestService_UpdateUsersResource_PatchDropsRepeatedChunks(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newContent := []byte("edited raw content")
+	footer := strings.Repeat("Confidential footer. ", 20)
+	oldText := paragraphs(5, map[int]string{1: footer, 3: footer})
+	newText := paragraphs(5, map[int]string{
+		1: footer,
+		2: strings.Repeat("Changed paragraph two. ", 20),
+		3: strings.ToUpper(footer),
+	})
+
+	oldContents, oldHashes, err := splitChunks(oldText, 0, nil)
+	require.NoError(t, err)
+	require.Len(t, oldHashes, 5)
+
+	// search-service indexed the repeated footer once
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	existingResource.ExtractedContent = oldText
+	existingResource.ChunkIDs = []string{"chunk-0", "chunk-1", "chunk-2", "chunk-4"}
+	existingResource.ChunkHashes = []string{oldHashes[0], oldHashes[1], oldHashes[2], oldHashes[4]}
+	require.Equal(t, oldContents[1], oldContents[3])
+
+	updatedResource := existingResource
+	updatedResource.RawContent = newContent
+	updatedResource.ExtractedContent = newText
+	updatedResource.Status = resourcemodel.ResourceStatusProcessing
+
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockExtractor.On("ExtractContent", ctx, newContent, string(existingResource.Type)).Return(newText, nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.Anything).Return(updatedResource, nil)
+
+	var eventData map[string]interface{}
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.content_patched", mock.Anything).
+		Run(func(args mock.Arguments) {
+			eventData = args.Get(3).(map[string]interface{})
+		}).
+		Return(nil)
+
+	// Act
+	_, err = service.UpdateUsersResource(ctx, userID, resourceID, nil, nil, &newContent)
+
+	// Assert
+	require.NoError(t, err)
+	mockEvent.AssertExpectations(t)
+
+	assert.Equal(t, []string{"chunk-2"}, eventData["removed_chunk_ids"])
+
+	// The footer in other case repeats the kept one and is not embedded again
+	added := eventData["added_chunks"].([]resourcemodel.AddedChunk)
+	require.Len(t, added, 1)
+	assert.Equal(t, 2, added[0].Index)
+	assert.Contains(t, added[0].Content, "Changed paragraph two.")
+
+	// Indexes are positions among the remaining chunks
+	assert.Equal(t, []resourcemodel.KeptChunk{
+		{ID: "chunk-0", Index: 0, Hash: oldHashes[0]},
+		{ID: "chunk-1", Index: 1, Hash: oldHashes[1]},
+		{ID: "chunk-4", Index: 3, Hash: oldHashes[4]},
+	}, eventData["kept_chunks"])
+}
+
+func TestService_UpdateUsersResource_WithoutChunkHashesReindexes(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newContent := []byte("edited raw content")
+
+	// Indexed before chunk hashes were stored
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	existingResource.ChunkIDs = []string{"chunk-0"}
+
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockExtractor.On("ExtractContent", ctx, newContent, string(existingResource.Type)).Return("new text", nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.Anything).Return(existingResource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", mock.Anything).Return(nil)
+
+	// Act
+	_, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, nil, &newContent)
+
+	// Assert
+	require.NoError(t, err)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_UpdateUsersResource_EveryEventCarriesContent(t *testing.T) {
+	oldText := paragraphs(3, nil)
+	_, oldHashes, err := splitChunks(oldText, 0, nil)
+	require.NoError(t, err)
+
+	newName := "Renamed"
+	newType := resourcemodel.ResourceTypeURL
+	newContent := []byte("replaced raw content")
+
+	tests := []struct {
+		name        string
+		chunkHashes []string
+		rename      *string
+		retype      *resourcemodel.ResourceType
+		content     *[]byte
+		extracted   string
+		wantEvent   string
+	}{
+		{name: "metadata", rename: &newName, extracted: oldText, wantEvent: "resource.metadata_updated"},
+		{name: "type change", retype: &newType, extracted: "# Example page", wantEvent: "resource.updated"},
+		{name: "content replaced", content: &newContent, extracted: "replaced text", wantEvent: "resource.updated"},
+		{
+			name:        "content patched",
+			chunkHashes: oldHashes,
+			content:     &newContent,
+			extracted:   paragraphs(3, map[int]string{1: strings.Repeat("Changed paragraph one. ", 20)}),
+			wantEvent:   "resource.content_patched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockResourceRepository{}
+			mockExtractor := &mockContentExtractor{}
+			mockEvent := &mockEventService{}
+			service := NewService(mockRepo, mockExtractor, mockEvent)
+
+			ctx := context.Background()
+			existingResource := createTestResource()
+			existingResource.RawContent = []byte("https://example.com/page")
+			existingResource.ExtractedContent = oldText
+			existingResource.ChunkIDs = []string{"chunk-0", "chunk-1", "chunk-2"}
+			existingResource.ChunkHashes = tt.chunkHashes
+
+			mockRepo.On("GetUsersResourceByID", ctx, existingResource.ID, existingResource.OwnerID).Return(existingResource, nil)
+			mockExtractor.On("ExtractContent", ctx, mock.Anything, mock.Anything).Return(tt.extracted, nil)
+			storedResource := existingResource
+			storedResource.ExtractedContent = tt.extracted
+			mockRepo.On("UpdateUsersResource", ctx, existingResource.OwnerID, mock.MatchedBy(func(r resourcemodel.Resource) bool {
+				return r.ExtractedContent == tt.extracted
+			})).Return(storedResource, nil)
+
+			var eventData map[string]interface{}
+			mockEvent.On("PublishEvent", ctx, "resources", tt.wantEvent, mock.Anything).
+				Run(func(args mock.Arguments) {
+					eventData = args.Get(3).(map[string]interface{})
+				}).
+				Return(nil)
+
+			_, err := service.UpdateUsersResource(ctx, existingResource.OwnerID, existingResource.ID, tt.rename, tt.retype, tt.content)
+
+			require.NoError(t, err)
+			mockEvent.AssertExpectations(t)
+			assert.Equal(t, tt.extracted, eventData["extracted_content"])
+		})
+	}
+}
+
+func TestService_UpdateUsersResource_KeepsIndexationSettings(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newContent := []byte("%PDF-1.4 edited")
+	overlap := 20
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	existingResource.Type = resourcemodel.ResourceTypePDF
+	existingResource.ChunkSize, existingResource.ChunkOverlap = 256, &overlap
+	existingResource.Pages = &resourcemodel.PageRange{First: 2, Last: 3}
+
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	// The stored page range is extracted again
+	mockExtractor.On("ExtractContent", ctx, newContent, string(resourcemodel.ResourceTypePDF), []resourcemodel.PageRange{{First: 2, Last: 3}}).Return("new text", nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.Anything).Return(existingResource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["chunk_size"] == 256 && data["chunk_overlap"] == 20
+	})).Return(nil)
+
+	// Act
+	_, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, nil, &newContent)
+
+	// Assert
+	require.NoError(t, err)
+	mockExtractor.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_UpdateUsersResource_GetResourceError(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newName := "Updated Resource"
+
+	expectedError := errors.New("resource not found")
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(resourcemodel.Resource{}, expectedError)
+
+	// Act
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, nil, nil)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource not found")
+	assert.Equal(t, resourcemodel.Resource{}, result)
+
+	mockRepo.AssertExpectations(t)
+	mockExtractor.AssertNotCalled(t, "ExtractContent")
+	mockEvent.AssertNotCalled(t, "PublishEvent")
+}
+
+func TestService_DeleteUsersResource_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockRepo.On("DeleteUsersResource", ctx, resourceID, userID).Return(nil)
+
+	// Use a more flexible matching for event data since time.Now() is dynamic
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.deleted", mock.MatchedBy(func(data interface{}) bool {
+		eventData, ok := data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		return eventData["resource_id"] == resourceID &&
+			eventData["owner_id"] == userID &&
+			eventData["name"] == existingResource.Name &&
+			eventData["type"] == existingResource.Type &&
+			eventData["deleted_at"] != nil
+	})).Return(nil)
+
+	// Act
+	err := service.DeleteUsersResource(ctx, userID, resourceID)
+
+	// Assert
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_DeleteUsersResource_GetResourceError(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	othersResource := createTestResource()
+	othersResource.ID = resourceID
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("GetResourceByID", ctx, resourceID).Return(othersResource, nil)
+
+	// Act
+	err := service.DeleteUsersResource(ctx, userID, resourceID)
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrNotOwner)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "DeleteUsersResource", mock.Anything, mock.Anything, mock.Anything)
+	mockEvent.AssertNotCalled(t, "PublishEvent")
+}
+
+func TestService_DeleteUsersResource_DeleteError(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+
+	expectedError := errors.New("delete failed")
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockRepo.On("DeleteUsersResource", ctx, resourceID, userID).Return(expectedError)
+
+	// Act
+	err := service.DeleteUsersResource(ctx, userID, resourceID)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "delete failed")
+
+	mockRepo.AssertExpectations(t)
+	mockEvent.AssertNotCalled(t, "PublishEvent")
+}
+
+func TestService_DeleteUsersResources_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	first, second := createTestResource(), createTestResource()
+
+	mockRepo.On("GetUsersResourceByID", ctx, first.ID, userID).Return(first, nil)
+	mockRepo.On("GetUsersResourceByID", ctx, second.ID, userID).Return(second, nil)
+	mockRepo.On("DeleteUsersResource", ctx, first.ID, userID).Return(nil)
+	mockRepo.On("DeleteUsersResource", ctx, second.ID, userID).Return(nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.deleted", mock.Anything).Return(nil).Twice()
+
+	// Act
+	results, err := service.DeleteUsersResources(ctx, userID, []uuid.UUID{first.ID, second.ID})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []resourcemodel.DeleteResult{
+		{ID: first.ID, Deleted: true},
+		{ID: second.ID, Deleted: true},
+	}, results)
+	assert.True(t, mockRepo.txRun)
+	assert.NoError(t, mockRepo.txErr)
+	mockRepo.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_DeleteUsersResources_NotOwnerRollsBack(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	owned, othersResource := createTestResource(), createTestResource()
+
+	mockRepo.On("GetUsersResourceByID", ctx, owned.ID, userID).Return(owned, nil)
+	mockRepo.On("GetUsersResourceByID", ctx, othersResource.ID, userID).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("GetResourceByID", ctx, othersResource.ID).Return(othersResource, nil)
+
+	// Act
+	results, err := service.DeleteUsersResources(ctx, userID, []uuid.UUID{owned.ID, othersResource.ID})
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrNotOwner)
+	assert.Equal(t, []resourcemodel.DeleteResult{
+		{ID: owned.ID, Error: resourcemodel.ErrBulkRolledBack.Error()},
+		{ID: othersResource.ID, Error: resourcemodel.ErrNotOwner.Error()},
+	}, results)
+	assert.ErrorIs(t, mockRepo.txErr, resourcemodel.ErrNotOwner)
+	mockRepo.AssertNotCalled(t, "DeleteUsersResource", mock.Anything, mock.Anything, mock.Anything)
+	mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_DeleteUsersResources_EventFailureRollsBack(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resource := createTestResource()
+	outboxErr := errors.New("outbox insert failed")
+
+	mockRepo.On("GetUsersResourceByID", ctx, resource.ID, userID).Return(resource, nil)
+	mockRepo.On("DeleteUsersResource", ctx, resource.ID, userID).Return(nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.deleted", mock.Anything).Return(outboxErr)
+
+	// Act
+	results, err := service.DeleteUsersResources(ctx, userID, []uuid.UUID{resource.ID})
+
+	// Assert: the event is part of the transaction, so the delete is rolled back
+	require.ErrorIs(t, err, outboxErr)
+	assert.Nil(t, results)
+	assert.ErrorIs(t, mockRepo.txErr, outboxErr)
+}
+
+func TestService_CancelUsersResourceIndexation_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resource := createTestResource()
+	resource.OwnerID = userID
+	resource.Status = resourcemodel.ResourceStatusProcessing
+
+	cancelled := resource
+	cancelled.Status = resourcemodel.ResourceStatusCancelled
+
+	statusCh := service.RegisterResourceStatusChannel(resource.ID)
+
+	mockRepo.On("GetUsersResourceByID", ctx, resource.ID, userID).Return(resource, nil)
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, resourcemodel.ResourceStatusCancelled).Return(cancelled, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.indexation_cancelled", mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["resource_id"] == resource.ID &&
+			data["owner_id"] == userID &&
+			data["old_status"] == resourcemodel.ResourceStatusProcessing &&
+			data["cancelled_at"] != nil
+	})).Return(nil)
+
+	// Act
+	result, err := service.CancelUsersResourceIndexation(ctx, userID, resource.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, resourcemodel.ResourceStatusCancelled, result.Status)
+	assert.NoError(t, mockRepo.txErr)
+
+	// The status stream ends with the cancelled status
+	update, ok := <-statusCh
+	require.True(t, ok)
+	assert.Equal(t, resourcemodel.ResourceStatusUpdate{ResourceID: resource.ID, Status: resourcemodel.ResourceStatusCancelled}, update)
+	_, ok = <-statusCh
+	assert.False(t, ok)
+	_, exists := service.GetResourceStatusChannel(resource.ID)
+	assert.False(t, exists)
+
+	mockRepo.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_CancelUsersResourceIndexation_DuringIndexation(t *testing.T) {
+	// The cancellation races the progress and completion of the indexation
+	// for the status channel: only one of them closes it and nobody sends on
+	// it afterwards. Run with -race.
+	for i := 0; i < 20; i++ {
+		mockRepo := &mockResourceRepository{}
+		mockEvent := &mockEventService{}
+		service := NewService(mockRepo, &mockContentExtractor{}, mockEvent)
+
+		ctx := context.Background()
+		userID := uuid.New()
+		resource := createTestResource()
+		resource.OwnerID = userID
+		resource.Status = resourcemodel.ResourceStatusProcessing
+
+		cancelled := resource
+		cancelled.Status = resourcemodel.ResourceStatusCancelled
+
+		mockRepo.On("GetUsersResourceByID", ctx, resource.ID, userID).Return(resource, nil)
+		mockRepo.On("UpdateResourceStatus", ctx, resource.ID, resourcemodel.ResourceStatusCancelled).Return(cancelled, nil)
+		mockEvent.On("PublishEvent", ctx, "resources", "resource.indexation_cancelled", mock.Anything).Return(nil)
+
+		statusCh := service.RegisterResourceStatusChannel(resource.ID)
+
+		var wg sync.WaitGroup
+		for percent := 10; percent <= 90; percent += 10 {
+			wg.Add(1)
+			go func(percent int) {
+				defer wg.Done()
+				service.SendResourceStatus(resourcemodel.ResourceStatusUpdate{ResourceID: resource.ID, Status: resourcemodel.ResourceStatusProcessing, Percent: percent})
+			}(percent)
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := service.CancelUsersResourceIndexation(ctx, userID, resource.ID)
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			service.FinishResourceStatus(ctx, resourcemodel.ResourceStatusUpdate{ResourceID: resource.ID, Status: resourcemodel.ResourceStatusCompleted, Percent: 100}, 0)
+		}()
+
+		var last resourcemodel.ResourceStatusUpdate
+		for update := range statusCh {
+			last = update
+		}
+		wg.Wait()
+
+		assert.True(t, last.Status.IsTerminal(), "the stream ends with the status of whoever closed it")
+		assert.False(t, service.SendResourceStatus(resourcemodel.ResourceStatusUpdate{ResourceID: resource.ID, Status: resourcemodel.ResourceStatusProcessing, Percent: 95}))
+	}
+}
+
+func TestService_CancelUsersResourceIndexation_Finished(t *testing.T) {
+	for _, status := range []resourcemodel.ResourceStatus{
+		resourcemodel.ResourceStatusCompleted,
+		resourcemodel.ResourceStatusFailed,
+		resourcemodel.ResourceStatusCancelled,
+	} {
+		t.Run(string(status), func(t *testing.T) {
+			// Arrange
+			mockRepo := &mockResourceRepository{}
+			mockEvent := &mockEventService{}
+
+			service := NewService(mockRepo, &mockContentExtractor{}, mockEvent)
+
+			ctx := context.Background()
+			userID := uuid.New()
+			resource := createTestResource()
+			resource.Status = status
+
+			mockRepo.On("GetUsersResourceByID", ctx, resource.ID, userID).Return(resource, nil)
+
+			// Act
+			_, err := service.CancelUsersResourceIndexation(ctx, userID, resource.ID)
+
+			// Assert
+			require.ErrorIs(t, err, resourcemodel.ErrIndexationFinished)
+			mockRepo.AssertNotCalled(t, "UpdateResourceStatus", mock.Anything, mock.Anything, mock.Anything)
+			mockEvent.AssertNotCalled(t, "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CancelUsersResourceIndexation_EventFailureRollsBack(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, &mockContentExtractor{}, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resource := createTestResource()
+	outboxErr := errors.New("outbox insert failed")
+
+	cancelled := resource
+	cancelled.Status = resourcemodel.ResourceStatusCancelled
+
+	mockRepo.On("GetUsersResourceByID", ctx, resource.ID, userID).Return(resource, nil)
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, resourcemodel.ResourceStatusCancelled).Return(cancelled, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.indexation_cancelled", mock.Anything).Return(outboxErr)
+
+	// Act
+	_, err := service.CancelUsersResourceIndexation(ctx, userID, resource.ID)
+
+	// Assert: the status change is rolled back with the event
+	require.ErrorIs(t, err, outboxErr)
+	assert.ErrorIs(t, mockRepo.txErr, outboxErr)
+}
+
+func TestService_GetUsersResourceByID_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	expectedResource := createTestResource()
+	expectedResource.ID = resourceID
+	expectedResource.OwnerID = userID
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(expectedResource, nil)
+
+	// Act
+	result, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, expectedResource, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetUsersResourceByID_Error(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("GetResourceByID", ctx, resourceID).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+
+	// Act
+	result, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrResourceNotFound)
+	assert.Equal(t, resourcemodel.Resource{}, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetUsersResourceByID_NotOwner(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	othersResource := createTestResource()
+	othersResource.ID = resourceID
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
+	mockRepo.On("GetResourceByID", ctx, resourceID).Return(othersResource, nil)
+
+	// Act
+	result, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+
+	// Assert
+	require.ErrorIs(t, err, resourcemodel.ErrNotOwner)
+	assert.Equal(t, resourcemodel.Resource{}, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetUsersResourceByID_RepositoryError(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	expectedError := errors.New("connection refused")
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(resourcemodel.Resource{}, expectedError)
+
+	// Act
+	_, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+
+	// Assert
+	require.ErrorIs(t, err, expectedError)
+	assert.NotErrorIs(t, err, resourcemodel.ErrResourceNotFound)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetResourceByID", mock.Anything, mock.Anything)
+}
+
+func TestService_GetUsersResourceStats(t *testing.T) {
+	mockRepo := &mockResourceRepository{}
+	service := NewService(mockRepo, &mockContentExtractor{}, &mockEventService{})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockRepo.On("CountResourcesByOwnerIDPerStatus", ctx, userID).Return(map[resourcemodel.ResourceStatus]int{
+		resourcemodel.ResourceStatusCompleted: 7,
+		resourcemodel.ResourceStatusFailed:    2,
+		resourcemodel.ResourceStatusPending:   1,
+	}, nil)
+
+	stats, err := service.GetUsersResourceStats(ctx, userID)
+
+	require.NoError(t, err)
+	// Statuses without resources are reported as zero
+	assert.Equal(t, resourcemodel.ResourceStats{
+		Total: 10,
+		ByStatus: map[resourcemodel.ResourceStatus]int{
+			resourcemodel.ResourceStatusPending:    1,
+			resourcemodel.ResourceStatusProcessing: 0,
+			resourcemodel.ResourceStatusCompleted:  7,
+			resourcemodel.ResourceStatusFailed:     2,
+			resourcemodel.ResourceStatusCancelled:  0,
+		},
+	}, stats)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_GetUsersResourceStatus(t *testing.T) {
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	tests := []struct {
+		name        string
+		repoErr     error
+		owner       error
+		expected    resourcemodel.ResourceStatus
+		expectedErr error
+	}{
+		{name: "owned resource", expected: resourcemodel.ResourceStatusProcessing},
+		{name: "missing resource", repoErr: resourcemodel.ErrResourceNotFound, owner: resourcemodel.ErrResourceNotFound, expectedErr: resourcemodel.ErrResourceNotFound},
+		{name: "resource of another user", repoErr: resourcemodel.ErrResourceNotFound, expectedErr: resourcemodel.ErrNotOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &mockResourceRepository{}
+			service := NewService(mockRepo, &mockContentExtractor{}, &mockEventService{})
+			ctx := context.Background()
+
+			mockRepo.On("GetUsersResourceStatus", ctx, resourceID, userID).Return(tt.expected, tt.repoErr)
+			if tt.repoErr != nil {
+				mockRepo.On("GetResourceByID", ctx, resourceID).Return(resourcemodel.Resource{ID: resourceID}, tt.owner)
+			}
+
+			status, err := service.GetUsersResourceStatus(ctx, userID, resourceID)
+
+			require.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expected, status)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_UpdateResourceStatus_Success(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	resource := createTestResource()
+	newStatus := resourcemodel.ResourceStatusCompleted
+
+	updatedResource := resource
+	updatedResource.Status = newStatus
+
+	// Mock expectations
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, newStatus).Return(updatedResource, nil)
 
-	expectedEventData := map[string]interface{}{
-		"resource_id": updatedResource.ID,
-		"owner_id":    updatedResource.OwnerID,
-		"name":        updatedResource.Name,
-		"type":        updatedResource.Type,
-		"status":      updatedResource.Status,
-		"updated_at":  updatedResource.UpdatedAt,
-	}
-	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", expectedEventData).Return(nil)
+	// Use flexible matching for event data since time.Now() is dynamic
+	// Note: There's a bug in the service where old_status shows the new status
+	// because resource.Status is updated before the event is published
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.status_updated", mock.MatchedBy(func(data interface{}) bool {
+		eventData, ok := data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		return eventData["resource_id"] == resource.ID &&
+			eventData["owner_id"] == resource.OwnerID &&
+			eventData["old_status"] == newStatus && // Bug: shows new status instead of old
+			eventData["new_status"] == newStatus &&
+			eventData["updated_at"] != nil
+	})).Return(nil)
 
 	// Act
-	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, nil)
+	result, err := service.UpdateResourceStatus(ctx, resource, newStatus)
 
 	// Assert
 	require.NoError(t, err)
 	assert.Equal(t, updatedResource, result)
+	assert.Equal(t, newStatus, result.Status)
 
 	mockRepo.AssertExpectations(t)
-	mockExtractor.AssertNotCalled(t, "ExtractContent")
 	mockEvent.AssertExpectations(t)
 }
 
-func TestService_UpdateUsersResource_GetResourceError(t *testing.T) {
+func TestService_UpdateResourceStatus_RepositoryError(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -454,29 +1780,27 @@ func TestService_UpdateUsersResource_GetResourceError(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
-	newName := "Updated Resource"
+	resource := createTestResource()
+	newStatus := resourcemodel.ResourceStatusCompleted
 
-	expectedError := errors.New("resource not found")
+	expectedError := errors.New("update failed")
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(resourcemodel.Resource{}, expectedError)
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, newStatus).Return(resourcemodel.Resource{}, expectedError)
 
 	// Act
-	result, err := service.UpdateUsersResource(ctx, userID, resourceID, &newName, nil)
+	result, err := service.UpdateResourceStatus(ctx, resource, newStatus)
 
 	// Assert
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "resource not found")
+	assert.Contains(t, err.Error(), "update failed")
 	assert.Equal(t, resourcemodel.Resource{}, result)
 
 	mockRepo.AssertExpectations(t)
-	mockExtractor.AssertNotCalled(t, "ExtractContent")
 	mockEvent.AssertNotCalled(t, "PublishEvent")
 }
 
-func TestService_DeleteUsersResource_Success(t *testing.T) {
+func TestService_UpdateUsersResourceVisibility_Success(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -485,41 +1809,39 @@ func TestService_DeleteUsersResource_Success(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
+	resource := createTestResource()
+	resource.Visibility = resourcemodel.ResourceVisibilityPrivate
 
-	existingResource := createTestResource()
-	existingResource.ID = resourceID
-	existingResource.OwnerID = userID
+	updatedResource := resource
+	updatedResource.Visibility = resourcemodel.ResourceVisibilityPublic
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(existingResource, nil)
-	mockRepo.On("DeleteUsersResource", ctx, userID, resourceID).Return(nil)
-
-	// Use a more flexible matching for event data since time.Now() is dynamic
-	mockEvent.On("PublishEvent", ctx, "resources", "resource.deleted", mock.MatchedBy(func(data interface{}) bool {
+	mockRepo.On("GetUsersResourceByID", ctx, resource.ID, resource.OwnerID).Return(resource, nil)
+	mockRepo.On("UpdateResourceVisibility", ctx, resource.ID, resource.OwnerID, resourcemodel.ResourceVisibilityPublic).Return(updatedResource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.visibility_updated", mock.MatchedBy(func(data interface{}) bool {
 		eventData, ok := data.(map[string]interface{})
 		if !ok {
 			return false
 		}
-		return eventData["resource_id"] == resourceID &&
-			eventData["owner_id"] == userID &&
-			eventData["name"] == existingResource.Name &&
-			eventData["type"] == existingResource.Type &&
-			eventData["deleted_at"] != nil
+		return eventData["resource_id"] == resource.ID &&
+			eventData["owner_id"] == resource.OwnerID &&
+			eventData["old_visibility"] == resourcemodel.ResourceVisibilityPrivate &&
+			eventData["visibility"] == resourcemodel.ResourceVisibilityPublic
 	})).Return(nil)
 
 	// Act
-	err := service.DeleteUsersResource(ctx, userID, resourceID)
+	result, err := service.UpdateUsersResourceVisibility(ctx, resource.OwnerID, resource.ID, resourcemodel.ResourceVisibilityPublic)
 
 	// Assert
 	require.NoError(t, err)
+	assert.Equal(t, updatedResource, result)
+	assert.True(t, mockRepo.txRun)
 
 	mockRepo.AssertExpectations(t)
 	mockEvent.AssertExpectations(t)
 }
 
-func TestService_DeleteUsersResource_GetResourceError(t *testing.T) {
+func TestService_UpdateUsersResourceVisibility_Unchanged(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -528,26 +1850,24 @@ func TestService_DeleteUsersResource_GetResourceError(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
-
-	expectedError := errors.New("resource not found")
+	resource := createTestResource()
+	resource.Visibility = resourcemodel.ResourceVisibilityPublic
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(resourcemodel.Resource{}, expectedError)
+	mockRepo.On("GetUsersResourceByID", ctx, resource.ID, resource.OwnerID).Return(resource, nil)
 
 	// Act
-	err := service.DeleteUsersResource(ctx, userID, resourceID)
+	result, err := service.UpdateUsersResourceVisibility(ctx, resource.OwnerID, resource.ID, resourcemodel.ResourceVisibilityPublic)
 
 	// Assert
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "resource not found")
+	require.NoError(t, err)
+	assert.Equal(t, resource, result)
 
-	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateResourceVisibility")
 	mockEvent.AssertNotCalled(t, "PublishEvent")
 }
 
-func TestService_DeleteUsersResource_DeleteError(t *testing.T) {
+func TestService_UpdateUsersResourceVisibility_InvalidVisibility(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -555,32 +1875,17 @@ func TestService_DeleteUsersResource_DeleteError(t *testing.T) {
 
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
-	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
-
-	existingResource := createTestResource()
-	existingResource.ID = resourceID
-	existingResource.OwnerID = userID
-
-	expectedError := errors.New("delete failed")
-
-	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(existingResource, nil)
-	mockRepo.On("DeleteUsersResource", ctx, userID, resourceID).Return(expectedError)
-
-	// Act
-	err := service.DeleteUsersResource(ctx, userID, resourceID)
-
-	// Assert
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "delete failed")
+	for _, visibility := range []resourcemodel.ResourceVisibility{"everyone", resourcemodel.ResourceVisibilityShared} {
+		// Act
+		_, err := service.UpdateUsersResourceVisibility(context.Background(), uuid.New(), uuid.New(), visibility)
 
-	mockRepo.AssertExpectations(t)
-	mockEvent.AssertNotCalled(t, "PublishEvent")
+		// Assert
+		require.ErrorIs(t, err, resourcemodel.ErrorWrongVisibility, visibility)
+		assert.False(t, mockRepo.txRun)
+	}
 }
 
-func TestService_GetUsersResourceByID_Success(t *testing.T) {
+func TestService_FailStaleResources_PublishesStatusUpdates(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -589,27 +1894,31 @@ func TestService_GetUsersResourceByID_Success(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
-
-	expectedResource := createTestResource()
-	expectedResource.ID = resourceID
-	expectedResource.OwnerID = userID
+	updatedBefore := time.Now().Add(-time.Hour)
+	failed := createTestResource()
+	failed.Status = resourcemodel.ResourceStatusFailed
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(expectedResource, nil)
+	mockRepo.On("FailStaleResources", ctx, updatedBefore, 50).Return([]resourcemodel.Resource{failed}, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.status_updated", map[string]interface{}{
+		"resource_id": failed.ID,
+		"owner_id":    failed.OwnerID,
+		"new_status":  resourcemodel.ResourceStatusFailed,
+		"updated_at":  failed.UpdatedAt,
+	}).Return(nil)
 
 	// Act
-	result, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+	result, err := service.FailStaleResources(ctx, updatedBefore, 50)
 
 	// Assert
 	require.NoError(t, err)
-	assert.Equal(t, expectedResource, result)
-
+	assert.Equal(t, []resourcemodel.Resource{failed}, result)
+	assert.True(t, mockRepo.txRun, "the status and its event are stored together")
 	mockRepo.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
 }
 
-func TestService_GetUsersResourceByID_Error(t *testing.T) {
+func TestService_FailStaleResources_EventErrorRollsBack(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
 	mockExtractor := &mockContentExtractor{}
@@ -618,97 +1927,80 @@ func TestService_GetUsersResourceByID_Error(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	ctx := context.Background()
-	userID := uuid.New()
-	resourceID := uuid.New()
-
-	expectedError := errors.New("resource not found")
+	updatedBefore := time.Now().Add(-time.Hour)
+	failed := createTestResource()
+	failed.Status = resourcemodel.ResourceStatusFailed
+	expectedError := errors.New("outbox unavailable")
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(resourcemodel.Resource{}, expectedError)
+	mockRepo.On("FailStaleResources", ctx, updatedBefore, 50).Return([]resourcemodel.Resource{failed}, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.status_updated", mock.Anything).Return(expectedError)
 
 	// Act
-	result, err := service.GetUsersResourceByID(ctx, userID, resourceID)
+	result, err := service.FailStaleResources(ctx, updatedBefore, 50)
 
 	// Assert
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "resource not found")
-	assert.Equal(t, resourcemodel.Resource{}, result)
-
+	require.ErrorIs(t, err, expectedError)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, mockRepo.txErr, expectedError)
 	mockRepo.AssertExpectations(t)
 }
 
-func TestService_UpdateResourceStatus_Success(t *testing.T) {
+func TestService_ReindexResource_PublishesUpdatedEvent(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
-	mockExtractor := &mockContentExtractor{}
 	mockEvent := &mockEventService{}
 
-	service := NewService(mockRepo, mockExtractor, mockEvent)
+	service := NewService(mockRepo, &mockContentExtractor{}, mockEvent)
 
 	ctx := context.Background()
 	resource := createTestResource()
-	newStatus := resourcemodel.ResourceStatusCompleted
-
-	updatedResource := resource
-	updatedResource.Status = newStatus
-
-	// Mock expectations
-	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, newStatus).Return(updatedResource, nil)
-
-	// Use flexible matching for event data since time.Now() is dynamic
-	// Note: There's a bug in the service where old_status shows the new status
-	// because resource.Status is updated before the event is published
-	mockEvent.On("PublishEvent", ctx, "resources", "resource.status_updated", mock.MatchedBy(func(data interface{}) bool {
-		eventData, ok := data.(map[string]interface{})
-		if !ok {
-			return false
-		}
-		return eventData["resource_id"] == resource.ID &&
-			eventData["owner_id"] == resource.OwnerID &&
-			eventData["old_status"] == newStatus && // Bug: shows new status instead of old
-			eventData["new_status"] == newStatus &&
-			eventData["updated_at"] != nil
+	resource.Status = resourcemodel.ResourceStatusCompleted
+	resource.ChunkSize = 256
+
+	processing := resource
+	processing.Status = resourcemodel.ResourceStatusProcessing
+
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, resourcemodel.ResourceStatusProcessing).Return(processing, nil)
+	// The stored chunking override is indexed with again
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["resource_id"] == resource.ID &&
+			data["owner_id"] == resource.OwnerID &&
+			data["status"] == resourcemodel.ResourceStatusProcessing &&
+			data["extracted_content"] == resource.ExtractedContent &&
+			data["chunk_size"] == 256
 	})).Return(nil)
 
 	// Act
-	result, err := service.UpdateResourceStatus(ctx, resource, newStatus)
+	err := service.ReindexResource(ctx, resource)
 
 	// Assert
 	require.NoError(t, err)
-	assert.Equal(t, updatedResource, result)
-	assert.Equal(t, newStatus, result.Status)
-
+	assert.True(t, mockRepo.txRun)
 	mockRepo.AssertExpectations(t)
 	mockEvent.AssertExpectations(t)
 }
 
-func TestService_UpdateResourceStatus_RepositoryError(t *testing.T) {
+func TestService_ReindexResource_EventFailureRollsBack(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
-	mockExtractor := &mockContentExtractor{}
 	mockEvent := &mockEventService{}
 
-	service := NewService(mockRepo, mockExtractor, mockEvent)
+	service := NewService(mockRepo, &mockContentExtractor{}, mockEvent)
 
 	ctx := context.Background()
 	resource := createTestResource()
-	newStatus := resourcemodel.ResourceStatusCompleted
+	outboxErr := errors.New("outbox insert failed")
 
-	expectedError := errors.New("update failed")
-
-	// Mock expectations
-	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, newStatus).Return(resourcemodel.Resource{}, expectedError)
+	mockRepo.On("UpdateResourceStatus", ctx, resource.ID, resourcemodel.ResourceStatusProcessing).Return(resource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", mock.Anything).Return(outboxErr)
 
 	// Act
-	result, err := service.UpdateResourceStatus(ctx, resource, newStatus)
-
-	// Assert
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "update failed")
-	assert.Equal(t, resourcemodel.Resource{}, result)
+	err := service.ReindexResource(ctx, resource)
 
-	mockRepo.AssertExpectations(t)
-	mockEvent.AssertNotCalled(t, "PublishEvent")
+	// Assert: the status change is rolled back with the event
+	require.ErrorIs(t, err, outboxErr)
+	assert.ErrorIs(t, mockRepo.txErr, outboxErr)
 }
 
 func TestService_GetResourceByID_Success(t *testing.T) {
@@ -774,10 +2066,7 @@ func TestService_GetResourceStatusChannel_Success(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	resourceID := uuid.New()
-	expectedChannel := make(chan resourcemodel.ResourceStatusUpdate)
-
-	// Manually add the channel to the sync.Map
-	service.statusChannels.Store(resourceID, expectedChannel)
+	expectedChannel := service.RegisterResourceStatusChannel(resourceID)
 
 	// Act
 	result, exists := service.GetResourceStatusChannel(resourceID)
@@ -816,7 +2105,7 @@ func TestService_GetResourceStatusChannel_WrongType(t *testing.T) {
 	resourceID := uuid.New()
 
 	// Store wrong type in the sync.Map
-	service.statusChannels.Store(resourceID, "not a channel")
+	service.channels.Store(resourceID, "not a channel")
 
 	// Act
 	result, exists := service.GetResourceStatusChannel(resourceID)
@@ -826,7 +2115,7 @@ func TestService_GetResourceStatusChannel_WrongType(t *testing.T) {
 	assert.Nil(t, result)
 
 	// Verify that the wrong type entry was removed
-	_, stillExists := service.statusChannels.Load(resourceID)
+	_, stillExists := service.channels.Load(resourceID)
 	assert.False(t, stillExists)
 }
 
@@ -839,21 +2128,21 @@ func TestService_RemoveResourceStatusChannel(t *testing.T) {
 	service := NewService(mockRepo, mockExtractor, mockEvent)
 
 	resourceID := uuid.New()
-	expectedChannel := make(chan resourcemodel.ResourceStatusUpdate)
-
-	// Manually add the channel to the sync.Map
-	service.statusChannels.Store(resourceID, expectedChannel)
+	statusCh := service.RegisterResourceStatusChannel(resourceID)
 
 	// Verify it exists
-	_, exists := service.statusChannels.Load(resourceID)
+	_, exists := service.GetResourceStatusChannel(resourceID)
 	assert.True(t, exists)
 
 	// Act
 	service.RemoveResourceStatusChannel(resourceID)
 
 	// Assert
-	_, exists = service.statusChannels.Load(resourceID)
+	_, exists = service.GetResourceStatusChannel(resourceID)
 	assert.False(t, exists)
+	_, open := <-statusCh
+	assert.False(t, open, "the channel is closed")
+	assert.False(t, service.SendResourceStatus(resourcemodel.ResourceStatusUpdate{ResourceID: resourceID}))
 }
 
 func TestService_extractContent_Success(t *testing.T) {
@@ -911,20 +2200,24 @@ func TestService_SaveUsersResource_EventPublishError(t *testing.T) {
 
 	// Mock expectations
 	mockExtractor.On("ExtractContent", ctx, content, string(resourceType)).Return(extractedContent, nil)
+	mockRepo.On("GetUsersResourceByContentHash", ctx, mock.Anything, mock.Anything).Return(resourcemodel.Resource{}, resourcemodel.ErrResourceNotFound)
 	mockRepo.On("SaveResource", ctx, mock.AnythingOfType("resourcemodel.Resource")).Return(savedResource, nil)
 
 	expectedEventData := map[string]interface{}{
 		"resource_id": savedResource.ID,
 		"owner_id":    savedResource.OwnerID,
+		"tenant_id":   savedResource.TenantID,
 		"name":        savedResource.Name,
 		"type":        savedResource.Type,
 		"status":      savedResource.Status,
+		"priority":    resourcemodel.ResourcePriorityNormal,
+		"visibility":  savedResource.Visibility,
 		"created_at":  savedResource.CreatedAt,
 	}
 	mockEvent.On("PublishEvent", ctx, "resources", "resource.created", expectedEventData).Return(eventError)
 
 	// Act
-	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url)
+	result, statusCh, err := service.SaveUsersResource(ctx, userID, content, resourceType, name, url, "")
 
 	// Assert
 	// Should return the error from event publishing
@@ -958,11 +2251,11 @@ func TestService_UpdateUsersResource_ExtractContentError(t *testing.T) {
 	extractError := errors.New("content extraction failed")
 
 	// Mock expectations
-	mockRepo.On("GetUsersResourceByID", ctx, userID, resourceID).Return(existingResource, nil)
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
 	mockExtractor.On("ExtractContent", ctx, newContent, string(existingResource.Type)).Return("", extractError)
 
 	// Act
-	result, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, &newContent)
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, nil, &newContent)
 
 	// Assert
 	require.Error(t, err)
@@ -974,6 +2267,135 @@ func TestService_UpdateUsersResource_ExtractContentError(t *testing.T) {
 	mockEvent.AssertNotCalled(t, "PublishEvent")
 }
 
+func TestService_UpdateUsersResource_TypeChangeReextracts(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newType := resourcemodel.ResourceTypeURL
+	extractedContent := "# Example page"
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	existingResource.Status = resourcemodel.ResourceStatusCompleted
+	existingResource.RawContent = []byte("https://example.com/page")
+
+	updatedResource := existingResource
+	updatedResource.Type = newType
+	updatedResource.ExtractedContent = extractedContent
+	updatedResource.Status = resourcemodel.ResourceStatusProcessing
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockExtractor.On("ExtractContent", ctx, existingResource.RawContent, string(newType)).Return(extractedContent, nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, mock.MatchedBy(func(r resourcemodel.Resource) bool {
+		return r.Type == newType &&
+			r.ExtractedContent == extractedContent &&
+			r.Status == resourcemodel.ResourceStatusProcessing
+	})).Return(updatedResource, nil)
+
+	expectedEventData := map[string]interface{}{
+		"resource_id":       updatedResource.ID,
+		"owner_id":          updatedResource.OwnerID,
+		"tenant_id":         updatedResource.TenantID,
+		"name":              updatedResource.Name,
+		"url":               updatedResource.URL,
+		"visibility":        updatedResource.Visibility,
+		"type":              newType,
+		"status":            resourcemodel.ResourceStatusProcessing,
+		"created_at":        updatedResource.CreatedAt,
+		"updated_at":        updatedResource.UpdatedAt,
+		"extracted_content": updatedResource.ExtractedContent,
+	}
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.updated", expectedEventData).Return(nil)
+
+	// Act
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, &newType, nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, updatedResource, result)
+
+	mockRepo.AssertExpectations(t)
+	mockExtractor.AssertExpectations(t)
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_UpdateUsersResource_SameTypeSkipsExtraction(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+	sameType := existingResource.Type
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+	mockRepo.On("UpdateUsersResource", ctx, userID, existingResource).Return(existingResource, nil)
+	mockEvent.On("PublishEvent", ctx, "resources", "resource.metadata_updated", mock.Anything).Return(nil)
+
+	// Act
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, &sameType, nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, existingResource, result)
+
+	mockRepo.AssertExpectations(t)
+	mockExtractor.AssertNotCalled(t, "ExtractContent")
+	mockEvent.AssertExpectations(t)
+}
+
+func TestService_UpdateUsersResource_IncompatibleType(t *testing.T) {
+	// Arrange
+	mockRepo := &mockResourceRepository{}
+	mockExtractor := &mockContentExtractor{}
+	mockEvent := &mockEventService{}
+
+	service := NewService(mockRepo, mockExtractor, mockEvent)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	resourceID := uuid.New()
+	newType := resourcemodel.ResourceTypePDF
+
+	existingResource := createTestResource()
+	existingResource.ID = resourceID
+	existingResource.OwnerID = userID
+
+	// Mock expectations
+	mockRepo.On("GetUsersResourceByID", ctx, resourceID, userID).Return(existingResource, nil)
+
+	// Act
+	result, err := service.UpdateUsersResource(ctx, userID, resourceID, nil, &newType, nil)
+
+	// Assert
+	require.Error(t, err)
+	assert.ErrorIs(t, err, resourcemodel.ErrorIncompatibleType)
+	assert.Equal(t, resourcemodel.Resource{}, result)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateUsersResource", mock.Anything, mock.Anything, mock.Anything)
+	mockExtractor.AssertNotCalled(t, "ExtractContent")
+	mockEvent.AssertNotCalled(t, "PublishEvent")
+}
+
 func TestService_extractContent_Error(t *testing.T) {
 	// Arrange
 	mockRepo := &mockResourceRepository{}
@@ -0,0 +1,171 @@
+package resourceservcie
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// statusChannel is the channel the status updates of a resource are streamed
+// to its reader through. Progress, completion, cancellation and the status
+// reconciler run in different goroutines, so the channel is only sent on while
+// holding mu and closed by whoever took it from StatusChannels.
+type statusChannel struct {
+	mu     sync.Mutex
+	ch     chan resourcemodel.ResourceStatusUpdate
+	closed bool
+}
+
+func newStatusChannel() *statusChannel {
+	return &statusChannel{ch: make(chan resourcemodel.ResourceStatusUpdate, statusChannelBuffer)}
+}
+
+// send hands the update to the reader unless the channel is full or was taken
+// to be closed. It reports whether the update was queued.
+func (c *statusChannel) send(update resourcemodel.ResourceStatusUpdate) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	select {
+	case c.ch <- update:
+		return true
+	default:
+		return false
+	}
+}
+
+// finish sends the final update, waiting up to timeout for the reader, and
+// closes the channel. It must only be called on a taken channel.
+func (c *statusChannel) finish(ctx context.Context, update resourcemodel.ResourceStatusUpdate, timeout time.Duration) bool {
+	defer close(c.ch)
+
+	if timeout <= 0 {
+		select {
+		case c.ch <- update:
+			return true
+		default:
+			return false
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case c.ch <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return false
+	}
+}
+
+// StatusChannels are the channels the status updates of resources are read
+// from while they are indexed, one per resource. The zero value is ready to use.
+type StatusChannels struct {
+	// channels maps resource.ID to its *statusChannel
+	channels sync.Map
+}
+
+// RegisterResourceStatusChannel opens the status channel of the resource,
+// closing the one it had
+func (s *StatusChannels) RegisterResourceStatusChannel(resourceID uuid.UUID) <-chan resourcemodel.ResourceStatusUpdate {
+	statusCh := newStatusChannel()
+	s.registerStatusChannel(resourceID, statusCh)
+	return statusCh.ch
+}
+
+func (s *StatusChannels) registerStatusChannel(resourceID uuid.UUID, statusCh *statusChannel) {
+	if previous, loaded := s.channels.Swap(resourceID, statusCh); loaded {
+		if previous, ok := previous.(*statusChannel); ok {
+			previous.take()
+			close(previous.ch)
+		}
+	}
+}
+
+// take marks the channel as closing, so that no sender uses it any longer
+func (c *statusChannel) take() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+}
+
+// takeStatusChannel removes the resource's status channel so that the caller
+// is the only one to close it. Senders that loaded it before skip it from now on.
+func (s *StatusChannels) takeStatusChannel(resourceID uuid.UUID) (*statusChannel, bool) {
+	value, exists := s.channels.LoadAndDelete(resourceID)
+	if !exists {
+		return nil, false
+	}
+	statusCh, ok := value.(*statusChannel)
+	if !ok {
+		return nil, false
+	}
+
+	statusCh.take()
+	return statusCh, true
+}
+
+// GetResourceStatusChannel returns the channel the status updates of the
+// resource are read from
+func (s *StatusChannels) GetResourceStatusChannel(resourceID uuid.UUID) (<-chan resourcemodel.ResourceStatusUpdate, bool) {
+	value, exists := s.channels.Load(resourceID)
+	if !exists {
+		return nil, false
+	}
+
+	statusCh, ok := value.(*statusChannel)
+	if !ok {
+		s.channels.Delete(resourceID)
+		return nil, false
+	}
+
+	return statusCh.ch, true
+}
+
+// SendResourceStatus queues an intermediate status update for the reader of
+// the resource without waiting. It reports whether the update was queued: it
+// is not when nobody reads the status, the reader is behind or the channel is
+// being closed.
+func (s *StatusChannels) SendResourceStatus(update resourcemodel.ResourceStatusUpdate) bool {
+	value, exists := s.channels.Load(update.ResourceID)
+	if !exists {
+		return false
+	}
+	statusCh, ok := value.(*statusChannel)
+	if !ok {
+		return false
+	}
+
+	return statusCh.send(update)
+}
+
+// FinishResourceStatus sends the final status update of the resource, waiting
+// up to timeout for the reader, and closes its status channel. found reports
+// whether the resource had a channel left to close, delivered whether the
+// reader got the update.
+func (s *StatusChannels) FinishResourceStatus(ctx context.Context, update resourcemodel.ResourceStatusUpdate, timeout time.Duration) (found, delivered bool) {
+	statusCh, found := s.takeStatusChannel(update.ResourceID)
+	if !found {
+		return false, false
+	}
+
+	return true, statusCh.finish(ctx, update, timeout)
+}
+
+// RemoveResourceStatusChannel closes the status channel of a resource nobody
+// streams the status of
+func (s *StatusChannels) RemoveResourceStatusChannel(resourceID uuid.UUID) {
+	if statusCh, ok := s.takeStatusChannel(resourceID); ok {
+		close(statusCh.ch)
+	}
+}
@@ -0,0 +1,141 @@
+package statusreconciler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/configurator"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// resourceService defines the operations the reconciler needs on resources
+// and their status channels
+type resourceService interface {
+	FailStaleResources(ctx context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error)
+	FinishResourceStatus(ctx context.Context, update resourcemodel.ResourceStatusUpdate, timeout time.Duration) (found, delivered bool)
+}
+
+// Config holds configuration for the status reconciler
+type Config struct {
+	// Interval is how often unfinished resources are checked after the check
+	// at startup
+	Interval time.Duration `yaml:"interval" mapstructure:"interval" validate:"gt=0"`
+	// StaleAfter is how long a resource may stay pending or processing
+	// without an update before its indexation is taken for lost
+	StaleAfter time.Duration `yaml:"stale_after" mapstructure:"stale_after" validate:"gt=0"`
+	// BatchSize is the number of resources failed in one transaction
+	BatchSize int `yaml:"batch_size" mapstructure:"batch_size" validate:"min=1"`
+}
+
+// DefaultConfig returns the reconciler settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		Interval:   5 * time.Minute,
+		StaleAfter: time.Hour,
+		BatchSize:  100,
+	}
+}
+
+// NewConfig loads the reconciler settings from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("reconciler", DefaultConfig())
+}
+
+// Reconciler fails the resources whose indexation was lost, e.g. because the
+// service restarted while they were processed. The status channels of such
+// resources lived in memory, so nothing else would ever finish them.
+// Resources updated more recently are left to their pending indexation
+// completion events, which Kafka delivers after a restart, and are checked
+// again on the next run.
+type Reconciler struct {
+	resourceService resourceService
+	config          Config
+	now             func() time.Time
+	stopCh          chan struct{}
+	doneCh          chan struct{}
+}
+
+// NewReconciler creates a status reconciler with the given configuration
+func NewReconciler(resourceService resourceService, config Config) *Reconciler {
+	return &Reconciler{
+		resourceService: resourceService,
+		config:          config,
+		now:             time.Now,
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+	}
+}
+
+// Start reconciles the resources at once and then every interval.
+// This method blocks until Stop is called or the context is cancelled
+func (r *Reconciler) Start(ctx context.Context) {
+	defer close(r.doneCh)
+
+	slog.InfoContext(ctx, "Starting status reconciler",
+		"interval", r.config.Interval,
+		"stale_after", r.config.StaleAfter)
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.InfoContext(ctx, "Status reconciler stopped due to context cancellation")
+			return
+		case <-r.stopCh:
+			slog.InfoContext(ctx, "Status reconciler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop gracefully stops the status reconciler
+func (r *Reconciler) Stop() {
+	close(r.stopCh)
+	<-r.doneCh
+}
+
+// reconcile fails every stale resource, a batch at a time
+func (r *Reconciler) reconcile(ctx context.Context) {
+	const op = "StatusReconciler.reconcile"
+
+	updatedBefore := r.now().Add(-r.config.StaleAfter)
+	for {
+		failed, err := r.resourceService.FailStaleResources(ctx, updatedBefore, r.config.BatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to fail stale resources",
+				"op", op,
+				"error", err)
+			return
+		}
+
+		for _, resource := range failed {
+			slog.WarnContext(ctx, "Failed resource whose indexation was lost",
+				"op", op,
+				"resource_id", resource.ID,
+				"updated_before", updatedBefore)
+			r.notify(ctx, resource.ID)
+		}
+
+		if len(failed) < r.config.BatchSize {
+			return
+		}
+	}
+}
+
+// notify sends the failed status to a reader still watching the resource and
+// closes its status channel. Readers without a channel poll the status.
+func (r *Reconciler) notify(ctx context.Context, resourceID uuid.UUID) {
+	update := resourcemodel.ResourceStatusUpdate{ResourceID: resourceID, Status: resourcemodel.ResourceStatusFailed}
+	if found, delivered := r.resourceService.FinishResourceStatus(ctx, update, 0); found && !delivered {
+		slog.WarnContext(ctx, "Status channel is busy, dropping failed status update",
+			"resource_id", resourceID)
+	}
+}
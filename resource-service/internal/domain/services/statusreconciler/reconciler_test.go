@@ -0,0 +1,179 @@
+package statusreconciler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+)
+
+// fakeResourceService keeps resources in memory and fails them like the
+// repository query does
+type fakeResourceService struct {
+	mu        sync.Mutex
+	resources map[uuid.UUID]resourcemodel.Resource
+	channels  map[uuid.UUID]chan resourcemodel.ResourceStatusUpdate
+	calls     int
+}
+
+func newFakeResourceService() *fakeResourceService {
+	return &fakeResourceService{
+		resources: make(map[uuid.UUID]resourcemodel.Resource),
+		channels:  make(map[uuid.UUID]chan resourcemodel.ResourceStatusUpdate),
+	}
+}
+
+func (s *fakeResourceService) seed(status resourcemodel.ResourceStatus, updatedAt time.Time) uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resource := resourcemodel.Resource{ID: uuid.New(), Status: status, UpdatedAt: updatedAt}
+	s.resources[resource.ID] = resource
+	return resource.ID
+}
+
+func (s *fakeResourceService) status(id uuid.UUID) resourcemodel.ResourceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resources[id].Status
+}
+
+func (s *fakeResourceService) FailStaleResources(_ context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+
+	var stale []resourcemodel.Resource
+	for _, resource := range s.resources {
+		if !resource.Status.IsTerminal() && resource.UpdatedAt.Before(updatedBefore) {
+			stale = append(stale, resource)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].UpdatedAt.Before(stale[j].UpdatedAt) })
+	if len(stale) > limit {
+		stale = stale[:limit]
+	}
+
+	for i := range stale {
+		stale[i].Status = resourcemodel.ResourceStatusFailed
+		s.resources[stale[i].ID] = stale[i]
+	}
+	return stale, nil
+}
+
+func (s *fakeResourceService) FinishResourceStatus(_ context.Context, update resourcemodel.ResourceStatusUpdate, _ time.Duration) (bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.channels[update.ResourceID]
+	if !ok {
+		return false, false
+	}
+	delete(s.channels, update.ResourceID)
+	defer close(ch)
+
+	select {
+	case ch <- update:
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+func TestReconcile_FailsResourcesPastThreshold(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	service := newFakeResourceService()
+	staleProcessing := service.seed(resourcemodel.ResourceStatusProcessing, now.Add(-2*time.Hour))
+	stalePending := service.seed(resourcemodel.ResourceStatusPending, now.Add(-3*time.Hour))
+	recent := service.seed(resourcemodel.ResourceStatusProcessing, now.Add(-10*time.Minute))
+	completed := service.seed(resourcemodel.ResourceStatusCompleted, now.Add(-5*time.Hour))
+
+	reconciler := NewReconciler(service, Config{Interval: time.Minute, StaleAfter: time.Hour, BatchSize: 100})
+	reconciler.now = func() time.Time { return now }
+
+	reconciler.reconcile(context.Background())
+
+	if got := service.status(staleProcessing); got != resourcemodel.ResourceStatusFailed {
+		t.Errorf("stale processing resource is %s, want failed", got)
+	}
+	if got := service.status(stalePending); got != resourcemodel.ResourceStatusFailed {
+		t.Errorf("stale pending resource is %s, want failed", got)
+	}
+	if got := service.status(recent); got != resourcemodel.ResourceStatusProcessing {
+		t.Errorf("recent resource is %s, want it left processing", got)
+	}
+	if got := service.status(completed); got != resourcemodel.ResourceStatusCompleted {
+		t.Errorf("completed resource is %s, want it left completed", got)
+	}
+
+	// The recent resource is failed once it passes the threshold too
+	now = now.Add(time.Hour)
+	reconciler.reconcile(context.Background())
+
+	if got := service.status(recent); got != resourcemodel.ResourceStatusFailed {
+		t.Errorf("resource past the threshold is %s, want failed", got)
+	}
+}
+
+func TestReconcile_FailsEveryBatch(t *testing.T) {
+	now := time.Now()
+	service := newFakeResourceService()
+	ids := []uuid.UUID{
+		service.seed(resourcemodel.ResourceStatusProcessing, now.Add(-4*time.Hour)),
+		service.seed(resourcemodel.ResourceStatusProcessing, now.Add(-3*time.Hour)),
+		service.seed(resourcemodel.ResourceStatusProcessing, now.Add(-2*time.Hour)),
+	}
+
+	reconciler := NewReconciler(service, Config{Interval: time.Minute, StaleAfter: time.Hour, BatchSize: 2})
+	reconciler.reconcile(context.Background())
+
+	for _, id := range ids {
+		if got := service.status(id); got != resourcemodel.ResourceStatusFailed {
+			t.Errorf("resource %s is %s, want failed", id, got)
+		}
+	}
+	// Two full batches, then one finding the rest
+	if service.calls != 2 {
+		t.Errorf("expected 2 batches, got %d", service.calls)
+	}
+}
+
+func TestReconcile_NotifiesStatusChannel(t *testing.T) {
+	service := newFakeResourceService()
+	id := service.seed(resourcemodel.ResourceStatusProcessing, time.Now().Add(-2*time.Hour))
+	statusCh := make(chan resourcemodel.ResourceStatusUpdate, 1)
+	service.channels[id] = statusCh
+
+	NewReconciler(service, Config{Interval: time.Minute, StaleAfter: time.Hour, BatchSize: 10}).reconcile(context.Background())
+
+	update, ok := <-statusCh
+	if !ok || update.Status != resourcemodel.ResourceStatusFailed || update.ResourceID != id {
+		t.Fatalf("expected a failed update, got %+v (open: %v)", update, ok)
+	}
+	if _, ok := <-statusCh; ok {
+		t.Error("status channel left open")
+	}
+	if _, exists := service.channels[id]; exists {
+		t.Error("status channel not removed")
+	}
+}
+
+func TestStart_ReconcilesAtStartup(t *testing.T) {
+	service := newFakeResourceService()
+	id := service.seed(resourcemodel.ResourceStatusProcessing, time.Now().Add(-2*time.Hour))
+
+	reconciler := NewReconciler(service, Config{Interval: time.Hour, StaleAfter: time.Hour, BatchSize: 10})
+	go reconciler.Start(context.Background())
+
+	deadline := time.Now().Add(time.Second)
+	for service.status(id) != resourcemodel.ResourceStatusFailed {
+		if time.Now().After(deadline) {
+			t.Fatal("stale resource not failed at startup")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	reconciler.Stop()
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Collectors are registered once at package initialization so that the
+// processors only increment them on their hot paths.
+var (
+	// OutboxEventsProcessed counts outbox events published to the broker
+	OutboxEventsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_events_processed_total",
+		Help: "Total number of outbox events published successfully.",
+	})
+
+	// OutboxEventsFailed counts outbox events that failed after all retries
+	OutboxEventsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_events_failed_total",
+		Help: "Total number of outbox events that could not be published after all retries.",
+	})
+
+	// OutboxRetries counts repeated publish attempts of outbox events
+	OutboxRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_retry_total",
+		Help: "Total number of outbox publish retries.",
+	})
+
+	// OutboxUnsentBacklog reports the events still unsent after the last outbox run
+	OutboxUnsentBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_unsent_backlog",
+		Help: "Number of outbox events still unsent after the last outbox run.",
+	})
+
+	// IndexationMessagesHandled counts indexation completion messages by outcome
+	IndexationMessagesHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "indexation_messages_handled_total",
+		Help: "Total number of indexation completion messages handled, labeled by success.",
+	}, []string{"success"})
+)
+
+// Handler exposes the registered metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
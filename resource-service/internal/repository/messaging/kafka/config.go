@@ -10,11 +10,13 @@ import (
 
 // AppConfig holds the complete Kafka configuration from config file
 type AppConfig struct {
-	Brokers         []string        `yaml:"brokers" mapstructure:"brokers" validate:"required,min=1"`
-	ConsumerGroupID string          `yaml:"consumer_group_id" mapstructure:"consumer_group_id" validate:"required"`
+	// Brokers and ConsumerGroupID are usually set from the environment and have defaults
+	Brokers         []string        `yaml:"brokers" mapstructure:"brokers" validate:"omitempty,dive,required"`
+	ConsumerGroupID string          `yaml:"consumer_group_id" mapstructure:"consumer_group_id"`
 	Topics          TopicsConfig    `yaml:"topics" mapstructure:"topics"`
 	Producer        ProducerConfig  `yaml:"producer" mapstructure:"producer"`
 	Consumer        ConsumerOptions `yaml:"consumer" mapstructure:"consumer"`
+	Security        SecurityOptions `yaml:"security" mapstructure:"security"`
 }
 
 // TopicsConfig holds Kafka topic names
@@ -31,7 +33,16 @@ type ProducerConfig struct {
 
 // ConsumerOptions holds Kafka consumer settings
 type ConsumerOptions struct {
-	AutoOffsetReset string `yaml:"auto_offset_reset" mapstructure:"auto_offset_reset"`
+	AutoOffsetReset string `yaml:"auto_offset_reset" mapstructure:"auto_offset_reset" validate:"omitempty,oneof=earliest latest"`
+}
+
+// SecurityOptions holds Kafka authentication and encryption settings
+type SecurityOptions struct {
+	SASLMechanism string `yaml:"sasl_mechanism" mapstructure:"sasl_mechanism" validate:"omitempty,oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"`
+	SASLUser      string `yaml:"sasl_user" mapstructure:"sasl_user"`
+	SASLPassword  string `yaml:"sasl_password" mapstructure:"sasl_password"`
+	TLSEnabled    bool   `yaml:"tls_enabled" mapstructure:"tls_enabled"`
+	TLSCACert     string `yaml:"tls_ca_cert" mapstructure:"tls_ca_cert"`
 }
 
 // NewConfig loads Kafka configuration from config file and environment variables
@@ -64,6 +75,7 @@ func NewConfig() (*Config, error) {
 		RequiredAcks:    sarama.RequiredAcks(appConfig.Producer.RequiredAcks),
 		RetryMax:        appConfig.Producer.RetryMax,
 		CompressionType: getCompressionCodec(appConfig.Producer.CompressionType),
+		SecurityConfig:  newSecurityConfig(appConfig.Security),
 	}
 
 	return config, nil
@@ -102,8 +114,7 @@ func NewConsumerConfig() (*ConsumerConfig, error) {
 	// Provide default for auto offset reset if not set
 	autoOffsetReset := appConfig.Consumer.AutoOffsetReset
 	if autoOffsetReset == "" {
-		// Default to earliest
-		autoOffsetReset = "earliest"
+		autoOffsetReset = OffsetResetEarliest
 	}
 
 	// Convert to consumer Config struct
@@ -111,6 +122,7 @@ func NewConsumerConfig() (*ConsumerConfig, error) {
 		Brokers:         brokers,
 		GroupID:         groupID,
 		AutoOffsetReset: autoOffsetReset,
+		SecurityConfig:  newSecurityConfig(appConfig.Security),
 	}
 
 	return config, nil
@@ -170,6 +182,36 @@ func GetTopicResource() (string, error) {
 	return appConfig.Topics.Resource, nil
 }
 
+// newSecurityConfig builds security settings from the config file, letting
+// environment variables override them so credentials stay out of config.yml
+func newSecurityConfig(options SecurityOptions) SecurityConfig {
+	security := SecurityConfig{
+		SASLMechanism: options.SASLMechanism,
+		SASLUser:      options.SASLUser,
+		SASLPassword:  options.SASLPassword,
+		TLSEnabled:    options.TLSEnabled,
+		TLSCACert:     options.TLSCACert,
+	}
+
+	if mechanism := configurator.GetString("KAFKA_SASL_MECHANISM"); mechanism != "" {
+		security.SASLMechanism = mechanism
+	}
+	if user := configurator.GetString("KAFKA_SASL_USER"); user != "" {
+		security.SASLUser = user
+	}
+	if password := configurator.GetString("KAFKA_SASL_PASSWORD"); password != "" {
+		security.SASLPassword = password
+	}
+	if configurator.GetString("KAFKA_TLS_ENABLED") != "" {
+		security.TLSEnabled = configurator.GetBool("KAFKA_TLS_ENABLED")
+	}
+	if caCert := configurator.GetString("KAFKA_TLS_CA_CERT"); caCert != "" {
+		security.TLSCACert = caCert
+	}
+
+	return security
+}
+
 // getCompressionCodec converts string to sarama compression codec
 func getCompressionCodec(compressionType string) sarama.CompressionCodec {
 	switch strings.ToLower(compressionType) {
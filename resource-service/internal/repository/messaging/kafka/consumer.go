@@ -7,8 +7,13 @@ import (
 	"sync"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/resource-service/internal/repository/messaging"
+	"github.com/nzb3/diploma/resource-service/internal/tracing"
 )
 
 // Consumer implements the MessageConsumer interface using Apache Kafka
@@ -23,15 +28,25 @@ type Consumer struct {
 type ConsumerConfig struct {
 	Brokers         []string
 	GroupID         string
-	AutoOffsetReset string // earliest, latest
+	AutoOffsetReset string // OffsetResetEarliest or OffsetResetLatest
+	SecurityConfig
 }
 
+// Where a consumer group without a committed offset starts reading a partition
+const (
+	// OffsetResetEarliest reads the messages kept by the broker, so that none
+	// produced before the group first joined is missed
+	OffsetResetEarliest = "earliest"
+	// OffsetResetLatest only reads the messages produced after the group joined
+	OffsetResetLatest = "latest"
+)
+
 // NewDefaultConsumerConfig returns a consumer configuration with sensible defaults
 func NewDefaultConsumerConfig(brokers []string, groupID string) *ConsumerConfig {
 	return &ConsumerConfig{
 		Brokers:         brokers,
 		GroupID:         groupID,
-		AutoOffsetReset: "earliest",
+		AutoOffsetReset: OffsetResetEarliest,
 	}
 }
 
@@ -49,17 +64,23 @@ func NewKafkaConsumer(config *ConsumerConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("kafka consumer group ID cannot be empty")
 	}
 
+	offset, err := initialOffset(config.AutoOffsetReset)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create Sarama configuration
-	saramaConfig := sarama.NewConfig()
+	saramaConfig, err := newSaramaConfig(config.SecurityConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kafka consumer security: %w", err)
+	}
+	// Round robin spreads whole partitions over the members, so every partition is
+	// read by a single member, one message after the other. Events sharing a key
+	// are therefore handled in the order they were produced.
 	saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
 	saramaConfig.Consumer.Return.Errors = true
 
-	// Set auto offset reset
-	if config.AutoOffsetReset == "earliest" {
-		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
-	} else {
-		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
-	}
+	saramaConfig.Consumer.Offsets.Initial = offset
 
 	// Create the consumer group
 	consumer, err := sarama.NewConsumerGroup(config.Brokers, config.GroupID, saramaConfig)
@@ -73,6 +94,20 @@ func NewKafkaConsumer(config *ConsumerConfig) (*Consumer, error) {
 	}, nil
 }
 
+// initialOffset returns the sarama offset of the auto offset reset, earliest
+// when it is unset
+func initialOffset(autoOffsetReset string) (int64, error) {
+	switch autoOffsetReset {
+	case OffsetResetEarliest, "":
+		return sarama.OffsetOldest, nil
+	case OffsetResetLatest:
+		return sarama.OffsetNewest, nil
+	default:
+		return 0, fmt.Errorf("invalid kafka auto offset reset %q, expected %q or %q",
+			autoOffsetReset, OffsetResetEarliest, OffsetResetLatest)
+	}
+}
+
 // Subscribe subscribes to topics and starts consuming messages
 func (c *Consumer) Subscribe(ctx context.Context, topics []string, handler messaging.MessageHandler) error {
 	if len(topics) == 0 {
@@ -138,7 +173,12 @@ func (c *Consumer) Subscribe(ctx context.Context, topics []string, handler messa
 // Health checks if the consumer can communicate with Kafka brokers
 func (c *Consumer) Health(ctx context.Context) error {
 	// Create a simple health check by trying to get metadata
-	client, err := sarama.NewClient(c.config.Brokers, sarama.NewConfig())
+	saramaConfig, err := newSaramaConfig(c.config.SecurityConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure kafka client for health check: %w", err)
+	}
+
+	client, err := sarama.NewClient(c.config.Brokers, saramaConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create kafka client for health check: %w", err)
 	}
@@ -207,14 +247,25 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				headers[string(header.Key)] = string(header.Value)
 			}
 
+			// Continue the producer's trace, if the message carries one
+			ctx := otel.GetTextMapPropagator().Extract(session.Context(), propagation.MapCarrier(headers))
+			ctx, span := tracing.StartSpan(ctx, "kafka.consume "+message.Topic,
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.destination.name", message.Topic),
+					attribute.Int("messaging.kafka.partition", int(message.Partition)),
+					attribute.Int64("messaging.kafka.offset", message.Offset),
+				))
+
 			// Handle the message
 			err := h.handler.HandleMessage(
-				session.Context(),
+				ctx,
 				message.Topic,
 				string(message.Key),
 				message.Value,
 				headers,
 			)
+			tracing.EndSpan(span, err)
 
 			if err != nil {
 				slog.Error("Error handling message",
@@ -2,12 +2,19 @@ package kafka
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/resource-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/resource-service/internal/tracing"
 )
 
 // Producer implements the MessageProducer interface using Apache Kafka
@@ -23,6 +30,7 @@ type Config struct {
 	RequiredAcks    sarama.RequiredAcks
 	RetryMax        int
 	CompressionType sarama.CompressionCodec
+	SecurityConfig
 }
 
 // NewKafkaProducer creates a new Kafka producer with the given configuration
@@ -36,11 +44,16 @@ func NewKafkaProducer(config *Config) (*Producer, error) {
 	}
 
 	// Create Sarama configuration
-	saramaConfig := sarama.NewConfig()
+	saramaConfig, err := newSaramaConfig(config.SecurityConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kafka producer security: %w", err)
+	}
 	saramaConfig.Producer.Return.Successes = true
 	saramaConfig.Producer.Retry.Max = config.RetryMax
 	saramaConfig.Producer.RequiredAcks = config.RequiredAcks
 	saramaConfig.Producer.Compression = config.CompressionType
+	// Messages with the same key always go to the same partition
+	saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
 
 	// Enable idempotent producer for exactly-once semantics
 	saramaConfig.Producer.Idempotent = true
@@ -73,7 +86,7 @@ func (p *Producer) PublishEvent(ctx context.Context, event eventmodel.Event) err
 	// Create Kafka message
 	message := &sarama.ProducerMessage{
 		Topic: event.Topic,
-		Key:   sarama.StringEncoder(event.ID.String()),
+		Key:   sarama.StringEncoder(partitionKey(event)),
 		Value: sarama.ByteEncoder(event.Payload),
 		Headers: []sarama.RecordHeader{
 			{Key: []byte("event_name"), Value: []byte(event.Name)},
@@ -82,8 +95,22 @@ func (p *Producer) PublishEvent(ctx context.Context, event eventmodel.Event) err
 		},
 	}
 
+	// Events retried by the outbox processor are published long after the
+	// operation that created them, whose trace they carry in their headers
+	if len(event.Headers) > 0 {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(event.Headers))
+	}
+	ctx, span := tracing.StartSpan(ctx, "kafka.publish "+event.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", event.Topic),
+			attribute.String("event.name", event.Name),
+		))
+	otel.GetTextMapPropagator().Inject(ctx, recordHeaderCarrier{headers: &message.Headers})
+
 	// Send message
 	partition, offset, err := p.producer.SendMessage(message)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("failed to publish event to kafka: %w", err)
 	}
@@ -92,16 +119,36 @@ func (p *Producer) PublishEvent(ctx context.Context, event eventmodel.Event) err
 		"topic", event.Topic,
 		"partition", partition,
 		"offset", offset,
+		"key", message.Key,
 		"event_id", event.ID,
 		"event_name", event.Name)
 
 	return nil
 }
 
+// partitionKey returns the message key of event. Events about a resource are
+// keyed by its ID, so that they share a partition and are consumed in the order
+// they were published, e.g. never created after deleted. Other events fall
+// back to their own ID.
+func partitionKey(event eventmodel.Event) string {
+	var payload struct {
+		ResourceID uuid.UUID `json:"resource_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err == nil && payload.ResourceID != uuid.Nil {
+		return payload.ResourceID.String()
+	}
+	return event.ID.String()
+}
+
 // Health checks if the producer can communicate with Kafka brokers
 func (p *Producer) Health(ctx context.Context) error {
 	// Create a simple health check by trying to get metadata
-	client, err := sarama.NewClient(p.config.Brokers, sarama.NewConfig())
+	saramaConfig, err := newSaramaConfig(p.config.SecurityConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure kafka client for health check: %w", err)
+	}
+
+	client, err := sarama.NewClient(p.config.Brokers, saramaConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create kafka client for health check: %w", err)
 	}
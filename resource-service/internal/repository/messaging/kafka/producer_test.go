@@ -0,0 +1,104 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/eventmodel"
+)
+
+func TestPublishEvent_MessageKey(t *testing.T) {
+	resourceID := uuid.New()
+	eventID := uuid.New()
+
+	tests := []struct {
+		name        string
+		data        any
+		expectedKey string
+	}{
+		{
+			name:        "resource event keyed by resource",
+			data:        map[string]any{"resource_id": resourceID, "name": "notes.pdf"},
+			expectedKey: resourceID.String(),
+		},
+		{
+			name:        "other event keyed by event",
+			data:        map[string]any{"query": "notes"},
+			expectedKey: eventID.String(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := eventmodel.NewEvent("resource.deleted", "resources", tt.data)
+			require.NoError(t, err)
+			event.ID = eventID
+
+			var key []byte
+			producer := mocks.NewSyncProducer(t, nil)
+			producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+				key, err = message.Key.Encode()
+				return err
+			})
+
+			p := &Producer{producer: producer, config: &Config{}}
+			require.NoError(t, p.PublishEvent(context.Background(), event))
+			require.NoError(t, producer.Close())
+
+			assert.Equal(t, tt.expectedKey, string(key))
+		})
+	}
+}
+
+func TestPartitionKey_SameResourceSamePartition(t *testing.T) {
+	resourceID := uuid.New()
+	partitioner := sarama.NewHashPartitioner("resources")
+
+	var partitions []int32
+	for _, name := range []string{"resource.created", "resource.updated", "resource.deleted"} {
+		event, err := eventmodel.NewEvent(name, "resources", map[string]any{"resource_id": resourceID})
+		require.NoError(t, err)
+		event.ID = uuid.New()
+
+		partition, err := partitioner.Partition(&sarama.ProducerMessage{Key: sarama.StringEncoder(partitionKey(event))}, 12)
+		require.NoError(t, err)
+		partitions = append(partitions, partition)
+	}
+
+	assert.Equal(t, []int32{partitions[0], partitions[0], partitions[0]}, partitions)
+}
+
+func TestPublishEvent_RestoresTraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	event, err := eventmodel.NewEvent("resource.deleted", "resources", map[string]any{"name": "notes.pdf"})
+	require.NoError(t, err)
+	event.Headers = map[string]string{"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01"}
+
+	var traceparent string
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+		traceparent = recordHeaderCarrier{headers: &message.Headers}.Get("traceparent")
+		return nil
+	})
+
+	// Published by the outbox processor, outside of the operation creating it
+	p := &Producer{producer: producer, config: &Config{}}
+	require.NoError(t, p.PublishEvent(context.Background(), event))
+	require.NoError(t, producer.Close())
+
+	restored := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	assert.Equal(t, traceID, trace.SpanContextFromContext(restored).TraceID().String())
+}
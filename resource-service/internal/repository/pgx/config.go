@@ -20,74 +20,21 @@ type Config struct {
 	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"`
 }
 
-// NewConfig creates a new configuration with values from environment variables
+// NewConfig loads the database configuration, falling back to local
+// development defaults for settings that are not set
 func NewConfig() (*Config, error) {
-	config := &Config{
-		Host:     configurator.GetString("database.host"),
-		Port:     configurator.GetString("database.port"),
-		Database: configurator.GetString("database.dbname"),
-		Username: configurator.GetString("database.user"),
-		Password: configurator.GetString("database.password"),
-		SSLMode:  configurator.GetString("database.sslmode"),
-	}
-
-	// Set defaults for empty values
-	if config.Host == "" {
-		config.Host = "localhost"
-	}
-	if config.Port == "" {
-		config.Port = "5432"
-	}
-	if config.Database == "" {
-		config.Database = "postgres"
-	}
-	if config.Username == "" {
-		config.Username = "postgres"
-	}
-	if config.Password == "" {
-		config.Password = "postgres"
-	}
-	if config.SSLMode == "" {
-		config.SSLMode = "disable"
-	}
-
-	// Parse integer values with defaults
-	maxOpenConns := configurator.GetInt("database.max_open_conns")
-	if maxOpenConns > 0 {
-		config.MaxOpenConns = maxOpenConns
-	} else {
-		config.MaxOpenConns = 25
-	}
-
-	maxIdleConns := configurator.GetInt("database.max_idle_conns")
-	if maxIdleConns > 0 {
-		config.MaxIdleConns = maxIdleConns
-	} else {
-		config.MaxIdleConns = 5
-	}
-
-	// Parse duration values with defaults
-	if connMaxLifetimeStr := configurator.GetString("database.conn_max_lifetime"); connMaxLifetimeStr != "" {
-		if duration, err := time.ParseDuration(connMaxLifetimeStr); err == nil {
-			config.ConnMaxLifetime = duration
-		} else {
-			config.ConnMaxLifetime = 30 * time.Minute
-		}
-	} else {
-		config.ConnMaxLifetime = 30 * time.Minute
-	}
-
-	if connMaxIdleTimeStr := configurator.GetString("database.conn_max_idle_time"); connMaxIdleTimeStr != "" {
-		if duration, err := time.ParseDuration(connMaxIdleTimeStr); err == nil {
-			config.ConnMaxIdleTime = duration
-		} else {
-			config.ConnMaxIdleTime = 5 * time.Minute
-		}
-	} else {
-		config.ConnMaxIdleTime = 5 * time.Minute
-	}
-
-	return config, nil
+	return configurator.LoadKeys("database", Config{
+		Host:            "localhost",
+		Port:            "5432",
+		Database:        "postgres",
+		Username:        "postgres",
+		Password:        "postgres",
+		SSLMode:         "disable",
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	})
 }
 
 // GetDSN returns the data source name for the database connection
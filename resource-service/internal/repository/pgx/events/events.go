@@ -2,8 +2,11 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/samber/lo"
 
@@ -16,6 +19,9 @@ type baseRepository interface {
 	Close()
 	DB() *pgxpool.Pool
 	Queries() *sqlc.Queries
+	QueriesContext(ctx context.Context) *sqlc.Queries
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+	AfterCommit(ctx context.Context, fn func(ctx context.Context))
 	Health(ctx context.Context) error
 }
 
@@ -29,9 +35,9 @@ func NewEventRepository(ctx context.Context, repository baseRepository) *Reposit
 	}
 }
 
-// GetNotSentEvents retrieves all events that have not been sent
+// GetNotSentEvents retrieves the events that have not been sent, oldest first
 func (r *Repository) GetNotSentEvents(ctx context.Context, limit int, offset int) ([]eventmodel.Event, error) {
-	sqlcEvents, err := r.Queries().GetNotSentEvents(ctx, sqlc.GetNotSentEventsParams{
+	sqlcEvents, err := r.QueriesContext(ctx).GetNotSentEvents(ctx, sqlc.GetNotSentEventsParams{
 		Limit:  int32(limit),
 		Offset: int32(offset),
 	})
@@ -39,34 +45,64 @@ func (r *Repository) GetNotSentEvents(ctx context.Context, limit int, offset int
 		return nil, err
 	}
 
-	return lo.Map(sqlcEvents, func(sqlcEvent sqlc.Events, _ int) eventmodel.Event {
-		return sqlcEventToModel(sqlcEvent)
-	}), nil
+	events := make([]eventmodel.Event, 0, len(sqlcEvents))
+	for _, sqlcEvent := range sqlcEvents {
+		event, err := sqlcEventToModel(sqlcEvent)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// CountNotSentEvents returns the number of events that have not been sent
+func (r *Repository) CountNotSentEvents(ctx context.Context) (int, error) {
+	count, err := r.QueriesContext(ctx).CountNotSentEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// HasOlderNotSentEvents reports whether an event older than the given one has
+// not been sent yet
+func (r *Repository) HasOlderNotSentEvents(ctx context.Context, event eventmodel.Event) (bool, error) {
+	return r.QueriesContext(ctx).HasOlderNotSentEvents(ctx, sqlc.HasOlderNotSentEventsParams{
+		EventTime: pgtype.Timestamp{Time: event.EventTime, Valid: true},
+		ID:        pgx.UuidToPgType(event.ID),
+	})
 }
 
 // CreateEvent saves a new event to the database
 func (r *Repository) CreateEvent(ctx context.Context, event eventmodel.Event) (eventmodel.Event, error) {
+	headers, err := json.Marshal(lo.CoalesceMapOrEmpty(event.Headers))
+	if err != nil {
+		return eventmodel.Event{}, fmt.Errorf("encoding event headers: %w", err)
+	}
+
 	params := sqlc.CreateEventParams{
 		Name:    event.Name,
 		Topic:   event.Topic,
 		Payload: event.Payload,
+		Headers: headers,
 	}
 
-	sqlcEvent, err := r.Queries().CreateEvent(ctx, params)
+	sqlcEvent, err := r.QueriesContext(ctx).CreateEvent(ctx, params)
 	if err != nil {
 		return eventmodel.Event{}, err
 	}
 
-	return sqlcEventToModel(sqlcEvent), nil
+	return sqlcEventToModel(sqlcEvent)
 }
 
 // MarkEventAsSent marks an event as sent in the database
 func (r *Repository) MarkEventAsSent(ctx context.Context, eventID uuid.UUID) error {
-	return r.Queries().MarkEventAsSent(ctx, pgx.UuidToPgType(eventID))
+	return r.QueriesContext(ctx).MarkEventAsSent(ctx, pgx.UuidToPgType(eventID))
 }
 
-func sqlcEventToModel(sqlcEvent sqlc.Events) eventmodel.Event {
-	return eventmodel.Event{
+func sqlcEventToModel(sqlcEvent sqlc.Events) (eventmodel.Event, error) {
+	event := eventmodel.Event{
 		ID:        pgx.PgTypeToUUID(sqlcEvent.ID),
 		Name:      sqlcEvent.Name,
 		Topic:     sqlcEvent.Topic,
@@ -74,4 +110,10 @@ func sqlcEventToModel(sqlcEvent sqlc.Events) eventmodel.Event {
 		Sent:      sqlcEvent.Sent,
 		EventTime: sqlcEvent.EventTime.Time,
 	}
+	if len(sqlcEvent.Headers) > 0 {
+		if err := json.Unmarshal(sqlcEvent.Headers, &event.Headers); err != nil {
+			return eventmodel.Event{}, fmt.Errorf("decoding headers of event %s: %w", event.ID, err)
+		}
+	}
+	return event, nil
 }
@@ -2,8 +2,11 @@ package pgx
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
+	pgxv5 "github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -41,6 +44,69 @@ func (r *Repository) Queries() *sqlc.Queries {
 	return r.queries
 }
 
+// txKey is the context key of the transaction started by InTx
+type txKey struct{}
+
+// txState is the transaction started by InTx with the functions to run once it
+// is committed
+type txState struct {
+	tx          pgxv5.Tx
+	afterCommit []func(ctx context.Context)
+}
+
+// QueriesContext returns the sqlc queries bound to the transaction carried by
+// ctx, or the pool queries outside of a transaction
+func (r *Repository) QueriesContext(ctx context.Context) *sqlc.Queries {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return r.queries.WithTx(state.tx)
+	}
+	return r.queries
+}
+
+// InTx runs fn in a database transaction, committed when fn succeeds and
+// rolled back when it fails. Repositories called with the context passed to
+// fn take part in the transaction. Nested calls join the outer transaction.
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	const op = "Repository.InTx"
+
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: begin: %w", op, err)
+	}
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil && !errors.Is(rollbackErr, pgxv5.ErrTxClosed) {
+			return errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rollbackErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	for _, fn := range state.afterCommit {
+		fn(ctx)
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the transaction carried by ctx is committed, and
+// never when it is rolled back. Outside of a transaction fn runs at once.
+func (r *Repository) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	state.afterCommit = append(state.afterCommit, fn)
+}
+
 // Health checks if the database connection is healthy
 func (r *Repository) Health(ctx context.Context) error {
 	return r.db.Ping(ctx)
@@ -73,3 +139,35 @@ func PgTypeToString(pgtext pgtype.Text) string {
 	}
 	return pgtext.String
 }
+
+func IntToPgType(i int) pgtype.Int4 {
+	return pgtype.Int4{
+		Int32: int32(i),
+		Valid: i != 0,
+	}
+}
+
+func IntPtrToPgType(i *int) pgtype.Int4 {
+	if i == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{
+		Int32: int32(*i),
+		Valid: true,
+	}
+}
+
+func PgTypeToInt(pgint pgtype.Int4) int {
+	if !pgint.Valid {
+		return 0
+	}
+	return int(pgint.Int32)
+}
+
+func PgTypeToIntPtr(pgint pgtype.Int4) *int {
+	if !pgint.Valid {
+		return nil
+	}
+	i := int(pgint.Int32)
+	return &i
+}
@@ -2,9 +2,13 @@ package resources
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/samber/lo"
 
@@ -17,6 +21,8 @@ type baseRepository interface {
 	Close()
 	DB() *pgxpool.Pool
 	Queries() *sqlc.Queries
+	QueriesContext(ctx context.Context) *sqlc.Queries
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
 	Health(ctx context.Context) error
 }
 
@@ -37,7 +43,7 @@ func (r *Repository) ResourceOwnedByUser(ctx context.Context, resourceID uuid.UU
 		OwnerID: pgx.UuidToPgType(userID),
 	}
 
-	owned, err := r.Queries().CheckResourceOwnership(ctx, params)
+	owned, err := r.QueriesContext(ctx).CheckResourceOwnership(ctx, params)
 	if err != nil {
 		return false, fmt.Errorf("failed to check resource ownership: %w", err)
 	}
@@ -47,7 +53,7 @@ func (r *Repository) ResourceOwnedByUser(ctx context.Context, resourceID uuid.UU
 
 // GetResources retrieves all resources
 func (r *Repository) GetResources(ctx context.Context, limit int, offset int) ([]resourcemodel.Resource, error) {
-	sqlcResources, err := r.Queries().GetResources(ctx, sqlc.GetResourcesParams{
+	sqlcResources, err := r.QueriesContext(ctx).GetResources(ctx, sqlc.GetResourcesParams{
 		Limit:  int32(limit),
 		Offset: int32(offset),
 	})
@@ -61,11 +67,13 @@ func (r *Repository) GetResources(ctx context.Context, limit int, offset int) ([
 }
 
 // GetResourcesByOwnerID retrieves all resources by owner ID
-func (r *Repository) GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, limit int, offset int) ([]resourcemodel.Resource, error) {
-	sqlcResources, err := r.Queries().GetResourcesByOwnerID(ctx, sqlc.GetResourcesByOwnerIDParams{
-		OwnerID: pgx.UuidToPgType(ownerID),
-		Limit:   int32(limit),
-		Offset:  int32(offset),
+func (r *Repository) GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error) {
+	sqlcResources, err := r.QueriesContext(ctx).GetResourcesByOwnerID(ctx, sqlc.GetResourcesByOwnerIDParams{
+		OwnerID:   pgx.UuidToPgType(ownerID),
+		SortBy:    string(sort.Field),
+		SortOrder: string(sort.Order),
+		Limit:     int32(limit),
+		Offset:    int32(offset),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get resources by owner id: %w", err)
@@ -76,20 +84,112 @@ func (r *Repository) GetResourcesByOwnerID(ctx context.Context, ownerID uuid.UUI
 	}), nil
 }
 
+// GetResourcesByOwnerIDAndStatus retrieves the resources of an owner having the status
+func (r *Repository) GetResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus, sort resourcemodel.ResourceSort, limit int, offset int) ([]resourcemodel.Resource, error) {
+	sqlcResources, err := r.QueriesContext(ctx).GetResourcesByOwnerIDAndStatus(ctx, sqlc.GetResourcesByOwnerIDAndStatusParams{
+		OwnerID:   pgx.UuidToPgType(ownerID),
+		Status:    sqlc.ResourceStatus(status),
+		SortBy:    string(sort.Field),
+		SortOrder: string(sort.Order),
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resources by owner id and status: %w", err)
+	}
+
+	return lo.Map(sqlcResources, func(sqlcResource sqlc.Resources, _ int) resourcemodel.Resource {
+		return sqlcResourceToModel(sqlcResource)
+	}), nil
+}
+
+// CountResources counts the resources of all owners
+func (r *Repository) CountResources(ctx context.Context) (int, error) {
+	count, err := r.QueriesContext(ctx).CountResources(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count resources: %w", err)
+	}
+	return int(count), nil
+}
+
+// CountResourcesByOwnerID counts the resources of an owner
+func (r *Repository) CountResourcesByOwnerID(ctx context.Context, ownerID uuid.UUID) (int, error) {
+	count, err := r.QueriesContext(ctx).CountResourcesByOwnerID(ctx, pgx.UuidToPgType(ownerID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count resources by owner id: %w", err)
+	}
+	return int(count), nil
+}
+
+// CountResourcesByOwnerIDAndStatus counts the resources of an owner having the status
+func (r *Repository) CountResourcesByOwnerIDAndStatus(ctx context.Context, ownerID uuid.UUID, status resourcemodel.ResourceStatus) (int, error) {
+	count, err := r.QueriesContext(ctx).CountResourcesByOwnerIDAndStatus(ctx, sqlc.CountResourcesByOwnerIDAndStatusParams{
+		OwnerID: pgx.UuidToPgType(ownerID),
+		Status:  sqlc.ResourceStatus(status),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count resources by owner id and status: %w", err)
+	}
+	return int(count), nil
+}
+
+// CountResourcesByOwnerIDPerStatus counts the resources of an owner in each
+// status, statuses without resources are left out
+func (r *Repository) CountResourcesByOwnerIDPerStatus(ctx context.Context, ownerID uuid.UUID) (map[resourcemodel.ResourceStatus]int, error) {
+	rows, err := r.QueriesContext(ctx).CountResourcesByOwnerIDPerStatus(ctx, pgx.UuidToPgType(ownerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count resources by owner id per status: %w", err)
+	}
+
+	counts := make(map[resourcemodel.ResourceStatus]int, len(rows))
+	for _, row := range rows {
+		counts[resourcemodel.ResourceStatus(row.Status)] = int(row.Count)
+	}
+	return counts, nil
+}
+
 // GetResourceByID retrieves a resource by ID
 func (r *Repository) GetUsersResourceByID(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.Resource, error) {
-	sqlcResource, err := r.Queries().GetUsersResourceByID(ctx, sqlc.GetUsersResourceByIDParams{
+	sqlcResource, err := r.QueriesContext(ctx).GetUsersResourceByID(ctx, sqlc.GetUsersResourceByIDParams{
 		ID:      pgx.UuidToPgType(resourceID),
 		OwnerID: pgx.UuidToPgType(ownerID),
 	})
 	if err != nil {
-		return resourcemodel.Resource{}, fmt.Errorf("failed to get resource by ID: %w", err)
+		return resourcemodel.Resource{}, fmt.Errorf("failed to get resource by ID: %w", notFound(err))
 	}
 
 	resource := sqlcResourceToModel(sqlcResource)
 	return resource, nil
 }
 
+// GetUsersResourceStatus retrieves the status of a resource of the owner
+// without reading the rest of the resource
+func (r *Repository) GetUsersResourceStatus(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID) (resourcemodel.ResourceStatus, error) {
+	row, err := r.QueriesContext(ctx).GetResourceStatus(ctx, sqlc.GetResourceStatusParams{
+		ID:      pgx.UuidToPgType(resourceID),
+		OwnerID: pgx.UuidToPgType(ownerID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get resource status: %w", notFound(err))
+	}
+
+	return resourcemodel.ResourceStatus(row.Status), nil
+}
+
+// GetUsersResourceByContentHash retrieves the oldest resource of the owner with
+// the content hash
+func (r *Repository) GetUsersResourceByContentHash(ctx context.Context, ownerID uuid.UUID, contentHash string) (resourcemodel.Resource, error) {
+	sqlcResource, err := r.QueriesContext(ctx).GetUsersResourceByContentHash(ctx, sqlc.GetUsersResourceByContentHashParams{
+		OwnerID:     pgx.UuidToPgType(ownerID),
+		ContentHash: pgx.StringToPgType(contentHash),
+	})
+	if err != nil {
+		return resourcemodel.Resource{}, fmt.Errorf("failed to get resource by content hash: %w", notFound(err))
+	}
+
+	return sqlcResourceToModel(sqlcResource), nil
+}
+
 // SaveResource creates a new resource
 func (r *Repository) SaveResource(ctx context.Context, resource resourcemodel.Resource) (resourcemodel.Resource, error) {
 	params := sqlc.CreateResourceParams{
@@ -99,9 +199,17 @@ func (r *Repository) SaveResource(ctx context.Context, resource resourcemodel.Re
 		ExtractedContent: pgx.StringToPgType(resource.ExtractedContent),
 		RawContent:       resource.RawContent,
 		OwnerID:          pgx.UuidToPgType(resource.OwnerID),
+		ContentHash:      pgx.StringToPgType(resource.ContentHash),
+		TenantID:         resource.TenantID,
+		ChunkSize:        pgx.IntToPgType(resource.ChunkSize),
+		ChunkOverlap:     pgx.IntPtrToPgType(resource.ChunkOverlap),
+	}
+	if resource.Pages != nil {
+		params.PageFirst = pgx.IntToPgType(resource.Pages.First)
+		params.PageLast = pgx.IntToPgType(resource.Pages.Last)
 	}
 
-	sqlcResource, err := r.Queries().CreateResource(ctx, params)
+	sqlcResource, err := r.QueriesContext(ctx).CreateResource(ctx, params)
 	if err != nil {
 		return resourcemodel.Resource{}, fmt.Errorf("failed to save resource: %w", err)
 	}
@@ -113,23 +221,24 @@ func (r *Repository) SaveResource(ctx context.Context, resource resourcemodel.Re
 // UpdateUsersResource updates an existing resource
 func (r *Repository) UpdateUsersResource(ctx context.Context, userID uuid.UUID, resource resourcemodel.Resource) (resourcemodel.Resource, error) {
 	if userID != resource.OwnerID {
-		return resourcemodel.Resource{}, fmt.Errorf("user id must be owned by another user")
+		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource: %w", resourcemodel.ErrNotOwner)
 	}
 
 	params := sqlc.UpdateUsersResourceParams{
 		ID:               pgx.UuidToPgType(resource.ID),
 		Name:             resource.Name,
-		Type:             sqlc.ResourceType(resource.Type),
+		Type:             modelTypeToSqlc(resource.Type),
 		Url:              pgx.StringToPgType(resource.URL),
 		ExtractedContent: pgx.StringToPgType(resource.ExtractedContent),
 		RawContent:       resource.RawContent,
 		Status:           sqlc.ResourceStatus(resource.Status),
 		OwnerID:          pgx.UuidToPgType(userID),
+		ContentHash:      pgx.StringToPgType(resource.ContentHash),
 	}
 
-	sqlcResource, err := r.Queries().UpdateUsersResource(ctx, params)
+	sqlcResource, err := r.QueriesContext(ctx).UpdateUsersResource(ctx, params)
 	if err != nil {
-		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource: %w", err)
+		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource: %w", notFound(err))
 	}
 
 	updatedResource := sqlcResourceToModel(sqlcResource)
@@ -138,21 +247,74 @@ func (r *Repository) UpdateUsersResource(ctx context.Context, userID uuid.UUID,
 
 // UpdateResourceStatus update status of resource
 func (r *Repository) UpdateResourceStatus(ctx context.Context, resourceID uuid.UUID, status resourcemodel.ResourceStatus) (resourcemodel.Resource, error) {
-	sqlcResource, err := r.Queries().UpdateResourceStatus(ctx, sqlc.UpdateResourceStatusParams{
+	sqlcResource, err := r.QueriesContext(ctx).UpdateResourceStatus(ctx, sqlc.UpdateResourceStatusParams{
 		ID:     pgx.UuidToPgType(resourceID),
 		Status: sqlc.ResourceStatus(status),
 	})
 	if err != nil {
-		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource status: %w", err)
+		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource status: %w", notFound(err))
 	}
 
 	updatedResource := sqlcResourceToModel(sqlcResource)
 	return updatedResource, nil
 }
 
+// UpdateResourceVisibility changes the visibility of the owner's resource
+func (r *Repository) UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, ownerID uuid.UUID, visibility resourcemodel.ResourceVisibility) (resourcemodel.Resource, error) {
+	sqlcResource, err := r.QueriesContext(ctx).UpdateResourceVisibility(ctx, sqlc.UpdateResourceVisibilityParams{
+		ID:         pgx.UuidToPgType(resourceID),
+		OwnerID:    pgx.UuidToPgType(ownerID),
+		Visibility: sqlc.ResourceVisibility(visibility),
+	})
+	if err != nil {
+		return resourcemodel.Resource{}, fmt.Errorf("failed to update resource visibility: %w", notFound(err))
+	}
+
+	return sqlcResourceToModel(sqlcResource), nil
+}
+
+// FailStaleResources marks up to limit resources still pending or processing
+// that were last updated before updatedBefore as failed, the oldest first, and
+// returns them. Resources locked by another transaction, e.g. by a completion
+// being stored, are left for a later call.
+func (r *Repository) FailStaleResources(ctx context.Context, updatedBefore time.Time, limit int) ([]resourcemodel.Resource, error) {
+	sqlcResources, err := r.QueriesContext(ctx).FailStaleResources(ctx, sqlc.FailStaleResourcesParams{
+		UpdatedAt: pgtype.Timestamptz{Time: updatedBefore, Valid: true},
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail stale resources: %w", err)
+	}
+
+	return lo.Map(sqlcResources, func(sqlcResource sqlc.Resources, _ int) resourcemodel.Resource {
+		return sqlcResourceToModel(sqlcResource)
+	}), nil
+}
+
+// UpdateResourceChunks replaces the chunk IDs and chunk hashes stored for the resource
+func (r *Repository) UpdateResourceChunks(ctx context.Context, resourceID uuid.UUID, chunkIDs []string, chunkHashes []string) error {
+	if chunkIDs == nil {
+		chunkIDs = []string{}
+	}
+	if chunkHashes == nil {
+		chunkHashes = []string{}
+	}
+
+	err := r.QueriesContext(ctx).UpdateResourceChunks(ctx, sqlc.UpdateResourceChunksParams{
+		ID:          pgx.UuidToPgType(resourceID),
+		ChunkIds:    chunkIDs,
+		ChunkHashes: chunkHashes,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update resource chunks: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteUsersResource deletes a resource by ID
 func (r *Repository) DeleteUsersResource(ctx context.Context, id uuid.UUID, ownerID uuid.UUID) error {
-	err := r.Queries().DeleteUsersResource(ctx, sqlc.DeleteUsersResourceParams{
+	err := r.QueriesContext(ctx).DeleteUsersResource(ctx, sqlc.DeleteUsersResourceParams{
 		ID:      pgx.UuidToPgType(id),
 		OwnerID: pgx.UuidToPgType(ownerID),
 	})
@@ -165,15 +327,23 @@ func (r *Repository) DeleteUsersResource(ctx context.Context, id uuid.UUID, owne
 
 // GetResourceByID retrieves a resource by ID without owner check
 func (r *Repository) GetResourceByID(ctx context.Context, resourceID uuid.UUID) (resourcemodel.Resource, error) {
-	sqlcResource, err := r.Queries().GetResourceByID(ctx, pgx.UuidToPgType(resourceID))
+	sqlcResource, err := r.QueriesContext(ctx).GetResourceByID(ctx, pgx.UuidToPgType(resourceID))
 	if err != nil {
-		return resourcemodel.Resource{}, fmt.Errorf("failed to get resource by ID: %w", err)
+		return resourcemodel.Resource{}, fmt.Errorf("failed to get resource by ID: %w", notFound(err))
 	}
 
 	resource := sqlcResourceToModel(sqlcResource)
 	return resource, nil
 }
 
+// notFound reports a missing row as resourcemodel.ErrResourceNotFound
+func notFound(err error) error {
+	if errors.Is(err, pgxv5.ErrNoRows) {
+		return resourcemodel.ErrResourceNotFound
+	}
+	return err
+}
+
 func modelTypeToSqlc(resourceType resourcemodel.ResourceType) sqlc.ResourceType {
 	switch resourceType {
 	case resourcemodel.ResourceTypePDF:
@@ -182,6 +352,10 @@ func modelTypeToSqlc(resourceType resourcemodel.ResourceType) sqlc.ResourceType
 		return sqlc.ResourceTypeTxt
 	case resourcemodel.ResourceTypeURL:
 		return sqlc.ResourceTypeUrl
+	case resourcemodel.ResourceTypeCSV:
+		return sqlc.ResourceTypeCsv
+	case resourcemodel.ResourceTypeEPUB:
+		return sqlc.ResourceTypeEpub
 	default:
 		return sqlc.ResourceTypeTxt
 	}
@@ -195,6 +369,10 @@ func sqlcTypeToModel(resourceType sqlc.ResourceType) resourcemodel.ResourceType
 		return resourcemodel.ResourceTypeText
 	case sqlc.ResourceTypeUrl:
 		return resourcemodel.ResourceTypeURL
+	case sqlc.ResourceTypeCsv:
+		return resourcemodel.ResourceTypeCSV
+	case sqlc.ResourceTypeEpub:
+		return resourcemodel.ResourceTypeEPUB
 	default:
 		return resourcemodel.ResourceTypeText
 	}
@@ -210,6 +388,8 @@ func modelStatusToSqlc(status resourcemodel.ResourceStatus) sqlc.ResourceStatus
 		return sqlc.ResourceStatusCompleted
 	case resourcemodel.ResourceStatusFailed:
 		return sqlc.ResourceStatusFailed
+	case resourcemodel.ResourceStatusCancelled:
+		return sqlc.ResourceStatusCancelled
 	default:
 		return sqlc.ResourceStatusPending
 	}
@@ -225,6 +405,8 @@ func sqlcStatusToModel(status sqlc.ResourceStatus) resourcemodel.ResourceStatus
 		return resourcemodel.ResourceStatusCompleted
 	case sqlc.ResourceStatusFailed:
 		return resourcemodel.ResourceStatusFailed
+	case sqlc.ResourceStatusCancelled:
+		return resourcemodel.ResourceStatusCancelled
 	default:
 		return resourcemodel.ResourceStatusPending
 	}
@@ -242,5 +424,24 @@ func sqlcResourceToModel(sqlcResource sqlc.Resources) resourcemodel.Resource {
 		OwnerID:          pgx.PgTypeToUUID(sqlcResource.OwnerID),
 		CreatedAt:        sqlcResource.CreatedAt.Time,
 		UpdatedAt:        sqlcResource.UpdatedAt.Time,
+		ChunkIDs:         sqlcResource.ChunkIds,
+		ChunkHashes:      sqlcResource.ChunkHashes,
+		ContentHash:      pgx.PgTypeToString(sqlcResource.ContentHash),
+		Visibility:       resourcemodel.ResourceVisibility(sqlcResource.Visibility),
+		TenantID:         sqlcResource.TenantID,
+		ChunkSize:        pgx.PgTypeToInt(sqlcResource.ChunkSize),
+		ChunkOverlap:     pgx.PgTypeToIntPtr(sqlcResource.ChunkOverlap),
+		Pages:            sqlcPageRangeToModel(sqlcResource.PageFirst, sqlcResource.PageLast),
+	}
+}
+
+// sqlcPageRangeToModel returns the stored page range, nil for every page
+func sqlcPageRangeToModel(first, last pgtype.Int4) *resourcemodel.PageRange {
+	if !first.Valid {
+		return nil
+	}
+	return &resourcemodel.PageRange{
+		First: pgx.PgTypeToInt(first),
+		Last:  pgx.PgTypeToInt(last),
 	}
 }
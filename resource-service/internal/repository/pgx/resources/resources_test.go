@@ -0,0 +1,124 @@
+package resources
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	pgxv5 "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/resource-service/database/sqlc"
+	"github.com/nzb3/diploma/resource-service/internal/domain/models/resourcemodel"
+	"github.com/nzb3/diploma/resource-service/internal/repository/pgx"
+)
+
+// statusCountRows serves the rows of a count grouped by status
+type statusCountRows struct {
+	pgxv5.Rows
+	counts []sqlc.CountResourcesByOwnerIDPerStatusRow
+	next   int
+}
+
+func (r *statusCountRows) Next() bool {
+	r.next++
+	return r.next <= len(r.counts)
+}
+
+func (r *statusCountRows) Scan(dest ...any) error {
+	row := r.counts[r.next-1]
+	*dest[0].(*sqlc.ResourceStatus) = row.Status
+	*dest[1].(*int64) = row.Count
+	return nil
+}
+
+func (r *statusCountRows) Err() error { return nil }
+
+func (r *statusCountRows) Close() {}
+
+// queryRecorder records the query sent to the database and answers it with rows
+type queryRecorder struct {
+	sqlc.DBTX
+	rows  pgxv5.Rows
+	query string
+	args  []any
+}
+
+func (db *queryRecorder) Query(_ context.Context, query string, args ...any) (pgxv5.Rows, error) {
+	db.query, db.args = query, args
+	return db.rows, nil
+}
+
+// queriesRepository runs the queries of the repository on db
+type queriesRepository struct {
+	baseRepository
+	db sqlc.DBTX
+}
+
+func (r queriesRepository) QueriesContext(context.Context) *sqlc.Queries {
+	return sqlc.New(r.db)
+}
+
+func TestCountResourcesByOwnerIDPerStatus(t *testing.T) {
+	db := &queryRecorder{rows: &statusCountRows{counts: []sqlc.CountResourcesByOwnerIDPerStatusRow{
+		{Status: sqlc.ResourceStatusCompleted, Count: 7},
+		{Status: sqlc.ResourceStatusFailed, Count: 2},
+	}}}
+	repo := NewResourceRepository(context.Background(), queriesRepository{db: db})
+	ownerID := uuid.New()
+
+	counts, err := repo.CountResourcesByOwnerIDPerStatus(context.Background(), ownerID)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[resourcemodel.ResourceStatus]int{
+		resourcemodel.ResourceStatusCompleted: 7,
+		resourcemodel.ResourceStatusFailed:    2,
+	}, counts)
+	// A single grouped query over the resources of the owner
+	assert.Contains(t, strings.Join(strings.Fields(db.query), " "), "WHERE owner_id = $1 GROUP BY status")
+	assert.Equal(t, []any{pgx.UuidToPgType(ownerID)}, db.args)
+}
+
+func TestSqlcResourceToModel_IndexationSettings(t *testing.T) {
+	overlap := 0
+
+	tests := []struct {
+		name     string
+		stored   sqlc.Resources
+		expected resourcemodel.Resource
+	}{
+		{
+			name:     "defaults",
+			stored:   sqlc.Resources{},
+			expected: resourcemodel.Resource{},
+		},
+		{
+			name: "chunking and page range",
+			stored: sqlc.Resources{
+				ChunkSize:    pgtype.Int4{Int32: 256, Valid: true},
+				ChunkOverlap: pgtype.Int4{Int32: 0, Valid: true},
+				PageFirst:    pgtype.Int4{Int32: 3, Valid: true},
+				PageLast:     pgtype.Int4{Int32: 10, Valid: true},
+			},
+			expected: resourcemodel.Resource{ChunkSize: 256, ChunkOverlap: &overlap, Pages: &resourcemodel.PageRange{First: 3, Last: 10}},
+		},
+		{
+			name:     "pages to the end",
+			stored:   sqlc.Resources{PageFirst: pgtype.Int4{Int32: 3, Valid: true}},
+			expected: resourcemodel.Resource{Pages: &resourcemodel.PageRange{First: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := sqlcResourceToModel(tt.stored)
+
+			assert.Equal(t, tt.expected.ChunkSize, resource.ChunkSize)
+			assert.Equal(t, tt.expected.ChunkOverlap, resource.ChunkOverlap)
+			assert.Equal(t, tt.expected.Pages, resource.Pages)
+		})
+	}
+}
@@ -18,22 +18,18 @@ type Config struct {
 	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
 }
 
-// NewConfig loads server configuration from config file and environment variables
-func NewConfig() (*Config, error) {
-	// Set defaults
-	setDefaults()
-
-	// Parse configuration from "server" section
-	config, err := configurator.ParseConfig[Config]("server")
-	if err != nil {
-		return nil, err
+// DefaultConfig returns the server settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		Host:              "0.0.0.0",
+		Port:              "8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		ShutdownTimeout:   10 * time.Second,
 	}
-
-	return config, nil
 }
 
-// setDefaults sets default values for server configuration
-func setDefaults() {
-	// Note: This is a placeholder. In practice, we'd use viper.SetDefault
-	// The actual defaults are set in the configurator package
+// NewConfig loads server configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("server", DefaultConfig())
 }
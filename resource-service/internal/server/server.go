@@ -1,16 +1,13 @@
 package server
 
 import (
-	"context"
-	"fmt"
-	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewServer(ctx context.Context, router *gin.Engine, cfg *Config) *http.Server {
-
+// NewServer creates the HTTP server. The app shuts it down, see App.Start.
+func NewServer(router *gin.Engine, cfg *Config) *http.Server {
 	s := &http.Server{
 		Addr:              ":" + cfg.Port,
 		Handler:           router,
@@ -21,19 +18,5 @@ func NewServer(ctx context.Context, router *gin.Engine, cfg *Config) *http.Serve
 		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(
-			ctx,
-			cfg.ShutdownTimeout,
-		)
-		defer cancel()
-
-		if err := s.Shutdown(shutdownCtx); err != nil {
-			slog.Error("server shutdown error", "error", err)
-			panic(fmt.Errorf("server shutdown error: %w", err))
-		}
-	}()
-
 	return s
 }
@@ -1,21 +1,134 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+
+	playground "github.com/go-playground/validator/v10"
 )
 
 type Validator[T any] interface {
 	Validate(validators ...ValidateFunc[T]) error
 }
 
+// Validate checks the `validate` struct tags of obj and, if obj implements
+// Validator, its own rules. All failures are reported in one error.
 func Validate[T any](obj *T) error {
+	var errs []error
+	if err := validateTags(obj); err != nil {
+		errs = append(errs, err)
+	}
+
 	if validator, ok := any(obj).(Validator[T]); ok {
 		if err := validator.Validate(); err != nil {
-			return fmt.Errorf("validation error: %w", err)
+			errs = append(errs, err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return &ValidationError{Errs: errs}
+	}
+
 	return nil
 }
 
+// ValidationError lists every failure of a validated object, the FieldErrors
+// of its tags and the error of its own rules
+type ValidationError struct {
+	Errs []error
+}
+
+func (e *ValidationError) Error() string {
+	return "validation error: " + errors.Join(e.Errs...).Error()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errs
+}
+
 type ValidateFunc[T any] func(r *T) error
+
+// FieldError describes a field failing its `validate` tag
+type FieldError struct {
+	// Field is the dotted path of the field, named by its mapstructure tag
+	Field   string
+	Message string
+}
+
+// FieldErrors lists every field failing its `validate` tag
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Field+" "+fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Fields returns the paths of the invalid fields
+func (e FieldErrors) Fields() []string {
+	fields := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		fields = append(fields, fieldErr.Field)
+	}
+	return fields
+}
+
+var tagValidator = newTagValidator()
+
+func newTagValidator() *playground.Validate {
+	v := playground.New(playground.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(fieldName)
+	return v
+}
+
+// fieldName names fields after their mapstructure key so that errors point at
+// the setting to fix rather than at the Go field
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func validateTags(obj any) error {
+	if reflect.Indirect(reflect.ValueOf(obj)).Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := tagValidator.Struct(obj)
+	var validationErrs playground.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+
+	fieldErrs := make(FieldErrors, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		// Drop the struct name leading the namespace
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		fieldErrs = append(fieldErrs, FieldError{
+			Field:   field,
+			Message: describe(fieldErr),
+		})
+	}
+	return fieldErrs
+}
+
+func describe(fieldErr playground.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + fieldErr.Param()
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	default:
+		return fmt.Sprintf("failed the %q rule", fieldErr.Tag())
+	}
+}
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE resources ADD COLUMN content_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_resources_owner_id_content_hash ON resources (owner_id, content_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_resources_owner_id_content_hash;
+ALTER TABLE resources DROP COLUMN content_hash;
+-- +goose StatementEnd
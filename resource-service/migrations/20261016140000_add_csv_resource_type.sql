@@ -0,0 +1,7 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE resource_type ADD VALUE IF NOT EXISTS 'csv';
+
+-- +goose Down
+-- Enum values cannot be dropped, the type keeps 'csv' and the resources are kept as text
+UPDATE resources SET type = 'txt' WHERE type = 'csv';
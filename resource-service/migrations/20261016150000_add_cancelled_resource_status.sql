@@ -0,0 +1,7 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE resource_status ADD VALUE IF NOT EXISTS 'cancelled';
+
+-- +goose Down
+-- Enum values cannot be dropped, the type keeps 'cancelled' and the resources are kept as failed
+UPDATE resources SET status = 'failed' WHERE status = 'cancelled';
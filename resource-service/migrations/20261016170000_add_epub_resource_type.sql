@@ -0,0 +1,7 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+ALTER TYPE resource_type ADD VALUE IF NOT EXISTS 'epub';
+
+-- +goose Down
+-- Enum values cannot be dropped, the type keeps 'epub' and the e-books are removed
+DELETE FROM resources WHERE type = 'epub';
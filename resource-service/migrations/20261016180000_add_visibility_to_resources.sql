@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE resource_visibility AS ENUM ('private', 'shared', 'public');
+ALTER TABLE resources ADD COLUMN visibility resource_visibility NOT NULL DEFAULT 'private';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE resources DROP COLUMN visibility;
+DROP TYPE resource_visibility;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE resources
+    ADD COLUMN chunk_size INTEGER,
+    ADD COLUMN chunk_overlap INTEGER,
+    ADD COLUMN page_first INTEGER,
+    ADD COLUMN page_last INTEGER;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE resources
+    DROP COLUMN chunk_size,
+    DROP COLUMN chunk_overlap,
+    DROP COLUMN page_first,
+    DROP COLUMN page_last;
+-- +goose StatementEnd
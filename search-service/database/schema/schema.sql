@@ -6,11 +6,22 @@ CREATE TABLE events (
     topic VARCHAR(255) NOT NULL,
     payload JSONB NOT NULL,
     sent BOOLEAN NOT NULL DEFAULT FALSE,
-    event_time TIMESTAMP NOT NULL DEFAULT NOW()
+    event_time TIMESTAMP NOT NULL DEFAULT NOW(),
+    headers JSONB NOT NULL DEFAULT '{}'
 );
 
 -- Index on sent column for efficient querying of unsent events
 CREATE INDEX IF NOT EXISTS idx_events_sent ON events (sent);
 
 -- Index on event_time for chronological processing
-CREATE INDEX IF NOT EXISTS idx_events_event_time ON events (event_time);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_events_event_time ON events (event_time);
+
+-- Index serving the unsent events in the order they were created
+CREATE INDEX IF NOT EXISTS idx_events_sent_event_time ON events (sent, event_time);
+
+-- Embeddings by SHA-256 of model and chunk text, created on startup when persistence is enabled
+CREATE TABLE IF NOT EXISTS embedding_cache (
+    hash CHAR(64) PRIMARY KEY,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
\ No newline at end of file
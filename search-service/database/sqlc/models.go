@@ -15,4 +15,5 @@ type Event struct {
 	Payload   []byte           `json:"payload"`
 	Sent      bool             `json:"sent"`
 	EventTime pgtype.Timestamp `json:"event_time"`
+	Headers   []byte           `json:"headers"`
 }
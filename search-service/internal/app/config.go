@@ -0,0 +1,40 @@
+package app
+
+import (
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+	"github.com/nzb3/diploma/search-service/internal/controllers/middleware"
+)
+
+// OllamaConfig selects the Ollama servers and the models they run
+type OllamaConfig struct {
+	EmbedderURL     string `yaml:"embedder_url" mapstructure:"embedder_url" validate:"required,url"`
+	GeneratorURL    string `yaml:"generator_url" mapstructure:"generator_url" validate:"required,url"`
+	EmbeddingModel  string `yaml:"embedding_model" mapstructure:"embedding_model" validate:"required"`
+	GenerationModel string `yaml:"generation_model" mapstructure:"generation_model" validate:"required"`
+}
+
+// NewOllamaConfig loads the Ollama settings from config file and environment variables
+func NewOllamaConfig() (*OllamaConfig, error) {
+	return configurator.LoadKeys("ollama", OllamaConfig{
+		EmbedderURL:     "http://ollama-embedder:11434/",
+		GeneratorURL:    "http://ollama-generator:11434/",
+		EmbeddingModel:  "bge-m3",
+		GenerationModel: "gemma3:4b-it-qat",
+	})
+}
+
+// LogConfig configures the log output
+type LogConfig struct {
+	// Format is "text" or "json"
+	Format string `yaml:"format" mapstructure:"format" validate:"oneof=text json"`
+	// MaxValueBytes is the length log values are truncated to
+	MaxValueBytes int `yaml:"max_value_bytes" mapstructure:"max_value_bytes" validate:"min=1"`
+}
+
+// NewLogConfig loads the log settings from config file and environment variables
+func NewLogConfig() (*LogConfig, error) {
+	return configurator.LoadKeys("logger", LogConfig{
+		Format:        "text",
+		MaxValueBytes: middleware.DefaultMaxLogValueBytes,
+	})
+}
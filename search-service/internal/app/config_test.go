@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+func TestValidateConfig_ReportsEveryMissingAndInvalidSetting(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	for _, env := range []string{"AUTH_HOST", "AUTH_PORT", "AUTH_REALM", "AUTH_SEARCH_SERVICE_CLIENT_ID", "AUTH_SEARCH_SERVICE_CLIENT_SECRET", "SEARCH_DB_HOST"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("OLLAMA_EMBEDDER_URL", "ollama-embedder")
+
+	configurator.SetupEnvironmentMapping()
+	viper.SetConfigFile("../../configs/config.yml")
+	require.NoError(t, viper.ReadInConfig())
+
+	sp := NewServiceProvider()
+	err := sp.ValidateConfig(context.Background())
+
+	require.Error(t, err)
+	for _, want := range []string{
+		"auth.host is required (set AUTH_HOST)",
+		"auth.port is required (set AUTH_PORT)",
+		"auth.realm is required (set AUTH_REALM)",
+		"auth.client_id is required (set AUTH_SEARCH_SERVICE_CLIENT_ID)",
+		"auth.client_secret is required (set AUTH_SEARCH_SERVICE_CLIENT_SECRET)",
+		"postgres.host is required (set SEARCH_DB_HOST)",
+		"logger.format must be one of: text json (set LOG_FORMAT)",
+		`ollama.embedder_url failed the "url" rule (set OLLAMA_EMBEDDER_URL)`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+
+	// Sections that loaded are kept, the failed ones are left unset
+	assert.NotNil(t, sp.serverConfig)
+	assert.Nil(t, sp.authConfig)
+}
+
+func TestValidateConfig_Defaults(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Setenv("AUTH_HOST", "keycloak")
+	t.Setenv("AUTH_PORT", "8080")
+	t.Setenv("AUTH_REALM", "deltanotes")
+	t.Setenv("AUTH_SEARCH_SERVICE_CLIENT_ID", "search-service")
+	t.Setenv("AUTH_SEARCH_SERVICE_CLIENT_SECRET", "secret")
+	t.Setenv("SEARCH_DB_HOST", "postgres")
+	t.Setenv("SEARCH_DB_USER", "search")
+	t.Setenv("SEARCH_DB_PASSWORD", "secret")
+	t.Setenv("SEARCH_DB_NAME", "search")
+	t.Setenv("SERVER_PORT", "9091")
+	t.Setenv("GIN_MODE", "")
+	t.Setenv("LOG_FORMAT", "")
+	t.Setenv("OLLAMA_EMBEDDER_URL", "")
+
+	configurator.SetupEnvironmentMapping()
+	viper.SetConfigFile("../../configs/config.yml")
+	require.NoError(t, viper.ReadInConfig())
+
+	sp := NewServiceProvider()
+	require.NoError(t, sp.ValidateConfig(context.Background()))
+
+	// The environment takes precedence over the config file
+	assert.Equal(t, "9091", sp.serverConfig.Port)
+	assert.Equal(t, "0.0.0.0", sp.serverConfig.Host)
+	assert.Equal(t, "text", sp.logConfig.Format)
+	assert.Equal(t, "http://ollama-embedder:11434/", sp.ollamaConfig.EmbedderURL)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,33 +12,58 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nzb3/closer"
 	"github.com/nzb3/slogmanager"
 	"github.com/tmc/langchaingo/llms/ollama"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"gorm.io/gorm"
 
 	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/controllers/admincontroller"
 	"github.com/nzb3/diploma/search-service/internal/controllers/middleware"
 	"github.com/nzb3/diploma/search-service/internal/controllers/searchcontroller"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/evaluationservice"
 	"github.com/nzb3/diploma/search-service/internal/domain/services/eventservice"
 	"github.com/nzb3/diploma/search-service/internal/domain/services/outboxprocessor"
 	"github.com/nzb3/diploma/search-service/internal/domain/services/resourceprocessor"
 	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+	"github.com/nzb3/diploma/search-service/internal/health"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
 	"github.com/nzb3/diploma/search-service/internal/repository/embedder"
 	"github.com/nzb3/diploma/search-service/internal/repository/events/pgx"
 	"github.com/nzb3/diploma/search-service/internal/repository/generator"
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
 	"github.com/nzb3/diploma/search-service/internal/repository/messaging"
 	"github.com/nzb3/diploma/search-service/internal/repository/messaging/kafka"
 	"github.com/nzb3/diploma/search-service/internal/repository/postgres"
 	"github.com/nzb3/diploma/search-service/internal/repository/vectorstorage"
 	"github.com/nzb3/diploma/search-service/internal/server"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
+)
+
+const (
+	// embeddingProbeText is embedded at startup to check the model's dimensions
+	embeddingProbeText = "dimension check"
+
+	// readinessTimeout bounds all readiness checks so load balancers get an answer quickly
+	readinessTimeout = 2 * time.Second
+
+	// evaluationConcurrency bounds the evaluation cases answered at once
+	evaluationConcurrency = 4
 )
 
 // ServiceProvider implementation of DI-container haves method to initialize components of application
 type ServiceProvider struct {
 	slogManager         *slogmanager.Manager
-	embeddingLLM        *ollama.LLM
+	logConfig           *LogConfig
+	ollamaConfig        *OllamaConfig
+	embeddingLLM        *embedder.OllamaModel
 	generationLLM       *ollama.LLM
 	embedder            *embedder.Embedder
+	embedderConfig      *embedder.CacheConfig
+	embeddingConfig     *embedder.Config
+	ollamaRetryConfig   *llmretry.Config
 	generator           *generator.Generator
 	server              *http.Server
 	ginEngine           *gin.Engine
@@ -47,10 +73,17 @@ type ServiceProvider struct {
 	serverConfig        *server.Config
 	kafkaConfig         *kafka.Config
 	authConfig          *middleware.AuthConfig
+	compressionConfig   *middleware.CompressionConfig
+	streamConfig        *searchcontroller.Config
+	rateLimitConfig     *middleware.RateLimitConfig
+	rateLimitStore      *middleware.MemoryRateLimitStore
 	gormDB              *gorm.DB
 	searchController    *searchcontroller.Controller
 	searchService       *searchservice.Service
+	searchConfig        *searchservice.Config
 	authMiddleware      *middleware.AuthMiddleware
+	adminController     *admincontroller.Controller
+	evaluationService   *evaluationservice.Service
 	// Event system components
 	pgxPool           *pgxpool.Pool
 	eventRepository   *pgx.Repository
@@ -59,6 +92,11 @@ type ServiceProvider struct {
 	eventService      *eventservice.Service
 	outboxProcessor   *outboxprocessor.Processor
 	resourceProcessor *resourceprocessor.Processor
+	processorConfig   *resourceprocessor.Config
+	// Tracing components
+	tracingConfig  *tracing.Config
+	tracerProvider *sdktrace.TracerProvider
+	health         *health.Health
 }
 
 // NewServiceProvider creates and returns a new instance of ServiceProvider
@@ -66,28 +104,101 @@ func NewServiceProvider() *ServiceProvider {
 	return &ServiceProvider{}
 }
 
+// ValidateConfig loads every configuration section up front, so that all
+// missing or invalid settings are reported together at startup instead of
+// panicking on the first one when its component is built
+func (sp *ServiceProvider) ValidateConfig(_ context.Context) error {
+	return errors.Join(
+		loadConfig(&sp.logConfig, NewLogConfig),
+		loadConfig(&sp.ollamaConfig, NewOllamaConfig),
+		loadConfig(&sp.serverConfig, server.NewConfig),
+		loadConfig(&sp.authConfig, middleware.NewAuthConfig),
+		loadConfig(&sp.compressionConfig, middleware.NewCompressionConfig),
+		loadConfig(&sp.streamConfig, searchcontroller.NewConfig),
+		loadConfig(&sp.rateLimitConfig, middleware.NewRateLimitConfig),
+		loadConfig(&sp.postgresConfig, postgres.NewConfig),
+		loadConfig(&sp.kafkaConfig, kafka.NewConfig),
+		loadConfig(&sp.tracingConfig, tracing.NewConfig),
+		loadConfig(&sp.vectorStorageConfig, vectorstorage.NewConfig),
+		loadConfig(&sp.embedderConfig, embedder.NewCacheConfig),
+		loadConfig(&sp.ollamaRetryConfig, llmretry.NewConfig),
+		loadConfig(&sp.searchConfig, searchservice.NewConfig),
+		loadConfig(&sp.processorConfig, resourceprocessor.NewConfig),
+	)
+}
+
+// loadConfig stores the config returned by load in field, leaving it unset on error
+func loadConfig[T any](field **T, load func() (*T, error)) error {
+	config, err := load()
+	if err != nil {
+		return err
+	}
+
+	*field = config
+	return nil
+}
+
 // Logger returns the application's slog manager, creating it if it doesn't exist
 func (sp *ServiceProvider) Logger(ctx context.Context) *slogmanager.Manager {
 	if sp.slogManager != nil {
 		return sp.slogManager
 	}
-	_ = ctx
+	config := sp.LogConfig(ctx)
+	format := slogmanager.WithTextFormat()
+	if config.Format == "json" {
+		format = slogmanager.WithJSONFormat()
+	}
+
 	manager := slogmanager.New()
-	manager.AddWriter("stdout", slogmanager.NewWriter(os.Stdout, slogmanager.WithTextFormat()))
+	manager.AddWriter("stdout", slogmanager.NewWriter(os.Stdout, format))
+	handler := middleware.NewRedactHandler(manager.Logger().Handler(), config.MaxValueBytes)
+	slog.SetDefault(slog.New(middleware.NewRequestIDHandler(handler)))
 	sp.slogManager = manager
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 	return sp.slogManager
 }
 
-// EmbeddingLLM returns the LLM instance for embeddings, creating it if it doesn't exist
-func (sp *ServiceProvider) EmbeddingLLM(ctx context.Context) *ollama.LLM {
+// LogConfig returns the log configuration, creating it if it doesn't exist.
+// It is loaded before the logger exists, so errors are only returned by panic.
+func (sp *ServiceProvider) LogConfig(_ context.Context) *LogConfig {
+	if sp.logConfig != nil {
+		return sp.logConfig
+	}
+
+	config, err := NewLogConfig()
+	if err != nil {
+		panic(fmt.Errorf("error creating log config: %w", err))
+	}
+
+	sp.logConfig = config
+	return config
+}
+
+// OllamaConfig returns the Ollama configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) OllamaConfig(ctx context.Context) *OllamaConfig {
+	if sp.ollamaConfig != nil {
+		return sp.ollamaConfig
+	}
+
+	config, err := NewOllamaConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating ollama config", "error", err.Error())
+		panic(fmt.Errorf("error creating ollama config: %w", err))
+	}
+
+	sp.ollamaConfig = config
+	return config
+}
+
+// EmbeddingLLM returns the Ollama model for embeddings, creating it if it doesn't exist
+func (sp *ServiceProvider) EmbeddingLLM(ctx context.Context) *embedder.OllamaModel {
 	if sp.embeddingLLM != nil {
 		return sp.embeddingLLM
 	}
 
-	llm, err := ollama.New(
-		ollama.WithServerURL("http://ollama-embedder:11434/"),
-		ollama.WithModel("bge-m3"),
+	llm, err := embedder.NewOllamaModel(
+		sp.OllamaConfig(ctx).EmbedderURL,
+		sp.OllamaConfig(ctx).EmbeddingModel,
 	)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating ollama embedding LLM", "error", err.Error())
@@ -105,8 +216,8 @@ func (sp *ServiceProvider) GeneratingLLM(ctx context.Context) *ollama.LLM {
 		return sp.generationLLM
 	}
 
-	llm, err := ollama.New(ollama.WithServerURL("http://ollama-generator:11434/"),
-		ollama.WithModel("gemma3:4b-it-qat"),
+	llm, err := ollama.New(ollama.WithServerURL(sp.OllamaConfig(ctx).GeneratorURL),
+		ollama.WithModel(sp.OllamaConfig(ctx).GenerationModel),
 	)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating ollama generating LLM", "error", err.Error())
@@ -117,13 +228,55 @@ func (sp *ServiceProvider) GeneratingLLM(ctx context.Context) *ollama.LLM {
 	return llm
 }
 
+// ValidateEmbeddingModel embeds a probe text with the selected embedding model
+// and fails when its vectors don't have the dimensions the vector storage was
+// configured with, which would otherwise only surface on the first insert, or
+// aren't normalized while the distance metric needs them to be.
+func (sp *ServiceProvider) ValidateEmbeddingModel(ctx context.Context) error {
+	model := sp.OllamaConfig(ctx).EmbeddingModel
+
+	vectors, err := sp.EmbeddingLLM(ctx).CreateEmbedding(ctx, []string{embeddingProbeText})
+	if err != nil {
+		return fmt.Errorf("embedding model %q: %w", model, err)
+	}
+	if len(vectors) == 0 {
+		return fmt.Errorf("embedding model %q returned no embedding", model)
+	}
+
+	expected := sp.VectorStorageConfig(ctx).EmbeddingDimensions
+	if len(vectors[0]) != expected {
+		return fmt.Errorf("embedding model %q produces %d dimensional vectors, vector_storage.embedding_dimensions is %d",
+			model, len(vectors[0]), expected)
+	}
+	if err := vectorstorage.CheckNormalization(sp.VectorStorageConfig(ctx).DistanceMetric, vectors[0]); err != nil {
+		return fmt.Errorf("embedding model %q: %w", model, err)
+	}
+
+	return nil
+}
+
 // Embedder returns the embedder service instance, creating it if it doesn't exist
 func (sp *ServiceProvider) Embedder(ctx context.Context) *embedder.Embedder {
 	if sp.embedder != nil {
 		return sp.embedder
 	}
 
-	e, err := embedder.NewEmbedder(sp.EmbeddingLLM(ctx))
+	cacheConfig := sp.EmbeddingCacheConfig(ctx)
+	opts := []embedder.Option{
+		embedder.WithCache(sp.OllamaConfig(ctx).EmbeddingModel, cacheConfig.Size),
+		embedder.WithRetry(llmretry.NewPolicy(*sp.OllamaRetryConfig(ctx))),
+		embedder.WithBatchSize(sp.EmbeddingConfig(ctx).BatchSize),
+	}
+	if cacheConfig.Persist {
+		store, err := embedder.NewPostgresCache(ctx, sp.PgxPool(ctx))
+		if err != nil {
+			sp.Logger(ctx).Logger().Error("error creating embedding cache store", "error", err.Error())
+			panic(fmt.Errorf("error creating embedding cache store: %w", err))
+		}
+		opts = append(opts, embedder.WithPersistentCache(store))
+	}
+
+	e, err := embedder.NewEmbedder(sp.EmbeddingLLM(ctx), opts...)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating embedding LLM", "error", err.Error())
 		panic(fmt.Errorf("error creating embedding LLM: %w", err))
@@ -134,13 +287,47 @@ func (sp *ServiceProvider) Embedder(ctx context.Context) *embedder.Embedder {
 	return e
 }
 
+// EmbeddingCacheConfig returns the embedding cache configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) EmbeddingCacheConfig(ctx context.Context) *embedder.CacheConfig {
+	if sp.embedderConfig != nil {
+		return sp.embedderConfig
+	}
+
+	config, err := embedder.NewCacheConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating embedding cache config", "error", err.Error())
+		panic(fmt.Errorf("error creating embedding cache config: %w", err))
+	}
+
+	sp.embedderConfig = config
+	return config
+}
+
+// EmbeddingConfig returns the embedding request configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) EmbeddingConfig(ctx context.Context) *embedder.Config {
+	if sp.embeddingConfig != nil {
+		return sp.embeddingConfig
+	}
+
+	config, err := embedder.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating embedding config", "error", err.Error())
+		panic(fmt.Errorf("error creating embedding config: %w", err))
+	}
+
+	sp.embeddingConfig = config
+	return config
+}
+
 // Generator returns the text generator service instance, creating it if it doesn't exist
 func (sp *ServiceProvider) Generator(ctx context.Context) *generator.Generator {
 	if sp.generator != nil {
 		return sp.generator
 	}
 
-	g, err := generator.NewGenerator(sp.GeneratingLLM(ctx))
+	g, err := generator.NewGenerator(sp.GeneratingLLM(ctx),
+		generator.WithRetry(llmretry.NewPolicy(*sp.OllamaRetryConfig(ctx))),
+	)
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error creating generating LLM", "error", err.Error())
 		panic(fmt.Errorf("error creating generating LLM: %w", err))
@@ -151,6 +338,22 @@ func (sp *ServiceProvider) Generator(ctx context.Context) *generator.Generator {
 	return g
 }
 
+// OllamaRetryConfig returns the retry configuration of Ollama calls, creating it if it doesn't exist
+func (sp *ServiceProvider) OllamaRetryConfig(ctx context.Context) *llmretry.Config {
+	if sp.ollamaRetryConfig != nil {
+		return sp.ollamaRetryConfig
+	}
+
+	config, err := llmretry.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating ollama retry config", "error", err.Error())
+		panic(fmt.Errorf("error creating ollama retry config: %w", err))
+	}
+
+	sp.ollamaRetryConfig = config
+	return config
+}
+
 // PostgresConfig returns the PostgreSQL configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) PostgresConfig(ctx context.Context) *postgres.Config {
 	if sp.postgresConfig != nil {
@@ -183,6 +386,45 @@ func (sp *ServiceProvider) KafkaConfig(ctx context.Context) *kafka.Config {
 	return config
 }
 
+// TracingConfig returns the tracing configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) TracingConfig(ctx context.Context) *tracing.Config {
+	if sp.tracingConfig != nil {
+		return sp.tracingConfig
+	}
+
+	config, err := tracing.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating tracing config", "error", err.Error())
+		panic(fmt.Errorf("error creating tracing config: %w", err))
+	}
+
+	sp.tracingConfig = config
+	return config
+}
+
+// TracerProvider returns the OTLP tracer provider, creating it if it doesn't exist.
+// It returns nil when tracing is disabled; the trace context is still propagated.
+func (sp *ServiceProvider) TracerProvider(ctx context.Context) *sdktrace.TracerProvider {
+	if sp.tracerProvider != nil {
+		return sp.tracerProvider
+	}
+
+	provider, err := tracing.NewTracerProvider(ctx, sp.TracingConfig(ctx))
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating tracer provider", "error", err.Error())
+		panic(fmt.Errorf("error creating tracer provider: %w", err))
+	}
+
+	if provider != nil {
+		closer.Add(func() error {
+			return provider.Shutdown(context.Background())
+		})
+	}
+
+	sp.tracerProvider = provider
+	return provider
+}
+
 // AuthConfig returns the auth configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) AuthConfig(ctx context.Context) *middleware.AuthConfig {
 	if sp.authConfig != nil {
@@ -199,38 +441,120 @@ func (sp *ServiceProvider) AuthConfig(ctx context.Context) *middleware.AuthConfi
 	return config
 }
 
+// CompressionConfig returns the stream compression configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) CompressionConfig(ctx context.Context) *middleware.CompressionConfig {
+	if sp.compressionConfig != nil {
+		return sp.compressionConfig
+	}
+
+	config, err := middleware.NewCompressionConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating stream compression config", "error", err.Error())
+		panic(fmt.Errorf("error creating stream compression config: %w", err))
+	}
+
+	sp.compressionConfig = config
+	return config
+}
+
+// StreamConfig returns the answer stream configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) StreamConfig(ctx context.Context) *searchcontroller.Config {
+	if sp.streamConfig != nil {
+		return sp.streamConfig
+	}
+
+	config, err := searchcontroller.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating stream config", "error", err.Error())
+		panic(fmt.Errorf("error creating stream config: %w", err))
+	}
+
+	sp.streamConfig = config
+	return config
+}
+
+// RateLimitConfig returns the /ask rate limit configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) RateLimitConfig(ctx context.Context) *middleware.RateLimitConfig {
+	if sp.rateLimitConfig != nil {
+		return sp.rateLimitConfig
+	}
+
+	config, err := middleware.NewRateLimitConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating rate limit config", "error", err.Error())
+		panic(fmt.Errorf("error creating rate limit config: %w", err))
+	}
+
+	sp.rateLimitConfig = config
+	return config
+}
+
+// RateLimitStore returns the in-memory token bucket store, creating it if it doesn't exist
+func (sp *ServiceProvider) RateLimitStore(ctx context.Context) *middleware.MemoryRateLimitStore {
+	if sp.rateLimitStore != nil {
+		return sp.rateLimitStore
+	}
+
+	sp.rateLimitStore = middleware.NewMemoryRateLimitStore(sp.RateLimitConfig(ctx))
+	return sp.rateLimitStore
+}
+
 // GinEngine returns the configured Gin web engine instance, creating it if it doesn't exist
 func (sp *ServiceProvider) GinEngine(ctx context.Context) *gin.Engine {
 	if sp.ginEngine != nil {
 		return sp.ginEngine
 	}
 	_ = ctx
-	engine := gin.Default()
+	// gin.Default's access log would print tokens passed in the query
+	engine := gin.New()
 
 	corsConfig := cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 	corsConfig.AllowAllOrigins = true
 
+	engine.Use(middleware.RequestID())
 	engine.Use(cors.New(corsConfig))
 
-	engine.Use(gin.Logger())
+	engine.Use(gin.LoggerWithFormatter(middleware.AccessLogFormatter))
 	engine.Use(gin.Recovery())
+	engine.Use(otelgin.Middleware(tracing.ServiceName))
+
+	engine.GET("/metrics", metrics.Handler())
+	sp.Health(ctx).RegisterRoutes(engine)
 
 	engine = sp.setupRoutes(
 		ctx,
 		engine,
 		sp.SearchController(ctx),
+		sp.AdminController(ctx),
 	)
 
 	sp.ginEngine = engine
 	return engine
 }
 
+// Health returns the dependency health checks of the service, creating them if they don't exist
+func (sp *ServiceProvider) Health(ctx context.Context) *health.Health {
+	if sp.health != nil {
+		return sp.health
+	}
+
+	ollamaClient := &http.Client{Timeout: readinessTimeout}
+
+	sp.health = health.New(readinessTimeout).
+		AddCheck("postgres", sp.PgxPool(ctx).Ping).
+		AddCheck("kafka_producer", sp.KafkaProducer(ctx).Health).
+		AddCheck("kafka_consumer", sp.KafkaConsumer(ctx).Health).
+		AddCheck("ollama_embedder", health.HTTPCheck(ollamaClient, sp.OllamaConfig(ctx).EmbedderURL)).
+		AddCheck("ollama_generator", health.HTTPCheck(ollamaClient, sp.OllamaConfig(ctx).GeneratorURL))
+	return sp.health
+}
+
 func (sp *ServiceProvider) setupRoutes(ctx context.Context, router *gin.Engine, controllers ...controllers.Controller) *gin.Engine {
 	api := router.Group("/api")
 	v1 := api.Group("/v1")
@@ -283,7 +607,7 @@ func (sp *ServiceProvider) VectorStore(ctx context.Context) *vectorstorage.Vecto
 	vectorStore, err := vectorstorage.NewVectorStorage(
 		ctx,
 		sp.VectorStorageConfig(ctx),
-		sp.PostgresConfig(ctx),
+		sp.PgxPool(ctx),
 		sp.Embedder(ctx),
 		sp.Generator(ctx),
 	)
@@ -303,13 +627,47 @@ func (sp *ServiceProvider) SearchController(ctx context.Context) *searchcontroll
 		return sp.searchController
 	}
 
-	controller := searchcontroller.NewController(sp.SearchService(ctx))
+	var askMiddleware []gin.HandlerFunc
+	if sp.RateLimitConfig(ctx).Enabled {
+		askMiddleware = append(askMiddleware, middleware.RateLimit(sp.RateLimitStore(ctx)))
+	}
+
+	controller := searchcontroller.NewController(
+		sp.SearchService(ctx),
+		sp.CompressionConfig(ctx),
+		sp.StreamConfig(ctx),
+		askMiddleware...,
+	)
 
 	sp.searchController = controller
 
 	return controller
 }
 
+// AdminController returns the admin controller instance, creating it if it doesn't exist
+func (sp *ServiceProvider) AdminController(ctx context.Context) *admincontroller.Controller {
+	if sp.adminController != nil {
+		return sp.adminController
+	}
+
+	sp.adminController = admincontroller.NewController(sp.EvaluationService(ctx), sp.OutboxProcessor(ctx))
+	return sp.adminController
+}
+
+// EvaluationService returns the answer evaluation service instance, creating it if it doesn't exist
+func (sp *ServiceProvider) EvaluationService(ctx context.Context) *evaluationservice.Service {
+	if sp.evaluationService != nil {
+		return sp.evaluationService
+	}
+
+	sp.evaluationService = evaluationservice.NewService(
+		sp.SearchService(ctx),
+		sp.Embedder(ctx),
+		evaluationConcurrency,
+	)
+	return sp.evaluationService
+}
+
 // SearchService returns the search service instance, creating it if it doesn't exist
 func (sp *ServiceProvider) SearchService(ctx context.Context) *searchservice.Service {
 	if sp.searchService != nil {
@@ -319,6 +677,7 @@ func (sp *ServiceProvider) SearchService(ctx context.Context) *searchservice.Ser
 	// Create search service with optional event service
 	service := searchservice.NewService(
 		sp.VectorStore(ctx),
+		sp.SearchConfig(ctx),
 		sp.EventService(ctx),
 	)
 
@@ -327,6 +686,22 @@ func (sp *ServiceProvider) SearchService(ctx context.Context) *searchservice.Ser
 	return service
 }
 
+// SearchConfig returns the search service configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) SearchConfig(ctx context.Context) *searchservice.Config {
+	if sp.searchConfig != nil {
+		return sp.searchConfig
+	}
+
+	config, err := searchservice.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating search config", "error", err.Error())
+		panic(fmt.Errorf("error creating search config: %w", err))
+	}
+
+	sp.searchConfig = config
+	return config
+}
+
 // ServerConfig returns the server configuration, creating it if it doesn't exist
 func (sp *ServiceProvider) ServerConfig(ctx context.Context) *server.Config {
 	if sp.serverConfig != nil {
@@ -350,7 +725,6 @@ func (sp *ServiceProvider) Server(ctx context.Context) *http.Server {
 	}
 
 	s := server.NewServer(
-		ctx,
 		sp.GinEngine(ctx),
 		sp.ServerConfig(ctx),
 	)
@@ -366,9 +740,8 @@ func (sp *ServiceProvider) PgxPool(ctx context.Context) *pgxpool.Pool {
 	}
 
 	postgresConfig := sp.PostgresConfig(ctx)
-	dbURL := postgresConfig.GetConnectionString()
 
-	config, err := pgxpool.ParseConfig(dbURL)
+	config, err := postgresConfig.PoolConfig()
 	if err != nil {
 		sp.Logger(ctx).Logger().Error("error parsing database URL", "error", err.Error())
 		panic(fmt.Errorf("error parsing database URL: %w", err))
@@ -380,12 +753,23 @@ func (sp *ServiceProvider) PgxPool(ctx context.Context) *pgxpool.Pool {
 		panic(fmt.Errorf("error creating database pool: %w", err))
 	}
 
-	// Test the connection
-	if err := pool.Ping(ctx); err != nil {
+	// Test the connection, failing fast when the database is unreachable
+	pingCtx := ctx
+	if postgresConfig.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		pingCtx, cancel = context.WithTimeout(ctx, postgresConfig.ConnectTimeout)
+		defer cancel()
+	}
+	if err := pool.Ping(pingCtx); err != nil {
 		sp.Logger(ctx).Logger().Error("error pinging database", "error", err.Error())
 		panic(fmt.Errorf("error pinging database: %w", err))
 	}
 
+	closer.Add(func() error {
+		pool.Close()
+		return nil
+	})
+
 	sp.pgxPool = pool
 	return pool
 }
@@ -420,6 +804,8 @@ func (sp *ServiceProvider) KafkaProducer(ctx context.Context) *kafka.Producer {
 		panic(fmt.Errorf("error creating Kafka producer: %w", err))
 	}
 
+	closer.Add(producer.Close)
+
 	sp.kafkaProducer = producer
 	return producer
 }
@@ -495,8 +881,58 @@ func (sp *ServiceProvider) ResourceProcessor(ctx context.Context) *resourceproce
 		sp.VectorStore(ctx),
 		sp.EventService(ctx),
 		sp.KafkaConsumer(ctx),
+		sp.SearchService(ctx),
 	)
 
+	config := sp.ResourceProcessorConfig(ctx)
+	workers := config.Workers
+	if workers == 0 {
+		workers = sp.resourcePartitions(ctx)
+	}
+	processor.WithWorkers(workers, config.Backlog).
+		WithTimeout(config.Timeout).
+		WithRetry(config.RetryAttempts, config.RetryDelay, config.RetryMaxDelay)
+
 	sp.resourceProcessor = processor
 	return processor
 }
+
+// ResourceProcessorConfig returns the resource processor configuration, creating it if it doesn't exist
+func (sp *ServiceProvider) ResourceProcessorConfig(ctx context.Context) *resourceprocessor.Config {
+	if sp.processorConfig != nil {
+		return sp.processorConfig
+	}
+
+	config, err := resourceprocessor.NewConfig()
+	if err != nil {
+		sp.Logger(ctx).Logger().Error("error creating resource processor config", "error", err.Error())
+		panic(fmt.Errorf("error creating resource processor config: %w", err))
+	}
+
+	sp.processorConfig = config
+	return config
+}
+
+// resourcePartitions returns the partition count of the resource topic, falling
+// back to a single worker when the brokers cannot tell
+func (sp *ServiceProvider) resourcePartitions(ctx context.Context) int {
+	logger := sp.Logger(ctx).Logger()
+
+	consumerConfig, err := kafka.NewConsumerConfig()
+	if err != nil {
+		logger.Warn("error creating kafka consumer config, using one worker", "error", err.Error())
+		return 1
+	}
+	topic, err := kafka.GetTopicResource()
+	if err != nil {
+		logger.Warn("error reading resource topic, using one worker", "error", err.Error())
+		return 1
+	}
+
+	partitions, err := kafka.PartitionCount(consumerConfig, topic)
+	if err != nil || partitions == 0 {
+		logger.Warn("error getting resource topic partitions, using one worker", "error", err)
+		return 1
+	}
+	return partitions
+}
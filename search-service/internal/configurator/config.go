@@ -54,14 +54,7 @@ func ParseConfig[T any](configPath string) (*T, error) {
 
 	// Parse from the specified config section
 	if configPath != "" {
-		var sub *viper.Viper
-
-		mode := os.Getenv("GIN_MODE")
-		if mode == "release" {
-			sub = viper.Sub("production")
-		} else {
-			sub = viper.Sub("debug")
-		}
+		sub := viper.Sub(modeSection())
 
 		// If we couldn't get the mode-specific config, try the root level
 		if sub == nil {
@@ -86,13 +79,22 @@ func ParseConfig[T any](configPath string) (*T, error) {
 
 	// Validate configuration
 	if err := validator.Validate(config); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
+		return nil, fmt.Errorf("config validation failed: %w", qualify(configPath, err))
 	}
 
 	slog.Debug("Configuration parsed and validated successfully", "section", configPath)
 	return config, nil
 }
 
+// modeSection returns the section of the config file holding the settings of
+// the GIN_MODE the service runs in
+func modeSection() string {
+	if os.Getenv("GIN_MODE") == "release" {
+		return "production"
+	}
+	return "debug"
+}
+
 // GetString gets a string value from config with environment variable fallback
 func GetString(key string) string {
 	return viper.GetString(key)
@@ -113,6 +115,16 @@ func GetStringSlice(key string) []string {
 	return viper.GetStringSlice(key)
 }
 
+// envNames maps the config keys bound to environment variables to their
+// names, so that errors can name the variable to set
+var envNames = map[string]string{}
+
+// bindEnv binds the config key to the environment variable
+func bindEnv(key, env string) {
+	_ = viper.BindEnv(key, env)
+	envNames[key] = env
+}
+
 // SetupEnvironmentMapping configures viper to map environment variables to config keys
 func SetupEnvironmentMapping() {
 	// No prefix for environment variables
@@ -123,11 +135,11 @@ func SetupEnvironmentMapping() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	// Server configuration
-	viper.BindEnv("server.host", "SERVER_HOST")
-	viper.BindEnv("server.port", "SERVER_PORT")
+	bindEnv("server.host", "SERVER_HOST")
+	bindEnv("server.port", "SERVER_PORT")
 
 	// Database configuration
-	viper.BindEnv("postgres.host", "SEARCH_DB_HOST")
+	bindEnv("postgres.host", "SEARCH_DB_HOST")
 	// Use container port for internal Docker network connections
 	containerPort := viper.GetString("SEARCH_DB_CONTAINER_PORT")
 	if containerPort == "" {
@@ -136,28 +148,68 @@ func SetupEnvironmentMapping() {
 	viper.Set("postgres.port", containerPort)
 	// Note: We're not binding SEARCH_DB_PORT directly to postgres.port
 	// because within Docker network, we need to use the container port (5432)
-	viper.BindEnv("postgres.user", "SEARCH_DB_USER")
-	viper.BindEnv("postgres.password", "SEARCH_DB_PASSWORD")
-	viper.BindEnv("postgres.dbname", "SEARCH_DB_NAME")
-	viper.BindEnv("postgres.sslmode", "SEARCH_DB_SSL_MODE")
+	bindEnv("postgres.user", "SEARCH_DB_USER")
+	bindEnv("postgres.password", "SEARCH_DB_PASSWORD")
+	bindEnv("postgres.dbname", "SEARCH_DB_NAME")
+	bindEnv("postgres.sslmode", "SEARCH_DB_SSL_MODE")
+	bindEnv("postgres.max_conns", "SEARCH_DB_MAX_CONNS")
+	bindEnv("postgres.min_conns", "SEARCH_DB_MIN_CONNS")
+	bindEnv("postgres.max_conn_lifetime", "SEARCH_DB_MAX_CONN_LIFETIME")
+	bindEnv("postgres.health_check_period", "SEARCH_DB_HEALTH_CHECK_PERIOD")
+	bindEnv("postgres.connect_timeout", "SEARCH_DB_CONNECT_TIMEOUT")
 
 	// Auth configuration
-	viper.BindEnv("auth.host", "AUTH_HOST")
-	viper.BindEnv("auth.port", "AUTH_PORT")
-	viper.BindEnv("auth.realm", "AUTH_REALM")
-	viper.BindEnv("auth.client_id", "AUTH_SEARCH_SERVICE_CLIENT_ID")
-	viper.BindEnv("auth.client_secret", "AUTH_SEARCH_SERVICE_CLIENT_SECRET")
+	bindEnv("auth.host", "AUTH_HOST")
+	bindEnv("auth.port", "AUTH_PORT")
+	bindEnv("auth.realm", "AUTH_REALM")
+	bindEnv("auth.client_id", "AUTH_SEARCH_SERVICE_CLIENT_ID")
+	bindEnv("auth.client_secret", "AUTH_SEARCH_SERVICE_CLIENT_SECRET")
+	bindEnv("auth.tenant_claim", "AUTH_TENANT_CLAIM")
+	bindEnv("auth.issuer", "AUTH_ISSUER")
+	bindEnv("auth.audience", "AUTH_SEARCH_SERVICE_AUDIENCE")
+	bindEnv("auth.jwks_refresh_interval", "AUTH_JWKS_REFRESH_INTERVAL")
 
 	// Kafka configuration
-	viper.BindEnv("kafka.brokers", "KAFKA_BROKERS")
-	viper.BindEnv("kafka.consumer_group_id", "KAFKA_CONSUMER_GROUP_ID")
-	viper.BindEnv("kafka.topics.resource", "KAFKA_TOPIC_RESOURCE")
+	bindEnv("kafka.brokers", "KAFKA_BROKERS")
+	bindEnv("kafka.consumer_group_id", "KAFKA_CONSUMER_GROUP_ID")
+	bindEnv("kafka.topics.resource", "KAFKA_TOPIC_RESOURCE")
 
 	// Vector storage configuration (from config file only)
 	// No environment bindings for these as they should be in config.yml
 
+	// Rate limit configuration
+	bindEnv("rate_limit.enabled", "RATE_LIMIT_ENABLED")
+	bindEnv("rate_limit.requests_per_minute", "RATE_LIMIT_REQUESTS_PER_MINUTE")
+	bindEnv("rate_limit.burst", "RATE_LIMIT_BURST")
+
+	// Ollama retry configuration
+	bindEnv("ollama_retry.attempts", "OLLAMA_RETRY_ATTEMPTS")
+	bindEnv("ollama_retry.base_delay", "OLLAMA_RETRY_BASE_DELAY")
+	bindEnv("ollama_retry.backoff", "OLLAMA_RETRY_BACKOFF")
+	bindEnv("ollama_retry.max_delay", "OLLAMA_RETRY_MAX_DELAY")
+	bindEnv("ollama_retry.timeout", "OLLAMA_CALL_TIMEOUT")
+
+	// Embedding configuration
+	bindEnv("embedding.batch_size", "OLLAMA_EMBEDDING_BATCH_SIZE")
+
+	// Resource processor configuration
+	bindEnv("resource_processor.workers", "RESOURCE_PROCESSOR_WORKERS")
+	bindEnv("resource_processor.backlog", "RESOURCE_PROCESSOR_BACKLOG")
+	bindEnv("resource_processor.timeout", "RESOURCE_PROCESSOR_TIMEOUT")
+	bindEnv("resource_processor.retry_attempts", "RESOURCE_PROCESSOR_RETRY_ATTEMPTS")
+	bindEnv("resource_processor.retry_delay", "RESOURCE_PROCESSOR_RETRY_DELAY")
+	bindEnv("resource_processor.retry_max_delay", "RESOURCE_PROCESSOR_RETRY_MAX_DELAY")
+
 	// Logger configuration
-	viper.BindEnv("logger.level", "LOG_LEVEL")
+	bindEnv("logger.level", "LOG_LEVEL")
+	bindEnv("logger.format", "LOG_FORMAT")
+	bindEnv("logger.max_value_bytes", "LOG_MAX_VALUE_BYTES")
+
+	// Ollama servers and models
+	bindEnv("ollama.embedder_url", "OLLAMA_EMBEDDER_URL")
+	bindEnv("ollama.generator_url", "OLLAMA_GENERATOR_URL")
+	bindEnv("ollama.embedding_model", "OLLAMA_EMBEDDING_MODEL")
+	bindEnv("ollama.generation_model", "OLLAMA_GENERATION_MODEL")
 
 	// Handle Kafka brokers specially (comma-separated list)
 	if brokersEnv := viper.GetString("KAFKA_BROKERS"); brokersEnv != "" {
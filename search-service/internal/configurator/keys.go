@@ -0,0 +1,130 @@
+package configurator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"github.com/nzb3/diploma/search-service/internal/validator"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadKeys loads the settings under prefix into a copy of defaults, one key per
+// field named by its mapstructure tag. Unlike ParseConfig it reads keys one by
+// one, so it also sees values bound to environment variables. A key that is not
+// set falls back to the section of the config file for the GIN mode, and then
+// to its default. Values that cannot be converted and fields failing their
+// validate tags are all reported in a single error, naming the full key and the
+// environment variable bound to it.
+func LoadKeys[T any](prefix string, defaults T) (*T, error) {
+	config := defaults
+
+	value := reflect.ValueOf(&config).Elem()
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config %s: %T is not a struct", prefix, config)
+	}
+
+	var errs []error
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		key := prefix + "." + name
+		raw, ok := lookup(key)
+		if !ok {
+			continue
+		}
+
+		if err := setField(value.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	if err := validator.Validate(&config); err != nil {
+		errs = append(errs, qualify(prefix, err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", prefix, err)
+	}
+
+	return &config, nil
+}
+
+// lookup returns the value of the key, set in the environment or at the root
+// of the config file, or else in the section for the GIN mode
+func lookup(key string) (any, bool) {
+	if viper.IsSet(key) {
+		return viper.Get(key), true
+	}
+	if modeKey := modeSection() + "." + key; viper.IsSet(modeKey) {
+		return viper.Get(modeKey), true
+	}
+	return nil, false
+}
+
+// qualify names the invalid fields of a validation error by their full key
+// under prefix and points at the environment variable bound to it, if any
+func qualify(prefix string, err error) error {
+	var validationErr *validator.ValidationError
+	if prefix == "" || !errors.As(err, &validationErr) {
+		return err
+	}
+
+	for i, e := range validationErr.Errs {
+		fieldErrs, ok := e.(validator.FieldErrors)
+		if !ok {
+			continue
+		}
+
+		qualified := make(validator.FieldErrors, 0, len(fieldErrs))
+		for _, fieldErr := range fieldErrs {
+			fieldErr.Field = prefix + "." + fieldErr.Field
+			if env, ok := envNames[fieldErr.Field]; ok {
+				fieldErr.Message += " (set " + env + ")"
+			}
+			qualified = append(qualified, fieldErr)
+		}
+		validationErr.Errs[i] = qualified
+	}
+	return err
+}
+
+func setField(field reflect.Value, raw any) error {
+	var (
+		value any
+		err   error
+	)
+
+	switch {
+	case field.Type() == durationType:
+		value, err = cast.ToDurationE(raw)
+	case field.Kind() == reflect.String:
+		value, err = cast.ToStringE(raw)
+	case field.Kind() == reflect.Bool:
+		value, err = cast.ToBoolE(raw)
+	case field.Kind() == reflect.Int:
+		value, err = cast.ToIntE(raw)
+	case field.Kind() == reflect.Float64:
+		value, err = cast.ToFloat64E(raw)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		value, err = cast.ToStringSliceE(raw)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	if err != nil {
+		return err
+	}
+
+	field.Set(reflect.ValueOf(value).Convert(field.Type()))
+	return nil
+}
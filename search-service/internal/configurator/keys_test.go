@@ -0,0 +1,114 @@
+package configurator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/validator"
+)
+
+type testConfig struct {
+	Host    string        `mapstructure:"host" validate:"required"`
+	Port    string        `mapstructure:"port" validate:"required"`
+	Secret  string        `mapstructure:"secret" validate:"required"`
+	Mode    string        `mapstructure:"mode" validate:"omitempty,oneof=fast safe"`
+	Retries int           `mapstructure:"retries" validate:"min=0"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func TestLoadKeys_ReadsEnvironmentBindings(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Setenv("TEST_HOST", "db")
+	t.Setenv("TEST_TIMEOUT", "3s")
+	require.NoError(t, viper.BindEnv("test.host", "TEST_HOST"))
+	require.NoError(t, viper.BindEnv("test.timeout", "TEST_TIMEOUT"))
+	viper.Set("test.secret", "s3cret")
+
+	config, err := LoadKeys("test", testConfig{Port: "5432", Retries: 3})
+
+	require.NoError(t, err)
+	assert.Equal(t, testConfig{
+		Host:    "db",
+		Port:    "5432",
+		Secret:  "s3cret",
+		Retries: 3,
+		Timeout: 3 * time.Second,
+	}, *config)
+}
+
+func TestLoadKeys_ReportsAllInvalidSettings(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("test.mode", "reckless")
+	viper.Set("test.retries", -1)
+
+	config, err := LoadKeys("test", testConfig{})
+
+	require.Error(t, err)
+	assert.Nil(t, config)
+
+	var fieldErrs validator.FieldErrors
+	require.True(t, errors.As(err, &fieldErrs))
+	assert.ElementsMatch(t, []string{"test.host", "test.port", "test.secret", "test.mode", "test.retries"}, fieldErrs.Fields())
+	assert.Contains(t, err.Error(), "invalid test config")
+	assert.Contains(t, err.Error(), "test.host is required")
+	assert.Contains(t, err.Error(), "test.mode must be one of: fast safe")
+}
+
+func TestLoadKeys_ReportsUnconvertibleValues(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("test.host", "db")
+	viper.Set("test.port", "5432")
+	viper.Set("test.secret", "s3cret")
+	viper.Set("test.timeout", "soon")
+
+	_, err := LoadKeys("test", testConfig{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test.timeout")
+}
+
+func TestLoadKeys_NamesBoundEnvironmentVariables(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(func() { delete(envNames, "test.host") })
+
+	bindEnv("test.host", "TEST_HOST")
+	viper.Set("test.port", "5432")
+	viper.Set("test.secret", "s3cret")
+
+	_, err := LoadKeys("test", testConfig{})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test.host is required (set TEST_HOST)")
+}
+
+func TestLoadKeys_FallsBackToModeSection(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Setenv("GIN_MODE", "release")
+
+	viper.Set("production.test.host", "db")
+	viper.Set("production.test.port", "5432")
+	viper.Set("debug.test.port", "15432")
+	viper.Set("test.secret", "s3cret")
+	viper.Set("production.test.secret", "ignored")
+
+	config, err := LoadKeys("test", testConfig{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "db", config.Host)
+	assert.Equal(t, "5432", config.Port)
+	// Keys set at the root, e.g. from the environment, take precedence
+	assert.Equal(t, "s3cret", config.Secret)
+}
@@ -0,0 +1,91 @@
+package admincontroller
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/controllers/middleware"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/evaluationservice"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/outboxprocessor"
+)
+
+type evaluationService interface {
+	Evaluate(ctx context.Context, cases []evaluationservice.Case) (evaluationservice.Report, error)
+}
+
+type outboxProcessor interface {
+	ProcessNow(ctx context.Context) (outboxprocessor.FlushResult, error)
+}
+
+// Controller serves operational endpoints restricted to administrators
+type Controller struct {
+	evaluationService evaluationService
+	outboxProcessor   outboxProcessor
+}
+
+func NewController(es evaluationService, op outboxProcessor) *Controller {
+	return &Controller{
+		evaluationService: es,
+		outboxProcessor:   op,
+	}
+}
+
+func (c *Controller) RegisterRoutes(router *gin.RouterGroup) {
+	adminGroup := router.Group("/admin", middleware.RequireRole(middleware.ResourceAdminRole))
+	{
+		adminGroup.POST("/eval", c.Evaluate())
+		adminGroup.POST("/outbox/flush", c.FlushOutbox())
+	}
+}
+
+// EvaluateRequest is a ground truth set; cases are answered as the calling admin
+type EvaluateRequest struct {
+	Cases []evaluationservice.Case `json:"cases" binding:"required,min=1,max=100,dive"`
+}
+
+// Evaluate runs an evaluation set and returns retrieval and answer quality metrics
+func (c *Controller) Evaluate() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		req, ok := controllers.ValidateRequest[EvaluateRequest](ctx)
+		if !ok {
+			return
+		}
+
+		report, err := c.evaluationService.Evaluate(ctx, req.Cases)
+		if err != nil {
+			slog.ErrorContext(ctx, "Evaluation failed", "error", err, "cases", len(req.Cases))
+			controllers.RespondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		slog.InfoContext(ctx, "Evaluation completed",
+			"cases", report.Total,
+			"failed", report.Failed,
+			"retrieval_hit_rate", report.RetrievalHitRate,
+			"mean_answer_similarity", report.MeanAnswerSimilarity)
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+// FlushOutbox delivers the pending outbox events without waiting for the next
+// processor tick and reports how many were delivered and how many remain
+func (c *Controller) FlushOutbox() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		result, err := c.outboxProcessor.ProcessNow(ctx.Request.Context())
+		if err != nil {
+			slog.ErrorContext(ctx, "Outbox flush failed", "error", err, "processed", result.Processed)
+			controllers.RespondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, err.Error())
+			return
+		}
+
+		slog.InfoContext(ctx, "Outbox flushed",
+			"processed", result.Processed,
+			"failed", result.Failed,
+			"remaining", result.Remaining)
+		ctx.JSON(http.StatusOK, result)
+	}
+}
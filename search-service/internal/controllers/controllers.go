@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -9,15 +11,46 @@ import (
 func ValidateRequest[T any](ctx *gin.Context) (*T, bool) {
 	var req T
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		RespondWithError(ctx, http.StatusBadRequest, CodeInvalidRequest, err.Error())
 		return nil, false
 	}
 	return &req, true
 }
 
+// SendSSEEvent writes an event and flushes it, so that it reaches the client
+// at once instead of when the response buffer fills up or the handler returns
 func SendSSEEvent(ctx *gin.Context, event string, data interface{}) {
 	ctx.SSEvent(event, data)
-	ctx.Writer.Flush()
+	Flush(ctx.Writer)
+}
+
+// Flush sends the buffered response to the client. Gin's writer assumes the
+// writer it wraps can flush and panics otherwise, so a writer that can't, e.g.
+// one wrapped by a middleware, keeps the data until the handler returns.
+func Flush(w gin.ResponseWriter) {
+	var inner http.ResponseWriter = w
+	for {
+		unwrapper, ok := inner.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		inner = unwrapper.Unwrap()
+	}
+	if _, ok := inner.(http.Flusher); ok {
+		w.Flush()
+	}
+}
+
+// ExtendWriteDeadline moves the write deadline of the connection timeout past
+// now, so that a stream may outlive the server write timeout as long as each
+// write completes in time. Writers without a deadline, e.g. in tests, are left
+// as they are.
+func ExtendWriteDeadline(ctx *gin.Context, timeout time.Duration) error {
+	err := http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Now().Add(timeout))
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
 }
 
 type Controller interface {
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flushRecorder records the body sent to the client at every flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.String())
+	r.ResponseRecorder.Flush()
+}
+
+// bufferedWriter is a response writer that can't flush
+type bufferedWriter struct {
+	header http.Header
+	body   []byte
+}
+
+func (w *bufferedWriter) Header() http.Header { return w.header }
+func (w *bufferedWriter) WriteHeader(int)     {}
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.body = append(w.body, data...)
+	return len(data), nil
+}
+
+func TestSendSSEEvent_FlushesEachEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	ctx, _ := gin.CreateTestContext(recorder)
+
+	SendSSEEvent(ctx, "references", "first")
+	SendSSEEvent(ctx, "chunk", "second")
+
+	require.Len(t, recorder.flushed, 2)
+	assert.Equal(t, "event:references\ndata:first\n\n", recorder.flushed[0], "the first event reaches the client before the second is written")
+	assert.Equal(t, "event:references\ndata:first\n\nevent:chunk\ndata:second\n\n", recorder.flushed[1])
+}
+
+func TestSendSSEEvent_WriterWithoutFlush(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	writer := &bufferedWriter{header: http.Header{}}
+	ctx, _ := gin.CreateTestContext(writer)
+
+	require.NotPanics(t, func() { SendSSEEvent(ctx, "chunk", "data") })
+	assert.Equal(t, "event:chunk\ndata:data\n\n", string(writer.body))
+}
+
+func TestExtendWriteDeadline_WriterWithoutDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	assert.NoError(t, ExtendWriteDeadline(ctx, time.Second))
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is a stable identifier of an error that clients can branch on,
+// unlike the message, which is meant for people and may change
+type ErrorCode string
+
+// Error codes shared by every controller. Controllers add codes for the
+// errors of their domain.
+const (
+	CodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeForbidden          ErrorCode = "FORBIDDEN"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeRateLimited        ErrorCode = "RATE_LIMITED"
+	CodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	// Stable error code, e.g. RESOURCE_NOT_FOUND
+	Code ErrorCode `json:"code"`
+	// Human-readable description of the error
+	Message string `json:"message"`
+	// Optional structured details
+	Details any `json:"details,omitempty"`
+}
+
+// RespondWithError aborts the request with an error response. At most one
+// details value is used.
+func RespondWithError(ctx *gin.Context, status int, code ErrorCode, message string, details ...any) {
+	response := ErrorResponse{Code: code, Message: message}
+	if len(details) > 0 {
+		response.Details = details[0]
+	}
+	ctx.AbortWithStatusJSON(status, response)
+}
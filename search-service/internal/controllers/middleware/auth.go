@@ -6,123 +6,139 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/Nerzal/gocloak/v13"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
 )
 
 // Constants for context keys
 const (
-	UserIDKey    string = "user_id"
+	UserIDKey    string = identity.UserIDKey
 	UserNameKey  string = "user_name"
 	UserRolesKey string = "user_roles"
+	TenantIDKey  string = identity.TenantIDKey
 )
 
+// ResourceAdminRole is the Keycloak realm role granting access to admin
+// endpoints, the same role resource-service audits and reindexes resources with
+const ResourceAdminRole = "resource-admin"
+
 // AuthMiddlewareConfig holds necessary configuration for Keycloak authentication
 type AuthMiddlewareConfig = AuthConfig
 
-// AuthMiddleware provides JWT validation with Keycloak
+// clockSkew is the leeway given to the time claims of tokens for clocks of
+// Keycloak and the service drifting apart
+const clockSkew = 30 * time.Second
+
+// signingMethods are the algorithms Keycloak signs access tokens with. Any
+// other, above all "none" and HMAC with the public key as secret, is rejected.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// AuthMiddleware validates the access tokens of Keycloak locally: the signature
+// against the cached keys of the realm, and the expiry, issuer and audience
 type AuthMiddleware struct {
-	keycloak    *gocloak.GoCloak
-	config      *AuthConfig
-	publicKey   string
-	publicKeyMu sync.RWMutex
-	lastFetched time.Time
+	config *AuthConfig
+	keys   *keySet
+	parser *jwt.Parser
 }
 
 // NewAuthMiddleware creates a new middleware instance
 func NewAuthMiddleware(config *AuthConfig) *AuthMiddleware {
-	keycloakURL := config.GetKeycloakURL()
 	return &AuthMiddleware{
-		keycloak: gocloak.NewClient(keycloakURL),
-		config:   config,
+		config: config,
+		keys:   newKeySet(config.GetJWKSURL(), config.JWKSRefreshInterval),
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(signingMethods),
+			jwt.WithIssuer(config.GetIssuer()),
+			jwt.WithAudience(config.GetAudience()),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(clockSkew),
+		),
 	}
 }
 
-func (k *AuthMiddleware) getToken(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
-	token, claims, headersErr := k.getFromHeaders(ctx)
+func (k *AuthMiddleware) getToken(ctx *gin.Context) (jwt.MapClaims, error) {
+	claims, headersErr := k.getFromHeaders(ctx)
 	if headersErr == nil {
-		return token, claims, nil
+		return claims, nil
 	}
 
-	token, claims, paramsErr := k.getFromParams(ctx)
+	claims, paramsErr := k.getFromParams(ctx)
 	if paramsErr == nil {
-		return token, claims, nil
+		return claims, nil
 	}
 
 	err := errors.Join(headersErr, paramsErr)
-	return nil, nil, fmt.Errorf("token was not found neither in headers nor in params: %w", err)
+	return nil, fmt.Errorf("token was not found neither in headers nor in params: %w", err)
 }
 
-func (k *AuthMiddleware) getFromParams(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
+func (k *AuthMiddleware) getFromParams(ctx *gin.Context) (jwt.MapClaims, error) {
 	tokenString := ctx.Query("auth_token")
 	if tokenString == "" {
-		return nil, nil, errors.New("token is required")
+		return nil, errors.New("token is required")
 	}
 
-	token, claims, err := k.keycloak.DecodeAccessToken(ctx, tokenString, k.config.Realm)
-	if err != nil {
-		return nil, nil, err
-	}
-	return token, claims, nil
+	return k.parseToken(ctx, tokenString)
 }
 
-func (k *AuthMiddleware) getFromHeaders(ctx *gin.Context) (*jwt.Token, *jwt.MapClaims, error) {
+func (k *AuthMiddleware) getFromHeaders(ctx *gin.Context) (jwt.MapClaims, error) {
 	authHeader := ctx.GetHeader("Authorization")
 	if authHeader == "" {
-		return nil, nil, errors.New("authorization header is required")
+		return nil, errors.New("authorization header is required")
 	}
 
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return nil, nil, errors.New("invalid authorization format")
+		return nil, errors.New("invalid authorization format")
 
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenString == "" {
-		return nil, nil, errors.New("token not found")
+		return nil, errors.New("token not found")
 	}
 
-	token, claims, err := k.keycloak.DecodeAccessToken(ctx, tokenString, k.config.Realm)
+	return k.parseToken(ctx, tokenString)
+}
+
+// parseToken verifies the signature of the token with the key named by its kid
+// header and validates its claims
+func (k *AuthMiddleware) parseToken(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := k.parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return k.keys.key(ctx, kid)
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return token, claims, nil
+	return claims, nil
 }
 
 // Authenticate creates a gin handler function for Keycloak authentication
 func (k *AuthMiddleware) Authenticate() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		token, _, err := k.getToken(ctx)
+		claims, err := k.getToken(ctx)
 		if err != nil {
-			slog.Error("failed to decode access token", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			slog.ErrorContext(ctx, "failed to validate access token", "error", err)
+			controllers.RespondWithError(ctx, http.StatusUnauthorized, controllers.CodeUnauthorized, "Invalid token")
 			return
 		}
 
-		userID, err := token.Claims.GetSubject()
-		if err != nil {
-			slog.Error("failed to get subject from token", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		userID, err := claims.GetSubject()
+		if err != nil || userID == "" {
+			slog.ErrorContext(ctx, "failed to get subject from token", "error", err)
+			controllers.RespondWithError(ctx, http.StatusUnauthorized, controllers.CodeUnauthorized, "Invalid user ID in token")
 			return
 		}
 
-		isValid, err := k.validateToken(ctx, token.Raw)
-		if err != nil || !isValid {
-			slog.Error("token validation failed", "error", err)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token validation failed"})
-			return
-		}
-
-		userName, roles, err := k.getUserInfo(ctx, token.Raw)
-		if err != nil {
-			slog.Error("failed to get user info", "error", err)
-			// Continue anyway as we have the user ID
-		}
+		userName, _ := claims["preferred_username"].(string)
+		roles := realmRoles(&claims)
 
 		ctx.Set(UserIDKey, userID)
 		ctx.Set(UserNameKey, userName)
@@ -131,37 +147,74 @@ func (k *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		reqCtx := context.WithValue(ctx.Request.Context(), UserIDKey, userID)
 		reqCtx = context.WithValue(reqCtx, UserNameKey, userName)
 		reqCtx = context.WithValue(reqCtx, UserRolesKey, roles)
+		if tenantID := tenantID(&claims, k.config.TenantClaim); tenantID != "" {
+			ctx.Set(TenantIDKey, tenantID)
+			reqCtx = context.WithValue(reqCtx, TenantIDKey, tenantID)
+		}
 		ctx.Request = ctx.Request.WithContext(reqCtx)
 
 		ctx.Next()
 	}
 }
 
-// validateToken performs token introspection to ensure it's still valid
-func (k *AuthMiddleware) validateToken(ctx context.Context, tokenString string) (bool, error) {
-	rptResult, err := k.keycloak.RetrospectToken(ctx, tokenString, k.config.ClientID, k.config.ClientSecret, k.config.Realm)
-	if err != nil {
-		return false, fmt.Errorf("failed to introspect token: %w", err)
+// realmRoles reads the Keycloak realm roles from the realm_access claim
+func realmRoles(claims *jwt.MapClaims) []string {
+	if claims == nil {
+		return nil
+	}
+
+	realmAccess, ok := (*claims)["realm_access"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	values, ok := realmAccess["roles"].([]interface{})
+	if !ok {
+		return nil
 	}
 
-	return *rptResult.Active, nil
+	roles := make([]string, 0, len(values))
+	for _, value := range values {
+		if role, ok := value.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
 }
 
-// getUserInfo gets additional user information from Keycloak
-func (k *AuthMiddleware) getUserInfo(ctx context.Context, tokenString string) (string, []string, error) {
-	userInfo, err := k.keycloak.GetUserInfo(ctx, tokenString, k.config.Realm)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to get user info: %w", err)
+// tenantID reads the tenant of the user from the claim, empty when the token has none
+func tenantID(claims *jwt.MapClaims, claim string) string {
+	if claims == nil || claim == "" {
+		return ""
 	}
 
-	var roles []string
+	tenantID, _ := (*claims)[claim].(string)
+	return tenantID
+}
 
-	return *userInfo.PreferredUsername, roles, nil
+// RequireRole rejects authenticated requests of users without the role.
+// It must run after Authenticate.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		roles, _ := GetUserRoles(ctx.Request.Context())
+		if !slices.Contains(roles, role) {
+			slog.WarnContext(ctx, "access denied: missing role", "role", role)
+			controllers.RespondWithError(ctx, http.StatusForbidden, controllers.CodeForbidden, "Insufficient permissions")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// WithUserID returns a copy of ctx acting on behalf of the user, for work that
+// does not come with a request, such as indexing a resource from an event
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return identity.WithUserID(ctx, userID)
 }
 
 func GetUserID(ctx context.Context) (string, bool) {
-	id, ok := ctx.Value(UserIDKey).(string)
-	return id, ok
+	return identity.UserID(ctx)
 }
 
 func GetUserName(ctx context.Context) (string, bool) {
@@ -169,6 +222,10 @@ func GetUserName(ctx context.Context) (string, bool) {
 	return name, ok
 }
 
+func GetTenantID(ctx context.Context) (string, bool) {
+	return identity.TenantID(ctx)
+}
+
 func GetUserRoles(ctx context.Context) ([]string, bool) {
 	roles, ok := ctx.Value(UserRolesKey).([]string)
 	return roles, ok
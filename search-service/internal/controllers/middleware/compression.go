@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// CompressionConfig holds configuration for streamed response compression
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	Level   int  `yaml:"level" mapstructure:"level" validate:"min=-2,max=9"`
+}
+
+// NewCompressionConfig loads stream compression configuration from config file
+func NewCompressionConfig() (*CompressionConfig, error) {
+	config, err := configurator.ParseConfig[CompressionConfig]("stream_compression")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stream compression config: %w", err)
+	}
+
+	return config, nil
+}
+
+// streamCompressor is implemented by both gzip.Writer and flate.Writer
+type streamCompressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter compresses the response body and pushes every flushed
+// frame through to the client, so SSE events are not held back until the end.
+type compressWriter struct {
+	gin.ResponseWriter
+	compressor streamCompressor
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	return w.compressor.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.compressor.Write([]byte(s))
+}
+
+func (w *compressWriter) Flush() {
+	if err := w.compressor.Flush(); err != nil {
+		slog.Error("Failed to flush compressed stream", "error", err)
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to set its
+// write deadline
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// StreamCompression negotiates gzip or deflate via Accept-Encoding and
+// compresses streamed responses frame by frame.
+func StreamCompression(config *CompressionConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if config == nil || !config.Enabled {
+			ctx.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(ctx.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			ctx.Next()
+			return
+		}
+
+		compressor, err := newCompressor(encoding, ctx.Writer, config.Level)
+		if err != nil {
+			slog.Error("Failed to create stream compressor", "encoding", encoding, "error", err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Content-Encoding", encoding)
+		ctx.Header("Vary", "Accept-Encoding")
+		ctx.Writer.Header().Del("Content-Length")
+
+		ctx.Writer = &compressWriter{ResponseWriter: ctx.Writer, compressor: compressor}
+		defer func() {
+			if err := compressor.Close(); err != nil {
+				slog.Error("Failed to close stream compressor", "encoding", encoding, "error", err)
+			}
+		}()
+
+		ctx.Next()
+	}
+}
+
+func newCompressor(encoding string, w io.Writer, level int) (streamCompressor, error) {
+	switch encoding {
+	case encodingGzip:
+		return gzip.NewWriterLevel(w, level)
+	case encodingDeflate:
+		return flate.NewWriter(w, level)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring the higher q-value and gzip on ties.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if qValue, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(qValue, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			name = encodingGzip
+		}
+
+		if name != encodingGzip && name != encodingDeflate || q <= 0 {
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && name == encodingGzip) {
+			best, bestQ = name, q
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStreamingServer(t *testing.T, config *CompressionConfig, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.GET("/stream", SSEHeadersMiddleware(), StreamCompression(config), func(ctx *gin.Context) {
+		ctx.SSEvent("chunk", "first")
+		ctx.Writer.Flush()
+
+		<-release
+
+		ctx.SSEvent("chunk", "second")
+		ctx.Writer.Flush()
+	})
+
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func requestStream(t *testing.T, url, acceptEncoding string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	// A custom transport prevents net/http from transparently decompressing gzip
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamCompression_GzipFlushesFramesIncrementally(t *testing.T) {
+	release := make(chan struct{})
+	server := newStreamingServer(t, &CompressionConfig{Enabled: true, Level: gzip.DefaultCompression}, release)
+
+	resp := requestStream(t, server.URL+"/stream", "gzip")
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	gzipReader, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	reader := bufio.NewReader(gzipReader)
+
+	// The first event must be readable while the handler is still blocked
+	firstCh := make(chan string, 1)
+	go func() { firstCh <- readEvent(t, reader) }()
+	select {
+	case first := <-firstCh:
+		assert.Equal(t, "event:chunk\ndata:first\n", first)
+	case <-time.After(2 * time.Second):
+		t.Fatal("first compressed event was buffered until the end of the stream")
+	}
+
+	close(release)
+	assert.Equal(t, "event:chunk\ndata:second\n", readEvent(t, reader))
+
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+}
+
+func TestStreamCompression_Deflate(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	server := newStreamingServer(t, &CompressionConfig{Enabled: true, Level: flate.BestSpeed}, release)
+
+	resp := requestStream(t, server.URL+"/stream", "deflate")
+	assert.Equal(t, "deflate", resp.Header.Get("Content-Encoding"))
+
+	body, err := io.ReadAll(flate.NewReader(resp.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "event:chunk\ndata:first\n\nevent:chunk\ndata:second\n\n", string(body))
+}
+
+func TestStreamCompression_Disabled(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	server := newStreamingServer(t, &CompressionConfig{Enabled: false}, release)
+
+	resp := requestStream(t, server.URL+"/stream", "gzip")
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "event:chunk\ndata:first\n\nevent:chunk\ndata:second\n\n", string(body))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br, *", "gzip"},
+		{"GZIP;q=0.8", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateEncoding(tt.header))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/nzb3/diploma/search-service/internal/configurator"
 )
@@ -13,6 +14,43 @@ type AuthConfig struct {
 	Realm        string `yaml:"realm" mapstructure:"realm" validate:"required"`
 	ClientID     string `yaml:"client_id" mapstructure:"client_id" validate:"required"`
 	ClientSecret string `yaml:"client_secret" mapstructure:"client_secret" validate:"required"`
+	// TenantClaim is the token claim naming the tenant of the user
+	TenantClaim string `yaml:"tenant_claim" mapstructure:"tenant_claim"`
+	// Issuer is the iss claim tokens must carry. Keycloak issues tokens for the
+	// URL clients reach it at, so it defaults to the realm at Host and Port only
+	// when clients use the same address.
+	Issuer string `yaml:"issuer" mapstructure:"issuer"`
+	// Audience is the aud claim tokens must carry, ClientID when unset. The
+	// clients of the frontend need an audience mapper adding it in Keycloak.
+	Audience string `yaml:"audience" mapstructure:"audience"`
+	// JWKSRefreshInterval is how long the signing keys of the realm are cached
+	JWKSRefreshInterval time.Duration `yaml:"jwks_refresh_interval" mapstructure:"jwks_refresh_interval" validate:"min=0"`
+}
+
+// GetRealmURL returns the URL of the realm at Host and Port
+func (c *AuthConfig) GetRealmURL() string {
+	return fmt.Sprintf("%s/realms/%s", c.GetKeycloakURL(), c.Realm)
+}
+
+// GetJWKSURL returns the URL the signing keys of the realm are served at
+func (c *AuthConfig) GetJWKSURL() string {
+	return c.GetRealmURL() + "/protocol/openid-connect/certs"
+}
+
+// GetIssuer returns the issuer tokens are validated against
+func (c *AuthConfig) GetIssuer() string {
+	if c.Issuer != "" {
+		return c.Issuer
+	}
+	return c.GetRealmURL()
+}
+
+// GetAudience returns the audience tokens are validated against
+func (c *AuthConfig) GetAudience() string {
+	if c.Audience != "" {
+		return c.Audience
+	}
+	return c.ClientID
 }
 
 // GetKeycloakURL constructs the full Keycloak URL
@@ -22,21 +60,8 @@ func (c *AuthConfig) GetKeycloakURL() string {
 
 // NewAuthConfig loads authentication configuration from config file and environment variables
 func NewAuthConfig() (*AuthConfig, error) {
-	// Set defaults
-	setDefaults()
-
-	config := new(AuthConfig)
-	config.Realm = configurator.GetString("auth.realm")
-	config.ClientID = configurator.GetString("auth.client_id")
-	config.ClientSecret = configurator.GetString("auth.client_secret")
-	config.Host = configurator.GetString("auth.host")
-	config.Port = configurator.GetString("auth.port")
-
-	return config, nil
-}
-
-// setDefaults sets default values for authentication configuration
-func setDefaults() {
-	// Note: This is a placeholder. In practice, we'd use viper.SetDefault
-	// The actual defaults are set in the configurator package
+	return configurator.LoadKeys("auth", AuthConfig{
+		TenantClaim:         "tenant_id",
+		JWKSRefreshInterval: 15 * time.Minute,
+	})
 }
@@ -0,0 +1,225 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minJWKSRefreshInterval limits the refreshes forced by unknown key IDs, so
+// that tokens with made up key IDs can't flood Keycloak with JWKS requests
+const minJWKSRefreshInterval = 10 * time.Second
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// jwk is a JSON Web Key as served by the JWKS endpoint of a Keycloak realm
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	// RSA keys
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet caches the signing keys of the realm. The keys are fetched again once
+// they are older than refreshInterval, and early for a key ID the set doesn't
+// know, which is how Keycloak rotating its keys shows up.
+type keySet struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+
+	// refreshMu lets one request fetch the keys while the others wait for them
+	refreshMu sync.Mutex
+	// lastForced is when an unknown key ID last refreshed the keys, guarded by refreshMu
+	lastForced time.Time
+}
+
+func newKeySet(url string, refreshInterval time.Duration) *keySet {
+	return &keySet{
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// key returns the public key with the key ID, refreshing the keys when they are
+// stale, or once when the key ID is unknown
+func (s *keySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, fetchedAt, ok := s.cached(kid)
+	if ok && s.now().Sub(fetchedAt) < s.refreshInterval {
+		return key, nil
+	}
+
+	// A failed refresh keeps the cached keys, so that a short Keycloak outage
+	// doesn't reject tokens signed with known keys
+	if err := s.refresh(ctx, fetchedAt, !ok); err != nil {
+		if ok {
+			slog.WarnContext(ctx, "Failed to refresh JWKS, using cached keys", "error", err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	if key, _, ok := s.cached(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: kid %q", errUnknownKey, kid)
+}
+
+func (s *keySet) cached(kid string) (crypto.PublicKey, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[kid]
+	return key, s.fetchedAt, ok
+}
+
+// refresh fetches the keys unless another request did since seen. Forced
+// refreshes for unknown key IDs happen at most every minJWKSRefreshInterval.
+func (s *keySet) refresh(ctx context.Context, seen time.Time, forced bool) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	fetchedAt := s.fetchedAt
+	s.mu.RUnlock()
+
+	if fetchedAt.After(seen) {
+		return nil
+	}
+	if forced && !fetchedAt.IsZero() {
+		if s.now().Sub(s.lastForced) < minJWKSRefreshInterval {
+			return nil
+		}
+		s.lastForced = s.now()
+	}
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = s.now()
+	s.mu.Unlock()
+
+	slog.DebugContext(ctx, "Fetched JWKS", "keys", len(keys))
+	return nil
+}
+
+func (s *keySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	const op = "keySet.fetch"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: JWKS request failed with status code %d", op, resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		// Keycloak also publishes encryption keys, which never sign tokens
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			slog.WarnContext(ctx, "Skipping invalid JWK", "op", op, "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes the RSA or EC public key of the JWK
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -1,27 +1,38 @@
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"log/slog"
-	"net/http/httputil"
+	"mime"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// maxLoggedBodyBytes bounds the part of a request body read for the debug log,
+// the log handler truncates it further
+const maxLoggedBodyBytes = 4096
+
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 
-		if c.Request.Body != nil {
-			if dump, err := httputil.DumpRequest(c.Request, true); err == nil {
-				slog.Debug("Incoming request", "dump", string(dump))
-			}
+		if slog.Default().Enabled(c, slog.LevelDebug) {
+			slog.DebugContext(c, "Incoming request",
+				"method", c.Request.Method,
+				"url", RedactPath(c.Request.URL.RequestURI()),
+				"headers", RedactHeaders(c.Request.Header),
+				"body", peekBody(c.Request),
+			)
 		}
 
 		c.Next()
 
-		slog.Info("Request processed",
+		slog.InfoContext(c, "Request processed",
 			"path", path,
 			"status", c.Writer.Status(),
 			"duration", time.Since(start),
@@ -30,3 +41,31 @@ func RequestLogger() gin.HandlerFunc {
 		)
 	}
 }
+
+// peekBody returns the start of a textual request body, leaving the body
+// intact for the handlers. Uploaded files are not logged.
+func peekBody(req *http.Request) string {
+	if req.Body == nil || req.Body == http.NoBody || !isTextual(req.Header.Get("Content-Type")) {
+		return ""
+	}
+
+	head, err := io.ReadAll(io.LimitReader(req.Body, maxLoggedBodyBytes))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	if err != nil {
+		return ""
+	}
+	return string(head)
+}
+
+func isTextual(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		mediaType == "application/x-www-form-urlencoded"
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+)
+
+// RateLimitConfig holds the per-user token bucket settings
+type RateLimitConfig struct {
+	Enabled           bool          `yaml:"enabled" mapstructure:"enabled"`
+	RequestsPerMinute int           `yaml:"requests_per_minute" mapstructure:"requests_per_minute" validate:"min=1"`
+	Burst             int           `yaml:"burst" mapstructure:"burst" validate:"min=1"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout" validate:"min=0"`
+	CleanupInterval   time.Duration `yaml:"cleanup_interval" mapstructure:"cleanup_interval" validate:"min=0"`
+}
+
+// NewRateLimitConfig loads rate limit configuration from config file and environment variables
+func NewRateLimitConfig() (*RateLimitConfig, error) {
+	return configurator.LoadKeys("rate_limit", RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 30,
+		Burst:             10,
+		IdleTimeout:       10 * time.Minute,
+		CleanupInterval:   time.Minute,
+	})
+}
+
+// RateLimitStore keeps the token buckets. Allow takes a token from the bucket of
+// key and, when the bucket is empty, reports how long until the next one.
+// The in-memory store only limits a single instance, a shared store such as
+// Redis can implement the same interface.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// MemoryRateLimitStore is a RateLimitStore keeping the buckets in process memory
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+
+	ratePerSecond float64
+	burst         float64
+	idleTimeout   time.Duration
+	now           func() time.Time
+}
+
+func NewMemoryRateLimitStore(config *RateLimitConfig) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:       make(map[string]*tokenBucket),
+		ratePerSecond: float64(config.RequestsPerMinute) / 60,
+		burst:         float64(config.Burst),
+		idleTimeout:   config.IdleTimeout,
+		now:           time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Allow(_ context.Context, key string) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: s.burst, lastSeen: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastSeen).Seconds()
+	bucket.tokens = math.Min(s.burst, bucket.tokens+elapsed*s.ratePerSecond)
+	bucket.lastSeen = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+
+	wait := (1 - bucket.tokens) / s.ratePerSecond
+	return false, time.Duration(wait * float64(time.Second)), nil
+}
+
+// Cleanup removes the buckets not used for longer than the idle timeout,
+// they would be full again by now anyway
+func (s *MemoryRateLimitStore) Cleanup() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	removed := 0
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.lastSeen) > s.idleTimeout {
+			delete(s.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Run cleans up idle buckets every interval until ctx is done
+func (s *MemoryRateLimitStore) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.Cleanup(); removed > 0 {
+				slog.Debug("Removed idle rate limit buckets", "count", removed)
+			}
+		}
+	}
+}
+
+// RateLimit limits the requests of each authenticated user with a token bucket.
+// It must run after Authenticate; requests without a user are keyed by client IP.
+// Rejected requests get 429 with a Retry-After header in whole seconds.
+func RateLimit(store RateLimitStore) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, ok := GetUserID(ctx)
+		if !ok {
+			key = "ip:" + ctx.ClientIP()
+		}
+
+		allowed, retryAfter, err := store.Allow(ctx, key)
+		if err != nil {
+			// Failing open keeps the API usable when a shared store is down
+			slog.ErrorContext(ctx, "Rate limit check failed", "error", err)
+			ctx.Next()
+			return
+		}
+
+		if !allowed {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			slog.WarnContext(ctx, "Rate limit exceeded", "key", key, "retry_after", seconds)
+			ctx.Header("Retry-After", strconv.Itoa(seconds))
+			controllers.RespondWithError(ctx, http.StatusTooManyRequests, controllers.CodeRateLimited, "rate limit exceeded")
+			return
+		}
+
+		ctx.Next()
+	}
+}
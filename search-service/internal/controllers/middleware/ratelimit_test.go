@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestRateLimitStore(rpm, burst int) (*MemoryRateLimitStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewMemoryRateLimitStore(&RateLimitConfig{
+		RequestsPerMinute: rpm,
+		Burst:             burst,
+		IdleTimeout:       time.Minute,
+	})
+	store.now = clock.Now
+	return store, clock
+}
+
+func TestMemoryRateLimitStore_Allow(t *testing.T) {
+	store, clock := newTestRateLimitStore(60, 2)
+	ctx := context.Background()
+
+	for range 2 {
+		allowed, _, err := store.Allow(ctx, "user")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter, err := store.Allow(ctx, "user")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Buckets are per key
+	allowed, _, _ = store.Allow(ctx, "other")
+	assert.True(t, allowed)
+
+	clock.now = clock.now.Add(time.Second)
+	allowed, _, _ = store.Allow(ctx, "user")
+	assert.True(t, allowed)
+}
+
+func TestMemoryRateLimitStore_Cleanup(t *testing.T) {
+	store, clock := newTestRateLimitStore(60, 1)
+	ctx := context.Background()
+
+	_, _, _ = store.Allow(ctx, "idle")
+	clock.now = clock.now.Add(30 * time.Second)
+	_, _, _ = store.Allow(ctx, "active")
+	clock.now = clock.now.Add(45 * time.Second)
+
+	assert.Equal(t, 1, store.Cleanup())
+	assert.Contains(t, store.buckets, "active")
+	assert.NotContains(t, store.buckets, "idle")
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Allow(context.Context, string) (bool, time.Duration, error) {
+	return false, 0, errors.New("store unavailable")
+}
+
+func newRateLimitEngine(store RateLimitStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(func(ctx *gin.Context) {
+		if userID := ctx.GetHeader("X-User"); userID != "" {
+			ctx.Set(UserIDKey, userID)
+		}
+	})
+	engine.GET("/", RateLimit(store), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	return engine
+}
+
+func doRateLimitedRequest(engine *gin.Engine, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", userID)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	store, _ := newTestRateLimitStore(2, 1)
+	engine := newRateLimitEngine(store)
+
+	assert.Equal(t, http.StatusNoContent, doRateLimitedRequest(engine, "alice").Code)
+
+	w := doRateLimitedRequest(engine, "alice")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"code":"RATE_LIMITED","message":"rate limit exceeded"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNoContent, doRateLimitedRequest(engine, "bob").Code)
+}
+
+func TestRateLimit_StoreErrorFailsOpen(t *testing.T) {
+	engine := newRateLimitEngine(failingRateLimitStore{})
+
+	assert.Equal(t, http.StatusNoContent, doRateLimitedRequest(engine, "alice").Code)
+}
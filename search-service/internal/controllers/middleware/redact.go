@@ -0,0 +1,250 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMaxLogValueBytes is the length log values are truncated to unless configured
+const DefaultMaxLogValueBytes = 1024
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the log attributes, headers and query parameters whose
+// values are masked, compared in lower case
+var sensitiveKeys = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"auth_token":          true,
+	"access_token":        true,
+	"refresh_token":       true,
+	"id_token":            true,
+	"password":            true,
+	"client_secret":       true,
+	"api_key":             true,
+	"x-api-key":           true,
+}
+
+func isSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// redactHandler masks sensitive attributes and truncates long values, so that
+// contents of resources, answers and payloads don't flood the logs
+type redactHandler struct {
+	slog.Handler
+	maxValueBytes int
+}
+
+// NewRedactHandler wraps handler so that values of sensitive attributes are
+// masked and values longer than maxValueBytes are truncated. Structs too long
+// to log are logged field by field, slices and maps by their length.
+func NewRedactHandler(handler slog.Handler, maxValueBytes int) slog.Handler {
+	if maxValueBytes <= 0 {
+		maxValueBytes = DefaultMaxLogValueBytes
+	}
+	return redactHandler{Handler: handler, maxValueBytes: maxValueBytes}
+}
+
+func (h redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	redactedRecord := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redactedRecord.AddAttrs(h.redact(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redactedRecord)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redactedAttrs[i] = h.redact(attr)
+	}
+	return redactHandler{Handler: h.Handler.WithAttrs(redactedAttrs), maxValueBytes: h.maxValueBytes}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{Handler: h.Handler.WithGroup(name), maxValueBytes: h.maxValueBytes}
+}
+
+func (h redactHandler) redact(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if isSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, truncate(attr.Value.String(), h.maxValueBytes))
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		attrs := make([]any, len(group))
+		for i, groupAttr := range group {
+			attrs[i] = h.redact(groupAttr)
+		}
+		return slog.Group(attr.Key, attrs...)
+	case slog.KindAny:
+		return slog.Attr{Key: attr.Key, Value: h.redactAny(attr.Value)}
+	default:
+		return attr
+	}
+}
+
+func (h redactHandler) redactAny(value slog.Value) slog.Value {
+	switch v := value.Any().(type) {
+	case []byte:
+		return slog.StringValue(fmt.Sprintf("<%d bytes>", len(v)))
+	case http.Header:
+		return slog.AnyValue(RedactHeaders(v))
+	case error:
+		if message := v.Error(); len(message) > h.maxValueBytes {
+			return slog.StringValue(truncate(message, h.maxValueBytes))
+		}
+		return value
+	default:
+		// Values are measured before they are formatted, so that a large one
+		// isn't formatted whole only to be cut
+		if rv := reflect.ValueOf(v); formattedSize(rv, h.maxValueBytes) > h.maxValueBytes {
+			return h.redactLarge(rv)
+		}
+		// Only values too long to log are replaced, others keep their encoding
+		if formatted := fmt.Sprintf("%+v", v); len(formatted) > h.maxValueBytes {
+			return slog.StringValue(truncate(formatted, h.maxValueBytes))
+		}
+		return value
+	}
+}
+
+// redactLarge stands in for a value too long to log: a struct becomes a group
+// of its exported fields, each redacted in turn, and a slice or map its length
+func (h redactHandler) redactLarge(v reflect.Value) slog.Value {
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		attrs := make([]slog.Attr, 0, v.NumField())
+		for i := range v.NumField() {
+			if field := v.Type().Field(i); field.IsExported() {
+				attrs = append(attrs, h.redact(slog.Any(field.Name, v.Field(i).Interface())))
+			}
+		}
+		return slog.GroupValue(attrs...)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return slog.StringValue(fmt.Sprintf("<%d items>", v.Len()))
+	case reflect.String:
+		return slog.StringValue(truncate(v.String(), h.maxValueBytes))
+	default:
+		return slog.StringValue(truncate(fmt.Sprintf("%+v", v), h.maxValueBytes))
+	}
+}
+
+// formattedSize estimates the length of v formatted with %+v from its strings
+// and the number of its elements, walking no further once it is over limit
+func formattedSize(v reflect.Value, limit int) int {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return len("<nil>")
+	case reflect.String:
+		return v.Len()
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() || limit <= 0 {
+			return len("<nil>")
+		}
+		return 1 + formattedSize(v.Elem(), limit-1)
+	case reflect.Struct:
+		size := len("{}")
+		for i := 0; i < v.NumField() && size <= limit; i++ {
+			size += len(v.Type().Field(i).Name) + len(": ") + formattedSize(v.Field(i), limit-size)
+		}
+		return size
+	case reflect.Slice, reflect.Array:
+		size := len("[]")
+		for i := 0; i < v.Len() && size <= limit; i++ {
+			size += len(" ") + formattedSize(v.Index(i), limit-size)
+		}
+		return size
+	case reflect.Map:
+		size := len("map[]")
+		for iter := v.MapRange(); size <= limit && iter.Next(); {
+			size += len(": ") + formattedSize(iter.Key(), limit-size) + formattedSize(iter.Value(), limit-size)
+		}
+		return size
+	default:
+		return 1
+	}
+}
+
+// truncate cuts s to at most maxBytes on a rune boundary, noting how much was cut
+func truncate(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	n := maxBytes
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s...(%d more bytes)", s[:n], len(s)-n)
+}
+
+// RedactHeaders returns a copy of the headers with sensitive values masked
+func RedactHeaders(headers http.Header) http.Header {
+	redactedHeaders := headers.Clone()
+	for key := range redactedHeaders {
+		if isSensitive(key) {
+			redactedHeaders[key] = []string{redacted}
+		}
+	}
+	return redactedHeaders
+}
+
+// RedactPath masks the sensitive query parameters of a request path, such as
+// the access token SSE clients pass as auth_token
+func RedactPath(path string) string {
+	base, rawQuery, ok := strings.Cut(path, "?")
+	if !ok {
+		return path
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// An unparsable query may still carry a token
+		return base + "?" + redacted
+	}
+	for key := range query {
+		if isSensitive(key) {
+			query[key] = []string{redacted}
+		}
+	}
+	return base + "?" + query.Encode()
+}
+
+// AccessLogFormatter formats gin's access log lines like its default
+// formatter, without colors and with the query redacted
+func AccessLogFormatter(params gin.LogFormatterParams) string {
+	if params.Latency > time.Minute {
+		params.Latency = params.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		params.StatusCode,
+		params.Latency,
+		params.ClientIP,
+		params.Method,
+		RedactPath(params.Path),
+		params.ErrorMessage,
+	)
+}
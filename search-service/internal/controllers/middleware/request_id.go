@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader carries the request ID from and back to clients and proxies
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the context key of the request ID
+	RequestIDKey string = "request_id"
+)
+
+// requestIDRe limits accepted request IDs to short tokens safe to log
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID propagates the X-Request-ID header of the request, or a generated
+// one when it is missing or malformed. The ID is echoed in the response and
+// stored in the context, where the log handler picks it up.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(RequestIDHeader)
+		if !requestIDRe.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Header(RequestIDHeader, requestID)
+		ctx.Set(RequestIDKey, requestID)
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), RequestIDKey, requestID))
+
+		ctx.Next()
+	}
+}
+
+func GetRequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(RequestIDKey).(string)
+	return id, ok
+}
+
+// requestIDHandler adds the request ID of the context to every record
+type requestIDHandler struct {
+	slog.Handler
+}
+
+// NewRequestIDHandler wraps handler so that records logged with a request
+// context carry its request ID
+func NewRequestIDHandler(handler slog.Handler) slog.Handler {
+	return requestIDHandler{Handler: handler}
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if requestID, ok := GetRequestID(ctx); ok {
+			record.AddAttrs(slog.String(RequestIDKey, requestID))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{Handler: h.Handler.WithGroup(name)}
+}
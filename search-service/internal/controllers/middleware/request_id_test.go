@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRequestIDEngine(logger *slog.Logger) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(RequestID())
+	engine.GET("/", func(ctx *gin.Context) {
+		logger.InfoContext(ctx, "Handling request", "op", "Test.Handler")
+		ctx.Status(http.StatusNoContent)
+	})
+	return engine
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "propagates incoming", incoming: "req-123.abc", keep: true},
+		{name: "generates when missing", incoming: ""},
+		{name: "replaces malformed", incoming: "bad id\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newRequestIDEngine(slog.New(slog.DiscardHandler))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, got)
+				return
+			}
+			_, err := uuid.Parse(got)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestRequestIDHandler_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRequestIDHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+	engine := newRequestIDEngine(logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "req-42", record[RequestIDKey])
+	assert.Equal(t, "Test.Handler", record["op"])
+	assert.Equal(t, "test", record["component"])
+}
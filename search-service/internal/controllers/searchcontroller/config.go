@@ -0,0 +1,47 @@
+package searchcontroller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// Config holds configuration of the ask and search endpoints
+type Config struct {
+	// HeartbeatInterval is how long an answer stream may wait for its first
+	// chunk before a keepalive comment is sent, so that proxies don't close
+	// the idle connection. Zero disables heartbeats.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" mapstructure:"heartbeat_interval" validate:"min=0"`
+	// WriteTimeout bounds how long each event of a stream may take to reach
+	// the client. The write deadline moves past every event, so an answer
+	// may stream for longer than the server write timeout while a stalled
+	// client still fails the stream. Zero keeps the server write timeout.
+	WriteTimeout time.Duration `yaml:"write_timeout" mapstructure:"write_timeout" validate:"min=0"`
+	// Question is loaded from its own section
+	Question QuestionConfig `yaml:"-" mapstructure:"-"`
+	// References is loaded from its own section
+	References ReferencesConfig `yaml:"-" mapstructure:"-"`
+}
+
+// NewConfig loads answer stream, question and references configuration from config file
+func NewConfig() (*Config, error) {
+	config, err := configurator.ParseConfig[Config]("stream")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stream config: %w", err)
+	}
+
+	question, err := configurator.ParseConfig[QuestionConfig]("question")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse question config: %w", err)
+	}
+	config.Question = *question
+
+	references, err := configurator.ParseConfig[ReferencesConfig]("references")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse references config: %w", err)
+	}
+	config.References = *references
+
+	return config, nil
+}
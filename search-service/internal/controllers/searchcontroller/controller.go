@@ -3,11 +3,15 @@ package searchcontroller
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -15,130 +19,433 @@ import (
 	"github.com/nzb3/diploma/search-service/internal/controllers"
 	"github.com/nzb3/diploma/search-service/internal/controllers/middleware"
 	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
 )
 
+// statusClientClosedRequest is the non-standard status recorded for requests
+// the client abandoned; it is never seen by the client itself.
+const statusClientClosedRequest = 499
+
+// errShuttingDown is the cancellation cause of the processes still active when
+// the server shuts down
+var errShuttingDown = errors.New("server is shutting down")
+
 type searchService interface {
-	GetAnswer(ctx context.Context, question string) (models.SearchResult, error)
-	GetAnswerStream(ctx context.Context, question string, numReferences int) (<-chan models.SearchResult, <-chan []models.Reference, <-chan []byte, <-chan error)
-	SemanticSearch(ctx context.Context, query string) ([]models.Reference, error)
+	GetAnswer(ctx context.Context, question string, opts ...searchservice.SearchOption) (models.SearchResult, error)
+	GetAnswerStream(ctx context.Context, question string, opts ...searchservice.SearchOption) (<-chan models.SearchResult, <-chan []models.Reference, <-chan []byte, <-chan error)
+	SemanticSearch(ctx context.Context, query string, opts ...searchservice.SearchOption) ([]models.Reference, error)
+	GetResourceChunks(ctx context.Context, resourceID uuid.UUID, limit, offset int) (models.ChunkPage, error)
+	GetIndexStats(ctx context.Context) (models.IndexStats, error)
+	FindSimilarResources(ctx context.Context, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error)
 }
 
 type Controller struct {
-	searchService  searchService
-	activeRequests sync.Map
+	searchService     searchService
+	compressionConfig *middleware.CompressionConfig
+	config            Config
+	askMiddleware     []gin.HandlerFunc
+	activeRequests    sync.Map
+	// processesMu guards draining, so that no process is added to processes
+	// once Drain waits for them
+	processesMu sync.Mutex
+	draining    bool
+	processes   sync.WaitGroup
 }
 
-func NewController(ss searchService) *Controller {
-	return &Controller{
-		searchService: ss,
+// NewController creates the search controller, askMiddleware runs before every
+// route of the /ask group but stream cancellation, e.g. to rate limit the
+// expensive answer generation. A nil config disables stream heartbeats.
+func NewController(ss searchService, compressionConfig *middleware.CompressionConfig, config *Config, askMiddleware ...gin.HandlerFunc) *Controller {
+	c := &Controller{
+		searchService:     ss,
+		compressionConfig: compressionConfig,
+		askMiddleware:     askMiddleware,
 	}
+	if config != nil {
+		c.config = *config
+	}
+	return c
 }
 
 func (c *Controller) RegisterRoutes(router *gin.RouterGroup) {
 	slog.Debug("Registering routes")
 	askGroup := router.Group("/ask", middleware.RequestLogger())
 	{
-		askGroup.POST("/", middleware.SSEHeadersMiddleware(), c.createProcessMiddleware(), c.Ask())
-		streamGroup := askGroup.Group("/stream")
+		// A rate limited user must still be able to stop their own stream
+		askGroup.DELETE("/stream/cancel/:process_id", c.CancelProcess())
+
+		limitedGroup := askGroup.Group("", c.askMiddleware...)
+		limitedGroup.POST("/", middleware.SSEHeadersMiddleware(), c.createProcessMiddleware(), c.Ask())
+		streamGroup := limitedGroup.Group("/stream")
 		{
-			streamGroup.GET("/", middleware.SSEHeadersMiddleware(), c.createProcessMiddleware(), c.AskStream())
-			streamGroup.DELETE("/cancel/:process_id", c.CancelProcess())
+			streamGroup.GET("/",
+				middleware.SSEHeadersMiddleware(),
+				middleware.StreamCompression(c.compressionConfig),
+				c.createProcessMiddleware(),
+				c.AskStream(),
+			)
 		}
+		limitedGroup.GET("/ws", c.createProcessMiddleware(), c.AskWebSocket())
 	}
 
 	searchGroup := router.Group("/search")
 	{
 		searchGroup.GET("/", c.SemanticSearch())
 	}
+
+	resourcesGroup := router.Group("/resources")
+	{
+		resourcesGroup.GET("/stats", c.GetIndexStats())
+		resourcesGroup.GET("/:id/chunks", c.GetResourceChunks())
+		resourcesGroup.GET("/:id/similar", c.GetSimilarResources())
+	}
 }
 
 type AskRequest struct {
 	Question string `json:"question" binding:"required"`
+	// Generate set to false only returns the references, without generating an answer
+	Generate *bool `json:"generate,omitempty"`
+	// ExpandQuery also retrieves for model-written variants of the question
+	ExpandQuery bool `json:"expand_query,omitempty"`
+	// SystemPrompt is appended to the configured persona of the answer
+	SystemPrompt string `json:"system_prompt,omitempty"`
+}
+
+// SearchEnvelope is shared by the responses of every search endpoint, so that
+// clients read the query, references, latency and answer the same way
+type SearchEnvelope struct {
+	Query      string             `json:"query"`
+	References []models.Reference `json:"references"`
+	// TookMs is how long the search took in milliseconds
+	TookMs int64 `json:"took_ms"`
+	// Answer is only set when one was generated
+	Answer *string `json:"answer,omitempty"`
+}
+
+// newSearchEnvelope wraps the references found for the query since started
+func newSearchEnvelope(query string, references []models.Reference, started time.Time) SearchEnvelope {
+	if references == nil {
+		references = []models.Reference{}
+	}
+	return SearchEnvelope{
+		Query:      query,
+		References: references,
+		TookMs:     time.Since(started).Milliseconds(),
+	}
 }
 
 type AskResponse struct {
+	SearchEnvelope
+	// Result is the former shape of the response, kept for compatibility
 	Result models.SearchResult `json:"result"`
 }
 
 func (c *Controller) Ask() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		slog.Info("Handling Ask request")
+		slog.InfoContext(ctx, "Handling Ask request")
 		var req AskRequest
 		if err := ctx.ShouldBindJSON(&req); err != nil {
-			slog.Error("Error binding request", "error", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			slog.ErrorContext(ctx, "Error binding request", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		if !c.checkQuestion(ctx, req.Question) {
 			return
 		}
 
-		slog.Debug("Processing question", "question", req.Question)
-		searchResult, err := c.searchService.GetAnswer(ctx, req.Question)
-
+		var opts []searchservice.SearchOption
+		generate := req.Generate == nil || *req.Generate
+		if !generate {
+			opts = append(opts, searchservice.WithoutGeneration())
+		}
+		if req.ExpandQuery {
+			opts = append(opts, searchservice.WithQueryExpansion(true))
+		}
+		if req.SystemPrompt != "" {
+			opts = append(opts, searchservice.WithSystemPrompt(req.SystemPrompt))
+		}
+		cacheOpts, err := getCacheOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid no_cache parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, cacheOpts...)
+
+		slog.DebugContext(ctx, "Processing question", "question", req.Question, "generate", generate, "expand_query", req.ExpandQuery)
+		started := time.Now()
+		searchResult, err := c.searchService.GetAnswer(ctx, req.Question, opts...)
+		if errors.Is(err, models.ErrSearchCancelled) {
+			slog.InfoContext(ctx, "Ask request cancelled by client", "question", req.Question)
+			ctx.AbortWithStatus(statusClientClosedRequest)
+			return
+		}
 		if err != nil {
-			slog.Error("Error getting answer", "error", err, "question", req.Question)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			slog.ErrorContext(ctx, "Error getting answer", "error", err, "question", req.Question)
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
-		slog.Info("Successfully processed request", "question", req.Question)
-		ctx.JSON(http.StatusOK, AskResponse{Result: searchResult})
+		slog.InfoContext(ctx, "Successfully processed request", "question", req.Question)
+		response := AskResponse{
+			SearchEnvelope: newSearchEnvelope(req.Question, searchResult.References, started),
+			Result:         searchResult,
+		}
+		if !searchResult.GenerationSkipped {
+			response.Answer = &searchResult.Answer
+		}
+		ctx.JSON(http.StatusOK, response)
 	}
 }
 
 func (c *Controller) AskStream() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		slog.Info("Initializing stream request")
-		question := ctx.Query("question")
-		if question == "" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "question is required"})
+		slog.InfoContext(ctx, "Initializing stream request")
+		question, numReferences, opts, err := getAskStreamParams(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid stream request", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
 			return
 		}
-
-		numReferences := 10
-		numReferencesStr := ctx.Query("num_references")
-		if numReferencesStr != "" {
-			var err error
-			numReferences, err = strconv.Atoi(numReferencesStr)
-			if err != nil {
-				slog.Error("Invalid num_references parameter", "error", err)
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid num_references parameter: must be an integer"})
-				return
-			}
+		if !c.checkQuestion(ctx, question) {
+			return
+		}
+		if !c.checkReferenceCount(ctx, "num_references", numReferences) {
+			return
 		}
 
-		slog.Info("Processing question", "question", question, "num_references", numReferences)
+		slog.InfoContext(ctx, "Processing question", "question", question, "num_references", numReferences)
 
 		processID, err := getProcessIDFromContext(ctx)
 		if err != nil {
-			slog.Error("Error getting process ID check createProcessMiddleware", "error", err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start process"})
+			slog.ErrorContext(ctx, "Error getting process ID check createProcessMiddleware", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, "failed to start process")
 			return
 		}
 
-		slog.Info("Starting stream processing",
+		slog.InfoContext(ctx, "Starting stream processing",
 			"process_id", processID,
 			"question", question,
 			"num_references", numReferences,
 			"client", ctx.ClientIP())
 
-		resultCh, referencesCh, chunkCh, errCh := c.searchService.GetAnswerStream(ctx, question, numReferences)
+		// The process context, so that cancelling the process stops the generation
+		stream := c.startAnswerStream(ctx.Request.Context(), question, opts...)
+		stream.startHeartbeat(c.config.HeartbeatInterval)
+		defer stream.stopHeartbeat()
+		events := sseWriter{ctx: ctx, writeTimeout: c.config.WriteTimeout}
 
 		ctx.Stream(func(w io.Writer) bool {
-			select {
-			case chunk := <-chunkCh:
-				return c.handleChunk(ctx, processID, chunk)
-			case references := <-referencesCh:
-				return c.handleReferences(ctx, processID, references)
-			case result := <-resultCh:
-				return c.handleResult(ctx, processID, result)
-			case err := <-errCh:
-				return c.handleError(ctx, processID, err)
-			case <-ctx.Done():
-				return c.handleCancellationEvent(ctx, processID, ctx.Err())
-			}
+			return c.nextStreamEvent(ctx, events, processID, stream)
 		})
 	}
 }
 
+// getAskStreamParams reads the question and the search options of a streamed
+// answer from the query. The returned error is meant for the client.
+func getAskStreamParams(ctx *gin.Context) (string, int, []searchservice.SearchOption, error) {
+	question := ctx.Query("question")
+	if question == "" {
+		return "", 0, nil, errors.New("question is required")
+	}
+
+	// Without num_references the storage retrieves its configured number of chunks
+	var numReferences int
+	var opts []searchservice.SearchOption
+	numReferencesStr := ctx.Query("num_references")
+	if numReferencesStr != "" {
+		var err error
+		numReferences, err = strconv.Atoi(numReferencesStr)
+		if err != nil {
+			return "", 0, nil, errors.New("Invalid num_references parameter: must be an integer")
+		}
+		opts = append(opts, searchservice.WithNumberOfReferences(numReferences))
+	}
+
+	// The answer context defaults to all the references
+	if contextSizeStr := ctx.Query("context_size"); contextSizeStr != "" {
+		contextSize, err := strconv.Atoi(contextSizeStr)
+		if err != nil || contextSize < 1 {
+			return "", 0, nil, errors.New("Invalid context_size parameter: must be a positive integer")
+		}
+		opts = append(opts, searchservice.WithContextSize(contextSize))
+	}
+
+	mmrOpts, err := getMMROptions(ctx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	opts = append(opts, mmrOpts...)
+
+	thresholdOpts, err := getScoreThresholdOptions(ctx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	opts = append(opts, thresholdOpts...)
+
+	generationOpts, err := getGenerationOptions(ctx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	opts = append(opts, generationOpts...)
+
+	if language := ctx.Query("language"); language != "" {
+		if !models.IsAnswerLanguage(language) {
+			return "", 0, nil, fmt.Errorf("invalid language parameter: must be one of %s",
+				strings.Join(models.AnswerLanguages, ", "))
+		}
+		opts = append(opts, searchservice.WithLanguage(language))
+	}
+
+	if expandStr := ctx.Query("expand_query"); expandStr != "" {
+		expand, err := strconv.ParseBool(expandStr)
+		if err != nil {
+			return "", 0, nil, errors.New("invalid expand_query parameter: must be a boolean")
+		}
+		opts = append(opts, searchservice.WithQueryExpansion(expand))
+	}
+
+	if systemPrompt := ctx.Query("system_prompt"); systemPrompt != "" {
+		opts = append(opts, searchservice.WithSystemPrompt(systemPrompt))
+	}
+
+	cacheOpts, err := getCacheOptions(ctx)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	opts = append(opts, cacheOpts...)
+
+	return question, numReferences, opts, nil
+}
+
+// getCacheOptions reads the optional "no_cache" query parameter bypassing
+// cached results
+func getCacheOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	noCacheStr := ctx.Query("no_cache")
+	if noCacheStr == "" {
+		return nil, nil
+	}
+
+	noCache, err := strconv.ParseBool(noCacheStr)
+	if err != nil {
+		return nil, errors.New("invalid no_cache parameter: must be a boolean")
+	}
+	if !noCache {
+		return nil, nil
+	}
+
+	return []searchservice.SearchOption{searchservice.WithoutCache()}, nil
+}
+
+// getScoreThresholdOptions reads the optional "score_threshold" query parameter
+// overriding the default threshold of the selected search mode.
+func getScoreThresholdOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	thresholdStr := ctx.Query("score_threshold")
+	if thresholdStr == "" {
+		return nil, nil
+	}
+
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		return nil, errors.New("invalid score_threshold parameter: must be a number between 0 and 1")
+	}
+
+	return []searchservice.SearchOption{searchservice.WithScoreThreshold(threshold)}, nil
+}
+
+// getHighlightOptions reads the optional "highlight" query parameter marking
+// the query terms in the returned references
+func getHighlightOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	highlightStr := ctx.Query("highlight")
+	if highlightStr == "" {
+		return nil, nil
+	}
+
+	highlight, err := strconv.ParseBool(highlightStr)
+	if err != nil {
+		return nil, errors.New("invalid highlight parameter: must be a boolean")
+	}
+
+	return []searchservice.SearchOption{searchservice.WithHighlight(highlight)}, nil
+}
+
+// getSummaryOptions reads the optional "summarize" query parameter adding a
+// one-line summary to the first references
+func getSummaryOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	summarizeStr := ctx.Query("summarize")
+	if summarizeStr == "" {
+		return nil, nil
+	}
+
+	summarize, err := strconv.ParseBool(summarizeStr)
+	if err != nil {
+		return nil, errors.New("invalid summarize parameter: must be a boolean")
+	}
+
+	return []searchservice.SearchOption{searchservice.WithSummaries(summarize)}, nil
+}
+
+// getRecencyOptions reads the optional "recency" query parameter favouring
+// recently created resources among the returned references
+func getRecencyOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	recencyStr := ctx.Query("recency")
+	if recencyStr == "" {
+		return nil, nil
+	}
+
+	recency, err := strconv.ParseBool(recencyStr)
+	if err != nil {
+		return nil, errors.New("invalid recency parameter: must be a boolean")
+	}
+	if !recency {
+		return nil, nil
+	}
+
+	return []searchservice.SearchOption{searchservice.WithRecencyBoost(0)}, nil
+}
+
+// getGenerationOptions reads the optional "temperature" and "max_tokens" query
+// parameters. Out of range values are clamped by the storage, only malformed
+// ones are rejected here.
+func getGenerationOptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	var opts []searchservice.SearchOption
+
+	if temperatureStr := ctx.Query("temperature"); temperatureStr != "" {
+		temperature, err := strconv.ParseFloat(temperatureStr, 64)
+		if err != nil || math.IsNaN(temperature) {
+			return nil, errors.New("invalid temperature parameter: must be a number")
+		}
+		opts = append(opts, searchservice.WithTemperature(temperature))
+	}
+
+	if maxTokensStr := ctx.Query("max_tokens"); maxTokensStr != "" {
+		maxTokens, err := strconv.Atoi(maxTokensStr)
+		if err != nil {
+			return nil, errors.New("invalid max_tokens parameter: must be an integer")
+		}
+		opts = append(opts, searchservice.WithMaxTokens(maxTokens))
+	}
+
+	return opts, nil
+}
+
+// getMMROptions reads the optional "mmr" query parameter, a lambda in [0, 1]
+// trading relevance (1) for diversity (0). MMR over-fetches candidates and
+// re-embeds them, so it adds latency to every search it is enabled for.
+func getMMROptions(ctx *gin.Context) ([]searchservice.SearchOption, error) {
+	mmrStr := ctx.Query("mmr")
+	if mmrStr == "" {
+		return nil, nil
+	}
+
+	lambda, err := strconv.ParseFloat(mmrStr, 64)
+	if err != nil || lambda < 0 || lambda > 1 {
+		return nil, errors.New("invalid mmr parameter: must be a number between 0 and 1")
+	}
+
+	return []searchservice.SearchOption{searchservice.WithMMR(lambda)}, nil
+}
+
 func getProcessIDFromContext(ctx *gin.Context) (uuid.UUID, error) {
 	value, ok := ctx.Get("process_id")
 	if !ok {
@@ -159,15 +466,26 @@ func getProcessIDFromContext(ctx *gin.Context) (uuid.UUID, error) {
 
 func (c *Controller) createProcessMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		c.processesMu.Lock()
+		if c.draining {
+			c.processesMu.Unlock()
+			c.respondWithError(ctx, http.StatusServiceUnavailable, controllers.CodeServiceUnavailable, errShuttingDown.Error())
+			return
+		}
+		c.processes.Add(1)
+		c.processesMu.Unlock()
+		defer c.processes.Done()
+
 		processID := uuid.New()
-		cancelCtx, cancel := context.WithCancel(ctx.Request.Context())
+		cancelCtx, cancel := context.WithCancelCause(ctx.Request.Context())
 		c.activeRequests.Store(processID, cancel)
+		defer c.cleanupProcess(processID)
 
 		ctx.Request = ctx.Request.WithContext(cancelCtx)
 
 		ctx.Set("process_id", processID)
 
-		slog.Debug("Created new process context",
+		slog.DebugContext(ctx, "Created new process context",
 			"process_id", processID,
 			"active_requests", c.activeRequestsCount(),
 		)
@@ -179,36 +497,80 @@ func (c *Controller) createProcessMiddleware() gin.HandlerFunc {
 func (c *Controller) cleanupProcess(processID uuid.UUID) {
 	if cancel, loaded := c.activeRequests.LoadAndDelete(processID); loaded {
 		slog.Debug("Cleaning up process", "process_id", processID)
-		cancel.(context.CancelFunc)()
+		cancel.(context.CancelCauseFunc)(nil)
 	}
 }
 
-func (c *Controller) handleReferences(ctx *gin.Context, processID uuid.UUID, references []models.Reference) bool {
+// Drain stops accepting new processes and waits for the active ones until ctx
+// is done, then cancels those left. Cancelled streams end with a cancelled
+// event instead of their connection being dropped. Drain returns once every
+// process handler returned.
+func (c *Controller) Drain(ctx context.Context) {
+	c.processesMu.Lock()
+	c.draining = true
+	c.processesMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.processes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+
+	slog.InfoContext(ctx, "Cancelling active processes on shutdown",
+		"active_requests", c.activeRequestsCount())
+	c.activeRequests.Range(func(_, cancel any) bool {
+		cancel.(context.CancelCauseFunc)(errShuttingDown)
+		return true
+	})
+	<-done
+}
+
+func (c *Controller) handleReferences(w streamWriter, processID uuid.UUID, references []models.Reference) bool {
 	slog.Debug("Processing reference",
 		"process_id", processID,
 		"references", references)
-	controllers.SendSSEEvent(ctx, "references", gin.H{
+	err := w.writeEvent("references", gin.H{
 		"process_id": processID,
 		"references": references,
 		"complete":   false,
 	})
-	return true
+	return err == nil
 }
 
-func (c *Controller) handleChunk(ctx *gin.Context, processID uuid.UUID, chunk []byte) bool {
+func (c *Controller) handleChunk(w streamWriter, processID uuid.UUID, chunk []byte) bool {
 	slog.Debug("Processing chunk", "process_id", processID, "chunk_size", len(chunk))
-	controllers.SendSSEEvent(ctx, "chunk", gin.H{
+	err := w.writeEvent("chunk", gin.H{
 		"process_id": processID.String(),
 		"content":    string(chunk),
 		"complete":   false,
 	})
+	return err == nil
+}
+
+// handleHeartbeat keeps the stream open while the answer is not flowing yet.
+// Transports without heartbeats ignore it.
+func (c *Controller) handleHeartbeat(ctx *gin.Context, w streamWriter, processID uuid.UUID) bool {
+	hw, ok := w.(heartbeatWriter)
+	if !ok {
+		return true
+	}
+	if err := hw.writeHeartbeat(); err != nil {
+		slog.Debug("Failed to send heartbeat", "process_id", processID, "error", err)
+		return false
+	}
 	return true
 }
 
-func (c *Controller) handleResult(ctx *gin.Context, processID uuid.UUID, result models.SearchResult) bool {
+func (c *Controller) handleResult(w streamWriter, processID uuid.UUID, result models.SearchResult) bool {
 	slog.Info("Finalizing stream processing", "process_id", processID)
 
-	controllers.SendSSEEvent(ctx, "complete", gin.H{
+	w.writeEvent("complete", gin.H{
 		"process_id": processID.String(),
 		"result":     result,
 		"complete":   true,
@@ -218,34 +580,41 @@ func (c *Controller) handleResult(ctx *gin.Context, processID uuid.UUID, result
 	return false
 }
 
-func (c *Controller) handleError(ctx *gin.Context, processID uuid.UUID, err error) bool {
-	slog.Error("Stream processing error", "process_id", processID, "error", err)
-
+func (c *Controller) handleError(ctx *gin.Context, w streamWriter, processID uuid.UUID, err error) bool {
 	if err == nil {
-		slog.Error("RECEIVED NIL ERROR")
+		slog.ErrorContext(ctx, "RECEIVED NIL ERROR")
 		return false
 	}
 
+	if errors.Is(err, models.ErrSearchCancelled) {
+		return c.handleCancellationEvent(ctx, w, processID, err)
+	}
+
 	ctx.Status(http.StatusInternalServerError)
 
-	controllers.SendSSEEvent(ctx, "error", gin.H{
+	w.writeEvent("error", gin.H{
 		"process_id": processID.String(),
 		"error":      err.Error(),
 	})
-	slog.Error("Stream error occurred", "process_id", processID, "error", err)
+	slog.ErrorContext(ctx, "Stream error occurred", "process_id", processID, "error", err)
 	c.cleanupProcess(processID)
 	return false
 }
 
-func (c *Controller) handleCancellationEvent(ctx *gin.Context, processID uuid.UUID, err error) bool {
-	slog.Warn("Stream processing cancelled", "process_id", processID, "reason", err)
+func (c *Controller) handleCancellationEvent(ctx *gin.Context, w streamWriter, processID uuid.UUID, err error) bool {
+	slog.WarnContext(ctx, "Stream processing cancelled", "process_id", processID, "reason", err)
 
-	controllers.SendSSEEvent(ctx, "cancelled", gin.H{
+	message := "Request cancelled by user"
+	if errors.Is(context.Cause(ctx.Request.Context()), errShuttingDown) {
+		message = "Server is shutting down"
+	}
+
+	w.writeEvent("cancelled", gin.H{
 		"process_id": processID.String(),
-		"message":    "Request cancelled by user",
+		"message":    message,
 	})
 
-	slog.Info("Cancellation completed", "process_id", processID, "client", ctx.ClientIP())
+	slog.InfoContext(ctx, "Cancellation completed", "process_id", processID, "client", ctx.ClientIP())
 
 	return false
 }
@@ -253,26 +622,26 @@ func (c *Controller) handleCancellationEvent(ctx *gin.Context, processID uuid.UU
 func (c *Controller) CancelProcess() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		processID := ctx.Param("process_id")
-		slog.Info("Processing cancellation request",
+		slog.InfoContext(ctx, "Processing cancellation request",
 			"process_id", processID,
 			"client", ctx.ClientIP())
 
 		uuidID, err := uuid.Parse(processID)
 		if err != nil {
-			slog.Warn("Invalid process ID format",
+			slog.WarnContext(ctx, "Invalid process ID format",
 				"input", processID,
 				"error", err)
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid process id"})
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidProcessID, "invalid process id")
 			return
 		}
 
 		if cancel, ok := c.activeRequests.Load(uuidID); ok {
-			slog.Debug("Found active process to cancel", "process_id", uuidID)
-			cancel.(context.CancelFunc)()
+			slog.DebugContext(ctx, "Found active process to cancel", "process_id", uuidID)
+			cancel.(context.CancelCauseFunc)(nil)
 			ctx.JSON(http.StatusOK, gin.H{"message": "Cancellation requested"})
 		} else {
-			slog.Warn("Process not found for cancellation", "process_id", uuidID)
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "process not found"})
+			slog.WarnContext(ctx, "Process not found for cancellation", "process_id", uuidID)
+			c.respondWithError(ctx, http.StatusNotFound, CodeProcessNotFound, "process not found")
 		}
 	}
 }
@@ -283,50 +652,211 @@ type SearchRequest struct {
 }
 
 type SearchResponse struct {
-	References []models.Reference `json:"references"`
+	SearchEnvelope
 }
 
 func (c *Controller) SemanticSearch() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		slog.Info("Handling semantic search request")
+		slog.InfoContext(ctx, "Handling semantic search request")
 
 		question := ctx.Query("question")
 		if question == "" {
-			slog.Error("Missing required query parameter: question")
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Missing required query parameter: question"})
+			slog.ErrorContext(ctx, "Missing required query parameter: question")
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, "Missing required query parameter: question")
+			return
+		}
+		if !c.checkQuestion(ctx, question) {
 			return
 		}
 
-		maxResults := 10 // Default value
+		// Without max_results the storage returns its configured number of results
+		var maxResults int
+		var opts []searchservice.SearchOption
 		maxResultsStr := ctx.Query("max_results")
 		if maxResultsStr != "" {
 			var err error
 			maxResults, err = strconv.Atoi(maxResultsStr)
 			if err != nil {
-				slog.Error("Invalid max_results parameter", "error", err)
-				ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_results parameter: must be an integer"})
+				slog.ErrorContext(ctx, "Invalid max_results parameter", "error", err)
+				c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, "Invalid max_results parameter: must be an integer")
+				return
+			}
+			if !c.checkReferenceCount(ctx, "max_results", maxResults) {
 				return
 			}
+			opts = append(opts, searchservice.WithNumberOfReferences(maxResults))
+		}
+
+		mmrOpts, err := getMMROptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid mmr parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, mmrOpts...)
+
+		thresholdOpts, err := getScoreThresholdOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid score_threshold parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, thresholdOpts...)
+
+		highlightOpts, err := getHighlightOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid highlight parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, highlightOpts...)
+
+		summaryOpts, err := getSummaryOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid summarize parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, summaryOpts...)
+
+		recencyOpts, err := getRecencyOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid recency parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		opts = append(opts, recencyOpts...)
+
+		cacheOpts, err := getCacheOptions(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid no_cache parameter", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
 		}
+		opts = append(opts, cacheOpts...)
 
-		slog.Debug("Executing semantic search",
+		slog.DebugContext(ctx, "Executing semantic search",
 			"query", question,
 			"max_results", maxResults)
 
-		references, err := c.searchService.SemanticSearch(ctx, question)
+		started := time.Now()
+		references, err := c.searchService.SemanticSearch(ctx, question, opts...)
+		if errors.Is(err, models.ErrSearchCancelled) {
+			slog.InfoContext(ctx, "Semantic search cancelled by client", "query", question)
+			ctx.AbortWithStatus(statusClientClosedRequest)
+			return
+		}
 		if err != nil {
-			slog.Error("Semantic search failed",
+			slog.ErrorContext(ctx, "Semantic search failed",
 				"error", err,
 				"query", question)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.respondWithServiceError(ctx, err)
 			return
 		}
 
-		slog.Info("Semantic search completed",
+		slog.InfoContext(ctx, "Semantic search completed",
 			"query", question,
 			"results_count", len(references))
-		ctx.JSON(http.StatusOK, SearchResponse{References: references})
+		ctx.JSON(http.StatusOK, SearchResponse{
+			SearchEnvelope: newSearchEnvelope(question, references, started),
+		})
+	}
+}
+
+// GetResourceChunks lists how an indexed resource of the caller was chunked
+func (c *Controller) GetResourceChunks() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resourceID, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidResourceID, "invalid resource id")
+			return
+		}
+
+		limit, err := getIntQuery(ctx, "limit")
+		if err != nil {
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, "Invalid limit parameter: must be an integer")
+			return
+		}
+
+		offset, err := getIntQuery(ctx, "offset")
+		if err != nil {
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, "Invalid offset parameter: must be an integer")
+			return
+		}
+
+		page, err := c.searchService.GetResourceChunks(ctx, resourceID, limit, offset)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get resource chunks",
+				"error", err,
+				"resource_id", resourceID)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, page)
+	}
+}
+
+// SimilarResourcesResponse lists the resources related to a resource
+type SimilarResourcesResponse struct {
+	ResourceID uuid.UUID                `json:"resource_id"`
+	Similar    []models.SimilarResource `json:"similar"`
+}
+
+// GetSimilarResources lists the indexed resources of the caller closest in
+// content to one of them
+func (c *Controller) GetSimilarResources() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resourceID, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			c.respondWithError(ctx, http.StatusBadRequest, CodeInvalidResourceID, "invalid resource id")
+			return
+		}
+
+		limit, err := getIntQuery(ctx, "limit")
+		if err != nil {
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, "Invalid limit parameter: must be an integer")
+			return
+		}
+
+		similar, err := c.searchService.FindSimilarResources(ctx, resourceID, limit)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to find similar resources",
+				"error", err,
+				"resource_id", resourceID)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, SimilarResourcesResponse{
+			ResourceID: resourceID,
+			Similar:    similar,
+		})
+	}
+}
+
+// GetIndexStats reports how many resources and chunks of the caller are indexed
+// and how much storage they take up
+func (c *Controller) GetIndexStats() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		stats, err := c.searchService.GetIndexStats(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to get index stats", "error", err)
+			c.respondWithServiceError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, stats)
+	}
+}
+
+// getIntQuery reads an optional integer query parameter, returning 0 when absent
+func getIntQuery(ctx *gin.Context, name string) (int, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return 0, nil
 	}
+	return strconv.Atoi(value)
 }
 
 func (c *Controller) activeRequestsCount() int {
@@ -0,0 +1,104 @@
+package searchcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// answeringService answers every question with the same result
+type answeringService struct {
+	searchService
+	result models.SearchResult
+}
+
+func (s *answeringService) GetAnswer(context.Context, string, ...searchservice.SearchOption) (models.SearchResult, error) {
+	return s.result, nil
+}
+
+func (s *answeringService) SemanticSearch(context.Context, string, ...searchservice.SearchOption) ([]models.Reference, error) {
+	return s.result.References, nil
+}
+
+// optionsRecordingService records the options of the last search
+type optionsRecordingService struct {
+	answeringService
+	options searchservice.SearchOptions
+}
+
+func (s *optionsRecordingService) SemanticSearch(_ context.Context, _ string, opts ...searchservice.SearchOption) ([]models.Reference, error) {
+	s.options = searchservice.SearchOptions{}
+	for _, opt := range opts {
+		opt(&s.options)
+	}
+	return nil, nil
+}
+
+func serveSearch(t *testing.T, service searchService, req *http.Request) map[string]any {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	NewController(service, nil, nil).RegisterRoutes(router.Group("/"))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return body
+}
+
+func TestSearchEnvelope_SharedByBothEndpoints(t *testing.T) {
+	service := &answeringService{result: models.SearchResult{
+		Answer:     "Channels connect goroutines.",
+		References: []models.Reference{{ResourceID: uuid.New(), Content: "channels"}},
+	}}
+
+	search := serveSearch(t, service, httptest.NewRequest(http.MethodGet, "/search/?question=channels", nil))
+	ask := serveSearch(t, service, httptest.NewRequest(http.MethodPost, "/ask/", strings.NewReader(`{"question":"channels"}`)))
+
+	for name, body := range map[string]map[string]any{"search": search, "ask": ask} {
+		assert.Equal(t, "channels", body["query"], name)
+		assert.Len(t, body["references"], 1, name)
+		assert.Contains(t, body, "took_ms", name)
+	}
+
+	// Only the endpoint generating an answer has one
+	assert.NotContains(t, search, "answer")
+	assert.Equal(t, "Channels connect goroutines.", ask["answer"])
+
+	// The former shape of the ask response is kept
+	require.Contains(t, ask, "result")
+	assert.Equal(t, "Channels connect goroutines.", ask["result"].(map[string]any)["answer"])
+}
+
+func TestSearchEnvelope_NoAnswerWhenGenerationSkipped(t *testing.T) {
+	service := &answeringService{result: models.SearchResult{GenerationSkipped: true}}
+
+	ask := serveSearch(t, service, httptest.NewRequest(http.MethodPost, "/ask/", strings.NewReader(`{"question":"channels","generate":false}`)))
+
+	assert.NotContains(t, ask, "answer")
+	assert.Equal(t, []any{}, ask["references"])
+}
+
+func TestSemanticSearch_MaxResultsDefaultsToStorageConfig(t *testing.T) {
+	service := &optionsRecordingService{}
+
+	serveSearch(t, service, httptest.NewRequest(http.MethodGet, "/search/?question=channels", nil))
+	assert.Zero(t, service.options.NumberOfReferences, "the configured num_of_results of the storage applies")
+
+	serveSearch(t, service, httptest.NewRequest(http.MethodGet, "/search/?question=channels&max_results=3", nil))
+	assert.Equal(t, 3, service.options.NumberOfReferences)
+}
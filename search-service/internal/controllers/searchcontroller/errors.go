@@ -0,0 +1,53 @@
+package searchcontroller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// Error codes of the search endpoints
+const (
+	CodeResourceNotFound  controllers.ErrorCode = "RESOURCE_NOT_FOUND"
+	CodeInvalidResourceID controllers.ErrorCode = "INVALID_RESOURCE_ID"
+	CodeProcessNotFound   controllers.ErrorCode = "PROCESS_NOT_FOUND"
+	CodeInvalidProcessID  controllers.ErrorCode = "INVALID_PROCESS_ID"
+	CodeModelTimeout      controllers.ErrorCode = "MODEL_TIMEOUT"
+)
+
+// serviceErrors maps the domain errors services return to the status and code
+// of the response. Errors not listed here are internal.
+var serviceErrors = []struct {
+	err    error
+	status int
+	code   controllers.ErrorCode
+}{
+	{models.ErrResourceNotFound, http.StatusNotFound, CodeResourceNotFound},
+	{models.ErrSystemPromptTooLong, http.StatusBadRequest, controllers.CodeInvalidRequest},
+	{models.ErrModelTimeout, http.StatusGatewayTimeout, CodeModelTimeout},
+}
+
+// serviceError maps a service error to the HTTP status code and error code
+// reflecting it
+func serviceError(err error) (int, controllers.ErrorCode) {
+	for _, e := range serviceErrors {
+		if errors.Is(err, e.err) {
+			return e.status, e.code
+		}
+	}
+	return http.StatusInternalServerError, controllers.CodeInternal
+}
+
+func (c *Controller) respondWithError(ctx *gin.Context, statusCode int, code controllers.ErrorCode, message string) {
+	controllers.RespondWithError(ctx, statusCode, code, message)
+}
+
+// respondWithServiceError responds with the status and code of a service error
+func (c *Controller) respondWithServiceError(ctx *gin.Context, err error) {
+	status, code := serviceError(err)
+	c.respondWithError(ctx, status, code, err.Error())
+}
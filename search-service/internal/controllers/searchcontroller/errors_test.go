@@ -0,0 +1,35 @@
+package searchcontroller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   controllers.ErrorCode
+	}{
+		{"resource not found", models.ErrResourceNotFound, http.StatusNotFound, CodeResourceNotFound},
+		{"system prompt too long", models.ErrSystemPromptTooLong, http.StatusBadRequest, controllers.CodeInvalidRequest},
+		{"model timeout", models.ErrModelTimeout, http.StatusGatewayTimeout, CodeModelTimeout},
+		{"unexpected", errors.New("connection refused"), http.StatusInternalServerError, controllers.CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serviceError(fmt.Errorf("Service.GetResourceChunks: %w", tt.err))
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package searchcontroller
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// slowService sends the references and holds the answer back until released
+type slowService struct {
+	searchService
+	release chan struct{}
+}
+
+func (s *slowService) GetAnswerStream(ctx context.Context, _ string, _ ...searchservice.SearchOption) (<-chan models.SearchResult, <-chan []models.Reference, <-chan []byte, <-chan error) {
+	resultCh := make(chan models.SearchResult)
+	refsCh := make(chan []models.Reference)
+	chunkCh := make(chan []byte)
+	errCh := make(chan error)
+
+	go func() {
+		refsCh <- []models.Reference{{ResourceID: uuid.New(), Content: "context"}}
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return
+		}
+		chunkCh <- []byte("Hello")
+		close(chunkCh)
+		resultCh <- models.SearchResult{Answer: "Hello"}
+	}()
+
+	return resultCh, refsCh, chunkCh, errCh
+}
+
+func TestAskStream_SendsHeartbeatUntilFirstChunk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &slowService{release: make(chan struct{})}
+
+	router := gin.New()
+	NewController(service, nil, &Config{HeartbeatInterval: 10 * time.Millisecond}).RegisterRoutes(router.Group("/"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ask/stream/?question=what")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	nextLine := func() (string, bool) {
+		select {
+		case line, ok := <-lines:
+			return line, ok
+		case <-time.After(time.Second):
+			t.Fatal("stream stalled")
+			return "", false
+		}
+	}
+
+	// No chunk arrives within the interval, so a heartbeat keeps the stream open
+	for {
+		line, ok := nextLine()
+		require.True(t, ok, "stream ended without a heartbeat")
+		require.NotEqual(t, "event:chunk", line, "chunk sent before the heartbeat")
+		if line == ": keepalive" {
+			break
+		}
+	}
+
+	close(service.release)
+
+	// Heartbeats stop once the answer flows
+	var afterChunk []string
+	seenChunk := false
+	for {
+		line, ok := nextLine()
+		if !ok {
+			break
+		}
+		if line == "event:chunk" {
+			seenChunk = true
+		}
+		if seenChunk {
+			afterChunk = append(afterChunk, line)
+		}
+	}
+	require.True(t, seenChunk)
+	assert.NotContains(t, afterChunk, ": keepalive")
+	assert.Contains(t, afterChunk, "event:complete")
+}
+
+func TestAskStream_NoHeartbeatWithoutConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &slowService{release: make(chan struct{})}
+	close(service.release)
+
+	router := gin.New()
+	NewController(service, nil, nil).RegisterRoutes(router.Group("/"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ask/stream/?question=what")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.NotContains(t, lines, ": keepalive")
+	assert.Contains(t, lines, "event:complete")
+}
+
+func TestAskStream_OutlivesServerWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &slowService{release: make(chan struct{})}
+	time.AfterFunc(200*time.Millisecond, func() { close(service.release) })
+
+	router := gin.New()
+	NewController(service, nil, &Config{WriteTimeout: time.Second}).RegisterRoutes(router.Group("/"))
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ask/stream/?question=what")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Every event moves the deadline, so the answer arrives after the server
+	// write timeout has passed
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Contains(t, lines, "event:chunk")
+	assert.Contains(t, lines, "event:complete")
+}
@@ -0,0 +1,48 @@
+package searchcontroller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+func TestGetAskStreamParams_Language(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		language string
+		wantErr  string
+	}{
+		{"en", ""},
+		{"ru", ""},
+		// Without a prompt of its own the answer would not be in German
+		{"de", "invalid language parameter: must be one of en, ru"},
+		{"russian", "invalid language parameter: must be one of en, ru"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/ask/stream/?question=q&language="+tt.language, nil)
+
+			_, _, opts, err := getAskStreamParams(ctx)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var options searchservice.SearchOptions
+			for _, opt := range opts {
+				opt(&options)
+			}
+			assert.Equal(t, tt.language, options.Language)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package searchcontroller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// chunkFirstStorage generates the first chunk before the retriever reports
+// the references, the order a fast model can produce them in
+type chunkFirstStorage struct{}
+
+func (chunkFirstStorage) GetAnswer(context.Context, string, ...searchservice.SearchOption) (string, []models.Reference, error) {
+	return "", nil, nil
+}
+
+func (chunkFirstStorage) GetAnswerStream(context.Context, string, ...searchservice.SearchOption) (<-chan string, <-chan []models.Reference, <-chan []byte, <-chan error) {
+	answerCh := make(chan string)
+	refsCh := make(chan []models.Reference)
+	chunkCh := make(chan []byte, 1)
+	errCh := make(chan error)
+
+	go func() {
+		chunkCh <- []byte("Hello")
+		refsCh <- []models.Reference{{ResourceID: uuid.New(), Content: "context"}}
+		close(chunkCh)
+		answerCh <- "Hello"
+	}()
+
+	return answerCh, refsCh, chunkCh, errCh
+}
+
+func (chunkFirstStorage) SemanticSearch(context.Context, string, ...searchservice.SearchOption) ([]models.Reference, error) {
+	return nil, nil
+}
+
+func (chunkFirstStorage) GetResourceChunks(context.Context, uuid.UUID, int, int) ([]models.Chunk, int, error) {
+	return nil, 0, nil
+}
+
+func (chunkFirstStorage) GetIndexUsage(context.Context) ([]models.ResourceUsage, error) {
+	return nil, nil
+}
+
+func (chunkFirstStorage) FindSimilarResources(context.Context, uuid.UUID, int) ([]models.SimilarResource, error) {
+	return nil, nil
+}
+
+func (chunkFirstStorage) GetResourcesByIDs(context.Context, []uuid.UUID) (map[uuid.UUID]models.Resource, error) {
+	return nil, nil
+}
+
+func (chunkFirstStorage) SummarizeReferences(_ context.Context, refs []models.Reference) ([]models.Reference, error) {
+	return refs, nil
+}
+
+func TestAskStream_ReferencesBeforeFirstChunk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	NewController(searchservice.NewService(chunkFirstStorage{}, nil), nil, nil).RegisterRoutes(router.Group("/"))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// The chunk is ready first every time, so a race would show within a few runs
+	for range 20 {
+		resp, err := http.Get(server.URL + "/ask/stream/?question=what")
+		require.NoError(t, err)
+		raw, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+
+		body := string(raw)
+		references := strings.Index(body, "event:references")
+		chunk := strings.Index(body, "event:chunk")
+		complete := strings.Index(body, "event:complete")
+		require.NotEqual(t, -1, references, body)
+		require.NotEqual(t, -1, chunk, body)
+		require.Less(t, references, chunk, body)
+		require.Less(t, chunk, complete, body)
+	}
+}
@@ -0,0 +1,77 @@
+package searchcontroller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+)
+
+// QuestionConfig bounds the questions of the ask and search endpoints, so that
+// oversized questions don't waste the token budget of the model
+type QuestionConfig struct {
+	// MinLength is the least number of characters of a question, one when unset
+	MinLength int `yaml:"min_length" mapstructure:"min_length" validate:"min=0"`
+	// MaxLength is the most characters of a question, zero doesn't bound it
+	MaxLength int `yaml:"max_length" mapstructure:"max_length" validate:"min=0"`
+}
+
+// QuestionDetails are the details of an invalid question
+type QuestionDetails struct {
+	// Length of the question in characters, surrounding whitespace excluded
+	Length    int `json:"length"`
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length,omitempty"`
+}
+
+// questionError explains why a question was rejected
+type questionError struct {
+	message string
+	details QuestionDetails
+}
+
+// validateQuestion checks the length of the question without the surrounding
+// whitespace and rejects control characters other than line breaks and tabs
+func (cfg QuestionConfig) validateQuestion(question string) *questionError {
+	question = strings.TrimSpace(question)
+	details := QuestionDetails{
+		Length:    utf8.RuneCountInString(question),
+		MinLength: max(cfg.MinLength, 1),
+		MaxLength: cfg.MaxLength,
+	}
+
+	switch {
+	case details.Length == 0:
+		return &questionError{"question is required", details}
+	case strings.ContainsFunc(question, isForbiddenControl):
+		return &questionError{"question must not contain control characters", details}
+	case details.Length < details.MinLength:
+		return &questionError{fmt.Sprintf("question is too short: %d characters, at least %d required",
+			details.Length, details.MinLength), details}
+	case details.MaxLength > 0 && details.Length > details.MaxLength:
+		return &questionError{fmt.Sprintf("question is too long: %d characters, at most %d allowed",
+			details.Length, details.MaxLength), details}
+	}
+	return nil
+}
+
+func isForbiddenControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+// checkQuestion responds with 400 and the limits of questions when the
+// question is invalid
+func (c *Controller) checkQuestion(ctx *gin.Context, question string) bool {
+	if err := c.config.Question.validateQuestion(question); err != nil {
+		slog.WarnContext(ctx, "Invalid question", "error", err.message, "length", err.details.Length)
+		controllers.RespondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.message, err.details)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package searchcontroller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func TestQuestionValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	config := &Config{Question: QuestionConfig{MinLength: 2, MaxLength: 10}}
+	NewController(&answeringService{result: models.SearchResult{Answer: "Yes"}}, nil, config).RegisterRoutes(router.Group("/"))
+
+	ask := func(question string) *http.Request {
+		body, err := json.Marshal(AskRequest{Question: question})
+		require.NoError(t, err)
+		return httptest.NewRequest(http.MethodPost, "/ask/", strings.NewReader(string(body)))
+	}
+	search := func(question string) *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/search/?question="+url.QueryEscape(question), nil)
+	}
+
+	tests := []struct {
+		name     string
+		question string
+		status   int
+		message  string
+		length   int
+	}{
+		{"empty", "   ", http.StatusBadRequest, "question is required", 0},
+		{"too short", "a", http.StatusBadRequest, "question is too short: 1 characters, at least 2 required", 1},
+		{"too long", "What are goroutines?", http.StatusBadRequest, "question is too long: 20 characters, at most 10 allowed", 20},
+		{"control characters", "why\x00not", http.StatusBadRequest, "question must not contain control characters", 7},
+		{"counts characters rather than bytes", "горутины?", http.StatusOK, "", 0},
+		{"valid", " channels\n", http.StatusOK, "", 0},
+	}
+
+	for _, tt := range tests {
+		for endpoint, newRequest := range map[string]func(string) *http.Request{"ask": ask, "search": search} {
+			t.Run(endpoint+"/"+tt.name, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, newRequest(tt.question))
+				require.Equal(t, tt.status, rec.Code, rec.Body.String())
+				if tt.status == http.StatusOK {
+					return
+				}
+
+				var response struct {
+					controllers.ErrorResponse
+					Details QuestionDetails `json:"details"`
+				}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				assert.Equal(t, controllers.CodeInvalidRequest, response.Code)
+				assert.Equal(t, tt.message, response.Message)
+				assert.Equal(t, QuestionDetails{Length: tt.length, MinLength: 2, MaxLength: 10}, response.Details)
+			})
+		}
+	}
+}
+
+func TestQuestionValidation_UnboundedWithoutConfig(t *testing.T) {
+	assert.Nil(t, QuestionConfig{}.validateQuestion(strings.Repeat("long question ", 1000)))
+	assert.NotNil(t, QuestionConfig{}.validateQuestion(""))
+}
@@ -0,0 +1,48 @@
+package searchcontroller
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+)
+
+// ReferencesConfig bounds how many references the ask and search endpoints
+// retrieve. MMR over-fetches candidates for every reference and embeds them
+// again, so the cost of a request grows with the number asked for.
+type ReferencesConfig struct {
+	// MaxCount is the most references a request may ask for, zero doesn't bound it
+	MaxCount int `yaml:"max_count" mapstructure:"max_count" validate:"min=0"`
+}
+
+// ReferencesDetails are the details of a request asking for too many references
+type ReferencesDetails struct {
+	// Parameter asking for the references
+	Parameter string `json:"parameter"`
+	Requested int    `json:"requested"`
+	MaxCount  int    `json:"max_count"`
+}
+
+// validateCount rejects asking for more than MaxCount references with the
+// parameter. Zero keeps the configured number of the storage.
+func (cfg ReferencesConfig) validateCount(parameter string, count int) (string, *ReferencesDetails) {
+	if cfg.MaxCount == 0 || count <= cfg.MaxCount {
+		return "", nil
+	}
+	return fmt.Sprintf("%s is too large: %d references, at most %d allowed", parameter, count, cfg.MaxCount),
+		&ReferencesDetails{Parameter: parameter, Requested: count, MaxCount: cfg.MaxCount}
+}
+
+// checkReferenceCount responds with 400 and the limit of references when the
+// parameter asks for more
+func (c *Controller) checkReferenceCount(ctx *gin.Context, parameter string, count int) bool {
+	if message, details := c.config.References.validateCount(parameter, count); details != nil {
+		slog.WarnContext(ctx, "Too many references requested", "parameter", parameter, "requested", count)
+		controllers.RespondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, message, *details)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package searchcontroller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func TestReferenceCountValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	config := &Config{References: ReferencesConfig{MaxCount: 20}}
+	NewController(&answeringService{result: models.SearchResult{Answer: "Yes"}}, nil, config).RegisterRoutes(router.Group("/"))
+
+	tests := []struct {
+		name      string
+		target    string
+		status    int
+		parameter string
+		count     int
+	}{
+		{"search within the bound", "/search/?question=channels&max_results=20", http.StatusOK, "", 0},
+		{"search above the bound", "/search/?question=channels&max_results=21", http.StatusBadRequest, "max_results", 21},
+		{"search with the default", "/search/?question=channels", http.StatusOK, "", 0},
+		{"stream above the bound", "/ask/stream/?question=channels&num_references=21", http.StatusBadRequest, "num_references", 21},
+		{"websocket above the bound", "/ask/ws?question=channels&num_references=100", http.StatusBadRequest, "num_references", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+			if tt.status == http.StatusOK {
+				return
+			}
+
+			var response struct {
+				controllers.ErrorResponse
+				Details ReferencesDetails `json:"details"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, controllers.CodeInvalidRequest, response.Code)
+			assert.Equal(t, ReferencesDetails{Parameter: tt.parameter, Requested: tt.count, MaxCount: 20}, response.Details)
+		})
+	}
+}
+
+func TestReferenceCountValidation_UnboundedWithoutConfig(t *testing.T) {
+	_, details := ReferencesConfig{}.validateCount("max_results", 10000)
+	assert.Nil(t, details)
+}
@@ -0,0 +1,44 @@
+package searchcontroller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRoutes_CancelSkipsAskMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The ask middleware rejects every request, as a rate limiter out of quota
+	limited := func(ctx *gin.Context) {
+		ctx.AbortWithStatus(http.StatusTooManyRequests)
+	}
+	router := gin.New()
+	NewController(&answeringService{}, nil, nil, limited).RegisterRoutes(router.Group("/"))
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+		code   string
+	}{
+		{http.MethodPost, "/ask/", http.StatusTooManyRequests, ""},
+		{http.MethodGet, "/ask/stream/", http.StatusTooManyRequests, ""},
+		{http.MethodGet, "/ask/ws", http.StatusTooManyRequests, ""},
+		{http.MethodDelete, "/ask/stream/cancel/" + uuid.NewString(), http.StatusNotFound, string(CodeProcessNotFound)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.code)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package searchcontroller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+)
+
+// sseEvent is an event read from a server-sent event stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// readSSEEvents delivers the events of the stream until it ends
+func readSSEEvents(body io.Reader) <-chan sseEvent {
+	events := make(chan sseEvent)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event.name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				event.data = strings.TrimPrefix(line, "data:")
+			case line == "" && event.name != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+func nextSSEEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "stream ended")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return sseEvent{}
+	}
+}
+
+// startStream starts an answer stream and waits for its first chunk
+func startStream(t *testing.T, service *streamingService) (*Controller, string, <-chan sseEvent) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	controller := NewController(service, nil, nil)
+	router := gin.New()
+	controller.RegisterRoutes(router.Group("/"))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/ask/stream/?question=what")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	events := readSSEEvents(resp.Body)
+	assert.Equal(t, "references", nextSSEEvent(t, events).name)
+	assert.Equal(t, "chunk", nextSSEEvent(t, events).name)
+	return controller, server.URL, events
+}
+
+func TestDrain_CancelsStreamAfterGracePeriod(t *testing.T) {
+	service := &streamingService{
+		chunks:    []string{"Hello"},
+		finish:    make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+	controller, url, events := startStream(t, service)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	drained := make(chan struct{})
+	go func() {
+		controller.Drain(ctx)
+		close(drained)
+	}()
+
+	event := nextSSEEvent(t, events)
+	assert.Equal(t, "cancelled", event.name)
+	assert.Contains(t, event.data, "Server is shutting down")
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after cancelling the stream")
+	}
+
+	select {
+	case <-service.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the shutdown did not cancel the generation")
+	}
+
+	resp, err := http.Get(url + "/ask/stream/?question=what")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	var body controllers.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, controllers.CodeServiceUnavailable, body.Code)
+	assert.Equal(t, errShuttingDown.Error(), body.Message)
+}
+
+func TestDrain_StreamFinishesWithinGracePeriod(t *testing.T) {
+	service := &streamingService{
+		chunks:    []string{"Hello"},
+		finish:    make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+	controller, _, events := startStream(t, service)
+
+	drained := make(chan struct{})
+	go func() {
+		controller.Drain(context.Background())
+		close(drained)
+	}()
+	close(service.finish)
+
+	assert.Equal(t, "complete", nextSSEEvent(t, events).name)
+
+	select {
+	case <-drained:
+	case <-time.After(time.Second):
+		t.Fatal("drain did not return after the stream completed")
+	}
+}
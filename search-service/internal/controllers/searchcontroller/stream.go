@@ -0,0 +1,127 @@
+package searchcontroller
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// streamWriter delivers the events of a streamed answer to the client. The
+// events and their payloads are the same for every transport.
+type streamWriter interface {
+	writeEvent(event string, data any) error
+}
+
+// sseWriter sends events as server-sent events
+type sseWriter struct {
+	ctx *gin.Context
+	// writeTimeout bounds how long an event may take to reach the client,
+	// zero leaves the server write timeout in place
+	writeTimeout time.Duration
+}
+
+func (w sseWriter) writeEvent(event string, data any) error {
+	if err := w.extendDeadline(); err != nil {
+		return err
+	}
+	controllers.SendSSEEvent(w.ctx, event, data)
+	return nil
+}
+
+// writeHeartbeat sends a comment, which clients ignore but which keeps
+// intermediaries from closing the idle connection
+func (w sseWriter) writeHeartbeat() error {
+	if err := w.extendDeadline(); err != nil {
+		return err
+	}
+	if _, err := w.ctx.Writer.WriteString(": keepalive\n\n"); err != nil {
+		return err
+	}
+	controllers.Flush(w.ctx.Writer)
+	return nil
+}
+
+func (w sseWriter) extendDeadline() error {
+	if w.writeTimeout <= 0 {
+		return nil
+	}
+	return controllers.ExtendWriteDeadline(w.ctx, w.writeTimeout)
+}
+
+// heartbeatWriter is implemented by the transports that need traffic to keep
+// an idle stream open
+type heartbeatWriter interface {
+	writeHeartbeat() error
+}
+
+// answerStream holds the channels of an answer being generated
+type answerStream struct {
+	resultCh     <-chan models.SearchResult
+	referencesCh <-chan []models.Reference
+	chunkCh      <-chan []byte
+	errCh        <-chan error
+
+	// heartbeat ticks until the first chunk arrives, nil without heartbeats
+	heartbeat       <-chan time.Time
+	heartbeatTicker *time.Ticker
+}
+
+func (c *Controller) startAnswerStream(ctx context.Context, question string, opts ...searchservice.SearchOption) *answerStream {
+	resultCh, referencesCh, chunkCh, errCh := c.searchService.GetAnswerStream(ctx, question, opts...)
+	return &answerStream{
+		resultCh:     resultCh,
+		referencesCh: referencesCh,
+		chunkCh:      chunkCh,
+		errCh:        errCh,
+	}
+}
+
+// startHeartbeat ticks every interval until the first chunk arrives or the
+// heartbeat is stopped. A non-positive interval disables it.
+func (s *answerStream) startHeartbeat(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.heartbeatTicker = time.NewTicker(interval)
+	s.heartbeat = s.heartbeatTicker.C
+}
+
+func (s *answerStream) stopHeartbeat() {
+	if s.heartbeatTicker != nil {
+		s.heartbeatTicker.Stop()
+	}
+	s.heartbeat = nil
+}
+
+// nextStreamEvent waits for the next event of the stream and writes it. It
+// reports whether the stream goes on.
+func (c *Controller) nextStreamEvent(ctx *gin.Context, w streamWriter, processID uuid.UUID, stream *answerStream) bool {
+	select {
+	case chunk, ok := <-stream.chunkCh:
+		// Chunks keep the connection busy from now on
+		stream.stopHeartbeat()
+		if !ok {
+			// Generation finished, the result or error is still to come
+			stream.chunkCh = nil
+			return true
+		}
+		return c.handleChunk(w, processID, chunk)
+	case <-stream.heartbeat:
+		return c.handleHeartbeat(ctx, w, processID)
+	case references := <-stream.referencesCh:
+		return c.handleReferences(w, processID, references)
+	case result := <-stream.resultCh:
+		return c.handleResult(w, processID, result)
+	case err := <-stream.errCh:
+		return c.handleError(ctx, w, processID, err)
+	case <-ctx.Request.Context().Done():
+		// The request context is the one the process middleware cancels
+		return c.handleCancellationEvent(ctx, w, processID, ctx.Request.Context().Err())
+	}
+}
@@ -0,0 +1,111 @@
+package searchcontroller
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/nzb3/diploma/search-service/internal/controllers"
+)
+
+// wsWriteTimeout bounds how long a frame may take to reach a slow client
+const wsWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// Requests are authenticated by a bearer token rather than cookies, so
+	// like the CORS configuration any origin is accepted
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsFrame is the JSON frame carrying one stream event over a WebSocket
+type wsFrame struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// wsWriter sends events as JSON frames over a WebSocket
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (w wsWriter) writeEvent(event string, data any) error {
+	if err := w.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+		return err
+	}
+	return w.conn.WriteJSON(wsFrame{Event: event, Data: data})
+}
+
+// AskWebSocket streams an answer over a WebSocket for clients that can't use
+// SSE. It takes the query parameters of AskStream and sends the same events.
+// Closing the socket cancels the generation.
+func (c *Controller) AskWebSocket() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		slog.InfoContext(ctx, "Initializing websocket stream request")
+		question, numReferences, opts, err := getAskStreamParams(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Invalid stream request", "error", err)
+			c.respondWithError(ctx, http.StatusBadRequest, controllers.CodeInvalidRequest, err.Error())
+			return
+		}
+		if !c.checkQuestion(ctx, question) {
+			return
+		}
+		if !c.checkReferenceCount(ctx, "num_references", numReferences) {
+			return
+		}
+
+		processID, err := getProcessIDFromContext(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error getting process ID check createProcessMiddleware", "error", err)
+			c.respondWithError(ctx, http.StatusInternalServerError, controllers.CodeInternal, "failed to start process")
+			return
+		}
+		defer c.cleanupProcess(processID)
+
+		conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			// The upgrader has already replied with an error status
+			slog.ErrorContext(ctx, "Failed to upgrade to websocket", "process_id", processID, "error", err)
+			return
+		}
+		defer conn.Close()
+
+		slog.InfoContext(ctx, "Starting websocket stream processing",
+			"process_id", processID,
+			"question", question,
+			"num_references", numReferences,
+			"client", ctx.ClientIP())
+
+		// Clients don't send anything, reading only notices the socket closing
+		var readerDone sync.WaitGroup
+		readerDone.Add(1)
+		go func() {
+			defer readerDone.Done()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					slog.Debug("Websocket closed by client", "process_id", processID, "reason", err)
+					c.cleanupProcess(processID)
+					return
+				}
+			}
+		}()
+
+		stream := c.startAnswerStream(ctx.Request.Context(), question, opts...)
+		events := wsWriter{conn: conn}
+		for c.nextStreamEvent(ctx, events, processID, stream) {
+		}
+
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		if err := conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(wsWriteTimeout)); err != nil {
+			slog.DebugContext(ctx, "Failed to send websocket close", "process_id", processID, "error", err)
+		}
+		conn.Close()
+		readerDone.Wait()
+	}
+}
@@ -0,0 +1,137 @@
+package searchcontroller
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// streamingService streams the given chunks and then waits for the caller to
+// finish or cancel the answer
+type streamingService struct {
+	searchService
+	chunks    []string
+	finish    chan struct{}
+	cancelled chan struct{}
+}
+
+func (s *streamingService) GetAnswerStream(ctx context.Context, _ string, _ ...searchservice.SearchOption) (<-chan models.SearchResult, <-chan []models.Reference, <-chan []byte, <-chan error) {
+	resultCh := make(chan models.SearchResult)
+	refsCh := make(chan []models.Reference)
+	chunkCh := make(chan []byte)
+	errCh := make(chan error)
+
+	go func() {
+		refsCh <- []models.Reference{{ResourceID: uuid.New(), Content: "context"}}
+		for _, chunk := range s.chunks {
+			chunkCh <- []byte(chunk)
+		}
+		close(chunkCh)
+
+		select {
+		case <-s.finish:
+			resultCh <- models.SearchResult{Answer: strings.Join(s.chunks, "")}
+		case <-ctx.Done():
+			close(s.cancelled)
+		}
+	}()
+
+	return resultCh, refsCh, chunkCh, errCh
+}
+
+func newWebSocketServer(t *testing.T, service searchService) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	NewController(service, nil, nil).RegisterRoutes(router.Group("/"))
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ask/ws?question=what"
+}
+
+func readFrame(t *testing.T, conn *websocket.Conn) wsFrame {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	var frame wsFrame
+	require.NoError(t, conn.ReadJSON(&frame))
+	return frame
+}
+
+func TestAskWebSocket_StreamsEvents(t *testing.T) {
+	service := &streamingService{
+		chunks:    []string{"Hello", " world"},
+		finish:    make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+	close(service.finish)
+
+	conn, _, err := websocket.DefaultDialer.Dial(newWebSocketServer(t, service), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var events []string
+	var content string
+	for {
+		frame := readFrame(t, conn)
+		events = append(events, frame.Event)
+		data := frame.Data.(map[string]any)
+		if frame.Event == "chunk" {
+			content += data["content"].(string)
+		}
+		if frame.Event == "complete" {
+			assert.Equal(t, "Hello world", data["result"].(map[string]any)["answer"])
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"references", "chunk", "chunk", "complete"}, events)
+	assert.Equal(t, "Hello world", content)
+
+	// The server closes the socket once the answer is complete
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error %v", err)
+}
+
+func TestAskWebSocket_CloseCancelsGeneration(t *testing.T) {
+	service := &streamingService{
+		chunks:    []string{"Hello"},
+		finish:    make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(newWebSocketServer(t, service), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "references", readFrame(t, conn).Event)
+	assert.Equal(t, "chunk", readFrame(t, conn).Event)
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-service.cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("closing the socket did not cancel the generation")
+	}
+}
+
+func TestAskWebSocket_RequiresQuestion(t *testing.T) {
+	url := strings.TrimSuffix(newWebSocketServer(t, &streamingService{}), "?question=what")
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, 400, resp.StatusCode)
+}
@@ -0,0 +1,36 @@
+// Package identity carries the user and tenant a piece of work acts for in its
+// context. The auth middleware sets them for requests and the event consumers
+// for the resources they index, so the services and repositories read them
+// without depending on either.
+package identity
+
+import "context"
+
+// Context keys of the user and tenant. They are plain strings as gin copies the
+// values it is given under the same keys.
+const (
+	UserIDKey   string = "user_id"
+	TenantIDKey string = "tenant_id"
+)
+
+// WithUserID returns a copy of ctx acting on behalf of the user
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, UserIDKey, userID)
+}
+
+// UserID returns the user ctx acts for
+func UserID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(UserIDKey).(string)
+	return id, ok
+}
+
+// WithTenantID returns a copy of ctx acting within the tenant
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, TenantIDKey, tenantID)
+}
+
+// TenantID returns the tenant ctx acts within
+func TenantID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(TenantIDKey).(string)
+	return id, ok
+}
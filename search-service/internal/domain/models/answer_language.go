@@ -0,0 +1,13 @@
+package models
+
+import "slices"
+
+// AnswerLanguages are the ISO 639-1 codes an answer language can be selected
+// in. Each has a QA prompt of its own, questions in other languages are
+// answered in the language they are asked in.
+var AnswerLanguages = []string{"en", "ru"}
+
+// IsAnswerLanguage reports whether the code is one of AnswerLanguages
+func IsAnswerLanguage(code string) bool {
+	return slices.Contains(AnswerLanguages, code)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Chunk is a stored piece of a resource as it was indexed for retrieval
+type Chunk struct {
+	ID         uuid.UUID `json:"id"`
+	ResourceID uuid.UUID `json:"resource_id"`
+	Index      int       `json:"index"`
+	Content    string    `json:"content"`
+	// StartOffset and EndOffset are byte offsets into the extracted content,
+	// or -1 when the chunk could not be located in it
+	StartOffset int            `json:"start_offset"`
+	EndOffset   int            `json:"end_offset"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+}
+
+// ChunkPage is a paginated list of resource chunks
+type ChunkPage struct {
+	Chunks []Chunk `json:"chunks"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
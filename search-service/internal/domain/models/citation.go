@@ -0,0 +1,37 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Citation links a bracketed marker in an answer, like [1], to the reference it cites
+type Citation struct {
+	Marker    int       `json:"marker"`
+	Reference Reference `json:"reference"`
+}
+
+var citationMarkerRe = regexp.MustCompile(`\[(\d+)\]`)
+
+// ParseCitations maps the markers of an answer to the references, which are
+// numbered from 1 in retrieval order. Citations are ordered by their first
+// occurrence; markers without a matching reference are dropped.
+func ParseCitations(answer string, refs []Reference) []Citation {
+	var citations []Citation
+	seen := make(map[int]bool)
+
+	for _, match := range citationMarkerRe.FindAllStringSubmatch(answer, -1) {
+		marker, err := strconv.Atoi(match[1])
+		if err != nil || marker < 1 || marker > len(refs) || seen[marker] {
+			continue
+		}
+		seen[marker] = true
+
+		citations = append(citations, Citation{
+			Marker:    marker,
+			Reference: refs[marker-1],
+		})
+	}
+
+	return citations
+}
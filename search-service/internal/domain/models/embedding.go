@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"math"
 
 	"github.com/google/uuid"
 )
@@ -38,3 +39,24 @@ func (e *Embedding) Validate() error {
 func (e *Embedding) TableName() string {
 	return "embeddings"
 }
+
+// CosineSimilarity returns the cosine of the angle between two embeddings, 0
+// when they differ in length or one of them is zero
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
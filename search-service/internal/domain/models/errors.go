@@ -6,6 +6,21 @@ import (
 
 var ErrNil = errors.New("received nil")
 
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrTooManyChunks reports a resource producing more chunks than may be indexed
+var ErrTooManyChunks = errors.New("resource produces too many chunks")
+
+// ErrSystemPromptTooLong reports a system prompt over its token budget
+var ErrSystemPromptTooLong = errors.New("system prompt is too long")
+
+// ErrSearchCancelled reports a search abandoned by the client rather than failed
+var ErrSearchCancelled = errors.New("search cancelled")
+
+// ErrModelTimeout reports a call to the embedding or generation model that did
+// not finish in time
+var ErrModelTimeout = errors.New("model call timed out")
+
 type ResourceValidationError error
 
 var (
@@ -0,0 +1,53 @@
+package models
+
+// IndexOption configures a single resource indexation
+type IndexOption func(*IndexOptions)
+
+// IndexOptions holds per-resource indexation settings
+type IndexOptions struct {
+	// OnProgress is called after each stored batch with processed and total chunk counts
+	OnProgress func(processed, total int)
+	// OnChunkLimit is called when the resource exceeded the chunk cap but was still indexed
+	OnChunkLimit func(ChunkLimitReport)
+	// OnChunkHashes is called with the content hashes of the stored chunks, in chunk ID order
+	OnChunkHashes func(hashes []string)
+	// OnDuplicates is called with the number of chunks dropped as duplicates of
+	// earlier ones, when any were
+	OnDuplicates func(dropped int)
+}
+
+// ChunkLimitReport describes how a resource exceeding the chunk cap was indexed
+type ChunkLimitReport struct {
+	Policy   string `json:"policy"`
+	Limit    int    `json:"limit"`
+	Produced int    `json:"produced"`
+	Stored   int    `json:"stored"`
+}
+
+// WithProgress registers a callback receiving indexation progress
+func WithProgress(fn func(processed, total int)) IndexOption {
+	return func(o *IndexOptions) {
+		o.OnProgress = fn
+	}
+}
+
+// WithChunkLimitReport registers a callback receiving the outcome of the chunk cap
+func WithChunkLimitReport(fn func(ChunkLimitReport)) IndexOption {
+	return func(o *IndexOptions) {
+		o.OnChunkLimit = fn
+	}
+}
+
+// WithChunkHashes registers a callback receiving the content hashes of the stored chunks
+func WithChunkHashes(fn func(hashes []string)) IndexOption {
+	return func(o *IndexOptions) {
+		o.OnChunkHashes = fn
+	}
+}
+
+// WithDuplicatesReport registers a callback receiving the number of duplicate chunks dropped
+func WithDuplicatesReport(fn func(dropped int)) IndexOption {
+	return func(o *IndexOptions) {
+		o.OnDuplicates = fn
+	}
+}
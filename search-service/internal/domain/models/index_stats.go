@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// ResourceUsage is what one indexed resource takes up in the embeddings table
+type ResourceUsage struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	// Chunks counts the content chunks, without the metadata chunk
+	Chunks int `json:"chunks"`
+	// StorageBytes approximates the size of the stored rows, vectors included
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// IndexStats sums up what the indexed resources of a user take up
+type IndexStats struct {
+	Resources    int             `json:"resources"`
+	Chunks       int             `json:"chunks"`
+	StorageBytes int64           `json:"storage_bytes"`
+	ByResource   []ResourceUsage `json:"by_resource"`
+}
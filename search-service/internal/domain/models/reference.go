@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -8,4 +10,21 @@ type Reference struct {
 	ResourceID uuid.UUID `json:"resource_id"`
 	Content    string    `json:"content"`
 	Score      float32   `json:"score"`
+	// Highlighted is the content as HTML with the query terms marked, set only
+	// when highlighting was requested
+	Highlighted string `json:"highlighted,omitempty"`
+	// Summary is a one-line summary of the content, set only when summaries
+	// were requested and only on the first references of a search
+	Summary string `json:"summary,omitempty"`
+	// ResourceName and ResourceURL describe the resource of the chunk, empty
+	// when it was deleted or indexed before they were recorded
+	ResourceName string `json:"resource_name,omitempty"`
+	ResourceURL  string `json:"resource_url,omitempty"`
+	OwnerID      string `json:"-"`
+	// Visibility of the resource, references to resources of other users are
+	// public
+	Visibility ResourceVisibility `json:"-"`
+	// CreatedAt is when the resource was created, zero for chunks indexed
+	// before it was recorded
+	CreatedAt time.Time `json:"-"`
 }
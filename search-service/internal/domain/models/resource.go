@@ -22,17 +22,22 @@ type ResourceEvent struct {
 }
 
 type Resource struct {
-	ID               uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	Name             string         `gorm:"type:varchar(255)" json:"name"`
-	Type             ResourceType   `gorm:"type:varchar(100)" json:"type"`
-	URL              string         `gorm:"type:varchar(255)" json:"url,omitempty"`
-	ExtractedContent string         `gorm:"type:text" json:"extracted_content"`
-	RawContent       []byte         `gorm:"type:bytea" json:"raw_content"`
-	ChunkIDs         []string       `gorm:"-" json:"chunk_ids,omitempty"`
-	Status           ResourceStatus `gorm:"type:varchar(50)" json:"status,omitempty"`
-	OwnerID          string         `gorm:"type:varchar(100)" json:"owner_id,omitempty"`
-	CreatedAt        time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	ID               uuid.UUID          `gorm:"type:uuid;primaryKey" json:"id"`
+	Name             string             `gorm:"type:varchar(255)" json:"name"`
+	Type             ResourceType       `gorm:"type:varchar(100)" json:"type"`
+	URL              string             `gorm:"type:varchar(255)" json:"url,omitempty"`
+	ExtractedContent string             `gorm:"type:text" json:"extracted_content"`
+	RawContent       []byte             `gorm:"type:bytea" json:"raw_content"`
+	ChunkIDs         []string           `gorm:"-" json:"chunk_ids,omitempty"`
+	Status           ResourceStatus     `gorm:"type:varchar(50)" json:"status,omitempty"`
+	OwnerID          string             `gorm:"type:varchar(100)" json:"owner_id,omitempty"`
+	TenantID         string             `gorm:"-" json:"tenant_id,omitempty"`
+	Visibility       ResourceVisibility `gorm:"-" json:"visibility,omitempty"`
+	Priority         ResourcePriority   `gorm:"-" json:"priority,omitempty"`
+	ChunkSize        int                `gorm:"-" json:"chunk_size,omitempty"`
+	ChunkOverlap     *int               `gorm:"-" json:"chunk_overlap,omitempty"`
+	CreatedAt        time.Time          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (r *Resource) SetStatusFailed() {
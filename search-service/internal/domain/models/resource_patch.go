@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResourcePatch is the payload of a resource.content_patched event. It lists the
+// indexed chunks the edited content still contains and the new chunks to embed,
+// each at its index in the chunks of the new content.
+type ResourcePatch struct {
+	ResourceID       uuid.UUID          `json:"resource_id"`
+	OwnerID          string             `json:"owner_id"`
+	TenantID         string             `json:"tenant_id,omitempty"`
+	Visibility       ResourceVisibility `json:"visibility,omitempty"`
+	Name             string             `json:"name"`
+	URL              string             `json:"url,omitempty"`
+	Type             ResourceType       `json:"type"`
+	ExtractedContent string             `json:"extracted_content"`
+	CreatedAt        time.Time          `json:"created_at"`
+	RemovedChunkIDs  []string           `json:"removed_chunk_ids"`
+	KeptChunks       []PatchedChunk     `json:"kept_chunks"`
+	AddedChunks      []PatchedChunk     `json:"added_chunks"`
+}
+
+// PatchedChunk is a chunk of a patch. Kept chunks carry their ID, added chunks
+// their content.
+type PatchedChunk struct {
+	ID      string `json:"id,omitempty"`
+	Index   int    `json:"index"`
+	Hash    string `json:"hash"`
+	Content string `json:"content,omitempty"`
+}
+
+// Resource returns the patched resource, used to reindex it as a whole when the
+// patch cannot be applied
+func (p ResourcePatch) Resource() Resource {
+	return Resource{
+		ID:               p.ResourceID,
+		Name:             p.Name,
+		Type:             p.Type,
+		OwnerID:          p.OwnerID,
+		TenantID:         p.TenantID,
+		Visibility:       p.Visibility,
+		ExtractedContent: p.ExtractedContent,
+		CreatedAt:        p.CreatedAt,
+	}
+}
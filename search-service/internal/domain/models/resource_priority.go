@@ -0,0 +1,23 @@
+package models
+
+// ResourcePriority is the indexation priority requested for a resource
+type ResourcePriority string
+
+const (
+	ResourcePriorityHigh   ResourcePriority = "high"
+	ResourcePriorityNormal ResourcePriority = "normal"
+	ResourcePriorityLow    ResourcePriority = "low"
+)
+
+// Rank orders priorities for scheduling, higher ranks are indexed first.
+// Empty and unknown priorities rank as normal.
+func (p ResourcePriority) Rank() int {
+	switch p {
+	case ResourcePriorityHigh:
+		return 2
+	case ResourcePriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
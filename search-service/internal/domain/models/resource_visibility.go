@@ -0,0 +1,17 @@
+package models
+
+// ResourceVisibility tells who finds a resource in their searches
+type ResourceVisibility string
+
+const (
+	ResourceVisibilityPrivate ResourceVisibility = "private"
+	ResourceVisibilityShared  ResourceVisibility = "shared"
+	ResourceVisibilityPublic  ResourceVisibility = "public"
+)
+
+// IsShared reports whether users other than the owner find the resource.
+// Empty and unknown visibilities are private, and so is shared: it is meant
+// for the members of a team, which do not exist yet.
+func (v ResourceVisibility) IsShared() bool {
+	return v == ResourceVisibilityPublic
+}
@@ -3,4 +3,7 @@ package models
 type SearchResult struct {
 	Answer     string      `json:"answer"`
 	References []Reference `json:"references,omitempty"`
+	Citations  []Citation  `json:"citations,omitempty"`
+	// GenerationSkipped is set when only references were requested and Answer is empty by design
+	GenerationSkipped bool `json:"generation_skipped,omitempty"`
 }
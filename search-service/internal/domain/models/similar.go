@@ -0,0 +1,13 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// SimilarResource is an indexed resource whose content is close to another one
+type SimilarResource struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	Score      float32   `json:"score"`
+	// Content is the chunk of the resource closest to the other one
+	Content string `json:"content"`
+}
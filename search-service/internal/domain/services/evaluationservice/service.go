@@ -0,0 +1,225 @@
+package evaluationservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// defaultConcurrency is the number of cases evaluated at once when not configured
+const defaultConcurrency = 4
+
+// ErrNoCases reports an evaluation request without cases
+var ErrNoCases = errors.New("evaluation set is empty")
+
+// answerer produces answers the same way the ask endpoints do
+type answerer interface {
+	GetAnswer(ctx context.Context, question string, opts ...searchservice.SearchOption) (models.SearchResult, error)
+}
+
+// embedder embeds answers to compare them semantically
+type embedder interface {
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Case is a question with its ground truth. Either expectation may be left
+// empty, in which case the case doesn't contribute to the matching metric.
+type Case struct {
+	Question            string      `json:"question" binding:"required"`
+	ExpectedAnswer      string      `json:"expected_answer"`
+	ExpectedResourceIDs []uuid.UUID `json:"expected_resource_ids"`
+}
+
+// CaseResult is the outcome of a single evaluated case
+type CaseResult struct {
+	Question             string      `json:"question"`
+	Answer               string      `json:"answer"`
+	RetrievedResourceIDs []uuid.UUID `json:"retrieved_resource_ids"`
+	// Hit is set when a reference came from one of the expected resources
+	Hit *bool `json:"hit,omitempty"`
+	// AnswerSimilarity is the cosine similarity of the answer and expected answer embeddings
+	AnswerSimilarity *float64 `json:"answer_similarity,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// Report aggregates the results of an evaluation set
+type Report struct {
+	Total  int `json:"total"`
+	Failed int `json:"failed"`
+	// RetrievalHitRate is the share of cases with expected sources that retrieved one of them
+	RetrievalHitRate float64 `json:"retrieval_hit_rate"`
+	// MeanAnswerSimilarity averages AnswerSimilarity over cases with an expected answer
+	MeanAnswerSimilarity float64      `json:"mean_answer_similarity"`
+	Cases                []CaseResult `json:"cases"`
+}
+
+// Service runs evaluation sets against the search pipeline
+type Service struct {
+	answerer    answerer
+	embedder    embedder
+	concurrency int
+}
+
+// NewService creates an evaluation service running at most concurrency cases at once
+func NewService(answerer answerer, embedder embedder, concurrency int) *Service {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	return &Service{
+		answerer:    answerer,
+		embedder:    embedder,
+		concurrency: concurrency,
+	}
+}
+
+// Evaluate answers every case as the calling user and scores the answers
+// against the ground truth. Failing cases are reported, not returned as errors.
+func (s *Service) Evaluate(ctx context.Context, cases []Case) (Report, error) {
+	const op = "EvaluationService.Evaluate"
+
+	if len(cases) == 0 {
+		return Report{}, fmt.Errorf("%s: %w", op, ErrNoCases)
+	}
+
+	slog.InfoContext(ctx, "Running evaluation set",
+		"op", op,
+		"cases", len(cases),
+		"concurrency", s.concurrency)
+
+	results := make([]CaseResult, len(cases))
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+
+	for i, c := range cases {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return Report{}, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = s.evaluateCase(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	return summarize(results), nil
+}
+
+func (s *Service) evaluateCase(ctx context.Context, c Case) CaseResult {
+	const op = "EvaluationService.evaluateCase"
+
+	result := CaseResult{Question: c.Question}
+
+	answer, err := s.answerer.GetAnswer(ctx, c.Question)
+	if err != nil {
+		slog.WarnContext(ctx, "Evaluation case failed",
+			"op", op,
+			"question", c.Question,
+			"error", err)
+		result.Error = err.Error()
+		// Nothing was retrieved, so a failed case misses its expected sources
+		if len(c.ExpectedResourceIDs) > 0 {
+			hit := false
+			result.Hit = &hit
+		}
+		return result
+	}
+
+	result.Answer = answer.Answer
+	result.RetrievedResourceIDs = make([]uuid.UUID, 0, len(answer.References))
+	for _, ref := range answer.References {
+		result.RetrievedResourceIDs = append(result.RetrievedResourceIDs, ref.ResourceID)
+	}
+
+	if len(c.ExpectedResourceIDs) > 0 {
+		hit := containsAny(result.RetrievedResourceIDs, c.ExpectedResourceIDs)
+		result.Hit = &hit
+	}
+
+	if c.ExpectedAnswer != "" {
+		similarity, err := s.answerSimilarity(ctx, c.ExpectedAnswer, answer.Answer)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to score answer similarity",
+				"op", op,
+				"question", c.Question,
+				"error", err)
+			result.Error = err.Error()
+			return result
+		}
+		result.AnswerSimilarity = &similarity
+	}
+
+	return result
+}
+
+func (s *Service) answerSimilarity(ctx context.Context, expected, actual string) (float64, error) {
+	if actual == "" {
+		return 0, nil
+	}
+
+	vectors, err := s.embedder.EmbedDocuments(ctx, []string{expected, actual})
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed answers: %w", err)
+	}
+	if len(vectors) != 2 {
+		return 0, fmt.Errorf("failed to embed answers: got %d embeddings, want 2", len(vectors))
+	}
+
+	return models.CosineSimilarity(vectors[0], vectors[1]), nil
+}
+
+// summarize aggregates case results into the report metrics
+func summarize(results []CaseResult) Report {
+	report := Report{Total: len(results), Cases: results}
+
+	var hits, withSources, withAnswers int
+	var similarity float64
+	for _, result := range results {
+		if result.Error != "" {
+			report.Failed++
+		}
+		if result.Hit != nil {
+			withSources++
+			if *result.Hit {
+				hits++
+			}
+		}
+		if result.AnswerSimilarity != nil {
+			withAnswers++
+			similarity += *result.AnswerSimilarity
+		}
+	}
+
+	if withSources > 0 {
+		report.RetrievalHitRate = float64(hits) / float64(withSources)
+	}
+	if withAnswers > 0 {
+		report.MeanAnswerSimilarity = similarity / float64(withAnswers)
+	}
+	return report
+}
+
+func containsAny(ids []uuid.UUID, expected []uuid.UUID) bool {
+	for _, id := range ids {
+		for _, want := range expected {
+			if id == want {
+				return true
+			}
+		}
+	}
+	return false
+}
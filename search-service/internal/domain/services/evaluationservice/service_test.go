@@ -0,0 +1,145 @@
+package evaluationservice
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// fakeAnswerer answers from a fixed table and records the peak concurrency
+type fakeAnswerer struct {
+	answers map[string]models.SearchResult
+	delay   time.Duration
+
+	active atomic.Int32
+	mu     sync.Mutex
+	peak   int32
+}
+
+func (f *fakeAnswerer) GetAnswer(_ context.Context, question string, _ ...searchservice.SearchOption) (models.SearchResult, error) {
+	active := f.active.Add(1)
+	defer f.active.Add(-1)
+
+	f.mu.Lock()
+	f.peak = max(f.peak, active)
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	result, ok := f.answers[question]
+	if !ok {
+		return models.SearchResult{}, errors.New("generator unavailable")
+	}
+	return result, nil
+}
+
+// fakeEmbedder embeds known texts to fixed vectors
+type fakeEmbedder map[string][]float32
+
+func (f fakeEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, f[text])
+	}
+	return vectors, nil
+}
+
+// EvaluationServiceTestSuite is the test suite for evaluation Service
+type EvaluationServiceTestSuite struct {
+	suite.Suite
+	ctx context.Context
+}
+
+func (suite *EvaluationServiceTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+}
+
+// TestEvaluate_Metrics tests the metrics computed for a tiny evaluation set
+func (suite *EvaluationServiceTestSuite) TestEvaluate_Metrics() {
+	capitals, rivers, other := uuid.New(), uuid.New(), uuid.New()
+
+	answerer := &fakeAnswerer{answers: map[string]models.SearchResult{
+		"capital of France?": {
+			Answer:     "Paris",
+			References: []models.Reference{{ResourceID: other}, {ResourceID: capitals}},
+		},
+		"longest river?": {
+			Answer:     "The Amazon",
+			References: []models.Reference{{ResourceID: other}},
+		},
+	}}
+	embedder := fakeEmbedder{
+		"Paris":      {1, 0},
+		"The Nile":   {0, 1},
+		"The Amazon": {1, 0},
+	}
+	service := NewService(answerer, embedder, 2)
+
+	report, err := service.Evaluate(suite.ctx, []Case{
+		{Question: "capital of France?", ExpectedAnswer: "Paris", ExpectedResourceIDs: []uuid.UUID{capitals}},
+		{Question: "longest river?", ExpectedAnswer: "The Nile", ExpectedResourceIDs: []uuid.UUID{rivers}},
+		{Question: "unanswerable?", ExpectedResourceIDs: []uuid.UUID{capitals}},
+		{Question: "capital of France?"},
+	})
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 4, report.Total)
+	assert.Equal(suite.T(), 1, report.Failed)
+	// One of the three cases with expected sources retrieved one, the failed case counts as a miss
+	assert.InDelta(suite.T(), 1.0/3.0, report.RetrievalHitRate, 1e-9)
+	// Similarities 1 and 0 over the two cases with an expected answer
+	assert.InDelta(suite.T(), 0.5, report.MeanAnswerSimilarity, 1e-9)
+
+	suite.Require().Len(report.Cases, 4)
+	assert.True(suite.T(), *report.Cases[0].Hit)
+	assert.InDelta(suite.T(), 1.0, *report.Cases[0].AnswerSimilarity, 1e-9)
+	assert.False(suite.T(), *report.Cases[1].Hit)
+	assert.InDelta(suite.T(), 0.0, *report.Cases[1].AnswerSimilarity, 1e-9)
+	assert.Equal(suite.T(), "generator unavailable", report.Cases[2].Error)
+	assert.Nil(suite.T(), report.Cases[3].Hit)
+	assert.Nil(suite.T(), report.Cases[3].AnswerSimilarity)
+}
+
+// TestEvaluate_BoundedConcurrency tests that no more cases run at once than configured
+func (suite *EvaluationServiceTestSuite) TestEvaluate_BoundedConcurrency() {
+	answerer := &fakeAnswerer{
+		answers: map[string]models.SearchResult{"question": {Answer: "answer"}},
+		delay:   20 * time.Millisecond,
+	}
+	service := NewService(answerer, fakeEmbedder{}, 2)
+
+	cases := make([]Case, 6)
+	for i := range cases {
+		cases[i] = Case{Question: "question"}
+	}
+
+	report, err := service.Evaluate(suite.ctx, cases)
+
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 6, report.Total)
+	assert.Equal(suite.T(), int32(2), answerer.peak)
+}
+
+// TestEvaluate_NoCases tests that an empty evaluation set is rejected
+func (suite *EvaluationServiceTestSuite) TestEvaluate_NoCases() {
+	service := NewService(&fakeAnswerer{}, fakeEmbedder{}, 2)
+
+	_, err := service.Evaluate(suite.ctx, nil)
+
+	assert.ErrorIs(suite.T(), err, ErrNoCases)
+}
+
+// TestEvaluationServiceTestSuite runs the test suite
+func TestEvaluationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(EvaluationServiceTestSuite))
+}
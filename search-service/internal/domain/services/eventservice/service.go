@@ -6,14 +6,23 @@ import (
 	"log/slog"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
 )
 
 // eventRepository defines the interface for event persistence operations
 type eventRepository interface {
 	CreateEvent(ctx context.Context, event eventmodel.Event) (eventmodel.Event, error)
 	GetNotSentEvents(ctx context.Context, limit int, offset int) ([]eventmodel.Event, error)
+	CountNotSentEvents(ctx context.Context) (int, error)
+	// HasOlderNotSentEvents reports whether an event older than the given one
+	// is still waiting in the outbox
+	HasOlderNotSentEvents(ctx context.Context, event eventmodel.Event) (bool, error)
 	MarkEventAsSent(ctx context.Context, eventID uuid.UUID) error
 }
 
@@ -41,20 +50,49 @@ func NewEventService(repo eventRepository, producer messageProducer) *Service {
 
 // PublishEvent publishes a search-related event using the outbox pattern
 // This method ensures ACID properties by storing the event in the same transaction
-// as the business operation and then attempting immediate delivery
+// as the business operation and then attempting immediate delivery. While older
+// events wait in the outbox, the event is left to the outbox processor, so that
+// it does not overtake them.
 func (s *Service) PublishEvent(ctx context.Context, topic string, eventName string, data interface{}) error {
+	ctx, span := tracing.StartSpan(ctx, "EventService.PublishEvent",
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("event.name", eventName),
+		))
+	err := s.publishEvent(ctx, topic, eventName, data)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (s *Service) publishEvent(ctx context.Context, topic string, eventName string, data interface{}) error {
 	const op = "EventService.PublishEvent"
 
 	event, err := eventmodel.NewEvent(eventName, topic, data)
 	if err != nil {
 		return fmt.Errorf("%s: failed to create event: %w", op, err)
 	}
+	event.Headers = traceHeaders(ctx)
 
 	savedEvent, err := s.eventRepo.CreateEvent(ctx, event)
 	if err != nil {
 		return fmt.Errorf("%s: failed to save event to outbox: %w", op, err)
 	}
 
+	older, err := s.eventRepo.HasOlderNotSentEvents(ctx, savedEvent)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to check for older unsent events, leaving the event to the outbox processor",
+			"error", err,
+			"event_id", savedEvent.ID,
+			"event_name", savedEvent.Name)
+		return nil
+	}
+	if older {
+		slog.InfoContext(ctx, "Older events are still unsent, leaving the event to the outbox processor",
+			"event_id", savedEvent.ID,
+			"event_name", savedEvent.Name)
+		return nil
+	}
+
 	err = s.producer.PublishEvent(ctx, savedEvent)
 	if err != nil {
 		slog.WarnContext(ctx, "Failed to publish event immediately, will retry via outbox processor",
@@ -93,6 +131,18 @@ func (s *Service) GetUnsentEvents(ctx context.Context, limit, offset int) ([]eve
 	return events, nil
 }
 
+// CountUnsentEvents returns how many events are still waiting in the outbox
+func (s *Service) CountUnsentEvents(ctx context.Context) (int, error) {
+	const op = "EventService.CountUnsentEvents"
+
+	count, err := s.eventRepo.CountNotSentEvents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to count unsent events: %w", op, err)
+	}
+
+	return count, nil
+}
+
 // ProcessEvent attempts to publish a single event and marks it as sent if successful
 // This is used by the outbox processor
 func (s *Service) ProcessEvent(ctx context.Context, event eventmodel.Event) error {
@@ -122,3 +172,14 @@ func (s *Service) Health(ctx context.Context) error {
 
 	return nil
 }
+
+// traceHeaders returns the W3C trace context of ctx as event headers, nil when
+// ctx is not traced. The producer restores it when the event is published.
+func traceHeaders(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
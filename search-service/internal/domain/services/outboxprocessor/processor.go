@@ -2,15 +2,21 @@ package outboxprocessor
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"sync"
 	"time"
 
 	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
 )
 
 // eventService defines the interface for event processing operations
 type eventService interface {
+	// GetUnsentEvents returns unsent events oldest first
 	GetUnsentEvents(ctx context.Context, limit, offset int) ([]eventmodel.Event, error)
+	CountUnsentEvents(ctx context.Context) (int, error)
 	ProcessEvent(ctx context.Context, event eventmodel.Event) error
 }
 
@@ -22,8 +28,12 @@ type Config struct {
 	BatchSize int
 	// MaxRetries specifies the maximum number of retry attempts for failed events
 	MaxRetries int
-	// RetryDelay specifies the delay between retry attempts
+	// RetryDelay specifies the delay before the first retry, doubled on every further attempt
 	RetryDelay time.Duration
+	// MaxRetryDelay caps the exponentially growing retry delay
+	MaxRetryDelay time.Duration
+	// RetryJitter randomizes each retry delay by up to the given fraction (0 disables jitter)
+	RetryJitter float64
 }
 
 // Processor handles the reliable delivery of events using the outbox pattern
@@ -33,6 +43,9 @@ type Processor struct {
 	config       Config
 	stopCh       chan struct{}
 	doneCh       chan struct{}
+	after        func(time.Duration) <-chan time.Time
+	// runMu keeps scheduled and manual runs from delivering the same events twice
+	runMu sync.Mutex
 }
 
 // NewOutboxProcessor creates a new outbox processor with the given configuration
@@ -49,22 +62,28 @@ func NewOutboxProcessor(eventService eventService, config Config) *Processor {
 	if config.RetryDelay == 0 {
 		config.RetryDelay = 5 * time.Second
 	}
+	if config.MaxRetryDelay == 0 {
+		config.MaxRetryDelay = time.Minute
+	}
 
 	return &Processor{
 		eventService: eventService,
 		config:       config,
 		stopCh:       make(chan struct{}),
 		doneCh:       make(chan struct{}),
+		after:        time.After,
 	}
 }
 
 // NewDefaultOutboxProcessor creates a new outbox processor with default configuration
 func NewDefaultOutboxProcessor(eventService eventService) *Processor {
 	return NewOutboxProcessor(eventService, Config{
-		Interval:   30 * time.Second,
-		BatchSize:  100,
-		MaxRetries: 3,
-		RetryDelay: 5 * time.Second,
+		Interval:      30 * time.Second,
+		BatchSize:     100,
+		MaxRetries:    3,
+		RetryDelay:    5 * time.Second,
+		MaxRetryDelay: time.Minute,
+		RetryJitter:   0.2,
 	})
 }
 
@@ -80,7 +99,8 @@ func (p *Processor) Start(ctx context.Context) {
 		"interval", p.config.Interval,
 		"batch_size", p.config.BatchSize,
 		"max_retries", p.config.MaxRetries,
-		"retry_delay", p.config.RetryDelay)
+		"retry_delay", p.config.RetryDelay,
+		"max_retry_delay", p.config.MaxRetryDelay)
 
 	for {
 		select {
@@ -104,39 +124,53 @@ func (p *Processor) Stop() {
 
 // processEvents processes a batch of unsent events
 func (p *Processor) processEvents(ctx context.Context) {
-	const op = "OutboxProcessor.processEvents"
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	if _, _, err := p.processBatch(ctx); err == nil {
+		p.reportBacklog(ctx)
+	}
+}
+
+// processBatch processes up to BatchSize unsent events and returns how many
+// were delivered and how many failed, at most one as a failure ends the batch
+func (p *Processor) processBatch(ctx context.Context) (successCount, failureCount int, err error) {
+	const op = "OutboxProcessor.processBatch"
 
 	events, err := p.eventService.GetUnsentEvents(ctx, p.config.BatchSize, 0)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to get unsent events",
 			"op", op,
 			"error", err)
-		return
+		return 0, 0, err
 	}
 
 	if len(events) == 0 {
-		return
+		return 0, 0, nil
 	}
 
 	slog.InfoContext(ctx, "Processing unsent events",
 		"op", op,
 		"count", len(events))
 
-	successCount := 0
-	failureCount := 0
-
+	// Search events are consumed as a history, so they must arrive in the
+	// order they happened. Delivery stops at the first event that still fails
+	// after its retries; the ones behind it wait until it goes out. The event
+	// service does not deliver new events right away while these wait either.
 	for _, event := range events {
 		err := p.processEventWithRetry(ctx, event)
 		if err != nil {
 			failureCount++
-			slog.ErrorContext(ctx, "Failed to process event after retries",
+			metrics.OutboxEventsFailed.Inc()
+			slog.ErrorContext(ctx, "Failed to process event after retries, holding back the later ones",
 				"op", op,
 				"error", err,
 				"event_id", event.ID,
 				"event_name", event.Name)
-		} else {
-			successCount++
+			break
 		}
+		successCount++
+		metrics.OutboxEventsProcessed.Inc()
 	}
 
 	slog.InfoContext(ctx, "Batch processing completed",
@@ -144,6 +178,23 @@ func (p *Processor) processEvents(ctx context.Context) {
 		"total", len(events),
 		"success", successCount,
 		"failed", failureCount)
+
+	return successCount, failureCount, nil
+}
+
+// reportBacklog sets the backlog gauge to the number of events still unsent,
+// which may be many batches
+func (p *Processor) reportBacklog(ctx context.Context) {
+	const op = "OutboxProcessor.reportBacklog"
+
+	count, err := p.eventService.CountUnsentEvents(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to count unsent events",
+			"op", op,
+			"error", err)
+		return
+	}
+	metrics.OutboxUnsentBacklog.Set(float64(count))
 }
 
 // processEventWithRetry attempts to process an event with retry logic
@@ -153,6 +204,10 @@ func (p *Processor) processEventWithRetry(ctx context.Context, event eventmodel.
 	var lastErr error
 
 	for attempt := 1; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 1 {
+			metrics.OutboxRetries.Inc()
+		}
+
 		err := p.eventService.ProcessEvent(ctx, event)
 		if err == nil {
 			if attempt > 1 {
@@ -177,7 +232,7 @@ func (p *Processor) processEventWithRetry(ctx context.Context, event eventmodel.
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(p.config.RetryDelay):
+			case <-p.after(p.retryDelay(attempt)):
 			}
 		}
 	}
@@ -185,11 +240,77 @@ func (p *Processor) processEventWithRetry(ctx context.Context, event eventmodel.
 	return lastErr
 }
 
-// ProcessNow immediately processes any pending events (useful for testing or manual triggers)
-func (p *Processor) ProcessNow(ctx context.Context) error {
+// retryDelay returns RetryDelay * 2^(attempt-1) capped at MaxRetryDelay,
+// randomized by RetryJitter so that concurrent retries do not line up.
+func (p *Processor) retryDelay(attempt int) time.Duration {
+	delay := p.config.RetryDelay
+	for i := 1; i < attempt && delay < p.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.config.MaxRetryDelay {
+		delay = p.config.MaxRetryDelay
+	}
+
+	if p.config.RetryJitter > 0 {
+		spread := float64(delay) * p.config.RetryJitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+
+	return delay
+}
+
+// FlushResult reports the outcome of an immediate outbox flush
+type FlushResult struct {
+	// Processed is the number of events delivered by the flush
+	Processed int `json:"processed"`
+	// Failed is 1 when an event could not be delivered, even after retries,
+	// which stopped the flush, and 0 otherwise
+	Failed int `json:"failed"`
+	// Remaining is the number of events still unsent once the flush is done
+	Remaining int `json:"remaining"`
+}
+
+// ProcessNow immediately processes the pending events batch by batch, without
+// waiting for the next tick. It goes through the events unsent when called
+// once, and stops at an event that fails, leaving it and the ones after it for
+// the regular schedule.
+func (p *Processor) ProcessNow(ctx context.Context) (FlushResult, error) {
 	const op = "OutboxProcessor.ProcessNow"
 
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
 	slog.InfoContext(ctx, "Manual processing of unsent events triggered", "op", op)
-	p.processEvents(ctx)
-	return nil
+
+	backlog, err := p.eventService.CountUnsentEvents(ctx)
+	if err != nil {
+		return FlushResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var result FlushResult
+	for result.Processed < backlog {
+		processed, failed, err := p.processBatch(ctx)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", op, err)
+		}
+		result.Processed += processed
+		result.Failed += failed
+		if processed == 0 || failed > 0 {
+			break
+		}
+	}
+
+	result.Remaining, err = p.eventService.CountUnsentEvents(ctx)
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", op, err)
+	}
+	metrics.OutboxUnsentBacklog.Set(float64(result.Remaining))
+
+	slog.InfoContext(ctx, "Manual processing of unsent events completed",
+		"op", op,
+		"processed", result.Processed,
+		"failed", result.Failed,
+		"remaining", result.Remaining)
+
+	return result, nil
 }
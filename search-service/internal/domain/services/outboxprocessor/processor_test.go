@@ -0,0 +1,146 @@
+package outboxprocessor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+)
+
+// outbox keeps events in memory, oldest first, and fails to deliver the ones
+// in failing. delivered records the delivered events in order.
+type outbox struct {
+	mu        sync.Mutex
+	events    []eventmodel.Event
+	failing   map[uuid.UUID]bool
+	delivered []uuid.UUID
+}
+
+func newOutbox(count int) *outbox {
+	o := &outbox{failing: make(map[uuid.UUID]bool)}
+	created := time.Now().Add(-time.Hour)
+	for i := range count {
+		o.events = append(o.events, eventmodel.Event{
+			ID:        uuid.New(),
+			Name:      "search.performed",
+			EventTime: created.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return o
+}
+
+func (o *outbox) GetUnsentEvents(_ context.Context, limit, offset int) ([]eventmodel.Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var unsent []eventmodel.Event
+	for _, event := range o.events {
+		if !event.Sent {
+			unsent = append(unsent, event)
+		}
+	}
+	if offset >= len(unsent) {
+		return nil, nil
+	}
+	return unsent[offset:min(offset+limit, len(unsent))], nil
+}
+
+func (o *outbox) CountUnsentEvents(_ context.Context) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	count := 0
+	for _, event := range o.events {
+		if !event.Sent {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (o *outbox) ProcessEvent(_ context.Context, event eventmodel.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failing[event.ID] {
+		return errors.New("broker unavailable")
+	}
+	for i := range o.events {
+		if o.events[i].ID == event.ID {
+			o.events[i].Sent = true
+		}
+	}
+	o.delivered = append(o.delivered, event.ID)
+	return nil
+}
+
+func newTestProcessor(o *outbox) *Processor {
+	p := NewOutboxProcessor(o, Config{BatchSize: 2, MaxRetries: 2, RetryDelay: time.Millisecond})
+	p.after = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	return p
+}
+
+func TestProcessEvents_ReportsWholeBacklog(t *testing.T) {
+	o := newOutbox(5)
+
+	// A scheduled run delivers one batch of two, three events are left
+	newTestProcessor(o).processEvents(context.Background())
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.OutboxUnsentBacklog))
+}
+
+func TestProcessNow_DrainsEveryBatch(t *testing.T) {
+	o := newOutbox(5)
+
+	result, err := newTestProcessor(o).ProcessNow(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, FlushResult{Processed: 5, Failed: 0, Remaining: 0}, result)
+}
+
+func TestProcessNow_StopsAtFailingEvent(t *testing.T) {
+	o := newOutbox(5)
+	o.failing[o.events[3].ID] = true
+
+	result, err := newTestProcessor(o).ProcessNow(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, FlushResult{Processed: 3, Failed: 1, Remaining: 2}, result)
+}
+
+func TestProcessNow_DeliversInEventTimeOrder(t *testing.T) {
+	o := newOutbox(7)
+	o.failing[o.events[2].ID] = true
+
+	p := newTestProcessor(o)
+	_, err := p.ProcessNow(context.Background())
+	require.NoError(t, err)
+
+	// Nothing after the failed event goes out before it
+	assert.Equal(t, []uuid.UUID{o.events[0].ID, o.events[1].ID}, o.delivered)
+
+	// Once it is delivered, the rest follow in order
+	delete(o.failing, o.events[2].ID)
+	result, err := p.ProcessNow(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, FlushResult{Processed: 5, Remaining: 0}, result)
+	var want []uuid.UUID
+	for _, event := range o.events {
+		want = append(want, event.ID)
+	}
+	assert.Equal(t, want, o.delivered)
+}
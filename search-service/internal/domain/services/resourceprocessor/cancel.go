@@ -0,0 +1,173 @@
+package resourceprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// indexationCancelledEvent is published by the resource-service when the owner
+// cancels the indexation of a resource
+const indexationCancelledEvent = "resource.indexation_cancelled"
+
+// errIndexingCancelled is the cause of indexations cancelled by the owner of the resource
+var errIndexingCancelled = errors.New("indexing cancelled")
+
+// IndexationCancelledEvent represents the cancellation of a resource indexation
+type IndexationCancelledEvent struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	OwnerID    string    `json:"owner_id"`
+}
+
+// indexings tracks the indexations of resources, so that they can be cancelled
+// while running or while their message waits for a worker
+type indexings struct {
+	mu        sync.Mutex
+	running   map[uuid.UUID]*runningIndexing
+	queued    map[uuid.UUID]int // Messages handed to a worker and not started yet
+	cancelled map[uuid.UUID]int // Queued messages to skip
+}
+
+type runningIndexing struct {
+	cancel context.CancelCauseFunc
+}
+
+func newIndexings() *indexings {
+	return &indexings{
+		running:   make(map[uuid.UUID]*runningIndexing),
+		queued:    make(map[uuid.UUID]int),
+		cancelled: make(map[uuid.UUID]int),
+	}
+}
+
+// queue records a message of the resource handed to a worker
+func (t *indexings) queue(resourceID uuid.UUID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.queued[resourceID]++
+}
+
+// dequeue records that a queued message of the resource left the backlog and
+// reports whether it was cancelled meanwhile
+func (t *indexings) dequeue(resourceID uuid.UUID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.queued[resourceID]--; t.queued[resourceID] <= 0 {
+		delete(t.queued, resourceID)
+	}
+	if t.cancelled[resourceID] == 0 {
+		return false
+	}
+	if t.cancelled[resourceID]--; t.cancelled[resourceID] == 0 {
+		delete(t.cancelled, resourceID)
+	}
+	return true
+}
+
+// start registers a running indexation of the resource, cancelled through the
+// returned context. The returned function unregisters it and reports whether
+// it was cancelled.
+func (t *indexings) start(ctx context.Context, resourceID uuid.UUID) (context.Context, func() bool) {
+	indexCtx, cancel := context.WithCancelCause(ctx)
+	indexing := &runningIndexing{cancel: cancel}
+
+	t.mu.Lock()
+	t.running[resourceID] = indexing
+	t.mu.Unlock()
+
+	return indexCtx, func() bool {
+		t.mu.Lock()
+		if t.running[resourceID] == indexing {
+			delete(t.running, resourceID)
+		}
+		t.mu.Unlock()
+
+		cancelled := isCancelled(indexCtx)
+		cancel(nil)
+		return cancelled
+	}
+}
+
+// cancel stops the running indexation of the resource and marks its queued
+// messages to be skipped. It reports whether there was any to cancel.
+func (t *indexings) cancel(resourceID uuid.UUID) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	found := false
+	if indexing, ok := t.running[resourceID]; ok {
+		indexing.cancel(errIndexingCancelled)
+		found = true
+	}
+	if queued := t.queued[resourceID]; queued > 0 {
+		t.cancelled[resourceID] = queued
+		found = true
+	}
+	return found
+}
+
+// cancelIndexation handles a resource.indexation_cancelled event. It is handled
+// as soon as it arrives rather than after the messages queued before it, which
+// include the indexation it cancels.
+func (p *Processor) cancelIndexation(ctx context.Context, value []byte) error {
+	const op = "ResourceProcessor.cancelIndexation"
+
+	var event IndexationCancelledEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal indexation cancellation",
+			"op", op,
+			"error", err)
+		return fmt.Errorf("%s: failed to unmarshal indexation cancellation: %w", op, err)
+	}
+
+	if p.indexings.cancel(event.ResourceID) {
+		// The indexation drops its chunks once it stopped
+		slog.InfoContext(ctx, "Cancelling resource indexation",
+			"resource_id", event.ResourceID)
+		return nil
+	}
+
+	// The indexation may have finished before the cancellation arrived
+	slog.InfoContext(ctx, "No indexation running, dropping indexed chunks",
+		"resource_id", event.ResourceID)
+	if err := p.dropCancelledChunks(ctx, event.ResourceID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// startIndexing registers the indexation of the resource so that its owner can
+// cancel it. The returned function must be called once the indexation ended,
+// it drops the chunks a cancelled indexation stored.
+func (p *Processor) startIndexing(ctx context.Context, resourceID uuid.UUID) (context.Context, func()) {
+	indexCtx, finish := p.indexings.start(ctx, resourceID)
+	return indexCtx, func() {
+		if finish() {
+			_ = p.dropCancelledChunks(ctx, resourceID)
+		}
+	}
+}
+
+// dropCancelledChunks removes the chunks of a resource whose indexation was
+// cancelled and evicts the search results they contributed to
+func (p *Processor) dropCancelledChunks(ctx context.Context, resourceID uuid.UUID) error {
+	if err := p.dropResourceChunks(ctx, resourceID); err != nil {
+		return err
+	}
+	if p.cache != nil {
+		p.cache.InvalidateResource(ctx, resourceID)
+	}
+	return nil
+}
+
+// isCancelled reports whether the indexation on ctx was cancelled by the owner
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errIndexingCancelled)
+}
@@ -0,0 +1,39 @@
+package resourceprocessor
+
+import (
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// Config holds resource processor concurrency settings
+type Config struct {
+	// Workers is the number of goroutines indexing resources. Zero uses the
+	// partition count of the resource topic.
+	Workers int `yaml:"workers" mapstructure:"workers" validate:"min=0"`
+	// Backlog is how many messages a worker buffers before the consumer waits
+	// for it, so a slow embedder holds back consumption instead of memory growing
+	Backlog int `yaml:"backlog" mapstructure:"backlog" validate:"min=1"`
+	// Timeout bounds the indexation of a single resource, after which it is
+	// reported as failed. Zero disables the limit.
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout" validate:"min=0"`
+	// RetryAttempts is the number of times a resource failing transiently is
+	// indexed before it is reported as failed; 1 disables retrying
+	RetryAttempts int `yaml:"retry_attempts" mapstructure:"retry_attempts" validate:"min=1"`
+	// RetryDelay is the wait before the first retry, doubled for every further one
+	RetryDelay time.Duration `yaml:"retry_delay" mapstructure:"retry_delay" validate:"min=0"`
+	// RetryMaxDelay caps the doubling wait, zero leaves it uncapped
+	RetryMaxDelay time.Duration `yaml:"retry_max_delay" mapstructure:"retry_max_delay" validate:"min=0"`
+}
+
+// NewConfig loads resource processor configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("resource_processor", Config{
+		Workers:       0,
+		Backlog:       4,
+		Timeout:       20 * time.Minute,
+		RetryAttempts: 3,
+		RetryDelay:    2 * time.Second,
+		RetryMaxDelay: 30 * time.Second,
+	})
+}
@@ -0,0 +1,66 @@
+package resourceprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// metadataUpdatedEvent is published by the resource-service when a resource
+// was renamed without changing its content, which needs no reindexation
+const metadataUpdatedEvent = "resource.metadata_updated"
+
+// MetadataUpdatedEvent represents a change of the metadata of a resource
+type MetadataUpdatedEvent struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	OwnerID    string    `json:"owner_id"`
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Visibility is carried over to the replaced metadata chunk
+	Visibility models.ResourceVisibility `json:"visibility,omitempty"`
+}
+
+// updateMetadata handles a resource.metadata_updated event. The content chunks
+// stay as they are, only the chunk embedding the metadata is replaced.
+func (p *Processor) updateMetadata(ctx context.Context, value []byte) error {
+	const op = "ResourceProcessor.updateMetadata"
+
+	var event MetadataUpdatedEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal metadata update",
+			"op", op,
+			"error", err)
+		return fmt.Errorf("%s: failed to unmarshal metadata update: %w", op, err)
+	}
+
+	resource := models.Resource{
+		ID:         event.ResourceID,
+		Name:       event.Name,
+		URL:        event.URL,
+		OwnerID:    event.OwnerID,
+		TenantID:   event.TenantID,
+		Visibility: event.Visibility,
+		CreatedAt:  event.CreatedAt,
+	}
+	ctx = withOwner(ctx, resource.OwnerID, resource.TenantID)
+
+	if err := p.vectorStorage.UpdateResourceMetadata(ctx, resource); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Queries may match the new name through the metadata chunk
+	p.invalidateCache(ctx, metadataUpdatedEvent, resource)
+
+	slog.InfoContext(ctx, "Resource metadata updated",
+		"resource_id", resource.ID,
+		"resource_name", resource.Name)
+	return nil
+}
@@ -0,0 +1,87 @@
+package resourceprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// workerPool runs message handling on a fixed set of goroutines. Messages of the
+// same resource always go to the same worker, which handles them one at a time
+// in arrival order, so their indexation_complete events keep that order too.
+type workerPool struct {
+	jobs []chan func(context.Context)
+	wg   sync.WaitGroup
+}
+
+func newWorkerPool(workers, backlog int) *workerPool {
+	workers = max(workers, 1)
+	backlog = max(backlog, 0)
+
+	pool := &workerPool{jobs: make([]chan func(context.Context), workers)}
+	for i := range pool.jobs {
+		pool.jobs[i] = make(chan func(context.Context), backlog)
+	}
+	return pool
+}
+
+// run starts the workers. They stop taking jobs once ctx is done and cancel the
+// job in progress.
+func (wp *workerPool) run(ctx context.Context) {
+	for _, jobs := range wp.jobs {
+		wp.wg.Add(1)
+		go func() {
+			defer wp.wg.Done()
+			for {
+				select {
+				case job := <-jobs:
+					job(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// dispatch queues job on the worker owning key. It blocks while that worker's
+// backlog is full, until ctx is done.
+func (wp *workerPool) dispatch(ctx context.Context, key string, job func(context.Context)) error {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	jobs := wp.jobs[hash.Sum32()%uint32(len(wp.jobs))]
+
+	select {
+	case jobs <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wait blocks until the workers have stopped
+func (wp *workerPool) wait() {
+	wp.wg.Wait()
+}
+
+// routingKey returns the resource a message is about. Resource events carry it
+// as id, patches as resource_id. The message key is used when neither is set.
+func routingKey(key string, value []byte) string {
+	var ids struct {
+		ID         uuid.UUID `json:"id"`
+		ResourceID uuid.UUID `json:"resource_id"`
+	}
+	_ = json.Unmarshal(value, &ids)
+
+	switch {
+	case ids.ResourceID != uuid.Nil:
+		return ids.ResourceID.String()
+	case ids.ID != uuid.Nil:
+		return ids.ID.String()
+	default:
+		return key
+	}
+}
@@ -3,19 +3,26 @@ package resourceprocessor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
 	"github.com/nzb3/diploma/search-service/internal/domain/models"
 	"github.com/nzb3/diploma/search-service/internal/repository/messaging"
 )
 
 // vectorStorage defines the interface for vector storage operations
 type vectorStorage interface {
-	PutResource(ctx context.Context, resource models.Resource) ([]string, error)
+	PutResource(ctx context.Context, resource models.Resource, opts ...models.IndexOption) ([]string, error)
+	PatchResource(ctx context.Context, patch models.ResourcePatch, opts ...models.IndexOption) ([]string, error)
+	DeleteResource(ctx context.Context, resourceID uuid.UUID) (int64, error)
+	UpdateResourceMetadata(ctx context.Context, resource models.Resource) error
+	UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, visibility models.ResourceVisibility) error
 }
 
 // eventService defines the interface for event publishing operations
@@ -23,19 +30,60 @@ type eventService interface {
 	PublishEvent(ctx context.Context, topic string, eventName string, data interface{}) error
 }
 
+// cacheInvalidator evicts cached search results affected by resource changes
+type cacheInvalidator interface {
+	InvalidateResource(ctx context.Context, resourceID uuid.UUID)
+	InvalidateUser(ctx context.Context, userID string)
+}
+
 // IndexationCompleteEvent represents the event published after indexation
 type IndexationCompleteEvent struct {
 	ResourceID uuid.UUID `json:"resource_id"`
 	Success    bool      `json:"success"`
 	Message    string    `json:"message"`
 	ChunkIDs   []string  `json:"chunk_ids,omitempty"`
+	// ChunkHashes are the content hashes of the chunks, in the order of ChunkIDs
+	ChunkHashes []string `json:"chunk_hashes,omitempty"`
+	// ChunkLimit is set when only part or a summary of the resource was indexed
+	ChunkLimit *models.ChunkLimitReport `json:"chunk_limit,omitempty"`
+	// DuplicateChunks is the number of chunks not stored because they repeat
+	// another chunk of the resource
+	DuplicateChunks int `json:"duplicate_chunks,omitempty"`
 }
 
+// indexResult describes the chunks stored for a resource
+type indexResult struct {
+	chunkIDs []string
+	// chunkHashes are the content hashes of the chunks, in the order of chunkIDs
+	chunkHashes []string
+	// chunkLimit is set when the resource exceeded the chunk cap
+	chunkLimit *models.ChunkLimitReport
+	// duplicates is the number of chunks dropped as duplicates
+	duplicates int
+}
+
+// IndexationProgressEvent represents an intermediate indexation progress event
+type IndexationProgressEvent struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	Percent    int       `json:"percent"`
+}
+
+// errIndexingTimeout is the cause of indexations cancelled for exceeding the timeout
+var errIndexingTimeout = errors.New("indexing timed out")
+
 // Processor handles resource indexation events from the resource-service
 type Processor struct {
 	vectorStorage vectorStorage
 	eventService  eventService
 	consumer      messaging.MessageConsumer
+	cache         cacheInvalidator // Optional search cache
+	queue         *indexQueue
+	indexings     *indexings
+	pool          *workerPool   // Optional, messages are handled inline without it
+	timeout       time.Duration // Zero leaves indexation unbounded
+	retryAttempts int           // Indexation calls per resource, one or less disables retrying
+	retryDelay    time.Duration
+	retryMaxDelay time.Duration // Zero leaves the doubling delay uncapped
 	stopCh        chan struct{}
 	doneCh        chan struct{}
 	wg            sync.WaitGroup
@@ -46,20 +94,63 @@ func NewResourceProcessor(
 	vectorStorage vectorStorage,
 	eventService eventService,
 	consumer messaging.MessageConsumer,
+	cache ...cacheInvalidator,
 ) *Processor {
-	return &Processor{
+	processor := &Processor{
 		vectorStorage: vectorStorage,
 		eventService:  eventService,
 		consumer:      consumer,
+		queue:         newIndexQueue(defaultIndexingSlots),
+		indexings:     newIndexings(),
 		stopCh:        make(chan struct{}),
 		doneCh:        make(chan struct{}),
 	}
+	if len(cache) > 0 {
+		processor.cache = cache[0]
+	}
+	return processor
+}
+
+// WithWorkers makes the processor handle messages on a pool of workers instead
+// of the consumer's partition loops, with as many indexing slots as workers.
+// backlog bounds the messages buffered per worker. It must be called before Start.
+func (p *Processor) WithWorkers(workers, backlog int) *Processor {
+	p.pool = newWorkerPool(workers, backlog)
+	p.queue = newIndexQueue(max(defaultIndexingSlots, len(p.pool.jobs)))
+	return p
+}
+
+// WithTimeout bounds the indexation of each resource; the time spent waiting
+// for an indexing slot does not count. It must be called before Start.
+func (p *Processor) WithTimeout(timeout time.Duration) *Processor {
+	p.timeout = timeout
+	return p
+}
+
+// WithRetry retries indexations failing transiently, such as while Ollama
+// restarts, making up to attempts calls in total. The first retry waits delay,
+// which doubles for every further one up to maxDelay. Retries count towards
+// the indexation timeout. It must be called before Start.
+func (p *Processor) WithRetry(attempts int, delay, maxDelay time.Duration) *Processor {
+	p.retryAttempts = attempts
+	p.retryDelay = delay
+	p.retryMaxDelay = maxDelay
+	return p
 }
 
 // Start begins listening for resource created events
 func (p *Processor) Start(ctx context.Context) error {
 	defer close(p.doneCh)
 
+	if p.pool != nil {
+		poolCtx, cancel := context.WithCancel(ctx)
+		p.pool.run(poolCtx)
+		defer p.pool.wait()
+		defer cancel()
+
+		slog.InfoContext(ctx, "Resource processor workers started", "workers", len(p.pool.jobs))
+	}
+
 	topics := []string{"resource"}
 
 	err := p.consumer.Subscribe(ctx, topics, p)
@@ -91,30 +182,139 @@ func (p *Processor) Stop() {
 	<-p.doneCh
 }
 
-// HandleMessage implements the MessageHandler interface
+// HandleMessage implements the MessageHandler interface. It returns once the
+// message was handled, also when a worker pool handles it.
 func (p *Processor) HandleMessage(ctx context.Context, topic string, key string, value []byte, headers map[string]string) error {
+	finished := make(chan error, 1)
+	if err := p.HandleMessageAsync(ctx, topic, key, value, headers, func(err error) {
+		finished <- err
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-finished:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HandleMessageAsync implements the AsyncMessageHandler interface. With a worker
+// pool the message is handed to the worker of its resource and done is called
+// when the worker finished it, so the consumer commits its offset only then.
+func (p *Processor) HandleMessageAsync(ctx context.Context, topic string, key string, value []byte, headers map[string]string, done func(error)) error {
 	const op = "ResourceProcessor.HandleMessage"
 
 	if topic != "resource" {
+		done(nil)
 		return nil
 	}
 
+	// Not queued behind the indexation it cancels
+	if headers["event-name"] == indexationCancelledEvent {
+		if err := p.cancelIndexation(ctx, value); err != nil {
+			return err
+		}
+		done(nil)
+		return nil
+	}
+
+	if p.pool == nil {
+		if err := p.handleMessage(ctx, key, value, headers); err != nil {
+			return err
+		}
+		done(nil)
+		return nil
+	}
+
+	resourceID, indexing := indexedResource(key, value, headers)
+	if indexing {
+		p.indexings.queue(resourceID)
+	}
+
+	// The job outlives this call, so it keeps the values of the message context,
+	// such as the trace, but not its cancellation, which comes with a rebalance
+	msgCtx := context.WithoutCancel(ctx)
+	err := p.pool.dispatch(ctx, routingKey(key, value), func(workerCtx context.Context) {
+		if indexing && p.indexings.dequeue(resourceID) {
+			slog.InfoContext(msgCtx, "Skipping cancelled resource indexation",
+				"resource_id", resourceID)
+			// Chunks of an earlier indexation are dropped like those of a running one
+			_ = p.dropCancelledChunks(msgCtx, resourceID)
+			done(nil)
+			return
+		}
+
+		jobCtx, cancel := context.WithCancel(msgCtx)
+		defer cancel()
+		stop := context.AfterFunc(workerCtx, cancel)
+		defer stop()
+
+		err := p.handleMessage(jobCtx, key, value, headers)
+		if err != nil {
+			slog.ErrorContext(jobCtx, "Failed to handle resource message",
+				"op", op,
+				"key", key,
+				"error", err)
+		}
+		done(err)
+	})
+	if err != nil {
+		if indexing {
+			p.indexings.dequeue(resourceID)
+		}
+		return fmt.Errorf("%s: waiting for worker: %w", op, err)
+	}
+	return nil
+}
+
+// indexedResource returns the resource a message indexes, if it indexes one
+func indexedResource(key string, value []byte, headers map[string]string) (uuid.UUID, bool) {
+	switch headers["event-name"] {
+	case "resource.created", "resource.updated", "resource.content_patched":
+	default:
+		return uuid.Nil, false
+	}
+
+	resourceID, err := uuid.Parse(routingKey(key, value))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return resourceID, true
+}
+
+// handleMessage indexes or evicts the resource of a message from the resource topic
+func (p *Processor) handleMessage(ctx context.Context, key string, value []byte, headers map[string]string) error {
+	const op = "ResourceProcessor.HandleMessage"
+
 	p.wg.Add(1)
 	defer p.wg.Done()
 
 	slog.DebugContext(ctx, "Processing resource message",
-		"topic", topic,
 		"key", key,
 		"headers", headers)
 
-	// Check if this is a resource.created event
+	// Only created, updated, patched, renamed, shared and deleted resources affect the index or the search cache
 	eventName, exists := headers["event-name"]
-	if !exists || eventName != "resource.created" {
-		slog.DebugContext(ctx, "Ignoring non-resource.created event",
+	if !exists || (eventName != "resource.created" && eventName != "resource.updated" &&
+		eventName != "resource.content_patched" && eventName != metadataUpdatedEvent &&
+		eventName != visibilityUpdatedEvent && eventName != "resource.deleted") {
+		slog.DebugContext(ctx, "Ignoring event without indexation work",
 			"event_name", eventName)
 		return nil
 	}
 
+	if eventName == "resource.content_patched" {
+		return p.patchResource(ctx, value)
+	}
+	if eventName == metadataUpdatedEvent {
+		return p.updateMetadata(ctx, value)
+	}
+	if eventName == visibilityUpdatedEvent {
+		return p.updateVisibility(ctx, value)
+	}
+
 	// Parse the resource from the message payload
 	var resource models.Resource
 	if err := json.Unmarshal(value, &resource); err != nil {
@@ -124,63 +324,399 @@ func (p *Processor) HandleMessage(ctx context.Context, topic string, key string,
 		return fmt.Errorf("%s: failed to unmarshal resource: %w", op, err)
 	}
 
+	p.invalidateCache(ctx, eventName, resource)
+	if eventName == "resource.deleted" {
+		return nil
+	}
+
+	// Chunks are stored for the owner, as there is no request with a user
+	ctx = withOwner(ctx, resource.OwnerID, resource.TenantID)
+
+	// The owner may cancel the indexation, events are still published on ctx
+	jobCtx, finish := p.startIndexing(ctx, resource.ID)
+	defer finish()
+
+	// Messages from different partitions compete for indexing slots, so a
+	// high priority resource overtakes others that are still waiting
+	if err := p.queue.acquire(jobCtx, resource.Priority); err != nil {
+		if isCancelled(jobCtx) {
+			slog.InfoContext(ctx, "Resource indexation cancelled while waiting",
+				"resource_id", resource.ID)
+			return nil
+		}
+		return fmt.Errorf("%s: waiting for indexing slot: %w", op, err)
+	}
+	defer p.queue.release()
+
 	slog.InfoContext(ctx, "Processing resource for indexation",
 		"resource_id", resource.ID,
 		"resource_name", resource.Name,
-		"resource_type", resource.Type)
+		"resource_type", resource.Type,
+		"priority", resource.Priority)
+
+	// Events are published on ctx, so a failure is reported even after a timeout
+	indexCtx, cancel := p.withIndexingTimeout(jobCtx)
+	defer cancel()
+
+	// Updated resources may have new content or type, so drop their old chunks first
+	if eventName == "resource.updated" {
+		if err := p.dropResourceChunks(indexCtx, resource.ID); err != nil {
+			p.publishIndexationEvent(ctx, resource.ID, false, failureMessage(indexCtx, err), indexResult{})
+			return fmt.Errorf("%s: failed to reindex resource: %w", op, err)
+		}
+	}
 
 	// Process the resource
-	chunkIDs, err := p.processResource(ctx, resource)
+	result, err := p.indexResource(indexCtx, resource, p.newProgressHandler(ctx, resource.ID))
 	if err != nil {
 		// Publish failure event
-		p.publishIndexationEvent(ctx, resource.ID, false, err.Error(), nil)
+		p.publishIndexationEvent(ctx, resource.ID, false, failureMessage(indexCtx, err), indexResult{})
+		if isCancelled(indexCtx) {
+			slog.InfoContext(ctx, "Resource indexation cancelled",
+				"resource_id", resource.ID)
+			return nil
+		}
 		return fmt.Errorf("%s: failed to process resource: %w", op, err)
 	}
 
 	// Publish success event
-	p.publishIndexationEvent(ctx, resource.ID, true, "Resource indexed successfully", chunkIDs)
+	p.publishIndexationEvent(ctx, resource.ID, true, indexedMessage(result.chunkLimit), result)
 
 	slog.InfoContext(ctx, "Resource processed successfully",
 		"resource_id", resource.ID,
-		"chunks_count", len(chunkIDs))
+		"chunks_count", len(result.chunkIDs),
+		"duplicates_dropped", result.duplicates)
 
 	return nil
 }
 
-// processResource handles the actual resource processing
-func (p *Processor) processResource(ctx context.Context, resource models.Resource) ([]string, error) {
+// indexResource processes the resource, retrying transient failures as
+// configured with WithRetry. Chunks stored by a failed attempt are dropped
+// before the next one, so that a retry doesn't index them twice, and after the
+// last one, so that a failed resource doesn't leave part of its chunks
+// searchable. Every attempt reports to the same progress callback.
+func (p *Processor) indexResource(ctx context.Context, resource models.Resource, progress func(processed, total int)) (indexResult, error) {
+	const op = "ResourceProcessor.indexResource"
+
+	delay := p.retryDelay
+	for attempt := 1; ; attempt++ {
+		result, err := p.processResource(ctx, resource, progress)
+		if err == nil {
+			return result, nil
+		}
+		if attempt >= p.retryAttempts || !retryable(ctx, err) {
+			return indexResult{}, p.dropFailedChunks(ctx, resource.ID, err)
+		}
+
+		slog.WarnContext(ctx, "Indexation failed, retrying",
+			"op", op,
+			"resource_id", resource.ID,
+			"attempt", attempt+1,
+			"delay", delay,
+			"error", err)
+
+		if waitErr := waitRetry(ctx, delay); waitErr != nil {
+			return indexResult{}, p.dropFailedChunks(ctx, resource.ID, err)
+		}
+		if dropErr := p.dropResourceChunks(ctx, resource.ID); dropErr != nil {
+			return indexResult{}, errors.Join(err, dropErr)
+		}
+
+		delay *= 2
+		if p.retryMaxDelay > 0 && delay > p.retryMaxDelay {
+			delay = p.retryMaxDelay
+		}
+	}
+}
+
+// retryable reports whether a failed indexation may succeed when repeated,
+// which it won't once it was cancelled or timed out, nor for resources over
+// the chunk cap
+func retryable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, models.ErrTooManyChunks)
+}
+
+// waitRetry blocks for delay or until ctx is done, releasing its timer either way
+func waitRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// processResource handles the actual resource processing. The chunk limit report
+// is nil unless the resource exceeded the chunk cap.
+func (p *Processor) processResource(ctx context.Context, resource models.Resource, progress func(processed, total int)) (indexResult, error) {
 	const op = "ResourceProcessor.processResource"
 
 	slog.DebugContext(ctx, "Starting resource processing",
 		"resource_id", resource.ID,
 		"content_length", len(resource.ExtractedContent))
 
+	var result indexResult
+
 	// Use the PutResource method to store the resource in vector storage
-	chunkIDs, err := p.vectorStorage.PutResource(ctx, resource)
+	chunkIDs, err := p.vectorStorage.PutResource(ctx, resource,
+		models.WithProgress(progress),
+		models.WithChunkLimitReport(func(report models.ChunkLimitReport) {
+			result.chunkLimit = &report
+		}),
+		models.WithChunkHashes(func(hashes []string) {
+			result.chunkHashes = hashes
+		}),
+		models.WithDuplicatesReport(func(dropped int) {
+			result.duplicates = dropped
+		}),
+	)
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to store resource in vector storage",
 			"op", op,
 			"resource_id", resource.ID,
 			"error", err)
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return indexResult{}, fmt.Errorf("%s: %w", op, err)
 	}
+	result.chunkIDs = chunkIDs
 
 	slog.InfoContext(ctx, "Resource stored in vector storage",
 		"resource_id", resource.ID,
 		"chunks_created", len(chunkIDs))
 
-	return chunkIDs, nil
+	return result, nil
+}
+
+// patchResource handles a resource.content_patched event by embedding only the
+// chunks the edit added. A patch that cannot be applied, e.g. because another
+// update reindexed the resource meanwhile, falls back to reindexing the whole
+// patched content.
+func (p *Processor) patchResource(ctx context.Context, value []byte) error {
+	const op = "ResourceProcessor.patchResource"
+
+	var patch models.ResourcePatch
+	if err := json.Unmarshal(value, &patch); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal resource patch",
+			"op", op,
+			"error", err)
+		return fmt.Errorf("%s: failed to unmarshal resource patch: %w", op, err)
+	}
+
+	if p.cache != nil {
+		p.cache.InvalidateResource(ctx, patch.ResourceID)
+		if patch.OwnerID != "" {
+			p.cache.InvalidateUser(ctx, patch.OwnerID)
+		}
+	}
+
+	ctx = withOwner(ctx, patch.OwnerID, patch.TenantID)
+
+	jobCtx, finish := p.startIndexing(ctx, patch.ResourceID)
+	defer finish()
+
+	if err := p.queue.acquire(jobCtx, models.ResourcePriorityNormal); err != nil {
+		if isCancelled(jobCtx) {
+			return nil
+		}
+		return fmt.Errorf("%s: waiting for indexing slot: %w", op, err)
+	}
+	defer p.queue.release()
+
+	slog.InfoContext(ctx, "Patching resource chunks",
+		"resource_id", patch.ResourceID,
+		"kept", len(patch.KeptChunks),
+		"added", len(patch.AddedChunks),
+		"removed", len(patch.RemovedChunkIDs))
+
+	indexCtx, cancel := p.withIndexingTimeout(jobCtx)
+	defer cancel()
+
+	progress := p.newProgressHandler(ctx, patch.ResourceID)
+
+	var chunkHashes []string
+	chunkIDs, err := p.vectorStorage.PatchResource(indexCtx, patch,
+		models.WithProgress(progress),
+		models.WithChunkHashes(func(hashes []string) {
+			chunkHashes = hashes
+		}),
+	)
+	if err == nil {
+		p.publishIndexationEvent(ctx, patch.ResourceID, true, "Resource patched successfully",
+			indexResult{chunkIDs: chunkIDs, chunkHashes: chunkHashes})
+		return nil
+	}
+
+	if indexCtx.Err() != nil {
+		p.publishIndexationEvent(ctx, patch.ResourceID, false, failureMessage(indexCtx, err), indexResult{})
+		return fmt.Errorf("%s: failed to patch resource: %w", op, p.dropFailedChunks(indexCtx, patch.ResourceID, err))
+	}
+
+	slog.WarnContext(ctx, "Failed to patch resource, reindexing it",
+		"op", op,
+		"resource_id", patch.ResourceID,
+		"error", err)
+
+	if err := p.dropResourceChunks(indexCtx, patch.ResourceID); err != nil {
+		p.publishIndexationEvent(ctx, patch.ResourceID, false, failureMessage(indexCtx, err), indexResult{})
+		return fmt.Errorf("%s: failed to reindex resource: %w", op, err)
+	}
+
+	result, err := p.indexResource(indexCtx, patch.Resource(), progress)
+	if err != nil {
+		p.publishIndexationEvent(ctx, patch.ResourceID, false, failureMessage(indexCtx, err), indexResult{})
+		return fmt.Errorf("%s: failed to process resource: %w", op, err)
+	}
+
+	p.publishIndexationEvent(ctx, patch.ResourceID, true, indexedMessage(result.chunkLimit), result)
+	return nil
+}
+
+// withIndexingTimeout returns the context a resource is indexed on, cancelled
+// with errIndexingTimeout once the configured timeout elapsed
+func (p *Processor) withIndexingTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, p.timeout, fmt.Errorf("%w after %s", errIndexingTimeout, p.timeout))
+}
+
+// failureMessage describes a failed indexation, naming the timeout or the
+// cancellation when it was the reason rather than the error it caused further down
+func failureMessage(indexCtx context.Context, err error) string {
+	if cause := context.Cause(indexCtx); errors.Is(cause, errIndexingTimeout) || errors.Is(cause, errIndexingCancelled) {
+		return cause.Error()
+	}
+	return err.Error()
+}
+
+// indexedMessage describes a successful indexation, which may have stored only
+// part of the resource
+func indexedMessage(chunkLimit *models.ChunkLimitReport) string {
+	if chunkLimit == nil {
+		return "Resource indexed successfully"
+	}
+	return fmt.Sprintf("Resource indexed partially: %d of %d chunks stored (policy %s)",
+		chunkLimit.Stored, chunkLimit.Produced, chunkLimit.Policy)
+}
+
+// newProgressHandler returns a callback publishing indexation_progress events
+// whenever the completed percentage grows, so that a retried or reindexed
+// resource doesn't report the same percentages again. The completion is left to
+// the indexation_complete event, which the resource service reports as 100 percent.
+func (p *Processor) newProgressHandler(ctx context.Context, resourceID uuid.UUID) func(processed, total int) {
+	const op = "ResourceProcessor.progressHandler"
+
+	lastPercent := -1
+	return func(processed, total int) {
+		if total <= 0 {
+			return
+		}
+
+		percent := processed * 100 / total
+		if percent <= lastPercent || percent >= 100 {
+			return
+		}
+		lastPercent = percent
+
+		event := IndexationProgressEvent{
+			ResourceID: resourceID,
+			Percent:    percent,
+		}
+
+		if err := p.eventService.PublishEvent(ctx, "indexation_progress", "indexation_progress", event); err != nil {
+			slog.WarnContext(ctx, "Failed to publish indexation progress event",
+				"op", op,
+				"resource_id", resourceID,
+				"percent", percent,
+				"error", err)
+		}
+	}
+}
+
+// withOwner returns a copy of ctx acting for the owner of a resource and the
+// tenant it belongs to, which select the collection of its chunks
+func withOwner(ctx context.Context, ownerID, tenantID string) context.Context {
+	if ownerID != "" {
+		ctx = identity.WithUserID(ctx, ownerID)
+	}
+	if tenantID != "" {
+		ctx = identity.WithTenantID(ctx, tenantID)
+	}
+	return ctx
+}
+
+// invalidateCache evicts cached search results affected by the resource event.
+// Deleted resources only evict results they contributed to, while new or
+// changed content or names may be relevant to any earlier question of its owner.
+func (p *Processor) invalidateCache(ctx context.Context, eventName string, resource models.Resource) {
+	if p.cache == nil {
+		return
+	}
+
+	switch eventName {
+	case "resource.created":
+		p.cache.InvalidateUser(ctx, resource.OwnerID)
+	case "resource.updated", metadataUpdatedEvent:
+		p.cache.InvalidateResource(ctx, resource.ID)
+		if resource.OwnerID != "" {
+			p.cache.InvalidateUser(ctx, resource.OwnerID)
+		}
+	case "resource.deleted":
+		p.cache.InvalidateResource(ctx, resource.ID)
+	}
+}
+
+// dropResourceChunks removes previously indexed chunks of the resource
+func (p *Processor) dropResourceChunks(ctx context.Context, resourceID uuid.UUID) error {
+	const op = "ResourceProcessor.dropResourceChunks"
+
+	deleted, err := p.vectorStorage.DeleteResource(ctx, resourceID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete previous resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.DebugContext(ctx, "Dropped previous resource chunks",
+		"resource_id", resourceID,
+		"chunks_deleted", deleted)
+
+	return nil
+}
+
+// dropFailedChunks drops the chunks a failed indexation stored and returns its
+// error, joined with the one of the deletion if that failed too. The chunks of
+// a timed out indexation are dropped as well, those of a cancelled one are left
+// to startIndexing.
+func (p *Processor) dropFailedChunks(ctx context.Context, resourceID uuid.UUID, err error) error {
+	if isCancelled(ctx) {
+		return err
+	}
+	if dropErr := p.dropResourceChunks(context.WithoutCancel(ctx), resourceID); dropErr != nil {
+		return errors.Join(err, dropErr)
+	}
+	return err
 }
 
-// publishIndexationEvent publishes the indexation complete event
-func (p *Processor) publishIndexationEvent(ctx context.Context, resourceID uuid.UUID, success bool, message string, chunkIDs []string) {
+// publishIndexationEvent publishes the indexation complete event, with the
+// chunks of the result when it succeeded
+func (p *Processor) publishIndexationEvent(ctx context.Context, resourceID uuid.UUID, success bool, message string, result indexResult) {
 	const op = "ResourceProcessor.publishIndexationEvent"
 
 	event := IndexationCompleteEvent{
-		ResourceID: resourceID,
-		Success:    success,
-		Message:    message,
-		ChunkIDs:   chunkIDs,
+		ResourceID:      resourceID,
+		Success:         success,
+		Message:         message,
+		ChunkIDs:        result.chunkIDs,
+		ChunkHashes:     result.chunkHashes,
+		ChunkLimit:      result.chunkLimit,
+		DuplicateChunks: result.duplicates,
 	}
 
 	err := p.eventService.PublishEvent(ctx, "indexation_complete", "indexation_complete", event)
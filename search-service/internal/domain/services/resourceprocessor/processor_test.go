@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
 	"github.com/nzb3/diploma/search-service/internal/domain/models"
 	"github.com/nzb3/diploma/search-service/internal/repository/messaging"
 )
@@ -19,13 +22,75 @@ import (
 // MockVectorStorage is a mock implementation of vectorStorage interface
 type MockVectorStorage struct {
 	mock.Mock
+	// progress lists processed/total pairs reported while PutResource runs
+	progress [][2]int
+	// chunkLimit is reported when set, as if the resource exceeded the chunk cap
+	chunkLimit *models.ChunkLimitReport
+	// chunkHashes are reported as the hashes of the stored chunks when set
+	chunkHashes []string
+	// duplicates are reported as dropped duplicate chunks when set
+	duplicates int
 }
 
-func (m *MockVectorStorage) PutResource(ctx context.Context, resource models.Resource) ([]string, error) {
+func (m *MockVectorStorage) reportProgress(onProgress func(processed, total int)) {
+	for _, step := range m.progress {
+		onProgress(step[0], step[1])
+	}
+}
+
+func (m *MockVectorStorage) PutResource(ctx context.Context, resource models.Resource, opts ...models.IndexOption) ([]string, error) {
+	options := &models.IndexOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.OnProgress != nil {
+		m.reportProgress(options.OnProgress)
+	}
+	if m.chunkLimit != nil && options.OnChunkLimit != nil {
+		options.OnChunkLimit(*m.chunkLimit)
+	}
+	if m.chunkHashes != nil && options.OnChunkHashes != nil {
+		options.OnChunkHashes(m.chunkHashes)
+	}
+	if m.duplicates > 0 && options.OnDuplicates != nil {
+		options.OnDuplicates(m.duplicates)
+	}
+
 	args := m.Called(ctx, resource)
 	return args.Get(0).([]string), args.Error(1)
 }
 
+func (m *MockVectorStorage) PatchResource(ctx context.Context, patch models.ResourcePatch, opts ...models.IndexOption) ([]string, error) {
+	options := &models.IndexOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.OnProgress != nil {
+		m.reportProgress(options.OnProgress)
+	}
+
+	args := m.Called(ctx, patch)
+	if args.Error(1) == nil && m.chunkHashes != nil && options.OnChunkHashes != nil {
+		options.OnChunkHashes(m.chunkHashes)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockVectorStorage) DeleteResource(ctx context.Context, resourceID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, resourceID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockVectorStorage) UpdateResourceMetadata(ctx context.Context, resource models.Resource) error {
+	args := m.Called(ctx, resource)
+	return args.Error(0)
+}
+
+func (m *MockVectorStorage) UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, visibility models.ResourceVisibility) error {
+	args := m.Called(ctx, resourceID, visibility)
+	return args.Error(0)
+}
+
 // MockEventService is a mock implementation of eventService interface
 type MockEventService struct {
 	mock.Mock
@@ -56,6 +121,19 @@ func (m *MockMessageConsumer) Health(ctx context.Context) error {
 	return args.Error(0)
 }
 
+// MockCacheInvalidator is a mock implementation of cacheInvalidator interface
+type MockCacheInvalidator struct {
+	mock.Mock
+}
+
+func (m *MockCacheInvalidator) InvalidateResource(ctx context.Context, resourceID uuid.UUID) {
+	m.Called(ctx, resourceID)
+}
+
+func (m *MockCacheInvalidator) InvalidateUser(ctx context.Context, userID string) {
+	m.Called(ctx, userID)
+}
+
 // ResourceProcessorTestSuite is the test suite for ResourceProcessor
 type ResourceProcessorTestSuite struct {
 	suite.Suite
@@ -154,7 +232,150 @@ func (suite *ResourceProcessorTestSuite) TestHandleMessage_Success() {
 	assert.NoError(suite.T(), err)
 }
 
-// TestHandleMessage_VectorStorageError tests handling vector storage error
+// TestHandleMessage_PublishesProgress tests that intermediate indexation progress is published
+// as percentages, once each, and leaves the completion to the complete event
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_PublishesProgress() {
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "large-pdf",
+		Type:             "pdf",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	chunkIDs := []string{"chunk1", "chunk2", "chunk3", "chunk4"}
+	suite.mockVectorStorage.progress = [][2]int{{1, 4}, {1, 4}, {2, 4}, {4, 4}}
+
+	var percents []int
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return(chunkIDs, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_progress", "indexation_progress", mock.Anything).
+		Run(func(args mock.Arguments) {
+			event := args.Get(3).(IndexationProgressEvent)
+			assert.Equal(suite.T(), resourceID, event.ResourceID)
+			percents = append(percents, event.Percent)
+		}).Return(nil).Times(2)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int{25, 50}, percents)
+}
+
+// TestHandleMessage_RetryRepeatsNoProgress tests that a retried indexation doesn't
+// publish the percentages the failed attempt already reported
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_RetryRepeatsNoProgress() {
+	suite.processor.WithRetry(2, time.Millisecond, 0)
+
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "large-pdf",
+		Type:             "pdf",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	chunkIDs := []string{"chunk1", "chunk2", "chunk3", "chunk4"}
+	suite.mockVectorStorage.progress = [][2]int{{1, 4}, {2, 4}, {3, 4}}
+
+	var percents []int
+	failedCall := suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return([]string(nil), errors.New("connection refused")).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(0), nil).Once()
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return(chunkIDs, nil).Once().NotBefore(failedCall)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_progress", "indexation_progress", mock.Anything).
+		Run(func(args mock.Arguments) {
+			percents = append(percents, args.Get(3).(IndexationProgressEvent).Percent)
+		}).Return(nil)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int{25, 50, 75}, percents)
+}
+
+// TestHandleMessage_ChunkLimitReported tests that a capped resource is reported in the complete event
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_ChunkLimitReported() {
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "huge-document",
+		Type:             "text",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	chunkIDs := []string{"chunk1", "chunk2"}
+	suite.mockVectorStorage.chunkLimit = &models.ChunkLimitReport{Policy: "truncate", Limit: 2, Produced: 5, Stored: 2}
+
+	expectedEvent := IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+		Message:    "Resource indexed partially: 2 of 5 chunks stored (policy truncate)",
+		ChunkIDs:   chunkIDs,
+		ChunkLimit: suite.mockVectorStorage.chunkLimit,
+	}
+
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return(chunkIDs, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestHandleMessage_DuplicatesReported tests that dropped duplicate chunks are counted in the complete event
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_DuplicatesReported() {
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "boilerplate-document",
+		Type:             "text",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	chunkIDs := []string{"chunk1", "chunk2"}
+	suite.mockVectorStorage.duplicates = 3
+
+	expectedEvent := IndexationCompleteEvent{
+		ResourceID:      resourceID,
+		Success:         true,
+		Message:         "Resource indexed successfully",
+		ChunkIDs:        chunkIDs,
+		DuplicateChunks: 3,
+	}
+
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return(chunkIDs, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestHandleMessage_VectorStorageError tests handling vector storage error, which
+// drops the chunks stored before the failure
 func (suite *ResourceProcessorTestSuite) TestHandleMessage_VectorStorageError() {
 	resourceID := uuid.New()
 	resource := models.Resource{
@@ -180,6 +401,7 @@ func (suite *ResourceProcessorTestSuite) TestHandleMessage_VectorStorageError()
 
 	// Setup expectations
 	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return([]string{}, expectedError).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(2), nil).Once()
 	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
 
 	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
@@ -188,6 +410,211 @@ func (suite *ResourceProcessorTestSuite) TestHandleMessage_VectorStorageError()
 	assert.Contains(suite.T(), err.Error(), "failed to process resource")
 }
 
+// TestHandleMessage_TimeoutFailsResource tests that an indexation exceeding the
+// timeout is reported as failed with the timeout as its reason
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_TimeoutFailsResource() {
+	suite.processor.WithTimeout(20 * time.Millisecond)
+
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "large-corpus",
+		Type:             "text",
+		ExtractedContent: "test content",
+		OwnerID:          "owner-1",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	var userID string
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			userID, _ = identity.UserID(ctx)
+			<-ctx.Done()
+		}).
+		Return([]string(nil), context.DeadlineExceeded).Once()
+	var deleteErr error
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).
+		Run(func(args mock.Arguments) {
+			deleteErr = args.Get(0).(context.Context).Err()
+		}).
+		Return(int64(1), nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    false,
+		Message:    "indexing timed out after 20ms",
+	}).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	assert.Equal(suite.T(), "owner-1", userID)
+	// The stored chunks are dropped although the indexation timed out
+	assert.NoError(suite.T(), deleteErr)
+}
+
+// TestHandleMessage_TenantReachesStorage tests that the chunks of a resource are
+// stored within the tenant of its event, which selects a tenant's collection
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_TenantReachesStorage() {
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "test content",
+		OwnerID:          "owner-1",
+		TenantID:         "tenant-1",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	var userID, tenantID string
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Run(func(args mock.Arguments) {
+			ctx := args.Get(0).(context.Context)
+			userID, _ = identity.UserID(ctx)
+			tenantID, _ = identity.TenantID(ctx)
+		}).
+		Return([]string{"chunk1"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "owner-1", userID)
+	assert.Equal(suite.T(), "tenant-1", tenantID)
+}
+
+// TestHandleMessage_PatchTenantReachesStorage tests that a patch is applied
+// within the tenant of its event
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_PatchTenantReachesStorage() {
+	resourceID := uuid.New()
+	patch := models.ResourcePatch{
+		ResourceID:       resourceID,
+		OwnerID:          "owner-1",
+		TenantID:         "tenant-1",
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "edited paragraph",
+		AddedChunks:      []models.PatchedChunk{{Index: 0, Hash: "hash-1", Content: "edited paragraph"}},
+	}
+
+	patchJSON, _ := json.Marshal(patch)
+	headers := map[string]string{
+		"event-name": "resource.content_patched",
+	}
+
+	var tenantID string
+	suite.mockVectorStorage.On("PatchResource", mock.Anything, patch).
+		Run(func(args mock.Arguments) {
+			tenantID, _ = identity.TenantID(args.Get(0).(context.Context))
+		}).
+		Return([]string{"chunk1"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), patchJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "tenant-1", tenantID)
+}
+
+// TestHandleMessage_RetriesTransientFailure tests that a resource failing once
+// is indexed again, after the chunks of the failed attempt were dropped
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_RetriesTransientFailure() {
+	suite.processor.WithRetry(3, time.Millisecond, 0)
+
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	chunkIDs := []string{"chunk1"}
+
+	failedCall := suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return([]string(nil), errors.New("connection refused")).Once()
+	deleteCall := suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).
+		Return(int64(0), nil).Once().NotBefore(failedCall)
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return(chunkIDs, nil).Once().NotBefore(deleteCall)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+		Message:    "Resource indexed successfully",
+		ChunkIDs:   chunkIDs,
+	}).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestHandleMessage_RetriesExhausted tests that a resource failing on every
+// attempt is reported as failed with the last error
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_RetriesExhausted() {
+	suite.processor.WithRetry(2, time.Millisecond, 0)
+
+	resourceID := uuid.New()
+	resource := models.Resource{ID: resourceID, Type: "text", ExtractedContent: "test content"}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return([]string(nil), errors.New("connection refused")).Twice()
+	// Once between the attempts and once after the last one
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(0), nil).Twice()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    false,
+		Message:    "ResourceProcessor.processResource: connection refused",
+	}).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.Error(suite.T(), err)
+}
+
+// TestHandleMessage_TooManyChunksNotRetried tests that a resource rejected for
+// exceeding the chunk cap fails right away
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_TooManyChunksNotRetried() {
+	suite.processor.WithRetry(3, time.Millisecond, 0)
+
+	resourceID := uuid.New()
+	resource := models.Resource{ID: resourceID, Type: "text", ExtractedContent: "test content"}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Return([]string(nil), models.ErrTooManyChunks).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(0), nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).
+		Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.ErrorIs(suite.T(), err, models.ErrTooManyChunks)
+}
+
 // TestHandleMessage_InvalidJSON tests handling invalid JSON payload
 func (suite *ResourceProcessorTestSuite) TestHandleMessage_InvalidJSON() {
 	resourceID := uuid.New()
@@ -212,19 +639,290 @@ func (suite *ResourceProcessorTestSuite) TestHandleMessage_IgnoreOtherTopics() {
 	// No expectations should be called since the topic is ignored
 }
 
-// TestHandleMessage_IgnoreOtherEvents tests that non-resource.created events are ignored
-func (suite *ResourceProcessorTestSuite) TestHandleMessage_IgnoreOtherEvents() {
+// TestHandleMessage_UpdatedResourceReindexed tests that an updated resource drops old chunks and is indexed again
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_UpdatedResourceReindexed() {
 	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "test-resource",
+		Type:             "url",
+		ExtractedContent: "re-extracted content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
 	headers := map[string]string{
 		"event-name": "resource.updated",
 	}
 
+	chunkIDs := []string{"chunk3"}
+
+	expectedEvent := IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+		Message:    "Resource indexed successfully",
+		ChunkIDs:   chunkIDs,
+	}
+
+	deleteCall := suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(2), nil).Once()
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return(chunkIDs, nil).Once().NotBefore(deleteCall)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestHandleMessage_UpdatedResourceDeleteError tests that a failed chunk cleanup is reported and skips indexation
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_UpdatedResourceDeleteError() {
+	resourceID := uuid.New()
+	resource := models.Resource{
+		ID:               resourceID,
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "test content",
+	}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.updated",
+	}
+
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(0), errors.New("delete failed")).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete",
+		mock.MatchedBy(func(event IndexationCompleteEvent) bool {
+			return event.ResourceID == resourceID && !event.Success
+		})).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), resourceJSON, headers)
+
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "failed to reindex resource")
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_ContentPatched tests that a content patch is applied and reported with the chunk hashes
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_ContentPatched() {
+	resourceID := uuid.New()
+	patch := models.ResourcePatch{
+		ResourceID:       resourceID,
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "first paragraph\n\nedited paragraph",
+		RemovedChunkIDs:  []string{"chunk2"},
+		KeptChunks:       []models.PatchedChunk{{ID: "chunk1", Index: 0, Hash: "hash-1"}},
+		AddedChunks:      []models.PatchedChunk{{Index: 1, Hash: "hash-3", Content: "edited paragraph"}},
+	}
+
+	patchJSON, _ := json.Marshal(patch)
+	headers := map[string]string{
+		"event-name": "resource.content_patched",
+	}
+
+	chunkIDs := []string{"chunk1", "chunk3"}
+	suite.mockVectorStorage.chunkHashes = []string{"hash-1", "hash-3"}
+
+	expectedEvent := IndexationCompleteEvent{
+		ResourceID:  resourceID,
+		Success:     true,
+		Message:     "Resource patched successfully",
+		ChunkIDs:    chunkIDs,
+		ChunkHashes: []string{"hash-1", "hash-3"},
+	}
+
+	suite.mockVectorStorage.On("PatchResource", mock.Anything, patch).Return(chunkIDs, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), patchJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "DeleteResource", mock.Anything, mock.Anything)
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_ContentPatchFallsBackToReindex tests that a patch that cannot be applied reindexes the resource
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_ContentPatchFallsBackToReindex() {
+	resourceID := uuid.New()
+	patch := models.ResourcePatch{
+		ResourceID:       resourceID,
+		Name:             "test-resource",
+		Type:             "text",
+		ExtractedContent: "edited content",
+		KeptChunks:       []models.PatchedChunk{{ID: "chunk1", Index: 0, Hash: "stale"}},
+	}
+
+	patchJSON, _ := json.Marshal(patch)
+	headers := map[string]string{
+		"event-name": "resource.content_patched",
+	}
+
+	chunkIDs := []string{"chunk4"}
+
+	expectedEvent := IndexationCompleteEvent{
+		ResourceID: resourceID,
+		Success:    true,
+		Message:    "Resource indexed successfully",
+		ChunkIDs:   chunkIDs,
+	}
+
+	patchCall := suite.mockVectorStorage.On("PatchResource", mock.Anything, patch).Return([]string(nil), errors.New("stale patch")).Once()
+	deleteCall := suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(1), nil).Once().NotBefore(patchCall)
+	suite.mockVectorStorage.On("PutResource", mock.Anything, patch.Resource()).Return(chunkIDs, nil).Once().NotBefore(deleteCall)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", expectedEvent).Return(nil).Once()
+
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), patchJSON, headers)
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestHandleMessage_IgnoreOtherEvents tests that events without indexation work are ignored
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_IgnoreOtherEvents() {
+	resourceID := uuid.New()
+	headers := map[string]string{
+		"event-name": "resource.status_updated",
+	}
+
 	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), []byte("some data"), headers)
 
 	assert.NoError(suite.T(), err)
 	// No expectations should be called since the event is ignored
 }
 
+// TestHandleMessage_DeletedResourceInvalidatesCache tests that deletions only evict cached results
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_DeletedResourceInvalidatesCache() {
+	cache := new(MockCacheInvalidator)
+	processor := NewResourceProcessor(suite.mockVectorStorage, suite.mockEventService, suite.mockConsumer, cache)
+	resource := models.Resource{ID: uuid.New(), OwnerID: uuid.NewString()}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.deleted",
+	}
+
+	cache.On("InvalidateResource", mock.Anything, resource.ID).Once()
+
+	err := processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	cache.AssertExpectations(suite.T())
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_CreatedResourceInvalidatesUserCache tests that new resources evict the owner's cached results
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_CreatedResourceInvalidatesUserCache() {
+	cache := new(MockCacheInvalidator)
+	processor := NewResourceProcessor(suite.mockVectorStorage, suite.mockEventService, suite.mockConsumer, cache)
+	resource := models.Resource{ID: uuid.New(), OwnerID: uuid.NewString(), ExtractedContent: "content"}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	cache.On("InvalidateUser", mock.Anything, resource.OwnerID).Once()
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return([]string{"chunk1"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).
+		Return(nil).Once()
+
+	err := processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	cache.AssertExpectations(suite.T())
+}
+
+// TestHandleMessage_UpdatedResourceInvalidatesUserCache tests that changed content evicts the owner's cached results
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_UpdatedResourceInvalidatesUserCache() {
+	cache := new(MockCacheInvalidator)
+	processor := NewResourceProcessor(suite.mockVectorStorage, suite.mockEventService, suite.mockConsumer, cache)
+	resource := models.Resource{ID: uuid.New(), OwnerID: uuid.NewString(), ExtractedContent: "changed content"}
+
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.updated",
+	}
+
+	cache.On("InvalidateResource", mock.Anything, resource.ID).Once()
+	cache.On("InvalidateUser", mock.Anything, resource.OwnerID).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resource.ID).Return(int64(1), nil).Once()
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).Return([]string{"chunk1"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).
+		Return(nil).Once()
+
+	err := processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), resourceJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	cache.AssertExpectations(suite.T())
+}
+
+// TestHandleMessage_MetadataUpdatedSkipsReindex tests that a renamed resource only gets its metadata chunk replaced
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_MetadataUpdatedSkipsReindex() {
+	cache := new(MockCacheInvalidator)
+	processor := NewResourceProcessor(suite.mockVectorStorage, suite.mockEventService, suite.mockConsumer, cache)
+	event := MetadataUpdatedEvent{
+		ResourceID: uuid.New(),
+		OwnerID:    uuid.NewString(),
+		TenantID:   "tenant-1",
+		Name:       "Renamed notes",
+		CreatedAt:  time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	eventJSON, _ := json.Marshal(event)
+	headers := map[string]string{
+		"event-name": "resource.metadata_updated",
+	}
+
+	var tenantID string
+	suite.mockVectorStorage.On("UpdateResourceMetadata", mock.Anything, models.Resource{
+		ID:        event.ResourceID,
+		Name:      event.Name,
+		OwnerID:   event.OwnerID,
+		TenantID:  event.TenantID,
+		CreatedAt: event.CreatedAt,
+	}).
+		Run(func(args mock.Arguments) {
+			tenantID, _ = identity.TenantID(args.Get(0).(context.Context))
+		}).
+		Return(nil).Once()
+	cache.On("InvalidateResource", mock.Anything, event.ResourceID).Once()
+	cache.On("InvalidateUser", mock.Anything, event.OwnerID).Once()
+
+	err := processor.HandleMessage(suite.ctx, "resource", event.ResourceID.String(), eventJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "tenant-1", tenantID)
+	cache.AssertExpectations(suite.T())
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "DeleteResource", mock.Anything, mock.Anything)
+	// No indexation ran, so none is reported
+	suite.mockEventService.AssertNotCalled(suite.T(), "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_VisibilityUpdatedRelabelsChunks tests that a shared resource is relabelled without reindexing
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_VisibilityUpdatedRelabelsChunks() {
+	cache := new(MockCacheInvalidator)
+	processor := NewResourceProcessor(suite.mockVectorStorage, suite.mockEventService, suite.mockConsumer, cache)
+	event := VisibilityUpdatedEvent{
+		ResourceID: uuid.New(),
+		OwnerID:    uuid.NewString(),
+		Visibility: models.ResourceVisibilityPublic,
+	}
+
+	eventJSON, _ := json.Marshal(event)
+	headers := map[string]string{
+		"event-name": "resource.visibility_updated",
+	}
+
+	suite.mockVectorStorage.On("UpdateResourceVisibility", mock.Anything, event.ResourceID, models.ResourceVisibilityPublic).Return(nil).Once()
+	cache.On("InvalidateResource", mock.Anything, event.ResourceID).Once()
+
+	err := processor.HandleMessage(suite.ctx, "resource", event.ResourceID.String(), eventJSON, headers)
+
+	assert.NoError(suite.T(), err)
+	cache.AssertExpectations(suite.T())
+	suite.mockVectorStorage.AssertExpectations(suite.T())
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+	suite.mockEventService.AssertNotCalled(suite.T(), "PublishEvent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 // TestHandleMessage_MissingEventName tests handling missing event-name header
 func (suite *ResourceProcessorTestSuite) TestHandleMessage_MissingEventName() {
 	resourceID := uuid.New()
@@ -236,6 +934,297 @@ func (suite *ResourceProcessorTestSuite) TestHandleMessage_MissingEventName() {
 	// No expectations should be called since the event-name is missing
 }
 
+// TestHandleMessage_PriorityOverridesArrivalOrder tests that waiting resources are indexed by explicit priority
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_PriorityOverridesArrivalOrder() {
+	suite.processor.queue = newIndexQueue(1)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	// Occupy the only slot so that every message below has to wait
+	require.NoError(suite.T(), suite.processor.queue.acquire(suite.ctx, models.ResourcePriorityNormal))
+
+	var (
+		mu    sync.Mutex
+		order []models.ResourcePriority
+		wg    sync.WaitGroup
+	)
+	suite.mockVectorStorage.On("PutResource", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, args.Get(1).(models.Resource).Priority)
+		}).
+		Return([]string{"chunk1"}, nil).Times(3)
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Times(3)
+
+	arrivals := []models.ResourcePriority{models.ResourcePriorityLow, "", models.ResourcePriorityHigh}
+	for i, priority := range arrivals {
+		resource := models.Resource{
+			ID:               uuid.New(),
+			Name:             "test-resource",
+			Type:             "text",
+			ExtractedContent: "test content",
+			Priority:         priority,
+		}
+		resourceJSON, _ := json.Marshal(resource)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := suite.processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), resourceJSON, headers)
+			assert.NoError(suite.T(), err)
+		}()
+
+		// Let each message join the queue before the next one arrives
+		waiting := i + 1
+		require.Eventually(suite.T(), func() bool {
+			return suite.processor.queue.pending() == waiting
+		}, time.Second, time.Millisecond)
+	}
+
+	suite.processor.queue.release()
+	wg.Wait()
+
+	assert.Equal(suite.T(), []models.ResourcePriority{models.ResourcePriorityHigh, "", models.ResourcePriorityLow}, order)
+}
+
+// TestHandleMessage_CancelledWhileQueued tests that a cancelled message leaves the queue without indexing
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_CancelledWhileQueued() {
+	suite.processor.queue = newIndexQueue(1)
+	require.NoError(suite.T(), suite.processor.queue.acquire(suite.ctx, models.ResourcePriorityNormal))
+
+	resource := models.Resource{
+		ID:               uuid.New(),
+		ExtractedContent: "test content",
+		Priority:         models.ResourcePriorityHigh,
+	}
+	resourceJSON, _ := json.Marshal(resource)
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	ctx, cancel := context.WithTimeout(suite.ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err := suite.processor.HandleMessage(ctx, "resource", resource.ID.String(), resourceJSON, headers)
+
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+	assert.Equal(suite.T(), 0, suite.processor.queue.pending())
+
+	// The held slot is still usable once released
+	suite.processor.queue.release()
+	assert.NoError(suite.T(), suite.processor.queue.acquire(suite.ctx, models.ResourcePriorityLow))
+}
+
+// TestHandleMessage_IndexationCancelled tests that a cancellation stops a running
+// indexation, reports it as failed and drops the chunks it stored
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_IndexationCancelled() {
+	resource := models.Resource{
+		ID:               uuid.New(),
+		Name:             "large-corpus",
+		Type:             "text",
+		ExtractedContent: "test content",
+		OwnerID:          "owner-1",
+	}
+	resourceJSON, _ := json.Marshal(resource)
+
+	started := make(chan struct{})
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Run(func(args mock.Arguments) {
+			close(started)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return([]string(nil), context.Canceled).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resource.ID).Return(int64(2), nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", IndexationCompleteEvent{
+		ResourceID: resource.ID,
+		Success:    false,
+		Message:    "indexing cancelled",
+	}).Return(nil).Once()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- suite.processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), resourceJSON,
+			map[string]string{"event-name": "resource.created"})
+	}()
+	<-started
+
+	cancelJSON, _ := json.Marshal(IndexationCancelledEvent{ResourceID: resource.ID, OwnerID: "owner-1"})
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resource.ID.String(), cancelJSON,
+		map[string]string{"event-name": "resource.indexation_cancelled"})
+	require.NoError(suite.T(), err)
+
+	select {
+	case err := <-done:
+		assert.NoError(suite.T(), err)
+	case <-time.After(time.Second):
+		suite.T().Fatal("indexation was not cancelled")
+	}
+}
+
+// TestHandleMessage_IndexationCancelledAfterIndexing tests that a cancellation
+// arriving after the indexation finished drops the stored chunks
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_IndexationCancelledAfterIndexing() {
+	resourceID := uuid.New()
+	mockCache := new(MockCacheInvalidator)
+	suite.processor.cache = mockCache
+
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(3), nil).Once()
+	mockCache.On("InvalidateResource", mock.Anything, resourceID).Once()
+
+	cancelJSON, _ := json.Marshal(IndexationCancelledEvent{ResourceID: resourceID})
+	err := suite.processor.HandleMessage(suite.ctx, "resource", resourceID.String(), cancelJSON,
+		map[string]string{"event-name": "resource.indexation_cancelled"})
+
+	assert.NoError(suite.T(), err)
+	mockCache.AssertExpectations(suite.T())
+}
+
+// TestHandleMessage_IndexationCancelledWhileQueued tests that a message waiting
+// for its worker is skipped once the indexation was cancelled
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_IndexationCancelledWhileQueued() {
+	// Workers are not running yet, so the message stays in the backlog
+	suite.processor.WithWorkers(1, 1)
+
+	resource := models.Resource{ID: uuid.New(), ExtractedContent: "test content"}
+	resourceJSON, _ := json.Marshal(resource)
+	require.NoError(suite.T(), suite.processor.HandleMessageAsync(suite.ctx, "resource", "key", resourceJSON,
+		map[string]string{"event-name": "resource.created"}, func(error) {}))
+
+	dropped := make(chan struct{})
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resource.ID).
+		Run(func(mock.Arguments) { close(dropped) }).
+		Return(int64(0), nil).Once()
+
+	cancelJSON, _ := json.Marshal(IndexationCancelledEvent{ResourceID: resource.ID})
+	require.NoError(suite.T(), suite.processor.HandleMessage(suite.ctx, "resource", "key", cancelJSON,
+		map[string]string{"event-name": "resource.indexation_cancelled"}))
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	defer cancel()
+	suite.processor.pool.run(ctx)
+
+	select {
+	case <-dropped:
+	case <-time.After(time.Second):
+		suite.T().Fatal("cancelled message was not skipped")
+	}
+	cancel()
+	suite.processor.pool.wait()
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "PutResource", mock.Anything, mock.Anything)
+}
+
+// TestHandleMessage_WorkersKeepResourceOrder tests that messages of a resource are indexed in arrival order on the pool
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_WorkersKeepResourceOrder() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	defer cancel()
+	suite.processor.WithWorkers(4, 2)
+	suite.processor.pool.run(ctx)
+
+	resourceID := uuid.New()
+	created := models.Resource{ID: resourceID, Name: "test-resource", Type: "text", ExtractedContent: "first content"}
+	updated := models.Resource{ID: resourceID, Name: "test-resource", Type: "text", ExtractedContent: "second content"}
+
+	var (
+		mu        sync.Mutex
+		published [][]string
+	)
+	suite.mockVectorStorage.On("PutResource", mock.Anything, created).
+		Run(func(mock.Arguments) {
+			// A slow first indexation must not let the update overtake it
+			time.Sleep(20 * time.Millisecond)
+		}).
+		Return([]string{"chunk1"}, nil).Once()
+	suite.mockVectorStorage.On("DeleteResource", mock.Anything, resourceID).Return(int64(1), nil).Once()
+	suite.mockVectorStorage.On("PutResource", mock.Anything, updated).Return([]string{"chunk2"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).
+		Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			published = append(published, args.Get(3).(IndexationCompleteEvent).ChunkIDs)
+		}).
+		Return(nil).Times(2)
+
+	for _, message := range []struct {
+		event    string
+		resource models.Resource
+	}{
+		{"resource.created", created},
+		{"resource.updated", updated},
+	} {
+		resourceJSON, _ := json.Marshal(message.resource)
+		// Keys differ, as they do for events, so only the resource ID routes the messages
+		err := suite.processor.HandleMessageAsync(suite.ctx, "resource", uuid.NewString(), resourceJSON,
+			map[string]string{"event-name": message.event}, func(error) {})
+		require.NoError(suite.T(), err)
+	}
+
+	require.Eventually(suite.T(), func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(published) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(suite.T(), [][]string{{"chunk1"}, {"chunk2"}}, published)
+}
+
+// TestHandleMessageAsync_DoneAfterIndexing tests that a message handed to the
+// pool is only reported done, and so committed, once its indexation finished
+func (suite *ResourceProcessorTestSuite) TestHandleMessageAsync_DoneAfterIndexing() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	defer cancel()
+	suite.processor.WithWorkers(1, 1)
+	suite.processor.pool.run(ctx)
+
+	resource := models.Resource{ID: uuid.New(), Name: "test-resource", Type: "text", ExtractedContent: "test content"}
+	resourceJSON, _ := json.Marshal(resource)
+
+	release := make(chan struct{})
+	suite.mockVectorStorage.On("PutResource", mock.Anything, resource).
+		Run(func(mock.Arguments) { <-release }).
+		Return([]string{"chunk1"}, nil).Once()
+	suite.mockEventService.On("PublishEvent", mock.Anything, "indexation_complete", "indexation_complete", mock.Anything).Return(nil).Once()
+
+	done := make(chan error, 1)
+	err := suite.processor.HandleMessageAsync(suite.ctx, "resource", resource.ID.String(), resourceJSON,
+		map[string]string{"event-name": "resource.created"}, func(err error) { done <- err })
+	require.NoError(suite.T(), err)
+
+	select {
+	case <-done:
+		suite.T().Fatal("message reported done while indexing")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		assert.NoError(suite.T(), err)
+	case <-time.After(time.Second):
+		suite.T().Fatal("message was not reported done")
+	}
+}
+
+// TestHandleMessage_WorkerBacklogFull tests that a full worker backlog holds the consumer back
+func (suite *ResourceProcessorTestSuite) TestHandleMessage_WorkerBacklogFull() {
+	// Workers are not running, so the single backlog slot stays taken
+	suite.processor.WithWorkers(1, 1)
+
+	resourceJSON, _ := json.Marshal(models.Resource{ID: uuid.New(), ExtractedContent: "test content"})
+	headers := map[string]string{
+		"event-name": "resource.created",
+	}
+
+	require.NoError(suite.T(), suite.processor.HandleMessageAsync(suite.ctx, "resource", "key", resourceJSON, headers, func(error) {}))
+
+	ctx, cancel := context.WithTimeout(suite.ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err := suite.processor.HandleMessageAsync(ctx, "resource", "key", resourceJSON, headers, func(error) {})
+
+	assert.ErrorIs(suite.T(), err, context.DeadlineExceeded)
+}
+
 // TestHealth_Success tests successful health check
 func (suite *ResourceProcessorTestSuite) TestHealth_Success() {
 	suite.mockConsumer.On("Health", mock.Anything).Return(nil).Once()
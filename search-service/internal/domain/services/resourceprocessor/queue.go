@@ -0,0 +1,133 @@
+package resourceprocessor
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// defaultIndexingSlots bounds how many resources are indexed at the same time
+const defaultIndexingSlots = 2
+
+// indexQueue hands out a fixed number of indexing slots. When all slots are
+// taken, waiting resources are granted a slot by priority and, within the same
+// priority, in arrival order.
+type indexQueue struct {
+	mu      sync.Mutex
+	free    int
+	seq     uint64
+	waiting waiterHeap
+}
+
+// waiter is a resource blocked until an indexing slot is granted
+type waiter struct {
+	rank  int
+	seq   uint64
+	index int
+	ready chan struct{}
+}
+
+func newIndexQueue(slots int) *indexQueue {
+	if slots <= 0 {
+		slots = 1
+	}
+	return &indexQueue{free: slots}
+}
+
+// acquire blocks until an indexing slot is granted or the context is done.
+// Every successful acquire must be paired with release.
+func (q *indexQueue) acquire(ctx context.Context, priority models.ResourcePriority) error {
+	q.mu.Lock()
+	if q.free > 0 && q.waiting.Len() == 0 {
+		q.free--
+		q.mu.Unlock()
+		return nil
+	}
+
+	q.seq++
+	w := &waiter{
+		rank:  priority.Rank(),
+		seq:   q.seq,
+		ready: make(chan struct{}),
+	}
+	heap.Push(&q.waiting, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+
+		select {
+		case <-w.ready:
+			// The slot was granted while giving up, hand it on
+			q.releaseLocked()
+		default:
+			heap.Remove(&q.waiting, w.index)
+		}
+		return ctx.Err()
+	}
+}
+
+// release returns a slot, granting it to the highest priority waiter if any
+func (q *indexQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.releaseLocked()
+}
+
+func (q *indexQueue) releaseLocked() {
+	if q.waiting.Len() == 0 {
+		q.free++
+		return
+	}
+
+	w := heap.Pop(&q.waiting).(*waiter)
+	close(w.ready)
+}
+
+// pending returns the number of resources waiting for a slot
+func (q *indexQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.waiting.Len()
+}
+
+// waiterHeap implements heap.Interface ordering waiters by rank, then arrival
+type waiterHeap []*waiter
+
+func (h waiterHeap) Len() int { return len(h) }
+
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *waiterHeap) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *waiterHeap) Pop() any {
+	old := *h
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return w
+}
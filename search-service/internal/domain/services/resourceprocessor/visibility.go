@@ -0,0 +1,55 @@
+package resourceprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// visibilityUpdatedEvent is published by the resource-service when the owner
+// shares a resource or makes it private again
+const visibilityUpdatedEvent = "resource.visibility_updated"
+
+// VisibilityUpdatedEvent represents a change of who finds a resource
+type VisibilityUpdatedEvent struct {
+	ResourceID uuid.UUID                 `json:"resource_id"`
+	OwnerID    string                    `json:"owner_id"`
+	TenantID   string                    `json:"tenant_id,omitempty"`
+	Visibility models.ResourceVisibility `json:"visibility"`
+}
+
+// updateVisibility handles a resource.visibility_updated event by relabelling
+// the chunks of the resource, which stay indexed as they are
+func (p *Processor) updateVisibility(ctx context.Context, value []byte) error {
+	const op = "ResourceProcessor.updateVisibility"
+
+	var event VisibilityUpdatedEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		slog.ErrorContext(ctx, "Failed to unmarshal visibility update",
+			"op", op,
+			"error", err)
+		return fmt.Errorf("%s: failed to unmarshal visibility update: %w", op, err)
+	}
+
+	ctx = withOwner(ctx, event.OwnerID, event.TenantID)
+
+	if err := p.vectorStorage.UpdateResourceVisibility(ctx, event.ResourceID, event.Visibility); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Results of other users may no longer show the resource. Their cached
+	// results without it expire on their own once it is shared.
+	if p.cache != nil {
+		p.cache.InvalidateResource(ctx, event.ResourceID)
+	}
+
+	slog.InfoContext(ctx, "Resource visibility updated",
+		"resource_id", event.ResourceID,
+		"visibility", event.Visibility)
+	return nil
+}
@@ -0,0 +1,168 @@
+package searchservice
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// defaultCacheSize is the number of results cached when no size is configured
+const defaultCacheSize = 10000
+
+type cacheEntry struct {
+	key       string
+	value     any
+	userID    string
+	resources []uuid.UUID
+	expiresAt time.Time
+}
+
+// resultCache keeps search results together with the resources their references
+// came from, so a change to one resource only evicts the results it contributed to.
+// Past capacity entries, the least recently used one is evicted.
+type resultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	capacity   int
+	order      *list.List // Front is the most recently used
+	entries    map[string]*list.Element
+	byResource map[uuid.UUID]map[string]struct{}
+	byUser     map[string]map[string]struct{}
+	now        func() time.Time
+}
+
+// newResultCache returns a cache of up to capacity entries, defaultCacheSize when not positive
+func newResultCache(ttl time.Duration, capacity int) *resultCache {
+	if capacity <= 0 {
+		capacity = defaultCacheSize
+	}
+	return &resultCache{
+		ttl:        ttl,
+		capacity:   capacity,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		byResource: make(map[uuid.UUID]map[string]struct{}),
+		byUser:     make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+func (c *resultCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.removeLocked(key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *resultCache) put(key string, userID string, value any, refs []models.Reference) {
+	c.putFor(key, userID, value, refs, c.ttl)
+}
+
+// putFor caches value for ttl instead of the cache's default one
+func (c *resultCache) putFor(key string, userID string, value any, refs []models.Reference, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(key)
+
+	addToIndex(c.byUser, userID, key)
+
+	resources := make([]uuid.UUID, 0, len(refs))
+	seen := make(map[uuid.UUID]struct{}, len(refs))
+	for _, ref := range refs {
+		if _, ok := seen[ref.ResourceID]; ok {
+			continue
+		}
+		seen[ref.ResourceID] = struct{}{}
+		resources = append(resources, ref.ResourceID)
+		addToIndex(c.byResource, ref.ResourceID, key)
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{
+		key:       key,
+		value:     value,
+		userID:    userID,
+		resources: resources,
+		expiresAt: c.now().Add(ttl),
+	})
+	if c.order.Len() > c.capacity {
+		c.removeLocked(c.order.Back().Value.(*cacheEntry).key)
+	}
+}
+
+// invalidateResource evicts every entry whose references touched the resource
+// and returns the number of evicted entries.
+func (c *resultCache) invalidateResource(resourceID uuid.UUID) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.byResource[resourceID]
+	evicted := len(keys)
+	for key := range keys {
+		c.removeLocked(key)
+	}
+
+	return evicted
+}
+
+// invalidateUser evicts every entry of the user. New resources may answer any
+// earlier question, so they cannot be narrowed down to contributing resources.
+func (c *resultCache) invalidateUser(userID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.byUser[userID]
+	evicted := len(keys)
+	for key := range keys {
+		c.removeLocked(key)
+	}
+
+	return evicted
+}
+
+func (c *resultCache) removeLocked(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+
+	entry := c.order.Remove(element).(*cacheEntry)
+	delete(c.entries, key)
+	removeFromIndex(c.byUser, entry.userID, key)
+	for _, resourceID := range entry.resources {
+		removeFromIndex(c.byResource, resourceID, key)
+	}
+}
+
+func addToIndex[K comparable](index map[K]map[string]struct{}, id K, key string) {
+	keys, ok := index[id]
+	if !ok {
+		keys = make(map[string]struct{})
+		index[id] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]map[string]struct{}, id K, key string) {
+	keys := index[id]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(index, id)
+	}
+}
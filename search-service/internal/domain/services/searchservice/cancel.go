@@ -0,0 +1,49 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+)
+
+// Operation labels of search metrics
+const (
+	operationAnswer       = "answer"
+	operationAnswerStream = "answer_stream"
+	operationSemantic     = "semantic"
+)
+
+// IsCancelled reports whether err is the result of the caller cancelling ctx.
+// Clients that go away surface as context.Canceled, but the LLM and database
+// clients may wrap it in their own errors, so ctx itself is consulted as well.
+// Deadlines are not cancellations: a search that times out has failed.
+func IsCancelled(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled)
+}
+
+// searchFailed classifies the error of a search operation. Cancellations are
+// logged at debug level, counted apart from failures and wrapped with
+// models.ErrSearchCancelled; anything else is logged and counted as an error.
+func (s *Service) searchFailed(ctx context.Context, op string, operation string, err error) error {
+	if IsCancelled(ctx, err) {
+		slog.DebugContext(ctx, "Search cancelled by client",
+			"op", op,
+			"operation", operation)
+		metrics.SearchCancellations.WithLabelValues(operation).Inc()
+		return fmt.Errorf("%s: %w: %w", op, models.ErrSearchCancelled, err)
+	}
+
+	slog.ErrorContext(ctx, "Search failed",
+		"op", op,
+		"operation", operation,
+		"error", err)
+	metrics.SearchErrors.WithLabelValues(operation).Inc()
+	return fmt.Errorf("%s: %w", op, err)
+}
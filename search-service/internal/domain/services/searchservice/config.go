@@ -0,0 +1,45 @@
+package searchservice
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// Config holds search service configuration
+type Config struct {
+	// VerifyUserIsolation re-checks that every returned chunk belongs to the caller
+	// or to a shared resource. Intended for staging, where a broken search scope
+	// must surface early.
+	VerifyUserIsolation bool `yaml:"verify_user_isolation" mapstructure:"verify_user_isolation"`
+	// CacheTTL is how long search results are cached; zero disables caching.
+	CacheTTL time.Duration `yaml:"cache_ttl" mapstructure:"cache_ttl"`
+	// AnswerCacheTTL is how long generated answers are cached; CacheTTL when unset.
+	AnswerCacheTTL time.Duration `yaml:"answer_cache_ttl" mapstructure:"answer_cache_ttl"`
+	// CacheSize is the number of results, answers and stats cached together,
+	// the least recently used being evicted first; defaultCacheSize when unset.
+	CacheSize int `yaml:"cache_size" mapstructure:"cache_size" validate:"min=0"`
+	// StatsCacheTTL is how long the index stats of a user are cached; zero
+	// computes them on every request.
+	StatsCacheTTL time.Duration `yaml:"stats_cache_ttl" mapstructure:"stats_cache_ttl"`
+	// RecencyHalfLife is the resource age at which the recency boost has lost
+	// half of its effect; 30 days when unset.
+	RecencyHalfLife time.Duration `yaml:"recency_half_life" mapstructure:"recency_half_life"`
+}
+
+// NewConfig loads search service configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	// Parse configuration from "search" section
+	config, err := configurator.ParseConfig[Config]("search")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search config: %w", err)
+	}
+
+	// Allow staging deployments to switch verification on without a config change
+	if verifyEnv := configurator.GetString("SEARCH_VERIFY_USER_ISOLATION"); verifyEnv != "" {
+		config.VerifyUserIsolation = configurator.GetBool("SEARCH_VERIFY_USER_ISOLATION")
+	}
+
+	return config, nil
+}
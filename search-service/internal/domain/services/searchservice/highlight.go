@@ -0,0 +1,155 @@
+package searchservice
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+
+	// minTermRunes drops query terms too short to be worth marking
+	minTermRunes = 3
+)
+
+// stopWords are frequent question words that would mark most of a snippet
+var stopWords = map[string]struct{}{
+	"and": {}, "are": {}, "but": {}, "can": {}, "did": {}, "does": {}, "for": {},
+	"from": {}, "has": {}, "have": {}, "how": {}, "into": {}, "not": {}, "that": {},
+	"the": {}, "their": {}, "there": {}, "this": {}, "was": {}, "what": {}, "when": {},
+	"where": {}, "which": {}, "who": {}, "why": {}, "with": {}, "you": {},
+}
+
+// stemSuffixes are stripped from words so that close variants of a term, like
+// plurals and verb forms, match it. Longer suffixes come first.
+var stemSuffixes = []struct{ suffix, replacement string }{
+	{"ies", "y"},
+	{"ied", "y"},
+	{"ing", ""},
+	{"es", ""},
+	{"ed", ""},
+	{"ss", "ss"},
+	{"s", ""},
+}
+
+// span is the byte range of a match in the content
+type span struct {
+	start, end int
+}
+
+// highlightIfRequested returns a copy of the references with Highlighted set
+// when the options ask for it. The references themselves are left untouched,
+// since they may be shared with the result cache.
+func highlightIfRequested(options *SearchOptions, query string, refs []models.Reference) []models.Reference {
+	if !options.Highlight || len(refs) == 0 {
+		return refs
+	}
+
+	terms := queryTerms(query)
+	highlighted := make([]models.Reference, len(refs))
+	for i, ref := range refs {
+		ref.Highlighted = highlight(ref.Content, terms)
+		highlighted[i] = ref
+	}
+	return highlighted
+}
+
+// queryTerms returns the stems of the words of the query worth marking
+func queryTerms(query string) map[string]struct{} {
+	terms := make(map[string]struct{})
+	for _, word := range words(query) {
+		folded := strings.ToLower(query[word.start:word.end])
+		if utf8.RuneCountInString(folded) < minTermRunes {
+			continue
+		}
+		if _, ok := stopWords[folded]; ok {
+			continue
+		}
+		terms[stem(folded)] = struct{}{}
+	}
+	return terms
+}
+
+// highlight escapes the content as HTML and wraps the words sharing a stem with
+// a query term in <mark> tags. Matches next to each other, separated only by
+// spaces, are marked as one.
+func highlight(content string, terms map[string]struct{}) string {
+	var matches []span
+	if len(terms) > 0 {
+		for _, word := range words(content) {
+			if _, ok := terms[stem(strings.ToLower(content[word.start:word.end]))]; ok {
+				matches = append(matches, word)
+			}
+		}
+	}
+	matches = mergeSpans(content, matches)
+
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(html.EscapeString(content[last:match.start]))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(content[match.start:match.end]))
+		b.WriteString(highlightClose)
+		last = match.end
+	}
+	b.WriteString(html.EscapeString(content[last:]))
+	return b.String()
+}
+
+// mergeSpans joins sorted spans that overlap or are separated only by spaces,
+// so that no marks nest and a matched phrase is marked once
+func mergeSpans(content string, spans []span) []span {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	merged := []span{spans[0]}
+	for _, next := range spans[1:] {
+		current := &merged[len(merged)-1]
+		if next.start <= current.end || strings.TrimSpace(content[current.end:next.start]) == "" {
+			current.end = max(current.end, next.end)
+			continue
+		}
+		merged = append(merged, next)
+	}
+	return merged
+}
+
+// words returns the byte ranges of the runs of letters and digits in s.
+// Combining marks stay part of their word, so accented text is not split.
+func words(s string) []span {
+	var result []span
+	start := -1
+	for i, r := range s {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && unicode.Is(unicode.Mn, r))
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			result = append(result, span{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, span{start: start, end: len(s)})
+	}
+	return result
+}
+
+// stem strips a common inflection suffix from a lowercase word, keeping at least
+// minTermRunes runes of it
+func stem(word string) string {
+	for _, s := range stemSuffixes {
+		base, ok := strings.CutSuffix(word, s.suffix)
+		if ok && utf8.RuneCountInString(base)+utf8.RuneCountInString(s.replacement) >= minTermRunes {
+			return base + s.replacement
+		}
+	}
+	return word
+}
@@ -0,0 +1,83 @@
+package searchservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		content  string
+		expected string
+	}{
+		{
+			name:     "case insensitive",
+			query:    "goroutines",
+			content:  "Goroutines are cheap.",
+			expected: "<mark>Goroutines</mark> are cheap.",
+		},
+		{
+			name:     "close variants",
+			query:    "indexing studies",
+			content:  "The index was studied, then indexed again.",
+			expected: "The <mark>index</mark> was <mark>studied</mark>, then <mark>indexed</mark> again.",
+		},
+		{
+			name:     "adjacent matches marked once",
+			query:    "neural network",
+			content:  "A neural  networks paper.",
+			expected: "A <mark>neural  networks</mark> paper.",
+		},
+		{
+			name:     "terms sharing a stem",
+			query:    "class classes",
+			content:  "Each class, then classes.",
+			expected: "Each <mark>class</mark>, then <mark>classes</mark>.",
+		},
+		{
+			name:     "stop words and short terms skipped",
+			query:    "what is the go scheduler",
+			content:  "What is the Go scheduler?",
+			expected: "What is the Go <mark>scheduler</mark>?",
+		},
+		{
+			name:     "unicode case folding",
+			query:    "ÉCOLE straße",
+			content:  "L'école près de la Straße.",
+			expected: "L&#39;<mark>école</mark> près de la <mark>Straße</mark>.",
+		},
+		{
+			name:     "combining marks stay in the word",
+			query:    "cafe\u0301",
+			content:  "Un CAFE\u0301 noir.",
+			expected: "Un <mark>CAFE\u0301</mark> noir.",
+		},
+		{
+			name:     "cyrillic",
+			query:    "поиск",
+			content:  "Семантический Поиск по документам.",
+			expected: "Семантический <mark>Поиск</mark> по документам.",
+		},
+		{
+			name:     "content escaped",
+			query:    "mark",
+			content:  "<b>mark</b> & co",
+			expected: "&lt;b&gt;<mark>mark</mark>&lt;/b&gt; &amp; co",
+		},
+		{
+			name:     "no terms",
+			query:    "is it",
+			content:  "It is.",
+			expected: "It is.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, highlight(tt.content, queryTerms(tt.query)))
+		})
+	}
+}
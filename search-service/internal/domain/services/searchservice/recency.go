@@ -0,0 +1,66 @@
+package searchservice
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// defaultRecencyHalfLife is used when neither the option nor the config sets one
+const defaultRecencyHalfLife = 30 * 24 * time.Hour
+
+// recencyWeight is the share of the score that depends on the age of the
+// resource. A brand new resource keeps its similarity, a very old one keeps
+// half of it, so recency reorders close matches without burying relevant
+// older documents.
+const recencyWeight = 0.5
+
+// boostIfRequested returns a copy of the references re-scored by the age of
+// their resource and sorted by the new scores when the options ask for it.
+// The references themselves are left untouched, since they may be shared
+// with the result cache.
+func (s *Service) boostIfRequested(options *SearchOptions, refs []models.Reference) []models.Reference {
+	if !options.RecencyBoost || len(refs) == 0 {
+		return refs
+	}
+
+	halfLife := options.RecencyHalfLife
+	if halfLife <= 0 {
+		halfLife = s.cfg.RecencyHalfLife
+	}
+	if halfLife <= 0 {
+		halfLife = defaultRecencyHalfLife
+	}
+
+	return boostRecency(refs, halfLife, time.Now())
+}
+
+// boostRecency scales each score by a factor between 1-recencyWeight and 1
+// that halves its recency part every halfLife of resource age. References of
+// unknown age count as old. Equal scores keep their retrieval order.
+func boostRecency(refs []models.Reference, halfLife time.Duration, now time.Time) []models.Reference {
+	boosted := make([]models.Reference, len(refs))
+	for i, ref := range refs {
+		decay := 0.0
+		if !ref.CreatedAt.IsZero() {
+			age := max(now.Sub(ref.CreatedAt), 0)
+			decay = math.Exp2(-float64(age) / float64(halfLife))
+		}
+		ref.Score = float32(float64(ref.Score) * (1 - recencyWeight + recencyWeight*decay))
+		boosted[i] = ref
+	}
+
+	slices.SortStableFunc(boosted, func(a, b models.Reference) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return boosted
+}
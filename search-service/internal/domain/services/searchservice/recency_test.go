@@ -0,0 +1,73 @@
+package searchservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func TestBoostRecency(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	halfLife := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		refs     []models.Reference
+		expected []string
+		scores   []float32
+	}{
+		{
+			name: "fresh resource overtakes a slightly closer stale one",
+			refs: []models.Reference{
+				{Content: "stale", Score: 0.8, CreatedAt: now.Add(-2 * halfLife)},
+				{Content: "fresh", Score: 0.7, CreatedAt: now},
+			},
+			expected: []string{"fresh", "stale"},
+			scores:   []float32{0.7, 0.5},
+		},
+		{
+			name: "much closer old resource stays first",
+			refs: []models.Reference{
+				{Content: "old", Score: 0.9, CreatedAt: now.Add(-halfLife)},
+				{Content: "new", Score: 0.6, CreatedAt: now},
+			},
+			expected: []string{"old", "new"},
+			scores:   []float32{0.675, 0.6},
+		},
+		{
+			name: "unknown age counts as old",
+			refs: []models.Reference{
+				{Content: "unknown", Score: 0.8},
+				{Content: "dated", Score: 0.8, CreatedAt: now.Add(-halfLife)},
+			},
+			expected: []string{"dated", "unknown"},
+			scores:   []float32{0.6, 0.4},
+		},
+		{
+			name: "equal scores keep retrieval order",
+			refs: []models.Reference{
+				{Content: "first", Score: 0.5, CreatedAt: now},
+				{Content: "second", Score: 0.5, CreatedAt: now.Add(time.Hour)},
+			},
+			expected: []string{"first", "second"},
+			scores:   []float32{0.5, 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append([]models.Reference(nil), tt.refs...)
+
+			boosted := boostRecency(tt.refs, halfLife, now)
+
+			for i, ref := range boosted {
+				assert.Equal(t, tt.expected[i], ref.Content)
+				assert.InDelta(t, tt.scores[i], ref.Score, 1e-6)
+			}
+			assert.Equal(t, original, tt.refs, "references must not be modified")
+		})
+	}
+}
@@ -2,16 +2,72 @@ package searchservice
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
 	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
 )
 
 type SearchOption func(*SearchOptions)
 
+// SearchMode identifies how chunks are retrieved for a search
+type SearchMode string
+
+const (
+	// SearchModeVector ranks chunks by embedding similarity only
+	SearchModeVector SearchMode = "vector"
+	// SearchModeMMR reranks similar chunks by max marginal relevance
+	SearchModeMMR SearchMode = "mmr"
+)
+
 type SearchOptions struct {
 	NumberOfReferences int
+	MMR                bool
+	MMRLambda          float64
+	// ContextSize is the number of references given to the model as answer
+	// context, all of them when unset, see WithContextSize
+	ContextSize int
+	// ScoreThreshold overrides the configured threshold of the search mode when set
+	ScoreThreshold *float64
+	// Temperature and MaxTokens override the configured generation settings when set
+	Temperature *float64
+	MaxTokens   *int
+	// Language is the ISO 639-1 code of the answer language, detected from the question when empty
+	Language string
+	// SystemPrompt is appended to the configured system prompt when set
+	SystemPrompt string
+	// SkipGeneration returns the retrieved references without generating an answer
+	SkipGeneration bool
+	// QueryExpansion retrieves for model-written variants of the question as well
+	QueryExpansion bool
+	// Highlight marks the query terms in the content of semantic search references
+	Highlight bool
+	// RecencyBoost re-scores semantic search references by the age of their
+	// resource, halving the recency part every RecencyHalfLife
+	RecencyBoost    bool
+	RecencyHalfLife time.Duration
+	// NoCache skips cached results; the fresh result still replaces the cached one
+	NoCache bool
+	// Summarize has the generator summarize the first semantic search references
+	Summarize bool
+}
+
+// Mode returns the search mode selected by the options
+func (o SearchOptions) Mode() SearchMode {
+	if o.MMR {
+		return SearchModeMMR
+	}
+	return SearchModeVector
 }
 
 func WithNumberOfReferences(n int) SearchOption {
@@ -20,12 +76,142 @@ func WithNumberOfReferences(n int) SearchOption {
 	}
 }
 
+// WithContextSize gives only the n most relevant references to the model as
+// answer context, while WithNumberOfReferences still sets how many are
+// retrieved and returned. This keeps prompts small when many references are
+// shown. The context can't hold more than the retrieved references, so a
+// larger n is clamped to their number.
+func WithContextSize(n int) SearchOption {
+	return func(o *SearchOptions) {
+		o.ContextSize = n
+	}
+}
+
+// WithMMR enables max-marginal-relevance reranking of retrieved chunks.
+// lambda balances relevance (1) against diversity (0). MMR over-fetches
+// candidates and embeds them again, so it costs an extra embedding call
+// per search in exchange for fewer near-duplicate references.
+func WithMMR(lambda float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.MMR = true
+		o.MMRLambda = lambda
+	}
+}
+
+// WithScoreThreshold drops chunks scoring below threshold instead of using
+// the default threshold configured for the search mode.
+func WithScoreThreshold(threshold float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.ScoreThreshold = &threshold
+	}
+}
+
+// WithTemperature sets the sampling temperature of the generated answer.
+// Lower values give more focused answers, higher values more varied ones.
+func WithTemperature(temperature float64) SearchOption {
+	return func(o *SearchOptions) {
+		o.Temperature = &temperature
+	}
+}
+
+// WithMaxTokens limits the length of the generated answer
+func WithMaxTokens(maxTokens int) SearchOption {
+	return func(o *SearchOptions) {
+		o.MaxTokens = &maxTokens
+	}
+}
+
+// WithLanguage selects the answer language instead of detecting it from the
+// question. The code should be one of models.AnswerLanguages, any other code
+// gets the prompt answering in the language of the question.
+func WithLanguage(code string) SearchOption {
+	return func(o *SearchOptions) {
+		o.Language = code
+	}
+}
+
+// WithSystemPrompt adds to the persona the answer is written in, e.g. its tone,
+// domain or what to refuse. It follows the configured system prompt, which a
+// request cannot replace, and must fit in the same token budget.
+func WithSystemPrompt(prompt string) SearchOption {
+	return func(o *SearchOptions) {
+		o.SystemPrompt = prompt
+	}
+}
+
+// WithoutGeneration only retrieves the references of an answer. It is meant
+// for clients rendering the snippets themselves, which don't need the model
+// to write an answer.
+func WithoutGeneration() SearchOption {
+	return func(o *SearchOptions) {
+		o.SkipGeneration = true
+	}
+}
+
+// WithQueryExpansion has the generation model rewrite the question into a few
+// variants that are retrieved for alongside it, which helps short or keyword
+// questions. It costs an extra model round-trip before retrieval.
+func WithQueryExpansion(enabled bool) SearchOption {
+	return func(o *SearchOptions) {
+		o.QueryExpansion = enabled
+	}
+}
+
+// WithHighlight marks the query terms and their close variants in the returned
+// references, see models.Reference.Highlighted
+func WithHighlight(enabled bool) SearchOption {
+	return func(o *SearchOptions) {
+		o.Highlight = enabled
+	}
+}
+
+// WithRecencyBoost favours recently created resources among the semantic search
+// references. Scores are scaled down with the age of the resource, losing at
+// most half of the similarity, and the references are sorted again. A zero
+// halfLife uses the configured search.recency_half_life.
+func WithRecencyBoost(halfLife time.Duration) SearchOption {
+	return func(o *SearchOptions) {
+		o.RecencyBoost = true
+		o.RecencyHalfLife = halfLife
+	}
+}
+
+// WithSummaries has the generator write a one-line summary of the first
+// semantic search references, see models.Reference.Summary. It costs a model
+// round-trip per summarized reference, which are made concurrently.
+func WithSummaries(enabled bool) SearchOption {
+	return func(o *SearchOptions) {
+		o.Summarize = enabled
+	}
+}
+
+// WithoutCache runs the search even when its result is cached. The fresh
+// result is cached in place of the previous one.
+func WithoutCache() SearchOption {
+	return func(o *SearchOptions) {
+		o.NoCache = true
+	}
+}
+
 type vectorStorage interface {
-	GetAnswer(ctx context.Context, question string) (string, []models.Reference, error)
+	GetAnswer(ctx context.Context, question string, opts ...SearchOption) (string, []models.Reference, error)
 	GetAnswerStream(ctx context.Context, question string, opts ...SearchOption) (<-chan string, <-chan []models.Reference, <-chan []byte, <-chan error)
-	SemanticSearch(ctx context.Context, query string) ([]models.Reference, error)
+	SemanticSearch(ctx context.Context, query string, opts ...SearchOption) ([]models.Reference, error)
+	GetResourceChunks(ctx context.Context, resourceID uuid.UUID, limit, offset int) ([]models.Chunk, int, error)
+	GetIndexUsage(ctx context.Context) ([]models.ResourceUsage, error)
+	FindSimilarResources(ctx context.Context, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error)
+	GetResourcesByIDs(ctx context.Context, resourceIDs []uuid.UUID) (map[uuid.UUID]models.Resource, error)
+	SummarizeReferences(ctx context.Context, refs []models.Reference) ([]models.Reference, error)
 }
 
+const (
+	defaultChunksLimit = 20
+	maxChunksLimit     = 100
+
+	defaultSimilarLimit = 5
+	maxSimilarLimit     = 20
+)
+
 type eventPublisher interface {
 	PublishEvent(ctx context.Context, topic string, eventName string, data interface{}) error
 }
@@ -33,15 +219,23 @@ type eventPublisher interface {
 type Service struct {
 	vectorStorage  vectorStorage
 	eventPublisher eventPublisher // Optional event publisher
+	cfg            *Config
+	cache          *resultCache // Nil when caching is disabled
 }
 
 // NewService creates a new search service with optional event publisher
-func NewService(vs vectorStorage, eventPublisher ...eventPublisher) *Service {
+func NewService(vs vectorStorage, cfg *Config, eventPublisher ...eventPublisher) *Service {
 	slog.Debug("Initializing search service",
-		"vector_storage_type", fmt.Sprintf("%T", vs),
-		"repository_type", fmt.Sprintf("%T"))
+		"vector_storage_type", fmt.Sprintf("%T", vs))
+
+	if cfg == nil {
+		cfg = &Config{}
+	}
 
-	service := &Service{vectorStorage: vs}
+	service := &Service{vectorStorage: vs, cfg: cfg}
+	if cfg.CacheTTL > 0 || cfg.AnswerCacheTTL > 0 || cfg.StatsCacheTTL > 0 {
+		service.cache = newResultCache(cfg.CacheTTL, cfg.CacheSize)
+	}
 	if len(eventPublisher) > 0 {
 		service.eventPublisher = eventPublisher[0]
 		slog.Debug("Event publisher configured for search service")
@@ -52,7 +246,7 @@ func NewService(vs vectorStorage, eventPublisher ...eventPublisher) *Service {
 func (s *Service) GetAnswerStream(
 	ctx context.Context,
 	question string,
-	numReferences int,
+	opts ...SearchOption,
 ) (
 	<-chan models.SearchResult,
 	<-chan []models.Reference,
@@ -61,6 +255,21 @@ func (s *Service) GetAnswerStream(
 ) {
 	const op = "Service.GetAnswerStream"
 
+	options := &SearchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cacheKey, userID, cacheable := s.answerCacheKey(ctx, options, question)
+	if cacheable && !options.NoCache {
+		if cached, ok := s.cache.get(cacheKey); ok {
+			metrics.AnswerCacheHits.WithLabelValues(operationAnswerStream).Inc()
+			slog.DebugContext(ctx, "Serving cached answer as a single chunk", "question", question)
+			return streamCachedAnswer(cached.(models.SearchResult))
+		}
+		metrics.AnswerCacheMisses.WithLabelValues(operationAnswerStream).Inc()
+	}
+
 	errOutputCh := make(chan error, 1)
 	refsOutputCh := make(chan []models.Reference)
 	searchResultOutputCh := make(chan models.SearchResult)
@@ -68,11 +277,18 @@ func (s *Service) GetAnswerStream(
 	answerCh, refsCh, chunkCh, getAnswerErrCh := s.vectorStorage.GetAnswerStream(
 		ctx,
 		question,
-		WithNumberOfReferences(numReferences),
+		opts...,
 	)
 
+	// Chunks may be generated before the references reached the client, they
+	// are held back until then so that sources can be shown first
+	refsSent := make(chan struct{})
+	chunkOutputCh, chunksForwarded := forwardChunksAfter(ctx, refsSent, chunkCh)
+
 	go func() {
+		var releaseChunks sync.Once
 		defer func() {
+			releaseChunks.Do(func() { close(refsSent) })
 			close(refsOutputCh)
 			close(errOutputCh)
 			close(searchResultOutputCh)
@@ -81,25 +297,43 @@ func (s *Service) GetAnswerStream(
 		processedRefsCh := make(chan []models.Reference, 1)
 		defer close(processedRefsCh)
 
+		// Markers in the answer number the references as retrieved, before verification
+		var retrievedRefs []models.Reference
+
 		for {
 			select {
 			case refs := <-refsCh:
+				refs = s.attachResources(ctx, refs)
+				retrievedRefs = refs
+				refs = s.verifyUserIsolation(ctx, refs)
 				processedRefsCh <- refs
 				refsOutputCh <- refs
+				releaseChunks.Do(func() { close(refsSent) })
 			case <-ctx.Done():
-				slog.Debug("Context cancelled")
-				errOutputCh <- ctx.Err()
+				errOutputCh <- s.searchFailed(ctx, op, operationAnswerStream, ctx.Err())
 				return
 			case err := <-getAnswerErrCh:
-				slog.Error("Error getting answer stream", "err", err)
-				errOutputCh <- fmt.Errorf("%s: %w", op, err)
+				errOutputCh <- s.searchFailed(ctx, op, operationAnswerStream, err)
 				return
 			case answer := <-answerCh:
-				slog.Info("Processing answer", "question", question)
+				slog.InfoContext(ctx, "Processing answer", "question", question)
 
+				refs := <-processedRefsCh
 				searchResult := models.SearchResult{
 					Answer:     answer,
-					References: <-processedRefsCh,
+					References: refs,
+					Citations:  citations(answer, retrievedRefs, refs),
+				}
+				if cacheable {
+					s.cache.putFor(cacheKey, userID, searchResult, refs, s.answerCacheTTL())
+				}
+
+				// The result completes the stream, so it follows the last chunk
+				select {
+				case <-chunksForwarded:
+				case <-ctx.Done():
+					errOutputCh <- s.searchFailed(ctx, op, operationAnswerStream, ctx.Err())
+					return
 				}
 
 				searchResultOutputCh <- searchResult
@@ -108,23 +342,112 @@ func (s *Service) GetAnswerStream(
 		}
 	}()
 
-	return searchResultOutputCh, refsOutputCh, chunkCh, errOutputCh
+	return searchResultOutputCh, refsOutputCh, chunkOutputCh, errOutputCh
+}
+
+// forwardChunksAfter forwards the chunks once release is closed. The returned
+// done channel is closed when every chunk was forwarded or ctx is done.
+func forwardChunksAfter(ctx context.Context, release <-chan struct{}, chunkCh <-chan []byte) (<-chan []byte, <-chan struct{}) {
+	done := make(chan struct{})
+	if chunkCh == nil {
+		close(done)
+		return nil, done
+	}
+
+	out := make(chan []byte)
+
+	go func() {
+		defer func() {
+			close(out)
+			close(done)
+		}()
+
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return
+		}
+
+		for chunk := range chunkCh {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, done
+}
+
+// streamCachedAnswer replays a cached answer the way a generated one is
+// streamed: the references, the whole answer as a single chunk, then the result
+func streamCachedAnswer(result models.SearchResult) (
+	<-chan models.SearchResult,
+	<-chan []models.Reference,
+	<-chan []byte,
+	<-chan error,
+) {
+	errCh := make(chan error, 1)
+	refsCh := make(chan []models.Reference)
+	chunkCh := make(chan []byte)
+	resultCh := make(chan models.SearchResult)
+
+	go func() {
+		defer func() {
+			close(refsCh)
+			close(errCh)
+			close(resultCh)
+		}()
+
+		refsCh <- result.References
+		chunkCh <- []byte(result.Answer)
+		close(chunkCh)
+		resultCh <- result
+	}()
+
+	return resultCh, refsCh, chunkCh, errCh
 }
 
-func (s *Service) GetAnswer(ctx context.Context, question string) (models.SearchResult, error) {
+func (s *Service) GetAnswer(ctx context.Context, question string, opts ...SearchOption) (models.SearchResult, error) {
 	const op = "Service.GetAnswer"
 	slog.InfoContext(ctx, "Getting answer",
 		"question", question)
 
-	answer, refs, err := s.vectorStorage.GetAnswer(ctx, question)
+	options := &SearchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cacheKey, userID, cacheable := s.answerCacheKey(ctx, options, question)
+	if cacheable && !options.NoCache {
+		if cached, ok := s.cache.get(cacheKey); ok {
+			metrics.AnswerCacheHits.WithLabelValues(operationAnswer).Inc()
+			slog.DebugContext(ctx, "Serving cached answer", "question", question)
+			return cached.(models.SearchResult), nil
+		}
+		metrics.AnswerCacheMisses.WithLabelValues(operationAnswer).Inc()
+	}
+
+	answer, refs, err := s.vectorStorage.GetAnswer(ctx, question, opts...)
 	if err != nil {
-		slog.Error("Error getting answer", "err", err)
-		return models.SearchResult{}, fmt.Errorf("%s: %w", op, err)
+		return models.SearchResult{}, s.searchFailed(ctx, op, operationAnswer, err)
 	}
 
+	refs = s.attachResources(ctx, refs)
+	verified := s.verifyUserIsolation(ctx, refs)
+
 	result := models.SearchResult{
 		Answer:     answer,
-		References: refs,
+		References: verified,
+		Citations:  citations(answer, refs, verified),
+		// Set so clients can tell a skipped generation from an empty answer
+		GenerationSkipped: options.SkipGeneration,
+	}
+	refs = verified
+
+	if cacheable {
+		s.cache.putFor(cacheKey, userID, result, refs, s.answerCacheTTL())
 	}
 
 	// Publish search event if event publisher is available
@@ -134,6 +457,7 @@ func (s *Service) GetAnswer(ctx context.Context, question string) (models.Search
 			"answer_length":    len(answer),
 			"references_count": len(refs),
 			"operation":        "get_answer",
+			"generation":       !options.SkipGeneration,
 		}
 		if err := s.eventPublisher.PublishEvent(ctx, "search", "search.performed", searchEvent); err != nil {
 			slog.WarnContext(ctx, "Failed to publish search event", "error", err)
@@ -144,20 +468,36 @@ func (s *Service) GetAnswer(ctx context.Context, question string) (models.Search
 	return result, nil
 }
 
-func (s *Service) SemanticSearch(ctx context.Context, query string) ([]models.Reference, error) {
+func (s *Service) SemanticSearch(ctx context.Context, query string, opts ...SearchOption) ([]models.Reference, error) {
 	const op = "Service.SemanticSearch"
 	slog.InfoContext(ctx, "Performing semantic search",
 		"query", query)
 	select {
 	case <-ctx.Done():
-		return nil, ctx.Err()
+		return nil, s.searchFailed(ctx, op, operationSemantic, ctx.Err())
 	default:
-		references, err := s.vectorStorage.SemanticSearch(ctx, query)
+		options := &SearchOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+
+		cacheKey, userID, cacheable := s.cacheKey(ctx, s.cfg.CacheTTL, "semantic", query+"\x00"+optionsKey(options))
+		if cacheable && !options.NoCache {
+			if cached, ok := s.cache.get(cacheKey); ok {
+				slog.DebugContext(ctx, "Serving cached semantic search", "query", query)
+				return highlightIfRequested(options, query, s.summarizeIfRequested(ctx, options, s.boostIfRequested(options, cached.([]models.Reference)))), nil
+			}
+		}
+
+		references, err := s.vectorStorage.SemanticSearch(ctx, query, opts...)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to perform semantic search",
-				"op", op,
-				"error", err)
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return nil, s.searchFailed(ctx, op, operationSemantic, err)
+		}
+
+		references = s.verifyUserIsolation(ctx, s.attachResources(ctx, references))
+
+		if cacheable {
+			s.cache.put(cacheKey, userID, references, references)
 		}
 
 		slog.InfoContext(ctx, "Semantic search completed",
@@ -176,6 +516,304 @@ func (s *Service) SemanticSearch(ctx context.Context, query string) ([]models.Re
 			}
 		}
 
-		return references, nil
+		return highlightIfRequested(options, query, s.summarizeIfRequested(ctx, options, s.boostIfRequested(options, references))), nil
+	}
+}
+
+// GetResourceChunks returns a page of the stored chunks of the caller's resource.
+// Resources of other users are reported as not found.
+func (s *Service) GetResourceChunks(ctx context.Context, resourceID uuid.UUID, limit, offset int) (models.ChunkPage, error) {
+	const op = "Service.GetResourceChunks"
+
+	if limit <= 0 {
+		limit = defaultChunksLimit
+	}
+	limit = min(limit, maxChunksLimit)
+	offset = max(offset, 0)
+
+	chunks, total, err := s.vectorStorage.GetResourceChunks(ctx, resourceID, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return models.ChunkPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if total == 0 {
+		return models.ChunkPage{}, fmt.Errorf("%s: %w", op, models.ErrResourceNotFound)
+	}
+
+	return models.ChunkPage{
+		Chunks: chunks,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// FindSimilarResources returns up to limit resources of the caller related to
+// the resource by content, the closest first. Resources of other users are
+// reported as not found.
+func (s *Service) FindSimilarResources(ctx context.Context, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error) {
+	const op = "Service.FindSimilarResources"
+
+	if limit <= 0 {
+		limit = defaultSimilarLimit
+	}
+	limit = min(limit, maxSimilarLimit)
+
+	similar, err := s.vectorStorage.FindSimilarResources(ctx, resourceID, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find similar resources",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return similar, nil
+}
+
+// GetIndexStats sums up what the indexed resources of the caller take up.
+// The sums scan every chunk of the user, so they are cached for StatsCacheTTL.
+// New or changed resources of the user evict them like cached answers, and a
+// deleted resource evicts them as it is one of the summed up resources.
+func (s *Service) GetIndexStats(ctx context.Context) (models.IndexStats, error) {
+	const op = "Service.GetIndexStats"
+
+	cacheKey, userID, cacheable := s.cacheKey(ctx, s.cfg.StatsCacheTTL, "stats", "")
+	if cacheable {
+		if cached, ok := s.cache.get(cacheKey); ok {
+			slog.DebugContext(ctx, "Serving cached index stats")
+			return cached.(models.IndexStats), nil
+		}
+	}
+
+	usage, err := s.vectorStorage.GetIndexUsage(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get index usage",
+			"op", op,
+			"error", err)
+		return models.IndexStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stats := models.IndexStats{
+		Resources:  len(usage),
+		ByResource: make([]models.ResourceUsage, 0, len(usage)),
+	}
+	// The resources are kept as references for the cache to evict by
+	refs := make([]models.Reference, 0, len(usage))
+	for _, resource := range usage {
+		stats.Chunks += resource.Chunks
+		stats.StorageBytes += resource.StorageBytes
+		stats.ByResource = append(stats.ByResource, resource)
+		refs = append(refs, models.Reference{ResourceID: resource.ResourceID})
+	}
+
+	if cacheable {
+		s.cache.putFor(cacheKey, userID, stats, refs, s.cfg.StatsCacheTTL)
+	}
+
+	return stats, nil
+}
+
+// InvalidateResource evicts cached results whose references came from the resource
+func (s *Service) InvalidateResource(ctx context.Context, resourceID uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+
+	evicted := s.cache.invalidateResource(resourceID)
+	slog.DebugContext(ctx, "Invalidated cached results for resource",
+		"resource_id", resourceID,
+		"evicted", evicted)
+}
+
+// InvalidateUser evicts every cached result of the user
+func (s *Service) InvalidateUser(ctx context.Context, userID string) {
+	if s.cache == nil {
+		return
+	}
+
+	evicted := s.cache.invalidateUser(userID)
+	slog.DebugContext(ctx, "Invalidated cached results for user",
+		"user_id", userID,
+		"evicted", evicted)
+}
+
+// cacheKey builds a per-user cache key; results are only cacheable when caching
+// is enabled with ttl and the caller is known.
+func (s *Service) cacheKey(ctx context.Context, ttl time.Duration, operation string, query string) (string, string, bool) {
+	if s.cache == nil || ttl <= 0 {
+		return "", "", false
+	}
+
+	userID, ok := identity.UserID(ctx)
+	if !ok {
+		return "", "", false
 	}
+
+	return userID + "\x00" + operation + "\x00" + query, userID, true
+}
+
+// answerCacheKey builds the cache key of an answer from the normalized question
+// and the options shaping the answer, so that rephrasings differing only in
+// case, spacing or final punctuation share a cached answer
+func (s *Service) answerCacheKey(ctx context.Context, options *SearchOptions, question string) (string, string, bool) {
+	operation := "answer"
+	if options.SkipGeneration {
+		operation = "answer_references"
+	}
+
+	sum := sha256.Sum256([]byte(normalizeQuestion(question) + "\x00" + answerOptionsKey(options)))
+	return s.cacheKey(ctx, s.answerCacheTTL(), operation, hex.EncodeToString(sum[:]))
+}
+
+// answerCacheTTL returns how long answers are cached
+func (s *Service) answerCacheTTL() time.Duration {
+	if s.cfg.AnswerCacheTTL > 0 {
+		return s.cfg.AnswerCacheTTL
+	}
+	return s.cfg.CacheTTL
+}
+
+// normalizeQuestion lowercases a question, collapses its whitespace and drops
+// its final punctuation
+func normalizeQuestion(question string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(question)), " ")
+	return strings.TrimRight(normalized, "?!.…。？！ ")
+}
+
+// answerOptionsKey renders the options of an answer for cache keys
+func answerOptionsKey(options *SearchOptions) string {
+	temperature, maxTokens := "default", "default"
+	if options.Temperature != nil {
+		temperature = fmt.Sprintf("%g", *options.Temperature)
+	}
+	if options.MaxTokens != nil {
+		maxTokens = fmt.Sprintf("%d", *options.MaxTokens)
+	}
+	return fmt.Sprintf("%s:%d:%s:%s:%s:%t:%q", optionsKey(options), options.ContextSize, temperature, maxTokens, options.Language, options.QueryExpansion, options.SystemPrompt)
+}
+
+// optionsKey renders search options for cache keys, dereferencing optional values
+func optionsKey(options *SearchOptions) string {
+	threshold := "default"
+	if options.ScoreThreshold != nil {
+		threshold = fmt.Sprintf("%g", *options.ScoreThreshold)
+	}
+	return fmt.Sprintf("%d:%t:%g:%s", options.NumberOfReferences, options.MMR, options.MMRLambda, threshold)
+}
+
+// citations parses the citation markers of an answer against the retrieved
+// references and keeps only those citing a reference that passed verification
+func citations(answer string, retrieved, verified []models.Reference) []models.Citation {
+	parsed := models.ParseCitations(answer, retrieved)
+	return slices.DeleteFunc(parsed, func(c models.Citation) bool {
+		return !slices.Contains(verified, c.Reference)
+	})
+}
+
+// summarizeIfRequested returns the references with summaries when the options
+// ask for them. Summaries are not cached, and as they only add to the
+// references, the references are returned without them when summarizing fails.
+func (s *Service) summarizeIfRequested(ctx context.Context, options *SearchOptions, refs []models.Reference) []models.Reference {
+	const op = "Service.summarizeIfRequested"
+
+	if !options.Summarize || len(refs) == 0 {
+		return refs
+	}
+
+	summarized, err := s.vectorStorage.SummarizeReferences(ctx, refs)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to summarize references",
+			"op", op,
+			"error", err)
+		return refs
+	}
+	return summarized
+}
+
+// attachResources sets the name and URL of their resource on the references,
+// looked up in one batch. References of deleted resources keep them empty, and
+// so do all references when the lookup fails, as they are still usable.
+func (s *Service) attachResources(ctx context.Context, refs []models.Reference) []models.Reference {
+	const op = "Service.attachResources"
+
+	if len(refs) == 0 {
+		return refs
+	}
+
+	seen := make(map[uuid.UUID]bool, len(refs))
+	resourceIDs := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref.ResourceID] {
+			seen[ref.ResourceID] = true
+			resourceIDs = append(resourceIDs, ref.ResourceID)
+		}
+	}
+
+	resources, err := s.vectorStorage.GetResourcesByIDs(ctx, resourceIDs)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to look up referenced resources",
+			"op", op,
+			"resources_count", len(resourceIDs),
+			"error", err)
+		return refs
+	}
+
+	for i := range refs {
+		if resource, ok := resources[refs[i].ResourceID]; ok {
+			refs[i].ResourceName = resource.Name
+			refs[i].ResourceURL = resource.URL
+		}
+	}
+	return refs
+}
+
+// verifyUserIsolation drops references to private resources of other users
+// when isolation verification is enabled. Every leak is logged and reported as
+// a "search.isolation_violation" event, since it means the search scope regressed.
+func (s *Service) verifyUserIsolation(ctx context.Context, refs []models.Reference) []models.Reference {
+	const op = "Service.verifyUserIsolation"
+
+	if !s.cfg.VerifyUserIsolation || refs == nil {
+		return refs
+	}
+
+	userID, ok := identity.UserID(ctx)
+	if !ok {
+		slog.ErrorContext(ctx, "User ID not found in context, dropping references",
+			"op", op,
+			"references_count", len(refs))
+		return []models.Reference{}
+	}
+
+	verified := make([]models.Reference, 0, len(refs))
+	for _, ref := range refs {
+		if ref.OwnerID == userID || ref.Visibility.IsShared() {
+			verified = append(verified, ref)
+			continue
+		}
+
+		slog.ErrorContext(ctx, "User isolation violation: reference belongs to another user",
+			"op", op,
+			"user_id", userID,
+			"owner_id", ref.OwnerID,
+			"resource_id", ref.ResourceID)
+
+		if s.eventPublisher != nil {
+			violationEvent := map[string]interface{}{
+				"user_id":     userID,
+				"owner_id":    ref.OwnerID,
+				"resource_id": ref.ResourceID.String(),
+			}
+			if err := s.eventPublisher.PublishEvent(ctx, "search", "search.isolation_violation", violationEvent); err != nil {
+				slog.WarnContext(ctx, "Failed to publish isolation violation event", "error", err)
+			}
+		}
+	}
+
+	return verified
 }
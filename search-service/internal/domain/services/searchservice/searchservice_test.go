@@ -0,0 +1,711 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+)
+
+// MockVectorStorage is a mock implementation of vectorStorage interface
+type MockVectorStorage struct {
+	mock.Mock
+
+	// resources are returned by GetResourcesByIDs, which records its lookups
+	// rather than requiring an expectation from every search test
+	resources map[uuid.UUID]models.Resource
+	lookups   [][]uuid.UUID
+}
+
+func (m *MockVectorStorage) GetAnswer(ctx context.Context, question string, opts ...SearchOption) (string, []models.Reference, error) {
+	// Options are only passed to the expectation when given
+	callArgs := []interface{}{ctx, question}
+	if len(opts) > 0 {
+		callArgs = append(callArgs, opts)
+	}
+	args := m.Called(callArgs...)
+	return args.String(0), args.Get(1).([]models.Reference), args.Error(2)
+}
+
+func (m *MockVectorStorage) GetAnswerStream(ctx context.Context, question string, opts ...SearchOption) (<-chan string, <-chan []models.Reference, <-chan []byte, <-chan error) {
+	args := m.Called(ctx, question, opts)
+	return args.Get(0).(<-chan string), args.Get(1).(<-chan []models.Reference), args.Get(2).(<-chan []byte), args.Get(3).(<-chan error)
+}
+
+func (m *MockVectorStorage) SemanticSearch(ctx context.Context, query string, opts ...SearchOption) ([]models.Reference, error) {
+	args := m.Called(ctx, query, opts)
+	return args.Get(0).([]models.Reference), args.Error(1)
+}
+
+func (m *MockVectorStorage) GetResourceChunks(ctx context.Context, resourceID uuid.UUID, limit, offset int) ([]models.Chunk, int, error) {
+	args := m.Called(ctx, resourceID, limit, offset)
+	return args.Get(0).([]models.Chunk), args.Int(1), args.Error(2)
+}
+
+func (m *MockVectorStorage) GetIndexUsage(ctx context.Context) ([]models.ResourceUsage, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.ResourceUsage), args.Error(1)
+}
+
+func (m *MockVectorStorage) FindSimilarResources(ctx context.Context, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error) {
+	args := m.Called(ctx, resourceID, limit)
+	return args.Get(0).([]models.SimilarResource), args.Error(1)
+}
+
+func (m *MockVectorStorage) GetResourcesByIDs(ctx context.Context, resourceIDs []uuid.UUID) (map[uuid.UUID]models.Resource, error) {
+	m.lookups = append(m.lookups, resourceIDs)
+	found := make(map[uuid.UUID]models.Resource)
+	for _, id := range resourceIDs {
+		if resource, ok := m.resources[id]; ok {
+			found[id] = resource
+		}
+	}
+	return found, nil
+}
+
+func (m *MockVectorStorage) SummarizeReferences(ctx context.Context, refs []models.Reference) ([]models.Reference, error) {
+	args := m.Called(ctx, refs)
+	return args.Get(0).([]models.Reference), args.Error(1)
+}
+
+// MockEventPublisher is a mock implementation of eventPublisher interface
+type MockEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockEventPublisher) PublishEvent(ctx context.Context, topic string, eventName string, data interface{}) error {
+	args := m.Called(ctx, topic, eventName, data)
+	return args.Error(0)
+}
+
+// SearchServiceTestSuite is the test suite for search Service
+type SearchServiceTestSuite struct {
+	suite.Suite
+	mockVectorStorage  *MockVectorStorage
+	mockEventPublisher *MockEventPublisher
+	ctx                context.Context
+	userID             string
+}
+
+func (suite *SearchServiceTestSuite) SetupTest() {
+	suite.mockVectorStorage = new(MockVectorStorage)
+	suite.mockEventPublisher = new(MockEventPublisher)
+	suite.userID = uuid.NewString()
+	suite.ctx = context.WithValue(context.Background(), identity.UserIDKey, suite.userID)
+}
+
+func (suite *SearchServiceTestSuite) TearDownTest() {
+	suite.mockVectorStorage.AssertExpectations(suite.T())
+	suite.mockEventPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *SearchServiceTestSuite) newService(verify bool) *Service {
+	return NewService(suite.mockVectorStorage, &Config{VerifyUserIsolation: verify}, suite.mockEventPublisher)
+}
+
+// TestSemanticSearch_IsolationViolationFlagged tests that a cross-user chunk is reported and dropped
+func (suite *SearchServiceTestSuite) TestSemanticSearch_IsolationViolationFlagged() {
+	service := suite.newService(true)
+	ownRef := models.Reference{ResourceID: uuid.New(), Content: "own", OwnerID: suite.userID}
+	leakedRef := models.Reference{ResourceID: uuid.New(), Content: "leaked", OwnerID: uuid.NewString()}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "query", mock.Anything).
+		Return([]models.Reference{ownRef, leakedRef}, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.isolation_violation",
+		mock.MatchedBy(func(data map[string]interface{}) bool {
+			return data["resource_id"] == leakedRef.ResourceID.String() &&
+				data["owner_id"] == leakedRef.OwnerID &&
+				data["user_id"] == suite.userID
+		})).Return(nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.semantic_performed", mock.Anything).
+		Return(nil).Once()
+
+	refs, err := service.SemanticSearch(suite.ctx, "query")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.Reference{ownRef}, refs)
+}
+
+// TestSemanticSearch_SharedResourcesOfOtherUsers tests that another user's public resource surfaces while
+// a private one does not, nor a shared one until there are teams to share it with
+func (suite *SearchServiceTestSuite) TestSemanticSearch_SharedResourcesOfOtherUsers() {
+	service := suite.newService(true)
+	otherUserID := uuid.NewString()
+	publicRef := models.Reference{ResourceID: uuid.New(), Content: "public", OwnerID: otherUserID, Visibility: models.ResourceVisibilityPublic}
+	privateRef := models.Reference{ResourceID: uuid.New(), Content: "private", OwnerID: otherUserID, Visibility: models.ResourceVisibilityPrivate}
+	sharedRef := models.Reference{ResourceID: uuid.New(), Content: "shared", OwnerID: otherUserID, Visibility: models.ResourceVisibilityShared}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "query", mock.Anything).
+		Return([]models.Reference{publicRef, privateRef, sharedRef}, nil).Once()
+	for _, ref := range []models.Reference{privateRef, sharedRef} {
+		suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.isolation_violation",
+			mock.MatchedBy(func(data map[string]interface{}) bool {
+				return data["resource_id"] == ref.ResourceID.String()
+			})).Return(nil).Once()
+	}
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.semantic_performed", mock.Anything).
+		Return(nil).Once()
+
+	refs, err := service.SemanticSearch(suite.ctx, "query")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.Reference{publicRef}, refs)
+}
+
+// TestGetAnswer_IsolationViolationFlagged tests that verification also applies to answer references
+func (suite *SearchServiceTestSuite) TestGetAnswer_IsolationViolationFlagged() {
+	service := suite.newService(true)
+	leakedRef := models.Reference{ResourceID: uuid.New(), Content: "leaked", OwnerID: uuid.NewString()}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("answer", []models.Reference{leakedRef}, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.isolation_violation", mock.Anything).
+		Return(nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.performed", mock.Anything).
+		Return(nil).Once()
+
+	result, err := service.GetAnswer(suite.ctx, "question")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "answer", result.Answer)
+	assert.Empty(suite.T(), result.References)
+}
+
+// TestGetAnswer_Citations tests that answer markers map to the references in retrieval order
+func (suite *SearchServiceTestSuite) TestGetAnswer_Citations() {
+	service := suite.newService(true)
+	first := models.Reference{ResourceID: uuid.New(), Content: "first", OwnerID: suite.userID}
+	second := models.Reference{ResourceID: uuid.New(), Content: "second", OwnerID: suite.userID}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("Go has goroutines [2]. It compiles fast [1][2]. It was made on Mars [3].",
+			[]models.Reference{first, second}, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.performed", mock.Anything).
+		Return(nil).Once()
+
+	result, err := service.GetAnswer(suite.ctx, "question")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.Citation{
+		{Marker: 2, Reference: second},
+		{Marker: 1, Reference: first},
+	}, result.Citations)
+}
+
+// TestGetAnswer_CitationOfDroppedReference tests that citations of references failing verification are dropped
+func (suite *SearchServiceTestSuite) TestGetAnswer_CitationOfDroppedReference() {
+	service := suite.newService(true)
+	leakedRef := models.Reference{ResourceID: uuid.New(), Content: "leaked", OwnerID: uuid.NewString()}
+	ownRef := models.Reference{ResourceID: uuid.New(), Content: "own", OwnerID: suite.userID}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("answer [1] [2]", []models.Reference{leakedRef, ownRef}, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.isolation_violation", mock.Anything).
+		Return(nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.performed", mock.Anything).
+		Return(nil).Once()
+
+	result, err := service.GetAnswer(suite.ctx, "question")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.Citation{{Marker: 2, Reference: ownRef}}, result.Citations)
+}
+
+// TestGetAnswer_WithoutGeneration tests that retrieval-only answers are flagged as skipped
+func (suite *SearchServiceTestSuite) TestGetAnswer_WithoutGeneration() {
+	service := suite.newService(false)
+	ref := models.Reference{ResourceID: uuid.New(), Content: "snippet", OwnerID: suite.userID}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question", mock.MatchedBy(func(opts []SearchOption) bool {
+		options := &SearchOptions{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		return options.SkipGeneration
+	})).Return("", []models.Reference{ref}, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.performed",
+		mock.MatchedBy(func(data map[string]interface{}) bool {
+			return data["generation"] == false
+		})).Return(nil).Once()
+
+	result, err := service.GetAnswer(suite.ctx, "question", WithoutGeneration())
+
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.Answer)
+	assert.True(suite.T(), result.GenerationSkipped)
+	assert.Equal(suite.T(), []models.Reference{ref}, result.References)
+}
+
+// TestSemanticSearch_VerificationDisabled tests that references pass through untouched when disabled
+func (suite *SearchServiceTestSuite) TestSemanticSearch_VerificationDisabled() {
+	service := suite.newService(false)
+	refs := []models.Reference{
+		{ResourceID: uuid.New(), Content: "foreign", OwnerID: uuid.NewString()},
+	}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "query", mock.Anything).Return(refs, nil).Once()
+	suite.mockEventPublisher.On("PublishEvent", suite.ctx, "search", "search.semantic_performed", mock.Anything).
+		Return(nil).Once()
+
+	result, err := service.SemanticSearch(suite.ctx, "query")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), refs, result)
+}
+
+// TestVerifyUserIsolation_MissingUserID tests that references are withheld without a caller identity
+func (suite *SearchServiceTestSuite) TestVerifyUserIsolation_MissingUserID() {
+	service := suite.newService(true)
+	refs := []models.Reference{{ResourceID: uuid.New(), OwnerID: suite.userID}}
+
+	result := service.verifyUserIsolation(context.Background(), refs)
+
+	assert.Empty(suite.T(), result)
+}
+
+func (suite *SearchServiceTestSuite) newCachedService() *Service {
+	return NewService(suite.mockVectorStorage, &Config{CacheTTL: time.Minute})
+}
+
+// TestSemanticSearch_CacheInvalidatedPerResource tests that a resource change only evicts results it contributed to
+func (suite *SearchServiceTestSuite) TestSemanticSearch_CacheInvalidatedPerResource() {
+	service := suite.newCachedService()
+	changedRef := models.Reference{ResourceID: uuid.New(), Content: "changed"}
+	unrelatedRef := models.Reference{ResourceID: uuid.New(), Content: "unrelated"}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "first", mock.Anything).
+		Return([]models.Reference{changedRef}, nil).Twice()
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "second", mock.Anything).
+		Return([]models.Reference{unrelatedRef}, nil).Once()
+
+	_, err := service.SemanticSearch(suite.ctx, "first")
+	suite.Require().NoError(err)
+	_, err = service.SemanticSearch(suite.ctx, "second")
+	suite.Require().NoError(err)
+
+	service.InvalidateResource(suite.ctx, changedRef.ResourceID)
+
+	first, err := service.SemanticSearch(suite.ctx, "first")
+	suite.Require().NoError(err)
+	second, err := service.SemanticSearch(suite.ctx, "second")
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), []models.Reference{changedRef}, first)
+	assert.Equal(suite.T(), []models.Reference{unrelatedRef}, second)
+}
+
+// TestSemanticSearch_CacheEvictsLeastRecentlyUsed tests that a full cache evicts the result used the longest ago
+func (suite *SearchServiceTestSuite) TestSemanticSearch_CacheEvictsLeastRecentlyUsed() {
+	service := NewService(suite.mockVectorStorage, &Config{CacheTTL: time.Minute, CacheSize: 2})
+	for _, query := range []string{"first", "second", "third"} {
+		suite.mockVectorStorage.On("SemanticSearch", suite.ctx, query, mock.Anything).
+			Return([]models.Reference{{ResourceID: uuid.New(), Content: query}}, nil)
+	}
+
+	for _, query := range []string{"first", "second", "first", "third", "first", "third", "second"} {
+		_, err := service.SemanticSearch(suite.ctx, query)
+		suite.Require().NoError(err)
+	}
+
+	// "second" was evicted by "third", the cache holding two results at most
+	suite.mockVectorStorage.AssertNumberOfCalls(suite.T(), "SemanticSearch", 4)
+	assert.Equal(suite.T(), 2, service.cache.order.Len())
+}
+
+// TestSemanticSearch_Highlight tests that highlighting is applied on request without leaking into the cache
+func (suite *SearchServiceTestSuite) TestSemanticSearch_Highlight() {
+	service := suite.newCachedService()
+	ref := models.Reference{ResourceID: uuid.New(), Content: "Indexed chunks"}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "index", mock.Anything).
+		Return([]models.Reference{ref}, nil).Once()
+
+	highlighted, err := service.SemanticSearch(suite.ctx, "index", WithHighlight(true))
+	suite.Require().NoError(err)
+	plain, err := service.SemanticSearch(suite.ctx, "index")
+	suite.Require().NoError(err)
+	cachedHighlighted, err := service.SemanticSearch(suite.ctx, "index", WithHighlight(true))
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), "<mark>Indexed</mark> chunks", highlighted[0].Highlighted)
+	assert.Equal(suite.T(), "Indexed chunks", highlighted[0].Content)
+	assert.Equal(suite.T(), []models.Reference{ref}, plain)
+	assert.Equal(suite.T(), highlighted, cachedHighlighted)
+}
+
+// TestSemanticSearch_RecencyBoost tests that the boost reorders references only when requested
+func (suite *SearchServiceTestSuite) TestSemanticSearch_RecencyBoost() {
+	service := suite.newCachedService()
+	stale := models.Reference{ResourceID: uuid.New(), Content: "stale", Score: 0.9, CreatedAt: time.Now().AddDate(-1, 0, 0)}
+	fresh := models.Reference{ResourceID: uuid.New(), Content: "fresh", Score: 0.8, CreatedAt: time.Now()}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "notes", mock.Anything).
+		Return([]models.Reference{stale, fresh}, nil).Once()
+
+	plain, err := service.SemanticSearch(suite.ctx, "notes")
+	suite.Require().NoError(err)
+	boosted, err := service.SemanticSearch(suite.ctx, "notes", WithRecencyBoost(24*time.Hour))
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), []models.Reference{stale, fresh}, plain)
+	suite.Require().Len(boosted, 2)
+	assert.Equal(suite.T(), "fresh", boosted[0].Content)
+	assert.Equal(suite.T(), "stale", boosted[1].Content)
+	assert.Less(suite.T(), boosted[1].Score, stale.Score)
+}
+
+// TestGetAnswer_CacheInvalidatedPerUser tests that a new resource evicts every cached answer of its owner
+func (suite *SearchServiceTestSuite) TestGetAnswer_CacheInvalidatedPerUser() {
+	service := suite.newCachedService()
+	ref := models.Reference{ResourceID: uuid.New(), Content: "content"}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("answer", []models.Reference{ref}, nil).Twice()
+
+	_, err := service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+	_, err = service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+
+	service.InvalidateUser(suite.ctx, uuid.NewString())
+	_, err = service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+
+	service.InvalidateUser(suite.ctx, suite.userID)
+	result, err := service.GetAnswer(suite.ctx, "question")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "answer", result.Answer)
+}
+
+// TestGetAnswer_CacheNormalizesQuestion tests that rephrasings differing in case, spacing or final punctuation share a cached answer
+func (suite *SearchServiceTestSuite) TestGetAnswer_CacheNormalizesQuestion() {
+	service := suite.newCachedService()
+	ref := models.Reference{ResourceID: uuid.New(), Content: "content"}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "What is Go?").
+		Return("answer", []models.Reference{ref}, nil).Once()
+
+	hits := testutil.ToFloat64(metrics.AnswerCacheHits.WithLabelValues(operationAnswer))
+	misses := testutil.ToFloat64(metrics.AnswerCacheMisses.WithLabelValues(operationAnswer))
+
+	_, err := service.GetAnswer(suite.ctx, "What is Go?")
+	suite.Require().NoError(err)
+	result, err := service.GetAnswer(suite.ctx, "  what is\tgo ")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "answer", result.Answer)
+	assert.Equal(suite.T(), hits+1, testutil.ToFloat64(metrics.AnswerCacheHits.WithLabelValues(operationAnswer)))
+	assert.Equal(suite.T(), misses+1, testutil.ToFloat64(metrics.AnswerCacheMisses.WithLabelValues(operationAnswer)))
+}
+
+// TestGetAnswer_NoCacheRefreshesAnswer tests that bypassing the cache generates a fresh answer which is cached in turn
+func (suite *SearchServiceTestSuite) TestGetAnswer_NoCacheRefreshesAnswer() {
+	service := suite.newCachedService()
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("stale", []models.Reference{}, nil).Once()
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question", mock.Anything).
+		Return("fresh", []models.Reference{}, nil).Twice()
+
+	_, err := service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+	bypassed, err := service.GetAnswer(suite.ctx, "question", WithoutCache())
+	suite.Require().NoError(err)
+	cached, err := service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+	otherLanguage, err := service.GetAnswer(suite.ctx, "question", WithLanguage("de"))
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), "fresh", bypassed.Answer)
+	assert.Equal(suite.T(), "fresh", cached.Answer)
+	assert.Equal(suite.T(), "fresh", otherLanguage.Answer)
+}
+
+// TestGetAnswerStream_CachedAnswerSingleChunk tests that a cached answer is streamed as one chunk without generating it
+func (suite *SearchServiceTestSuite) TestGetAnswerStream_CachedAnswerSingleChunk() {
+	service := suite.newCachedService()
+	ref := models.Reference{ResourceID: uuid.New(), Content: "content"}
+
+	suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+		Return("cached answer", []models.Reference{ref}, nil).Once()
+
+	_, err := service.GetAnswer(suite.ctx, "question")
+	suite.Require().NoError(err)
+
+	resultCh, refsCh, chunkCh, errCh := service.GetAnswerStream(suite.ctx, "Question?")
+
+	assert.Equal(suite.T(), []models.Reference{ref}, <-refsCh)
+	assert.Equal(suite.T(), []byte("cached answer"), <-chunkCh)
+	_, open := <-chunkCh
+	assert.False(suite.T(), open)
+	assert.Equal(suite.T(), "cached answer", (<-resultCh).Answer)
+	assert.NoError(suite.T(), <-errCh)
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "GetAnswerStream", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestSemanticSearch_CacheDisabled tests that every call reaches the vector storage without a TTL
+func (suite *SearchServiceTestSuite) TestSemanticSearch_CacheDisabled() {
+	service := NewService(suite.mockVectorStorage, &Config{})
+	refs := []models.Reference{{ResourceID: uuid.New()}}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "query", mock.Anything).Return(refs, nil).Twice()
+
+	_, err := service.SemanticSearch(suite.ctx, "query")
+	suite.Require().NoError(err)
+	_, err = service.SemanticSearch(suite.ctx, "query")
+
+	assert.NoError(suite.T(), err)
+}
+
+// TestSemanticSearch_AttachesResources tests that references get the name and URL of their resource from a single lookup
+func (suite *SearchServiceTestSuite) TestSemanticSearch_AttachesResources() {
+	service := NewService(suite.mockVectorStorage, &Config{})
+	article, notes, deleted := uuid.New(), uuid.New(), uuid.New()
+	suite.mockVectorStorage.resources = map[uuid.UUID]models.Resource{
+		article: {ID: article, Name: "Article", URL: "https://example.com/article"},
+		notes:   {ID: notes, Name: "Notes"},
+	}
+	refs := []models.Reference{
+		{ResourceID: article, Content: "intro"},
+		{ResourceID: notes, Content: "notes"},
+		{ResourceID: article, Content: "details"},
+		{ResourceID: deleted, Content: "orphan"},
+	}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "query", mock.Anything).Return(refs, nil).Once()
+
+	result, err := service.SemanticSearch(suite.ctx, "query")
+
+	suite.Require().NoError(err)
+	suite.Require().Len(result, 4)
+	assert.Equal(suite.T(), "Article", result[0].ResourceName)
+	assert.Equal(suite.T(), "https://example.com/article", result[0].ResourceURL)
+	assert.Equal(suite.T(), "Notes", result[1].ResourceName)
+	assert.Empty(suite.T(), result[1].ResourceURL)
+	assert.Equal(suite.T(), "Article", result[2].ResourceName)
+	assert.Empty(suite.T(), result[3].ResourceName, "a deleted resource leaves the fields empty")
+	assert.Empty(suite.T(), result[3].ResourceURL)
+	assert.Equal(suite.T(), [][]uuid.UUID{{article, notes, deleted}}, suite.mockVectorStorage.lookups)
+}
+
+// TestSemanticSearch_Summaries tests that summaries are only requested with the option and don't fail the search
+func (suite *SearchServiceTestSuite) TestSemanticSearch_Summaries() {
+	service := NewService(suite.mockVectorStorage, &Config{})
+	refs := []models.Reference{{ResourceID: uuid.New(), Content: "Channels connect goroutines."}}
+	summarized := []models.Reference{{ResourceID: refs[0].ResourceID, Content: refs[0].Content, Summary: "About channels."}}
+
+	suite.mockVectorStorage.On("SemanticSearch", suite.ctx, "channels", mock.Anything).Return(refs, nil)
+	suite.mockVectorStorage.On("SummarizeReferences", suite.ctx, refs).Return(summarized, nil).Once()
+
+	result, err := service.SemanticSearch(suite.ctx, "channels")
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), result[0].Summary)
+	suite.mockVectorStorage.AssertNotCalled(suite.T(), "SummarizeReferences", mock.Anything, mock.Anything)
+
+	result, err = service.SemanticSearch(suite.ctx, "channels", WithSummaries(true))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "About channels.", result[0].Summary)
+
+	suite.mockVectorStorage.On("SummarizeReferences", suite.ctx, refs).Return([]models.Reference(nil), context.Canceled).Once()
+	result, err = service.SemanticSearch(suite.ctx, "channels", WithSummaries(true))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), refs, result, "references are returned without summaries when summarizing fails")
+}
+
+// TestGetResourceChunks_Paginated tests that stored chunks are returned with pagination defaults applied
+func (suite *SearchServiceTestSuite) TestGetResourceChunks_Paginated() {
+	service := suite.newService(false)
+	resourceID := uuid.New()
+	chunks := []models.Chunk{
+		{ID: uuid.New(), ResourceID: resourceID, Index: 0, Content: "first", StartOffset: 0, EndOffset: 5},
+		{ID: uuid.New(), ResourceID: resourceID, Index: 1, Content: "second", StartOffset: 6, EndOffset: 12},
+	}
+
+	suite.mockVectorStorage.On("GetResourceChunks", suite.ctx, resourceID, defaultChunksLimit, 0).
+		Return(chunks, 2, nil).Once()
+
+	page, err := service.GetResourceChunks(suite.ctx, resourceID, 0, -5)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ChunkPage{Chunks: chunks, Total: 2, Limit: defaultChunksLimit, Offset: 0}, page)
+}
+
+// TestGetResourceChunks_NotFound tests that a resource without the caller's chunks is reported as not found
+func (suite *SearchServiceTestSuite) TestGetResourceChunks_NotFound() {
+	service := suite.newService(false)
+	resourceID := uuid.New()
+
+	suite.mockVectorStorage.On("GetResourceChunks", suite.ctx, resourceID, maxChunksLimit, 0).
+		Return([]models.Chunk{}, 0, nil).Once()
+
+	_, err := service.GetResourceChunks(suite.ctx, resourceID, 1000, 0)
+
+	assert.ErrorIs(suite.T(), err, models.ErrResourceNotFound)
+}
+
+// TestFindSimilarResources_BoundsLimit tests that the number of similar resources defaults and is capped
+func (suite *SearchServiceTestSuite) TestFindSimilarResources_BoundsLimit() {
+	service := suite.newService(false)
+	resourceID := uuid.New()
+	similar := []models.SimilarResource{{ResourceID: uuid.New(), Score: 0.9, Content: "closest chunk"}}
+
+	suite.mockVectorStorage.On("FindSimilarResources", suite.ctx, resourceID, defaultSimilarLimit).
+		Return(similar, nil).Once()
+	suite.mockVectorStorage.On("FindSimilarResources", suite.ctx, resourceID, maxSimilarLimit).
+		Return(similar, nil).Once()
+
+	found, err := service.FindSimilarResources(suite.ctx, resourceID, 0)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), similar, found)
+
+	_, err = service.FindSimilarResources(suite.ctx, resourceID, 1000)
+	assert.NoError(suite.T(), err)
+}
+
+// TestFindSimilarResources_NotFound tests that a resource without the caller's chunks is reported as not found
+func (suite *SearchServiceTestSuite) TestFindSimilarResources_NotFound() {
+	service := suite.newService(false)
+	resourceID := uuid.New()
+
+	suite.mockVectorStorage.On("FindSimilarResources", suite.ctx, resourceID, defaultSimilarLimit).
+		Return([]models.SimilarResource(nil), models.ErrResourceNotFound).Once()
+
+	_, err := service.FindSimilarResources(suite.ctx, resourceID, 0)
+
+	assert.ErrorIs(suite.T(), err, models.ErrResourceNotFound)
+}
+
+// TestGetIndexStats_SumsUsage tests that the usage of every resource is summed up and cached until one of them is deleted
+func (suite *SearchServiceTestSuite) TestGetIndexStats_SumsUsage() {
+	service := NewService(suite.mockVectorStorage, &Config{StatsCacheTTL: time.Minute})
+	usage := []models.ResourceUsage{
+		{ResourceID: uuid.New(), Chunks: 40, StorageBytes: 250_000},
+		{ResourceID: uuid.New(), Chunks: 12, StorageBytes: 80_000},
+		{ResourceID: uuid.New(), Chunks: 0, StorageBytes: 6_000},
+	}
+
+	suite.mockVectorStorage.On("GetIndexUsage", suite.ctx).Return(usage, nil).Twice()
+
+	stats, err := service.GetIndexStats(suite.ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.IndexStats{
+		Resources:    3,
+		Chunks:       52,
+		StorageBytes: 336_000,
+		ByResource:   usage,
+	}, stats)
+
+	cached, err := service.GetIndexStats(suite.ctx)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), stats, cached)
+
+	service.InvalidateResource(suite.ctx, usage[2].ResourceID)
+	_, err = service.GetIndexStats(suite.ctx)
+	assert.NoError(suite.T(), err)
+}
+
+// TestGetIndexStats_NothingIndexed tests that a user without chunks gets zero stats
+func (suite *SearchServiceTestSuite) TestGetIndexStats_NothingIndexed() {
+	service := suite.newService(false)
+
+	suite.mockVectorStorage.On("GetIndexUsage", suite.ctx).Return([]models.ResourceUsage(nil), nil).Once()
+
+	stats, err := service.GetIndexStats(suite.ctx)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.IndexStats{ByResource: []models.ResourceUsage{}}, stats)
+}
+
+// TestSemanticSearch_CancelledByClient tests that a cancelled search is classified and counted as a cancellation
+func (suite *SearchServiceTestSuite) TestSemanticSearch_CancelledByClient() {
+	service := suite.newService(false)
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	cancelled := testutil.ToFloat64(metrics.SearchCancellations.WithLabelValues(operationSemantic))
+	failed := testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationSemantic))
+
+	_, err := service.SemanticSearch(ctx, "query")
+
+	assert.ErrorIs(suite.T(), err, models.ErrSearchCancelled)
+	assert.ErrorIs(suite.T(), err, context.Canceled)
+	assert.Equal(suite.T(), cancelled+1, testutil.ToFloat64(metrics.SearchCancellations.WithLabelValues(operationSemantic)))
+	assert.Equal(suite.T(), failed, testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationSemantic)))
+}
+
+// TestGetAnswer_WrappedCancellation tests that cancellations wrapped by the LLM client are still recognized
+func (suite *SearchServiceTestSuite) TestGetAnswer_WrappedCancellation() {
+	service := suite.newService(false)
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	suite.mockVectorStorage.On("GetAnswer", ctx, "question").
+		Return("", []models.Reference(nil), errors.New("ollama: request aborted")).Once()
+
+	failed := testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationAnswer))
+
+	_, err := service.GetAnswer(ctx, "question")
+
+	assert.ErrorIs(suite.T(), err, models.ErrSearchCancelled)
+	assert.Equal(suite.T(), failed, testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationAnswer)))
+}
+
+// TestGetAnswer_FailureIsNotCancellation tests that genuine failures and timeouts are counted as errors
+func (suite *SearchServiceTestSuite) TestGetAnswer_FailureIsNotCancellation() {
+	for _, storageErr := range []error{errors.New("connection refused"), context.DeadlineExceeded} {
+		service := suite.newService(false)
+		suite.mockVectorStorage.On("GetAnswer", suite.ctx, "question").
+			Return("", []models.Reference(nil), storageErr).Once()
+
+		cancelled := testutil.ToFloat64(metrics.SearchCancellations.WithLabelValues(operationAnswer))
+		failed := testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationAnswer))
+
+		_, err := service.GetAnswer(suite.ctx, "question")
+
+		assert.ErrorIs(suite.T(), err, storageErr)
+		assert.NotErrorIs(suite.T(), err, models.ErrSearchCancelled)
+		assert.Equal(suite.T(), cancelled, testutil.ToFloat64(metrics.SearchCancellations.WithLabelValues(operationAnswer)))
+		assert.Equal(suite.T(), failed+1, testutil.ToFloat64(metrics.SearchErrors.WithLabelValues(operationAnswer)))
+	}
+}
+
+// TestGetAnswerStream_CancelledByClient tests that cancelling a stream reports a cancellation on the error channel
+func (suite *SearchServiceTestSuite) TestGetAnswerStream_CancelledByClient() {
+	service := suite.newService(false)
+	ctx, cancel := context.WithCancel(suite.ctx)
+
+	var (
+		answerCh <-chan string             = make(chan string)
+		refsCh   <-chan []models.Reference = make(chan []models.Reference)
+		chunkCh  <-chan []byte             = make(chan []byte)
+		errCh    <-chan error              = make(chan error)
+	)
+	suite.mockVectorStorage.On("GetAnswerStream", ctx, "question", mock.Anything).
+		Return(answerCh, refsCh, chunkCh, errCh).Once()
+
+	_, _, _, outErrCh := service.GetAnswerStream(ctx, "question")
+	cancel()
+
+	select {
+	case err := <-outErrCh:
+		assert.ErrorIs(suite.T(), err, models.ErrSearchCancelled)
+	case <-time.After(time.Second):
+		suite.T().Fatal("stream was not cancelled")
+	}
+}
+
+// TestSearchServiceTestSuite runs the test suite
+func TestSearchServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchServiceTestSuite))
+}
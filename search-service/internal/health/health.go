@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// Check reports whether a dependency is usable, returning nil when it is
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health serves the liveness and readiness endpoints of the service
+type Health struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// CheckResult is the outcome of a single dependency check
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the readiness response body
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// New creates a Health whose readiness checks are all bounded by timeout
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// AddCheck registers a dependency checked by the readiness endpoint
+func (h *Health) AddCheck(name string, check Check) *Health {
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+	return h
+}
+
+// RegisterRoutes mounts GET /healthz and GET /readyz on the router
+func (h *Health) RegisterRoutes(router gin.IRouter) {
+	router.GET("/healthz", h.Liveness())
+	router.GET("/readyz", h.Readiness())
+}
+
+// Liveness reports that the process is up and serving requests. It does not
+// look at dependencies, so an outage elsewhere doesn't get the service restarted.
+func (h *Health) Liveness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"status": statusOK})
+	}
+}
+
+// Readiness runs every check concurrently and responds 200 only when all of
+// them pass, or 503 with the failing checks otherwise.
+func (h *Health) Readiness() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := h.Check(ctx.Request.Context())
+
+		status := http.StatusOK
+		if report.Status != statusOK {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, report)
+	}
+}
+
+// Check runs the registered checks and aggregates their results
+func (h *Health) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	results := make([]CheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: statusOK, Checks: make(map[string]CheckResult, len(h.checks))}
+	for i, c := range h.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != statusOK {
+			report.Status = statusUnavailable
+		}
+	}
+	return report
+}
+
+// runCheck runs check, giving up when ctx expires even if the check itself
+// ignores the context, so a hanging dependency can't stall the probe.
+func runCheck(ctx context.Context, check Check) CheckResult {
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: statusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = statusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// HTTPCheck checks that url answers a GET request with a 2xx status
+func HTTPCheck(client *http.Client, url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return errors.New("unexpected status " + resp.Status)
+		}
+		return nil
+	}
+}
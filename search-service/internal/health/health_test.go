@@ -0,0 +1,99 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, h *Health, path string) (*httptest.ResponseRecorder, Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	h.RegisterRoutes(engine)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	return recorder, report
+}
+
+func passing(context.Context) error { return nil }
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	h := New(time.Second).AddCheck("postgres", passing).AddCheck("kafka_producer", passing)
+
+	recorder, report := serve(t, h, "/readyz")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, statusOK, report.Status)
+	assert.Equal(t, statusOK, report.Checks["postgres"].Status)
+	assert.Equal(t, statusOK, report.Checks["kafka_producer"].Status)
+}
+
+func TestReadiness_FailingCheck(t *testing.T) {
+	h := New(time.Second).
+		AddCheck("postgres", passing).
+		AddCheck("kafka_producer", func(context.Context) error { return errors.New("no kafka brokers available") })
+
+	recorder, report := serve(t, h, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, statusUnavailable, report.Status)
+	assert.Equal(t, statusOK, report.Checks["postgres"].Status)
+	assert.Equal(t, CheckResult{
+		Status:   statusUnavailable,
+		Error:    "no kafka brokers available",
+		Duration: report.Checks["kafka_producer"].Duration,
+	}, report.Checks["kafka_producer"])
+}
+
+func TestReadiness_HangingCheckTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	// The check ignores its context, the probe must still answer in time
+	h := New(50*time.Millisecond).AddCheck("ollama_generator", func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	start := time.Now()
+	recorder, report := serve(t, h, "/readyz")
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["ollama_generator"].Error)
+}
+
+func TestLiveness_IgnoresDependencies(t *testing.T) {
+	h := New(time.Second).AddCheck("postgres", func(context.Context) error { return errors.New("down") })
+
+	recorder, report := serve(t, h, "/healthz")
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, statusOK, report.Status)
+}
+
+func TestHTTPCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	assert.NoError(t, HTTPCheck(server.Client(), server.URL+"/")(context.Background()))
+	assert.EqualError(t, HTTPCheck(server.Client(), server.URL+"/down")(context.Background()), "unexpected status 502 Bad Gateway")
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Collectors are registered once at package initialization so that the
+// processors only increment them on their hot paths.
+var (
+	// OutboxEventsProcessed counts outbox events published to the broker
+	OutboxEventsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_events_processed_total",
+		Help: "Total number of outbox events published successfully.",
+	})
+
+	// OutboxEventsFailed counts outbox events that failed after all retries
+	OutboxEventsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_events_failed_total",
+		Help: "Total number of outbox events that could not be published after all retries.",
+	})
+
+	// OutboxRetries counts repeated publish attempts of outbox events
+	OutboxRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "outbox_retry_total",
+		Help: "Total number of outbox publish retries.",
+	})
+
+	// OutboxUnsentBacklog reports the events still unsent after the last outbox run
+	OutboxUnsentBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "outbox_unsent_backlog",
+		Help: "Number of outbox events still unsent after the last outbox run.",
+	})
+
+	// SearchErrors counts failed searches by operation
+	SearchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "search_errors_total",
+		Help: "Total number of searches that failed, excluding client cancellations.",
+	}, []string{"operation"})
+
+	// SearchCancellations counts searches abandoned by the client by operation
+	SearchCancellations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "search_cancelled_total",
+		Help: "Total number of searches cancelled by the client before completion.",
+	}, []string{"operation"})
+
+	// AnswerCacheHits counts answers served from the answer cache by operation
+	AnswerCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "answer_cache_hits_total",
+		Help: "Total number of answers served from the cache, by operation (answer, answer_stream).",
+	}, []string{"operation"})
+
+	// AnswerCacheMisses counts cacheable answers that had to be generated by operation
+	AnswerCacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "answer_cache_misses_total",
+		Help: "Total number of cacheable answers missing from the cache, by operation (answer, answer_stream).",
+	}, []string{"operation"})
+
+	// EmbeddingCacheHits counts texts whose embedding was served from a cache tier
+	EmbeddingCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "embedding_cache_hits_total",
+		Help: "Total number of embeddings served from the cache, by tier (memory, postgres).",
+	}, []string{"tier"})
+
+	// EmbeddingCacheMisses counts texts that had to be embedded by the model
+	EmbeddingCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "embedding_cache_misses_total",
+		Help: "Total number of embeddings missing from every cache tier.",
+	})
+
+	// KafkaConsumerLag reports the messages of a partition the consumer group has yet to handle
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_lag",
+		Help: "Messages between the committed offset of the consumer group and the high-water mark, by topic and partition.",
+	}, []string{"topic", "partition"})
+
+	// KafkaConsumerBacklog reports the messages of a topic the consumer group has yet to handle
+	KafkaConsumerBacklog = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kafka_consumer_backlog",
+		Help: "Sum of the consumer lag over the partitions of a topic.",
+	}, []string{"topic"})
+)
+
+// Handler exposes the registered metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
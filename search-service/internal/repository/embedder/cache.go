@@ -0,0 +1,57 @@
+package embedder
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache keeps the most recently used embeddings up to a fixed number of entries
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used
+	items    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key    string
+	vector []float32
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+func (c *lruCache) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry).vector, true
+}
+
+func (c *lruCache) put(key string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*lruEntry).vector = vector
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, vector: vector})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,42 @@
+package embedder
+
+import (
+	"fmt"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// DefaultBatchSize is the number of texts embedded per model call unless
+// configured, as many as vector storage stores at a time
+const DefaultBatchSize = 16
+
+// Config holds the settings of embedding requests
+type Config struct {
+	// BatchSize is the number of texts embedded per model call; zero embeds all texts of a call at once
+	BatchSize int `yaml:"batch_size" mapstructure:"batch_size" validate:"min=0"`
+}
+
+// NewConfig loads embedding configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("embedding", Config{
+		BatchSize: DefaultBatchSize,
+	})
+}
+
+// CacheConfig holds embedding cache configuration
+type CacheConfig struct {
+	// Size is the number of embeddings kept in memory; zero disables caching
+	Size int `yaml:"size" mapstructure:"size" validate:"min=0"`
+	// Persist additionally stores embeddings in Postgres so they survive restarts
+	Persist bool `yaml:"persist" mapstructure:"persist"`
+}
+
+// NewCacheConfig loads embedding cache configuration from config file
+func NewCacheConfig() (*CacheConfig, error) {
+	config, err := configurator.ParseConfig[CacheConfig]("embedding_cache")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedding cache config: %w", err)
+	}
+
+	return config, nil
+}
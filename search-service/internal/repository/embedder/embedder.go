@@ -2,42 +2,239 @@ package embedder
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"log/slog"
 
-	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
 )
 
-type Embedder struct {
-	llm *ollama.LLM
+// embeddingModel creates embeddings, typically an Ollama LLM
+type embeddingModel interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// persistentCache stores embeddings by content hash beyond the process lifetime
+type persistentCache interface {
+	GetEmbeddings(ctx context.Context, hashes []string) (map[string][]float32, error)
+	PutEmbeddings(ctx context.Context, embeddings map[string][]float32) error
 }
 
-func NewEmbedder(llm *ollama.LLM) (*Embedder, error) {
+// Option configures an Embedder
+type Option func(*Embedder)
+
+// WithCache keeps up to size embeddings of model in memory. Entries are keyed
+// by the SHA-256 of the model name and text, so switching models never serves
+// vectors of the previous one.
+func WithCache(model string, size int) Option {
+	return func(e *Embedder) {
+		if size > 0 {
+			e.model = model
+			e.cache = newLRUCache(size)
+		}
+	}
+}
+
+// WithPersistentCache looks up embeddings missing from memory in store before
+// calling the model. It only takes effect together with WithCache.
+func WithPersistentCache(store persistentCache) Option {
+	return func(e *Embedder) {
+		e.store = store
+	}
+}
 
-	return &Embedder{
+// WithBatchSize makes the embedder request the embeddings of at most size texts
+// per model call. Zero or less embeds all texts of a call at once.
+func WithBatchSize(size int) Option {
+	return func(e *Embedder) {
+		e.batchSize = size
+	}
+}
+
+// WithRetry retries model calls that fail while the model server is
+// unavailable according to policy
+func WithRetry(policy *llmretry.Policy) Option {
+	return func(e *Embedder) {
+		e.retry = policy
+	}
+}
+
+type Embedder struct {
+	llm   embeddingModel
+	model string
+	cache *lruCache       // Nil when caching is disabled
+	store persistentCache // Optional second cache tier
+	retry *llmretry.Policy
+	// batchSize bounds the texts per model call, zero leaves them unbounded
+	batchSize int
+}
+
+func NewEmbedder(llm embeddingModel, opts ...Option) (*Embedder, error) {
+	e := &Embedder{
 		llm: llm,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
 }
 
 func (e *Embedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
 	const op = "Embedder.EmbedDocuments"
 
-	embeddedTexts, err := e.llm.CreateEmbedding(ctx, texts)
-	if err != nil {
-		slog.Error("failed to create embedding", op, slog.String("error", err.Error()))
-		return nil, err
+	if e.cache == nil {
+		return e.createEmbeddings(ctx, op, texts)
 	}
 
-	return embeddedTexts, nil
+	return e.embedCached(ctx, op, texts)
 }
 
 func (e *Embedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
 	const op = "Embedder.EmbedQuery"
 
-	embeddedQuery, err := e.llm.CreateEmbedding(ctx, []string{query})
+	var embeddedQuery [][]float32
+	var err error
+	if e.cache == nil {
+		embeddedQuery, err = e.createEmbeddings(ctx, op, []string{query})
+	} else {
+		embeddedQuery, err = e.embedCached(ctx, op, []string{query})
+	}
 	if err != nil {
-		slog.Error("failed to create embedding", op, slog.String("error", err.Error()))
 		return nil, err
 	}
 
 	return embeddedQuery[0], nil
 }
+
+// createEmbeddings embeds texts with the model in batches of the configured
+// size, keeping their order. It stops between batches once ctx is done, and a
+// failing batch is retried on its own.
+func (e *Embedder) createEmbeddings(ctx context.Context, op string, texts []string) ([][]float32, error) {
+	batchSize := e.batchSize
+	if batchSize <= 0 {
+		batchSize = max(len(texts), 1)
+	}
+
+	embeddedTexts := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		batch := texts[start:min(start+batchSize, len(texts))]
+		embedded, err := llmretry.Do(ctx, e.retry, op, func(ctx context.Context) ([][]float32, error) {
+			return e.llm.CreateEmbedding(ctx, batch)
+		})
+		if err != nil {
+			slog.Error("failed to create embedding", op, slog.String("error", err.Error()))
+			return nil, err
+		}
+		if len(embedded) != len(batch) {
+			return nil, fmt.Errorf("%s: model returned %d embeddings for %d texts", op, len(embedded), len(batch))
+		}
+
+		embeddedTexts = append(embeddedTexts, embedded...)
+	}
+
+	return embeddedTexts, nil
+}
+
+// embedCached resolves texts from memory, then from the persistent cache, and
+// embeds only the remaining distinct texts with the model.
+func (e *Embedder) embedCached(ctx context.Context, op string, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	missing := make(map[string][]int) // key -> positions of texts still without a vector
+
+	for i, text := range texts {
+		keys[i] = e.cacheKey(text)
+		if vector, ok := e.cache.get(keys[i]); ok {
+			vectors[i] = vector
+			continue
+		}
+		missing[keys[i]] = append(missing[keys[i]], i)
+	}
+	metrics.EmbeddingCacheHits.WithLabelValues("memory").Add(float64(len(texts) - countPositions(missing)))
+
+	if len(missing) > 0 && e.store != nil {
+		e.resolveStored(ctx, op, missing, vectors)
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	metrics.EmbeddingCacheMisses.Add(float64(countPositions(missing)))
+
+	missingKeys := make([]string, 0, len(missing))
+	missingTexts := make([]string, 0, len(missing))
+	for key, positions := range missing {
+		missingKeys = append(missingKeys, key)
+		missingTexts = append(missingTexts, texts[positions[0]])
+	}
+
+	created, err := e.createEmbeddings(ctx, op, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string][]float32, len(created))
+	for i, vector := range created {
+		key := missingKeys[i]
+		e.cache.put(key, vector)
+		stored[key] = vector
+		for _, position := range missing[key] {
+			vectors[position] = vector
+		}
+	}
+
+	if e.store != nil {
+		if err := e.store.PutEmbeddings(ctx, stored); err != nil {
+			slog.WarnContext(ctx, "Failed to persist embeddings", "op", op, "error", err)
+		}
+	}
+
+	return vectors, nil
+}
+
+// resolveStored fills vectors found in the persistent cache and removes them from
+// missing. Store failures only cost a model call, so they are logged and ignored.
+func (e *Embedder) resolveStored(ctx context.Context, op string, missing map[string][]int, vectors [][]float32) {
+	keys := make([]string, 0, len(missing))
+	for key := range missing {
+		keys = append(keys, key)
+	}
+
+	stored, err := e.store.GetEmbeddings(ctx, keys)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read persisted embeddings", "op", op, "error", err)
+		return
+	}
+
+	for key, vector := range stored {
+		positions, ok := missing[key]
+		if !ok {
+			continue
+		}
+		e.cache.put(key, vector)
+		for _, position := range positions {
+			vectors[position] = vector
+		}
+		metrics.EmbeddingCacheHits.WithLabelValues("postgres").Add(float64(len(positions)))
+		delete(missing, key)
+	}
+}
+
+func (e *Embedder) cacheKey(text string) string {
+	sum := sha256.Sum256([]byte(e.model + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func countPositions(missing map[string][]int) int {
+	count := 0
+	for _, positions := range missing {
+		count += len(positions)
+	}
+	return count
+}
@@ -0,0 +1,247 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
+)
+
+// countingModel embeds a text to a vector of its length and records every request
+type countingModel struct {
+	calls [][]string
+}
+
+func (m *countingModel) CreateEmbedding(_ context.Context, texts []string) ([][]float32, error) {
+	m.calls = append(m.calls, texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+// failingModel fails its first requests with errs before embedding like countingModel
+type failingModel struct {
+	countingModel
+	errs     []error
+	attempts int
+}
+
+func (m *failingModel) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	m.attempts++
+	if m.attempts <= len(m.errs) {
+		return nil, m.errs[m.attempts-1]
+	}
+	return m.countingModel.CreateEmbedding(ctx, texts)
+}
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+func retryPolicy() *llmretry.Policy {
+	return llmretry.NewPolicy(llmretry.Config{Attempts: 3, BaseDelay: time.Millisecond, Backoff: 2})
+}
+
+type memoryStore map[string][]float32
+
+func (s memoryStore) GetEmbeddings(_ context.Context, hashes []string) (map[string][]float32, error) {
+	found := make(map[string][]float32)
+	for _, hash := range hashes {
+		if vector, ok := s[hash]; ok {
+			found[hash] = vector
+		}
+	}
+	return found, nil
+}
+
+func (s memoryStore) PutEmbeddings(_ context.Context, embeddings map[string][]float32) error {
+	for hash, vector := range embeddings {
+		s[hash] = vector
+	}
+	return nil
+}
+
+func TestEmbedDocuments_RepeatedChunkServedFromCache(t *testing.T) {
+	model := &countingModel{}
+	e, err := NewEmbedder(model, WithCache("bge-m3", 10))
+	require.NoError(t, err)
+
+	hits := testutil.ToFloat64(metrics.EmbeddingCacheHits.WithLabelValues("memory"))
+
+	first, err := e.EmbedDocuments(context.Background(), []string{"same chunk"})
+	require.NoError(t, err)
+	second, err := e.EmbedDocuments(context.Background(), []string{"same chunk"})
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Len(t, model.calls, 1)
+	assert.Equal(t, hits+1, testutil.ToFloat64(metrics.EmbeddingCacheHits.WithLabelValues("memory")))
+}
+
+func TestEmbedDocuments_DuplicatesInBatchEmbeddedOnce(t *testing.T) {
+	model := &countingModel{}
+	e, err := NewEmbedder(model, WithCache("bge-m3", 10))
+	require.NoError(t, err)
+
+	vectors, err := e.EmbedDocuments(context.Background(), []string{"a", "bb", "a"})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]float32{{1}, {2}, {1}}, vectors)
+	require.Len(t, model.calls, 1)
+	assert.ElementsMatch(t, []string{"a", "bb"}, model.calls[0])
+}
+
+func TestEmbedDocuments_PersistentCache(t *testing.T) {
+	store := memoryStore{}
+
+	// A previous process embedded the chunk and persisted it
+	previous, err := NewEmbedder(&countingModel{}, WithCache("bge-m3", 10), WithPersistentCache(store))
+	require.NoError(t, err)
+	_, err = previous.EmbedDocuments(context.Background(), []string{"persisted chunk"})
+	require.NoError(t, err)
+	require.Len(t, store, 1)
+
+	model := &countingModel{}
+	e, err := NewEmbedder(model, WithCache("bge-m3", 10), WithPersistentCache(store))
+	require.NoError(t, err)
+
+	vectors, err := e.EmbedDocuments(context.Background(), []string{"persisted chunk", "new"})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]float32{{15}, {3}}, vectors)
+	assert.Equal(t, [][]string{{"new"}}, model.calls)
+	assert.Len(t, store, 2)
+}
+
+func TestEmbedDocuments_KeyedByModel(t *testing.T) {
+	store := memoryStore{}
+	first, err := NewEmbedder(&countingModel{}, WithCache("bge-m3", 10), WithPersistentCache(store))
+	require.NoError(t, err)
+	_, err = first.EmbedDocuments(context.Background(), []string{"chunk"})
+	require.NoError(t, err)
+
+	model := &countingModel{}
+	e, err := NewEmbedder(model, WithCache("nomic-embed-text", 10), WithPersistentCache(store))
+	require.NoError(t, err)
+	_, err = e.EmbedDocuments(context.Background(), []string{"chunk"})
+	require.NoError(t, err)
+
+	assert.Len(t, model.calls, 1)
+}
+
+func TestEmbedDocuments_CacheDisabled(t *testing.T) {
+	model := &countingModel{}
+	e, err := NewEmbedder(model, WithCache("bge-m3", 0))
+	require.NoError(t, err)
+
+	_, err = e.EmbedDocuments(context.Background(), []string{"chunk"})
+	require.NoError(t, err)
+	_, err = e.EmbedQuery(context.Background(), "chunk")
+	require.NoError(t, err)
+
+	assert.Len(t, model.calls, 2)
+}
+
+func TestEmbedDocuments_BatchesModelCalls(t *testing.T) {
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee", "ffffff", "g", "hh", "iii", "jjjj"}
+	want := [][]float32{{1}, {2}, {3}, {4}, {5}, {6}, {1}, {2}, {3}, {4}}
+
+	perChunk := &countingModel{}
+	e, err := NewEmbedder(perChunk, WithBatchSize(1))
+	require.NoError(t, err)
+	vectors, err := e.EmbedDocuments(context.Background(), texts)
+	require.NoError(t, err)
+	assert.Equal(t, want, vectors)
+	assert.Len(t, perChunk.calls, len(texts))
+
+	batched := &countingModel{}
+	e, err = NewEmbedder(batched, WithBatchSize(4))
+	require.NoError(t, err)
+	vectors, err = e.EmbedDocuments(context.Background(), texts)
+	require.NoError(t, err)
+	assert.Equal(t, want, vectors)
+	assert.Equal(t, [][]string{texts[:4], texts[4:8], texts[8:]}, batched.calls)
+
+	unbounded := &countingModel{}
+	e, err = NewEmbedder(unbounded)
+	require.NoError(t, err)
+	_, err = e.EmbedDocuments(context.Background(), texts)
+	require.NoError(t, err)
+	assert.Len(t, unbounded.calls, 1)
+}
+
+// cancellingModel cancels the embedding after its first call
+type cancellingModel struct {
+	countingModel
+	cancel context.CancelFunc
+}
+
+func (m *cancellingModel) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	m.cancel()
+	return m.countingModel.CreateEmbedding(ctx, texts)
+}
+
+func TestEmbedDocuments_StopsBetweenBatchesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	model := &cancellingModel{cancel: cancel}
+	e, err := NewEmbedder(model, WithBatchSize(2))
+	require.NoError(t, err)
+
+	_, err = e.EmbedDocuments(ctx, []string{"a", "b", "c", "d", "e", "f"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, model.calls, 1)
+}
+
+func TestEmbedQuery_RetriedWhileOllamaUnavailable(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	model := &failingModel{errs: []error{errConnRefused, errConnRefused}}
+	e, err := NewEmbedder(model, WithRetry(retryPolicy()))
+	require.NoError(t, err)
+
+	vector, err := e.EmbedQuery(context.Background(), "query")
+
+	require.NoError(t, err)
+	assert.Equal(t, []float32{5}, vector)
+	assert.Equal(t, 3, model.attempts)
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestEmbedQuery_ModelErrorNotRetried(t *testing.T) {
+	model := &failingModel{errs: []error{errors.New(`model "bge-m3" not found, try pulling it first`)}}
+	e, err := NewEmbedder(model, WithRetry(retryPolicy()))
+	require.NoError(t, err)
+
+	_, err = e.EmbedQuery(context.Background(), "query")
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, model.attempts)
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.put("a", []float32{1})
+	cache.put("b", []float32{2})
+	_, _ = cache.get("a")
+	cache.put("c", []float32{3})
+
+	_, okA := cache.get("a")
+	_, okB := cache.get("b")
+	_, okC := cache.get("c")
+
+	assert.True(t, okA)
+	assert.False(t, okB)
+	assert.True(t, okC)
+}
@@ -0,0 +1,94 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OllamaModel creates embeddings with the /api/embed endpoint of Ollama, which
+// embeds all texts of a call in a single request. The langchaingo client sends
+// a request per text instead.
+type OllamaModel struct {
+	endpoint string
+	model    string
+	client   *http.Client
+}
+
+type embedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewOllamaModel creates an embedding model served by the Ollama server at serverURL
+func NewOllamaModel(serverURL, model string) (*OllamaModel, error) {
+	base, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ollama server url: %w", err)
+	}
+
+	return &OllamaModel{
+		endpoint: base.JoinPath("api", "embed").String(),
+		model:    model,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// CreateEmbedding returns the embeddings of texts in their order
+func (m *OllamaModel) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embedRequest{Model: m.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var embedded embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedded); err != nil {
+		return nil, fmt.Errorf("decoding embeddings: %w", err)
+	}
+	if len(embedded.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d texts", len(embedded.Embeddings), len(texts))
+	}
+
+	return embedded.Embeddings, nil
+}
+
+// statusError describes a failed request by its status text, which llmretry
+// recognizes for busy servers, and the error Ollama answered with
+func statusError(resp *http.Response) error {
+	status := strings.ToLower(http.StatusText(resp.StatusCode))
+
+	var body errorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("ollama: %s", status)
+	}
+	return fmt.Errorf("ollama: %s: %s", status, body.Error)
+}
@@ -0,0 +1,71 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
+)
+
+func TestOllamaModel_EmbedsBatchInOneRequest(t *testing.T) {
+	var requests []embedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/embed", r.URL.Path)
+
+		var req embedRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+
+		embeddings := make([][]float32, len(req.Input))
+		for i, text := range req.Input {
+			embeddings[i] = []float32{float32(len(text))}
+		}
+		_ = json.NewEncoder(w).Encode(embedResponse{Embeddings: embeddings})
+	}))
+	defer server.Close()
+
+	model, err := NewOllamaModel(server.URL+"/", "bge-m3")
+	require.NoError(t, err)
+
+	vectors, err := model.CreateEmbedding(context.Background(), []string{"a", "bb", "ccc"})
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1}, {2}, {3}}, vectors)
+	assert.Equal(t, []embedRequest{{Model: "bge-m3", Input: []string{"a", "bb", "ccc"}}}, requests)
+}
+
+func TestOllamaModel_BusyServerIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(errorResponse{Error: "model is loading"})
+	}))
+	defer server.Close()
+
+	model, err := NewOllamaModel(server.URL, "bge-m3")
+	require.NoError(t, err)
+
+	_, err = model.CreateEmbedding(context.Background(), []string{"a"})
+
+	require.EqualError(t, err, "ollama: service unavailable: model is loading")
+	assert.True(t, llmretry.IsRetryable(context.Background(), err))
+}
+
+func TestOllamaModel_MissingEmbeddings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(embedResponse{Embeddings: [][]float32{{1}}})
+	}))
+	defer server.Close()
+
+	model, err := NewOllamaModel(server.URL, "bge-m3")
+	require.NoError(t, err)
+
+	_, err = model.CreateEmbedding(context.Background(), []string{"a", "b"})
+
+	assert.EqualError(t, err, "ollama returned 1 embeddings for 2 texts")
+}
@@ -0,0 +1,74 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const createEmbeddingCacheTable = `
+CREATE TABLE IF NOT EXISTS embedding_cache (
+    hash CHAR(64) PRIMARY KEY,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+)`
+
+// PostgresCache persists embeddings by content hash in the embedding_cache table
+type PostgresCache struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCache creates the embedding_cache table if needed
+func NewPostgresCache(ctx context.Context, pool *pgxpool.Pool) (*PostgresCache, error) {
+	const op = "PostgresCache.New"
+
+	if _, err := pool.Exec(ctx, createEmbeddingCacheTable); err != nil {
+		return nil, fmt.Errorf("%s: failed to create embedding cache table: %w", op, err)
+	}
+
+	return &PostgresCache{pool: pool}, nil
+}
+
+// GetEmbeddings returns the stored embeddings of the hashes that are present
+func (c *PostgresCache) GetEmbeddings(ctx context.Context, hashes []string) (map[string][]float32, error) {
+	const op = "PostgresCache.GetEmbeddings"
+
+	rows, err := c.pool.Query(ctx, `SELECT hash, embedding FROM embedding_cache WHERE hash = ANY($1)`, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	embeddings := make(map[string][]float32, len(hashes))
+	for rows.Next() {
+		var hash string
+		var embedding []float32
+		if err := rows.Scan(&hash, &embedding); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		embeddings[hash] = embedding
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return embeddings, nil
+}
+
+// PutEmbeddings stores embeddings by hash, keeping existing entries
+func (c *PostgresCache) PutEmbeddings(ctx context.Context, embeddings map[string][]float32) error {
+	const op = "PostgresCache.PutEmbeddings"
+
+	batch := &pgx.Batch{}
+	for hash, embedding := range embeddings {
+		batch.Queue(`INSERT INTO embedding_cache (hash, embedding) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING`,
+			hash, embedding)
+	}
+
+	if err := c.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
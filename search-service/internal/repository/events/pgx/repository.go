@@ -2,6 +2,7 @@ package pgx
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -13,12 +14,22 @@ import (
 	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
 )
 
+// addHeadersColumn adds the headers of events to tables created before they
+// were stored
+const addHeadersColumn = `ALTER TABLE events ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '{}'`
+
 type Repository struct {
 	db      *pgxpool.Pool
 	queries *sqlc.Queries
 }
 
 func NewRepository(ctx context.Context, pool *pgxpool.Pool) (*Repository, error) {
+	const op = "EventRepository.New"
+
+	if _, err := pool.Exec(ctx, addHeadersColumn); err != nil {
+		return nil, fmt.Errorf("%s: failed to add the headers column: %w", op, err)
+	}
+
 	queries := sqlc.New(pool)
 
 	return &Repository{
@@ -31,10 +42,20 @@ func NewRepository(ctx context.Context, pool *pgxpool.Pool) (*Repository, error)
 func (r *Repository) CreateEvent(ctx context.Context, event eventmodel.Event) (eventmodel.Event, error) {
 	const op = "EventRepository.CreateEvent"
 
+	headers := event.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	encodedHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return eventmodel.Event{}, fmt.Errorf("%s: failed to encode headers: %w", op, err)
+	}
+
 	params := sqlc.CreateEventParams{
 		Name:    event.Name,
 		Topic:   event.Topic,
 		Payload: event.Payload,
+		Headers: encodedHeaders,
 	}
 
 	row, err := r.queries.CreateEvent(ctx, params)
@@ -42,17 +63,14 @@ func (r *Repository) CreateEvent(ctx context.Context, event eventmodel.Event) (e
 		return eventmodel.Event{}, fmt.Errorf("%s: failed to create event: %w", op, err)
 	}
 
-	return eventmodel.Event{
-		ID:        PgTypeToUUID(row.ID),
-		Name:      row.Name,
-		Topic:     row.Topic,
-		Payload:   row.Payload,
-		Sent:      row.Sent,
-		EventTime: PgTypeToTime(row.EventTime),
-	}, nil
+	created, err := rowToEvent(row)
+	if err != nil {
+		return eventmodel.Event{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return created, nil
 }
 
-// GetNotSentEvents retrieves events that haven't been sent yet
+// GetNotSentEvents retrieves events that haven't been sent yet, oldest first
 func (r *Repository) GetNotSentEvents(ctx context.Context, limit int, offset int) ([]eventmodel.Event, error) {
 	const op = "EventRepository.GetNotSentEvents"
 
@@ -68,19 +86,60 @@ func (r *Repository) GetNotSentEvents(ctx context.Context, limit int, offset int
 
 	events := make([]eventmodel.Event, len(rows))
 	for i, row := range rows {
-		events[i] = eventmodel.Event{
-			ID:        PgTypeToUUID(row.ID),
-			Name:      row.Name,
-			Topic:     row.Topic,
-			Payload:   row.Payload,
-			Sent:      row.Sent,
-			EventTime: PgTypeToTime(row.EventTime),
+		if events[i], err = rowToEvent(row); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 	}
 
 	return events, nil
 }
 
+// rowToEvent converts a row of the events table to an event
+func rowToEvent(row sqlc.Event) (eventmodel.Event, error) {
+	event := eventmodel.Event{
+		ID:        PgTypeToUUID(row.ID),
+		Name:      row.Name,
+		Topic:     row.Topic,
+		Payload:   row.Payload,
+		Sent:      row.Sent,
+		EventTime: PgTypeToTime(row.EventTime),
+	}
+	if len(row.Headers) > 0 {
+		if err := json.Unmarshal(row.Headers, &event.Headers); err != nil {
+			return eventmodel.Event{}, fmt.Errorf("failed to decode headers of event %s: %w", event.ID, err)
+		}
+	}
+	return event, nil
+}
+
+// CountNotSentEvents returns the number of events that haven't been sent yet
+func (r *Repository) CountNotSentEvents(ctx context.Context) (int, error) {
+	const op = "EventRepository.CountNotSentEvents"
+
+	count, err := r.queries.CountNotSentEvents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to count unsent events: %w", op, err)
+	}
+
+	return int(count), nil
+}
+
+// HasOlderNotSentEvents reports whether an event older than the given one
+// hasn't been sent yet
+func (r *Repository) HasOlderNotSentEvents(ctx context.Context, event eventmodel.Event) (bool, error) {
+	const op = "EventRepository.HasOlderNotSentEvents"
+
+	older, err := r.queries.HasOlderNotSentEvents(ctx, sqlc.HasOlderNotSentEventsParams{
+		EventTime: TimeToPgType(event.EventTime),
+		ID:        UuidToPgType(event.ID),
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: failed to check for older unsent events: %w", op, err)
+	}
+
+	return older, nil
+}
+
 // MarkEventAsSent marks an event as successfully sent
 func (r *Repository) MarkEventAsSent(ctx context.Context, eventID uuid.UUID) error {
 	const op = "EventRepository.MarkEventAsSent"
@@ -5,23 +5,54 @@ import (
 	"fmt"
 
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/ollama"
+
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
 )
 
+// generatingModel generates text, typically an Ollama LLM
+type generatingModel interface {
+	GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error)
+	Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error)
+}
+
+// Option configures a Generator
+type Option func(*Generator)
+
+// WithRetry retries model calls that fail while the model server is
+// unavailable according to policy. Streaming calls are only retried while
+// nothing was streamed yet, so a client never receives a chunk twice.
+func WithRetry(policy *llmretry.Policy) Option {
+	return func(g *Generator) {
+		g.retry = policy
+	}
+}
+
 type Generator struct {
-	llm *ollama.LLM
+	llm   generatingModel
+	retry *llmretry.Policy
 }
 
-func NewGenerator(llm *ollama.LLM) (*Generator, error) {
-	return &Generator{
+func NewGenerator(llm generatingModel, opts ...Option) (*Generator, error) {
+	g := &Generator{
 		llm: llm,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g, nil
 }
 
 func (g *Generator) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
 	const op = "Generator.GenerateContent"
 
-	response, err := g.llm.GenerateContent(ctx, messages, options...)
+	options, streamed := trackStreaming(options)
+	response, err := llmretry.Do(ctx, g.retry, op, func(ctx context.Context) (*llms.ContentResponse, error) {
+		response, err := g.llm.GenerateContent(ctx, messages, options...)
+		if err != nil && *streamed {
+			return nil, llmretry.Permanent(err)
+		}
+		return response, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -31,9 +62,37 @@ func (g *Generator) GenerateContent(ctx context.Context, messages []llms.Message
 
 func (g *Generator) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
 	const op = "Generator.Call"
-	response, err := g.llm.Call(ctx, prompt, options...)
+	options, streamed := trackStreaming(options)
+	response, err := llmretry.Do(ctx, g.retry, op, func(ctx context.Context) (string, error) {
+		response, err := g.llm.Call(ctx, prompt, options...)
+		if err != nil && *streamed {
+			return "", llmretry.Permanent(err)
+		}
+		return response, err
+	})
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 	return response, nil
 }
+
+// trackStreaming wraps the streaming function of options, if any, so that
+// streamed reports whether a chunk was already passed on
+func trackStreaming(options []llms.CallOption) ([]llms.CallOption, *bool) {
+	streamed := new(bool)
+
+	var callOptions llms.CallOptions
+	for _, option := range options {
+		option(&callOptions)
+	}
+	if callOptions.StreamingFunc == nil {
+		return options, streamed
+	}
+
+	streamingFunc := callOptions.StreamingFunc
+	tracked := llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		*streamed = true
+		return streamingFunc(ctx, chunk)
+	})
+	return append(options[:len(options):len(options)], tracked), streamed
+}
@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/nzb3/diploma/search-service/internal/repository/llmretry"
+)
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+// flakyModel fails its first calls with errs, then streams and returns answer
+type flakyModel struct {
+	errs   []error
+	answer string
+	calls  int
+}
+
+func (m *flakyModel) GenerateContent(ctx context.Context, _ []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var callOptions llms.CallOptions
+	for _, option := range options {
+		option(&callOptions)
+	}
+
+	m.calls++
+	if m.calls <= len(m.errs) {
+		return nil, m.errs[m.calls-1]
+	}
+	if callOptions.StreamingFunc != nil {
+		if err := callOptions.StreamingFunc(ctx, []byte(m.answer)); err != nil {
+			return nil, err
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.answer}}}, nil
+}
+
+func (m *flakyModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func newRetryingGenerator(t *testing.T, model *flakyModel) *Generator {
+	t.Helper()
+	g, err := NewGenerator(model, WithRetry(llmretry.NewPolicy(llmretry.Config{
+		Attempts:  3,
+		BaseDelay: time.Millisecond,
+		Backoff:   2,
+	})))
+	require.NoError(t, err)
+	return g
+}
+
+func TestCall_SucceedsAfterConnectionErrors(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	model := &flakyModel{errs: []error{errConnRefused, errConnRefused}, answer: "42"}
+
+	answer, err := newRetryingGenerator(t, model).Call(context.Background(), "question")
+
+	require.NoError(t, err)
+	assert.Equal(t, "42", answer)
+	assert.Equal(t, 3, model.calls)
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestGenerateContent_ModelErrorNotRetried(t *testing.T) {
+	model := &flakyModel{errs: []error{errors.New(`model "llama3.2:3b" not found`)}, answer: "42"}
+
+	_, err := newRetryingGenerator(t, model).GenerateContent(context.Background(), nil)
+
+	require.Error(t, err)
+	assert.Equal(t, 1, model.calls)
+}
+
+func TestGenerateContent_GivesUpAfterAttempts(t *testing.T) {
+	model := &flakyModel{errs: []error{errConnRefused, errConnRefused, errConnRefused}, answer: "42"}
+
+	_, err := newRetryingGenerator(t, model).GenerateContent(context.Background(), nil)
+
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, 3, model.calls)
+}
+
+func TestGenerateContent_StreamNotRetriedAfterFirstChunk(t *testing.T) {
+	model := &flakyModel{answer: "partial"}
+	var chunks []string
+	streamErr := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	_, err := newRetryingGenerator(t, model).GenerateContent(context.Background(), nil,
+		llms.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return streamErr
+		}))
+
+	assert.ErrorIs(t, err, syscall.ECONNRESET)
+	assert.Equal(t, 1, model.calls)
+	assert.Equal(t, []string{"partial"}, chunks)
+}
+
+// hungModel answers only once the context of the call is done, like a server
+// that stopped responding
+type hungModel struct {
+	calls int
+}
+
+func (m *hungModel) GenerateContent(ctx context.Context, _ []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *hungModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestGenerateContent_AbortsAtTimeout(t *testing.T) {
+	model := &hungModel{}
+	g, err := NewGenerator(model, WithRetry(llmretry.NewPolicy(llmretry.Config{
+		Attempts:  3,
+		BaseDelay: time.Millisecond,
+		Backoff:   2,
+		Timeout:   50 * time.Millisecond,
+	})))
+	require.NoError(t, err)
+
+	started := time.Now()
+	_, err = g.GenerateContent(context.Background(), nil)
+	elapsed := time.Since(started)
+
+	require.ErrorIs(t, err, llmretry.ErrTimeout)
+	assert.Equal(t, 1, model.calls, "timed out calls are not retried")
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+}
+
+func TestGenerateContent_ParentCancellationIsNotTimeout(t *testing.T) {
+	g, err := NewGenerator(&hungModel{}, WithRetry(llmretry.NewPolicy(llmretry.Config{
+		Attempts: 1,
+		Timeout:  time.Hour,
+	})))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = g.GenerateContent(ctx, nil)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, llmretry.ErrTimeout)
+}
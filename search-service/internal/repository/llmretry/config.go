@@ -0,0 +1,33 @@
+package llmretry
+
+import (
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// Config holds the retry settings of calls to Ollama
+type Config struct {
+	// Attempts is the total number of calls made, including the first one; 1 disables retrying
+	Attempts int `yaml:"attempts" mapstructure:"attempts" validate:"min=1"`
+	// BaseDelay is the wait before the first retry
+	BaseDelay time.Duration `yaml:"base_delay" mapstructure:"base_delay" validate:"min=0"`
+	// Backoff multiplies the wait after every further retry
+	Backoff float64 `yaml:"backoff" mapstructure:"backoff" validate:"gte=1"`
+	// MaxDelay caps the growing wait
+	MaxDelay time.Duration `yaml:"max_delay" mapstructure:"max_delay" validate:"min=0"`
+	// Timeout bounds every call, a streamed answer included, so that a hung
+	// server fails it with ErrTimeout; 0 leaves calls unbounded
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout" validate:"min=0"`
+}
+
+// NewConfig loads Ollama retry configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("ollama_retry", Config{
+		Attempts:  3,
+		BaseDelay: 500 * time.Millisecond,
+		Backoff:   2,
+		MaxDelay:  5 * time.Second,
+		Timeout:   2 * time.Minute,
+	})
+}
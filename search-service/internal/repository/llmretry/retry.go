@@ -0,0 +1,164 @@
+// Package llmretry retries calls to Ollama while its server is unreachable or
+// busy, for instance while it restarts or loads a model, and bounds how long
+// each call may take.
+package llmretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// unavailableMessages are the errors Ollama answers with while it is up but
+// cannot serve the call yet. Its client does not export the status code, so
+// they are matched by message.
+var unavailableMessages = []string{
+	"server busy",
+	"service unavailable",
+	"bad gateway",
+	"gateway timeout",
+	"too many requests",
+}
+
+// ErrTimeout is returned by calls that did not finish within the configured Timeout
+var ErrTimeout = models.ErrModelTimeout
+
+// permanentError marks an error as not retryable whatever its cause
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable, for failures after which a retry
+// would repeat side effects of the failed call
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Policy retries calls failing with retryable errors. A nil Policy calls once.
+type Policy struct {
+	config Config
+}
+
+// NewPolicy creates a retry policy with the given configuration
+func NewPolicy(config Config) *Policy {
+	return &Policy{config: config}
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable,
+// runs out of attempts or ctx is done. The last error is returned. Every call
+// gets a context bounded by the Timeout of the policy.
+func Do[T any](ctx context.Context, p *Policy, op string, fn func(context.Context) (T, error)) (T, error) {
+	result, err := callWithTimeout(ctx, p, fn)
+	if p == nil {
+		return result, err
+	}
+
+	delay := p.config.BaseDelay
+	for attempt := 2; attempt <= p.config.Attempts && err != nil && IsRetryable(ctx, err); attempt++ {
+		slog.WarnContext(ctx, "Ollama unavailable, retrying",
+			"op", op,
+			"error", err,
+			"attempt", attempt,
+			"delay", delay)
+
+		if waitErr := wait(ctx, delay); waitErr != nil {
+			return result, errors.Join(err, waitErr)
+		}
+
+		result, err = callWithTimeout(ctx, p, fn)
+		delay = p.nextDelay(delay)
+	}
+
+	return result, err
+}
+
+// callWithTimeout calls fn with ctx bounded by the Timeout of the policy. Running
+// out of time fails with ErrTimeout, while the errors of a cancelled or expired
+// ctx are returned as they are.
+func callWithTimeout[T any](ctx context.Context, p *Policy, fn func(context.Context) (T, error)) (T, error) {
+	if p == nil || p.config.Timeout <= 0 {
+		return fn(ctx)
+	}
+
+	callCtx, cancel := context.WithTimeoutCause(ctx, p.config.Timeout, ErrTimeout)
+	defer cancel()
+
+	result, err := fn(callCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(callCtx), ErrTimeout) {
+		return result, fmt.Errorf("%w after %s: %w", ErrTimeout, p.config.Timeout, err)
+	}
+	return result, err
+}
+
+// nextDelay grows delay by the backoff factor, capped at MaxDelay
+func (p *Policy) nextDelay(delay time.Duration) time.Duration {
+	delay = time.Duration(float64(delay) * p.config.Backoff)
+	if p.config.MaxDelay > 0 && delay > p.config.MaxDelay {
+		delay = p.config.MaxDelay
+	}
+	return delay
+}
+
+// wait blocks for delay or until ctx is done, releasing its timer either way
+func wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsRetryable reports whether err means that Ollama could not be reached or
+// was temporarily unable to serve the call. Errors of the model itself, such
+// as an unknown model or an invalid request, are not retryable, and neither
+// is anything once ctx is done. Calls that timed out are not retried either,
+// as a hung server would keep them waiting for the timeout every time.
+func IsRetryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) || errors.As(err, new(permanentError)) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, unavailable := range unavailableMessages {
+		if strings.Contains(message, unavailable) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package llmretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errConnRefused = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "connection refused", err: fmt.Errorf("post: %w", errConnRefused), expected: true},
+		{name: "connection reset", err: syscall.ECONNRESET, expected: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, expected: true},
+		{name: "server busy", err: errors.New("server busy, please try again.  maximum pending requests exceeded"), expected: true},
+		{name: "service unavailable", err: errors.New("503 Service Unavailable"), expected: true},
+		{name: "model not found", err: errors.New(`model "llama3.2:3b" not found, try pulling it first`), expected: false},
+		{name: "cancelled", err: context.Canceled, expected: false},
+		{name: "permanent", err: Permanent(errConnRefused), expected: false},
+		{name: "timed out", err: fmt.Errorf("%w: %w", ErrTimeout, context.DeadlineExceeded), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IsRetryable(context.Background(), tt.err))
+		})
+	}
+}
+
+func TestDo_BacksOffUpToMaxDelay(t *testing.T) {
+	policy := NewPolicy(Config{Attempts: 5, BaseDelay: time.Millisecond, Backoff: 2, MaxDelay: 3 * time.Millisecond})
+
+	delays := []time.Duration{policy.config.BaseDelay}
+	for range 3 {
+		delays = append(delays, policy.nextDelay(delays[len(delays)-1]))
+	}
+
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond}, delays)
+}
+
+func TestDo_StopsWhenContextIsDone(t *testing.T) {
+	policy := NewPolicy(Config{Attempts: 3, BaseDelay: time.Hour, Backoff: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	started := time.Now()
+	_, err := Do(ctx, policy, "test", func(context.Context) (string, error) {
+		calls++
+		return "", errConnRefused
+	})
+
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, 1, calls)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestDo_NilPolicyCallsOnce(t *testing.T) {
+	calls := 0
+	_, err := Do(context.Background(), nil, "test", func(context.Context) (string, error) {
+		calls++
+		return "", errConnRefused
+	})
+
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, 1, calls)
+}
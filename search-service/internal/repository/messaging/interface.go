@@ -12,6 +12,15 @@ type MessageHandler interface {
 	HandleMessage(ctx context.Context, topic string, key string, value []byte, headers map[string]string) error
 }
 
+// AsyncMessageHandler is a MessageHandler that may finish handling a message
+// after returning, e.g. on a pool of workers. Consumers mark a message consumed
+// only once done was called for it, so that a crash before then delivers it
+// again. done is not called when HandleMessageAsync returns an error.
+type AsyncMessageHandler interface {
+	MessageHandler
+	HandleMessageAsync(ctx context.Context, topic string, key string, value []byte, headers map[string]string, done func(error)) error
+}
+
 // MessageProducer defines the interface for publishing messages to a message broker
 // This interface abstracts the underlying messaging implementation (Kafka, RabbitMQ, etc.)
 // to ensure loose coupling, testability, and future flexibility
@@ -2,17 +2,25 @@ package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/search-service/internal/repository/messaging"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
 )
 
 // Consumer implements the MessageConsumer interface using Apache Kafka
 type Consumer struct {
+	client   sarama.Client
 	consumer sarama.ConsumerGroup
 	config   *ConsumerConfig
 	cancel   context.CancelFunc
@@ -23,15 +31,28 @@ type Consumer struct {
 type ConsumerConfig struct {
 	Brokers         []string
 	GroupID         string
-	AutoOffsetReset string // earliest, latest
+	AutoOffsetReset string // OffsetResetEarliest or OffsetResetLatest
+	// LagInterval is how often the consumer lag is reported, 0 disables the reports
+	LagInterval time.Duration
+	SecurityConfig
 }
 
+// Where a consumer group without a committed offset starts reading a partition
+const (
+	// OffsetResetEarliest reads the messages kept by the broker, so that none
+	// produced before the group first joined is missed
+	OffsetResetEarliest = "earliest"
+	// OffsetResetLatest only reads the messages produced after the group joined
+	OffsetResetLatest = "latest"
+)
+
 // NewDefaultConsumerConfig returns a consumer configuration with sensible defaults
 func NewDefaultConsumerConfig(brokers []string, groupID string) *ConsumerConfig {
 	return &ConsumerConfig{
 		Brokers:         brokers,
 		GroupID:         groupID,
-		AutoOffsetReset: "earliest",
+		AutoOffsetReset: OffsetResetEarliest,
+		LagInterval:     30 * time.Second,
 	}
 }
 
@@ -49,30 +70,78 @@ func NewKafkaConsumer(config *ConsumerConfig) (*Consumer, error) {
 		return nil, fmt.Errorf("kafka consumer group ID cannot be empty")
 	}
 
+	offset, err := initialOffset(config.AutoOffsetReset)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create Sarama configuration
-	saramaConfig := sarama.NewConfig()
+	saramaConfig, err := newSaramaConfig(config.SecurityConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kafka consumer security: %w", err)
+	}
+	// Round robin spreads whole partitions over the members, so every partition is
+	// read by a single member, one message after the other. Events sharing a key
+	// are therefore handled in the order they were produced.
 	saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
 	saramaConfig.Consumer.Return.Errors = true
 
-	// Set auto offset reset
-	if config.AutoOffsetReset == "earliest" {
-		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
-	} else {
-		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	saramaConfig.Consumer.Offsets.Initial = offset
+
+	// The consumer group shares its client with the lag reports
+	client, err := sarama.NewClient(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
 	}
 
 	// Create the consumer group
-	consumer, err := sarama.NewConsumerGroup(config.Brokers, config.GroupID, saramaConfig)
+	consumer, err := sarama.NewConsumerGroupFromClient(config.GroupID, client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create kafka consumer group: %w", err)
 	}
 
 	return &Consumer{
+		client:   client,
 		consumer: consumer,
 		config:   config,
 	}, nil
 }
 
+// PartitionCount returns the number of partitions of topic
+func PartitionCount(config *ConsumerConfig, topic string) (int, error) {
+	saramaConfig, err := newSaramaConfig(config.SecurityConfig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to configure kafka client security: %w", err)
+	}
+
+	client, err := sarama.NewClient(config.Brokers, saramaConfig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get partitions of topic %s: %w", topic, err)
+	}
+	return len(partitions), nil
+}
+
+// initialOffset returns the sarama offset of the auto offset reset, earliest
+// when it is unset
+func initialOffset(autoOffsetReset string) (int64, error) {
+	switch autoOffsetReset {
+	case OffsetResetEarliest, "":
+		return sarama.OffsetOldest, nil
+	case OffsetResetLatest:
+		return sarama.OffsetNewest, nil
+	default:
+		return 0, fmt.Errorf("invalid kafka auto offset reset %q, expected %q or %q",
+			autoOffsetReset, OffsetResetEarliest, OffsetResetLatest)
+	}
+}
+
 // Subscribe subscribes to topics and starts consuming messages
 func (c *Consumer) Subscribe(ctx context.Context, topics []string, handler messaging.MessageHandler) error {
 	if len(topics) == 0 {
@@ -128,6 +197,8 @@ func (c *Consumer) Subscribe(ctx context.Context, topics []string, handler messa
 		}
 	}()
 
+	c.startLagReports(consumerCtx, topics)
+
 	slog.Info("Kafka consumer subscribed to topics", "topics", topics, "group_id", c.config.GroupID)
 	return nil
 }
@@ -154,10 +225,15 @@ func (c *Consumer) Close() error {
 	// Wait for all goroutines to finish
 	c.wg.Wait()
 
+	var errs []error
 	if c.consumer != nil {
-		return c.consumer.Close()
+		errs = append(errs, c.consumer.Close())
 	}
-	return nil
+	// A consumer group created from a client leaves closing it to the caller
+	if c.client != nil {
+		errs = append(errs, c.client.Close())
+	}
+	return errors.Join(errs...)
 }
 
 // consumerGroupHandler implements sarama.ConsumerGroupHandler
@@ -177,6 +253,11 @@ func (h *consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
 
 // ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
 func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	async, isAsync := h.handler.(messaging.AsyncMessageHandler)
+	offsets := newOffsetTracker(func(message *sarama.ConsumerMessage) {
+		session.MarkMessage(message, "")
+	})
+
 	// Handle messages
 	for {
 		select {
@@ -191,14 +272,31 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				headers[string(header.Key)] = string(header.Value)
 			}
 
+			// Continue the producer's trace, if the message carries one
+			ctx := otel.GetTextMapPropagator().Extract(session.Context(), propagation.MapCarrier(headers))
+			ctx, span := tracing.StartSpan(ctx, "kafka.consume "+message.Topic,
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.destination.name", message.Topic),
+					attribute.Int("messaging.kafka.partition", int(message.Partition)),
+					attribute.Int64("messaging.kafka.offset", message.Offset),
+				))
+
+			if isAsync {
+				err := h.handleAsync(ctx, session, async, offsets, message, headers)
+				tracing.EndSpan(span, err)
+				continue
+			}
+
 			// Handle the message
 			err := h.handler.HandleMessage(
-				session.Context(),
+				ctx,
 				message.Topic,
 				string(message.Key),
 				message.Value,
 				headers,
 			)
+			tracing.EndSpan(span, err)
 
 			if err != nil {
 				slog.Error("Error handling message",
@@ -216,3 +314,38 @@ func (h *consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 		}
 	}
 }
+
+// handleAsync hands the message to an asynchronous handler. The message is
+// marked consumed once it and every earlier message of its partition were
+// handled, so a message still being indexed keeps its offset uncommitted.
+func (h *consumerGroupHandler) handleAsync(
+	ctx context.Context,
+	session sarama.ConsumerGroupSession,
+	handler messaging.AsyncMessageHandler,
+	offsets *offsetTracker,
+	message *sarama.ConsumerMessage,
+	headers map[string]string,
+) error {
+	tracked := offsets.add(message)
+	err := handler.HandleMessageAsync(ctx, message.Topic, string(message.Key), message.Value, headers, func(err error) {
+		if err != nil {
+			slog.Error("Error handling message",
+				"topic", message.Topic,
+				"key", string(message.Key),
+				"error", err)
+		}
+		offsets.done(tracked)
+	})
+	if err != nil {
+		slog.Error("Error handling message",
+			"topic", message.Topic,
+			"key", string(message.Key),
+			"error", err)
+		// Like a failed synchronous message the offset is passed by the next
+		// ones, unless the session ends and the message goes to the next owner
+		if session.Context().Err() == nil {
+			offsets.done(tracked)
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitialOffset(t *testing.T) {
+	tests := []struct {
+		name            string
+		autoOffsetReset string
+		expected        int64
+		wantErr         bool
+	}{
+		{name: "earliest", autoOffsetReset: OffsetResetEarliest, expected: sarama.OffsetOldest},
+		{name: "latest", autoOffsetReset: OffsetResetLatest, expected: sarama.OffsetNewest},
+		{name: "unset defaults to earliest", autoOffsetReset: "", expected: sarama.OffsetOldest},
+		{name: "typo", autoOffsetReset: "earliset", wantErr: true},
+		{name: "wrong case", autoOffsetReset: "Latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := initialOffset(tt.autoOffsetReset)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, tt.autoOffsetReset)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, offset)
+		})
+	}
+}
+
+func TestNewDefaultConsumerConfig_ReadsFromEarliest(t *testing.T) {
+	config := NewDefaultConsumerConfig([]string{"localhost:9092"}, "group")
+
+	assert.Equal(t, OffsetResetEarliest, config.AutoOffsetReset)
+}
+
+func TestNewKafkaConsumer_RejectsInvalidAutoOffsetReset(t *testing.T) {
+	config := NewDefaultConsumerConfig([]string{"localhost:9092"}, "group")
+	config.AutoOffsetReset = "newest"
+
+	// Fails before connecting to the brokers
+	consumer, err := NewKafkaConsumer(config)
+
+	assert.Nil(t, consumer)
+	assert.ErrorContains(t, err, `invalid kafka auto offset reset "newest"`)
+}
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+)
+
+// startLagReports reports the lag of the consumer group on the topics every
+// LagInterval until ctx is cancelled, which Close does
+func (c *Consumer) startLagReports(ctx context.Context, topics []string) {
+	if c.client == nil || c.config.LagInterval <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.config.LagInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.reportLag(topics); err != nil {
+				slog.Warn("Failed to report kafka consumer lag", "group_id", c.config.GroupID, "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// reportLag sets the lag of every partition of the topics, and their sum per topic
+func (c *Consumer) reportLag(topics []string) error {
+	const op = "Consumer.reportLag"
+
+	partitions := make(map[string][]int32, len(topics))
+	for _, topic := range topics {
+		ids, err := c.client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		partitions[topic] = ids
+	}
+
+	committed, err := c.committedOffsets(partitions)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var errs []error
+	for topic, ids := range partitions {
+		var backlog int64
+		for _, partition := range ids {
+			lag, err := c.partitionLag(topic, partition, committed[topic][partition])
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			metrics.KafkaConsumerLag.WithLabelValues(topic, strconv.Itoa(int(partition))).Set(float64(lag))
+			backlog += lag
+		}
+		metrics.KafkaConsumerBacklog.WithLabelValues(topic).Set(float64(backlog))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// committedOffsets fetches the offsets the group committed from its coordinator.
+// Partitions the group never committed an offset for are -1.
+func (c *Consumer) committedOffsets(partitions map[string][]int32) (map[string]map[int32]int64, error) {
+	coordinator, err := c.client.Coordinator(c.config.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := sarama.NewOffsetFetchRequest(c.client.Config().Version, c.config.GroupID, partitions)
+	response, err := coordinator.FetchOffset(request)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]map[int32]int64, len(partitions))
+	for topic, ids := range partitions {
+		offsets[topic] = make(map[int32]int64, len(ids))
+		for _, partition := range ids {
+			block := response.GetBlock(topic, partition)
+			if block == nil {
+				return nil, fmt.Errorf("no committed offset of %s/%d: %w", topic, partition, sarama.ErrIncompleteResponse)
+			}
+			if block.Err != sarama.ErrNoError {
+				return nil, fmt.Errorf("committed offset of %s/%d: %w", topic, partition, block.Err)
+			}
+			offsets[topic][partition] = block.Offset
+		}
+	}
+	return offsets, nil
+}
+
+// partitionLag returns the messages between the committed offset and the
+// high-water mark of the partition. Without a committed offset the group
+// starts at the initial offset, so the lag is counted from there.
+func (c *Consumer) partitionLag(topic string, partition int32, committed int64) (int64, error) {
+	highWaterMark, err := c.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, fmt.Errorf("high-water mark of %s/%d: %w", topic, partition, err)
+	}
+
+	if committed < 0 {
+		committed, err = c.client.GetOffset(topic, partition, c.client.Config().Consumer.Offsets.Initial)
+		if err != nil {
+			return 0, fmt.Errorf("initial offset of %s/%d: %w", topic, partition, err)
+		}
+	}
+
+	return max(highWaterMark-committed, 0), nil
+}
@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/metrics"
+)
+
+// newLagConsumer returns a consumer whose client talks to a broker serving the
+// topic "lag-test" with three partitions: one behind by 4, one caught up and
+// one the group never committed an offset for
+func newLagConsumer(t *testing.T, interval time.Duration) *Consumer {
+	t.Helper()
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest": sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("lag-test", 0, broker.BrokerID()).
+			SetLeader("lag-test", 1, broker.BrokerID()).
+			SetLeader("lag-test", 2, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "group", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("group", "lag-test", 0, 6, "", sarama.ErrNoError).
+			SetOffset("group", "lag-test", 1, 5, "", sarama.ErrNoError).
+			SetOffset("group", "lag-test", 2, -1, "", sarama.ErrNoError),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("lag-test", 0, sarama.OffsetNewest, 10).
+			SetOffset("lag-test", 1, sarama.OffsetNewest, 5).
+			SetOffset("lag-test", 2, sarama.OffsetNewest, 7).
+			SetOffset("lag-test", 2, sarama.OffsetOldest, 4),
+	})
+
+	config := sarama.NewConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	require.NoError(t, err)
+
+	return &Consumer{
+		client: client,
+		config: &ConsumerConfig{GroupID: "group", LagInterval: interval},
+	}
+}
+
+func TestReportLag_SetsPartitionLagAndBacklog(t *testing.T) {
+	consumer := newLagConsumer(t, time.Minute)
+	t.Cleanup(func() { consumer.client.Close() })
+
+	require.NoError(t, consumer.reportLag([]string{"lag-test"}))
+
+	assert.Equal(t, 4.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("lag-test", "0")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("lag-test", "1")))
+	// Without a committed offset the group starts at the oldest message
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("lag-test", "2")))
+	assert.Equal(t, 7.0, testutil.ToFloat64(metrics.KafkaConsumerBacklog.WithLabelValues("lag-test")))
+}
+
+func TestStartLagReports_StopsOnClose(t *testing.T) {
+	metrics.KafkaConsumerBacklog.DeleteLabelValues("lag-test")
+	consumer := newLagConsumer(t, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer.cancel = cancel
+	consumer.startLagReports(ctx, []string{"lag-test"})
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.KafkaConsumerBacklog.WithLabelValues("lag-test")) == 7
+	}, time.Second, time.Millisecond)
+
+	// Close waits for the reports to stop before closing the client
+	require.NoError(t, consumer.Close())
+}
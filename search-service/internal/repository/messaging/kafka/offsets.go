@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+// offsetTracker marks the messages of a partition consumed in offset order.
+// Messages handled on a pool of workers finish out of order, and marking one
+// commits every earlier offset of the partition, so a message is only marked
+// once the messages before it are done as well.
+type offsetTracker struct {
+	mu      sync.Mutex
+	mark    func(*sarama.ConsumerMessage)
+	pending []*trackedMessage // In offset order
+}
+
+type trackedMessage struct {
+	message *sarama.ConsumerMessage
+	done    bool
+}
+
+func newOffsetTracker(mark func(*sarama.ConsumerMessage)) *offsetTracker {
+	return &offsetTracker{mark: mark}
+}
+
+// add tracks a message handed to the handler. Messages are added in the order
+// they were consumed.
+func (t *offsetTracker) add(message *sarama.ConsumerMessage) *trackedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked := &trackedMessage{message: message}
+	t.pending = append(t.pending, tracked)
+	return tracked
+}
+
+// done records that the message was handled and marks the messages that are
+// done without an earlier one still pending
+func (t *offsetTracker) done(tracked *trackedMessage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tracked.done = true
+	for len(t.pending) > 0 && t.pending[0].done {
+		t.mark(t.pending[0].message)
+		t.pending[0] = nil
+		t.pending = t.pending[1:]
+	}
+}
@@ -0,0 +1,29 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker_MarksInOffsetOrder(t *testing.T) {
+	var marked []int64
+	offsets := newOffsetTracker(func(message *sarama.ConsumerMessage) {
+		marked = append(marked, message.Offset)
+	})
+
+	first := offsets.add(&sarama.ConsumerMessage{Offset: 10})
+	second := offsets.add(&sarama.ConsumerMessage{Offset: 11})
+	third := offsets.add(&sarama.ConsumerMessage{Offset: 12})
+
+	// A later message finishing first waits for the earlier one
+	offsets.done(second)
+	assert.Empty(t, marked)
+
+	offsets.done(first)
+	assert.Equal(t, []int64{10, 11}, marked)
+
+	offsets.done(third)
+	assert.Equal(t, []int64{10, 11, 12}, marked)
+}
@@ -6,8 +6,13 @@ import (
 	"log/slog"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
 )
 
 // Producer implements the MessageProducer interface using Apache Kafka
@@ -23,6 +28,7 @@ type Config struct {
 	RequiredAcks    sarama.RequiredAcks
 	RetryMax        int
 	CompressionType sarama.CompressionCodec
+	SecurityConfig
 }
 
 // NewKafkaProducer creates a new Kafka producer with the given configuration
@@ -36,7 +42,10 @@ func NewKafkaProducer(config *Config) (*Producer, error) {
 	}
 
 	// Create Sarama configuration
-	saramaConfig := sarama.NewConfig()
+	saramaConfig, err := newSaramaConfig(config.SecurityConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure kafka producer security: %w", err)
+	}
 	saramaConfig.Producer.Return.Successes = true
 	saramaConfig.Producer.Retry.Max = config.RetryMax
 	saramaConfig.Producer.RequiredAcks = config.RequiredAcks
@@ -70,8 +79,22 @@ func (p *Producer) PublishEvent(ctx context.Context, event eventmodel.Event) err
 		},
 	}
 
+	// Events retried by the outbox processor are published long after the
+	// operation that created them, whose trace they carry in their headers
+	if len(event.Headers) > 0 {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(event.Headers))
+	}
+	ctx, span := tracing.StartSpan(ctx, "kafka.publish "+event.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.destination.name", event.Topic),
+			attribute.String("event.name", event.Name),
+		))
+	otel.GetTextMapPropagator().Inject(ctx, recordHeaderCarrier{headers: &message.Headers})
+
 	// Send message
 	partition, offset, err := p.producer.SendMessage(message)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("failed to publish event to kafka: %w", err)
 	}
@@ -89,7 +112,12 @@ func (p *Producer) PublishEvent(ctx context.Context, event eventmodel.Event) err
 // Health checks if the producer can communicate with Kafka brokers
 func (p *Producer) Health(ctx context.Context) error {
 	// Create a simple health check by trying to get metadata
-	client, err := sarama.NewClient(p.config.Brokers, sarama.NewConfig())
+	saramaConfig, err := newSaramaConfig(p.config.SecurityConfig)
+	if err != nil {
+		return fmt.Errorf("failed to configure kafka client for health check: %w", err)
+	}
+
+	client, err := sarama.NewClient(p.config.Brokers, saramaConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create kafka client for health check: %w", err)
 	}
@@ -0,0 +1,42 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models/eventmodel"
+)
+
+func TestPublishEvent_RestoresTraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	event, err := eventmodel.NewEvent("resource.deleted", "resources", map[string]any{"name": "notes.pdf"})
+	require.NoError(t, err)
+	event.Headers = map[string]string{"traceparent": "00-" + traceID + "-00f067aa0ba902b7-01"}
+
+	var traceparent string
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+		traceparent = recordHeaderCarrier{headers: &message.Headers}.Get("traceparent")
+		return nil
+	})
+
+	// Published by the outbox processor, outside of the operation creating it
+	p := &Producer{producer: producer, config: &Config{}}
+	require.NoError(t, p.PublishEvent(context.Background(), event))
+	require.NoError(t, producer.Close())
+
+	restored := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	assert.Equal(t, traceID, trace.SpanContextFromContext(restored).TraceID().String())
+}
@@ -0,0 +1,126 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// SASL mechanisms supported for broker authentication
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismScramSHA256 = "SCRAM-SHA-256"
+	SASLMechanismScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SecurityConfig holds SASL and TLS settings shared by producers and consumers
+type SecurityConfig struct {
+	// SASLMechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; empty disables SASL
+	SASLMechanism string
+	SASLUser      string
+	SASLPassword  string
+	TLSEnabled    bool
+	// TLSCACert is a PEM encoded CA certificate or a path to one; the system pool is used when empty
+	TLSCACert string
+}
+
+// newSaramaConfig creates a sarama configuration with the security settings applied
+func newSaramaConfig(security SecurityConfig) (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	if err := security.apply(saramaConfig); err != nil {
+		return nil, err
+	}
+	return saramaConfig, nil
+}
+
+// apply configures sarama's SASL and TLS settings
+func (s SecurityConfig) apply(saramaConfig *sarama.Config) error {
+	if s.TLSEnabled {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	if s.SASLMechanism == "" {
+		return nil
+	}
+
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.User = s.SASLUser
+	saramaConfig.Net.SASL.Password = s.SASLPassword
+	saramaConfig.Net.SASL.Handshake = true
+
+	switch strings.ToUpper(s.SASLMechanism) {
+	case SASLMechanismPlain:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case SASLMechanismScramSHA256:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA256}
+		}
+	case SASLMechanismScramSHA512:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA512}
+		}
+	default:
+		return fmt.Errorf("unsupported kafka SASL mechanism %q", s.SASLMechanism)
+	}
+
+	return nil
+}
+
+func (s SecurityConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.TLSCACert == "" {
+		return tlsConfig, nil
+	}
+
+	caCert := []byte(s.TLSCACert)
+	if !strings.HasPrefix(strings.TrimSpace(s.TLSCACert), "-----BEGIN") {
+		var err error
+		caCert, err = os.ReadFile(s.TLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA certificate: %w", err)
+		}
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse kafka CA certificate")
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
+
+// scramClient implements sarama.SCRAMClient on top of xdg-go/scram
+type scramClient struct {
+	conversation  *scram.ClientConversation
+	hashGenerator scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
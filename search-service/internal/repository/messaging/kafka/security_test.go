@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCACert returns a PEM encoded self-signed CA certificate
+func testCACert(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestNewSaramaConfig_NoSecurity(t *testing.T) {
+	config, err := newSaramaConfig(SecurityConfig{})
+
+	require.NoError(t, err)
+	assert.False(t, config.Net.SASL.Enable)
+	assert.False(t, config.Net.TLS.Enable)
+}
+
+func TestNewSaramaConfig_SASL(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		want      sarama.SASLMechanism
+		scram     bool
+	}{
+		{"PLAIN", sarama.SASLTypePlaintext, false},
+		{"plain", sarama.SASLTypePlaintext, false},
+		{"SCRAM-SHA-256", sarama.SASLTypeSCRAMSHA256, true},
+		{"scram-sha-512", sarama.SASLTypeSCRAMSHA512, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mechanism, func(t *testing.T) {
+			config, err := newSaramaConfig(SecurityConfig{
+				SASLMechanism: tt.mechanism,
+				SASLUser:      "indexer",
+				SASLPassword:  "secret",
+			})
+
+			require.NoError(t, err)
+			assert.True(t, config.Net.SASL.Enable)
+			assert.True(t, config.Net.SASL.Handshake)
+			assert.Equal(t, tt.want, config.Net.SASL.Mechanism)
+			assert.Equal(t, "indexer", config.Net.SASL.User)
+			assert.Equal(t, "secret", config.Net.SASL.Password)
+
+			if !tt.scram {
+				assert.Nil(t, config.Net.SASL.SCRAMClientGeneratorFunc)
+				return
+			}
+			// The client opens the SCRAM conversation with the user name
+			client := config.Net.SASL.SCRAMClientGeneratorFunc()
+			require.NoError(t, client.Begin("indexer", "secret", ""))
+			first, err := client.Step("")
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(first, "n,,n=indexer,r="), first)
+			assert.False(t, client.Done())
+		})
+	}
+}
+
+func TestNewSaramaConfig_UnsupportedSASLMechanism(t *testing.T) {
+	_, err := newSaramaConfig(SecurityConfig{SASLMechanism: "GSSAPI"})
+
+	assert.EqualError(t, err, `unsupported kafka SASL mechanism "GSSAPI"`)
+}
+
+func TestNewSaramaConfig_TLS(t *testing.T) {
+	caCert := testCACert(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte(caCert), 0o600))
+
+	tests := []struct {
+		name      string
+		caCert    string
+		wantRoots bool
+	}{
+		{"system pool", "", false},
+		{"inline certificate", caCert, true},
+		{"certificate file", caFile, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newSaramaConfig(SecurityConfig{TLSEnabled: true, TLSCACert: tt.caCert})
+
+			require.NoError(t, err)
+			assert.True(t, config.Net.TLS.Enable)
+			assert.Equal(t, uint16(tls.VersionTLS12), config.Net.TLS.Config.MinVersion)
+			assert.Equal(t, tt.wantRoots, config.Net.TLS.Config.RootCAs != nil)
+		})
+	}
+}
+
+func TestNewSaramaConfig_InvalidCACert(t *testing.T) {
+	tests := []struct {
+		name    string
+		caCert  string
+		wantErr string
+	}{
+		{"missing file", filepath.Join(t.TempDir(), "missing.pem"), "failed to read kafka CA certificate"},
+		{"not a certificate", "-----BEGIN CERTIFICATE-----\nnot base64\n-----END CERTIFICATE-----", "failed to parse kafka CA certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSaramaConfig(SecurityConfig{TLSEnabled: true, TLSCACert: tt.caCert})
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// recordHeaderCarrier adapts sarama producer headers to the OpenTelemetry
+// propagator, so the W3C traceparent travels with every message.
+type recordHeaderCarrier struct {
+	headers *[]sarama.RecordHeader
+}
+
+var _ propagation.TextMapCarrier = recordHeaderCarrier{}
+
+func (c recordHeaderCarrier) Get(key string) string {
+	for _, header := range *c.headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c recordHeaderCarrier) Set(key, value string) {
+	for i, header := range *c.headers {
+		if string(header.Key) == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (c recordHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.headers))
+	for _, header := range *c.headers {
+		keys = append(keys, string(header.Key))
+	}
+	return keys
+}
@@ -2,11 +2,22 @@ package postgres
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/nzb3/diploma/search-service/internal/configurator"
 )
 
-// Config holds PostgreSQL database configuration
+// Config holds PostgreSQL database configuration.
+//
+// Every replica of the service opens up to MaxConns connections, so
+// MaxConns times the replicas, plus the connections of migrations and admin
+// tools, must stay below max_connections of the server, which defaults to 100.
+// A few connections per CPU core of the database is usually enough, searches
+// wait on the embedder far longer than on queries. MinConns keeps connections
+// open while idle, so the first searches after a quiet period don't pay for
+// connecting; it shouldn't exceed what the service uses under normal load.
 type Config struct {
 	Host     string `yaml:"host" mapstructure:"host" validate:"required"`
 	Port     string `yaml:"port" mapstructure:"port" validate:"required"`
@@ -14,6 +25,18 @@ type Config struct {
 	Password string `yaml:"password" mapstructure:"password" validate:"required"`
 	DBName   string `yaml:"dbname" mapstructure:"dbname" validate:"required"`
 	SSLMode  string `yaml:"sslmode" mapstructure:"sslmode"`
+	// MaxConns is the size of the pool, at least MinConns
+	MaxConns int `yaml:"max_conns" mapstructure:"max_conns" validate:"min=1"`
+	// MinConns is the number of connections kept open while idle
+	MinConns int `yaml:"min_conns" mapstructure:"min_conns" validate:"min=0"`
+	// MaxConnLifetime closes connections after this long, so that they are
+	// spread again after a failover or a server restart
+	MaxConnLifetime time.Duration `yaml:"max_conn_lifetime" mapstructure:"max_conn_lifetime" validate:"min=0"`
+	// HealthCheckPeriod is how often idle connections are checked
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" mapstructure:"health_check_period" validate:"min=0"`
+	// ConnectTimeout bounds connecting, and the ping at startup, so that an
+	// unreachable database fails the start instead of hanging it
+	ConnectTimeout time.Duration `yaml:"connect_timeout" mapstructure:"connect_timeout" validate:"min=0"`
 }
 
 // GetConnectionString builds PostgreSQL connection string
@@ -22,16 +45,46 @@ func (c *Config) GetConnectionString() string {
 		c.User, c.Password, c.Host, c.Port, c.DBName, c.SSLMode)
 }
 
+// PoolConfig returns the pgx pool configuration of the connection string with
+// the pool sizing and timeouts applied
+func (c *Config) PoolConfig() (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(c.GetConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	config.MaxConns = int32(c.MaxConns)
+	config.MinConns = int32(c.MinConns)
+	if c.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+	if c.ConnectTimeout > 0 {
+		config.ConnConfig.ConnectTimeout = c.ConnectTimeout
+	}
+
+	return config, nil
+}
+
 // NewConfig loads PostgreSQL configuration using the configurator package
 func NewConfig() (*Config, error) {
-	config := new(Config)
-
-	config.Host = configurator.GetString("postgres.host")
-	config.Port = configurator.GetString("postgres.port")
-	config.User = configurator.GetString("postgres.user")
-	config.Password = configurator.GetString("postgres.password")
-	config.DBName = configurator.GetString("postgres.dbname")
-	config.SSLMode = configurator.GetString("postgres.sslmode")
+	config, err := configurator.LoadKeys("postgres", Config{
+		MaxConns:          10,
+		MinConns:          2,
+		MaxConnLifetime:   time.Hour,
+		HealthCheckPeriod: time.Minute,
+		ConnectTimeout:    5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.MaxConns < config.MinConns {
+		return nil, fmt.Errorf("invalid postgres config: max_conns %d is less than min_conns %d",
+			config.MaxConns, config.MinConns)
+	}
 
 	return config, nil
 }
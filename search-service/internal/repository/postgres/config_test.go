@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setConnection(t *testing.T) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("postgres.host", "db")
+	viper.Set("postgres.port", "5432")
+	viper.Set("postgres.user", "search")
+	viper.Set("postgres.password", "secret")
+	viper.Set("postgres.dbname", "search")
+	viper.Set("postgres.sslmode", "disable")
+}
+
+func TestPoolConfig_AppliesPoolSettings(t *testing.T) {
+	setConnection(t)
+	viper.Set("postgres.max_conns", 20)
+	viper.Set("postgres.min_conns", 4)
+	viper.Set("postgres.max_conn_lifetime", "30m")
+	viper.Set("postgres.health_check_period", "15s")
+	viper.Set("postgres.connect_timeout", "3s")
+
+	config, err := NewConfig()
+	require.NoError(t, err)
+	poolConfig, err := config.PoolConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(20), poolConfig.MaxConns)
+	assert.Equal(t, int32(4), poolConfig.MinConns)
+	assert.Equal(t, 30*time.Minute, poolConfig.MaxConnLifetime)
+	assert.Equal(t, 15*time.Second, poolConfig.HealthCheckPeriod)
+	assert.Equal(t, 3*time.Second, poolConfig.ConnConfig.ConnectTimeout)
+}
+
+func TestNewConfig_Defaults(t *testing.T) {
+	setConnection(t)
+
+	config, err := NewConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, 10, config.MaxConns)
+	assert.Equal(t, 2, config.MinConns)
+	assert.Equal(t, 5*time.Second, config.ConnectTimeout)
+}
+
+func TestNewConfig_MaxConnsBelowMinConns(t *testing.T) {
+	setConnection(t)
+	viper.Set("postgres.max_conns", 2)
+	viper.Set("postgres.min_conns", 5)
+
+	config, err := NewConfig()
+
+	require.Error(t, err)
+	assert.Nil(t, config)
+	assert.Contains(t, err.Error(), "max_conns 2 is less than min_conns 5")
+}
@@ -0,0 +1,47 @@
+package vectorstorage
+
+import (
+	"github.com/tmc/langchaingo/textsplitter"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// Chunking defaults, measured in characters. They match the splitter's own
+// defaults and suit regular prose: chunks stay small enough to be specific
+// while the overlap keeps sentences cut at a boundary searchable.
+const (
+	defaultChunkSize    = 512
+	defaultChunkOverlap = 100
+)
+
+// chunkSettings resolves the chunk size and overlap for the resource. A size or
+// overlap set on the resource takes precedence over the configured one, and the
+// overlap is capped below the size so that the splitter always moves forward.
+func (s *VectorStorage) chunkSettings(resource models.Resource) (size, overlap int) {
+	size, overlap = defaultChunkSize, defaultChunkOverlap
+	if s.cfg.ChunkSize > 0 {
+		size = s.cfg.ChunkSize
+	}
+	if s.cfg.ChunkOverlap != nil {
+		overlap = *s.cfg.ChunkOverlap
+	}
+
+	if resource.ChunkSize > 0 {
+		size = resource.ChunkSize
+	}
+	if resource.ChunkOverlap != nil {
+		overlap = *resource.ChunkOverlap
+	}
+
+	overlap = max(0, min(overlap, size-1))
+	return size, overlap
+}
+
+// newTextSplitter builds the markdown splitter used to chunk the resource
+func (s *VectorStorage) newTextSplitter(resource models.Resource) textsplitter.TextSplitter {
+	size, overlap := s.chunkSettings(resource)
+	return textsplitter.NewMarkdownTextSplitter(
+		textsplitter.WithChunkSize(size),
+		textsplitter.WithChunkOverlap(overlap),
+	)
+}
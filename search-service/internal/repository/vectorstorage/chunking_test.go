@@ -0,0 +1,80 @@
+package vectorstorage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func TestNewTextSplitter_ChunkCount(t *testing.T) {
+	// 200 four-letter words, 20 of them fit into 100 characters
+	text := strings.TrimSpace(strings.Repeat("word ", 200))
+	noOverlap := 0
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		resource models.Resource
+		want     int
+	}{
+		{
+			name: "configured size",
+			cfg:  Config{ChunkSize: 100, ChunkOverlap: &noOverlap},
+			want: 10,
+		},
+		{
+			name:     "resource override",
+			cfg:      Config{ChunkSize: 100, ChunkOverlap: &noOverlap},
+			resource: models.Resource{ChunkSize: 200},
+			want:     5,
+		},
+		{
+			name: "defaults",
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &VectorStorage{cfg: &tt.cfg}
+
+			chunks, err := storage.newTextSplitter(tt.resource).SplitText(text)
+
+			require.NoError(t, err)
+			assert.Len(t, chunks, tt.want)
+		})
+	}
+}
+
+func TestChunkSettings_OverlapBelowSize(t *testing.T) {
+	overlap := 300
+	storage := &VectorStorage{cfg: &Config{ChunkOverlap: &overlap}}
+
+	size, gotOverlap := storage.chunkSettings(models.Resource{ChunkSize: 200})
+
+	assert.Equal(t, 200, size)
+	assert.Equal(t, 199, gotOverlap)
+}
+
+func TestNewTextSplitter_TableChunksKeepHeader(t *testing.T) {
+	// The table resource-service renders from a CSV file
+	var table strings.Builder
+	table.WriteString("| city | country | population |\n| --- | --- | --- |\n")
+	for range 50 {
+		table.WriteString("| Berlin | Germany | 3850809 |\n")
+	}
+	storage := &VectorStorage{cfg: &Config{}}
+
+	chunks, err := storage.newTextSplitter(models.Resource{}).SplitText(table.String())
+
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.True(t, strings.HasPrefix(chunk, "| city | country | population |\n| --- | --- | --- |\n"), chunk)
+		assert.Contains(t, chunk, "| Berlin | Germany | 3850809 |")
+	}
+}
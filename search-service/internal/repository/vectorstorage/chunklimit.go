@@ -0,0 +1,141 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// Policies applied to resources producing more chunks than MaxChunksPerResource
+const (
+	ChunkLimitPolicyReject    = "reject"
+	ChunkLimitPolicyTruncate  = "truncate"
+	ChunkLimitPolicySummarize = "summarize"
+)
+
+// defaultSummarizeConcurrency is how many chunk groups are summarized at once
+// when Config.SummarizeConcurrency is unset
+const defaultSummarizeConcurrency = 4
+
+const summarizeChunksPrompt = `Summarize the following part of a document. Keep names, numbers and
+terms that someone may search for. Answer with the summary only.
+
+%s`
+
+// applyChunkLimit enforces the chunk cap on the chunks of a single resource.
+// Resources within the cap are returned as is with a nil report. Otherwise the
+// resource is rejected, cut to its first chunks, or consecutive chunks are
+// merged and summarized by the generator so that one summary replaces each group.
+func (s *VectorStorage) applyChunkLimit(ctx context.Context, docs []schema.Document) ([]schema.Document, *models.ChunkLimitReport, error) {
+	limit := s.cfg.MaxChunksPerResource
+	if limit <= 0 || len(docs) <= limit {
+		return docs, nil, nil
+	}
+
+	policy := s.cfg.ChunkLimitPolicy
+	if policy == "" {
+		policy = ChunkLimitPolicyReject
+	}
+
+	slog.WarnContext(ctx, "Resource exceeds chunk limit",
+		"chunks_count", len(docs),
+		"limit", limit,
+		"policy", policy)
+
+	var limited []schema.Document
+	switch policy {
+	case ChunkLimitPolicyTruncate:
+		limited = docs[:limit]
+	case ChunkLimitPolicySummarize:
+		summaries, err := s.summarizeChunks(ctx, docs, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		limited = summaries
+	default:
+		return nil, nil, fmt.Errorf("%w: %d chunks, limit is %d", models.ErrTooManyChunks, len(docs), limit)
+	}
+
+	return limited, &models.ChunkLimitReport{
+		Policy:   policy,
+		Limit:    limit,
+		Produced: len(docs),
+		Stored:   len(limited),
+	}, nil
+}
+
+// summarizeChunks splits docs into at most limit groups of consecutive chunks
+// and returns one summary chunk per group, preserving document order. Groups
+// are summarized concurrently up to Config.SummarizeConcurrency, and a summary
+// spans the offsets of the chunks it replaces, see locateChunks.
+func (s *VectorStorage) summarizeChunks(ctx context.Context, docs []schema.Document, limit int) ([]schema.Document, error) {
+	const op = "VectorStorage.summarizeChunks"
+
+	groupSize := (len(docs) + limit - 1) / limit
+	summaries := make([]schema.Document, (len(docs)+groupSize-1)/groupSize)
+
+	concurrency := s.cfg.SummarizeConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultSummarizeConcurrency
+	}
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(concurrency)
+
+	for i := range summaries {
+		start := i * groupSize
+		end := min(start+groupSize, len(docs))
+		group := docs[start:end]
+
+		eg.Go(func() error {
+			parts := make([]string, 0, len(group))
+			for _, doc := range group {
+				parts = append(parts, doc.PageContent)
+			}
+
+			summary, err := llms.GenerateFromSinglePrompt(egCtx, s.generator,
+				fmt.Sprintf(summarizeChunksPrompt, strings.Join(parts, "\n\n")))
+			if err != nil {
+				return fmt.Errorf("%s: failed to summarize chunks %d-%d: %w", op, start, end-1, err)
+			}
+
+			groupStart, groupEnd := groupOffsets(group)
+			summaries[i] = schema.Document{
+				PageContent: strings.TrimSpace(summary),
+				Metadata: map[string]any{
+					chunkStartOffsetKey: groupStart,
+					chunkEndOffsetKey:   groupEnd,
+				},
+			}
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// groupOffsets returns the span of text covered by the located chunks of the
+// group, or -1 offsets when none of them was located
+func groupOffsets(group []schema.Document) (int, int) {
+	start, end := -1, -1
+	for _, doc := range group {
+		docStart, docEnd, ok := chunkOffsets(doc)
+		if !ok || docStart < 0 {
+			continue
+		}
+		if start < 0 {
+			start = docStart
+		}
+		end = max(end, docEnd)
+	}
+	return start, end
+}
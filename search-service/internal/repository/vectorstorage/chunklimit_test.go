@@ -0,0 +1,119 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+func newChunks(n int) []schema.Document {
+	docs := make([]schema.Document, n)
+	for i := range docs {
+		docs[i] = schema.Document{PageContent: fmt.Sprintf("chunk %d", i)}
+	}
+	return docs
+}
+
+func TestApplyChunkLimit_WithinLimit(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{MaxChunksPerResource: 5, ChunkLimitPolicy: ChunkLimitPolicyReject}}
+	docs := newChunks(5)
+
+	limited, report, err := storage.applyChunkLimit(context.Background(), docs)
+
+	require.NoError(t, err)
+	assert.Nil(t, report)
+	assert.Equal(t, docs, limited)
+}
+
+func TestApplyChunkLimit_Disabled(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{ChunkLimitPolicy: ChunkLimitPolicyReject}}
+
+	limited, report, err := storage.applyChunkLimit(context.Background(), newChunks(100))
+
+	require.NoError(t, err)
+	assert.Nil(t, report)
+	assert.Len(t, limited, 100)
+}
+
+func TestApplyChunkLimit_Reject(t *testing.T) {
+	for _, policy := range []string{ChunkLimitPolicyReject, ""} {
+		storage := &VectorStorage{cfg: &Config{MaxChunksPerResource: 3, ChunkLimitPolicy: policy}}
+
+		_, _, err := storage.applyChunkLimit(context.Background(), newChunks(4))
+
+		assert.ErrorIs(t, err, models.ErrTooManyChunks)
+		assert.ErrorContains(t, err, "4 chunks, limit is 3")
+	}
+}
+
+func TestApplyChunkLimit_Truncate(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{MaxChunksPerResource: 3, ChunkLimitPolicy: ChunkLimitPolicyTruncate}}
+	docs := newChunks(10)
+
+	limited, report, err := storage.applyChunkLimit(context.Background(), docs)
+
+	require.NoError(t, err)
+	assert.Equal(t, docs[:3], limited)
+	assert.Equal(t, &models.ChunkLimitReport{Policy: "truncate", Limit: 3, Produced: 10, Stored: 3}, report)
+}
+
+func TestApplyChunkLimit_Summarize(t *testing.T) {
+	generator := &summarizingLLM{}
+	storage := &VectorStorage{
+		generator: generator,
+		cfg: &Config{
+			MaxChunksPerResource: 3,
+			ChunkLimitPolicy:     ChunkLimitPolicySummarize,
+			SummarizeConcurrency: 2,
+		},
+	}
+
+	docs := newChunks(7)
+	parts := make([]string, len(docs))
+	for i, doc := range docs {
+		parts[i] = doc.PageContent
+	}
+	text := strings.Join(parts, "\n\n")
+	locateChunks(text, docs)
+
+	// 7 chunks over a cap of 3 are merged in groups of 3, 3 and 1
+	limited, report, err := storage.applyChunkLimit(context.Background(), docs)
+
+	require.NoError(t, err)
+	require.Len(t, limited, 3)
+	for i, want := range []struct {
+		summary string
+		source  string
+	}{
+		{"About chunk 2.", "chunk 0\n\nchunk 1\n\nchunk 2"},
+		{"About chunk 5.", "chunk 3\n\nchunk 4\n\nchunk 5"},
+		{"About chunk 6.", "chunk 6"},
+	} {
+		assert.Equal(t, want.summary, limited[i].PageContent)
+		// A summary spans the text of the chunks it replaces
+		start, end, ok := chunkOffsets(limited[i])
+		require.True(t, ok)
+		assert.Equal(t, want.source, text[start:end])
+	}
+	assert.Equal(t, &models.ChunkLimitReport{Policy: "summarize", Limit: 3, Produced: 7, Stored: 3}, report)
+	assert.LessOrEqual(t, generator.peak, 2)
+}
+
+func TestApplyChunkLimit_SummarizeFailure(t *testing.T) {
+	storage := &VectorStorage{
+		generator: &summarizingLLM{},
+		cfg:       &Config{MaxChunksPerResource: 2, ChunkLimitPolicy: ChunkLimitPolicySummarize},
+	}
+	docs := []schema.Document{{PageContent: "a"}, {PageContent: "b"}, {PageContent: "c"}, {PageContent: "fail"}}
+
+	_, _, err := storage.applyChunkLimit(context.Background(), docs)
+
+	assert.ErrorContains(t, err, "failed to summarize chunks 2-3")
+}
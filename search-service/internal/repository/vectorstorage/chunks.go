@@ -0,0 +1,365 @@
+package vectorstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+const (
+	chunkIndexKey       = "chunk_index"
+	chunkStartOffsetKey = "start_offset"
+	chunkEndOffsetKey   = "end_offset"
+	chunkHashKey        = "chunk_hash"
+	createdAtKey        = "created_at"
+	resourceNameKey     = "resource_name"
+	resourceURLKey      = "resource_url"
+	visibilityKey       = "visibility"
+)
+
+// locateChunks records the offsets of the split documents in the text they
+// were split from, before duplicates are dropped and chunks over the cap are
+// merged, so that every stored chunk keeps the span of its source text
+func locateChunks(text string, docs []schema.Document) {
+	cursor := 0
+	for i := range docs {
+		start, end := locateChunk(text, docs[i].PageContent, cursor)
+		if start >= 0 {
+			// Chunks may overlap, so the next one can start before this one ends
+			cursor = start + 1
+		}
+
+		// The splitter shares one metadata map between the documents of a text
+		docs[i].Metadata = map[string]any{
+			chunkStartOffsetKey: start,
+			chunkEndOffsetKey:   end,
+		}
+	}
+}
+
+// chunkOffsets returns the offsets recorded on the document by locateChunks
+func chunkOffsets(doc schema.Document) (int, int, bool) {
+	start, startOK := doc.Metadata[chunkStartOffsetKey].(int)
+	end, endOK := doc.Metadata[chunkEndOffsetKey].(int)
+	return start, end, startOK && endOK
+}
+
+// annotateChunks sets ownership, position, offset, content hash and, when known,
+// resource creation time metadata on split documents. Documents without offsets
+// recorded by locateChunks are located in the text; a chunk that cannot be
+// found keeps -1 offsets instead of failing the indexation.
+func annotateChunks(text string, docs []schema.Document, userID string, resourceID uuid.UUID, createdAt time.Time) {
+	cursor := 0
+	for i := range docs {
+		start, end, ok := chunkOffsets(docs[i])
+		if !ok {
+			start, end = locateChunk(text, docs[i].PageContent, cursor)
+		}
+		if start >= 0 {
+			// Chunks may overlap, so the next one can start before this one ends
+			cursor = start + 1
+		}
+
+		docs[i].Metadata = map[string]any{
+			userIDFilter:        userID,
+			resourceIdFilter:    resourceID.String(),
+			chunkIndexKey:       i,
+			chunkStartOffsetKey: start,
+			chunkEndOffsetKey:   end,
+			chunkHashKey:        hashChunk(docs[i].PageContent),
+		}
+		if !createdAt.IsZero() {
+			docs[i].Metadata[createdAtKey] = createdAt.UTC().Format(time.RFC3339)
+		}
+	}
+}
+
+// labelChunks sets the name and, when known, the URL of the resource on its
+// chunks, which GetResourcesByIDs reads back for the references
+func labelChunks(docs []schema.Document, name, url string) {
+	for i := range docs {
+		if name != "" {
+			docs[i].Metadata[resourceNameKey] = name
+		}
+		if url != "" {
+			docs[i].Metadata[resourceURLKey] = url
+		}
+	}
+}
+
+// markVisibility records the visibility of the resource on its chunks. Chunks
+// without one, e.g. indexed before it was recorded, are private.
+func markVisibility(docs []schema.Document, visibility models.ResourceVisibility) {
+	if visibility == "" {
+		return
+	}
+	for i := range docs {
+		docs[i].Metadata[visibilityKey] = string(visibility)
+	}
+}
+
+// hashChunk returns the hex encoded SHA-256 of the chunk content. resource-service
+// hashes the chunks of edited content the same way to find the changed ones.
+func hashChunk(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkHashes returns the content hashes stored in the metadata of the documents
+func chunkHashes(docs []schema.Document) []string {
+	hashes := make([]string, len(docs))
+	for i, doc := range docs {
+		hashes[i], _ = doc.Metadata[chunkHashKey].(string)
+	}
+	return hashes
+}
+
+// locateChunk finds the byte span of content in text starting at from. The
+// markdown splitter collapses whitespace between lines, so any whitespace run
+// in the chunk matches any whitespace run in the text.
+func locateChunk(text, content string, from int) (int, int) {
+	words := strings.Fields(content)
+	if len(words) == 0 {
+		return -1, -1
+	}
+
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+
+	re, err := regexp.Compile(strings.Join(words, `\s+`))
+	if err != nil {
+		return -1, -1
+	}
+
+	loc := re.FindStringIndex(text[from:])
+	if loc == nil {
+		return -1, -1
+	}
+
+	return from + loc[0], from + loc[1]
+}
+
+// GetResourceChunks returns a page of the caller's chunks of the resource in indexation order
+// together with the total number of chunks.
+func (s *VectorStorage) GetResourceChunks(ctx context.Context, resourceID uuid.UUID, limit, offset int) ([]models.Chunk, int, error) {
+	const op = "VectorStorage.GetResourceChunks"
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// The metadata chunk is not part of the content
+	countQuery := fmt.Sprintf("SELECT count(*) FROM %s WHERE cmetadata ->> '%s' = $1 AND cmetadata ->> '%s' = $2 AND NOT (cmetadata ? '%s')",
+		embeddingTableName, resourceIdFilter, userIDFilter, metadataChunkKey)
+
+	var total int
+	if err := s.pool.QueryRow(ctx, countQuery, resourceID.String(), userID).Scan(&total); err != nil {
+		slog.ErrorContext(ctx, "Failed to count resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Chunks indexed before positions were recorded have no chunk_index and come last
+	query := fmt.Sprintf(`SELECT uuid, document, cmetadata FROM %s
+		WHERE cmetadata ->> '%s' = $1 AND cmetadata ->> '%s' = $2 AND NOT (cmetadata ? '%s')
+		ORDER BY (cmetadata ->> '%s')::int NULLS LAST, uuid
+		LIMIT $3 OFFSET $4`,
+		embeddingTableName, resourceIdFilter, userIDFilter, metadataChunkKey, chunkIndexKey)
+
+	rows, err := s.pool.Query(ctx, query, resourceID.String(), userID, limit, offset)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to query resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	chunks := make([]models.Chunk, 0, limit)
+	for rows.Next() {
+		var (
+			id       uuid.UUID
+			document string
+			metadata map[string]any
+		)
+		if err := rows.Scan(&id, &document, &metadata); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		chunks = append(chunks, chunkFromRow(id, resourceID, document, metadata))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return chunks, total, nil
+}
+
+// chunkFromRow converts a stored embedding row into a chunk. Position metadata is
+// lifted into dedicated fields; ownership metadata is not exposed.
+func chunkFromRow(id uuid.UUID, resourceID uuid.UUID, document string, metadata map[string]any) models.Chunk {
+	chunk := models.Chunk{
+		ID:          id,
+		ResourceID:  resourceID,
+		Index:       -1,
+		Content:     document,
+		StartOffset: -1,
+		EndOffset:   -1,
+	}
+
+	rest := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		switch key {
+		case chunkIndexKey:
+			chunk.Index = metadataInt(value)
+		case chunkStartOffsetKey:
+			chunk.StartOffset = metadataInt(value)
+		case chunkEndOffsetKey:
+			chunk.EndOffset = metadataInt(value)
+		case userIDFilter, resourceIdFilter, chunkHashKey, resourceNameKey, resourceURLKey, visibilityKey:
+		default:
+			rest[key] = value
+		}
+	}
+	if len(rest) > 0 {
+		chunk.Metadata = rest
+	}
+
+	return chunk
+}
+
+// metadataInt reads an integer from JSONB metadata, which decodes numbers as float64
+func metadataInt(value any) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return -1
+	}
+}
+
+// GetIndexUsage returns what each indexed resource of the caller takes up, the
+// largest first. Storage is the size of the stored values, without the index.
+func (s *VectorStorage) GetIndexUsage(ctx context.Context) ([]models.ResourceUsage, error) {
+	const op = "VectorStorage.GetIndexUsage"
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := fmt.Sprintf(`SELECT cmetadata ->> '%s' AS resource_id,
+			count(*) FILTER (WHERE NOT (cmetadata ? '%s')),
+			coalesce(sum(pg_column_size(embedding) + pg_column_size(document) + pg_column_size(cmetadata)), 0)::bigint AS storage
+		FROM %s
+		WHERE cmetadata ->> '%s' = $1
+		GROUP BY 1
+		ORDER BY storage DESC, 1`,
+		resourceIdFilter, metadataChunkKey, embeddingTableName, userIDFilter)
+
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to query index usage",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var usage []models.ResourceUsage
+	for rows.Next() {
+		var (
+			resourceID *string
+			row        models.ResourceUsage
+		)
+		if err := rows.Scan(&resourceID, &row.Chunks, &row.StorageBytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if resourceID == nil {
+			continue
+		}
+		if row.ResourceID, err = uuid.Parse(*resourceID); err != nil {
+			slog.WarnContext(ctx, "Skipping chunks with invalid resource id",
+				"op", op,
+				"resource_id", *resourceID)
+			continue
+		}
+		usage = append(usage, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return usage, nil
+}
+
+// GetResourcesByIDs returns the name and URL of the resources among resourceIDs
+// the caller owns or that are shared, read from their chunks in a single query. Resources that are
+// not indexed, e.g. because they were deleted, or were indexed before names
+// were stored are missing from the result.
+func (s *VectorStorage) GetResourcesByIDs(ctx context.Context, resourceIDs []uuid.UUID) (map[uuid.UUID]models.Resource, error) {
+	const op = "VectorStorage.GetResourcesByIDs"
+
+	resources := make(map[uuid.UUID]models.Resource, len(resourceIDs))
+	if len(resourceIDs) == 0 {
+		return resources, nil
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ids := make([]string, len(resourceIDs))
+	for i, id := range resourceIDs {
+		ids[i] = id.String()
+	}
+
+	query := fmt.Sprintf(`SELECT DISTINCT ON (cmetadata ->> '%[1]s') cmetadata ->> '%[1]s', cmetadata ->> '%[2]s', coalesce(cmetadata ->> '%[3]s', '')
+		FROM %[4]s
+		WHERE cmetadata ->> '%[1]s' = ANY($1) AND %[5]s AND cmetadata ? '%[2]s'
+		ORDER BY cmetadata ->> '%[1]s'`,
+		resourceIdFilter, resourceNameKey, resourceURLKey, embeddingTableName, visibleCondition("cmetadata", 2))
+
+	rows, err := s.pool.Query(ctx, query, ids, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to query resources",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name, url string
+		if err := rows.Scan(&id, &name, &url); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		resourceID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		resources[resourceID] = models.Resource{ID: resourceID, Name: name, URL: url}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return resources, nil
+}
@@ -0,0 +1,124 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/documentloaders"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/textsplitter"
+)
+
+func TestAnnotateChunks_OffsetsMatchIndexedContent(t *testing.T) {
+	var builder strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&builder, "# Section %d\n\nParagraph %d  about retrieval. ![img](a.png)\n\n", i, i)
+	}
+	text := clearText(builder.String())
+	docs, err := documentloaders.NewText(strings.NewReader(text)).
+		LoadAndSplit(context.Background(), textsplitter.NewMarkdownTextSplitter())
+	require.NoError(t, err)
+	require.Greater(t, len(docs), 1)
+
+	resourceID := uuid.New()
+	annotateChunks(text, docs, "user", resourceID, time.Time{})
+
+	for i, doc := range docs {
+		assert.Equal(t, "user", doc.Metadata[userIDFilter])
+		assert.Equal(t, resourceID.String(), doc.Metadata[resourceIdFilter])
+		assert.Equal(t, i, doc.Metadata[chunkIndexKey])
+		assert.Equal(t, hashChunk(doc.PageContent), doc.Metadata[chunkHashKey])
+
+		start := doc.Metadata[chunkStartOffsetKey].(int)
+		end := doc.Metadata[chunkEndOffsetKey].(int)
+		require.GreaterOrEqual(t, start, 0, "chunk %d was not located", i)
+		assert.Equal(t, strings.Fields(doc.PageContent), strings.Fields(text[start:end]))
+	}
+}
+
+func TestAnnotateChunks_UnlocatedChunk(t *testing.T) {
+	docs := []schema.Document{{PageContent: "missing"}}
+
+	annotateChunks("some text", docs, "user", uuid.New(), time.Time{})
+
+	assert.Equal(t, -1, docs[0].Metadata[chunkStartOffsetKey])
+	assert.Equal(t, -1, docs[0].Metadata[chunkEndOffsetKey])
+}
+
+func TestAnnotateChunks_KeepsLocatedOffsets(t *testing.T) {
+	text := "first chunk\n\nsecond chunk"
+	// A summary replacing both chunks is not found in the text but spans them
+	docs := []schema.Document{{
+		PageContent: "both chunks",
+		Metadata:    map[string]any{chunkStartOffsetKey: 0, chunkEndOffsetKey: len(text)},
+	}}
+
+	annotateChunks(text, docs, "user", uuid.New(), time.Time{})
+
+	assert.Equal(t, 0, docs[0].Metadata[chunkStartOffsetKey])
+	assert.Equal(t, len(text), docs[0].Metadata[chunkEndOffsetKey])
+	assert.Equal(t, "user", docs[0].Metadata[userIDFilter])
+}
+
+func TestAnnotateChunks_CreatedAtReadBackByReferences(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	docs := []schema.Document{{PageContent: "some text"}}
+
+	annotateChunks("some text", docs, "user", uuid.New(), createdAt)
+	refs := parseReferences(docs)
+
+	assert.Equal(t, "2026-03-01T08:30:00Z", docs[0].Metadata[createdAtKey])
+	assert.True(t, createdAt.Equal(refs[0].CreatedAt))
+}
+
+func TestLabelChunks(t *testing.T) {
+	docs := []schema.Document{{Metadata: map[string]any{}}, {Metadata: map[string]any{}}}
+
+	labelChunks(docs, "Article", "")
+
+	for _, doc := range docs {
+		assert.Equal(t, "Article", doc.Metadata[resourceNameKey])
+		assert.NotContains(t, doc.Metadata, resourceURLKey, "uploaded files have no URL")
+	}
+}
+
+func TestChunkFromRow(t *testing.T) {
+	id := uuid.New()
+	resourceID := uuid.New()
+	// JSONB numbers are decoded as float64
+	metadata := map[string]any{
+		userIDFilter:        "user",
+		resourceIdFilter:    resourceID.String(),
+		chunkIndexKey:       float64(3),
+		chunkStartOffsetKey: float64(120),
+		chunkEndOffsetKey:   float64(180),
+		resourceNameKey:     "Article",
+		resourceURLKey:      "https://example.com/article",
+		"page":              float64(2),
+	}
+
+	chunk := chunkFromRow(id, resourceID, "content", metadata)
+
+	assert.Equal(t, id, chunk.ID)
+	assert.Equal(t, resourceID, chunk.ResourceID)
+	assert.Equal(t, "content", chunk.Content)
+	assert.Equal(t, 3, chunk.Index)
+	assert.Equal(t, 120, chunk.StartOffset)
+	assert.Equal(t, 180, chunk.EndOffset)
+	assert.Equal(t, map[string]any{"page": float64(2)}, chunk.Metadata)
+}
+
+func TestChunkFromRow_LegacyChunk(t *testing.T) {
+	chunk := chunkFromRow(uuid.New(), uuid.New(), "content", map[string]any{userIDFilter: "user"})
+
+	assert.Equal(t, -1, chunk.Index)
+	assert.Equal(t, -1, chunk.StartOffset)
+	assert.Equal(t, -1, chunk.EndOffset)
+	assert.Nil(t, chunk.Metadata)
+}
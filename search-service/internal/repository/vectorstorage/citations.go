@@ -0,0 +1,38 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+)
+
+// numberedRetriever prefixes every retrieved document with its citation
+// marker, so the model can cite it. Documents are numbered from 1 in
+// retrieval order, the same order the references are reported in.
+// The references themselves are parsed by the wrapped retriever's callbacks
+// and keep the original content. Only the first contextSize documents are
+// given to the model, all of them when it is 0.
+type numberedRetriever struct {
+	retriever   schema.Retriever
+	contextSize int
+}
+
+var _ schema.Retriever = numberedRetriever{}
+
+func (r numberedRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	docs, err := r.retriever.GetRelevantDocuments(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if r.contextSize > 0 && len(docs) > r.contextSize {
+		docs = docs[:r.contextSize]
+	}
+
+	numbered := make([]schema.Document, len(docs))
+	for i, doc := range docs {
+		doc.PageContent = fmt.Sprintf("[%d] %s", i+1, doc.PageContent)
+		numbered[i] = doc
+	}
+	return numbered, nil
+}
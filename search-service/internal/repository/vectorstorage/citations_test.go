@@ -0,0 +1,76 @@
+package vectorstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// promptRecorder answers with a fixed text and keeps the prompt it was given
+type promptRecorder struct {
+	answer  string
+	prompt  string
+	options llms.CallOptions
+}
+
+func (m *promptRecorder) GenerateContent(_ context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.options = llms.CallOptions{}
+	for _, opt := range options {
+		opt(&m.options)
+	}
+
+	var prompt strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt.WriteString(text.Text)
+			}
+		}
+	}
+	m.prompt = prompt.String()
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.answer}}}, nil
+}
+
+func (m *promptRecorder) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestGetAnswer_CitationMarkersFollowRetrievalOrder(t *testing.T) {
+	firstID, secondID := uuid.New(), uuid.New()
+	generator := &promptRecorder{answer: "Goroutines are cheap [2]. Channels connect them [1]. Unknown [7]."}
+	storage := &VectorStorage{
+		vectorStore: staticStore{docs: []schema.Document{
+			{PageContent: "Channels connect goroutines.", Metadata: map[string]any{resourceIdFilter: firstID.String(), userIDFilter: "user"}},
+			{PageContent: "Goroutines are cheap.", Metadata: map[string]any{resourceIdFilter: secondID.String(), userIDFilter: "user"}},
+		}},
+		generator: generator,
+		cfg:       &Config{NumOfResults: 2, MaxTokens: 100},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	answer, refs, err := storage.GetAnswer(ctx, "question")
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+
+	// The model sees the documents numbered, the references keep their content
+	assert.Contains(t, generator.prompt, "[1] Channels connect goroutines.")
+	assert.Contains(t, generator.prompt, "[2] Goroutines are cheap.")
+	assert.Equal(t, "Channels connect goroutines.", refs[0].Content)
+
+	citations := models.ParseCitations(answer, refs)
+	require.Len(t, citations, 2)
+	assert.Equal(t, 2, citations[0].Marker)
+	assert.Equal(t, secondID, citations[0].Reference.ResourceID)
+	assert.Equal(t, 1, citations[1].Marker)
+	assert.Equal(t, firstID, citations[1].Reference.ResourceID)
+}
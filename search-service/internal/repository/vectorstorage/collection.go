@@ -0,0 +1,129 @@
+package vectorstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/pgvector"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+)
+
+// Collection modes select which pgvector collection a request reads and writes.
+//
+// In the shared mode every user's chunks live in one collection and are kept
+// apart by the user_id metadata filter only. It needs no setup and works for
+// background indexing, where no token is around.
+//
+// The tenant and user modes give each tenant, or each user, a collection of
+// its own. Searches of a large tenant then only scan its rows, and a tenant
+// can be dropped or moved by its collection. The user_id filter still applies
+// inside a collection. Requests missing the tenant or user are rejected rather
+// than falling back to the shared collection, and chunks indexed before the
+// mode was switched stay in the shared collection until reindexed.
+const (
+	CollectionModeShared = "shared"
+	CollectionModeTenant = "tenant"
+	CollectionModeUser   = "user"
+)
+
+// ErrNoCollection is returned when the collection of a request cannot be
+// derived from its context
+var ErrNoCollection = errors.New("collection not found in context")
+
+// collectionName returns the collection of the request, empty in the shared mode
+func (s *VectorStorage) collectionName(ctx context.Context) (string, error) {
+	switch s.cfg.CollectionMode {
+	case CollectionModeTenant:
+		tenantID, ok := identity.TenantID(ctx)
+		if !ok || tenantID == "" {
+			return "", fmt.Errorf("%w: no tenant", ErrNoCollection)
+		}
+		return "tenant_" + tenantID, nil
+	case CollectionModeUser:
+		userID, ok := identity.UserID(ctx)
+		if !ok || userID == "" {
+			return "", fmt.Errorf("%w: no user", ErrNoCollection)
+		}
+		return "user_" + userID, nil
+	default:
+		return "", nil
+	}
+}
+
+// store returns the vector store of the request's collection, opening it on
+// first use. Opening creates the collection when it does not exist yet.
+func (s *VectorStorage) store(ctx context.Context) (vectorstores.VectorStore, error) {
+	const op = "VectorStorage.store"
+
+	name, err := s.collectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if name == "" {
+		return s.vectorStore, nil
+	}
+
+	s.storesMu.Lock()
+	defer s.storesMu.Unlock()
+
+	if store, ok := s.stores[name]; ok {
+		return store, nil
+	}
+
+	store, err := s.openStore(ctx, name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to open collection",
+			"op", op,
+			"collection", name,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if s.stores == nil {
+		s.stores = make(map[string]vectorstores.VectorStore)
+	}
+	s.stores[name] = store
+	slog.DebugContext(ctx, "Opened collection", "collection", name)
+	return store, nil
+}
+
+// openCollection opens a pgvector store on the named collection, or on the
+// default one when name is empty. Searches run the query of metricStore for
+// every metric, as the search scope needs more than langchaingo's filters.
+func (s *VectorStorage) openCollection(ctx context.Context, name string) (vectorstores.VectorStore, error) {
+	opts := []pgvector.Option{
+		pgvector.WithCollectionTableName("collections"),
+		pgvector.WithEmbeddingTableName(embeddingTableName),
+		pgvector.WithPreDeleteCollection(false),
+		pgvector.WithVectorDimensions(s.cfg.EmbeddingDimensions),
+		pgvector.WithEmbedder(s.embedder),
+		pgvector.WithConn(s.pool),
+	}
+	if name != "" {
+		opts = append(opts, pgvector.WithCollectionName(name))
+	}
+
+	store, err := pgvector.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	metric := s.cfg.DistanceMetric
+	if metric == "" {
+		metric = DistanceMetricCosine
+	}
+	if name == "" {
+		name = pgvector.DefaultCollectionName
+	}
+	return metricStore{
+		VectorStore: &store,
+		conn:        s.pool,
+		embedder:    s.embedder,
+		collection:  name,
+		metric:      metric,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package vectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+)
+
+// newCollectionStorage returns a storage in the mode whose opened collections
+// are recorded in opened
+func newCollectionStorage(mode string, opened *[]string) *VectorStorage {
+	return &VectorStorage{
+		vectorStore: staticStore{docs: []schema.Document{{PageContent: "shared"}}},
+		cfg:         &Config{CollectionMode: mode},
+		openStore: func(_ context.Context, name string) (vectorstores.VectorStore, error) {
+			*opened = append(*opened, name)
+			return staticStore{docs: []schema.Document{{PageContent: name}}}, nil
+		},
+	}
+}
+
+func TestStore_SharedByDefault(t *testing.T) {
+	var opened []string
+	storage := newCollectionStorage("", &opened)
+	ctx := context.WithValue(context.Background(), identity.TenantIDKey, "acme")
+
+	store, err := storage.store(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, storage.vectorStore, store)
+	assert.Empty(t, opened)
+}
+
+func TestStore_OpenedOncePerCollection(t *testing.T) {
+	tests := []struct {
+		mode     string
+		key      string
+		expected string
+	}{
+		{mode: CollectionModeTenant, key: identity.TenantIDKey, expected: "tenant_acme"},
+		{mode: CollectionModeUser, key: identity.UserIDKey, expected: "user_acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var opened []string
+			storage := newCollectionStorage(tt.mode, &opened)
+			ctx := context.WithValue(context.Background(), tt.key, "acme")
+
+			first, err := storage.store(ctx)
+			require.NoError(t, err)
+			second, err := storage.store(ctx)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{tt.expected}, opened)
+			assert.Equal(t, first, second)
+			assert.NotEqual(t, storage.vectorStore, first)
+		})
+	}
+}
+
+func TestStore_MissingTenantRejected(t *testing.T) {
+	var opened []string
+	storage := newCollectionStorage(CollectionModeTenant, &opened)
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, err := storage.store(ctx)
+
+	assert.ErrorIs(t, err, ErrNoCollection)
+	assert.Empty(t, opened)
+}
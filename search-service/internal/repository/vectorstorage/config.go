@@ -11,8 +11,53 @@ type Config struct {
 	NumOfResults        int `yaml:"num_of_results" mapstructure:"num_of_results"`
 	MaxTokens           int `yaml:"max_tokens" mapstructure:"max_tokens"`
 	EmbeddingDimensions int `yaml:"embedding_dimensions" mapstructure:"embedding_dimensions"`
+	// Temperature is the default sampling temperature, the model's own default is used when unset
+	Temperature *float64 `yaml:"temperature" mapstructure:"temperature" validate:"omitempty,min=0,max=2"`
+	// ScoreThresholds holds the default minimum chunk score per search mode
+	ScoreThresholds map[string]float64 `yaml:"score_thresholds" mapstructure:"score_thresholds" validate:"dive,min=0,max=1"`
+	// RetrieverK is the number of chunks retrieved as answer context, NumOfResults when unset
+	RetrieverK int `yaml:"retriever_k" mapstructure:"retriever_k" validate:"min=0"`
+	// RetrieverScoreThreshold is the minimum score of answer context chunks, the search mode threshold when unset
+	RetrieverScoreThreshold *float64 `yaml:"retriever_score_threshold" mapstructure:"retriever_score_threshold" validate:"omitempty,min=0,max=1"`
+	// MaxChunksPerResource caps the chunks stored for one resource, 0 disables the cap
+	MaxChunksPerResource int `yaml:"max_chunks_per_resource" mapstructure:"max_chunks_per_resource" validate:"min=0"`
+	// ChunkLimitPolicy selects how resources over the cap are handled: reject, truncate or summarize
+	ChunkLimitPolicy string `yaml:"chunk_limit_policy" mapstructure:"chunk_limit_policy" validate:"omitempty,oneof=reject truncate summarize"`
+	// SummarizeConcurrency is how many chunk groups the summarize policy
+	// summarizes at once, defaultSummarizeConcurrency when unset
+	SummarizeConcurrency int `yaml:"summarize_concurrency" mapstructure:"summarize_concurrency" validate:"min=0"`
+	// ChunkSize is the maximum chunk length in characters, 512 when unset
+	ChunkSize int `yaml:"chunk_size" mapstructure:"chunk_size" validate:"min=0"`
+	// ChunkOverlap is the number of characters shared by neighbouring chunks, 100 when unset
+	ChunkOverlap *int `yaml:"chunk_overlap" mapstructure:"chunk_overlap" validate:"omitempty,min=0"`
+	// NearDuplicateThreshold drops the chunks of a resource whose embedding is
+	// at least this cosine similar to an earlier chunk, 0 drops exact duplicates only
+	NearDuplicateThreshold float64 `yaml:"near_duplicate_threshold" mapstructure:"near_duplicate_threshold" validate:"min=0,max=1"`
+	// CollectionMode selects the collection of a request: shared (default),
+	// tenant or user, see CollectionModeShared for the tradeoffs
+	CollectionMode string `yaml:"collection_mode" mapstructure:"collection_mode" validate:"omitempty,oneof=shared tenant user"`
+	// EmbedMetadata indexes the name and URL of every resource as an extra chunk,
+	// so that searching by title finds the resource
+	EmbedMetadata bool `yaml:"embed_metadata" mapstructure:"embed_metadata"`
+	// DistanceMetric compares chunks with the query: cosine (default), l2 or
+	// inner_product, see DistanceMetricCosine for which models suit each
+	DistanceMetric string `yaml:"distance_metric" mapstructure:"distance_metric" validate:"omitempty,oneof=cosine l2 inner_product"`
+	// NoDocumentsAnswer is the answer given, without calling the model, when no
+	// chunk is relevant to the question; defaultNoDocumentsAnswer when unset
+	NoDocumentsAnswer string `yaml:"no_documents_answer" mapstructure:"no_documents_answer"`
+	// SystemPrompt is prepended to the QA prompt to set the persona of the
+	// answers, e.g. tone, domain constraints or refusal policy
+	SystemPrompt string `yaml:"system_prompt" mapstructure:"system_prompt"`
+	// SystemPromptMaxTokens is the token budget of system prompts, configured
+	// or per request; defaultSystemPromptMaxTokens when unset
+	SystemPromptMaxTokens int `yaml:"system_prompt_max_tokens" mapstructure:"system_prompt_max_tokens" validate:"min=0"`
+	// Summary configures the summaries of semantic search references, which
+	// cost a generation per reference and are only made on request
+	Summary SummaryConfig `yaml:"summary" mapstructure:"summary"`
 }
 
+const defaultNoDocumentsAnswer = "No relevant documents found."
+
 // NewConfig loads vector storage configuration from config file
 func NewConfig() (*Config, error) {
 	// Set defaults
@@ -24,6 +69,14 @@ func NewConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse vector storage config: %w", err)
 	}
 
+	// A persona can be set per deployment without a config change
+	if systemPrompt := configurator.GetString("SEARCH_SYSTEM_PROMPT"); systemPrompt != "" {
+		config.SystemPrompt = systemPrompt
+	}
+	if err := checkSystemPrompt(config.SystemPrompt, config.SystemPromptMaxTokens); err != nil {
+		return nil, fmt.Errorf("invalid vector storage config: %w", err)
+	}
+
 	return config, nil
 }
 
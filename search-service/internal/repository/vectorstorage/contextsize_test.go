@@ -0,0 +1,95 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// rankedStore returns the first k of its documents, most relevant first
+type rankedStore struct {
+	docs []schema.Document
+}
+
+func (s rankedStore) AddDocuments(context.Context, []schema.Document, ...vectorstores.Option) ([]string, error) {
+	return nil, nil
+}
+
+func (s rankedStore) SimilaritySearch(_ context.Context, _ string, k int, _ ...vectorstores.Option) ([]schema.Document, error) {
+	return s.docs[:min(k, len(s.docs))], nil
+}
+
+func newRankedStorage(generator *promptRecorder, n int) *VectorStorage {
+	docs := make([]schema.Document, n)
+	for i := range docs {
+		docs[i] = schema.Document{
+			PageContent: fmt.Sprintf("chunk %d", i+1),
+			Metadata:    map[string]any{resourceIdFilter: uuid.NewString(), userIDFilter: "user"},
+		}
+	}
+	return &VectorStorage{
+		vectorStore: rankedStore{docs: docs},
+		generator:   generator,
+		cfg:         &Config{NumOfResults: 10, MaxTokens: 100},
+	}
+}
+
+func TestGetAnswer_ContextSmallerThanReferences(t *testing.T) {
+	generator := &promptRecorder{answer: "answer"}
+	storage := newRankedStorage(generator, 10)
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, refs, err := storage.GetAnswer(ctx, "question",
+		searchservice.WithNumberOfReferences(5),
+		searchservice.WithContextSize(2))
+	require.NoError(t, err)
+
+	// Every reference is returned, only the most relevant reach the model
+	require.Len(t, refs, 5)
+	assert.Contains(t, generator.prompt, "[1] chunk 1")
+	assert.Contains(t, generator.prompt, "[2] chunk 2")
+	assert.NotContains(t, generator.prompt, "chunk 3")
+}
+
+func TestGetAnswer_ContextClampedToReferences(t *testing.T) {
+	generator := &promptRecorder{answer: "answer"}
+	storage := newRankedStorage(generator, 10)
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, refs, err := storage.GetAnswer(ctx, "question",
+		searchservice.WithNumberOfReferences(3),
+		searchservice.WithContextSize(8))
+	require.NoError(t, err)
+
+	require.Len(t, refs, 3)
+	assert.Contains(t, generator.prompt, "[3] chunk 3")
+	assert.NotContains(t, generator.prompt, "chunk 4")
+}
+
+func TestContextSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		references  int
+		contextSize int
+		want        int
+	}{
+		{name: "unset uses all references", references: 5, want: 5},
+		{name: "smaller than references", references: 5, contextSize: 2, want: 2},
+		{name: "clamped to references", references: 5, contextSize: 8, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &searchservice.SearchOptions{NumberOfReferences: tt.references, ContextSize: tt.contextSize}
+			assert.Equal(t, tt.want, contextSize(options))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// dedupeChunks drops the chunks of a resource repeating an earlier one, such
+// as the headers and footers of every page, and returns the remaining chunks in
+// order with the number dropped. Chunks are duplicates when their text is the
+// same once case and whitespace are ignored or, with a near-duplicate threshold
+// configured, when their embeddings are at least that similar.
+func (s *VectorStorage) dedupeChunks(ctx context.Context, docs []schema.Document) ([]schema.Document, int, error) {
+	unique := dropDuplicateChunks(docs)
+
+	if s.cfg.NearDuplicateThreshold > 0 && len(unique) > 1 {
+		var err error
+		if unique, err = s.dropNearDuplicateChunks(ctx, unique); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	dropped := len(docs) - len(unique)
+	if dropped > 0 {
+		slog.DebugContext(ctx, "Dropped duplicate chunks",
+			"chunks_count", len(docs),
+			"dropped", dropped)
+	}
+	return unique, dropped, nil
+}
+
+// dropDuplicateChunks keeps the first of the chunks with the same normalized text
+func dropDuplicateChunks(docs []schema.Document) []schema.Document {
+	seen := make(map[string]struct{}, len(docs))
+	unique := make([]schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		hash := hashChunk(normalizeChunk(doc.PageContent))
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		unique = append(unique, doc)
+	}
+	return unique
+}
+
+// normalizeChunk lowercases the chunk and collapses its whitespace, so that
+// chunks differing only in line breaks or capitalization hash the same
+func normalizeChunk(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// dropNearDuplicateChunks keeps the chunks whose embedding is less similar than
+// the threshold to every earlier kept chunk. The chunks are embedded once more
+// when stored, so the threshold roughly doubles the embedding cost of indexing.
+func (s *VectorStorage) dropNearDuplicateChunks(ctx context.Context, docs []schema.Document) ([]schema.Document, error) {
+	const op = "VectorStorage.dropNearDuplicateChunks"
+
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = doc.PageContent
+	}
+
+	vectors, err := s.embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(vectors) != len(docs) {
+		return nil, fmt.Errorf("%s: got %d embeddings for %d chunks", op, len(vectors), len(docs))
+	}
+
+	kept := make([]int, 0, len(docs))
+	unique := make([]schema.Document, 0, len(docs))
+	for i, doc := range docs {
+		if nearDuplicate(vectors, kept, i, s.cfg.NearDuplicateThreshold) {
+			continue
+		}
+		kept = append(kept, i)
+		unique = append(unique, doc)
+	}
+	return unique, nil
+}
+
+// nearDuplicate reports whether vector i is at least threshold similar to one
+// of the kept vectors
+func nearDuplicate(vectors [][]float32, kept []int, i int, threshold float64) bool {
+	for _, k := range kept {
+		if models.CosineSimilarity(vectors[i], vectors[k]) >= threshold {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package vectorstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// topicEmbedder embeds texts mentioning a topic word as that topic's vector,
+// so that chunks on the same topic are near duplicates
+type topicEmbedder struct {
+	topics map[string][]float32
+}
+
+func (e topicEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{0, 0, 1}
+		for topic, vector := range e.topics {
+			if strings.Contains(text, topic) {
+				vectors[i] = vector
+			}
+		}
+	}
+	return vectors, nil
+}
+
+func (e topicEmbedder) EmbedQuery(context.Context, string) ([]float32, error) {
+	return []float32{0, 0, 1}, nil
+}
+
+func TestPutResource_DropsDuplicateParagraphs(t *testing.T) {
+	store := &keywordStore{}
+	overlap := 0
+	storage := &VectorStorage{
+		vectorStore: store,
+		cfg:         &Config{ChunkSize: 60, ChunkOverlap: &overlap},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	// Every section ends with the same footer, once in other case and spacing
+	resource := models.Resource{
+		ID: uuid.New(),
+		ExtractedContent: strings.Join([]string{
+			"Reconciliation loops drive the cluster state.",
+			"Confidential. Do not distribute outside the company.",
+			"Operators extend the API with custom resources.",
+			"Confidential. Do not distribute outside the company.",
+			"Controllers watch resources and act on changes.",
+			"CONFIDENTIAL.   Do not distribute outside the company.",
+		}, "\n\n"),
+	}
+
+	var dropped int
+	chunkIDs, err := storage.PutResource(ctx, resource, models.WithDuplicatesReport(func(n int) {
+		dropped = n
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, dropped)
+	require.Len(t, chunkIDs, 4)
+	require.Len(t, store.docs, 4)
+
+	var contents []string
+	for i, doc := range store.docs {
+		contents = append(contents, doc.PageContent)
+		// Positions are those of the stored chunks
+		assert.Equal(t, i, doc.Metadata[chunkIndexKey])
+	}
+	assert.Equal(t, []string{
+		"Reconciliation loops drive the cluster state.",
+		"Confidential. Do not distribute outside the company.",
+		"Operators extend the API with custom resources.",
+		"Controllers watch resources and act on changes.",
+	}, contents)
+}
+
+func TestPutResource_NoDuplicatesReported(t *testing.T) {
+	storage := &VectorStorage{vectorStore: &keywordStore{}, cfg: &Config{}}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	reported := false
+	_, err := storage.PutResource(ctx, models.Resource{ID: uuid.New(), ExtractedContent: "A single paragraph."},
+		models.WithDuplicatesReport(func(int) { reported = true }))
+
+	require.NoError(t, err)
+	assert.False(t, reported)
+}
+
+func TestDedupeChunks_NearDuplicates(t *testing.T) {
+	embedder := topicEmbedder{topics: map[string][]float32{
+		"footer":   {1, 0, 0},
+		"operator": {0.6, 0.8, 0},
+	}}
+	docs := []schema.Document{
+		{PageContent: "page 1 footer"},
+		{PageContent: "operator basics"},
+		{PageContent: "page 2 footer"},
+		{PageContent: "unrelated"},
+		{PageContent: "page 3 footer"},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      []string
+	}{
+		{"exact duplicates only", 0, []string{"page 1 footer", "operator basics", "page 2 footer", "unrelated", "page 3 footer"}},
+		{"identical embeddings", 0.95, []string{"page 1 footer", "operator basics", "unrelated"}},
+		// The footer and operator vectors have a cosine similarity of 0.6
+		{"similar embeddings", 0.5, []string{"page 1 footer", "unrelated"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &VectorStorage{embedder: embedder, cfg: &Config{NearDuplicateThreshold: tt.threshold}}
+
+			unique, dropped, err := storage.dedupeChunks(context.Background(), docs)
+
+			require.NoError(t, err)
+			var contents []string
+			for _, doc := range unique {
+				contents = append(contents, doc.PageContent)
+			}
+			assert.Equal(t, tt.want, contents)
+			assert.Equal(t, len(docs)-len(tt.want), dropped)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package vectorstorage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// maxQueryVariants caps the rewritten queries retrieved for in addition to the question
+const maxQueryVariants = 3
+
+const expandQueryPrompt = `Rewrite the following search question into %d alternative queries that
+could find the same information: use synonyms, spell out abbreviations and
+add the terms a document answering it would likely contain. Keep the language
+of the question. Answer with one query per line and nothing else.
+
+Question: %s`
+
+// expansionRetriever asks the generator for rewritten variants of the query
+// and retrieves for each of them and the original query with the wrapped
+// retriever, so every sub-query keeps its filters. The results are merged by
+// chunk, keeping the best score of each, and cut to the requested number.
+type expansionRetriever struct {
+	CallbacksHandler callbacks.Handler
+	retriever        schema.Retriever
+	generator        llms.Model
+	numDocs          int
+}
+
+var _ schema.Retriever = expansionRetriever{}
+
+func (r expansionRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	const op = "expansionRetriever.GetRelevantDocuments"
+
+	if r.CallbacksHandler != nil {
+		r.CallbacksHandler.HandleRetrieverStart(ctx, query)
+	}
+
+	queries := append([]string{query}, r.expandQuery(ctx, query)...)
+
+	var results [][]schema.Document
+	for _, q := range queries {
+		docs, err := r.retriever.GetRelevantDocuments(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		results = append(results, docs)
+	}
+
+	docs := mergeDocuments(results...)
+	if r.numDocs > 0 && len(docs) > r.numDocs {
+		docs = docs[:r.numDocs]
+	}
+
+	slog.DebugContext(ctx, "Retrieved documents for expanded query",
+		"queries_count", len(queries),
+		"documents_count", len(docs))
+
+	if r.CallbacksHandler != nil {
+		r.CallbacksHandler.HandleRetrieverEnd(ctx, query, docs)
+	}
+
+	return docs, nil
+}
+
+// expandQuery returns up to maxQueryVariants rewrites of the query. Expansion
+// only improves recall, so when the generator fails the search goes on with
+// the original query alone.
+func (r expansionRetriever) expandQuery(ctx context.Context, query string) []string {
+	completion, err := llms.GenerateFromSinglePrompt(ctx, r.generator,
+		fmt.Sprintf(expandQueryPrompt, maxQueryVariants, query))
+	if err != nil {
+		logSearchError(ctx, err, "Query expansion failed, using the original query",
+			"op", "expansionRetriever.expandQuery")
+		return nil
+	}
+
+	return parseQueryVariants(completion, query)
+}
+
+// parseQueryVariants reads one query per line of the completion, dropping list
+// markers, blank lines and repeats of the original query
+func parseQueryVariants(completion, query string) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+
+	var variants []string
+	for _, line := range strings.Split(completion, "\n") {
+		variant := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.) "))
+		variant = strings.Trim(variant, `"`)
+		key := strings.ToLower(variant)
+		if variant == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		variants = append(variants, variant)
+		if len(variants) == maxQueryVariants {
+			break
+		}
+	}
+	return variants
+}
+
+// mergeDocuments merges retrieval results by chunk and orders them by score.
+// A chunk retrieved by several queries keeps its highest score.
+func mergeDocuments(results ...[]schema.Document) []schema.Document {
+	index := make(map[string]int)
+	var merged []schema.Document
+	for _, docs := range results {
+		for _, doc := range docs {
+			key := chunkKey(doc)
+			if i, ok := index[key]; ok {
+				if doc.Score > merged[i].Score {
+					merged[i] = doc
+				}
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, doc)
+		}
+	}
+
+	slices.SortStableFunc(merged, func(a, b schema.Document) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return merged
+}
+
+// chunkKey identifies a stored chunk by its resource and position. Chunks
+// indexed before positions were recorded fall back to their content.
+func chunkKey(doc schema.Document) string {
+	index, ok := doc.Metadata[chunkIndexKey]
+	if !ok {
+		return fmt.Sprintf("%v\x00%s", doc.Metadata[resourceIdFilter], doc.PageContent)
+	}
+	return fmt.Sprintf("%v\x00%v", doc.Metadata[resourceIdFilter], index)
+}
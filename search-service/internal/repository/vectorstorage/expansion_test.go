@@ -0,0 +1,82 @@
+package vectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// queryStore returns the documents stored for each query and records the
+// filters every search was made with
+type queryStore struct {
+	docs    map[string][]schema.Document
+	filters map[string]any
+}
+
+func (s *queryStore) AddDocuments(context.Context, []schema.Document, ...vectorstores.Option) ([]string, error) {
+	return nil, nil
+}
+
+func (s *queryStore) SimilaritySearch(_ context.Context, query string, _ int, options ...vectorstores.Option) ([]schema.Document, error) {
+	opts := vectorstores.Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	s.filters[query] = opts.Filters
+	return s.docs[query], nil
+}
+
+func TestGetAnswer_QueryExpansion(t *testing.T) {
+	resourceID := uuid.New().String()
+	chunk := func(index int, score float32) schema.Document {
+		return schema.Document{
+			PageContent: "chunk",
+			Score:       score,
+			Metadata:    map[string]any{resourceIdFilter: resourceID, userIDFilter: "user", chunkIndexKey: float64(index)},
+		}
+	}
+
+	store := &queryStore{
+		docs: map[string][]schema.Document{
+			"k8s":                  {chunk(0, 0.6)},
+			"Kubernetes":           {chunk(0, 0.9), chunk(1, 0.7)},
+			"container scheduling": {chunk(2, 0.65)},
+		},
+		filters: map[string]any{},
+	}
+	storage := &VectorStorage{
+		vectorStore: store,
+		generator:   &promptRecorder{answer: "1. Kubernetes\n2. container scheduling\n3. k8s\n"},
+		cfg:         &Config{NumOfResults: 3},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, refs, err := storage.GetAnswer(ctx, "k8s",
+		searchservice.WithQueryExpansion(true),
+		searchservice.WithoutGeneration())
+	require.NoError(t, err)
+
+	// Every sub-query is scoped to what the user may see
+	require.Len(t, store.filters, 3)
+	for query, filters := range store.filters {
+		assert.Equal(t, map[string]any{visibleToFilter: "user"}, filters, query)
+	}
+
+	// The chunk found by two queries keeps its best score
+	require.Len(t, refs, 3)
+	assert.Equal(t, []float32{0.9, 0.7, 0.65}, []float32{refs[0].Score, refs[1].Score, refs[2].Score})
+}
+
+func TestParseQueryVariants(t *testing.T) {
+	variants := parseQueryVariants("- \"go channels\"\n\n2) Go Channels\n* goroutine communication\nchannels\nselect statement\nbuffered channels", "channels")
+
+	assert.Equal(t, []string{"go channels", "goroutine communication", "select statement"}, variants)
+}
@@ -0,0 +1,150 @@
+package vectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+func TestAsk_GenerationOptions(t *testing.T) {
+	configured := 0.3
+
+	tests := []struct {
+		name            string
+		cfgTemperature  *float64
+		opts            []searchservice.SearchOption
+		wantTemperature float64
+		wantMaxTokens   int
+	}{
+		{
+			name:            "config defaults",
+			cfgTemperature:  &configured,
+			wantTemperature: 0.3,
+			wantMaxTokens:   512,
+		},
+		{
+			name:            "requested values",
+			cfgTemperature:  &configured,
+			opts:            []searchservice.SearchOption{searchservice.WithTemperature(1.2), searchservice.WithMaxTokens(128)},
+			wantTemperature: 1.2,
+			wantMaxTokens:   128,
+		},
+		{
+			name:            "clamped above",
+			opts:            []searchservice.SearchOption{searchservice.WithTemperature(9), searchservice.WithMaxTokens(100000)},
+			wantTemperature: maxTemperature,
+			wantMaxTokens:   512,
+		},
+		{
+			name:            "clamped below",
+			opts:            []searchservice.SearchOption{searchservice.WithTemperature(-1), searchservice.WithMaxTokens(0)},
+			wantTemperature: minTemperature,
+			wantMaxTokens:   minMaxTokens,
+		},
+		{
+			name:          "model default temperature",
+			wantMaxTokens: 512,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &promptRecorder{answer: "answer"}
+			storage := &VectorStorage{
+				vectorStore: staticStore{docs: []schema.Document{{
+					PageContent: "context",
+					Metadata:    map[string]any{resourceIdFilter: uuid.NewString(), userIDFilter: "user"},
+				}}},
+				generator: generator,
+				cfg:       &Config{NumOfResults: 1, MaxTokens: 512, Temperature: tt.cfgTemperature},
+			}
+			ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+			askOpts := make([]interface{}, 0, len(tt.opts))
+			for _, opt := range tt.opts {
+				askOpts = append(askOpts, opt)
+			}
+
+			answerCh, refsCh, errCh, _ := storage.ask(ctx, "question", askOpts...)
+			<-refsCh
+			select {
+			case answer := <-answerCh:
+				assert.Equal(t, "answer", answer)
+			case err := <-errCh:
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantMaxTokens, generator.options.MaxTokens)
+			assert.Equal(t, tt.wantTemperature, generator.options.Temperature)
+		})
+	}
+}
+
+func TestGetAnswer_WithoutGenerationSkipsModel(t *testing.T) {
+	resourceID := uuid.New()
+	generator := &promptRecorder{answer: "answer"}
+	storage := &VectorStorage{
+		vectorStore: staticStore{docs: []schema.Document{{
+			PageContent: "context",
+			Metadata:    map[string]any{resourceIdFilter: resourceID.String(), userIDFilter: "user"},
+		}}},
+		generator: generator,
+		cfg:       &Config{NumOfResults: 1, MaxTokens: 100},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	answer, refs, err := storage.GetAnswer(ctx, "question", searchservice.WithoutGeneration())
+
+	require.NoError(t, err)
+	assert.Empty(t, answer)
+	require.Len(t, refs, 1)
+	assert.Equal(t, resourceID, refs[0].ResourceID)
+	assert.Equal(t, "context", refs[0].Content)
+	assert.Empty(t, generator.prompt, "the model must not be called")
+}
+
+func TestGetAnswer_NothingRetrievedSkipsModel(t *testing.T) {
+	generator := &promptRecorder{answer: "made up"}
+	storage := &VectorStorage{
+		vectorStore: staticStore{},
+		generator:   generator,
+		cfg:         &Config{NumOfResults: 1, MaxTokens: 100, NoDocumentsAnswer: "Nothing found in your documents."},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	answer, refs, err := storage.GetAnswer(ctx, "question")
+
+	require.NoError(t, err)
+	assert.Equal(t, "Nothing found in your documents.", answer)
+	assert.Empty(t, refs)
+	assert.Empty(t, generator.prompt, "the model must not be called")
+}
+
+func TestGetAnswerStream_NothingRetrievedSendsAnswerAsChunk(t *testing.T) {
+	generator := &promptRecorder{answer: "made up"}
+	storage := &VectorStorage{
+		vectorStore: staticStore{},
+		generator:   generator,
+		cfg:         &Config{NumOfResults: 1, MaxTokens: 100},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	answerCh, refsCh, chunkCh, errCh := storage.GetAnswerStream(ctx, "question")
+
+	assert.Empty(t, <-refsCh)
+	assert.Equal(t, defaultNoDocumentsAnswer, string(<-chunkCh))
+	select {
+	case answer := <-answerCh:
+		assert.Equal(t, defaultNoDocumentsAnswer, answer)
+	case err := <-errCh:
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Empty(t, generator.prompt, "the model must not be called")
+}
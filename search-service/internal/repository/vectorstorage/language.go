@@ -0,0 +1,113 @@
+package vectorstorage
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/tmc/langchaingo/prompts"
+)
+
+// defaultLanguage is used when the question language can't be detected
+const defaultLanguage = "en"
+
+const englishPromptText = `Use the following pieces of context to answer the question at the end. If you don't know the answer, just say that you don't know, don't try to make up an answer.
+Each piece of context starts with its number in brackets. After every sentence that uses a piece of context, cite it with its bracketed number, for example [1] or [2]. Only cite numbers that appear in the context.
+Answer in the same language as the question.
+
+{{.context}}
+
+Question: {{.question}}
+
+Helpful Answer:
+`
+
+const russianPromptText = `Используй приведённые ниже фрагменты контекста, чтобы ответить на вопрос в конце. Если ты не знаешь ответа, просто скажи, что не знаешь, не пытайся его придумать.
+Каждый фрагмент контекста начинается со своего номера в квадратных скобках. После каждого предложения, использующего фрагмент контекста, укажи его номер в квадратных скобках, например [1] или [2]. Указывай только номера, которые есть в контексте.
+Отвечай на русском языке.
+
+{{.context}}
+
+Вопрос: {{.question}}
+
+Полезный ответ:
+`
+
+// qaPrompts holds the QA prompt of every models.AnswerLanguages code. Languages
+// without a prompt of their own use the English one, which asks the model to
+// answer in the language of the question.
+var qaPrompts = map[string]*prompts.PromptTemplate{
+	"en": newQAPrompt(englishPromptText),
+	"ru": newQAPrompt(russianPromptText),
+}
+
+func newQAPrompt(text string) *prompts.PromptTemplate {
+	prompt := prompts.NewPromptTemplate(text, []string{"context", "question"})
+	return &prompt
+}
+
+// qaPrompt returns the QA prompt for the language, falling back to English
+func qaPrompt(language string) *prompts.PromptTemplate {
+	if prompt, ok := qaPrompts[language]; ok {
+		return prompt
+	}
+	return qaPrompts[defaultLanguage]
+}
+
+// stopwords are frequent short words telling apart languages written in the
+// Latin script
+var stopwords = map[string][]string{
+	"en": {"the", "is", "are", "what", "how", "why", "which", "who", "of", "and", "to", "in", "does", "do", "can", "for"},
+	"de": {"der", "die", "das", "ist", "sind", "und", "wie", "was", "warum", "welche", "nicht", "ein", "eine", "mit", "für", "ich"},
+	"fr": {"le", "la", "les", "est", "sont", "et", "comment", "pourquoi", "quel", "quelle", "des", "une", "du", "que", "qui", "pour"},
+	"es": {"el", "la", "los", "las", "es", "son", "y", "cómo", "como", "qué", "que", "por", "una", "del", "para", "cuál"},
+}
+
+// detectLanguage guesses the ISO 639-1 code of the text language. Cyrillic
+// text is taken as Russian, or Ukrainian when it has letters specific to it.
+// Latin text is told apart by stopwords and defaults to English.
+func detectLanguage(text string) string {
+	var cyrillic, latin int
+	ukrainian := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian = true
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	if cyrillic > latin {
+		if ukrainian {
+			return "uk"
+		}
+		return "ru"
+	}
+	if latin == 0 {
+		return defaultLanguage
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	best, bestScore := defaultLanguage, 0
+	for _, language := range []string{"en", "de", "fr", "es"} {
+		score := 0
+		for _, word := range words {
+			for _, stopword := range stopwords[language] {
+				if word == stopword {
+					score++
+					break
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = language, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,83 @@
+package vectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"What is the capital of France?", "en"},
+		{"Какая столица у Франции?", "ru"},
+		{"Яка столиця Франції?", "uk"},
+		{"Was ist die Hauptstadt von Frankreich?", "de"},
+		{"Quelle est la capitale de la France ?", "fr"},
+		{"¿Cuál es la capital de Francia?", "es"},
+		{"Kubernetes", "en"},
+		{"12345 ?", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectLanguage(tt.text))
+		})
+	}
+}
+
+func TestQAPrompts_CoverAnswerLanguages(t *testing.T) {
+	for _, language := range models.AnswerLanguages {
+		assert.Contains(t, qaPrompts, language)
+	}
+}
+
+func TestAsk_SelectsPromptByLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		question   string
+		opts       []interface{}
+		wantPrompt string
+	}{
+		{"english question", "What is this about?", nil, "Helpful Answer:"},
+		{"russian question", "О чём этот документ?", nil, "Полезный ответ:"},
+		{"override", "What is this about?", []interface{}{searchservice.WithLanguage("ru")}, "Полезный ответ:"},
+		{"language without template", "Was ist das?", nil, "Answer in the same language as the question."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := &promptRecorder{answer: "answer"}
+			storage := &VectorStorage{
+				vectorStore: staticStore{docs: []schema.Document{{
+					PageContent: "context",
+					Metadata:    map[string]any{resourceIdFilter: uuid.NewString(), userIDFilter: "user"},
+				}}},
+				generator: generator,
+				cfg:       &Config{NumOfResults: 1, MaxTokens: 100},
+			}
+			ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+			answerCh, refsCh, errCh, _ := storage.ask(ctx, tt.question, tt.opts...)
+			<-refsCh
+			select {
+			case <-answerCh:
+			case err := <-errCh:
+				require.NoError(t, err)
+			}
+
+			assert.Contains(t, generator.prompt, tt.wantPrompt)
+			assert.Contains(t, generator.prompt, tt.question)
+		})
+	}
+}
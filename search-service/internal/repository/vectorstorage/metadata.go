@@ -0,0 +1,123 @@
+package vectorstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// metadataChunkKey marks the chunk indexing the metadata of a resource
+const metadataChunkKey = "metadata_chunk"
+
+// metadataChunk builds the chunk embedding the name and URL of the resource, so
+// that queries matching its title find it even when the content doesn't mention
+// it. The chunk has no position in the content and is kept apart from the
+// content chunks, whose IDs and hashes resource-service diffs on updates. It
+// reports false when the resource has no metadata worth indexing.
+func metadataChunk(resource models.Resource, userID string) (schema.Document, bool) {
+	var lines []string
+	if name := strings.TrimSpace(resource.Name); name != "" {
+		lines = append(lines, "Title: "+name)
+	}
+	if url := strings.TrimSpace(resource.URL); url != "" {
+		lines = append(lines, "URL: "+url)
+	}
+	if len(lines) == 0 {
+		return schema.Document{}, false
+	}
+
+	doc := schema.Document{
+		PageContent: strings.Join(lines, "\n"),
+		Metadata: map[string]any{
+			userIDFilter:     userID,
+			resourceIdFilter: resource.ID.String(),
+			metadataChunkKey: true,
+		},
+	}
+	labelChunks([]schema.Document{doc}, resource.Name, resource.URL)
+	markVisibility([]schema.Document{doc}, resource.Visibility)
+	if !resource.CreatedAt.IsZero() {
+		doc.Metadata[createdAtKey] = resource.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	return doc, true
+}
+
+// UpdateResourceMetadata relabels the chunks of an indexed resource after its
+// name changed and replaces its metadata chunk, embedding only that chunk
+// again. Resources without indexed chunks get none, their indexation adds it.
+func (s *VectorStorage) UpdateResourceMetadata(ctx context.Context, resource models.Resource) error {
+	const op = "VectorStorage.UpdateResourceMetadata"
+
+	labels := map[string]string{resourceNameKey: resource.Name}
+	if resource.URL != "" {
+		labels[resourceURLKey] = resource.URL
+	}
+	encoded, err := json.Marshal(labels)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	relabelQuery := fmt.Sprintf("UPDATE %s SET cmetadata = cmetadata || $2::jsonb WHERE cmetadata ->> '%s' = $1",
+		embeddingTableName, resourceIdFilter)
+	tag, err := s.pool.Exec(ctx, relabelQuery, resource.ID.String(), string(encoded))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to relabel chunks",
+			"op", op,
+			"resource_id", resource.ID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !s.cfg.EmbedMetadata {
+		return nil
+	}
+
+	userID := resource.OwnerID
+	if userID == "" {
+		var err error
+		if userID, err = getUserID(ctx); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE cmetadata ->> '%s' = $1 AND cmetadata ? '%s'",
+		embeddingTableName, resourceIdFilter, metadataChunkKey)
+	if _, err := s.pool.Exec(ctx, deleteQuery, resource.ID.String()); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete metadata chunk",
+			"op", op,
+			"resource_id", resource.ID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	doc, ok := metadataChunk(resource, userID)
+	if !ok {
+		return nil
+	}
+
+	if tag.RowsAffected() == 0 {
+		slog.DebugContext(ctx, "Resource not indexed, skipping metadata chunk",
+			"resource_id", resource.ID)
+		return nil
+	}
+
+	store, err := s.store(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := store.AddDocuments(ctx, []schema.Document{doc}); err != nil {
+		slog.ErrorContext(ctx, "Failed to add metadata chunk",
+			"op", op,
+			"resource_id", resource.ID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
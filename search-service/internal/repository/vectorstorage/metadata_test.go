@@ -0,0 +1,96 @@
+package vectorstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// keywordStore keeps the added documents and finds the ones containing every
+// word of the query, standing in for the similarity of embeddings
+type keywordStore struct {
+	docs []schema.Document
+}
+
+func (s *keywordStore) AddDocuments(_ context.Context, docs []schema.Document, _ ...vectorstores.Option) ([]string, error) {
+	ids := make([]string, len(docs))
+	for i := range docs {
+		s.docs = append(s.docs, docs[i])
+		ids[i] = uuid.NewString()
+	}
+	return ids, nil
+}
+
+func (s *keywordStore) SimilaritySearch(_ context.Context, query string, _ int, _ ...vectorstores.Option) ([]schema.Document, error) {
+	var found []schema.Document
+	for _, doc := range s.docs {
+		content := strings.ToLower(doc.PageContent)
+		matches := true
+		for _, word := range strings.Fields(strings.ToLower(query)) {
+			matches = matches && strings.Contains(content, word)
+		}
+		if matches {
+			doc.Score = 1
+			found = append(found, doc)
+		}
+	}
+	return found, nil
+}
+
+func indexByTitle(t *testing.T, embedMetadata bool) (models.Resource, []models.Reference) {
+	t.Helper()
+	storage := &VectorStorage{
+		vectorStore: &keywordStore{},
+		cfg:         &Config{NumOfResults: 5, EmbedMetadata: embedMetadata},
+	}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	resource := models.Resource{
+		ID:               uuid.New(),
+		Name:             "Kubernetes Operators Handbook",
+		URL:              "https://example.com/handbook",
+		ExtractedContent: "Reconciliation loops drive the cluster toward the desired state.",
+	}
+	var hashes []string
+	chunkIDs, err := storage.PutResource(ctx, resource, models.WithChunkHashes(func(h []string) {
+		hashes = h
+	}))
+	require.NoError(t, err)
+
+	// The metadata chunk is not one of the content chunks resource-service diffs
+	require.Len(t, chunkIDs, 1)
+	require.Len(t, hashes, 1)
+
+	refs, err := storage.SemanticSearch(ctx, "kubernetes operators")
+	require.NoError(t, err)
+	return resource, refs
+}
+
+func TestPutResource_MetadataSearchableByTitle(t *testing.T) {
+	resource, refs := indexByTitle(t, true)
+
+	require.Len(t, refs, 1)
+	assert.Equal(t, resource.ID, refs[0].ResourceID)
+	assert.Equal(t, "Title: Kubernetes Operators Handbook\nURL: https://example.com/handbook", refs[0].Content)
+}
+
+func TestPutResource_MetadataNotEmbeddedByDefault(t *testing.T) {
+	_, refs := indexByTitle(t, false)
+
+	assert.Empty(t, refs)
+}
+
+func TestMetadataChunk_WithoutMetadata(t *testing.T) {
+	_, ok := metadataChunk(models.Resource{ID: uuid.New(), Name: "  "}, "user")
+
+	assert.False(t, ok)
+}
@@ -0,0 +1,189 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	pgvectorgo "github.com/pgvector/pgvector-go"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"github.com/tmc/langchaingo/vectorstores/pgvector"
+)
+
+// Distance metrics select how chunks are compared with the query embedding.
+//
+// Cosine compares directions only and works with any embedding model, it is
+// the default. L2 and inner product rank chunks the same way as cosine when
+// the model returns unit-length vectors, as bge-m3 does, and inner product is
+// then the cheapest of the three to compute. With raw vectors the magnitude
+// skews both of them, so they are only accepted for models whose vectors are
+// normalized, see CheckNormalization.
+//
+// Scores stay comparable to the cosine similarity the score thresholds were
+// tuned with: for unit vectors the inner product is the cosine similarity, and
+// so is 1 - d²/2 for the L2 distance d.
+const (
+	DistanceMetricCosine       = "cosine"
+	DistanceMetricL2           = "l2"
+	DistanceMetricInnerProduct = "inner_product"
+)
+
+// normalizationTolerance is how far from 1 the norm of a normalized vector may be
+const normalizationTolerance = 1e-3
+
+// distanceOperator returns the pgvector operator of the metric
+func distanceOperator(metric string) string {
+	switch metric {
+	case DistanceMetricL2:
+		return "<->"
+	case DistanceMetricInnerProduct:
+		// pgvector returns the negative inner product, so that smaller is closer
+		return "<#>"
+	default:
+		return "<=>"
+	}
+}
+
+// scoreExpression returns the SQL turning the distance into a similarity score
+func scoreExpression(metric, distance string) string {
+	switch metric {
+	case DistanceMetricL2:
+		return fmt.Sprintf("1 - (%s) ^ 2 / 2", distance)
+	case DistanceMetricInnerProduct:
+		return fmt.Sprintf("(%s) * -1", distance)
+	default:
+		return fmt.Sprintf("1 - (%s)", distance)
+	}
+}
+
+// CheckNormalization fails when the metric needs unit-length vectors and the
+// probe vector of the embedding model isn't one
+func CheckNormalization(metric string, vector []float32) error {
+	if metric != DistanceMetricL2 && metric != DistanceMetricInnerProduct {
+		return nil
+	}
+
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if norm := math.Sqrt(sum); math.Abs(norm-1) > normalizationTolerance {
+		return fmt.Errorf("distance metric %q needs normalized embeddings, the model returns vectors of norm %.4f, use %q",
+			metric, norm, DistanceMetricCosine)
+	}
+	return nil
+}
+
+// rowsQuerier runs the similarity query, satisfied by the connection pool
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// metricStore is a pgvector store searching with the configured distance
+// metric. langchaingo always searches by cosine distance and only filters by
+// equality, so similarity searches run their own query, which also understands
+// visibleToFilter, while writes go to the wrapped store.
+type metricStore struct {
+	vectorstores.VectorStore
+	conn       rowsQuerier
+	embedder   embeddings.Embedder
+	collection string
+	metric     string
+}
+
+// SimilaritySearch returns the numDocuments chunks closest to the query by the
+// metric of the store, honouring the collection, filter and score threshold
+// options the way the pgvector store does
+func (s metricStore) SimilaritySearch(ctx context.Context, query string, numDocuments int, options ...vectorstores.Option) ([]schema.Document, error) {
+	opts := vectorstores.Options{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.ScoreThreshold < 0 || opts.ScoreThreshold > 1 {
+		return nil, pgvector.ErrInvalidScoreThreshold
+	}
+	filters := map[string]any{}
+	if opts.Filters != nil {
+		var ok bool
+		if filters, ok = opts.Filters.(map[string]any); !ok {
+			return nil, pgvector.ErrInvalidFilters
+		}
+	}
+	collection := s.collection
+	if opts.NameSpace != "" {
+		collection = opts.NameSpace
+	}
+	embedder := s.embedder
+	if opts.Embedder != nil {
+		embedder = opts.Embedder
+	}
+
+	vector, err := embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sql, args := similarityQuery(s.metric, collection, vector, numDocuments, opts.ScoreThreshold, filters)
+	rows, err := s.conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := make([]schema.Document, 0)
+	for rows.Next() {
+		var doc schema.Document
+		if err := rows.Scan(&doc.PageContent, &doc.Metadata, &doc.Score); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// similarityQuery builds the nearest neighbour query of the metric. Ordering by
+// the bare operator lets pgvector use an index built for the metric, the score
+// threshold applies once the nearest chunks are found.
+func similarityQuery(metric, collection string, vector []float32, limit int, scoreThreshold float32, filters map[string]any) (string, []any) {
+	args := []any{collection, len(vector), pgvectorgo.NewVector(vector), limit}
+	distance := fmt.Sprintf("e.embedding %s $3", distanceOperator(metric))
+
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := []string{"c.name = $1", "vector_dims(e.embedding) = $2"}
+	for _, key := range keys {
+		if key == visibleToFilter {
+			args = append(args, fmt.Sprint(filters[key]))
+			conditions = append(conditions, visibleCondition("e.cmetadata", len(args)))
+			continue
+		}
+		args = append(args, key, fmt.Sprint(filters[key]))
+		conditions = append(conditions, fmt.Sprintf("e.cmetadata ->> $%d = $%d", len(args)-1, len(args)))
+	}
+
+	threshold := ""
+	if scoreThreshold != 0 {
+		args = append(args, scoreThreshold)
+		threshold = fmt.Sprintf("WHERE score > $%d", len(args))
+	}
+
+	sql := fmt.Sprintf(`SELECT document, cmetadata, score FROM (
+		SELECT e.document, e.cmetadata, %s AS score
+		FROM %s e JOIN collections c ON e.collection_id = c.uuid
+		WHERE %s
+		ORDER BY %s
+		LIMIT $4
+	) nearest %s
+	ORDER BY score DESC`,
+		scoreExpression(metric, distance), embeddingTableName, strings.Join(conditions, " AND "), distance, threshold)
+	return sql, args
+}
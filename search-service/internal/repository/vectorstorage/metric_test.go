@@ -0,0 +1,108 @@
+package vectorstorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+var errQueryRecorded = errors.New("query recorded")
+
+// queryRecorder keeps the query it is asked to run and fails it
+type queryRecorder struct {
+	sql  string
+	args []any
+}
+
+func (q *queryRecorder) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	q.sql, q.args = sql, args
+	return nil, errQueryRecorded
+}
+
+// unitEmbedder embeds every text as the same unit vector
+type unitEmbedder struct{}
+
+func (unitEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{0.6, 0.8}
+	}
+	return vectors, nil
+}
+
+func (unitEmbedder) EmbedQuery(context.Context, string) ([]float32, error) {
+	return []float32{0.6, 0.8}, nil
+}
+
+func TestMetricStore_SearchesWithConfiguredOperator(t *testing.T) {
+	tests := []struct {
+		metric   string
+		operator string
+		score    string
+	}{
+		{metric: DistanceMetricCosine, operator: "e.embedding <=> $3", score: "1 - (e.embedding <=> $3) AS score"},
+		{metric: DistanceMetricL2, operator: "e.embedding <-> $3", score: "1 - (e.embedding <-> $3) ^ 2 / 2 AS score"},
+		{metric: DistanceMetricInnerProduct, operator: "e.embedding <#> $3", score: "(e.embedding <#> $3) * -1 AS score"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			conn := &queryRecorder{}
+			store := metricStore{conn: conn, embedder: unitEmbedder{}, collection: "langchain", metric: tt.metric}
+
+			_, err := store.SimilaritySearch(context.Background(), "question", 5,
+				vectorstores.WithFilters(map[string]any{userIDFilter: "user"}),
+				vectorstores.WithScoreThreshold(0.5))
+			require.ErrorIs(t, err, errQueryRecorded)
+
+			assert.Contains(t, conn.sql, "ORDER BY "+tt.operator+"\n")
+			assert.Contains(t, conn.sql, tt.score)
+			assert.Contains(t, conn.sql, "e.cmetadata ->> $5 = $6")
+			assert.Contains(t, conn.sql, "WHERE score > $7")
+			assert.Equal(t, []any{"langchain", 2}, conn.args[:2])
+			assert.Equal(t, []any{5, userIDFilter, "user", float32(0.5)}, conn.args[3:])
+		})
+	}
+}
+
+func TestMetricStore_NoThreshold(t *testing.T) {
+	conn := &queryRecorder{}
+	store := metricStore{conn: conn, embedder: unitEmbedder{}, collection: "langchain", metric: DistanceMetricL2}
+
+	_, err := store.SimilaritySearch(context.Background(), "question", 5, vectorstores.WithNameSpace("user_1"))
+	require.ErrorIs(t, err, errQueryRecorded)
+
+	assert.NotContains(t, conn.sql, "score >")
+	assert.Equal(t, "user_1", conn.args[0])
+}
+
+func TestMetricStore_VisibleToFilter(t *testing.T) {
+	conn := &queryRecorder{}
+	store := metricStore{conn: conn, embedder: unitEmbedder{}, collection: "langchain", metric: DistanceMetricCosine}
+
+	_, err := store.SimilaritySearch(context.Background(), "question", 5,
+		vectorstores.WithFilters(map[string]any{visibleToFilter: "user"}))
+	require.ErrorIs(t, err, errQueryRecorded)
+
+	// The user's own chunks and the public ones of others, never their
+	// private or shared ones
+	assert.Contains(t, conn.sql, "(e.cmetadata ->> 'user_id' = $5 OR e.cmetadata ->> 'visibility' = 'public')")
+	assert.NotContains(t, conn.sql, "visible_to")
+	assert.Equal(t, []any{5, "user"}, conn.args[3:])
+}
+
+func TestCheckNormalization(t *testing.T) {
+	unit := []float32{0.6, 0.8}
+	raw := []float32{3, 4}
+
+	assert.NoError(t, CheckNormalization(DistanceMetricCosine, raw))
+	assert.NoError(t, CheckNormalization("", raw))
+	assert.NoError(t, CheckNormalization(DistanceMetricL2, unit))
+	assert.NoError(t, CheckNormalization(DistanceMetricInnerProduct, unit))
+	assert.Error(t, CheckNormalization(DistanceMetricL2, raw))
+	assert.Error(t, CheckNormalization(DistanceMetricInnerProduct, raw))
+}
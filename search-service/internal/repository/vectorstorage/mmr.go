@@ -0,0 +1,133 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// mmrFetchMultiplier defines how many candidates are fetched per requested
+// result before max-marginal-relevance reranking is applied.
+const mmrFetchMultiplier = 4
+
+// mmrRetriever retrieves an over-fetched candidate set from the vector store
+// and greedily selects diverse documents out of it.
+type mmrRetriever struct {
+	CallbacksHandler callbacks.Handler
+	vectorStore      vectorstores.VectorStore
+	embedder         embeddings.Embedder
+	numDocs          int
+	lambda           float64
+	options          []vectorstores.Option
+}
+
+var _ schema.Retriever = mmrRetriever{}
+
+func (r mmrRetriever) GetRelevantDocuments(ctx context.Context, query string) ([]schema.Document, error) {
+	const op = "mmrRetriever.GetRelevantDocuments"
+
+	if r.CallbacksHandler != nil {
+		r.CallbacksHandler.HandleRetrieverStart(ctx, query)
+	}
+
+	candidates, err := r.vectorStore.SimilaritySearch(ctx, query, r.numDocs*mmrFetchMultiplier, r.options...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	docs, err := rerankMMR(ctx, r.embedder, query, candidates, r.numDocs, r.lambda)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if r.CallbacksHandler != nil {
+		r.CallbacksHandler.HandleRetrieverEnd(ctx, query, docs)
+	}
+
+	return docs, nil
+}
+
+// rerankMMR embeds the query and the candidate documents and returns up to k
+// documents selected by max marginal relevance.
+func rerankMMR(ctx context.Context, embedder embeddings.Embedder, query string, candidates []schema.Document, k int, lambda float64) ([]schema.Document, error) {
+	const op = "rerankMMR"
+
+	if len(candidates) <= 1 || k <= 0 {
+		return candidates, nil
+	}
+
+	queryVector, err := embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	texts := make([]string, len(candidates))
+	for i, doc := range candidates {
+		texts[i] = doc.PageContent
+	}
+
+	docVectors, err := embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	selected := maxMarginalRelevance(queryVector, docVectors, lambda, k)
+	docs := make([]schema.Document, 0, len(selected))
+	for _, idx := range selected {
+		docs = append(docs, candidates[idx])
+	}
+
+	slog.DebugContext(ctx, "Reranked documents with MMR",
+		"candidates_count", len(candidates),
+		"selected_count", len(docs),
+		"lambda", lambda)
+
+	return docs, nil
+}
+
+// maxMarginalRelevance returns indexes of up to k vectors, each chosen to
+// maximise lambda*sim(query, doc) - (1-lambda)*max(sim(doc, selected)).
+func maxMarginalRelevance(queryVector []float32, docVectors [][]float32, lambda float64, k int) []int {
+	if k > len(docVectors) {
+		k = len(docVectors)
+	}
+
+	relevance := make([]float64, len(docVectors))
+	for i, vector := range docVectors {
+		relevance[i] = models.CosineSimilarity(queryVector, vector)
+	}
+
+	selected := make([]int, 0, k)
+	used := make([]bool, len(docVectors))
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range docVectors {
+			if used[i] {
+				continue
+			}
+
+			redundancy := 0.0
+			for _, j := range selected {
+				redundancy = math.Max(redundancy, models.CosineSimilarity(docVectors[i], docVectors[j]))
+			}
+
+			score := lambda*relevance[i] - (1-lambda)*redundancy
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		used[best] = true
+		selected = append(selected, best)
+	}
+
+	return selected
+}
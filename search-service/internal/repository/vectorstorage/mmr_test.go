@@ -0,0 +1,83 @@
+package vectorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// mmrVectors are a query and candidates to rerank: a and its near duplicate
+// are the most relevant, b is less relevant but different from a, and c is
+// unrelated to the query and to every other candidate
+var (
+	mmrQuery          = []float32{1, 0, 0}
+	mmrA              = []float32{0.9, 0.436, 0}
+	mmrNearDuplicateA = []float32{0.89, 0.44, 0.1}
+	mmrB              = []float32{0.8, -0.6, 0}
+	mmrC              = []float32{0, 0, 1}
+)
+
+func TestMaxMarginalRelevance(t *testing.T) {
+	docVectors := [][]float32{mmrA, mmrNearDuplicateA, mmrB, mmrC}
+
+	tests := []struct {
+		name   string
+		lambda float64
+		k      int
+		want   []int
+	}{
+		// Relevance only, near duplicates included
+		{"lambda 1 ranks by relevance", 1, 4, []int{0, 1, 2, 3}},
+		// Diversity only: after the first pick, the least similar to it follows
+		{"lambda 0 ignores relevance", 0, 2, []int{0, 3}},
+		{"balanced skips the near duplicate", 0.5, 2, []int{0, 2}},
+		{"k over the candidates", 0.5, 10, []int{0, 2, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, maxMarginalRelevance(mmrQuery, docVectors, tt.lambda, tt.k))
+		})
+	}
+}
+
+// textEmbedder embeds each text as its vector in vectors
+type textEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e textEmbedder) EmbedDocuments(_ context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.vectors[text]
+	}
+	return vectors, nil
+}
+
+func (e textEmbedder) EmbedQuery(_ context.Context, text string) ([]float32, error) {
+	return e.vectors[text], nil
+}
+
+func TestRerankMMR_DiversifiesNearDuplicates(t *testing.T) {
+	embedder := textEmbedder{vectors: map[string][]float32{
+		"query":            mmrQuery,
+		"pods":             mmrA,
+		"pods, again":      mmrNearDuplicateA,
+		"deployments":      mmrB,
+		"unrelated recipe": mmrC,
+	}}
+	candidates := []schema.Document{
+		{PageContent: "pods"},
+		{PageContent: "pods, again"},
+		{PageContent: "deployments"},
+		{PageContent: "unrelated recipe"},
+	}
+
+	docs, err := rerankMMR(context.Background(), embedder, "query", candidates, 2, 0.5)
+
+	require.NoError(t, err)
+	assert.Equal(t, []schema.Document{candidates[0], candidates[2]}, docs)
+}
@@ -0,0 +1,230 @@
+package vectorstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
+)
+
+// errStalePatch is returned when a patch does not match the indexed chunks,
+// e.g. when another update reindexed the resource after the patch was computed
+var errStalePatch = errors.New("patch does not match the indexed chunks")
+
+// indexedChunk is a stored chunk of the resource being patched
+type indexedChunk struct {
+	content string
+	hash    string
+}
+
+// patchPlan holds the chunks of the patched content in order. ids has the ID
+// of every kept chunk and is empty for the chunks that still need embedding.
+type patchPlan struct {
+	docs []schema.Document
+	ids  []string
+}
+
+// planPatch lays out the chunks of the patched content from the kept chunks and
+// the added ones, and annotates them for their new positions in text
+func planPatch(text string, patch models.ResourcePatch, indexed map[string]indexedChunk, userID string) (patchPlan, error) {
+	total := len(patch.KeptChunks) + len(patch.AddedChunks)
+	plan := patchPlan{
+		docs: make([]schema.Document, total),
+		ids:  make([]string, total),
+	}
+	placed := make([]bool, total)
+
+	place := func(index int, id, content string) error {
+		if index < 0 || index >= total || placed[index] {
+			return fmt.Errorf("%w: invalid chunk index %d", errStalePatch, index)
+		}
+		placed[index] = true
+		plan.docs[index] = schema.Document{PageContent: content}
+		plan.ids[index] = id
+		return nil
+	}
+
+	for _, chunk := range patch.KeptChunks {
+		stored, ok := indexed[chunk.ID]
+		if !ok || stored.hash != chunk.Hash {
+			return patchPlan{}, fmt.Errorf("%w: chunk %s changed", errStalePatch, chunk.ID)
+		}
+		if err := place(chunk.Index, chunk.ID, stored.content); err != nil {
+			return patchPlan{}, err
+		}
+	}
+	for _, chunk := range patch.AddedChunks {
+		if err := place(chunk.Index, "", chunk.Content); err != nil {
+			return patchPlan{}, err
+		}
+	}
+
+	annotateChunks(text, plan.docs, userID, patch.ResourceID, patch.CreatedAt)
+	labelChunks(plan.docs, patch.Name, patch.URL)
+	markVisibility(plan.docs, patch.Visibility)
+	return plan, nil
+}
+
+// PatchResource applies a content patch to the indexed chunks of the resource:
+// chunks no longer in the content are deleted, kept chunks are moved to their
+// new positions, and only the added chunks are embedded. It returns the IDs of
+// all chunks of the patched content in order. A patch that does not match the
+// indexed chunks changes nothing and returns an error.
+func (s *VectorStorage) PatchResource(ctx context.Context, patch models.ResourcePatch, opts ...models.IndexOption) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "VectorStorage.PatchResource",
+		trace.WithAttributes(
+			attribute.String("resource.id", patch.ResourceID.String()),
+			attribute.Int("resource.chunks_added", len(patch.AddedChunks)),
+		))
+	chunkIDs, err := s.patchResource(ctx, patch, opts...)
+	span.SetAttributes(attribute.Int("resource.chunks", len(chunkIDs)))
+	tracing.EndSpan(span, err)
+	return chunkIDs, err
+}
+
+func (s *VectorStorage) patchResource(ctx context.Context, patch models.ResourcePatch, opts ...models.IndexOption) ([]string, error) {
+	const op = "VectorStorage.PatchResource"
+
+	options := &models.IndexOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	userID := patch.OwnerID
+	if userID == "" {
+		var err error
+		if userID, err = getUserID(ctx); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	indexed, err := s.indexedChunks(ctx, tx, patch.ResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	plan, err := planPatch(clearText(patch.ExtractedContent), patch, indexed, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	keptIDs := make([]string, 0, len(patch.KeptChunks))
+	for _, chunk := range patch.KeptChunks {
+		keptIDs = append(keptIDs, chunk.ID)
+	}
+
+	// Everything not kept goes, which also covers chunks the patch doesn't know
+	// of. The metadata chunk does not depend on the content and stays.
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE cmetadata ->> '%s' = $1 AND NOT (uuid::text = ANY($2)) AND NOT (cmetadata ? '%s')",
+		embeddingTableName, resourceIdFilter, metadataChunkKey)
+	tag, err := tx.Exec(ctx, deleteQuery, patch.ResourceID.String(), keptIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: delete removed chunks: %w", op, err)
+	}
+
+	moveQuery := fmt.Sprintf("UPDATE %s SET cmetadata = $2::jsonb WHERE uuid::text = $1", embeddingTableName)
+	for i, id := range plan.ids {
+		if id == "" {
+			continue
+		}
+		metadata, err := json.Marshal(plan.docs[i].Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := tx.Exec(ctx, moveQuery, id, metadata); err != nil {
+			return nil, fmt.Errorf("%s: move kept chunk: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	added := make([]int, 0, len(patch.AddedChunks))
+	for i, id := range plan.ids {
+		if id == "" {
+			added = append(added, i)
+		}
+	}
+
+	for start := 0; start < len(added); start += addDocumentsBatchSize {
+		end := min(start+addDocumentsBatchSize, len(added))
+
+		batch := make([]schema.Document, 0, end-start)
+		for _, i := range added[start:end] {
+			batch = append(batch, plan.docs[i])
+		}
+
+		ids, err := store.AddDocuments(ctx, batch)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to add patched chunks",
+				"op", op,
+				"resource_id", patch.ResourceID,
+				"error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for j, i := range added[start:end] {
+			plan.ids[i] = ids[j]
+		}
+
+		if options.OnProgress != nil {
+			options.OnProgress(end, len(added))
+		}
+	}
+
+	if options.OnChunkHashes != nil {
+		options.OnChunkHashes(chunkHashes(plan.docs))
+	}
+
+	slog.InfoContext(ctx, "Patched resource chunks",
+		"resource_id", patch.ResourceID,
+		"chunks_count", len(plan.ids),
+		"chunks_embedded", len(added),
+		"chunks_deleted", tag.RowsAffected())
+	return plan.ids, nil
+}
+
+// indexedChunks loads the content and hash of the stored chunks of the resource
+func (s *VectorStorage) indexedChunks(ctx context.Context, tx pgx.Tx, resourceID uuid.UUID) (map[string]indexedChunk, error) {
+	query := fmt.Sprintf("SELECT uuid::text, document, coalesce(cmetadata ->> '%s', '') FROM %s WHERE cmetadata ->> '%s' = $1",
+		chunkHashKey, embeddingTableName, resourceIdFilter)
+	rows, err := tx.Query(ctx, query, resourceID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexed := make(map[string]indexedChunk)
+	for rows.Next() {
+		var id string
+		var chunk indexedChunk
+		if err := rows.Scan(&id, &chunk.content, &chunk.hash); err != nil {
+			return nil, err
+		}
+		indexed[id] = chunk
+	}
+	return indexed, rows.Err()
+}
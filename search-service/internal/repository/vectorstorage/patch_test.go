@@ -0,0 +1,123 @@
+package vectorstorage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/textsplitter"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// sections builds markdown content of n sections, with the paragraph of section
+// edited replaced by an edited one
+func sections(n, edited int) string {
+	var builder strings.Builder
+	for i := 0; i < n; i++ {
+		paragraph := fmt.Sprintf("Paragraph %d about retrieval.", i)
+		if i == edited {
+			paragraph = fmt.Sprintf("Paragraph %d, rewritten to talk about ranking instead.", i)
+		}
+		fmt.Fprintf(&builder, "# Section %d\n\n%s\n\n", i, strings.Repeat(paragraph+" ", 8))
+	}
+	return builder.String()
+}
+
+func splitForTest(t *testing.T, text string) []string {
+	t.Helper()
+	chunks, err := textsplitter.NewMarkdownTextSplitter(
+		textsplitter.WithChunkSize(defaultChunkSize),
+		textsplitter.WithChunkOverlap(defaultChunkOverlap),
+	).SplitText(text)
+	require.NoError(t, err)
+	return chunks
+}
+
+func TestPlanPatch_OneParagraphChanged(t *testing.T) {
+	original := splitForTest(t, clearText(sections(4, -1)))
+	require.Len(t, original, 4)
+
+	indexed := make(map[string]indexedChunk, len(original))
+	byHash := make(map[string]string, len(original))
+	for i, content := range original {
+		id := fmt.Sprintf("chunk-%d", i)
+		indexed[id] = indexedChunk{content: content, hash: hashChunk(content)}
+		byHash[hashChunk(content)] = id
+	}
+
+	// Build the patch the way resource-service does, by matching chunk hashes
+	text := clearText(sections(4, 2))
+	patch := models.ResourcePatch{ResourceID: uuid.New(), ExtractedContent: text}
+	for i, content := range splitForTest(t, text) {
+		hash := hashChunk(content)
+		if id, ok := byHash[hash]; ok {
+			patch.KeptChunks = append(patch.KeptChunks, models.PatchedChunk{ID: id, Index: i, Hash: hash})
+			continue
+		}
+		patch.AddedChunks = append(patch.AddedChunks, models.PatchedChunk{Index: i, Hash: hash, Content: content})
+	}
+
+	plan, err := planPatch(text, patch, indexed, "user")
+	require.NoError(t, err)
+
+	// Only the chunk of the edited paragraph is left to embed
+	assert.Equal(t, []string{"chunk-0", "chunk-1", "", "chunk-3"}, plan.ids)
+	assert.Contains(t, plan.docs[2].PageContent, "rewritten")
+
+	for i, doc := range plan.docs {
+		assert.Equal(t, "user", doc.Metadata[userIDFilter])
+		assert.Equal(t, patch.ResourceID.String(), doc.Metadata[resourceIdFilter])
+		assert.Equal(t, i, doc.Metadata[chunkIndexKey])
+		assert.Equal(t, hashChunk(doc.PageContent), doc.Metadata[chunkHashKey])
+		assert.GreaterOrEqual(t, doc.Metadata[chunkStartOffsetKey].(int), 0)
+	}
+}
+
+func TestPlanPatch_Stale(t *testing.T) {
+	indexed := map[string]indexedChunk{
+		"chunk-0": {content: "first", hash: hashChunk("first")},
+	}
+
+	tests := []struct {
+		name  string
+		patch models.ResourcePatch
+	}{
+		{
+			name: "changed hash",
+			patch: models.ResourcePatch{
+				KeptChunks: []models.PatchedChunk{{ID: "chunk-0", Index: 0, Hash: hashChunk("other")}},
+			},
+		},
+		{
+			name: "unknown chunk",
+			patch: models.ResourcePatch{
+				KeptChunks: []models.PatchedChunk{{ID: "chunk-9", Index: 0, Hash: hashChunk("first")}},
+			},
+		},
+		{
+			name: "index out of range",
+			patch: models.ResourcePatch{
+				KeptChunks:  []models.PatchedChunk{{ID: "chunk-0", Index: 0, Hash: hashChunk("first")}},
+				AddedChunks: []models.PatchedChunk{{Index: 5, Content: "second"}},
+			},
+		},
+		{
+			name: "duplicate index",
+			patch: models.ResourcePatch{
+				KeptChunks:  []models.PatchedChunk{{ID: "chunk-0", Index: 0, Hash: hashChunk("first")}},
+				AddedChunks: []models.PatchedChunk{{Index: 0, Content: "second"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planPatch("first second", tt.patch, indexed, "user")
+			assert.ErrorIs(t, err, errStalePatch)
+		})
+	}
+}
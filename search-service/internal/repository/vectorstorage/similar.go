@@ -0,0 +1,183 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	pgvectorgo "github.com/pgvector/pgvector-go"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// similarCandidatesPerResource is how many nearest chunks are fetched per
+// requested resource, as a close resource usually contributes several of them
+const similarCandidatesPerResource = 5
+
+// neighbourChunk is a chunk found near the centroid of a resource
+type neighbourChunk struct {
+	resourceID string
+	userID     string
+	content    string
+	score      float32
+}
+
+// FindSimilarResources returns up to limit resources of the caller closest to
+// the resource, the closest first. Resources are compared by the centroid of
+// their content chunks, and each neighbour is scored by its closest chunk.
+func (s *VectorStorage) FindSimilarResources(ctx context.Context, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error) {
+	return s.findSimilarResources(ctx, s.pool, resourceID, limit)
+}
+
+func (s *VectorStorage) findSimilarResources(ctx context.Context, conn rowsQuerier, resourceID uuid.UUID, limit int) ([]models.SimilarResource, error) {
+	const op = "VectorStorage.FindSimilarResources"
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	embeddings, err := resourceEmbeddings(ctx, conn, resourceID, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load resource embeddings",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(embeddings) == 0 {
+		return nil, fmt.Errorf("%s: %w", op, models.ErrResourceNotFound)
+	}
+
+	sql, args := neighboursQuery(s.cfg.DistanceMetric, centroid(embeddings), resourceID, userID, limit*similarCandidatesPerResource)
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to query similar resources",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var neighbours []neighbourChunk
+	for rows.Next() {
+		var chunk neighbourChunk
+		if err := rows.Scan(&chunk.resourceID, &chunk.userID, &chunk.content, &chunk.score); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		neighbours = append(neighbours, chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collapseNeighbours(ctx, neighbours, resourceID, userID, limit), nil
+}
+
+// resourceEmbeddings loads the embeddings of the content chunks of the user's resource
+func resourceEmbeddings(ctx context.Context, conn rowsQuerier, resourceID uuid.UUID, userID string) ([][]float32, error) {
+	query := fmt.Sprintf("SELECT embedding FROM %s WHERE cmetadata ->> '%s' = $1 AND cmetadata ->> '%s' = $2 AND NOT (cmetadata ? '%s')",
+		embeddingTableName, resourceIdFilter, userIDFilter, metadataChunkKey)
+
+	rows, err := conn.Query(ctx, query, resourceID.String(), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeddings [][]float32
+	for rows.Next() {
+		var embedding pgvectorgo.Vector
+		if err := rows.Scan(&embedding); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, embedding.Slice())
+	}
+	return embeddings, rows.Err()
+}
+
+// centroid returns the normalized mean of the vectors. Normalizing keeps the
+// scores of the L2 and inner product metrics comparable to cosine similarity,
+// see DistanceMetricCosine.
+func centroid(vectors [][]float32) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+
+	sum := make([]float64, len(vectors[0]))
+	for _, vector := range vectors {
+		for i := range min(len(vector), len(sum)) {
+			sum[i] += float64(vector[i])
+		}
+	}
+
+	var norm float64
+	for _, v := range sum {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	mean := make([]float32, len(sum))
+	if norm == 0 {
+		return mean
+	}
+	for i, v := range sum {
+		mean[i] = float32(v / norm)
+	}
+	return mean
+}
+
+// neighboursQuery builds the query of the chunks nearest to the vector among
+// the content chunks of the user's other resources, the nearest first
+func neighboursQuery(metric string, vector []float32, resourceID uuid.UUID, userID string, limit int) (string, []any) {
+	distance := fmt.Sprintf("embedding %s $1", distanceOperator(metric))
+
+	sql := fmt.Sprintf(`SELECT resource_id, user_id, document, score FROM (
+		SELECT cmetadata ->> '%s' AS resource_id, cmetadata ->> '%s' AS user_id, document, %s AS score
+		FROM %s
+		WHERE vector_dims(embedding) = $2 AND cmetadata ->> '%s' = $3
+			AND cmetadata ->> '%s' <> $4 AND NOT (cmetadata ? '%s')
+		ORDER BY %s
+		LIMIT $5
+	) nearest
+	ORDER BY score DESC`,
+		resourceIdFilter, userIDFilter, scoreExpression(metric, distance), embeddingTableName, userIDFilter,
+		resourceIdFilter, metadataChunkKey, distance)
+
+	return sql, []any{pgvectorgo.NewVector(vector), len(vector), userID, resourceID.String(), limit}
+}
+
+// collapseNeighbours keeps the closest chunk of each resource among the
+// neighbours, which come closest first, and returns up to limit resources.
+// The query already leaves out the resource itself and the chunks of other
+// users; they are skipped here as well so that neither can leak into the list.
+func collapseNeighbours(ctx context.Context, neighbours []neighbourChunk, sourceID uuid.UUID, userID string, limit int) []models.SimilarResource {
+	seen := make(map[uuid.UUID]bool, limit)
+	similar := make([]models.SimilarResource, 0, limit)
+	for _, chunk := range neighbours {
+		if len(similar) == limit {
+			break
+		}
+
+		resourceID, err := uuid.Parse(chunk.resourceID)
+		if err != nil {
+			slog.WarnContext(ctx, "Skipping chunk with invalid resource id",
+				"resource_id", chunk.resourceID)
+			continue
+		}
+		if seen[resourceID] || resourceID == sourceID || chunk.userID != userID {
+			continue
+		}
+		seen[resourceID] = true
+
+		similar = append(similar, models.SimilarResource{
+			ResourceID: resourceID,
+			Score:      chunk.score,
+			Content:    chunk.content,
+		})
+	}
+	return similar
+}
@@ -0,0 +1,155 @@
+package vectorstorage
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	pgvectorgo "github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// storedChunk is a content chunk of the embeddings table
+type storedChunk struct {
+	resourceID uuid.UUID
+	userID     string
+	content    string
+	embedding  []float32
+}
+
+// chunkTable answers the queries of the similar resources with the chunks.
+// Embeddings are looked up by resource and owner, as the database does, while
+// neighbours are only ranked by cosine similarity and limited: none of their
+// conditions apply, so the chunks the storage must leave out are all returned.
+type chunkTable struct {
+	chunks []storedChunk
+}
+
+func (c *chunkTable) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows [][]any
+	if strings.HasPrefix(sql, "SELECT embedding ") {
+		for _, chunk := range c.chunks {
+			if chunk.resourceID.String() == args[0] && chunk.userID == args[1] {
+				rows = append(rows, []any{pgvectorgo.NewVector(chunk.embedding)})
+			}
+		}
+		return &tableRows{rows: rows}, nil
+	}
+
+	query := args[0].(pgvectorgo.Vector).Slice()
+	for _, chunk := range c.chunks {
+		score := float32(models.CosineSimilarity(query, chunk.embedding))
+		rows = append(rows, []any{chunk.resourceID.String(), chunk.userID, chunk.content, score})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i][3].(float32) > rows[j][3].(float32) })
+	return &tableRows{rows: rows[:min(len(rows), args[4].(int))]}, nil
+}
+
+// tableRows returns the rows, scanning their values into the destinations
+type tableRows struct {
+	rows [][]any
+	next int
+}
+
+func (r *tableRows) Close()                                       {}
+func (r *tableRows) Err() error                                   { return nil }
+func (r *tableRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *tableRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *tableRows) RawValues() [][]byte                          { return nil }
+func (r *tableRows) Conn() *pgx.Conn                              { return nil }
+func (r *tableRows) Values() ([]any, error)                       { return r.rows[r.next-1], nil }
+
+func (r *tableRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *tableRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func TestCentroid_NormalizedMean(t *testing.T) {
+	mean := centroid([][]float32{{2, 0}, {0, 2}})
+
+	assert.InDelta(t, math.Sqrt2/2, mean[0], 1e-6)
+	assert.InDelta(t, math.Sqrt2/2, mean[1], 1e-6)
+	assert.Nil(t, centroid(nil))
+	assert.Equal(t, []float32{0, 0}, centroid([][]float32{{1, -1}, {-1, 1}}))
+}
+
+func TestFindSimilarResources_RanksSeededResources(t *testing.T) {
+	resourceID, similarID, relatedID, dissimilarID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	foreignID := uuid.New()
+
+	// The resource is about one topic, the similar resource covers it in
+	// several chunks, the related one touches it and the dissimilar one doesn't.
+	// Another user's resource on the same topic is closer than any of them.
+	table := &chunkTable{chunks: []storedChunk{
+		{resourceID, "user", "resource intro", []float32{1, 0.1, 0}},
+		{resourceID, "user", "resource details", []float32{0.9, 0.2, 0}},
+		{similarID, "user", "similar intro", []float32{1, 0.15, 0.05}},
+		{similarID, "user", "similar details", []float32{0.8, 0.3, 0.1}},
+		{dissimilarID, "user", "dissimilar", []float32{0, 0.1, 1}},
+		{relatedID, "user", "related", []float32{0.6, 0.6, 0.4}},
+		{similarID, "user", "similar aside", []float32{0.5, 0.2, 0.7}},
+		{foreignID, "other", "private notes", []float32{0.95, 0.15, 0}},
+	}}
+	storage := &VectorStorage{cfg: &Config{}}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	similar, err := storage.findSimilarResources(ctx, table, resourceID, 10)
+	require.NoError(t, err)
+
+	require.Len(t, similar, 3, "neither the resource itself nor another user's resource is listed")
+	assert.Equal(t, similarID, similar[0].ResourceID)
+	assert.Equal(t, "similar intro", similar[0].Content, "a resource is scored by its closest chunk")
+	assert.Equal(t, relatedID, similar[1].ResourceID)
+	assert.Equal(t, dissimilarID, similar[2].ResourceID)
+	assert.Greater(t, similar[0].Score, similar[1].Score)
+	assert.Greater(t, similar[1].Score, similar[2].Score)
+
+	top, err := storage.findSimilarResources(ctx, table, resourceID, 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, similarID, top[0].ResourceID)
+}
+
+func TestFindSimilarResources_OtherUsersResource(t *testing.T) {
+	resourceID := uuid.New()
+	table := &chunkTable{chunks: []storedChunk{
+		{resourceID, "other", "private notes", []float32{1, 0, 0}},
+		{uuid.New(), "user", "own notes", []float32{1, 0, 0}},
+	}}
+	storage := &VectorStorage{cfg: &Config{}}
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, err := storage.findSimilarResources(ctx, table, resourceID, 5)
+
+	assert.ErrorIs(t, err, models.ErrResourceNotFound)
+}
+
+func TestCollapseNeighbours_SkipsInvalidResourceIDs(t *testing.T) {
+	resourceID := uuid.New()
+
+	similar := collapseNeighbours(context.Background(), []neighbourChunk{
+		{resourceID: "not-a-uuid", userID: "user", content: "orphan", score: 0.9},
+		{resourceID: resourceID.String(), userID: "user", content: "chunk", score: 0.8},
+	}, uuid.New(), "user", 5)
+
+	require.Len(t, similar, 1)
+	assert.Equal(t, resourceID, similar[0].ResourceID)
+	assert.NotNil(t, collapseNeighbours(context.Background(), nil, uuid.New(), "user", 5), "no neighbours encode as an empty list")
+}
@@ -7,69 +7,115 @@ import (
 	"log/slog"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/samber/lo"
 	"github.com/tmc/langchaingo/chains"
 	"github.com/tmc/langchaingo/documentloaders"
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/prompts"
 	"github.com/tmc/langchaingo/schema"
-	"github.com/tmc/langchaingo/textsplitter"
 	"github.com/tmc/langchaingo/vectorstores"
-	"github.com/tmc/langchaingo/vectorstores/pgvector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
-	"github.com/nzb3/diploma/search-service/internal/controllers/middleware"
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
 	"github.com/nzb3/diploma/search-service/internal/domain/models"
 	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
-	"github.com/nzb3/diploma/search-service/internal/repository/postgres"
 	"github.com/nzb3/diploma/search-service/internal/repository/vectorstorage/callback"
+	"github.com/nzb3/diploma/search-service/internal/tracing"
 )
 
 const userIDFilter = "user_id"
+
+// visibleToFilter scopes a search to the chunks the user is allowed to find:
+// those of their own resources and of public ones. langchaingo only
+// filters by equality, so it is understood by metricStore alone, see
+// visibleCondition.
+const visibleToFilter = "visible_to"
 const resourceIdFilter = "resource_id"
+const embeddingTableName = "embeddings"
+
+// defaultScoreThreshold applies to search modes without a configured threshold
+const defaultScoreThreshold = 0.5
+
+// Bounds of the generation settings a request may ask for
+const (
+	minTemperature = 0.0
+	maxTemperature = 2.0
+	minMaxTokens   = 16
+)
+
+// addDocumentsBatchSize is the number of chunks stored per AddDocuments call,
+// which also defines the granularity of indexation progress reports.
+const addDocumentsBatchSize = 16
 
 type Error error
 
 type VectorStorage struct {
+	// vectorStore is the shared collection, stores holds the per-tenant or
+	// per-user ones opened so far, see CollectionMode
 	vectorStore vectorstores.VectorStore
+	stores      map[string]vectorstores.VectorStore
+	storesMu    sync.Mutex
+	openStore   func(ctx context.Context, name string) (vectorstores.VectorStore, error)
+	pool        *pgxpool.Pool
 	generator   llms.Model
 	embedder    embeddings.Embedder
 	cfg         *Config
 }
 
-func NewVectorStorage(ctx context.Context, vectorStorageCfg *Config, databaseCfg *postgres.Config, embedder embeddings.Embedder, generator llms.Model) (*VectorStorage, error) {
+func NewVectorStorage(ctx context.Context, vectorStorageCfg *Config, pool *pgxpool.Pool, embedder embeddings.Embedder, generator llms.Model) (*VectorStorage, error) {
 	const op = "NewStorage"
 
-	store, err := pgvector.New(
-		ctx,
-		pgvector.WithCollectionTableName("collections"),
-		pgvector.WithEmbeddingTableName("embeddings"),
-		pgvector.WithPreDeleteCollection(false),
-		pgvector.WithVectorDimensions(vectorStorageCfg.EmbeddingDimensions),
-		pgvector.WithEmbedder(embedder),
-		pgvector.WithConnectionURL(databaseCfg.GetConnectionString()),
-	)
+	storage := &VectorStorage{
+		pool:      pool,
+		embedder:  embedder,
+		generator: generator,
+		cfg:       vectorStorageCfg,
+	}
+	storage.openStore = storage.openCollection
 
+	store, err := storage.openCollection(ctx, "")
 	if err != nil {
 		slog.ErrorContext(ctx, "Error creating vector store",
 			"op", op,
 			"error", err)
 		return nil, fmt.Errorf("%s:%w", op, err)
 	}
-	slog.DebugContext(ctx, "Vector storage initialized")
-	return &VectorStorage{
-		vectorStore: &store,
-		embedder:    embedder,
-		generator:   generator,
-		cfg:         vectorStorageCfg,
-	}, nil
+	storage.vectorStore = store
+
+	slog.DebugContext(ctx, "Vector storage initialized",
+		"collection_mode", vectorStorageCfg.CollectionMode)
+	return storage, nil
+}
+
+// PutResource splits the resource into chunks and stores their embeddings.
+// Chunks repeating an earlier chunk of the resource are dropped, see dedupeChunks.
+func (s *VectorStorage) PutResource(ctx context.Context, resource models.Resource, opts ...models.IndexOption) ([]string, error) {
+	ctx, span := tracing.StartSpan(ctx, "VectorStorage.PutResource",
+		trace.WithAttributes(
+			attribute.String("resource.id", resource.ID.String()),
+			attribute.String("resource.type", string(resource.Type)),
+		))
+	chunkIDs, err := s.putResource(ctx, resource, opts...)
+	span.SetAttributes(attribute.Int("resource.chunks", len(chunkIDs)))
+	tracing.EndSpan(span, err)
+	return chunkIDs, err
 }
 
-func (s *VectorStorage) PutResource(ctx context.Context, resource models.Resource) ([]string, error) {
+func (s *VectorStorage) putResource(ctx context.Context, resource models.Resource, opts ...models.IndexOption) ([]string, error) {
 	const op = "VectorStorage.PutResource"
+
+	options := &models.IndexOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
 	slog.DebugContext(ctx, "Processing resource",
 		"resource_type", resource.Type,
 		"content_length", len(resource.ExtractedContent))
@@ -80,7 +126,7 @@ func (s *VectorStorage) PutResource(ctx context.Context, resource models.Resourc
 	docs, err := documentloaders.NewText(strings.NewReader(text)).
 		LoadAndSplit(
 			ctx,
-			textsplitter.NewMarkdownTextSplitter(),
+			s.newTextSplitter(resource),
 		)
 
 	if err != nil {
@@ -89,6 +135,7 @@ func (s *VectorStorage) PutResource(ctx context.Context, resource models.Resourc
 			"error", err)
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	locateChunks(text, docs)
 
 	userID, err := getUserID(ctx)
 	if err != nil {
@@ -99,53 +146,163 @@ func (s *VectorStorage) PutResource(ctx context.Context, resource models.Resourc
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	for i := range docs {
-		docs[i].Metadata = map[string]any{
-			userIDFilter:     userID,
-			resourceIdFilter: resource.ID.String(),
-		}
+	docs, duplicates, err := s.dedupeChunks(ctx, docs)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to drop duplicate chunks",
+			"op", op,
+			"error", err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if duplicates > 0 && options.OnDuplicates != nil {
+		options.OnDuplicates(duplicates)
 	}
 
-	chunkIDs, err := s.vectorStore.AddDocuments(ctx, docs)
+	docs, chunkLimit, err := s.applyChunkLimit(ctx, docs)
 	if err != nil {
-		slog.ErrorContext(ctx, "Failed to add documents",
+		slog.ErrorContext(ctx, "Failed to apply chunk limit",
 			"op", op,
 			"error", err)
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	if chunkLimit != nil && options.OnChunkLimit != nil {
+		options.OnChunkLimit(*chunkLimit)
+	}
+
+	store, err := s.store(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	annotateChunks(text, docs, userID, resource.ID, resource.CreatedAt)
+	labelChunks(docs, resource.Name, resource.URL)
+	markVisibility(docs, resource.Visibility)
+
+	chunkIDs := make([]string, 0, len(docs))
+	for start := 0; start < len(docs); start += addDocumentsBatchSize {
+		// Stop between batches once indexing is cancelled or timed out
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, context.Cause(ctx))
+		}
+
+		end := min(start+addDocumentsBatchSize, len(docs))
+
+		ids, err := store.AddDocuments(ctx, docs[start:end])
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to add documents",
+				"op", op,
+				"error", err)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		chunkIDs = append(chunkIDs, ids...)
+
+		if options.OnProgress != nil {
+			options.OnProgress(end, len(docs))
+		}
+	}
+
+	if s.cfg.EmbedMetadata {
+		if doc, ok := metadataChunk(resource, userID); ok {
+			// Not returned with the content chunks, DeleteResource removes it
+			// together with them
+			if _, err := store.AddDocuments(ctx, []schema.Document{doc}); err != nil {
+				slog.ErrorContext(ctx, "Failed to add metadata chunk",
+					"op", op,
+					"error", err)
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+	}
+
+	if options.OnChunkHashes != nil {
+		options.OnChunkHashes(chunkHashes(docs))
+	}
 
 	slog.InfoContext(ctx, "Successfully processed resource",
 		"chunks_count", len(chunkIDs),
+		"duplicates_dropped", duplicates,
 		"resource_type", resource.Type)
 	return chunkIDs, nil
 }
 
-func (s *VectorStorage) SemanticSearch(ctx context.Context, query string) ([]models.Reference, error) {
+// DeleteResource removes every chunk indexed for the resource and returns how many were deleted.
+func (s *VectorStorage) DeleteResource(ctx context.Context, resourceID uuid.UUID) (int64, error) {
+	const op = "VectorStorage.DeleteResource"
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE cmetadata ->> '%s' = $1", embeddingTableName, resourceIdFilter)
+	tag, err := s.pool.Exec(ctx, query, resourceID.String())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to delete resource chunks",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.DebugContext(ctx, "Deleted resource chunks",
+		"resource_id", resourceID,
+		"chunks_count", tag.RowsAffected())
+	return tag.RowsAffected(), nil
+}
+
+func (s *VectorStorage) SemanticSearch(ctx context.Context, query string, opts ...searchservice.SearchOption) ([]models.Reference, error) {
 	const op = "VectorStorage.SemanticSearch"
 	slog.DebugContext(ctx, "Performing semantic search",
 		"query", query)
 
-	docs, err := s.vectorStore.SimilaritySearch(ctx, query, s.cfg.NumOfResults)
+	options := s.searchOptions(opts...)
+
+	numDocuments := options.NumberOfReferences
+	if options.MMR {
+		numDocuments *= mmrFetchMultiplier
+	}
+
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	store, err := s.store(ctx)
 	if err != nil {
-		slog.ErrorContext(ctx, "Semantic search failed",
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	docs, err := store.SimilaritySearch(ctx, query, numDocuments,
+		vectorstores.WithFilters(map[string]any{visibleToFilter: userID}),
+		vectorstores.WithScoreThreshold(s.scoreThreshold(options)))
+	if err != nil {
+		logSearchError(ctx, err, "Semantic search failed",
 			"op", op,
-			"query", query,
-			"error", err)
+			"query", query)
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if options.MMR {
+		docs, err = rerankMMR(ctx, s.embedder, query, docs, options.NumberOfReferences, options.MMRLambda)
+		if err != nil {
+			logSearchError(ctx, err, "MMR reranking failed",
+				"op", op,
+				"query", query)
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	slog.DebugContext(ctx, "Semantic search completed",
 		"results_count", len(docs))
 	return parseReferences(docs), nil
 }
 
-func (s *VectorStorage) GetAnswer(ctx context.Context, question string) (string, []models.Reference, error) {
+func (s *VectorStorage) GetAnswer(ctx context.Context, question string, opts ...searchservice.SearchOption) (string, []models.Reference, error) {
 	const op = "storage.GetAnswer"
 
 	slog.DebugContext(ctx, "Getting answer",
 		"question", question)
 
-	answerCh, refsCh, errCh, _ := s.ask(ctx, question)
+	askOpts := make([]interface{}, 0, len(opts))
+	for _, opt := range opts {
+		askOpts = append(askOpts, opt)
+	}
+
+	answerCh, refsCh, errCh, _ := s.ask(ctx, question, askOpts...)
 
 	select {
 	case <-ctx.Done():
@@ -154,11 +311,10 @@ func (s *VectorStorage) GetAnswer(ctx context.Context, question string) (string,
 		)
 		return "", nil, ctx.Err()
 	case err := <-errCh:
-		slog.DebugContext(ctx, "Error getting answer",
-			"question", question,
-			"error", err,
-		)
-		return "", nil, ctx.Err()
+		logSearchError(ctx, err, "Error getting answer",
+			"op", op,
+			"question", question)
+		return "", nil, fmt.Errorf("%s: %w", op, err)
 	case answer := <-answerCh:
 		slog.DebugContext(ctx, "Successfully got answer",
 			"question", question,
@@ -170,7 +326,17 @@ func (s *VectorStorage) GetAnswer(ctx context.Context, question string) (string,
 			"question", question,
 			"refs", refs,
 		)
-		return <-answerCh, <-refsCh, nil
+		select {
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		case err := <-errCh:
+			logSearchError(ctx, err, "Error getting answer",
+				"op", op,
+				"question", question)
+			return "", nil, fmt.Errorf("%s: %w", op, err)
+		case answer := <-answerCh:
+			return answer, refs, nil
+		}
 	}
 }
 
@@ -180,67 +346,85 @@ func (s *VectorStorage) GetAnswerStream(ctx context.Context, question string, op
 
 	chunkCh := make(chan []byte, 1)
 
-	options := &searchservice.SearchOptions{
-		NumberOfReferences: s.cfg.NumOfResults,
-	}
-	for _, opt := range opts {
-		opt(options)
-	}
+	options := s.answerOptions(opts...)
 
 	slog.DebugContext(ctx, "Configured answer stream",
 		"question", question,
-		"num_references", options.NumberOfReferences)
+		"num_references", options.NumberOfReferences,
+		"mmr", options.MMR,
+		"temperature", options.Temperature,
+		"max_tokens", options.MaxTokens)
+
+	askOpts := []interface{}{
+		chains.WithStreamingFunc(newChunkHandler(ctx, chunkCh)),
+		answerChunks(chunkCh),
+	}
+	for _, opt := range opts {
+		askOpts = append(askOpts, opt)
+	}
 
-	answerCh, refsCh, errCh, doneCh := s.ask(
-		ctx,
-		question,
-		chains.WithStreamingFunc(newChunkHandler(chunkCh)),
-		searchservice.WithNumberOfReferences(options.NumberOfReferences),
-	)
+	answerCh, refsCh, errCh, doneCh := s.ask(ctx, question, askOpts...)
 
+	// The chunk handler only runs inside ask, so once it is done nothing sends
+	// to chunkCh anymore, also when the request was cancelled
 	go func() {
-		select {
-		case <-ctx.Done():
-			return
-		case <-doneCh:
-			close(chunkCh)
-		}
+		<-doneCh
+		close(chunkCh)
 	}()
 
 	return answerCh, refsCh, chunkCh, errCh
 }
 
-func newChunkHandler(chunkCh chan<- []byte) func(ctx context.Context, chunk []byte) error {
-	return func(ctx context.Context, chunk []byte) error {
-		slog.Info("Received chunk", "chunk", string(chunk), "length", len(chunk))
+// newChunkHandler returns a streaming callback forwarding generated chunks.
+// It returns the cancellation error of the request context, or of the context
+// the model calls it with, which makes the model stop generating.
+func newChunkHandler(ctx context.Context, chunkCh chan<- []byte) func(ctx context.Context, chunk []byte) error {
+	return func(callCtx context.Context, chunk []byte) error {
+		// Checked before sending, as select picks randomly among ready cases
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := callCtx.Err(); err != nil {
+			return err
+		}
+
+		slog.DebugContext(ctx, "Received chunk", "length", len(chunk))
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-			chunkCh <- chunk
+		case <-callCtx.Done():
+			return callCtx.Err()
+		case chunkCh <- chunk:
 			return nil
 		}
 	}
 }
 
+// answerChunks receives answers that are not generated, like the one given
+// when nothing was retrieved, as a single chunk when streaming
+type answerChunks chan<- []byte
+
 func (s *VectorStorage) ask(ctx context.Context, question string, opts ...interface{}) (<-chan string, <-chan []models.Reference, <-chan error, <-chan struct{}) {
 	const op = "VectorStorage.ask"
 	slog.DebugContext(ctx, "Processing question", "question", question)
 
 	var chainOpts []chains.ChainCallOption
-	numOfResults := s.cfg.NumOfResults
+	var searchOpts []searchservice.SearchOption
+	var chunkCh answerChunks
 
 	for _, opt := range opts {
 		switch o := opt.(type) {
 		case chains.ChainCallOption:
 			chainOpts = append(chainOpts, o)
 		case searchservice.SearchOption:
-			sOpts := &searchservice.SearchOptions{NumberOfReferences: s.cfg.NumOfResults}
-			o(sOpts)
-			numOfResults = sOpts.NumberOfReferences
+			searchOpts = append(searchOpts, o)
+		case answerChunks:
+			chunkCh = o
 		}
 	}
 
+	options := s.answerOptions(searchOpts...)
+
 	refsCh := make(chan []models.Reference)
 	answerCh := make(chan string)
 	errCh := make(chan error)
@@ -254,47 +438,108 @@ func (s *VectorStorage) ask(ctx context.Context, question string, opts ...interf
 			close(doneCh)
 		}()
 
+		// found is set by the retriever callback along with reporting the references
+		var found atomic.Bool
+		reportReferences := newRetrieverEndHandler(refsCh)
 		cb := callback.NewCallbackHandler(
-			callback.WithRetrieverEndFunc(newRetrieverEndHandler(refsCh)),
+			callback.WithRetrieverEndFunc(func(ctx context.Context, query string, documents []schema.Document) {
+				found.Store(len(documents) > 0)
+				reportReferences(ctx, query, documents)
+			}),
 		)
 
 		userID, err := getUserID(ctx)
 		if err != nil {
 			slog.ErrorContext(ctx, "Failed to get user ID", "op", op, "error", err)
-			errCh <- fmt.Errorf("%s: %w", op, err)
+			sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
 			return
 		}
 
 		filters := map[string]interface{}{
-			userIDFilter: userID,
+			visibleToFilter: userID,
 		}
 
-		retriever := s.setupRetriever(filters, numOfResults, cb)
-		chain, err := s.setupChains(retriever)
+		store, err := s.store(ctx)
 		if err != nil {
-			slog.ErrorContext(ctx, "Failed to setup retriever", "op", op, "error", err)
-			errCh <- fmt.Errorf("%s: %w", op, err)
+			sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
+			return
 		}
 
-		chainOpts = append(chainOpts, chains.WithMaxTokens(s.cfg.MaxTokens), chains.WithCallback(cb))
+		retriever := s.setupRetriever(store, filters, options, cb)
 
-		select {
-		case <-ctx.Done():
-			errCh <- ctx.Err()
-		default:
-			slog.DebugContext(ctx, "Running retrieval QA chain")
-			answer, err := chains.Run(
-				ctx,
-				chain,
-				question,
-				chainOpts...,
-			)
-			if err != nil {
-				errCh <- fmt.Errorf("%s:%w", op, err)
+		if options.SkipGeneration {
+			// The retriever callback sends the references, the answer stays empty
+			slog.DebugContext(ctx, "Retrieving references without generation")
+			if _, err := retriever.GetRelevantDocuments(ctx, question); err != nil {
+				logSearchError(ctx, err, "Retrieval failed", "op", op)
+				sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
+				return
 			}
 
-			answerCh <- answer
+			sendOrDone(ctx, answerCh, "")
+			return
+		}
+
+		language := options.Language
+		if language == "" {
+			language = detectLanguage(question)
+		}
+		slog.DebugContext(ctx, "Selected answer language", "language", language)
+
+		// The configured system prompt was checked when loading the config
+		if err := checkSystemPrompt(options.SystemPrompt, s.cfg.SystemPromptMaxTokens); err != nil {
+			sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+		systemPrompt := joinSystemPrompts(s.cfg.SystemPrompt, options.SystemPrompt)
+
+		// Retrieving ahead of the chain lets an empty retrieval skip the model,
+		// which would otherwise answer without any context
+		docs, err := retriever.GetRelevantDocuments(ctx, question)
+		if err != nil {
+			logSearchError(ctx, err, "Retrieval failed", "op", op)
+			sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+		if !found.Load() {
+			answer := s.noDocumentsAnswer()
+			slog.InfoContext(ctx, "No relevant documents retrieved, skipping generation", "op", op)
+			if chunkCh != nil && !sendOrDone(ctx, chunkCh, []byte(answer)) {
+				return
+			}
+			sendOrDone(ctx, answerCh, answer)
+			return
+		}
+
+		chain, err := s.setupChains(retrievedDocuments(docs), language, systemPrompt, contextSize(options))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to setup retriever", "op", op, "error", err)
+			sendOrDone(ctx, errCh, fmt.Errorf("%s: %w", op, err))
+			return
+		}
+
+		chainOpts = append(chainOpts, s.generationOptions(options)...)
+		chainOpts = append(chainOpts, chains.WithCallback(cb))
+
+		if err := ctx.Err(); err != nil {
+			sendOrDone(ctx, errCh, err)
+			return
+		}
+
+		slog.DebugContext(ctx, "Running retrieval QA chain")
+		answer, err := chains.Run(
+			ctx,
+			chain,
+			question,
+			chainOpts...,
+		)
+		if err != nil {
+			logSearchError(ctx, err, "Retrieval QA chain stopped", "op", op)
+			sendOrDone(ctx, errCh, fmt.Errorf("%s:%w", op, err))
+			return
 		}
+
+		sendOrDone(ctx, answerCh, answer)
 	}()
 
 	return answerCh, refsCh, errCh, doneCh
@@ -303,77 +548,222 @@ func (s *VectorStorage) ask(ctx context.Context, question string, opts ...interf
 func newRetrieverEndHandler(refsChains ...chan<- []models.Reference) func(ctx context.Context, query string, documents []schema.Document) {
 	return func(ctx context.Context, query string, documents []schema.Document) {
 		slog.Info("On retrieving was received documents", "documents_count", len(documents))
-		select {
-		case <-ctx.Done():
+		if ctx.Err() != nil {
 			return
-		default:
-			refs := parseReferences(documents)
-			for _, ch := range refsChains {
-				ch <- refs
+		}
+
+		refs := parseReferences(documents)
+		for _, ch := range refsChains {
+			if !sendOrDone(ctx, ch, refs) {
+				return
 			}
 		}
 	}
 }
 
+// sendOrDone sends the value unless the context is done first, so that
+// producers never block on a reader that went away. It reports whether the
+// value was sent.
+func sendOrDone[T any](ctx context.Context, ch chan<- T, value T) bool {
+	select {
+	case ch <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// logSearchError logs a failed search step. Steps interrupted by the client
+// cancelling the search are logged at debug level since nothing failed.
+func logSearchError(ctx context.Context, err error, msg string, args ...any) {
+	level := slog.LevelError
+	if searchservice.IsCancelled(ctx, err) {
+		level = slog.LevelDebug
+	}
+	slog.Log(ctx, level, msg, append(args, "error", err)...)
+}
+
 func getUserID(ctx context.Context) (string, error) {
-	userID, ok := middleware.GetUserID(ctx)
+	userID, ok := identity.UserID(ctx)
 	if !ok {
 		return "", errors.New("user ID not found in context")
 	}
 	return userID, nil
 }
 
-func (s *VectorStorage) setupRetriever(filters map[string]interface{},
-	numResults int,
+func (s *VectorStorage) searchOptions(opts ...searchservice.SearchOption) *searchservice.SearchOptions {
+	options := &searchservice.SearchOptions{
+		NumberOfReferences: s.cfg.NumOfResults,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// answerOptions resolves the search options of the answer context, where the
+// number of retrieved chunks defaults to RetrieverK rather than NumOfResults
+func (s *VectorStorage) answerOptions(opts ...searchservice.SearchOption) *searchservice.SearchOptions {
+	numDocs := s.cfg.NumOfResults
+	if s.cfg.RetrieverK > 0 {
+		numDocs = s.cfg.RetrieverK
+	}
+
+	options := &searchservice.SearchOptions{
+		NumberOfReferences: numDocs,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// contextSize returns the number of retrieved references given to the model.
+// The retriever returns at most NumberOfReferences documents, so a larger
+// context size is clamped to it.
+func contextSize(options *searchservice.SearchOptions) int {
+	if options.ContextSize <= 0 {
+		return options.NumberOfReferences
+	}
+	return min(options.ContextSize, options.NumberOfReferences)
+}
+
+// noDocumentsAnswer returns the answer to questions nothing relevant was retrieved for
+func (s *VectorStorage) noDocumentsAnswer() string {
+	if s.cfg.NoDocumentsAnswer != "" {
+		return s.cfg.NoDocumentsAnswer
+	}
+	return defaultNoDocumentsAnswer
+}
+
+// answerScoreThreshold returns the threshold of answer context chunks: the
+// per-request one if given, then RetrieverScoreThreshold, then the threshold
+// of the search mode. The store keeps chunks scoring strictly above it.
+func (s *VectorStorage) answerScoreThreshold(options *searchservice.SearchOptions) float32 {
+	if options.ScoreThreshold == nil && s.cfg.RetrieverScoreThreshold != nil {
+		return float32(*s.cfg.RetrieverScoreThreshold)
+	}
+	return s.scoreThreshold(options)
+}
+
+// scoreThreshold returns the per-request threshold if given, otherwise the
+// threshold configured for the search mode.
+func (s *VectorStorage) scoreThreshold(options *searchservice.SearchOptions) float32 {
+	if options.ScoreThreshold != nil {
+		return float32(*options.ScoreThreshold)
+	}
+	if threshold, ok := s.cfg.ScoreThresholds[string(options.Mode())]; ok {
+		return float32(threshold)
+	}
+	return defaultScoreThreshold
+}
+
+// generationOptions returns the chain options for the requested generation
+// settings, clamped to safe ranges, falling back to the configured defaults.
+// The configured max tokens is also the upper bound of requested ones.
+func (s *VectorStorage) generationOptions(options *searchservice.SearchOptions) []chains.ChainCallOption {
+	maxTokens := s.cfg.MaxTokens
+	if options.MaxTokens != nil {
+		maxTokens = max(*options.MaxTokens, minMaxTokens)
+		if s.cfg.MaxTokens > 0 {
+			maxTokens = min(maxTokens, s.cfg.MaxTokens)
+		}
+	}
+	chainOpts := []chains.ChainCallOption{chains.WithMaxTokens(maxTokens)}
+
+	temperature := s.cfg.Temperature
+	if options.Temperature != nil {
+		clamped := min(max(*options.Temperature, minTemperature), maxTemperature)
+		temperature = &clamped
+	}
+	if temperature != nil {
+		chainOpts = append(chainOpts, chains.WithTemperature(*temperature))
+	}
+
+	return chainOpts
+}
+
+func (s *VectorStorage) setupRetriever(store vectorstores.VectorStore,
+	filters map[string]interface{},
+	options *searchservice.SearchOptions,
 	callbackHandler ...*callback.Handler,
-) *vectorstores.Retriever {
+) schema.Retriever {
 	slog.DebugContext(context.Background(), "Configuring retriever",
-		"num_results", numResults)
-	retriever := vectorstores.ToRetriever(
-		s.vectorStore,
-		numResults,
+		"num_results", options.NumberOfReferences,
+		"mmr", options.MMR,
+		"query_expansion", options.QueryExpansion)
+
+	if options.QueryExpansion {
+		// Sub-queries are reported through the expansion retriever only, once merged
+		inner := *options
+		inner.QueryExpansion = false
+		retriever := expansionRetriever{
+			retriever: s.setupRetriever(store, filters, &inner),
+			generator: s.generator,
+			numDocs:   options.NumberOfReferences,
+		}
+		if len(callbackHandler) > 0 {
+			retriever.CallbacksHandler = callbackHandler[0]
+		}
+		return retriever
+	}
+
+	storeOpts := []vectorstores.Option{
 		vectorstores.WithFilters(filters),
-		vectorstores.WithScoreThreshold(0.5),
+		vectorstores.WithScoreThreshold(s.answerScoreThreshold(options)),
+	}
+
+	if options.MMR {
+		retriever := mmrRetriever{
+			vectorStore: store,
+			embedder:    s.embedder,
+			numDocs:     options.NumberOfReferences,
+			lambda:      options.MMRLambda,
+			options:     storeOpts,
+		}
+		if len(callbackHandler) > 0 {
+			retriever.CallbacksHandler = callbackHandler[0]
+		}
+		return retriever
+	}
+
+	retriever := vectorstores.ToRetriever(
+		store,
+		options.NumberOfReferences,
+		storeOpts...,
 	)
 	if len(callbackHandler) > 0 {
 		retriever.CallbacksHandler = callbackHandler[0]
 	}
-	return &retriever
+	return retriever
 }
 
-func (s *VectorStorage) setupChains(retriever *vectorstores.Retriever) (chains.Chain, error) {
-	qaChain := s.setupRetrievalQA(retriever)
+// retrievedDocuments hands documents retrieved beforehand to the QA chain
+type retrievedDocuments []schema.Document
 
-	return chains.NewSimpleSequentialChain(
-		[]chains.Chain{qaChain},
-	)
+func (d retrievedDocuments) GetRelevantDocuments(context.Context, string) ([]schema.Document, error) {
+	return d, nil
 }
 
-func (s *VectorStorage) setupRetrievalQA(retriever *vectorstores.Retriever) chains.RetrievalQA {
-	customPromptText := `Use the following pieces of context to answer the question at the end. If you don't know the answer, just say that you don't know, don't try to make up an answer
+func (s *VectorStorage) setupChains(retriever schema.Retriever, language, systemPrompt string, contextSize int) (chains.Chain, error) {
+	qaChain := s.setupRetrievalQA(retriever, language, systemPrompt, contextSize)
 
-{{.context}}
-
-Question: {{.question}}
-
-Helpful Answer:
-`
-
-	prompt := prompts.NewPromptTemplate(
-		customPromptText,
-		[]string{"context", "question"},
+	return chains.NewSimpleSequentialChain(
+		[]chains.Chain{qaChain},
 	)
+}
 
+func (s *VectorStorage) setupRetrievalQA(retriever schema.Retriever, language, systemPrompt string, contextSize int) chains.RetrievalQA {
 	qaPromptSelector := chains.ConditionalPromptSelector{
-		DefaultPrompt: prompt,
+		DefaultPrompt: *withSystemPrompt(qaPrompt(language), systemPrompt),
 	}
 
-	prompt = qaPromptSelector.GetPrompt(s.generator)
+	prompt := qaPromptSelector.GetPrompt(s.generator)
 
 	llmChain := chains.NewLLMChain(s.generator, prompt)
 	return chains.NewRetrievalQA(
 		chains.NewStuffDocuments(llmChain),
-		retriever,
+		numberedRetriever{retriever: retriever, contextSize: contextSize},
 	)
 }
 
@@ -383,10 +773,17 @@ func parseReferences(docs []schema.Document) []models.Reference {
 	return lo.Map(docs, func(doc schema.Document, _ int) models.Reference {
 		stringId := doc.Metadata[resourceIdFilter].(string)
 		uuidId := uuid.MustParse(stringId)
+		ownerID, _ := doc.Metadata[userIDFilter].(string)
+		visibility, _ := doc.Metadata[visibilityKey].(string)
+		createdAt, _ := doc.Metadata[createdAtKey].(string)
+		created, _ := time.Parse(time.RFC3339, createdAt)
 		return models.Reference{
 			ResourceID: uuidId,
 			Content:    doc.PageContent,
 			Score:      doc.Score,
+			OwnerID:    ownerID,
+			Visibility: models.ResourceVisibility(visibility),
+			CreatedAt:  created,
 		}
 	})
 }
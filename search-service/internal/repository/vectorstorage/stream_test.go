@@ -0,0 +1,144 @@
+package vectorstorage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+)
+
+// staticStore returns the same documents for every search
+type staticStore struct {
+	docs []schema.Document
+}
+
+func (s staticStore) AddDocuments(context.Context, []schema.Document, ...vectorstores.Option) ([]string, error) {
+	return nil, nil
+}
+
+func (s staticStore) SimilaritySearch(context.Context, string, int, ...vectorstores.Option) ([]schema.Document, error) {
+	return s.docs, nil
+}
+
+// gatedLLM streams one token each time the test allows it. Like a model that
+// does not watch its context, it only stops when the streaming callback fails.
+type gatedLLM struct {
+	next chan struct{}
+
+	mu        sync.Mutex
+	streamed  int
+	streamErr error
+	done      chan struct{}
+}
+
+func newGatedLLM() *gatedLLM {
+	return &gatedLLM{
+		next: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+func (m *gatedLLM) GenerateContent(ctx context.Context, _ []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	defer close(m.done)
+
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	var answer string
+	for range m.next {
+		token := "token "
+		if err := opts.StreamingFunc(ctx, []byte(token)); err != nil {
+			m.mu.Lock()
+			m.streamErr = err
+			m.mu.Unlock()
+			return nil, err
+		}
+
+		m.mu.Lock()
+		m.streamed++
+		m.mu.Unlock()
+		answer += token
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: answer}}}, nil
+}
+
+func (m *gatedLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestGetAnswerStream_CancelStopsGeneration(t *testing.T) {
+	generator := newGatedLLM()
+	storage := &VectorStorage{
+		vectorStore: staticStore{docs: []schema.Document{{
+			PageContent: "context",
+			Metadata: map[string]any{
+				resourceIdFilter: uuid.NewString(),
+				userIDFilter:     "user",
+			},
+		}}},
+		generator: generator,
+		cfg:       &Config{NumOfResults: 1, MaxTokens: 100},
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), identity.UserIDKey, "user"))
+	defer cancel()
+
+	answerCh, refsCh, chunkCh, errCh := storage.GetAnswerStream(ctx, "question")
+
+	select {
+	case refs := <-refsCh:
+		require.Len(t, refs, 1)
+	case <-time.After(time.Second):
+		t.Fatal("references were not sent")
+	}
+
+	for i := 0; i < 2; i++ {
+		generator.next <- struct{}{}
+		select {
+		case chunk := <-chunkCh:
+			assert.Equal(t, "token ", string(chunk))
+		case <-time.After(time.Second):
+			t.Fatal("chunk was not streamed")
+		}
+	}
+
+	cancel()
+	// Let the model try to stream one more token after the cancellation
+	generator.next <- struct{}{}
+
+	select {
+	case <-generator.done:
+	case <-time.After(time.Second):
+		t.Fatal("generation did not stop after cancellation")
+	}
+
+	generator.mu.Lock()
+	assert.Equal(t, 2, generator.streamed)
+	assert.ErrorIs(t, generator.streamErr, context.Canceled)
+	generator.mu.Unlock()
+
+	// No further chunks are sent and the channels are closed once ask exits
+	select {
+	case chunk, ok := <-chunkCh:
+		assert.False(t, ok, "unexpected chunk %q after cancellation", chunk)
+	case <-time.After(time.Second):
+		t.Fatal("chunk channel was not closed")
+	}
+
+	_, ok := <-answerCh
+	assert.False(t, ok)
+	_, ok = <-errCh
+	assert.False(t, ok)
+}
@@ -0,0 +1,107 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// Defaults of the reference summaries, see Config.Summary
+const (
+	defaultSummaryPrompt = `Summarize the following search result in one short sentence.
+Answer with the summary only.`
+	defaultSummaryMaxReferences = 5
+	defaultSummaryConcurrency   = 3
+	defaultSummaryMaxTokens     = 60
+)
+
+// SummaryConfig configures the one-line summaries of semantic search references
+type SummaryConfig struct {
+	// Prompt instructs the generator, the content of the reference follows it
+	// after a blank line; defaultSummaryPrompt when unset
+	Prompt string `yaml:"prompt" mapstructure:"prompt"`
+	// MaxReferences is how many references of a search are summarized, the
+	// best scored first; defaultSummaryMaxReferences when unset
+	MaxReferences int `yaml:"max_references" mapstructure:"max_references" validate:"min=0"`
+	// Concurrency is how many summaries are generated at once
+	Concurrency int `yaml:"concurrency" mapstructure:"concurrency" validate:"min=0"`
+	// MaxTokens bounds the length of each summary
+	MaxTokens int `yaml:"max_tokens" mapstructure:"max_tokens" validate:"min=0"`
+}
+
+// withDefaults fills in the unset settings
+func (c SummaryConfig) withDefaults() SummaryConfig {
+	if c.Prompt == "" {
+		c.Prompt = defaultSummaryPrompt
+	}
+	if c.MaxReferences <= 0 {
+		c.MaxReferences = defaultSummaryMaxReferences
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultSummaryConcurrency
+	}
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = defaultSummaryMaxTokens
+	}
+	return c
+}
+
+// SummarizeReferences returns a copy of the references with Summary set on the
+// first Summary.MaxReferences of them. Summaries are generated concurrently,
+// and a reference whose summary fails keeps its content only. It fails only
+// when ctx is done.
+func (s *VectorStorage) SummarizeReferences(ctx context.Context, refs []models.Reference) ([]models.Reference, error) {
+	const op = "VectorStorage.SummarizeReferences"
+
+	config := s.cfg.Summary.withDefaults()
+	summarized := make([]models.Reference, len(refs))
+	copy(summarized, refs)
+
+	sem := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+	for i := range min(len(summarized), config.MaxReferences) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			summarized[i].Summary = s.summarizeReference(ctx, config, summarized[i])
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return summarized, nil
+}
+
+// summarizeReference returns the summary of the content of the reference,
+// empty when the generator fails
+func (s *VectorStorage) summarizeReference(ctx context.Context, config SummaryConfig, ref models.Reference) string {
+	summary, err := llms.GenerateFromSinglePrompt(ctx, s.generator,
+		config.Prompt+"\n\n"+ref.Content,
+		llms.WithMaxTokens(config.MaxTokens))
+	if err != nil {
+		logSearchError(ctx, err, "Failed to summarize reference",
+			"op", "VectorStorage.summarizeReference",
+			"resource_id", ref.ResourceID)
+		return ""
+	}
+
+	// The summary is meant for a single line
+	return strings.Join(strings.Fields(summary), " ")
+}
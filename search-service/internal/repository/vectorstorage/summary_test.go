@@ -0,0 +1,107 @@
+package vectorstorage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// summarizingLLM summarizes a prompt as its last line, fails on contents
+// containing "fail" and records how many calls ran at once
+type summarizingLLM struct {
+	mu        sync.Mutex
+	running   int
+	peak      int
+	maxTokens []int
+}
+
+func (m *summarizingLLM) GenerateContent(_ context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var callOptions llms.CallOptions
+	for _, opt := range options {
+		opt(&callOptions)
+	}
+
+	m.mu.Lock()
+	m.running++
+	m.peak = max(m.peak, m.running)
+	m.maxTokens = append(m.maxTokens, callOptions.MaxTokens)
+	m.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+
+	prompt := messages[0].Parts[0].(llms.TextContent).Text
+	content := prompt[strings.LastIndex(prompt, "\n")+1:]
+	if strings.Contains(content, "fail") {
+		return nil, errors.New("model unavailable")
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "About " + content + ".\n"}}}, nil
+}
+
+func (m *summarizingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func TestSummarizeReferences_PopulatesSummaries(t *testing.T) {
+	generator := &summarizingLLM{}
+	storage := &VectorStorage{
+		generator: generator,
+		cfg:       &Config{Summary: SummaryConfig{MaxReferences: 4, Concurrency: 2, MaxTokens: 30}},
+	}
+
+	refs := make([]models.Reference, 0, 6)
+	for _, content := range []string{"goroutines", "channels", "fail", "select", "mutexes", "contexts"} {
+		refs = append(refs, models.Reference{ResourceID: uuid.New(), Content: content})
+	}
+
+	summarized, err := storage.SummarizeReferences(context.Background(), refs)
+
+	require.NoError(t, err)
+	require.Len(t, summarized, len(refs))
+	assert.Equal(t, "About goroutines.", summarized[0].Summary)
+	assert.Equal(t, "About channels.", summarized[1].Summary)
+	assert.Empty(t, summarized[2].Summary, "a failed summary leaves the reference without one")
+	assert.Equal(t, "About select.", summarized[3].Summary)
+	assert.Empty(t, summarized[4].Summary, "references past the cap are not summarized")
+	assert.Empty(t, summarized[5].Summary)
+	assert.Equal(t, "mutexes", summarized[4].Content)
+
+	assert.Empty(t, refs[0].Summary, "the references passed in are left untouched")
+	assert.LessOrEqual(t, generator.peak, 2)
+	assert.Equal(t, []int{30, 30, 30, 30}, generator.maxTokens)
+}
+
+func TestSummarizeReferences_Defaults(t *testing.T) {
+	generator := &promptRecorder{answer: "  A summary\nover two lines "}
+	storage := &VectorStorage{generator: generator, cfg: &Config{}}
+
+	summarized, err := storage.SummarizeReferences(context.Background(), []models.Reference{{Content: "chunk"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "A summary over two lines", summarized[0].Summary)
+	assert.Equal(t, defaultSummaryPrompt+"\n\nchunk", generator.prompt)
+	assert.Equal(t, defaultSummaryMaxTokens, generator.options.MaxTokens)
+}
+
+func TestSummarizeReferences_Cancelled(t *testing.T) {
+	storage := &VectorStorage{generator: &summarizingLLM{}, cfg: &Config{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := storage.SummarizeReferences(ctx, []models.Reference{{Content: "chunk"}})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,63 @@
+package vectorstorage
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/prompts"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// defaultSystemPromptMaxTokens is the token budget of the system prompt when
+// none is configured. The prompt is sent with every question, so a long one
+// takes room from the context chunks in small context windows.
+const defaultSystemPromptMaxTokens = 512
+
+const systemPromptVariable = "system_prompt"
+
+// estimateTokens approximates the number of tokens of a text. Without the
+// tokenizer of the model, four characters per token is a fair estimate for
+// English; it undercounts other scripts, whose budget should be kept lower.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// checkSystemPrompt fails with models.ErrSystemPromptTooLong when the prompt
+// exceeds maxTokens, defaultSystemPromptMaxTokens when unset
+func checkSystemPrompt(prompt string, maxTokens int) error {
+	if maxTokens <= 0 {
+		maxTokens = defaultSystemPromptMaxTokens
+	}
+	if tokens := estimateTokens(prompt); tokens > maxTokens {
+		return fmt.Errorf("%w: about %d tokens, at most %d allowed", models.ErrSystemPromptTooLong, tokens, maxTokens)
+	}
+	return nil
+}
+
+// joinSystemPrompts appends the system prompt of a request to the configured
+// one, which stays in front so that a request can add to the persona of the
+// deployment but not drop its constraints
+func joinSystemPrompts(configured, requested string) string {
+	switch {
+	case requested == "":
+		return configured
+	case configured == "":
+		return requested
+	}
+	return configured + "\n\n" + requested
+}
+
+// withSystemPrompt prepends the system prompt to a QA prompt, which is kept as
+// is when there is none. The system prompt is passed as a variable, so that
+// braces in it are not read as template syntax.
+func withSystemPrompt(prompt *prompts.PromptTemplate, systemPrompt string) *prompts.PromptTemplate {
+	if systemPrompt == "" {
+		return prompt
+	}
+
+	composed := *prompt
+	composed.Template = "{{." + systemPromptVariable + "}}\n\n" + prompt.Template
+	composed.PartialVariables = map[string]any{systemPromptVariable: systemPrompt}
+	return &composed
+}
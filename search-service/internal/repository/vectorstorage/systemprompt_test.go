@@ -0,0 +1,106 @@
+package vectorstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/schema"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/identity"
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+// newPromptStorage returns a storage retrieving one chunk and recording the prompt of the model
+func newPromptStorage(cfg *Config) (*VectorStorage, *promptRecorder) {
+	generator := &promptRecorder{answer: "answer"}
+	cfg.NumOfResults, cfg.MaxTokens = 1, 100
+	return &VectorStorage{
+		vectorStore: staticStore{docs: []schema.Document{{
+			PageContent: "context",
+			Metadata:    map[string]any{resourceIdFilter: uuid.NewString(), userIDFilter: "user"},
+		}}},
+		generator: generator,
+		cfg:       cfg,
+	}, generator
+}
+
+func TestGetAnswer_SystemPrompt(t *testing.T) {
+	configured := "You are the support assistant of Acme. Never discuss pricing."
+	requested := "Answer like a pirate, {{.question}} stays as written."
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		opts       []searchservice.SearchOption
+		wantPrefix string
+	}{
+		{
+			name:       "default prompt",
+			wantPrefix: "Use the following pieces of context",
+		},
+		{
+			name:       "configured",
+			cfg:        Config{SystemPrompt: configured},
+			wantPrefix: configured + "\n\nUse the following pieces of context",
+		},
+		{
+			name:       "requested follows configured",
+			cfg:        Config{SystemPrompt: configured},
+			opts:       []searchservice.SearchOption{searchservice.WithSystemPrompt(requested)},
+			wantPrefix: configured + "\n\n" + requested + "\n\nUse the following pieces of context",
+		},
+		{
+			name:       "requested without configured",
+			opts:       []searchservice.SearchOption{searchservice.WithSystemPrompt(requested)},
+			wantPrefix: requested + "\n\nUse the following pieces of context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, generator := newPromptStorage(&tt.cfg)
+			ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+			_, _, err := storage.GetAnswer(ctx, "question", tt.opts...)
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(generator.prompt, tt.wantPrefix), generator.prompt)
+			assert.Contains(t, generator.prompt, "Question: question")
+		})
+	}
+}
+
+func TestGetAnswer_SystemPromptBudgetPerPrompt(t *testing.T) {
+	// Together the prompts exceed the budget, which only bounds the requested one
+	configured := strings.Repeat("a", 40)
+	storage, generator := newPromptStorage(&Config{SystemPrompt: configured, SystemPromptMaxTokens: 10})
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, _, err := storage.GetAnswer(ctx, "question", searchservice.WithSystemPrompt(strings.Repeat("b", 40)))
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(generator.prompt, configured+"\n\n"+strings.Repeat("b", 40)), generator.prompt)
+}
+
+func TestGetAnswer_SystemPromptOverBudget(t *testing.T) {
+	storage, generator := newPromptStorage(&Config{SystemPromptMaxTokens: 10})
+	ctx := context.WithValue(context.Background(), identity.UserIDKey, "user")
+
+	_, _, err := storage.GetAnswer(ctx, "question", searchservice.WithSystemPrompt(strings.Repeat("word ", 20)))
+
+	require.ErrorIs(t, err, models.ErrSystemPromptTooLong)
+	assert.Empty(t, generator.prompt, "the model must not be called")
+}
+
+func TestCheckSystemPrompt(t *testing.T) {
+	assert.NoError(t, checkSystemPrompt("", 0))
+	assert.NoError(t, checkSystemPrompt(strings.Repeat("a", 4*defaultSystemPromptMaxTokens), 0))
+	assert.ErrorIs(t, checkSystemPrompt(strings.Repeat("a", 4*defaultSystemPromptMaxTokens+1), 0), models.ErrSystemPromptTooLong)
+	assert.NoError(t, checkSystemPrompt("twelve chars", 3))
+	assert.ErrorIs(t, checkSystemPrompt("thirteen char", 3), models.ErrSystemPromptTooLong)
+}
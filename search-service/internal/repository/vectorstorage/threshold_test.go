@@ -0,0 +1,112 @@
+package vectorstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/services/searchservice"
+)
+
+func newThresholdOptions(opts ...searchservice.SearchOption) *searchservice.SearchOptions {
+	options := &searchservice.SearchOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+func TestScoreThreshold_ModeDefaults(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{
+		ScoreThresholds: map[string]float64{
+			string(searchservice.SearchModeVector): 0.6,
+			string(searchservice.SearchModeMMR):    0.3,
+		},
+	}}
+
+	assert.InDelta(t, 0.6, storage.scoreThreshold(newThresholdOptions()), 1e-6)
+	assert.InDelta(t, 0.3, storage.scoreThreshold(newThresholdOptions(searchservice.WithMMR(0.5))), 1e-6)
+}
+
+func TestScoreThreshold_RequestOverride(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{
+		ScoreThresholds: map[string]float64{string(searchservice.SearchModeMMR): 0.3},
+	}}
+
+	options := newThresholdOptions(searchservice.WithMMR(0.5), searchservice.WithScoreThreshold(0.8))
+
+	assert.InDelta(t, 0.8, storage.scoreThreshold(options), 1e-6)
+}
+
+func TestScoreThreshold_UnconfiguredMode(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{}}
+
+	assert.InDelta(t, defaultScoreThreshold, storage.scoreThreshold(newThresholdOptions()), 1e-6)
+}
+
+func TestAnswerScoreThreshold(t *testing.T) {
+	retrieverThreshold := 0.2
+	zero := 0.0
+
+	tests := []struct {
+		name string
+		cfg  Config
+		opts []searchservice.SearchOption
+		want float64
+	}{
+		{
+			name: "retriever threshold over mode threshold",
+			cfg:  Config{RetrieverScoreThreshold: &retrieverThreshold, ScoreThresholds: map[string]float64{"vector": 0.6}},
+			want: 0.2,
+		},
+		{
+			name: "mode threshold without retriever threshold",
+			cfg:  Config{ScoreThresholds: map[string]float64{"vector": 0.6}},
+			want: 0.6,
+		},
+		{
+			name: "request override over retriever threshold",
+			cfg:  Config{RetrieverScoreThreshold: &retrieverThreshold},
+			opts: []searchservice.SearchOption{searchservice.WithScoreThreshold(0.9)},
+			want: 0.9,
+		},
+		{
+			name: "zero request threshold disables filtering",
+			cfg:  Config{RetrieverScoreThreshold: &retrieverThreshold},
+			opts: []searchservice.SearchOption{searchservice.WithScoreThreshold(0)},
+			want: 0,
+		},
+		{
+			name: "zero retriever threshold is not unset",
+			cfg:  Config{RetrieverScoreThreshold: &zero, ScoreThresholds: map[string]float64{"vector": 0.6}},
+			want: 0,
+		},
+		{
+			name: "upper bound",
+			opts: []searchservice.SearchOption{searchservice.WithScoreThreshold(1)},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &VectorStorage{cfg: &tt.cfg}
+
+			got := storage.answerScoreThreshold(newThresholdOptions(tt.opts...))
+
+			assert.InDelta(t, tt.want, got, 1e-6)
+		})
+	}
+}
+
+func TestAnswerOptions_RetrieverK(t *testing.T) {
+	storage := &VectorStorage{cfg: &Config{NumOfResults: 10, RetrieverK: 4}}
+
+	assert.Equal(t, 4, storage.answerOptions().NumberOfReferences)
+	assert.Equal(t, 7, storage.answerOptions(searchservice.WithNumberOfReferences(7)).NumberOfReferences)
+	// Semantic search keeps returning NumOfResults
+	assert.Equal(t, 10, storage.searchOptions().NumberOfReferences)
+
+	storage.cfg.RetrieverK = 0
+	assert.Equal(t, 10, storage.answerOptions().NumberOfReferences)
+}
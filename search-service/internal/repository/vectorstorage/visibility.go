@@ -0,0 +1,46 @@
+package vectorstorage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/nzb3/diploma/search-service/internal/domain/models"
+)
+
+// visibleCondition returns the SQL condition matching the chunks the user
+// whose ID is the numbered query parameter may find, given the metadata
+// column: their own and those of public resources. Chunks without a visibility
+// are private, as are shared ones until there are teams to share them with.
+//
+// In the tenant and user collection modes searches only see the collection of
+// the request, so resources are shared within a tenant at most.
+func visibleCondition(metadata string, userParam int) string {
+	return fmt.Sprintf("(%[1]s ->> '%[2]s' = $%[3]d OR %[1]s ->> '%[4]s' = '%[5]s')",
+		metadata, userIDFilter, userParam, visibilityKey, models.ResourceVisibilityPublic)
+}
+
+// UpdateResourceVisibility records the new visibility of a resource on all its
+// chunks. Nothing is embedded again.
+func (s *VectorStorage) UpdateResourceVisibility(ctx context.Context, resourceID uuid.UUID, visibility models.ResourceVisibility) error {
+	const op = "VectorStorage.UpdateResourceVisibility"
+
+	query := fmt.Sprintf("UPDATE %s SET cmetadata = cmetadata || jsonb_build_object('%s', $2::text) WHERE cmetadata ->> '%s' = $1",
+		embeddingTableName, visibilityKey, resourceIdFilter)
+	tag, err := s.pool.Exec(ctx, query, resourceID.String(), string(visibility))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update chunk visibility",
+			"op", op,
+			"resource_id", resourceID,
+			"error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	slog.DebugContext(ctx, "Updated chunk visibility",
+		"resource_id", resourceID,
+		"visibility", visibility,
+		"chunks_count", tag.RowsAffected())
+	return nil
+}
@@ -16,24 +16,32 @@ type Config struct {
 	IdleTimeout       time.Duration `yaml:"idle_timeout" mapstructure:"idle_timeout"`
 	MaxHeaderBytes    int           `yaml:"max_header_bytes" mapstructure:"max_header_bytes"`
 	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	// StreamGracePeriod is how long active answer streams may go on after a
+	// shutdown signal before they are cancelled
+	StreamGracePeriod time.Duration `yaml:"stream_grace_period" mapstructure:"stream_grace_period"`
 }
 
-// NewConfig loads server configuration from config file and environment variables
-func NewConfig() (*Config, error) {
-	// Set defaults
-	setDefaults()
-
-	// Parse configuration from "server" section
-	config, err := configurator.ParseConfig[Config]("server")
-	if err != nil {
-		return nil, err
+// GetStreamGracePeriod returns the stream grace period, half of the shutdown
+// timeout when unset, so that cancelled streams still have time to end
+func (c *Config) GetStreamGracePeriod() time.Duration {
+	if c.StreamGracePeriod > 0 {
+		return min(c.StreamGracePeriod, c.ShutdownTimeout)
 	}
+	return c.ShutdownTimeout / 2
+}
 
-	return config, nil
+// DefaultConfig returns the server settings used when none are configured
+func DefaultConfig() Config {
+	return Config{
+		Host:              "0.0.0.0",
+		Port:              "8081",
+		ReadHeaderTimeout: 5 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		ShutdownTimeout:   10 * time.Second,
+	}
 }
 
-// setDefaults sets default values for server configuration
-func setDefaults() {
-	// Note: This is a placeholder. In practice, we'd use viper.SetDefault
-	// The actual defaults are set in the configurator package
+// NewConfig loads server configuration from config file and environment variables
+func NewConfig() (*Config, error) {
+	return configurator.LoadKeys("server", DefaultConfig())
 }
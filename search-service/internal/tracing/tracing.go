@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/nzb3/diploma/search-service/internal/configurator"
+)
+
+// ServiceName identifies this service in exported spans
+const ServiceName = "search-service"
+
+const tracerName = "github.com/nzb3/diploma/search-service"
+
+// Config holds OpenTelemetry tracing configuration
+type Config struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Endpoint is the OTLP/HTTP collector address, e.g. "otel-collector:4318"
+	Endpoint    string  `yaml:"endpoint" mapstructure:"endpoint"`
+	Insecure    bool    `yaml:"insecure" mapstructure:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" mapstructure:"sample_ratio" validate:"min=0,max=1"`
+}
+
+// NewConfig loads tracing configuration from config file, tracing is disabled
+// when it is not configured. Setting OTEL_EXPORTER_OTLP_ENDPOINT enables
+// exporting to the given collector.
+func NewConfig() (*Config, error) {
+	config, err := configurator.LoadKeys("tracing", Config{SampleRatio: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint := configurator.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+		config.Enabled = true
+	}
+
+	return config, nil
+}
+
+// NewTracerProvider installs the W3C trace context propagator and, when tracing
+// is enabled, a global tracer provider exporting spans over OTLP/HTTP.
+// The propagator is installed either way so traceparent headers are passed through.
+func NewTracerProvider(ctx context.Context, config *Config) (*sdktrace.TracerProvider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	exporterOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider, nil
+}
+
+// Tracer returns the service tracer from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartSpan starts a span named after the operation
+func StartSpan(ctx context.Context, op string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, op, opts...)
+}
+
+// EndSpan records err on the span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}